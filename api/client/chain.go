@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+
+	commands "github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chainsync/status"
+)
+
+// ID returns the node's libp2p identity details.
+func (c *Client) ID(ctx context.Context) (*commands.IDDetails, error) {
+	var out commands.IDDetails
+	if err := c.call(ctx, &out, []string{"id"}, nil); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ChainHead returns the cids of the blocks in the node's head tipset.
+func (c *Client) ChainHead(ctx context.Context) ([]cid.Cid, error) {
+	var out []cid.Cid
+	if err := c.call(ctx, &out, []string{"chain", "head"}, nil); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ChainStatus returns the status of the node's active or last chain sync.
+func (c *Client) ChainStatus(ctx context.Context) (*status.Status, error) {
+	var out status.Status
+	if err := c.call(ctx, &out, []string{"chain", "status"}, nil); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
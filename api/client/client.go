@@ -0,0 +1,177 @@
+// Package client provides a typed Go client for the go-filecoin node API.
+//
+// The client speaks the same HTTP command protocol as the go-filecoin CLI,
+// so every call here maps onto a CLI command and returns the same types the
+// command emits. Programs that would otherwise shell out to the go-filecoin
+// binary (directly or through FAST/IPTB) can depend on this package instead.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+	"reflect"
+	"strings"
+
+	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	cmdhttp "github.com/ipfs/go-ipfs-cmds/http"
+	files "github.com/ipfs/go-ipfs-files"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+	"github.com/pkg/errors"
+
+	commands "github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+// attoPerFIL is the number of attoFIL in one FIL.
+var attoPerFIL = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+// Client is a typed client for a running go-filecoin node.
+type Client struct {
+	root  *cmds.Command
+	http  cmdhttp.Client
+	token string
+}
+
+// New returns a client for the node API listening on apiAddr, a multiaddr
+// such as /ip4/127.0.0.1/tcp/3453 (the contents of the repo's api file).
+func New(apiAddr string) (*Client, error) {
+	maddr, err := ma.NewMultiaddr(apiAddr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to convert API endpoint address %s to a multiaddr", apiAddr)
+	}
+
+	_, host, err := manet.DialArgs(maddr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to dial API endpoint address %s", maddr)
+	}
+
+	return &Client{
+		root: commands.RootCmd,
+		http: cmdhttp.NewClient(host, cmdhttp.ClientWithAPIPrefix(commands.APIPrefix)),
+	}, nil
+}
+
+// WithToken returns a client sending `token` with its requests, identifying
+// it to the rate limits of the node API (see api.tokenRateLimits).
+func (c *Client) WithToken(token string) *Client {
+	withToken := *c
+	withToken.token = token
+	return &withToken
+}
+
+// call issues the command at path and decodes its single result into out.
+// A nil out discards the result.
+func (c *Client) call(ctx context.Context, out interface{}, path []string, opts cmdkit.OptMap, args ...string) error {
+	first := true
+	return c.stream(ctx, path, opts, nil, args, func(v interface{}) error {
+		if !first {
+			return nil
+		}
+		first = false
+		return assign(out, v)
+	})
+}
+
+// callWithFile issues the command at path, sending r as its file argument.
+func (c *Client) callWithFile(ctx context.Context, out interface{}, path []string, r io.Reader, args ...string) error {
+	dir := files.NewSliceDirectory([]files.DirEntry{files.FileEntry("file", files.NewReaderFile(r))})
+	first := true
+	return c.stream(ctx, path, nil, dir, args, func(v interface{}) error {
+		if !first {
+			return nil
+		}
+		first = false
+		return assign(out, v)
+	})
+}
+
+// stream issues the command at path and invokes cb for every value it emits.
+func (c *Client) stream(ctx context.Context, path []string, opts cmdkit.OptMap, dir files.Directory, args []string, cb func(interface{}) error) error {
+	if c.token != "" {
+		withToken := cmdkit.OptMap{commands.OptionAPIToken: c.token}
+		for k, v := range opts {
+			withToken[k] = v
+		}
+		opts = withToken
+	}
+
+	req, err := cmds.NewRequest(ctx, path, opts, args, dir, c.root)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.http.Send(req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		v, err := res.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := cb(v); err != nil {
+			return err
+		}
+	}
+}
+
+// assign copies a decoded response value into out. Values of the same type
+// are copied directly; anything else goes through a JSON round trip, which is
+// how the CLI itself moves values between daemon and client.
+func assign(out interface{}, v interface{}) error {
+	if out == nil || v == nil {
+		return nil
+	}
+
+	outVal := reflect.ValueOf(out)
+	if outVal.Kind() != reflect.Ptr || outVal.IsNil() {
+		return errors.New("output must be a non-nil pointer")
+	}
+
+	val := reflect.ValueOf(v)
+	if val.Type() == outVal.Type() {
+		outVal.Elem().Set(val.Elem())
+		return nil
+	}
+	if val.Type() == outVal.Type().Elem() {
+		outVal.Elem().Set(val)
+		return nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// filString formats an attoFIL amount as the decimal FIL string the CLI
+// commands expect, e.g. 1500000000000000000 becomes "1.5".
+func filString(amt types.AttoFIL) string {
+	if amt.Int == nil {
+		return "0"
+	}
+
+	whole, frac := new(big.Int).QuoRem(amt.Int, attoPerFIL, new(big.Int))
+	if frac.Sign() == 0 {
+		return whole.String()
+	}
+
+	fracStr := strings.TrimRight(leftPad(frac.String(), 18), "0")
+	return whole.String() + "." + fracStr
+}
+
+func leftPad(s string, n int) string {
+	if len(s) >= n {
+		return s
+	}
+	return strings.Repeat("0", n-len(s)) + s
+}
@@ -0,0 +1,79 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/api/client"
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/node"
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/node/test"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestClientWallet(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	ctx := context.Background()
+	builder := test.NewNodeBuilder(t)
+	cs := node.FixtureChainSeed(t)
+	builder.WithGenesisInit(cs.GenesisInitFunc)
+
+	n, cmdClient, done := builder.BuildAndStartAPI(ctx)
+	defer done()
+
+	c, err := client.New(cmdClient.Address())
+	require.NoError(t, err)
+
+	addr, err := c.WalletNewAddress(ctx, address.BLS)
+	require.NoError(t, err)
+	assert.True(t, n.Wallet.Wallet.HasAddress(addr))
+
+	addrs, err := c.WalletAddresses(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, addrs, addr)
+
+	balance, err := c.WalletBalance(ctx, addr)
+	require.NoError(t, err)
+	assert.True(t, balance.IsZero())
+
+	require.NoError(t, c.WalletSetLabel(ctx, addr, "client-test"))
+	labels, err := c.WalletLabels(ctx)
+	require.NoError(t, err)
+	require.Len(t, labels, 1)
+	assert.Equal(t, addr, labels[0].Address)
+}
+
+func TestClientImportAndCat(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	ctx := context.Background()
+	builder := test.NewNodeBuilder(t)
+
+	_, cmdClient, done := builder.BuildAndStartAPI(ctx)
+	defer done()
+
+	c, err := client.New(cmdClient.Address())
+	require.NoError(t, err)
+
+	data := []byte("typed client round trip")
+	root, err := c.ClientImport(ctx, bytes.NewReader(data))
+	require.NoError(t, err)
+
+	r, err := c.ClientCat(ctx, root)
+	require.NoError(t, err)
+	defer r.Close() // nolint: errcheck
+
+	out, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, data, out)
+
+	head, err := c.ChainHead(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, head)
+}
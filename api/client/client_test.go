@@ -0,0 +1,65 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	commands "github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+func TestFILString(t *testing.T) {
+	tf.UnitTest(t)
+
+	for _, tc := range []struct {
+		atto string
+		fil  string
+	}{
+		{"0", "0"},
+		{"1000000000000000000", "1"},
+		{"1500000000000000000", "1.5"},
+		{"1", "0.000000000000000001"},
+		{"123000000000000000000000", "123000"},
+	} {
+		amt, ok := types.NewAttoFILFromString(tc.atto, 10)
+		require.True(t, ok)
+		assert.Equal(t, tc.fil, filString(amt))
+
+		// Round trip through the parser the commands use.
+		parsed, ok := types.NewAttoFILFromFILString(filString(amt))
+		require.True(t, ok)
+		assert.True(t, amt.Equals(parsed))
+	}
+
+	assert.Equal(t, "0", filString(abi.TokenAmount{}))
+}
+
+func TestAssign(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("same pointer type", func(t *testing.T) {
+		in := &commands.MiningStatusResult{Active: true}
+		var out commands.MiningStatusResult
+		require.NoError(t, assign(&out, in))
+		assert.True(t, out.Active)
+	})
+
+	t.Run("through json", func(t *testing.T) {
+		c, err := cid.Decode("bafkqaaa")
+		require.NoError(t, err)
+
+		in := map[string]interface{}{"Cid": c}
+		var out commands.MessageSendResult
+		require.NoError(t, assign(&out, in))
+		assert.Equal(t, c, out.Cid)
+	})
+
+	t.Run("nil output discards", func(t *testing.T) {
+		assert.NoError(t, assign(nil, "ignored"))
+	})
+}
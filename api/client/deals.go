@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"io"
+	"strconv"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
+	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
+
+	commands "github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
+)
+
+// ClientImport imports data into the node's DAG and returns its root cid.
+func (c *Client) ClientImport(ctx context.Context, data io.Reader) (cid.Cid, error) {
+	var out cid.Cid
+	if err := c.callWithFile(ctx, &out, []string{"client", "import"}, data); err != nil {
+		return cid.Undef, err
+	}
+	return out, nil
+}
+
+// ClientCat returns the contents of the file with the given root cid.
+// The caller must close the returned reader.
+func (c *Client) ClientCat(ctx context.Context, root cid.Cid) (io.ReadCloser, error) {
+	var out io.ReadCloser
+	err := c.stream(ctx, []string{"client", "cat"}, nil, nil, []string{root.String()}, func(v interface{}) error {
+		if r, ok := v.(io.ReadCloser); ok {
+			out = r
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ClientProposeStorageDeal proposes a storage deal for data with a miner.
+func (c *Client) ClientProposeStorageDeal(ctx context.Context, miner address.Address, data cid.Cid, start, end abi.ChainEpoch, price, collateral types.AttoFIL) (*storagemarket.ProposeStorageDealResult, error) {
+	args := []string{
+		miner.String(),
+		data.String(),
+		strconv.FormatInt(int64(start), 10),
+		strconv.FormatInt(int64(end), 10),
+		filString(price),
+		filString(collateral),
+	}
+
+	var out storagemarket.ProposeStorageDealResult
+	if err := c.call(ctx, &out, []string{"client", "propose-storage-deal"}, nil, args...); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ClientQueryStorageDeal returns the client's view of a storage deal.
+func (c *Client) ClientQueryStorageDeal(ctx context.Context, proposal cid.Cid) (*storagemarket.ClientDeal, error) {
+	var out storagemarket.ClientDeal
+	if err := c.call(ctx, &out, []string{"client", "query-storage-deal"}, nil, proposal.String()); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ClientListAsks lists the asks in the storage market.
+func (c *Client) ClientListAsks(ctx context.Context) ([]*storagemarket.SignedStorageAsk, error) {
+	var out []*storagemarket.SignedStorageAsk
+	if err := c.call(ctx, &out, []string{"client", "list-asks"}, nil); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MinerCreate creates a new miner actor, paying collateral from the given address.
+func (c *Client) MinerCreate(ctx context.Context, from address.Address, collateral types.AttoFIL, gasPrice types.AttoFIL, gasLimit gas.Unit, sectorSize abi.SectorSize) (address.Address, error) {
+	opts := cmdkit.OptMap{
+		"from":       from.String(),
+		"gas-price":  filString(gasPrice),
+		"gas-limit":  int64(gasLimit),
+		"sectorsize": strconv.FormatUint(uint64(sectorSize), 10),
+	}
+
+	var out commands.MinerCreateResult
+	if err := c.call(ctx, &out, []string{"miner", "create"}, opts, filString(collateral)); err != nil {
+		return address.Undef, err
+	}
+	return out.Address, nil
+}
+
+// MinerGetStatus returns the on-chain status of a miner.
+func (c *Client) MinerGetStatus(ctx context.Context, miner address.Address) (*porcelain.MinerStatus, error) {
+	var out porcelain.MinerStatus
+	if err := c.call(ctx, &out, []string{"miner", "status"}, nil, miner.String()); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MinerSetPrice creates a new storage ask for the node's miner.
+func (c *Client) MinerSetPrice(ctx context.Context, price types.AttoFIL, duration abi.ChainEpoch) (*commands.MinerSetPriceResult, error) {
+	var out commands.MinerSetPriceResult
+	if err := c.call(ctx, &out, []string{"miner", "set-price"}, nil, filString(price), strconv.FormatInt(int64(duration), 10)); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
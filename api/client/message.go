@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/ipfs/go-cid"
+	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
+
+	commands "github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
+)
+
+// MessageSend sends a message from the given wallet address and returns its cid.
+// It does not wait for the message to be included in a block.
+func (c *Client) MessageSend(ctx context.Context, from, to address.Address, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit gas.Unit, method abi.MethodNum) (cid.Cid, error) {
+	opts := cmdkit.OptMap{
+		"value":     filString(value),
+		"from":      from.String(),
+		"gas-price": filString(gasPrice),
+		"gas-limit": int64(gasLimit),
+	}
+	args := []string{to.String()}
+	if method != builtin.MethodSend {
+		args = append(args, strconv.FormatUint(uint64(method), 10))
+	}
+
+	var out commands.MessageSendResult
+	if err := c.call(ctx, &out, []string{"message", "send"}, opts, args...); err != nil {
+		return cid.Undef, err
+	}
+	return out.Cid, nil
+}
+
+// MessageWait blocks until the message is included in a block, the node's
+// wait timeout expires or ctx is cancelled.
+func (c *Client) MessageWait(ctx context.Context, msgCid cid.Cid) (*commands.WaitResult, error) {
+	var out commands.WaitResult
+	if err := c.call(ctx, &out, []string{"message", "wait"}, nil, msgCid.String()); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MessagePoolPending lists the messages in the node's message pool.
+func (c *Client) MessagePoolPending(ctx context.Context) ([]*types.SignedMessage, error) {
+	var out []*types.SignedMessage
+	if err := c.call(ctx, &out, []string{"mpool", "ls"}, nil); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
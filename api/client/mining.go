@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/ipfs/go-cid"
+
+	commands "github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
+)
+
+// MiningAddress returns the address of the miner actor the node mines for.
+func (c *Client) MiningAddress(ctx context.Context) (address.Address, error) {
+	var out address.Address
+	if err := c.call(ctx, &out, []string{"mining", "address"}, nil); err != nil {
+		return address.Undef, err
+	}
+	return out, nil
+}
+
+// MiningOnce mines a single block and returns its cid.
+func (c *Client) MiningOnce(ctx context.Context) (cid.Cid, error) {
+	var out cid.Cid
+	if err := c.call(ctx, &out, []string{"mining", "once"}, nil); err != nil {
+		return cid.Undef, err
+	}
+	return out, nil
+}
+
+// MiningSetup prepares the node to accept storage deals without starting block mining.
+func (c *Client) MiningSetup(ctx context.Context) error {
+	return c.call(ctx, nil, []string{"mining", "setup"}, nil)
+}
+
+// MiningStart starts block mining.
+func (c *Client) MiningStart(ctx context.Context) error {
+	return c.call(ctx, nil, []string{"mining", "start"}, nil)
+}
+
+// MiningStop stops block mining.
+func (c *Client) MiningStop(ctx context.Context) error {
+	return c.call(ctx, nil, []string{"mining", "stop"}, nil)
+}
+
+// MiningStatus reports whether the node is mining and for which miner.
+func (c *Client) MiningStatus(ctx context.Context) (*commands.MiningStatusResult, error) {
+	var out commands.MiningStatusResult
+	if err := c.call(ctx, &out, []string{"mining", "status"}, nil); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
+	"github.com/pkg/errors"
+
+	commands "github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
+)
+
+// WalletAddresses lists the addresses in the node's wallet.
+func (c *Client) WalletAddresses(ctx context.Context) ([]address.Address, error) {
+	var out commands.AddressLsResult
+	if err := c.call(ctx, &out, []string{"address", "ls"}, nil); err != nil {
+		return nil, err
+	}
+	return out.Addresses, nil
+}
+
+// WalletNewAddress creates a new address of the given protocol in the node's wallet.
+func (c *Client) WalletNewAddress(ctx context.Context, protocol address.Protocol) (address.Address, error) {
+	var protocolName string
+	switch protocol {
+	case address.SECP256K1:
+		protocolName = "secp256k1"
+	case address.BLS:
+		protocolName = "bls"
+	default:
+		return address.Undef, errors.Errorf("unsupported address protocol %d", protocol)
+	}
+
+	var out commands.AddressResult
+	if err := c.call(ctx, &out, []string{"address", "new"}, cmdkit.OptMap{"type": protocolName}); err != nil {
+		return address.Undef, err
+	}
+	return out.Address, nil
+}
+
+// WalletDefaultAddress returns the node's default wallet address.
+func (c *Client) WalletDefaultAddress(ctx context.Context) (address.Address, error) {
+	var out commands.AddressResult
+	if err := c.call(ctx, &out, []string{"address", "default"}, nil); err != nil {
+		return address.Undef, err
+	}
+	return out.Address, nil
+}
+
+// WalletBalance returns the balance of an address.
+func (c *Client) WalletBalance(ctx context.Context, addr address.Address) (abi.TokenAmount, error) {
+	var out abi.TokenAmount
+	if err := c.call(ctx, &out, []string{"wallet", "balance"}, nil, addr.String()); err != nil {
+		return abi.NewTokenAmount(0), err
+	}
+	return out, nil
+}
+
+// WalletExport returns the key infos for the given wallet addresses.
+func (c *Client) WalletExport(ctx context.Context, addrs ...address.Address) ([]*crypto.KeyInfo, error) {
+	args := make([]string, len(addrs))
+	for i, addr := range addrs {
+		args[i] = addr.String()
+	}
+
+	var out commands.WalletSerializeResult
+	if err := c.call(ctx, &out, []string{"wallet", "export"}, nil, args...); err != nil {
+		return nil, err
+	}
+	return out.KeyInfo, nil
+}
+
+// WalletSetLabel assigns a label to an address in the node's address book.
+func (c *Client) WalletSetLabel(ctx context.Context, addr address.Address, label string) error {
+	return c.call(ctx, nil, []string{"wallet", "label"}, nil, addr.String(), label)
+}
+
+// WalletLabels lists the labels in the node's address book.
+func (c *Client) WalletLabels(ctx context.Context) ([]commands.WalletLabelResult, error) {
+	var out commands.WalletLabelsResult
+	if err := c.call(ctx, &out, []string{"wallet", "labels"}, nil); err != nil {
+		return nil, err
+	}
+	return out.Labels, nil
+}
@@ -151,6 +151,8 @@ func build() {
 	buildFilecoin()
 	buildGengen()
 	buildFaucet()
+	buildNetstat()
+	buildGasCalibration()
 	buildGenesisFileServer()
 	generateGenesis()
 	buildMigrations()
@@ -161,6 +163,8 @@ func forcebuild() {
 	forceBuildFC()
 	buildGengen()
 	buildFaucet()
+	buildNetstat()
+	buildGasCalibration()
 	buildGenesisFileServer()
 	generateGenesis()
 	buildMigrations()
@@ -271,6 +275,18 @@ func buildFaucet() {
 	runCmd(cmd([]string{"go", "build", "-o", "./tools/faucet/faucet", "./tools/faucet/"}...))
 }
 
+func buildNetstat() {
+	log.Println("Building netstat...")
+
+	runCmd(cmd([]string{"go", "build", "-o", "./tools/netstat/netstat", "./tools/netstat/"}...))
+}
+
+func buildGasCalibration() {
+	log.Println("Building gas-calibration...")
+
+	runCmd(cmd([]string{"go", "build", "-o", "./tools/gas-calibration/gas-calibration", "./tools/gas-calibration/"}...))
+}
+
 func buildGenesisFileServer() {
 	log.Println("Building genesis file server...")
 
@@ -1,16 +1,32 @@
 package commands
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"strconv"
+	"strings"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/actors/runtime/exitcode"
 	"github.com/ipfs/go-cid"
 	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
 	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/pkg/errors"
 
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/plumbing/msg"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/actor"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/actor/builtin/kv"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
 )
 
 // ActorView represents a generic way to represent details about any actor to the user.
@@ -27,7 +43,10 @@ var actorCmd = &cmds.Command{
 		Tagline: "Interact with actors. Actors are built-in smart contracts.",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"ls": actorLsCmd,
+		"call":   actorCallCmd,
+		"deploy": actorDeployCmd,
+		"ls":     actorLsCmd,
+		"state":  actorStateCmd,
 	},
 }
 
@@ -76,3 +95,329 @@ func makeActorView(act *actor.Actor, addr address.Address) *ActorView {
 		Head:    act.Head.Cid,
 	}
 }
+
+// ActorDeployResult is the result of deploying an actor.
+type ActorDeployResult struct {
+	Cid           cid.Cid
+	IDAddress     address.Address
+	RobustAddress address.Address
+	// DryRun is the message that would have been sent, set with --dry-run.
+	DryRun *DryRunResult `json:",omitempty"`
+}
+
+var actorDeployCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Deploy an experimental key-value actor (devnets only)",
+		ShortDescription: `
+Creates a key-value actor owned by the sender, through the init actor. The
+network must enable devnet actors with the parameters.DevnetActors config.
+The owner and writers may set and delete keys, and the owner adds payments of
+the actor's funds that anyone may settle once a key holds a value.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("writers", "Comma separated addresses or labels allowed to write keys"),
+		cmdkit.StringOption("value", "Value in FIL funding the actor"),
+		cmdkit.StringOption("from", "Address or label of the owner"),
+		priceOption,
+		limitOption,
+		dryRunOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		fromAddr, err := fromAddrOrDefault(req, env)
+		if err != nil {
+			return err
+		}
+		val, err := actorValueOption(req)
+		if err != nil {
+			return err
+		}
+		gasPrice, gasLimit, _, err := parseGasOptions(req)
+		if err != nil {
+			return err
+		}
+
+		ctorParams := kv.ConstructorParams{Owner: fromAddr, Writers: []address.Address{}}
+		if writers, ok := req.Options["writers"].(string); ok && writers != "" {
+			for _, w := range strings.Split(writers, ",") {
+				addr, err := addressFromString(env, strings.TrimSpace(w))
+				if err != nil {
+					return err
+				}
+				ctorParams.Writers = append(ctorParams.Writers, addr)
+			}
+		}
+		encoded, err := encoding.Encode(&ctorParams)
+		if err != nil {
+			return err
+		}
+
+		c, receipt, dryRun, err := sendActorMessage(req, env, fromAddr, builtin.InitActorAddr, val, gasPrice, gasLimit,
+			builtin.MethodsInit.Exec, &init_.ExecParams{CodeCID: kv.CodeID, ConstructorParams: encoded})
+		if err != nil {
+			return err
+		}
+		var ret init_.ExecReturn
+		if err := ret.UnmarshalCBOR(bytes.NewReader(receipt.ReturnValue)); err != nil {
+			return errors.Wrap(err, "failed to decode the address of the actor")
+		}
+		return re.Emit(&ActorDeployResult{Cid: c, IDAddress: ret.IDAddress, RobustAddress: ret.RobustAddress, DryRun: dryRun})
+	},
+	Type: ActorDeployResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *ActorDeployResult) error {
+			if _, err := fmt.Fprintf(w, "%s (%s)\n", res.RobustAddress, res.IDAddress); err != nil {
+				return err
+			}
+			return writeDryRunNote(w, res.DryRun)
+		}),
+	},
+}
+
+// ActorCallResult is the result of calling an actor.
+type ActorCallResult struct {
+	Cid cid.Cid
+	// PaymentID is set by add-payment.
+	PaymentID *uint64 `json:",omitempty"`
+	// DryRun is the message that would have been sent, set with --dry-run.
+	DryRun *DryRunResult `json:",omitempty"`
+}
+
+var actorCallCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Call a method of an experimental key-value actor (devnets only)",
+		ShortDescription: `
+Sends a message calling a method of a key-value actor and waits for it to be
+executed. The methods and their arguments are:
+
+  set <key> <value>                        set a key, by the owner or a writer
+  delete <key>                             delete a key, by the owner or a writer
+  add-payment <key> <value> <to> <amount>  pay <amount> FIL to <to> once <key> holds <value>, by the owner
+  cancel-payment <id>                      cancel a payment, by the owner
+  settle <id>                              make a payment whose condition holds, by anyone
+
+Use --value to fund the actor with the message.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("actor", true, false, "Address or label of the actor"),
+		cmdkit.StringArg("method", true, false, "The method to call"),
+		cmdkit.StringArg("args", false, true, "The arguments of the method"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("value", "Value to send with message in FIL"),
+		cmdkit.StringOption("from", "Address or label to send message from"),
+		priceOption,
+		limitOption,
+		dryRunOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		target, err := addressFromString(env, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		method, params, err := kvCallParams(env, req.Arguments[1], req.Arguments[2:])
+		if err != nil {
+			return err
+		}
+		fromAddr, err := fromAddrOrDefault(req, env)
+		if err != nil {
+			return err
+		}
+		val, err := actorValueOption(req)
+		if err != nil {
+			return err
+		}
+		gasPrice, gasLimit, _, err := parseGasOptions(req)
+		if err != nil {
+			return err
+		}
+
+		c, receipt, dryRun, err := sendActorMessage(req, env, fromAddr, target, val, gasPrice, gasLimit, method, params)
+		if err != nil {
+			return err
+		}
+
+		res := &ActorCallResult{Cid: c, DryRun: dryRun}
+		if method == kv.Methods.AddPayment {
+			var ret kv.PaymentIDReturn
+			if err := ret.UnmarshalCBOR(bytes.NewReader(receipt.ReturnValue)); err != nil {
+				return errors.Wrap(err, "failed to decode the payment id")
+			}
+			res.PaymentID = &ret.ID
+		}
+		return re.Emit(res)
+	},
+	Type: ActorCallResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *ActorCallResult) error {
+			var err error
+			if res.PaymentID != nil {
+				_, err = fmt.Fprintf(w, "payment %d\n", *res.PaymentID)
+			} else {
+				_, err = fmt.Fprintln(w, res.Cid)
+			}
+			if err != nil {
+				return err
+			}
+			return writeDryRunNote(w, res.DryRun)
+		}),
+	},
+}
+
+// kvCallParams parses the method and arguments of a key-value actor call.
+func kvCallParams(env cmds.Environment, method string, args []string) (abi.MethodNum, interface{}, error) {
+	arity := map[string]int{"set": 2, "delete": 1, "add-payment": 4, "cancel-payment": 1, "settle": 1}
+	n, ok := arity[method]
+	if !ok {
+		return 0, nil, fmt.Errorf("unknown method %q", method)
+	}
+	if len(args) != n {
+		return 0, nil, fmt.Errorf("%s takes %d arguments", method, n)
+	}
+
+	switch method {
+	case "set":
+		return kv.Methods.Set, &kv.SetParams{Key: args[0], Value: []byte(args[1])}, nil
+	case "delete":
+		return kv.Methods.Delete, &kv.KeyParams{Key: args[0]}, nil
+	case "add-payment":
+		to, err := addressFromString(env, args[2])
+		if err != nil {
+			return 0, nil, err
+		}
+		amount, ok := types.NewAttoFILFromFILString(args[3])
+		if !ok {
+			return 0, nil, errors.New("mal-formed amount")
+		}
+		return kv.Methods.AddPayment, &kv.AddPaymentParams{Key: args[0], Value: []byte(args[1]), To: to, Amount: amount}, nil
+	}
+
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "invalid payment id")
+	}
+	if method == "settle" {
+		return kv.Methods.Settle, &kv.PaymentIDParams{ID: id}, nil
+	}
+	return kv.Methods.CancelPayment, &kv.PaymentIDParams{ID: id}, nil
+}
+
+// ActorStateResult is the state of a key-value actor, with string values.
+type ActorStateResult struct {
+	Owner    address.Address
+	Writers  []address.Address
+	Entries  map[string]string
+	Payments []ActorPaymentView
+}
+
+// ActorPaymentView is a pending payment of a key-value actor.
+type ActorPaymentView struct {
+	ID     uint64
+	Key    string
+	Value  string
+	To     address.Address
+	Amount types.AttoFIL
+}
+
+var actorStateCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the state of an experimental key-value actor (devnets only)",
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("actor", true, false, "Address or label of the actor"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		addr, err := addressFromString(env, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		view, err := GetPorcelainAPI(env).StateView(GetPorcelainAPI(env).ChainHeadKey())
+		if err != nil {
+			return err
+		}
+		st, err := view.DevnetKVState(req.Context, addr)
+		if err != nil {
+			return err
+		}
+
+		res := &ActorStateResult{
+			Owner:    st.Owner,
+			Writers:  st.Writers,
+			Entries:  map[string]string{},
+			Payments: []ActorPaymentView{},
+		}
+		for _, e := range st.Entries {
+			res.Entries[e.Key] = string(e.Value)
+		}
+		for _, p := range st.Payments {
+			res.Payments = append(res.Payments, ActorPaymentView{ID: p.ID, Key: p.Key, Value: string(p.Value), To: p.To, Amount: p.Amount})
+		}
+		return re.Emit(res)
+	},
+	Type: ActorStateResult{},
+}
+
+func actorValueOption(req *cmds.Request) (types.AttoFIL, error) {
+	rawVal, ok := req.Options["value"].(string)
+	if !ok {
+		return types.ZeroAttoFIL, nil
+	}
+	val, ok := types.NewAttoFILFromFILString(rawVal)
+	if !ok {
+		return types.ZeroAttoFIL, errors.New("mal-formed value")
+	}
+	return val, nil
+}
+
+// sendActorMessage sends a message and waits for its receipt or, with
+// --dry-run, applies it to the current state without sending it. It fails when
+// the message is not executed successfully.
+func sendActorMessage(req *cmds.Request, env cmds.Environment, from, to address.Address, val, gasPrice types.AttoFIL, gasLimit gas.Unit,
+	method abi.MethodNum, params interface{}) (cid.Cid, *vm.MessageReceipt, *DryRunResult, error) {
+	if dryRun, _ := req.Options["dry-run"].(bool); dryRun {
+		d, err := GetPorcelainAPI(env).MessageDryRun(req.Context, from, to, val, gasPrice, gasLimit, method, params)
+		if err != nil {
+			return cid.Undef, nil, nil, err
+		}
+		if d.Receipt.ExitCode != exitcode.Ok {
+			return cid.Undef, nil, nil, fmt.Errorf("message %s would fail with exit code %d", d.Cid, d.Receipt.ExitCode)
+		}
+		return d.Cid, &d.Receipt, newDryRunResult(d), nil
+	}
+
+	c, _, err := GetPorcelainAPI(env).MessageSend(req.Context, from, to, val, gasPrice, gasLimit, method, params)
+	if err != nil {
+		return cid.Undef, nil, nil, err
+	}
+	receipt, err := waitActorReceipt(req.Context, env, c)
+	if err != nil {
+		return cid.Undef, nil, nil, err
+	}
+	return c, receipt, nil, nil
+}
+
+func writeDryRunNote(w io.Writer, d *DryRunResult) error {
+	if d == nil {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "dry run: message %s was not sent (gas used %d)\n", d.Cid, d.GasUsed)
+	return err
+}
+
+// waitActorReceipt waits for a message and fails when it was not executed
+// successfully.
+func waitActorReceipt(ctx context.Context, env cmds.Environment, c cid.Cid) (*vm.MessageReceipt, error) {
+	var receipt *vm.MessageReceipt
+	err := GetPorcelainAPI(env).MessageWait(ctx, c, msg.DefaultMessageWaitLookback, func(_ *block.Block, _ *types.SignedMessage, r *vm.MessageReceipt) error {
+		receipt = r
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if receipt.ExitCode != exitcode.Ok {
+		return nil, fmt.Errorf("message %s failed with exit code %d", c, receipt.ExitCode)
+	}
+	return receipt, nil
+}
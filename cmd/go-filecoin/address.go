@@ -1,13 +1,17 @@
 package commands
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 
 	"github.com/filecoin-project/go-address"
 	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
 	cmds "github.com/ipfs/go-ipfs-cmds"
 	files "github.com/ipfs/go-ipfs-files"
+	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
@@ -18,9 +22,14 @@ var walletCmd = &cmds.Command{
 		Tagline: "Manage your filecoin wallets",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"balance": balanceCmd,
-		"import":  walletImportCmd,
-		"export":  walletExportCmd,
+		"balance":     balanceCmd,
+		"import":      walletImportCmd,
+		"export":      walletExportCmd,
+		"label":       walletLabelCmd,
+		"labels":      walletLabelsCmd,
+		"sign-data":   walletSignDataCmd,
+		"unlabel":     walletUnlabelCmd,
+		"verify-data": walletVerifyDataCmd,
 	},
 }
 
@@ -42,6 +51,8 @@ type AddressResult struct {
 // AddressLsResult is the result of running the address list command.
 type AddressLsResult struct {
 	Addresses []address.Address
+	// Labels maps the string form of labeled addresses to their address book label.
+	Labels map[string]string `json:",omitempty"`
 }
 
 var addrsNewCmd = &cmds.Command{
@@ -75,6 +86,12 @@ var addrsLsCmd = &cmds.Command{
 		var alr AddressLsResult
 		for _, addr := range addrs {
 			alr.Addresses = append(alr.Addresses, addr)
+			if label, ok := GetPorcelainAPI(env).WalletLabel(addr); ok {
+				if alr.Labels == nil {
+					alr.Labels = make(map[string]string)
+				}
+				alr.Labels[addr.String()] = label
+			}
 		}
 
 		return re.Emit(&alr)
@@ -96,10 +113,10 @@ var defaultAddressCmd = &cmds.Command{
 
 var balanceCmd = &cmds.Command{
 	Arguments: []cmdkit.Argument{
-		cmdkit.StringArg("address", true, false, "Address to get balance for"),
+		cmdkit.StringArg("address", true, false, "Address or label to get balance for"),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		addr, err := address.NewFromString(req.Arguments[0])
+		addr, err := addressFromString(env, req.Arguments[0])
 		if err != nil {
 			return err
 		}
@@ -160,12 +177,12 @@ var walletImportCmd = &cmds.Command{
 
 var walletExportCmd = &cmds.Command{
 	Arguments: []cmdkit.Argument{
-		cmdkit.StringArg("addresses", true, true, "Addresses of keys to export").EnableStdin(),
+		cmdkit.StringArg("addresses", true, true, "Addresses or labels of keys to export").EnableStdin(),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		addrs := make([]address.Address, len(req.Arguments))
 		for i, arg := range req.Arguments {
-			addr, err := address.NewFromString(arg)
+			addr, err := addressFromString(env, arg)
 			if err != nil {
 				return err
 			}
@@ -184,3 +201,185 @@ var walletExportCmd = &cmds.Command{
 	},
 	Type: &WalletSerializeResult{},
 }
+
+// WalletLabelResult is the result of labeling an address.
+type WalletLabelResult struct {
+	Label   string
+	Address address.Address
+}
+
+// WalletLabelsResult is the result of listing the address book.
+type WalletLabelsResult struct {
+	Labels []WalletLabelResult
+}
+
+var walletLabelCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Assign a label to an address",
+		ShortDescription: `
+Labels may be used in place of an address in any command that accepts one.
+Each address has at most one label; labeling an address again replaces its label.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("address", true, false, "Address to label"),
+		cmdkit.StringArg("label", true, false, "Label to assign to the address"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		addr, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		label := req.Arguments[1]
+		if err := GetPorcelainAPI(env).WalletSetLabel(addr, label); err != nil {
+			return err
+		}
+		return re.Emit(&WalletLabelResult{Label: label, Address: addr})
+	},
+	Type: &WalletLabelResult{},
+}
+
+var walletUnlabelCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Remove a label from the address book",
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("label", true, false, "Label to remove"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		return GetPorcelainAPI(env).WalletRemoveLabel(req.Arguments[0])
+	},
+}
+
+var walletLabelsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List labeled addresses",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		var res WalletLabelsResult
+		for _, entry := range GetPorcelainAPI(env).WalletLabels() {
+			res.Labels = append(res.Labels, WalletLabelResult{Label: entry.Label, Address: entry.Address})
+		}
+		return re.Emit(&res)
+	},
+	Type: &WalletLabelsResult{},
+}
+
+// WalletSignDataResult is the result of signing data.
+type WalletSignDataResult struct {
+	Address address.Address
+	Domain  string
+	// Signature is the hex encoding of the signature type followed by the
+	// signature bytes.
+	Signature string
+}
+
+var walletSignDataCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Sign arbitrary data with the key of an address",
+		ShortDescription: `
+Signs the data for the application domain given with --domain. The signed
+payload includes the domain and a prefix no message or block signature has,
+so the signature can only be verified with verify-data for the same domain.
+The signature is printed in hex.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("address", true, false, "Address or label of the signing key"),
+		cmdkit.FileArg("data", true, false, "File containing the data to sign").EnableStdin(),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("domain", "The application domain of the signature, such as a service name"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		addr, err := addressFromString(env, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		domain, _ := req.Options["domain"].(string)
+		data, err := readFileArg(req)
+		if err != nil {
+			return err
+		}
+
+		sig, err := GetPorcelainAPI(env).WalletSignData(addr, domain, data)
+		if err != nil {
+			return err
+		}
+		raw, err := sig.MarshalBinary()
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(&WalletSignDataResult{Address: addr, Domain: domain, Signature: hex.EncodeToString(raw)})
+	},
+	Type: &WalletSignDataResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *WalletSignDataResult) error {
+			_, err := fmt.Fprintln(w, res.Signature)
+			return err
+		}),
+	},
+}
+
+var walletVerifyDataCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Verify a signature of data made with sign-data",
+		ShortDescription: `
+Verifies that the hex signature was made by sign-data by the key of the address
+for the domain given with --domain. Fails when the signature is invalid.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("address", true, false, "Address or label of the signing key"),
+		cmdkit.StringArg("signature", true, false, "The signature in hex"),
+		cmdkit.FileArg("data", true, false, "File containing the signed data").EnableStdin(),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("domain", "The application domain of the signature"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		addr, err := addressFromString(env, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		raw, err := hex.DecodeString(req.Arguments[1])
+		if err != nil {
+			return errors.Wrap(err, "invalid signature encoding")
+		}
+		var sig crypto.Signature
+		if err := sig.UnmarshalBinary(raw); err != nil {
+			return errors.Wrap(err, "invalid signature")
+		}
+		domain, _ := req.Options["domain"].(string)
+		data, err := readFileArg(req)
+		if err != nil {
+			return err
+		}
+
+		if err := crypto.ValidateDataSignature(domain, data, addr, sig); err != nil {
+			return err
+		}
+		return re.Emit(&AddressResult{Address: addr})
+	},
+	Type: &AddressResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *AddressResult) error {
+			_, err := fmt.Fprintf(w, "valid signature by %s\n", res.Address)
+			return err
+		}),
+	},
+}
+
+func readFileArg(req *cmds.Request) ([]byte, error) {
+	iter := req.Files.Entries()
+	if !iter.Next() {
+		return nil, fmt.Errorf("no file given: %s", iter.Err())
+	}
+	fi, ok := iter.Node().(files.File)
+	if !ok {
+		return nil, fmt.Errorf("given file was not a files.File")
+	}
+	return ioutil.ReadAll(fi)
+}
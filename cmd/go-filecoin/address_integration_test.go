@@ -132,3 +132,36 @@ func TestWalletExportImportRoundTrip(t *testing.T) {
 	assert.Len(t, importResult.Addresses, 1)
 	assert.Equal(t, lsResult.Addresses[0], importResult.Addresses[0])
 }
+
+func TestWalletLabels(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	ctx := context.Background()
+	builder := test.NewNodeBuilder(t)
+	cs := node.FixtureChainSeed(t)
+	builder.WithGenesisInit(cs.GenesisInitFunc)
+
+	_, cmdClient, done := builder.BuildAndStartAPI(ctx)
+	defer done()
+
+	rewardAddr := builtin.RewardActorAddr.String()
+
+	var labeled commands.WalletLabelResult
+	cmdClient.RunMarshaledJSON(ctx, &labeled, "wallet", "label", rewardAddr, "reward")
+	assert.Equal(t, "reward", labeled.Label)
+	assert.Equal(t, builtin.RewardActorAddr, labeled.Address)
+
+	t.Log("[success] labels are accepted in place of addresses")
+	var balance abi.TokenAmount
+	cmdClient.RunMarshaledJSON(ctx, &balance, "wallet", "balance", "reward")
+	assert.Equal(t, "1394000000000000000000000000", balance.String())
+
+	var labels commands.WalletLabelsResult
+	cmdClient.RunMarshaledJSON(ctx, &labels, "wallet", "labels")
+	require.Len(t, labels.Labels, 1)
+	assert.Equal(t, labeled, labels.Labels[0])
+
+	t.Log("[failure] unlabeled names are rejected")
+	cmdClient.RunSuccess(ctx, "wallet", "unlabel", "reward")
+	cmdClient.RunFail(ctx, "nor a known label", "wallet", "balance", "reward")
+}
@@ -0,0 +1,233 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
+)
+
+// maxClientBuckets is the number of client buckets past which those that
+// have refilled are discarded.
+const maxClientBuckets = 4096
+
+const (
+	// BackpressureRateLimited is the reason of the rejection of a request that
+	// would wait longer than the api.rateLimitWait for the rate limits.
+	BackpressureRateLimited = "rate-limited"
+	// BackpressureQueueFull is the reason of the rejection of a request while
+	// api.rateLimitQueue requests are waiting for the rate limits.
+	BackpressureQueueFull = "queue-full"
+)
+
+// APIBackpressureError is the body of the responses to the requests rejected
+// by the api rate limits, with the status 429 Too Many Requests. It decodes as
+// the error of a command for command clients, and tells other clients why the
+// request was rejected and how long to wait before retrying.
+type APIBackpressureError struct {
+	Message           string
+	Code              cmdkit.ErrorType
+	Type              string
+	Reason            string
+	RetryAfterSeconds float64
+}
+
+func (e *APIBackpressureError) Error() string {
+	return e.Message
+}
+
+// apiLimiter limits the rate of api requests across all clients and of each
+// client. Requests over the limits wait in a bounded queue until the limits
+// allow them.
+type apiLimiter struct {
+	clock   clock.Clock
+	rate    float64
+	client  float64
+	tokens  map[string]float64
+	queue   uint
+	maxWait time.Duration
+
+	lk      sync.Mutex
+	global  *requestBucket
+	clients map[string]*requestBucket
+	waiting uint
+}
+
+// requestBucket is a token bucket holding up to one second worth of requests.
+type requestBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// newAPILimiter creates the limiter of the api configured by `cfg`, or returns
+// nil when the api is not limited.
+func newAPILimiter(cfg *config.APIConfig, clk clock.Clock) (*apiLimiter, error) {
+	if cfg.RateLimit == 0 && cfg.ClientRateLimit == 0 && len(cfg.TokenRateLimits) == 0 {
+		return nil, nil
+	}
+	maxWait := time.Duration(0)
+	if cfg.RateLimitWait != "" {
+		d, err := time.ParseDuration(cfg.RateLimitWait)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid api.rateLimitWait %s", cfg.RateLimitWait)
+		}
+		maxWait = d
+	}
+	return &apiLimiter{
+		clock:   clk,
+		rate:    cfg.RateLimit,
+		client:  cfg.ClientRateLimit,
+		tokens:  cfg.TokenRateLimits,
+		queue:   cfg.RateLimitQueue,
+		maxWait: maxWait,
+		global:  newRequestBucket(cfg.RateLimit, clk.Now()),
+		clients: make(map[string]*requestBucket),
+	}, nil
+}
+
+// Wrap returns a handler serving the requests through `next` as the limits
+// allow, and rejecting those that cannot wait.
+func (l *apiLimiter) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delay, err := l.admit(r)
+		if err != nil {
+			writeBackpressure(w, err)
+			return
+		}
+		if delay > 0 {
+			select {
+			case <-l.clock.After(delay):
+			case <-r.Context().Done():
+			}
+			l.lk.Lock()
+			l.waiting--
+			l.lk.Unlock()
+			if r.Context().Err() != nil {
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// admit takes a request from the buckets of the client of `r` and returns how
+// long it must wait, counting it in the queue until then.
+func (l *apiLimiter) admit(r *http.Request) (time.Duration, *APIBackpressureError) {
+	// Only configured tokens get a bucket of their own, so that clients cannot
+	// escape their limit by sending a different unknown token with each request.
+	token, host := apiClient(r)
+	client, rate := "ip:"+host, l.client
+	if tokenRate, ok := l.tokens[token]; ok && token != "" {
+		client, rate = "token:"+token, tokenRate
+	}
+
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	now := l.clock.Now()
+	clientBucket, ok := l.clients[client]
+	if !ok {
+		if len(l.clients) >= maxClientBuckets {
+			l.prune(now)
+		}
+		clientBucket = newRequestBucket(rate, now)
+		l.clients[client] = clientBucket
+	}
+
+	delay := l.global.take(now)
+	if d := clientBucket.take(now); d > delay {
+		delay = d
+	}
+	if delay == 0 {
+		return 0, nil
+	}
+
+	var reason string
+	switch {
+	case delay > l.maxWait:
+		reason = BackpressureRateLimited
+	case l.waiting >= l.queue:
+		reason = BackpressureQueueFull
+	default:
+		l.waiting++
+		return delay, nil
+	}
+	l.global.refund()
+	clientBucket.refund()
+	return 0, &APIBackpressureError{
+		Message:           fmt.Sprintf("api request rejected (%s), retry after %s", reason, delay.Round(time.Millisecond)),
+		Code:              cmdkit.ErrClient,
+		Type:              "error",
+		Reason:            reason,
+		RetryAfterSeconds: delay.Seconds(),
+	}
+}
+
+// prune discards the buckets of the clients that have not sent requests for
+// long enough for their buckets to refill.
+func (l *apiLimiter) prune(now time.Time) {
+	for client, b := range l.clients {
+		if b.rate == 0 || now.Sub(b.last) >= time.Second {
+			delete(l.clients, client)
+		}
+	}
+}
+
+func newRequestBucket(rate float64, now time.Time) *requestBucket {
+	return &requestBucket{rate: rate, tokens: math.Max(rate, 1), last: now}
+}
+
+// take takes a request from the bucket, returning how long the request must
+// wait for it to refill. Buckets with a zero rate are unlimited.
+func (b *requestBucket) take(now time.Time) time.Duration {
+	if b.rate == 0 {
+		return 0
+	}
+	b.tokens = math.Min(b.tokens+now.Sub(b.last).Seconds()*b.rate, math.Max(b.rate, 1))
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func (b *requestBucket) refund() {
+	if b.rate != 0 {
+		b.tokens++
+	}
+}
+
+// apiClient returns the token sent with `r`, as the api-token option or as a
+// bearer token, and the host it was sent from.
+func apiClient(r *http.Request) (token string, host string) {
+	token = r.URL.Query().Get(OptionAPIToken)
+	if auth := r.Header.Get("Authorization"); token == "" && strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return token, host
+}
+
+func writeBackpressure(w http.ResponseWriter, e *APIBackpressureError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(e.RetryAfterSeconds))))
+	w.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(e)
+}
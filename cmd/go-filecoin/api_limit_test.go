@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestAPILimiterAdmit(t *testing.T) {
+	tf.UnitTest(t)
+
+	newLimiter := func(queue uint) *apiLimiter {
+		l, err := newAPILimiter(&config.APIConfig{
+			ClientRateLimit: 1,
+			TokenRateLimits: map[string]float64{"integration": 10},
+			RateLimitQueue:  queue,
+			RateLimitWait:   "2s",
+		}, clock.NewFake(time.Unix(1234567890, 0)))
+		require.NoError(t, err)
+		return l
+	}
+	fromAddr := func(remoteAddr string) *http.Request {
+		r := httptest.NewRequest("POST", "/api/chain/head", nil)
+		r.RemoteAddr = remoteAddr
+		return r
+	}
+	withToken := func(remoteAddr, token string) *http.Request {
+		r := fromAddr(remoteAddr)
+		r.Header.Set("Authorization", "Bearer "+token)
+		return r
+	}
+
+	t.Log("clients are limited by their address")
+	l := newLimiter(1)
+	delay, rejected := l.admit(fromAddr("10.0.0.1:1000"))
+	assert.Nil(t, rejected)
+	assert.Equal(t, time.Duration(0), delay)
+	delay, rejected = l.admit(fromAddr("10.0.0.1:1001"))
+	assert.Nil(t, rejected)
+	assert.Equal(t, time.Second, delay)
+
+	t.Log("requests past the queue are rejected")
+	_, rejected = l.admit(fromAddr("10.0.0.1:1000"))
+	require.NotNil(t, rejected)
+	assert.Equal(t, BackpressureQueueFull, rejected.Reason)
+	assert.Equal(t, 2.0, rejected.RetryAfterSeconds)
+
+	t.Log("and those that would wait too long")
+	l = newLimiter(10)
+	for _, expected := range []time.Duration{0, time.Second, 2 * time.Second} {
+		delay, rejected = l.admit(fromAddr("10.0.0.1:1000"))
+		assert.Nil(t, rejected)
+		assert.Equal(t, expected, delay)
+	}
+	_, rejected = l.admit(fromAddr("10.0.0.1:1000"))
+	require.NotNil(t, rejected)
+	assert.Equal(t, BackpressureRateLimited, rejected.Reason)
+
+	t.Log("other clients are not held back")
+	delay, rejected = l.admit(fromAddr("10.0.0.2:1000"))
+	assert.Nil(t, rejected)
+	assert.Equal(t, time.Duration(0), delay)
+
+	t.Log("clients sending a configured token get its limit")
+	l = newLimiter(1)
+	for i := 0; i < 10; i++ {
+		delay, rejected = l.admit(withToken("10.0.0.1:1000", "integration"))
+		assert.Nil(t, rejected)
+		assert.Equal(t, time.Duration(0), delay)
+	}
+	delay, rejected = l.admit(httptest.NewRequest("POST", "/api/chain/head?api-token=integration", nil))
+	assert.Nil(t, rejected)
+	assert.Equal(t, 100*time.Millisecond, delay)
+
+	t.Log("clients sending unknown tokens are limited by their address")
+	l = newLimiter(1)
+	delay, rejected = l.admit(withToken("10.0.0.3:1000", "unknown-1"))
+	assert.Nil(t, rejected)
+	assert.Equal(t, time.Duration(0), delay)
+	delay, rejected = l.admit(withToken("10.0.0.3:1001", "unknown-2"))
+	assert.Nil(t, rejected)
+	assert.Equal(t, time.Second, delay)
+}
+
+func TestAPILimiterWrap(t *testing.T) {
+	tf.UnitTest(t)
+
+	clk := clock.NewFake(time.Unix(1234567890, 0))
+	l, err := newAPILimiter(&config.APIConfig{RateLimit: 1, RateLimitQueue: 1, RateLimitWait: "5s"}, clk)
+	require.NoError(t, err)
+	served := 0
+	handler := l.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+	}))
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "/api/id", nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve().Code)
+
+	t.Log("requests over the limit wait in the queue")
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- serve()
+	}()
+	clk.BlockUntil(1)
+
+	t.Log("and are rejected once it is full")
+	w := serve()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	var body APIBackpressureError
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, BackpressureQueueFull, body.Reason)
+	assert.Equal(t, "error", body.Type)
+
+	clk.Advance(time.Second)
+	assert.Equal(t, http.StatusOK, (<-done).Code)
+	assert.Equal(t, 2, served)
+
+	t.Log("the api is not limited by default")
+	l, err = newAPILimiter(config.NewDefaultConfig().API, clk)
+	require.NoError(t, err)
+	assert.Nil(t, l)
+}
@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"github.com/ipfs/go-bitswap"
+	"github.com/ipfs/go-ipfs-cmdkit"
+	"github.com/ipfs/go-ipfs-cmds"
+)
+
+var bitswapCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Inspect bitswap data exchange",
+		ShortDescription: `
+Bitswap is used to exchange client data between nodes. What blocks are
+announced and how fast they are served to each peer is set in the bitswap
+section of the config.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"stat": bitswapStatCmd,
+	},
+}
+
+var bitswapStatCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show bitswap exchange statistics",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		stat, err := GetPorcelainAPI(env).BitswapStat()
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(stat)
+	},
+	Type: bitswap.Stat{},
+}
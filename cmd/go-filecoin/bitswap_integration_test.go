@@ -0,0 +1,28 @@
+package commands_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-bitswap"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/node/test"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestBitswapStat(t *testing.T) {
+	tf.IntegrationTest(t)
+	ctx := context.Background()
+	builder := test.NewNodeBuilder(t)
+
+	_, cmdClient, done := builder.BuildAndStartAPI(ctx)
+	defer done()
+
+	var stat bitswap.Stat
+	cmdClient.RunMarshaledJSON(ctx, &stat, "bitswap", "stat")
+
+	assert.Equal(t, uint64(0), stat.BlocksSent)
+	assert.Equal(t, uint64(0), stat.BlocksReceived)
+	assert.Empty(t, stat.Wantlist)
+}
@@ -3,9 +3,14 @@ package commands
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"sync"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/conformance"
 	"github.com/ipfs/go-cid"
 	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
 	cmds "github.com/ipfs/go-ipfs-cmds"
@@ -18,26 +23,84 @@ var chainCmd = &cmds.Command{
 		Tagline: "Inspect the filecoin blockchain",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"export":   storeExportCmd,
-		"head":     storeHeadCmd,
-		"import":   storeImportCmd,
-		"ls":       storeLsCmd,
-		"status":   storeStatusCmd,
-		"set-head": storeSetHeadCmd,
-		"sync":     storeSyncCmd,
+		"export":        storeExportCmd,
+		"export-vector": storeExportVectorCmd,
+		"head":          storeHeadCmd,
+		"import":        storeImportCmd,
+		"ls":            storeLsCmd,
+		"status":        storeStatusCmd,
+		"set-head":      storeSetHeadCmd,
+		"sync":          storeSyncCmd,
 	},
 }
 
 var storeHeadCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Get heaviest tipset CIDs",
+		ShortDescription: `
+With --watch, the CIDs of the head are printed again each time the head
+changes, until interrupted.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("watch", "Print each new head as the chain changes"),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		head, err := GetPorcelainAPI(env).ChainHead()
+		api := GetPorcelainAPI(env)
+		watch, _ := req.Options["watch"].(bool)
+		if !watch {
+			head, err := api.ChainHead()
+			if err != nil {
+				return err
+			}
+			return re.Emit(head.Key())
+		}
+
+		// Queue the heads so that the watcher never blocks the chain.
+		var lk sync.Mutex
+		var heads []block.TipSet
+		changed := make(chan struct{}, 1)
+		unwatch := api.ChainWatchHead(func(ts block.TipSet) {
+			lk.Lock()
+			heads = append(heads, ts)
+			lk.Unlock()
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		})
+		defer unwatch()
+
+		head, err := api.ChainHead()
 		if err != nil {
 			return err
 		}
-		return re.Emit(head.Key())
+		if err := re.Emit(head.Key()); err != nil {
+			return err
+		}
+		last := head.Key()
+		for {
+			select {
+			case <-req.Context.Done():
+				return nil
+			case <-changed:
+			}
+
+			lk.Lock()
+			updates := heads
+			heads = nil
+			lk.Unlock()
+
+			for _, ts := range updates {
+				if ts.Key().Equals(last) {
+					continue
+				}
+				last = ts.Key()
+				if err := re.Emit(last); err != nil {
+					return err
+				}
+			}
+		}
 	},
 	Type: []cid.Cid{},
 }
@@ -160,6 +223,75 @@ var storeExportCmd = &cmds.Command{
 	},
 }
 
+// ExportVectorResult summarizes a test vector exported by `chain export-vector`.
+type ExportVectorResult struct {
+	Epoch         abi.ChainEpoch
+	PreStateRoot  cid.Cid
+	PostStateRoot cid.Cid
+	Messages      int
+	StateBlocks   int
+	// Reproduced is false when re-applying the messages did not give the state
+	// root and receipts of the chain.
+	Reproduced bool
+}
+
+var storeExportVectorCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Export the messages of a tipset as a test vector.",
+		ShortDescription: `
+Re-applies the messages of the tipset to its parent state and writes them,
+the randomness and state they read and the state root and receipts recorded
+in the chain to a CBOR file. The vector can be run without a chain with
+'chain-util run-vectors'.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("file", true, false, "File to write the vector to."),
+		cmdkit.StringArg("cids", true, true, "CID's of the blocks of the tipset to export."),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		expCids, err := cidsFromSlice(req.Arguments[1:])
+		if err != nil {
+			return err
+		}
+		v, reproduced, err := GetPorcelainAPI(env).ChainExportVector(req.Context, block.NewTipSetKey(expCids...))
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Create(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		if err := conformance.WriteVector(f, v); err != nil {
+			return err
+		}
+
+		return re.Emit(&ExportVectorResult{
+			Epoch:         v.Epoch,
+			PreStateRoot:  v.PreStateRoot.Cid,
+			PostStateRoot: v.PostStateRoot.Cid,
+			Messages:      v.Messages(),
+			StateBlocks:   len(v.State),
+			Reproduced:    reproduced,
+		})
+	},
+	Type: ExportVectorResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *ExportVectorResult) error {
+			_, err := fmt.Fprintf(w, "epoch %d: %d messages, %d state blocks\n%s -> %s\n", res.Epoch, res.Messages, res.StateBlocks, res.PreStateRoot, res.PostStateRoot)
+			if err != nil {
+				return err
+			}
+			if !res.Reproduced {
+				_, err = fmt.Fprintln(w, "warning: re-applying the messages did not reproduce the chain")
+			}
+			return err
+		}),
+	},
+}
+
 var storeImportCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Import the chain from a car file.",
@@ -2,12 +2,27 @@ package commands
 
 import (
 	"fmt"
+	"io"
+	"sort"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/errcode"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/asksub"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/bidsub"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/piecemanager"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/escrow"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/pin"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/replication"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/transfer"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
 	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
 	"github.com/pkg/errors"
 
 	"github.com/ipfs/go-cid"
@@ -28,6 +43,20 @@ var clientCmd = &cmds.Command{
 		"query-storage-deal":   ClientQueryStorageDealCmd,
 		"verify-storage-deal":  clientVerifyStorageDealCmd,
 		"list-asks":            clientListAsksCmd,
+		"cached-asks":          clientCachedAsksCmd,
+		"deal-key":             clientDealKeyCmd,
+		"set-deal-key":         clientSetDealKeyCmd,
+		"add-funds":            clientAddFundsCmd,
+		"replicate":            clientReplicateCmd,
+		"replication-status":   clientReplicationStatusCmd,
+		"stop-replication":     clientStopReplicationCmd,
+		"transfers":            clientTransfersCmd,
+		"pin":                  clientPinCmd,
+		"unpin":                clientUnpinCmd,
+		"ls-pins":              clientLsPinsCmd,
+		"gc":                   clientGCCmd,
+		"request-storage":      clientRequestStorageCmd,
+		"storage-bids":         clientStorageBidsCmd,
 	},
 }
 
@@ -65,11 +94,17 @@ var clientImportDataCmd = &cmds.Command{
 Imports data previously exported with the client cat command into the storage
 market. This command takes only one argument, the path of the file to import.
 See the go-filecoin client cat command for more details.
+
+Imported data is pinned, so that client gc never removes it, unless --pin=false
+is given. Unpinned data is removed by client gc once its deals are done.
 `,
 	},
 	Arguments: []cmdkit.Argument{
 		cmdkit.FileArg("file", true, false, "Path to file to import").EnableStdin(),
 	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("pin", "Pin the imported data").WithDefault(true),
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		iter := req.Files.Entries()
 		if !iter.Next() {
@@ -81,16 +116,54 @@ See the go-filecoin client cat command for more details.
 			return fmt.Errorf("given file was not a files.File")
 		}
 
-		out, err := GetPorcelainAPI(env).DAGImportData(req.Context, fi)
+		pinned, _ := req.Options["pin"].(bool)
+		root, err := GetStorageAPI(env).AddClientData(pinned, func() (cid.Cid, error) {
+			out, err := GetPorcelainAPI(env).ClientImportData(req.Context, fi)
+			if err != nil {
+				return cid.Undef, err
+			}
+			return out.Cid(), nil
+		})
 		if err != nil {
 			return err
 		}
 
-		return re.Emit(out.Cid())
+		return re.Emit(root)
 	},
 	Type: cid.Cid{},
 }
 
+// ClientProposeStorageDealResult is the result of proposing a storage deal.
+type ClientProposeStorageDealResult struct {
+	storagemarket.ProposeStorageDealResult
+	// DryRun is the deal that would have been proposed, set with --dry-run.
+	DryRun *ClientDealDryRun `json:",omitempty"`
+}
+
+// ClientDealDryRun is a storage deal that would be proposed, with the funds
+// it needs.
+type ClientDealDryRun struct {
+	DealAddress    address.Address
+	FundingAddress address.Address
+	Miner          address.Address
+	PeerID         p2pcore.ID
+	Data           cid.Cid
+	Start          abi.ChainEpoch
+	End            abi.ChainEpoch
+	PricePerEpoch  types.AttoFIL
+	TotalPrice     types.AttoFIL
+	Escrow         escrow.Balance
+	// Missing is the part of TotalPrice the escrow would be topped up with.
+	Missing types.AttoFIL
+	// AddFunds is the message adding the missing funds, set when funds are
+	// missing.
+	AddFunds *DryRunResult `json:",omitempty"`
+}
+
+// minerPingTimeout is how long a miner that could not be reached with a deal
+// proposal is pinged for before it is reported offline.
+const minerPingTimeout = 10 * time.Second
+
 var ClientProposeStorageDealCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline:          "Propose a storage deal with a storage miner",
@@ -102,6 +175,16 @@ Start and end should be specified with the number of blocks for which to store t
 data. New blocks are generated about every 30 seconds, so the time given should
 be represented as a count of 30 second intervals. For example, 1 minute would
 be 2, 1 hour would be 120, and 1 day would be 2880.
+
+The deal is signed by the deal key set with set-deal-key, or the default
+address if none is set. Market funds missing from the escrow of the deal key
+are added from the default address, and reserved for the deal until it is
+published.
+
+With --dry-run the deal is not proposed: the deal parameters are checked against
+the miner's state and printed with the escrow balance of the deal key, and the
+message adding the missing funds, if any, is applied to the current state
+without being sent. The piece commitment is only computed when proposing.
 `,
 	},
 	Arguments: []cmdkit.Argument{
@@ -114,14 +197,15 @@ be 2, 1 hour would be 120, and 1 day would be 2880.
 	},
 	Options: []cmdkit.Option{
 		cmdkit.StringOption("peerid", "Override miner's peer id stored on chain"),
+		dryRunOption,
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		addr, err := GetPorcelainAPI(env).WalletDefaultAddress()
+		addr, err := GetPorcelainAPI(env).WalletDealAddress()
 		if err != nil {
 			return err
 		}
 
-		maddr, err := address.NewFromString(req.Arguments[0])
+		maddr, err := addressFromString(env, req.Arguments[0])
 		if err != nil {
 			return err
 		}
@@ -180,6 +264,17 @@ be 2, 1 hour would be 120, and 1 day would be 2880.
 			return errors.Errorf("could not parse collateral %s", req.Arguments[6])
 		}
 
+		if dryRun, _ := req.Options["dry-run"].(bool); dryRun {
+			if end <= start {
+				return errors.Errorf("deal end %d is not after its start %d", end, start)
+			}
+			d, err := dryRunStorageDeal(req, env, addr, providerInfo, dataCID, abi.ChainEpoch(start), abi.ChainEpoch(end), price)
+			if err != nil {
+				return err
+			}
+			return re.Emit(&ClientProposeStorageDealResult{DryRun: d})
+		}
+
 		resp, err := GetStorageAPI(env).ProposeStorageDeal(
 			req.Context,
 			addr,
@@ -192,12 +287,74 @@ be 2, 1 hour would be 120, and 1 day would be 2880.
 			status.SealProofType,
 		)
 		if err != nil {
+			// only failures to talk to the miner may be due to it being offline
+			if isStreamError(err) {
+				if pingErr := GetPorcelainAPI(env).PingMinerWithTimeout(req.Context, peerID, minerPingTimeout); pingErr != nil {
+					return errcode.Wrap(errcode.MinerOffline, err)
+				}
+			}
 			return err
 		}
 
-		return re.Emit(resp)
+		return re.Emit(&ClientProposeStorageDealResult{ProposeStorageDealResult: *resp})
 	},
-	Type: storagemarket.ProposeStorageDealResult{},
+	Type: ClientProposeStorageDealResult{},
+}
+
+// dryRunStorageDeal computes the funds a deal needs from the escrow of the deal
+// key and applies the message adding the missing funds to the current state.
+func dryRunStorageDeal(req *cmds.Request, env cmds.Environment, dealAddr address.Address, info *storagemarket.StorageProviderInfo,
+	data cid.Cid, start, end abi.ChainEpoch, price types.AttoFIL) (*ClientDealDryRun, error) {
+	fundingAddr, err := GetPorcelainAPI(env).WalletDefaultAddress()
+	if err != nil {
+		return nil, err
+	}
+	balance, err := GetStorageAPI(env).EscrowBalance(req.Context, dealAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	// the client collateral of deals is zero, the client pays the storage fee only
+	total := big.Mul(price, big.NewInt(int64(end-start)))
+	missing := types.ZeroAttoFIL
+	if needed := big.Add(balance.Reserved, total); balance.Available.LessThan(needed) {
+		missing = big.Sub(needed, balance.Available)
+	}
+
+	d := &ClientDealDryRun{
+		DealAddress:    dealAddr,
+		FundingAddress: fundingAddr,
+		Miner:          info.Address,
+		PeerID:         info.PeerID,
+		Data:           data,
+		Start:          start,
+		End:            end,
+		PricePerEpoch:  price,
+		TotalPrice:     total,
+		Escrow:         balance,
+		Missing:        missing,
+	}
+	if missing.IsZero() {
+		return d, nil
+	}
+
+	// the same message the storage client sends to add funds
+	addFunds, err := GetPorcelainAPI(env).MessageDryRun(req.Context, fundingAddr, builtin.StorageMarketActorAddr, missing,
+		types.NewGasPrice(1), gas.NewGas(5000), builtin.MethodsMarket.AddBalance, &dealAddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build the message adding funds")
+	}
+	d.AddFunds = newDryRunResult(addFunds)
+	return d, nil
+}
+
+// QueryStorageDealResult is a client deal and, while its piece is sealed,
+// the miner's sealing progress of the sectors holding it.
+type QueryStorageDealResult struct {
+	storagemarket.ClientDeal
+	Sealing []piecemanager.SectorProgress
+	// SealingError tells why the sealing progress could not be queried.
+	SealingError string
 }
 
 var ClientQueryStorageDealCmd = &cmds.Command{
@@ -207,6 +364,9 @@ var ClientQueryStorageDealCmd = &cmds.Command{
 Checks the status of the storage deal proposal specified by the id. The deal
 status and deal message will be returned as a formatted string unless another
 format is specified with the --enc flag.
+
+While the piece of the deal is sealed, the miner is asked for the phase and
+progress of the sectors holding it, with an estimate of when they complete.
 `,
 	},
 	Arguments: []cmdkit.Argument{
@@ -218,14 +378,60 @@ format is specified with the --enc flag.
 			return errors.Wrap(err, "could not decode deal cid")
 		}
 
-		deal, err := GetStorageAPI(env).GetStorageDeal(req.Context, dealCID)
+		api := GetStorageAPI(env)
+		deal, err := api.GetStorageDeal(req.Context, dealCID)
 		if err != nil {
 			return err
 		}
 
-		return re.Emit(deal)
+		result := &QueryStorageDealResult{ClientDeal: deal}
+		if deal.State == storagemarket.StorageDealStaged || deal.State == storagemarket.StorageDealSealing {
+			result.Sealing, err = api.GetStorageDealSealing(req.Context, deal)
+			if err != nil {
+				result.SealingError = errcode.Format(err)
+			}
+		}
+		return re.Emit(result)
+	},
+	Type: QueryStorageDealResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *QueryStorageDealResult) error {
+			_, err := fmt.Fprintf(w, "Proposal:  %s\nMiner:     %s\nState:     %s\nDeal ID:   %d\n",
+				res.ProposalCid, res.Proposal.Provider, storagemarket.DealStates[res.State], res.DealID)
+			if err != nil {
+				return err
+			}
+			if res.Message != "" {
+				if _, err := fmt.Fprintf(w, "Message:   %s\n", res.Message); err != nil {
+					return err
+				}
+			}
+			if res.SealingError != "" {
+				_, err := fmt.Fprintf(w, "Sealing:   unknown: %s\n", res.SealingError)
+				return err
+			}
+			for _, p := range res.Sealing {
+				if _, err := fmt.Fprintf(w, "Sector %d:  %s\n", p.Sector, formatSealingProgress(p)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
 	},
-	Type: storagemarket.ClientDeal{},
+}
+
+func formatSealingProgress(p piecemanager.SectorProgress) string {
+	if p.Failed {
+		return fmt.Sprintf("%s, failed: %s", p.Phase, p.Message)
+	}
+	out := fmt.Sprintf("%s, %d%%", p.Phase, p.Percent)
+	if p.PhaseStarted > 0 {
+		out += fmt.Sprintf(", in phase since %s", time.Unix(int64(p.PhaseStarted), 0).Format(time.RFC3339))
+	}
+	if p.EstimatedCompletion > 0 {
+		out += fmt.Sprintf(", complete around %s", time.Unix(int64(p.EstimatedCompletion), 0).Format(time.RFC3339))
+	}
+	return out
 }
 
 // VerifyStorageDealResult wraps the success in an interface type
@@ -285,3 +491,631 @@ Lists all asks in the storage market. This command takes no arguments.
 	},
 	Type: []*storagemarket.SignedStorageAsk{},
 }
+
+// ClientDealKeyResult is the result of the client deal-key command.
+type ClientDealKeyResult struct {
+	// DealAddress proposes and signs deals and holds their escrow.
+	DealAddress address.Address
+	// FundingAddress pays the escrow of the deal address.
+	FundingAddress address.Address
+	Escrow         escrow.Balance
+}
+
+var clientDealKeyCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the key signing storage deals and its market escrow",
+		ShortDescription: `
+Shows the address proposing and signing storage deals, the address funding its
+market escrow and the escrow balance. The part of the available escrow
+reserved by proposed deals not yet published is shown as reserved.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		dealAddr, err := GetPorcelainAPI(env).WalletDealAddress()
+		if err != nil {
+			return err
+		}
+		fundingAddr, err := GetPorcelainAPI(env).WalletDefaultAddress()
+		if err != nil {
+			return err
+		}
+		balance, err := GetStorageAPI(env).EscrowBalance(req.Context, dealAddr)
+		if err != nil {
+			return err
+		}
+		return re.Emit(&ClientDealKeyResult{
+			DealAddress:    dealAddr,
+			FundingAddress: fundingAddr,
+			Escrow:         balance,
+		})
+	},
+	Type: &ClientDealKeyResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, r *ClientDealKeyResult) error {
+			_, err := fmt.Fprintf(w, "deal key:\t%s\nfunded by:\t%s\navailable:\t%s\nreserved:\t%s\nlocked:\t\t%s\n",
+				r.DealAddress, r.FundingAddress,
+				types.NewAttoFIL(r.Escrow.Available.Int), types.NewAttoFIL(r.Escrow.Reserved.Int), types.NewAttoFIL(r.Escrow.Locked.Int))
+			return err
+		}),
+	},
+}
+
+var clientSetDealKeyCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Set the key signing storage deals",
+		ShortDescription: `
+Sets wallet.dealAddress in config, the wallet address that proposes and signs
+storage deals. The market escrow of the deal key is funded from the default
+address, so the deal key needs no funds, and services making deals through the
+api never use the default address key to sign.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("address", true, false, "Wallet address or label of the deal key"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		addr, err := addressFromString(env, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		inWallet := false
+		for _, a := range GetPorcelainAPI(env).WalletAddresses() {
+			if a == addr {
+				inWallet = true
+				break
+			}
+		}
+		if !inWallet {
+			return errors.Errorf("address %s is not in the wallet", addr)
+		}
+
+		if err := GetPorcelainAPI(env).ConfigSet("wallet.dealAddress", addr.String()); err != nil {
+			return err
+		}
+		return re.Emit(&AddressResult{addr})
+	},
+	Type: &AddressResult{},
+}
+
+var clientAddFundsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Add funds to the market escrow of the deal key",
+		ShortDescription: `
+Sends funds from the default address to the storage market escrow of the deal
+key, and waits for the message to be mined. Deals add missing funds
+themselves, adding funds in advance saves a message per deal.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("amount", true, false, "Amount to add in FIL (e.g. 0.01)"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		amount, ok := types.NewAttoFILFromFILString(req.Arguments[0])
+		if !ok {
+			return errors.Errorf("could not parse amount %s", req.Arguments[0])
+		}
+
+		dealAddr, err := GetPorcelainAPI(env).WalletDealAddress()
+		if err != nil {
+			return err
+		}
+		if err := GetStorageAPI(env).AddEscrow(req.Context, dealAddr, amount); err != nil {
+			return err
+		}
+
+		balance, err := GetStorageAPI(env).EscrowBalance(req.Context, dealAddr)
+		if err != nil {
+			return err
+		}
+		return re.Emit(&balance)
+	},
+	Type: &escrow.Balance{},
+}
+
+// ClientCachedAsk is an ask announced by a storage miner, with the size of
+// the sectors of the miner.
+type ClientCachedAsk struct {
+	asksub.CachedAsk
+	// SectorSize is zero when the miner is not found at the chain head.
+	SectorSize abi.SectorSize
+}
+
+var clientCachedAsksCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the asks announced by storage miners",
+		ShortDescription: `
+Lists the latest ask of each storage miner announced on the ask pubsub topic
+since the node started, cheapest first, with the peer the miner takes deals on,
+the capacity it announced and the size of its sectors. Expired asks are not
+listed. Miners of a network may commit sectors of different sizes, with
+--piece-size only the miners whose sectors fit a piece of that padded size are
+listed. This command takes no arguments.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.Uint64Option("piece-size", "Only list miners whose sectors fit a piece of this padded size in bytes"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		api := GetPorcelainAPI(env)
+		head, err := api.ChainHead()
+		if err != nil {
+			return err
+		}
+		height, err := head.Height()
+		if err != nil {
+			return err
+		}
+		pieceSize, _ := req.Options["piece-size"].(uint64)
+
+		for _, ask := range GetStorageAPI(env).CachedAsks(height) {
+			out := ClientCachedAsk{CachedAsk: ask}
+			if status, err := api.MinerGetStatus(req.Context, ask.Ask.Miner, head.Key()); err == nil {
+				out.SectorSize = status.SectorSize
+			}
+			if pieceSize != 0 && pieceSize > uint64(out.SectorSize) {
+				continue
+			}
+			if err := re.Emit(&out); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: ClientCachedAsk{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, a *ClientCachedAsk) error {
+			_, err := fmt.Fprintf(w, "%s\tprice %s\texpiry %d\tseq %d\tpeer %s\tcapacity %d\tsector size %d\n",
+				a.Ask.Miner, types.NewAttoFIL(a.Ask.Price.Int), a.Ask.Expiry, a.Ask.SeqNo, a.Peer, a.Capacity, a.SectorSize)
+			return err
+		}),
+	},
+}
+
+var clientTransfersCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the data transfers of deal payloads",
+		ShortDescription: `
+Lists the graphsync transfers of deal payloads between this node and storage
+miners or clients, with the bytes sent by this node and the number of times a
+failed transfer was started again. With --watch, the state of a transfer is
+printed again each time it changes, until the command is interrupted.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("watch", "print transfers as they progress"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		api := GetStorageAPI(env)
+
+		watch, _ := req.Options["watch"].(bool)
+		if !watch {
+			for _, t := range api.ListTransfers() {
+				if err := re.Emit(t); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		// Coalesce updates so that the watcher never blocks the transfers,
+		// only the latest state of each transfer is printed.
+		var lk sync.Mutex
+		pending := map[uint64]transfer.Transfer{}
+		changed := make(chan struct{}, 1)
+		unsubscribe := api.WatchTransfers(func(t transfer.Transfer) {
+			lk.Lock()
+			pending[t.ID] = t
+			lk.Unlock()
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		})
+		defer unsubscribe()
+
+		for _, t := range api.ListTransfers() {
+			if err := re.Emit(t); err != nil {
+				return err
+			}
+		}
+		for {
+			select {
+			case <-req.Context.Done():
+				return nil
+			case <-changed:
+			}
+
+			lk.Lock()
+			updates := make([]transfer.Transfer, 0, len(pending))
+			for _, t := range pending {
+				updates = append(updates, t)
+			}
+			pending = map[uint64]transfer.Transfer{}
+			lk.Unlock()
+
+			sort.Slice(updates, func(i, j int) bool { return updates[i].ID < updates[j].ID })
+			for _, t := range updates {
+				if err := re.Emit(t); err != nil {
+					return err
+				}
+			}
+		}
+	},
+	Type: transfer.Transfer{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, t *transfer.Transfer) error {
+			deal := "-"
+			if t.Deal.Defined() {
+				deal = t.Deal.String()
+			}
+			_, err := fmt.Fprintf(w, "%d\t%s\t%s\tfrom %s\tto %s\tsent %d\trestarts %d\tdeal %s\n",
+				t.ID, t.Status, t.BaseCid, t.Sender, t.Recipient, t.BytesSent, t.Restarts, deal)
+			return err
+		}),
+	},
+}
+
+var clientReplicateCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Keep data stored by several storage miners",
+		ShortDescription: `
+Proposes storage deals for the data to as many miners as the replication
+factor, cheapest ask first, skipping miners asking more than the price. The
+deals of the data are then checked every few epochs, and when deals fail or
+expire new deals are proposed to other miners, until the end of the duration.
+The state of the replicas is shown by replication-status.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("data", true, false, "CID of the data to be stored"),
+		cmdkit.StringArg("factor", true, false, "Number of miners to store the data with"),
+		cmdkit.StringArg("duration", true, false, "Number of epochs to store the data for"),
+		cmdkit.StringArg("price", true, false, "Storage price per epoch of each deal in FIL (e.g. 0.01)"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("collateral", "Collateral of each deal in FIL").WithDefault("0"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		dataCID, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return errors.Wrap(err, "could not decode data cid")
+		}
+
+		factor, err := strconv.ParseUint(req.Arguments[1], 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "could not parse replication factor")
+		}
+
+		duration, err := strconv.ParseUint(req.Arguments[2], 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "could not parse duration")
+		}
+
+		price, valid := types.NewAttoFILFromFILString(req.Arguments[3])
+		if !valid {
+			return errors.Errorf("could not parse price %s", req.Arguments[3])
+		}
+
+		collateralStr, _ := req.Options["collateral"].(string)
+		collateral, valid := types.NewAttoFILFromFILString(collateralStr)
+		if !valid {
+			return errors.Errorf("could not parse collateral %s", collateralStr)
+		}
+
+		piece, err := GetStorageAPI(env).Replicate(req.Context, dataCID, replication.Policy{
+			Factor:     factor,
+			Duration:   abi.ChainEpoch(duration),
+			Price:      price,
+			Collateral: collateral,
+		})
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(&piece)
+	},
+	Type: &replication.Piece{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, p *replication.Piece) error {
+			return writeReplicationStatus(w, p)
+		}),
+	},
+}
+
+var clientReplicationStatusCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the replicas of replicated data",
+		ShortDescription: `
+Shows the deals holding data replicated by the replicate command, as of their
+last check, and the number of them in progress or active.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("data", true, false, "CID of the replicated data"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		dataCID, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return errors.Wrap(err, "could not decode data cid")
+		}
+
+		piece, err := GetStorageAPI(env).ReplicationStatus(dataCID)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(&piece)
+	},
+	Type: &replication.Piece{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, p *replication.Piece) error {
+			return writeReplicationStatus(w, p)
+		}),
+	},
+}
+
+var clientStopReplicationCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Stop replicating data",
+		ShortDescription: `
+Stops making new deals for replicated data. The deals already made are left
+as they are.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("data", true, false, "CID of the replicated data"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		dataCID, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return errors.Wrap(err, "could not decode data cid")
+		}
+
+		return GetStorageAPI(env).StopReplication(dataCID)
+	},
+}
+
+var clientPinCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Keep data from being garbage collected",
+		ShortDescription: `
+Pins data stored by this node, such as imported or retrieved data, so that
+client gc never removes it.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("data", true, false, "CID of the data to pin"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		root, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return errors.Wrap(err, "could not decode data cid")
+		}
+
+		return GetStorageAPI(env).PinClientData(root)
+	},
+}
+
+var clientUnpinCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Let data be garbage collected",
+		ShortDescription: `
+Unpins data, so that client gc removes it once no deal in progress or
+replication needs it.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("data", true, false, "CID of the data to unpin"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		root, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return errors.Wrap(err, "could not decode data cid")
+		}
+
+		return GetStorageAPI(env).UnpinClientData(root)
+	},
+}
+
+var clientLsPinsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List pinned data",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		for _, d := range GetStorageAPI(env).ListPins() {
+			if err := re.Emit(d.Root); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: cid.Cid{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, c cid.Cid) error {
+			_, err := fmt.Fprintln(w, c)
+			return err
+		}),
+	},
+}
+
+var clientGCCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Remove client data no longer needed",
+		ShortDescription: `
+Removes the data imported into this node that is not pinned, unless it is the
+payload of a deal in progress or replicated with the replicate command. The
+data of deals that are active or failed is removed. Blocks shared with data that
+is kept, and data that was never imported or pinned, such as the chain, are not
+removed. With --dry-run, the data that would be removed is shown instead.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("dry-run", "Show the data that would be removed without removing it"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		dryRun, _ := req.Options["dry-run"].(bool)
+		res, err := GetStorageAPI(env).CollectGarbage(req.Context, dryRun)
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(&res)
+	},
+	Type: &pin.GCResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *pin.GCResult) error {
+			verb := "removed"
+			if dryRun, _ := req.Options["dry-run"].(bool); dryRun {
+				verb = "would remove"
+			}
+			for _, root := range res.Collected {
+				if _, err := fmt.Fprintf(w, "%s %s\n", verb, root); err != nil {
+					return err
+				}
+			}
+			for _, k := range res.Kept {
+				if _, err := fmt.Fprintf(w, "kept %s: %s\n", k.Root, k.Reason); err != nil {
+					return err
+				}
+			}
+			_, err := fmt.Fprintf(w, "%d blocks, %d bytes\n", res.Blocks, res.Size)
+			return err
+		}),
+	},
+}
+
+var clientRequestStorageCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Ask miners to bid on storing data",
+		ShortDescription: `
+Publishes a request for miners to bid on storing a piece of the padded size in
+bytes for the duration in epochs, at up to the price per GiB per epoch. Miners
+with auto bidding enabled that can take the request bid on it with their ask
+price for --validity epochs. The bids are listed by storage-bids with the ID of
+the request, and a deal is proposed to the chosen miner with
+propose-storage-deal.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("size", true, false, "Padded piece size in bytes of the data to store"),
+		cmdkit.StringArg("duration", true, false, "Number of epochs to store the data for"),
+		cmdkit.StringArg("max-price", true, false, "Highest storage price per GiB per epoch in FIL (e.g. 0.01)"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.Uint64Option("validity", "Number of epochs miners bid on the request for").WithDefault(uint64(20)),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		size, err := strconv.ParseUint(req.Arguments[0], 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "could not parse size")
+		}
+
+		duration, err := strconv.ParseUint(req.Arguments[1], 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "could not parse duration")
+		}
+
+		maxPrice, valid := types.NewAttoFILFromFILString(req.Arguments[2])
+		if !valid {
+			return errors.Errorf("could not parse max price %s", req.Arguments[2])
+		}
+
+		validity, _ := req.Options["validity"].(uint64)
+		r, err := GetStorageAPI(env).RequestStorage(req.Context, abi.PaddedPieceSize(size), abi.ChainEpoch(duration), maxPrice, abi.ChainEpoch(validity))
+		if err != nil {
+			return err
+		}
+
+		return re.Emit(&r)
+	},
+	Type: &bidsub.SignedRequest{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, r *bidsub.SignedRequest) error {
+			_, err := fmt.Fprintf(w, "%s\tsize %d\tduration %d\tmax price %s\tuntil epoch %d\n",
+				r.ID, r.Request.Size, r.Request.Duration, types.NewAttoFIL(r.Request.MaxPrice.Int), r.Request.Expiry)
+			return err
+		}),
+	},
+}
+
+// StorageBidResult is a bid on a storage request, with the price of storing
+// the requested data at the bid price.
+type StorageBidResult struct {
+	bidsub.Bid
+	// TotalPrice is the price of the whole deal.
+	TotalPrice types.AttoFIL
+}
+
+var clientStorageBidsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the bids on a storage request",
+		ShortDescription: `
+Lists the unexpired bids of miners on a storage request published by
+request-storage, cheapest first. Prices are in attoFIL.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("request", true, false, "ID of the storage request"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		id, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return errors.Wrap(err, "could not decode request id")
+		}
+
+		api := GetStorageAPI(env)
+		r, err := api.StorageRequest(id)
+		if err != nil {
+			return err
+		}
+		bids, err := api.StorageRequestBids(id)
+		if err != nil {
+			return err
+		}
+		for _, b := range bids {
+			perEpoch := big.Div(big.Mul(b.Bid.Price, big.NewIntUnsigned(uint64(r.Request.Size))), big.NewInt(1<<30))
+			total := big.Mul(perEpoch, big.NewInt(int64(r.Request.Duration)))
+			if err := re.Emit(&StorageBidResult{Bid: b.Bid, TotalPrice: types.NewAttoFIL(total.Int)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: &StorageBidResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, b *StorageBidResult) error {
+			_, err := fmt.Fprintf(w, "%s\t%s\tprice %s\ttotal %s\tuntil epoch %d\n",
+				b.Miner, b.Peer, types.NewAttoFIL(b.Price.Int), b.TotalPrice, b.Expiry)
+			return err
+		}),
+	},
+}
+
+func writeReplicationStatus(w io.Writer, p *replication.Piece) error {
+	if _, err := fmt.Fprintf(w, "%s: %d of %d replicas, until epoch %d\n", p.Root, p.Healthy(), p.Policy.Factor, p.End); err != nil {
+		return err
+	}
+	if p.Message != "" {
+		if _, err := fmt.Fprintf(w, "%s\n", p.Message); err != nil {
+			return err
+		}
+	}
+	for _, r := range p.Replicas {
+		deal := "-"
+		if r.ProposalCid != nil {
+			deal = r.ProposalCid.String()
+		}
+		line := fmt.Sprintf("%s\t%s\tepochs %d-%d\tdeal %s", r.Miner, r.State, r.StartEpoch, r.EndEpoch, deal)
+		if r.DealState != "" {
+			line += "\t" + r.DealState
+		}
+		if r.Message != "" {
+			line += "\t" + r.Message
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/pkg/errors"
+)
+
+var completionCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Generate a shell completion script",
+		ShortDescription: `
+Prints a script completing go-filecoin commands and flags for bash, zsh or fish.
+Arguments taking an address are completed with the addresses of the wallet of
+the running daemon.
+
+To enable completion in the current shell:
+
+  bash: source <(go-filecoin completion bash)
+  zsh:  source <(go-filecoin completion zsh)
+  fish: go-filecoin completion fish | source
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("shell", true, false, "The shell to complete for: bash, zsh or fish"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		var script string
+		switch req.Arguments[0] {
+		case "bash":
+			script = bashCompletion(RootCmd)
+		case "zsh":
+			script = zshCompletion(RootCmd)
+		case "fish":
+			script = fishCompletion(RootCmd)
+		default:
+			return errors.Errorf("unsupported shell %s, must be one of bash, zsh or fish", req.Arguments[0])
+		}
+		return re.Emit(strings.NewReader(script))
+	},
+}
+
+// completionEntry holds what may follow a command path on the command line.
+type completionEntry struct {
+	path        string
+	subcommands []string
+	flags       []string
+	addressArgs bool
+}
+
+// completionEntries walks the command tree under root, in path order. Flags of
+// a command include those of its parents, as go-ipfs-cmds accepts them anywhere.
+func completionEntries(root *cmds.Command) []completionEntry {
+	var entries []completionEntry
+	var walk func(cmd *cmds.Command, path []string, inherited []string)
+	walk = func(cmd *cmds.Command, path []string, inherited []string) {
+		flags := append(append([]string{}, inherited...), optionFlags(cmd.Options)...)
+
+		var subs []string
+		for name := range cmd.Subcommands {
+			subs = append(subs, name)
+		}
+		sort.Strings(subs)
+
+		entries = append(entries, completionEntry{
+			path:        strings.Join(path, " "),
+			subcommands: subs,
+			flags:       flags,
+			addressArgs: takesAddress(cmd.Arguments),
+		})
+		for _, name := range subs {
+			walk(cmd.Subcommands[name], append(append([]string{}, path...), name), flags)
+		}
+	}
+	walk(root, nil, nil)
+	return entries
+}
+
+func optionFlags(opts []cmdkit.Option) []string {
+	var flags []string
+	for _, opt := range opts {
+		for _, name := range opt.Names() {
+			if len(name) == 1 {
+				flags = append(flags, "-"+name)
+			} else {
+				flags = append(flags, "--"+name)
+			}
+		}
+	}
+	return flags
+}
+
+func takesAddress(args []cmdkit.Argument) bool {
+	for _, arg := range args {
+		if arg.Name == "address" || arg.Name == "addresses" || strings.HasSuffix(arg.Name, "-address") {
+			return true
+		}
+	}
+	return false
+}
+
+// completionAddresses is a shell pipeline printing the wallet addresses of the daemon.
+const completionAddresses = `go-filecoin address ls --enc=json 2>/dev/null | grep -o '"[ft][0-9][a-z0-9]*"' | tr -d '"'`
+
+func bashCompletion(root *cmds.Command) string {
+	var b bytes.Buffer
+	b.WriteString("# bash completion for go-filecoin, generated by `go-filecoin completion bash`\n\n")
+
+	b.WriteString("__go_filecoin_entry() {\n\tcase \"$1\" in\n")
+	for _, e := range completionEntries(root) {
+		fmt.Fprintf(&b, "\t%q)\n", e.path)
+		fmt.Fprintf(&b, "\t\tsubs=%q\n", strings.Join(e.subcommands, " "))
+		fmt.Fprintf(&b, "\t\tflags=%q\n", strings.Join(e.flags, " "))
+		if e.addressArgs {
+			b.WriteString("\t\taddrs=1\n")
+		}
+		b.WriteString("\t\t;;\n")
+	}
+	b.WriteString("\tesac\n}\n\n")
+
+	b.WriteString(`_go_filecoin() {
+	local cur="${COMP_WORDS[COMP_CWORD]}"
+	local path="" subs="" flags="" addrs=0 i w
+	__go_filecoin_entry ""
+	for ((i = 1; i < COMP_CWORD; i++)); do
+		w="${COMP_WORDS[i]}"
+		if [[ " $subs " == *" $w "* ]]; then
+			path="${path:+$path }$w"
+			subs="" flags="" addrs=0
+			__go_filecoin_entry "$path"
+		fi
+	done
+
+	if [[ "$cur" == -* ]]; then
+		COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+		return
+	fi
+	local words="$subs"
+	if [[ $addrs == 1 ]]; then
+		words="$words $(` + completionAddresses + `)"
+	fi
+	COMPREPLY=($(compgen -W "$words" -- "$cur"))
+}
+
+complete -o default -F _go_filecoin go-filecoin
+`)
+	return b.String()
+}
+
+func zshCompletion(root *cmds.Command) string {
+	return "# zsh completion for go-filecoin, generated by `go-filecoin completion zsh`\n\n" +
+		"autoload -U +X bashcompinit && bashcompinit\n\n" +
+		strings.TrimPrefix(bashCompletion(root), "# bash completion for go-filecoin, generated by `go-filecoin completion bash`\n\n")
+}
+
+func fishCompletion(root *cmds.Command) string {
+	entries := completionEntries(root)
+
+	var b bytes.Buffer
+	b.WriteString("# fish completion for go-filecoin, generated by `go-filecoin completion fish`\n\n")
+	b.WriteString(`function __go_filecoin_at
+	set -l path
+	set -l subs ` + strings.Join(entries[0].subcommands, " ") + `
+	for w in (commandline -opc)[2..-1]
+		if contains -- $w $subs
+			set path $path $w
+			set subs (__go_filecoin_subs (string join ' ' $path))
+		end
+	end
+	set -l joined (string join ' ' $path)
+	test "$joined" = "$argv[1]"
+end
+
+`)
+	b.WriteString("function __go_filecoin_subs\n\tswitch \"$argv[1]\"\n")
+	for _, e := range entries {
+		if len(e.subcommands) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\t\tcase %s\n\t\t\tprintf '%%s\\n' %s\n", fishQuote(e.path), strings.Join(e.subcommands, " "))
+	}
+	b.WriteString("\tend\nend\n\n")
+
+	b.WriteString("complete -c go-filecoin -f\n")
+	for _, e := range entries {
+		cond := fishQuote("__go_filecoin_at " + fishQuote(e.path))
+		if len(e.subcommands) > 0 {
+			fmt.Fprintf(&b, "complete -c go-filecoin -n %s -a %s\n", cond, fishQuote(strings.Join(e.subcommands, " ")))
+		}
+		for _, flag := range e.flags {
+			if strings.HasPrefix(flag, "--") {
+				fmt.Fprintf(&b, "complete -c go-filecoin -n %s -l %s\n", cond, strings.TrimPrefix(flag, "--"))
+			} else {
+				fmt.Fprintf(&b, "complete -c go-filecoin -n %s -s %s\n", cond, strings.TrimPrefix(flag, "-"))
+			}
+		}
+		if e.addressArgs {
+			fmt.Fprintf(&b, "complete -c go-filecoin -n %s -a %s\n", cond, fishQuote("("+completionAddresses+")"))
+		}
+	}
+	return b.String()
+}
+
+// fishQuote quotes s as a single fish argument.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
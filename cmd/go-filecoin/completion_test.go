@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestCompletionEntries(t *testing.T) {
+	tf.UnitTest(t)
+
+	entries := make(map[string]completionEntry)
+	for _, e := range completionEntries(RootCmd) {
+		entries[e.path] = e
+	}
+
+	root, ok := entries[""]
+	require.True(t, ok)
+	assert.Contains(t, root.subcommands, "wallet")
+	assert.Contains(t, root.subcommands, "daemon")
+	assert.Contains(t, root.flags, "--repodir")
+
+	t.Log("flags of parent commands are inherited")
+	balance, ok := entries["wallet balance"]
+	require.True(t, ok)
+	assert.Empty(t, balance.subcommands)
+	assert.Contains(t, balance.flags, "--repodir")
+	assert.True(t, balance.addressArgs)
+
+	send := entries["message send"]
+	assert.Contains(t, send.flags, "--gas-price")
+	assert.False(t, entries["chain head"].addressArgs)
+}
+
+func TestCompletionScripts(t *testing.T) {
+	tf.UnitTest(t)
+
+	assert.Contains(t, bashCompletion(RootCmd), "complete -o default -F _go_filecoin go-filecoin")
+	assert.Contains(t, zshCompletion(RootCmd), "bashcompinit")
+	assert.Contains(t, fishCompletion(RootCmd), `complete -c go-filecoin -n '__go_filecoin_at \'wallet\'' -a 'balance export import label labels sign-data unlabel verify-data'`)
+}
@@ -0,0 +1,9 @@
+// +build !windows
+
+package commands
+
+import "syscall"
+
+func isConnRefusedErrno(err error) bool {
+	return err == syscall.ECONNREFUSED
+}
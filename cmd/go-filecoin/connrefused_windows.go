@@ -0,0 +1,13 @@
+// +build windows
+
+package commands
+
+import "syscall"
+
+// wsaeconnrefused is the winsock error of refused connections, which is not
+// mapped to ECONNREFUSED.
+const wsaeconnrefused syscall.Errno = 10061
+
+func isConnRefusedErrno(err error) bool {
+	return err == syscall.ECONNREFUSED || err == wsaeconnrefused
+}
@@ -0,0 +1,238 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipfs/go-ipfs-cmds/cli"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/paths"
+)
+
+// consoleHistoryFile is the name of the file in the repo directory holding the console history.
+const consoleHistoryFile = "console_history"
+
+// consoleHistorySize is the number of lines of history kept.
+const consoleHistorySize = 1000
+
+var consoleCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Run commands against the daemon interactively",
+		ShortDescription: `
+Reads go-filecoin commands, without the leading "go-filecoin", one per line and
+runs them against the daemon. The API address is resolved once and the connection
+to the daemon is reused, so commands do not pay the startup cost of the binary.
+
+History is kept in the repo directory across sessions:
+  history   lists previous commands
+  !!        runs the previous command again
+  !<n>      runs command <n> of the history again
+  exit      leaves the console
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		api, err := getAPIAddress(req)
+		if err != nil {
+			return err
+		}
+
+		repoDir, _ := req.Options[OptionRepoDir].(string)
+		repoDir, err = paths.GetRepoPath(repoDir)
+		if err != nil {
+			return err
+		}
+		history, err := loadConsoleHistory(filepath.Join(repoDir, consoleHistoryFile))
+		if err != nil {
+			return err
+		}
+
+		c := &console{
+			api:     api,
+			history: history,
+			stdout:  os.Stdout,
+			stderr:  os.Stderr,
+		}
+		return c.run(req.Context, os.Stdin)
+	},
+}
+
+// consoleLocalCmds are commands that cannot be run from the console.
+var consoleLocalCmds = map[string]bool{
+	"console": true,
+	"daemon":  true,
+	"init":    true,
+}
+
+type console struct {
+	api     string
+	history *consoleHistory
+	stdout  *os.File
+	stderr  *os.File
+}
+
+func (c *console) run(ctx context.Context, in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(c.stdout, "go-filecoin> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(c.stdout)
+			return scanner.Err()
+		}
+
+		line, err := c.history.expand(strings.TrimSpace(scanner.Text()))
+		if err != nil {
+			fmt.Fprintf(c.stderr, "Error: %s\n", err)
+			continue
+		}
+		switch line {
+		case "":
+			continue
+		case "exit", "quit":
+			return nil
+		case "history":
+			c.history.print(c.stdout)
+			continue
+		}
+
+		if err := c.history.add(line); err != nil {
+			fmt.Fprintf(c.stderr, "Error: failed to save history: %s\n", err)
+		}
+		c.exec(ctx, line)
+	}
+}
+
+// exec runs one command line, errors are reported by cli.Run on stderr.
+func (c *console) exec(ctx context.Context, line string) {
+	args, err := splitCommandLine(line)
+	if err != nil {
+		fmt.Fprintf(c.stderr, "Error: %s\n", err)
+		return
+	}
+	if len(args) == 0 {
+		return
+	}
+	if consoleLocalCmds[args[0]] {
+		fmt.Fprintf(c.stderr, "Error: %s cannot be run from the console\n", args[0])
+		return
+	}
+
+	makeExecutor := func(req *cmds.Request, env interface{}) (cmds.Executor, error) {
+		api := c.api
+		if !requiresDaemon(req) {
+			api = ""
+		}
+		return &executor{api: api, exec: cmds.NewExecutor(RootCmd)}, nil
+	}
+	_ = cli.Run(ctx, RootCmd, append([]string{"go-filecoin"}, args...), nil, c.stdout, c.stderr, buildEnv, makeExecutor)
+}
+
+// splitCommandLine splits a line into arguments as a shell would for words,
+// single and double quotes and backslash escapes. Nothing is expanded.
+func splitCommandLine(line string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// consoleHistory is the list of lines entered in the console, persisted to a file.
+type consoleHistory struct {
+	path  string
+	lines []string
+}
+
+func loadConsoleHistory(path string) (*consoleHistory, error) {
+	h := &consoleHistory{path: path}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read console history")
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			h.lines = append(h.lines, line)
+		}
+	}
+	return h, nil
+}
+
+// expand replaces a history reference, "!!" or "!<n>", with the line it refers to.
+func (h *consoleHistory) expand(line string) (string, error) {
+	if !strings.HasPrefix(line, "!") {
+		return line, nil
+	}
+	if len(h.lines) == 0 {
+		return "", errors.New("history is empty")
+	}
+	if line == "!!" {
+		return h.lines[len(h.lines)-1], nil
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 || n > len(h.lines) {
+		return "", errors.Errorf("%s: not in history", line)
+	}
+	return h.lines[n-1], nil
+}
+
+// add appends a line to the history, keeping the last consoleHistorySize lines.
+func (h *consoleHistory) add(line string) error {
+	h.lines = append(h.lines, line)
+	if len(h.lines) > consoleHistorySize {
+		h.lines = h.lines[len(h.lines)-consoleHistorySize:]
+	}
+	return ioutil.WriteFile(h.path, []byte(strings.Join(h.lines, "\n")+"\n"), 0600)
+}
+
+func (h *consoleHistory) print(w io.Writer) {
+	for i, line := range h.lines {
+		fmt.Fprintf(w, "%5d  %s\n", i+1, line)
+	}
+}
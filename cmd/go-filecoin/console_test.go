@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	tf.UnitTest(t)
+
+	args, err := splitCommandLine(`config mining.minerAddress '"t01000"'  wallet\ label "a b"`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"config", "mining.minerAddress", `"t01000"`, "wallet label", "a b"}, args)
+
+	_, err = splitCommandLine(`show block "bafy`)
+	assert.Error(t, err)
+}
+
+func TestConsoleHistory(t *testing.T) {
+	tf.UnitTest(t)
+
+	dir, err := ioutil.TempDir("", "console")
+	require.NoError(t, err)
+	defer func() { require.NoError(t, os.RemoveAll(dir)) }()
+	path := filepath.Join(dir, consoleHistoryFile)
+
+	h, err := loadConsoleHistory(path)
+	require.NoError(t, err)
+	_, err = h.expand("!!")
+	assert.Error(t, err)
+
+	require.NoError(t, h.add("chain head"))
+	require.NoError(t, h.add("mpool ls"))
+
+	t.Log("history is persisted")
+	h, err = loadConsoleHistory(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"chain head", "mpool ls"}, h.lines)
+
+	t.Log("references expand to previous lines")
+	line, err := h.expand("!!")
+	require.NoError(t, err)
+	assert.Equal(t, "mpool ls", line)
+	line, err = h.expand("!1")
+	require.NoError(t, err)
+	assert.Equal(t, "chain head", line)
+	_, err = h.expand("!3")
+	assert.EqualError(t, err, "!3: not in history")
+	line, err = h.expand("id")
+	require.NoError(t, err)
+	assert.Equal(t, "id", line)
+}
+
+func TestConsoleRun(t *testing.T) {
+	tf.UnitTest(t)
+
+	dir, err := ioutil.TempDir("", "console")
+	require.NoError(t, err)
+	defer func() { require.NoError(t, os.RemoveAll(dir)) }()
+
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	require.NoError(t, err)
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	require.NoError(t, err)
+
+	h, err := loadConsoleHistory(filepath.Join(dir, consoleHistoryFile))
+	require.NoError(t, err)
+	c := &console{history: h, stdout: stdout, stderr: stderr}
+
+	// leb128 runs locally, so no daemon is needed
+	in := strings.NewReader("leb128 encode 300\n!!\n\ndaemon\nhistory\nexit\nleb128 encode 1\n")
+	require.NoError(t, c.run(context.Background(), in))
+	require.NoError(t, stdout.Close())
+	require.NoError(t, stderr.Close())
+
+	out, err := ioutil.ReadFile(stdout.Name())
+	require.NoError(t, err)
+	errOut, err := ioutil.ReadFile(stderr.Name())
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, strings.Count(string(out), `"rAI="`))
+	assert.Contains(t, string(out), "    2  leb128 encode 300")
+	assert.Contains(t, string(errOut), "daemon cannot be run from the console")
+	assert.Equal(t, []string{"leb128 encode 300", "leb128 encode 300", "daemon"}, h.lines)
+}
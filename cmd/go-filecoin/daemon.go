@@ -35,8 +35,10 @@ var daemonCmd = &cmds.Command{
 		cmdkit.BoolOption(OfflineMode, "start the node without networking"),
 		cmdkit.BoolOption(ELStdout),
 		cmdkit.BoolOption(IsRelay, "advertise and allow filecoin network traffic to be relayed through this node"),
+		cmdkit.BoolOption(ReadOnlyAPI, "serve only api commands that query the chain, state and deals, rejecting any that change state or sign"),
 		cmdkit.StringOption(BlockTime, "period a node waits between mining successive blocks").WithDefault(clock.DefaultEpochDuration.String()),
 		cmdkit.StringOption(PropagationDelay, "time a node waits after the start of an epoch for blocks to arrive").WithDefault(clock.DefaultPropagationDelay.String()),
+		cmdkit.StringOption(ClockSkew, "offset added to the node's clock, for testing"),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		return daemonRun(req, re)
@@ -69,6 +71,20 @@ func daemonRun(req *cmds.Request, re cmds.ResponseEmitter) error {
 		config.Swarm.PublicRelayAddress = publicRelayAddress
 	}
 
+	if readOnly, ok := req.Options[ReadOnlyAPI].(bool); ok && readOnly {
+		config.API.ReadOnly = true
+	}
+
+	repoDir, err := rep.Path()
+	if err != nil {
+		return err
+	}
+	stopLogging, err := setupDaemonLogging(config.Observability.Log, repoDir)
+	if err != nil {
+		return err
+	}
+	defer stopLogging() // nolint: errcheck
+
 	opts, err := node.OptionsFromRepo(rep)
 	if err != nil {
 		return err
@@ -102,12 +118,26 @@ func daemonRun(req *cmds.Request, re cmds.ResponseEmitter) error {
 	}
 	opts = append(opts, node.PropagationDelay(propDelay))
 
+	if skewStr, ok := req.Options[ClockSkew].(string); ok && skewStr != "" {
+		skew, err := time.ParseDuration(skewStr)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %s", ClockSkew, skewStr)
+		}
+		opts = append(opts, node.ClockSkew(skew))
+	}
+
 	journal, err := journal.NewZapJournal(rep.JournalPath())
 	if err != nil {
 		return err
 	}
 	opts = append(opts, node.JournalConfigOption(journal))
 
+	drain, err := drainTimeout(config.API)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, node.DrainTimeout(drain))
+
 	// Monkey-patch network parameters option will set package variables during node build
 	opts = append(opts, node.MonkeyPatchNetworkParamsOption(config.NetworkParams))
 
@@ -134,7 +164,11 @@ func daemonRun(req *cmds.Request, re cmds.ResponseEmitter) error {
 	if err := fcn.Start(req.Context); err != nil {
 		return err
 	}
-	defer fcn.Stop(req.Context)
+	defer func() {
+		// The request context is canceled by then, the node bounds each step
+		// of saving its state with the drain timeout.
+		fcn.Stop(context.Background())
+	}()
 
 	// Run API server around the node.
 	ready := make(chan interface{}, 1)
@@ -165,8 +199,18 @@ func getRepo(req *cmds.Request) (repo.Repo, error) {
 // RunAPIAndWait starts an API server and waits for it to finish.
 // The `ready` channel is closed when the server is running and its API address has been
 // saved to the node's repo.
-// A message sent to or closure of the `terminate` channel signals the server to stop.
+// A message sent to or closure of the `terminate` channel, or ctx being done, signals the
+// server to stop.
 func RunAPIAndWait(ctx context.Context, nd *node.Node, config *config.APIConfig, ready chan interface{}, terminate chan os.Signal) error {
+	drain, err := drainTimeout(config)
+	if err != nil {
+		return err
+	}
+	limiter, err := newAPILimiter(config, clock.NewSystemClock())
+	if err != nil {
+		return err
+	}
+
 	servenv := CreateServerEnv(ctx, nd)
 
 	cfg := cmdhttp.NewServerConfig()
@@ -187,9 +231,20 @@ func RunAPIAndWait(ctx context.Context, nd *node.Node, config *config.APIConfig,
 		return err
 	}
 
+	root := rootCmdDaemon
+	if config.ReadOnly {
+		root = readOnlyCmd(rootCmdDaemon)
+	}
+
 	handler := http.NewServeMux()
-	handler.Handle("/debug/pprof/", http.DefaultServeMux)
-	handler.Handle(APIPrefix+"/", cmdhttp.NewHandler(servenv, rootCmdDaemon, cfg))
+	if !config.ReadOnly {
+		handler.Handle("/debug/pprof/", http.DefaultServeMux)
+	}
+	var apiHandler http.Handler = cmdhttp.NewHandler(servenv, root, cfg)
+	if limiter != nil {
+		apiHandler = limiter.Wrap(apiHandler)
+	}
+	handler.Handle(APIPrefix+"/", apiHandler)
 
 	apiserv := http.Server{
 		Handler: handler,
@@ -209,14 +264,20 @@ func RunAPIAndWait(ctx context.Context, nd *node.Node, config *config.APIConfig,
 	}
 	// Signal that the sever has started and then wait for a signal to stop.
 	close(ready)
-	received := <-terminate
-	if received != nil {
-		fmt.Println("Received signal", received)
+	select {
+	case received := <-terminate:
+		if received != nil {
+			fmt.Println("Received signal", received)
+		}
+	case <-ctx.Done():
 	}
 	fmt.Println("Shutting down...")
 
-	// Allow a grace period for clean shutdown.
-	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	// Stop accepting requests and allow those in flight to complete.
+	ctx, cancel := context.WithCancel(context.Background())
+	if drain > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), drain)
+	}
 	defer cancel()
 
 	if err := apiserv.Shutdown(ctx); err != nil {
@@ -226,6 +287,21 @@ func RunAPIAndWait(ctx context.Context, nd *node.Node, config *config.APIConfig,
 	return nil
 }
 
+const defaultDrainTimeout = 30 * time.Second
+
+// drainTimeout returns the time given to each step of shutting down, zero for
+// the steps to be unbounded.
+func drainTimeout(config *config.APIConfig) (time.Duration, error) {
+	if config.DrainTimeout == "" {
+		return defaultDrainTimeout, nil
+	}
+	d, err := time.ParseDuration(config.DrainTimeout)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid api.drainTimeout %s", config.DrainTimeout)
+	}
+	return d, nil
+}
+
 func CreateServerEnv(ctx context.Context, nd *node.Node) *Env {
 	return &Env{
 		blockMiningAPI: nd.BlockMining.BlockMiningAPI,
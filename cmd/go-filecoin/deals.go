@@ -2,6 +2,7 @@ package commands
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/pkg/errors"
 
@@ -10,6 +11,8 @@ import (
 	"github.com/ipfs/go-cid"
 	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
 	cmds "github.com/ipfs/go-ipfs-cmds"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/reconcile"
 )
 
 const (
@@ -22,8 +25,9 @@ var dealsCmd = &cmds.Command{
 		Tagline: "Manage and inspect deals made by or with this node",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"list": dealsListCmd,
-		"show": dealsShowCmd,
+		"list":      dealsListCmd,
+		"show":      dealsShowCmd,
+		"reconcile": dealsReconcileCmd,
 	},
 }
 
@@ -114,3 +118,49 @@ var dealsShowCmd = &cmds.Command{
 	},
 	Type: storagemarket.ClientDeal{},
 }
+
+var dealsReconcileCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Compare the client deals with a miner with its records and the chain",
+		ShortDescription: `
+Asks the miner for its records of the deals this node made with it as a client
+and checks the deals either party considers active against the chain. Reports
+the deals whose records disagree, such as deals the miner has no record of and
+deals the miner claims complete whose sectors were never committed.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("miner", "Address of the miner to reconcile the deals with"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		minerOpt, _ := req.Options["miner"].(string)
+		if minerOpt == "" {
+			return errors.New("the --miner option is required")
+		}
+		minerAddr, err := addressFromString(env, minerOpt)
+		if err != nil {
+			return errors.Wrap(err, "invalid miner address")
+		}
+
+		report, err := GetStorageAPI(env).ReconcileDeals(req.Context, minerAddr)
+		if err != nil {
+			return err
+		}
+		return re.Emit(report)
+	},
+	Type: reconcile.Report{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, report *reconcile.Report) error {
+			if _, err := fmt.Fprintf(w, "%d deals with %s, %d mismatches\n", report.Deals, report.Miner, len(report.Mismatches)); err != nil {
+				return err
+			}
+			for _, m := range report.Mismatches {
+				_, err := fmt.Fprintf(w, "%s\tdeal %d\t%s\tclient: %s\tminer: %s\t%s\n", m.ProposalCid, m.DealID, m.Kind, m.ClientState, m.MinerState, m.Detail)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
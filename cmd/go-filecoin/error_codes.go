@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"net"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/libp2p/go-libp2p-core/network"
+	swarm "github.com/libp2p/go-libp2p-swarm"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/errcode"
+)
+
+// reportErrorCodes makes every command under `root` report the code of the
+// errors it fails with as a prefix of the error message, such as
+// "insufficient-funds: ...", which clients of the API parse with
+// errcode.Parse. The errors are wrapped, not replaced, so that callers of the
+// commands can still inspect them. Commands shared by several trees are
+// wrapped once.
+func reportErrorCodes(root *cmds.Command, wrapped map[*cmds.Command]bool) {
+	if wrapped[root] {
+		return
+	}
+	wrapped[root] = true
+	for _, sub := range root.Subcommands {
+		reportErrorCodes(sub, wrapped)
+	}
+
+	run := root.Run
+	if run == nil {
+		return
+	}
+	root.Run = func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		err := run(req, re, env)
+		if errcode.CodeOf(err) == errcode.Unknown {
+			return err
+		}
+		return &codedError{err: err}
+	}
+}
+
+// codedError is an error reported by a command with the message of the error
+// it wraps prefixed with its code.
+type codedError struct {
+	err error
+}
+
+func (e *codedError) Error() string {
+	return errcode.Format(e.err)
+}
+
+// Unwrap returns the error reported.
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// isStreamError returns whether `err` is a failure to reach a peer or to talk
+// to it over a stream, rather than an error the peer responded with.
+func isStreamError(err error) bool {
+	var netErr net.Error
+	var dialErr *swarm.DialError
+	return errors.As(err, &netErr) ||
+		errors.As(err, &dialErr) ||
+		errors.Is(err, swarm.ErrDialBackoff) ||
+		errors.Is(err, swarm.ErrNoAddresses) ||
+		errors.Is(err, swarm.ErrNoGoodAddresses) ||
+		errors.Is(err, network.ErrReset) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/libp2p/go-libp2p-core/network"
+	swarm "github.com/libp2p/go-libp2p-swarm"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/errcode"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestReportErrorCodes(t *testing.T) {
+	tf.UnitTest(t)
+
+	var err error
+	leaf := &cmds.Command{
+		Run: func(*cmds.Request, cmds.ResponseEmitter, cmds.Environment) error { return err },
+	}
+	root := &cmds.Command{Subcommands: map[string]*cmds.Command{"a": leaf, "b": leaf}}
+	reportErrorCodes(root, map[*cmds.Command]bool{})
+
+	t.Log("codes prefix the messages of tagged errors, which are kept")
+	err = errors.Wrap(errcode.New(errcode.GasTooLow, "gas limit 1 below minimum 2"), "invalid message")
+	reported := leaf.Run(nil, nil, nil)
+	assert.EqualError(t, reported, "gas-too-low: invalid message: gas limit 1 below minimum 2")
+	assert.True(t, errors.Is(reported, err))
+	assert.Equal(t, errcode.GasTooLow, errcode.CodeOf(reported))
+	var tagged *errcode.Error
+	assert.True(t, errors.As(reported, &tagged))
+
+	t.Log("other errors are unchanged")
+	err = errors.New("boom")
+	assert.Equal(t, err, leaf.Run(nil, nil, nil))
+	err = nil
+	assert.NoError(t, leaf.Run(nil, nil, nil))
+}
+
+func TestIsStreamError(t *testing.T) {
+	tf.UnitTest(t)
+
+	assert.True(t, isStreamError(xerrors.Errorf("failed to open stream: %w", swarm.ErrDialBackoff)))
+	assert.True(t, isStreamError(errors.Wrap(network.ErrReset, "failed to read response")))
+	assert.True(t, isStreamError(xerrors.Errorf("failed to read response: %w", io.EOF)))
+	assert.True(t, isStreamError(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+
+	t.Log("errors returned by the peer are not stream errors")
+	assert.False(t, isStreamError(errors.New("deal rejected: price too low")))
+	assert.False(t, isStreamError(errcode.New(errcode.InsufficientFunds, "not enough funds")))
+}
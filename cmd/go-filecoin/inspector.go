@@ -10,6 +10,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/build/flags"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/proofs"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
 )
 
@@ -164,6 +165,9 @@ type RuntimeInfo struct {
 	GoMaxProcs    int
 	NumGoRoutines int
 	NumCGoCalls   int64
+	// SealingSupported is false on platforms where the node cannot seal
+	// sectors, and so cannot mine
+	SealingSupported bool
 }
 
 // EnvironmentInfo contains information about the environment filecoin is running in.
@@ -197,6 +201,8 @@ func (g *Inspector) Runtime() *RuntimeInfo {
 		GoMaxProcs:    runtime.GOMAXPROCS(0),
 		NumGoRoutines: runtime.NumGoroutine(),
 		NumCGoCalls:   runtime.NumCgoCall(),
+
+		SealingSupported: proofs.SealingSupported(),
 	}
 }
 
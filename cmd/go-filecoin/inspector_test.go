@@ -8,6 +8,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/proofs"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 )
@@ -26,6 +27,7 @@ func TestRuntime(t *testing.T) {
 	assert.Equal(t, runtime.NumCPU(), rt.NumProc)
 	assert.Equal(t, runtime.GOMAXPROCS(0), rt.GoMaxProcs)
 	assert.Equal(t, runtime.NumCgoCall(), rt.NumCGoCalls)
+	assert.Equal(t, proofs.SealingSupported(), rt.SealingSupported)
 }
 
 func TestDisk(t *testing.T) {
@@ -2,11 +2,21 @@ package commands
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
 	cmds "github.com/ipfs/go-ipfs-cmds"
 	logging "github.com/ipfs/go-log/v2"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/logfile"
 )
 
 var loglogger = logging.Logger("commands/log")
@@ -52,11 +62,18 @@ var logLevelCmd = &cmds.Command{
 		ShortDescription: `
 Change the verbosity of one or all subsystems log output. This does not affect
 the event log.
+
+  go-filecoin log level <subsystem> <level>   changes the level of one subsystem
+  go-filecoin log level <level>               changes the level of all subsystems
+
+Subsystems are listed by 'go-filecoin log ls'. The change lasts until the daemon
+stops, levels applied at startup are set in the observability.log.levels config.
 `,
 	},
 
 	Arguments: []cmdkit.Argument{
-		cmdkit.StringArg("level", true, false, `The log level, with 'debug' the most verbose and 'panic' the least verbose.
+		cmdkit.StringArg("subsystem", true, false, "The subsystem logging identifier, or the level of all subsystems when no level is given"),
+		cmdkit.StringArg("level", false, false, `The log level, with 'debug' the most verbose and 'panic' the least verbose.
 			One of: debug, info, warning, error, fatal, panic.
 		`),
 	},
@@ -67,11 +84,15 @@ the event log.
 	},
 
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
-		level := strings.ToLower(req.Arguments[0])
+		level := strings.ToLower(req.Arguments[len(req.Arguments)-1])
+		subsystem, hasSubsystem := req.Options["subsystem"].(string)
+		if len(req.Arguments) == 2 {
+			subsystem, hasSubsystem = req.Arguments[0], true
+		}
 
 		var s string
-		if subsystem, ok := req.Options["subsystem"].(string); ok {
-			if err := logging.SetLogLevel(subsystem, level); err != nil {
+		if hasSubsystem {
+			if err := setLogLevel(subsystem, level); err != nil {
 				return err
 			}
 			s = fmt.Sprintf("Changed log level of '%s' to '%s'", subsystem, level)
@@ -80,7 +101,7 @@ the event log.
 			if err := logging.SetLogLevelRegex(expression, level); err != nil {
 				return err
 			}
-			s = fmt.Sprintf("Changed log level matching expression '%s' to '%s'", subsystem, level)
+			s = fmt.Sprintf("Changed log level matching expression '%s' to '%s'", expression, level)
 			loglogger.Info(s)
 		} else {
 			lvl, err := logging.LevelFromString(level)
@@ -97,6 +118,60 @@ the event log.
 	Type: string(""),
 }
 
+func setLogLevel(subsystem, level string) error {
+	err := logging.SetLogLevel(subsystem, level)
+	if err == logging.ErrNoSuchLogger {
+		return errors.Errorf("unknown subsystem '%s', see 'go-filecoin log ls'", subsystem)
+	}
+	return err
+}
+
+// setupDaemonLogging applies the levels of the log config and, when a log file
+// is configured, starts writing the log output to it. The returned function
+// stops writing and closes the file.
+func setupDaemonLogging(cfg *config.LogConfig, repoDir string) (func() error, error) {
+	for subsystem, level := range cfg.Levels {
+		if err := setLogLevel(subsystem, level); err != nil {
+			return nil, errors.Wrap(err, "invalid observability.log.levels")
+		}
+	}
+
+	if cfg.File == "" {
+		return func() error { return nil }, nil
+	}
+	path := cfg.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(repoDir, path)
+	}
+	var period time.Duration
+	if cfg.RotationPeriod != "" {
+		var err error
+		if period, err = time.ParseDuration(cfg.RotationPeriod); err != nil {
+			return nil, errors.Wrap(err, "invalid observability.log.rotationPeriod")
+		}
+	}
+	w, err := logfile.NewWriter(path, int64(cfg.MaxSizeMB)<<20, period, int(cfg.MaxBackups), clock.NewSystemClock())
+	if err != nil {
+		return nil, err
+	}
+
+	r := logging.NewPipeReader()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := logfile.Copy(w, r, cfg.Format); err != nil {
+			fmt.Fprintf(os.Stderr, "log file %s stopped: %s\n", path, err)
+			// logging blocks on the pipe, keep reading until it is closed
+			_, _ = io.Copy(ioutil.Discard, r)
+		}
+	}()
+	return func() error {
+		_ = r.Close()
+		<-done
+		return w.Close()
+	}, nil
+}
+
 var logLsCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "List the logging subsystems.",
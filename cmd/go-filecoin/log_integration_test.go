@@ -0,0 +1,29 @@
+package commands_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/node/test"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestLogLevel(t *testing.T) {
+	tf.IntegrationTest(t)
+	ctx := context.Background()
+
+	builder := test.NewNodeBuilder(t)
+	_, cmdClient, done := builder.BuildAndStartAPI(ctx)
+	defer done()
+
+	out := cmdClient.RunSuccess(ctx, "log", "level", "commands/log", "debug").ReadStdoutTrimNewlines()
+	assert.Contains(t, out, "Changed log level of 'commands/log' to 'debug'")
+	cmdClient.RunSuccess(ctx, "log", "level", "--subsystem=commands/log", "info")
+	cmdClient.RunSuccess(ctx, "log", "level", "--expression=^commands/", "info")
+	cmdClient.RunSuccess(ctx, "log", "level", "error")
+
+	cmdClient.RunFail(ctx, "unknown subsystem 'nosuchsubsystem'", "log", "level", "nosuchsubsystem", "debug")
+	cmdClient.RunFail(ctx, "unrecognized level", "log", "level", "commands/log", "chatty")
+}
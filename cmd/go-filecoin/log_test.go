@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestSetupDaemonLogging(t *testing.T) {
+	tf.UnitTest(t)
+
+	repoDir, err := ioutil.TempDir("", "logging")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(repoDir) }()
+
+	t.Run("writes json to a file relative to the repo", func(t *testing.T) {
+		cfg := config.NewDefaultConfig().Observability.Log
+		cfg.File = "logs/daemon.log"
+		cfg.Format = "json"
+		cfg.Levels = map[string]string{"commands/log": "info"}
+
+		stop, err := setupDaemonLogging(cfg, repoDir)
+		require.NoError(t, err)
+		loglogger.Infow("logging to a file", "answer", 42)
+		require.NoError(t, stop())
+
+		data, err := ioutil.ReadFile(filepath.Join(repoDir, "logs", "daemon.log"))
+		require.NoError(t, err)
+		var found bool
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var entry map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+			if entry["msg"] == "logging to a file" {
+				found = true
+				assert.Equal(t, "commands/log", entry["logger"])
+				assert.Equal(t, "info", entry["level"])
+				assert.Equal(t, float64(42), entry["answer"])
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("rejects unknown subsystems", func(t *testing.T) {
+		cfg := config.NewDefaultConfig().Observability.Log
+		cfg.Levels = map[string]string{"nosuchsubsystem": "debug"}
+
+		_, err := setupDaemonLogging(cfg, repoDir)
+		assert.Error(t, err)
+	})
+}
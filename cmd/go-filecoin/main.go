@@ -8,8 +8,9 @@ import (
 	"net"
 	"net/url"
 	"os"
-	"syscall"
 
+	"github.com/filecoin-project/specs-actors/actors/runtime/exitcode"
+	"github.com/ipfs/go-cid"
 	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
 	cmds "github.com/ipfs/go-ipfs-cmds"
 	"github.com/ipfs/go-ipfs-cmds/cli"
@@ -19,6 +20,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/paths"
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
@@ -31,6 +33,9 @@ const (
 	// OptionRepoDir is the name of the option for specifying the directory of the repo.
 	OptionRepoDir = "repodir"
 
+	// OptionAPIToken is the name of the option for specifying the token identifying the client to the api rate limits.
+	OptionAPIToken = "api-token"
+
 	// OptionSectorDir is the name of the option for specifying the directory into which staged and sealed sectors will be written.
 	OptionSectorDir = "sectordir"
 
@@ -69,6 +74,10 @@ const (
 	// PropagationDelay is the duration the miner will wait for blocks to arrive before attempting to mine a new one
 	PropagationDelay = "prop-delay"
 
+	// ClockSkew is the duration string of an offset added to the daemon's
+	// clock. It is meant for testing how a network copes with drifting clocks.
+	ClockSkew = "clock-skew"
+
 	// PeerKeyFile is the path of file containing key to use for new nodes libp2p identity
 	PeerKeyFile = "peerkeyfile"
 
@@ -90,6 +99,9 @@ const (
 	// IsRelay when set causes the the daemon to provide libp2p relay
 	// services allowing other filecoin nodes behind NATs to talk directly.
 	IsRelay = "is-relay"
+
+	// ReadOnlyAPI when set causes the daemon to serve only commands that do not change state or sign
+	ReadOnlyAPI = "read-only-api"
 )
 
 func init() {
@@ -128,14 +140,17 @@ VIEW DATA STRUCTURES
   go-filecoin dag                    - Interact with IPLD DAG objects
   go-filecoin deals                  - Manage deals made by or with this node
   go-filecoin show                   - Get human-readable representations of filecoin objects
+  go-filecoin state                  - Query the state of the chain, such as the power table
 
 NETWORK COMMANDS
+  go-filecoin bitswap                - Inspect bitswap data exchange
   go-filecoin bootstrap              - Interact with bootstrap addresses
   go-filecoin dht                    - Interact with the dht
   go-filecoin id                     - Show info about the network peers
   go-filecoin ping <peer ID>...      - Send echo request packets to p2p network members
   go-filecoin swarm                  - Interact with the swarm
   go-filecoin stats                  - Monitor statistics on your network usage
+  go-filecoin sync                   - Control which peers the chain syncer follows
   go-filecion drand configure        - Configure drand server connection
   go-filecoin drand random           - retrieve drand randomness
 
@@ -149,6 +164,8 @@ MESSAGE COMMANDS
   go-filecoin outbox                 - Manage the outbound message queue
 
 TOOL COMMANDS
+  go-filecoin completion <shell>     - Generate a bash, zsh or fish completion script
+  go-filecoin console                - Run commands against the daemon interactively
   go-filecoin inspect                - Show info about the go-filecoin node
   go-filecoin leb128                 - Leb128 cli encode/decode
   go-filecoin log                    - Interact with the daemon event log output
@@ -159,6 +176,7 @@ TOOL COMMANDS
 	Options: []cmdkit.Option{
 		cmdkit.StringOption(OptionAPI, "set the api port to use"),
 		cmdkit.StringOption(OptionRepoDir, "set the repo directory, defaults to ~/.filecoin/repo"),
+		cmdkit.StringOption(OptionAPIToken, "set the token identifying this client to the api rate limits"),
 		cmdkit.StringOption(cmds.EncLong, cmds.EncShort, "The encoding type the output should be encoded with (pretty-json or json)").WithDefault("pretty-json"),
 		cmdkit.BoolOption("help", "Show the full command help text."),
 		cmdkit.BoolOption("h", "Show a short version of the command help text."),
@@ -173,16 +191,18 @@ var rootCmdDaemon = &cmds.Command{
 
 // all top level commands, not available to daemon
 var rootSubcmdsLocal = map[string]*cmds.Command{
-	"daemon":  daemonCmd,
-	"init":    initCmd,
-	"version": versionCmd,
-	"leb128":  leb128Cmd,
+	"completion": completionCmd,
+	"daemon":     daemonCmd,
+	"init":       initCmd,
+	"version":    versionCmd,
+	"leb128":     leb128Cmd,
 }
 
 // all top level commands, available on daemon. set during init() to avoid configuration loops.
 var rootSubcmdsDaemon = map[string]*cmds.Command{
 	"actor":            actorCmd,
 	"address":          addrsCmd,
+	"bitswap":          bitswapCmd,
 	"bootstrap":        bootstrapCmd,
 	"chain":            chainCmd,
 	"config":           configCmd,
@@ -204,13 +224,18 @@ var rootSubcmdsDaemon = map[string]*cmds.Command{
 	"protocol":         protocolCmd,
 	"retrieval-client": retrievalClientCmd,
 	"show":             showCmd,
+	"state":            stateCmd,
 	"stats":            statsCmd,
 	"swarm":            swarmCmd,
+	"sync":             syncCmd,
 	"wallet":           walletCmd,
 	"version":          versionCmd,
 }
 
 func init() {
+	// The console runs commands itself, so it is registered here to break the initialization cycle.
+	rootSubcmdsLocal["console"] = consoleCmd
+
 	for k, v := range rootSubcmdsLocal {
 		RootCmd.Subcommands[k] = v
 	}
@@ -219,6 +244,8 @@ func init() {
 		RootCmd.Subcommands[k] = v
 		rootCmdDaemon.Subcommands[k] = v
 	}
+
+	reportErrorCodes(RootCmd, map[*cmds.Command]bool{})
 }
 
 // Run processes the arguments and stdin
@@ -355,12 +382,33 @@ func isConnectionRefused(err error) bool {
 	if !ok {
 		return false
 	}
-	return syscallErr.Err == syscall.ECONNREFUSED
+	return isConnRefusedErrno(syscallErr.Err)
 }
 
 var priceOption = cmdkit.StringOption("gas-price", "Price (FIL e.g. 0.00013) to pay for each GasUnit consumed mining this message")
 var limitOption = cmdkit.Int64Option("gas-limit", "Maximum GasUnits this message is allowed to consume")
 var previewOption = cmdkit.BoolOption("preview", "Preview the Gas cost of this command without actually executing it")
+var dryRunOption = cmdkit.BoolOption("dry-run", "Build, sign and apply the message to the current state, printing what would be sent without sending it")
+
+// DryRunResult is the message a command would have sent with its outcome on
+// the current state.
+type DryRunResult struct {
+	Message  *types.SignedMessage
+	Cid      cid.Cid
+	ExitCode exitcode.ExitCode
+	Return   []byte
+	GasUsed  gas.Unit
+}
+
+func newDryRunResult(d *msg.DryRun) *DryRunResult {
+	return &DryRunResult{
+		Message:  d.Message,
+		Cid:      d.Cid,
+		ExitCode: d.Receipt.ExitCode,
+		Return:   d.Receipt.ReturnValue,
+		GasUsed:  d.Receipt.GasUsed,
+	}
+}
 
 func parseGasOptions(req *cmds.Request) (types.AttoFIL, gas.Unit, bool, error) {
 	priceOption := req.Options["gas-price"]
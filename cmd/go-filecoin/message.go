@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/specs-actors/actors/abi"
 	"github.com/filecoin-project/specs-actors/actors/builtin"
 	"github.com/filecoin-project/specs-actors/actors/util/adt"
@@ -42,6 +41,8 @@ type MessageSendResult struct {
 	Cid     cid.Cid
 	GasUsed gas.Unit
 	Preview bool
+	// DryRun is the message that would have been sent, set with --dry-run.
+	DryRun *DryRunResult `json:",omitempty"`
 }
 
 var msgSendCmd = &cmds.Command{
@@ -49,19 +50,20 @@ var msgSendCmd = &cmds.Command{
 		Tagline: "Send a message", // This feels too generic...
 	},
 	Arguments: []cmdkit.Argument{
-		cmdkit.StringArg("target", true, false, "Address of the actor to send the message to"),
+		cmdkit.StringArg("target", true, false, "Address or label of the actor to send the message to"),
 		cmdkit.StringArg("method", false, false, "The method to invoke on the target actor"),
 	},
 	Options: []cmdkit.Option{
 		cmdkit.StringOption("value", "Value to send with message in FIL"),
-		cmdkit.StringOption("from", "Address to send message from"),
+		cmdkit.StringOption("from", "Address or label to send message from"),
 		priceOption,
 		limitOption,
 		previewOption,
+		dryRunOption,
 		// TODO: (per dignifiedquire) add an option to set the nonce and method explicitly
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		target, err := address.NewFromString(req.Arguments[0])
+		target, err := addressFromString(env, req.Arguments[0])
 		if err != nil {
 			return err
 		}
@@ -108,6 +110,19 @@ var msgSendCmd = &cmds.Command{
 			})
 		}
 
+		if dryRun, _ := req.Options["dry-run"].(bool); dryRun {
+			d, err := GetPorcelainAPI(env).MessageDryRun(req.Context, fromAddr, target, val, gasPrice, gasLimit, methodID, adt.Empty)
+			if err != nil {
+				return err
+			}
+			return re.Emit(&MessageSendResult{
+				Cid:     d.Cid,
+				GasUsed: d.Receipt.GasUsed,
+				Preview: true,
+				DryRun:  newDryRunResult(d),
+			})
+		}
+
 		c, _, err := GetPorcelainAPI(env).MessageSend(
 			req.Context,
 			fromAddr,
@@ -187,7 +202,7 @@ var msgWaitCmd = &cmds.Command{
 		cmdkit.BoolOption("message", "Print the whole message").WithDefault(true),
 		cmdkit.BoolOption("receipt", "Print the whole message receipt").WithDefault(true),
 		cmdkit.BoolOption("return", "Print the return value from the receipt").WithDefault(false),
-		cmdkit.Uint64Option("lookback", "Number of previous tipsets to be checked before waiting").WithDefault(msg.DefaultMessageWaitLookback),
+		cmdkit.Uint64Option("lookback", "Number of previous tipsets to be checked before waiting, messages indexed in archival mode are found at any depth").WithDefault(msg.DefaultMessageWaitLookback),
 		cmdkit.StringOption("timeout", "Maximum time to wait for message. e.g., 300ms, 1.5h, 2h45m.").WithDefault("10m"),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
@@ -265,6 +280,12 @@ var msgStatusCmd = &cmds.Command{
 		// Look in message pool
 		result.PoolMsg, result.InPool = api.MessagePoolGet(msgCid)
 
+		// Look in the receipts index
+		result.ChainMsg, _, err = api.MessageLookup(req.Context, msgCid)
+		if err != nil {
+			return err
+		}
+
 		// Look in outbox
 		for _, addr := range api.OutboxQueues() {
 			for _, qm := range api.OutboxQueueLs(addr) {
@@ -1,11 +1,14 @@
 package commands
 
 import (
+	"encoding/csv"
 	"fmt"
+	"io"
 	"math/big"
+	"strconv"
+	"time"
 
 	address "github.com/filecoin-project/go-address"
-	"github.com/filecoin-project/sector-storage/ffiwrapper"
 	"github.com/filecoin-project/specs-actors/actors/abi"
 	"github.com/filecoin-project/specs-actors/actors/builtin"
 	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
@@ -17,6 +20,9 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/constants"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/ledger"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/bidsub"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/scrubber"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
 )
@@ -27,10 +33,15 @@ var minerCmd = &cmds.Command{
 	},
 	Subcommands: map[string]*cmds.Command{
 		"create":        minerCreateCmd,
+		"earnings":      minerEarningsCmd,
 		"status":        minerStatusCommand,
 		"set-price":     minerSetPriceCmd,
 		"update-peerid": minerUpdatePeerIDCmd,
 		"set-worker":    minerSetWorkerAddressCmd,
+		"rotate-worker": minerRotateWorkerCmd,
+		"sectors":       minerSectorsCmd,
+		"bids":          minerBidsCmd,
+		"scrub":         minerScrubCmd,
 	},
 }
 
@@ -39,6 +50,8 @@ type MinerCreateResult struct {
 	Address address.Address
 	GasUsed gas.Unit
 	Preview bool
+	// DryRun is the message that would have been sent, set with --dry-run.
+	DryRun *DryRunResult `json:",omitempty"`
 }
 
 var minerCreateCmd = &cmds.Command{
@@ -54,12 +67,13 @@ additional sectors.`,
 		cmdkit.StringArg("collateral", true, false, "The amount of collateral, in FIL."),
 	},
 	Options: []cmdkit.Option{
-		cmdkit.StringOption("sectorsize", "size of the sectors which this miner will commit, in bytes"),
+		cmdkit.StringOption("sectorsize", "size of the sectors which this miner will commit, in bytes, one of the supported sizes listed by protocol"),
 		cmdkit.StringOption("from", "address to send from"),
 		cmdkit.StringOption("peerid", "Base58-encoded libp2p peer ID that the miner will operate"),
 		priceOption,
 		limitOption,
 		previewOption,
+		dryRunOption,
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		var err error
@@ -69,7 +83,7 @@ additional sectors.`,
 			return err
 		}
 
-		sealProofType, err := ffiwrapper.SealProofTypeFromSectorSize(sectorSize)
+		sealProofType, err := GetPorcelainAPI(env).SupportedSealProofType(sectorSize)
 		if err != nil {
 			return err
 		}
@@ -118,6 +132,19 @@ additional sectors.`,
 			})
 		}
 
+		if dryRun, _ := req.Options["dry-run"].(bool); dryRun {
+			d, addr, err := GetPorcelainAPI(env).MinerCreateDryRun(req.Context, fromAddr, gasPrice, gasLimit, sealProofType, pid, collateral)
+			if err != nil {
+				return err
+			}
+			return re.Emit(&MinerCreateResult{
+				Address: addr,
+				GasUsed: d.Receipt.GasUsed,
+				Preview: true,
+				DryRun:  newDryRunResult(d),
+			})
+		}
+
 		addr, err := GetPorcelainAPI(env).MinerCreate(
 			req.Context,
 			fromAddr,
@@ -144,13 +171,15 @@ additional sectors.`,
 type MinerSetPriceResult struct {
 	MinerAddress address.Address
 	Price        types.AttoFIL
+	// Announced is false when the ask could not be announced immediately.
+	Announced bool
 }
 
 var minerSetPriceCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Set the minimum price for storage",
 		ShortDescription: `Sets the mining.minimumPrice in config and creates a new ask for the given price.
-This command waits for the ask to be mined.`,
+This command waits for the ask to be mined. The new ask is announced to the network.`,
 	},
 	Arguments: []cmdkit.Argument{
 		cmdkit.StringArg("storageprice", true, false, "The new price of storage in FIL per byte per block"),
@@ -172,12 +201,15 @@ This command waits for the ask to be mined.`,
 			return err
 		}
 
+		// Clients learn the ask on the next periodic announcement if this fails.
+		announced := GetStorageAPI(env).AnnounceAsk(req.Context) == nil
+
 		minerAddr, err := GetBlockAPI(env).MinerAddress()
 		if err != nil {
 			return err
 		}
 
-		return re.Emit(&MinerSetPriceResult{minerAddr, price})
+		return re.Emit(&MinerSetPriceResult{MinerAddress: minerAddr, Price: price, Announced: announced})
 	},
 	Type: &MinerSetPriceResult{},
 }
@@ -187,6 +219,8 @@ type MinerUpdatePeerIDResult struct {
 	Cid     cid.Cid
 	GasUsed gas.Unit
 	Preview bool
+	// DryRun is the message that would have been sent, set with --dry-run.
+	DryRun *DryRunResult `json:",omitempty"`
 }
 
 var minerUpdatePeerIDCmd = &cmds.Command{
@@ -203,9 +237,10 @@ var minerUpdatePeerIDCmd = &cmds.Command{
 		priceOption,
 		limitOption,
 		previewOption,
+		dryRunOption,
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		minerAddr, err := address.NewFromString(req.Arguments[0])
+		minerAddr, err := addressFromString(env, req.Arguments[0])
 		if err != nil {
 			return err
 		}
@@ -246,6 +281,19 @@ var minerUpdatePeerIDCmd = &cmds.Command{
 
 		params := miner.ChangePeerIDParams{NewID: newPid}
 
+		if dryRun, _ := req.Options["dry-run"].(bool); dryRun {
+			d, err := GetPorcelainAPI(env).MessageDryRun(req.Context, fromAddr, minerAddr, types.ZeroAttoFIL, gasPrice, gasLimit, builtin.MethodsMiner.ChangePeerID, &params)
+			if err != nil {
+				return err
+			}
+			return re.Emit(&MinerUpdatePeerIDResult{
+				Cid:     d.Cid,
+				GasUsed: d.Receipt.GasUsed,
+				Preview: true,
+				DryRun:  newDryRunResult(d),
+			})
+		}
+
 		c, _, err := GetPorcelainAPI(env).MessageSend(
 			req.Context,
 			fromAddr,
@@ -274,7 +322,7 @@ var minerStatusCommand = &cmds.Command{
 		Tagline: "Get the status of a miner",
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		minerAddr, err := optionalAddr(req.Arguments[0])
+		minerAddr, err := addressFromString(env, req.Arguments[0])
 		if err != nil {
 			return err
 		}
@@ -305,7 +353,7 @@ var minerSetWorkerAddressCmd = &cmds.Command{
 		limitOption,
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		newWorker, err := address.NewFromString(req.Arguments[0])
+		newWorker, err := addressFromString(env, req.Arguments[0])
 		if err != nil {
 			return err
 		}
@@ -324,3 +372,354 @@ var minerSetWorkerAddressCmd = &cmds.Command{
 	},
 	Type: cid.Cid{},
 }
+
+var minerRotateWorkerCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Rotate the worker key of this node's miner",
+		ShortDescription: `
+Replaces the key signing the blocks and messages of this node's miner, e.g. when
+it is compromised, while the miner actor keeps its power and sectors. The owner
+first proposes the new worker key with 'propose', which takes effect on chain
+after a delay. Once it has, 'confirm' checks the proposal on chain and that the
+node signs with the key.
+
+Only the worker key can be rotated: the miner actor of this network version
+has no method to change its owner.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"propose": minerRotateWorkerProposeCmd,
+		"confirm": minerRotateWorkerConfirmCmd,
+	},
+}
+
+var minerRotateWorkerProposeCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Propose a new worker key for this node's miner",
+		ShortDescription: `
+Sends the new worker key to the miner actor from its owner, whose key must be in
+the wallet, and waits for the message to be mined. The new key must be a BLS key
+in the wallet, or is created in the wallet when <new-address> is omitted. The
+owner sends --funds FIL to the new key, which also creates its account on chain
+when it has none. The former key keeps signing until the height of the change.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("new-address", false, false, "The BLS key address of the new worker, defaults to a new key"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("funds", "FIL sent from the owner to the new worker").WithDefault("0"),
+		priceOption,
+		limitOption,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		var newWorker address.Address
+		if len(req.Arguments) > 0 {
+			var err error
+			newWorker, err = addressFromString(env, req.Arguments[0])
+			if err != nil {
+				return err
+			}
+		}
+
+		funds, ok := types.NewAttoFILFromFILString(req.Options["funds"].(string))
+		if !ok {
+			return fmt.Errorf("invalid funds %q", req.Options["funds"])
+		}
+
+		gasPrice, gasLimit, _, err := parseGasOptions(req)
+		if err != nil {
+			return err
+		}
+
+		rotation, err := GetPorcelainAPI(env).MinerProposeWorker(req.Context, newWorker, funds, gasPrice, gasLimit)
+		if err != nil {
+			return err
+		}
+		return re.Emit(&rotation)
+	},
+	Type: porcelain.MinerWorkerRotation{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, r *porcelain.MinerWorkerRotation) error {
+			_, err := fmt.Fprintf(w, "Worker of miner %s changes from %s to %s at height %d (message %s)\n", r.Miner, r.Worker, r.NewWorker, r.EffectiveAt, r.Message)
+			return err
+		}),
+	},
+}
+
+var minerRotateWorkerConfirmCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Confirm the new worker key of this node's miner",
+		ShortDescription: `
+Waits for the receipt of the message proposing a change of worker, as shown by
+'miner rotate-worker propose', then checks that the change has taken effect on
+chain and that the wallet holds the new key, which the node uses from then on to
+mine blocks and to sign deals and sector messages. Fails if the proposal failed,
+or while the change is pending, showing the height at which it takes effect.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("message", true, false, "The CID of the message proposing the new worker"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		proposal, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return errors.Wrap(err, "invalid message cid")
+		}
+		rotation, err := GetPorcelainAPI(env).MinerConfirmWorker(req.Context, proposal)
+		if err != nil {
+			return err
+		}
+		return re.Emit(&rotation)
+	},
+	Type: porcelain.MinerWorkerRotation{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, r *porcelain.MinerWorkerRotation) error {
+			_, err := fmt.Fprintf(w, "Worker of miner %s is %s (%s)\n", r.Miner, r.NewWorker, r.Worker)
+			return err
+		}),
+	},
+}
+
+var minerSectorsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Inspect the sectors of this node's miner",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"pieces": minerSectorsPiecesCmd,
+	},
+}
+
+// MinerPiece describes a piece stored by the miner and its references.
+type MinerPiece struct {
+	PieceCID       cid.Cid
+	Size           abi.UnpaddedPieceSize
+	Deals          []abi.DealID
+	Sectors        []abi.SectorNumber
+	RefCount       int
+	RedundantBytes uint64
+	Stored         bool
+}
+
+// MinerSectorsPiecesResult is the type returned when listing the pieces stored by the miner.
+type MinerSectorsPiecesResult struct {
+	Pieces []MinerPiece
+	// UniqueBytes is the size of all distinct pieces.
+	UniqueBytes uint64
+	// RedundantBytes is the size of all sealed copies of pieces beyond the first.
+	RedundantBytes uint64
+	// StoredBytes is the size of the pieces kept in the piece store, where
+	// each piece is kept once however many deals reference it.
+	StoredBytes uint64
+}
+
+var minerSectorsPiecesCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the deal pieces stored in the miner's sectors",
+		ShortDescription: `
+Lists each distinct piece commitment stored by the miner with the deals that
+reference it and the sectors holding it. Deals made by several clients for the
+same data are reported under a single piece, whose bytes the miner keeps once
+until the last of these deals releases it. Each deal is still sealed with its
+own copy of the piece, the bytes taken by the additional sealed copies are
+reported as redundant.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		pieces, err := GetPorcelainAPI(env).MinerListPieces(req.Context)
+		if err != nil {
+			return err
+		}
+
+		res := MinerSectorsPiecesResult{Pieces: []MinerPiece{}}
+		for _, p := range pieces {
+			res.Pieces = append(res.Pieces, MinerPiece{
+				PieceCID:       p.PieceCID,
+				Size:           p.Size,
+				Deals:          p.Deals,
+				Sectors:        p.Sectors,
+				RefCount:       p.RefCount(),
+				RedundantBytes: p.RedundantBytes(),
+				Stored:         p.Stored,
+			})
+			res.UniqueBytes += uint64(p.Size)
+			res.RedundantBytes += p.RedundantBytes()
+			if p.Stored {
+				res.StoredBytes += uint64(p.Size)
+			}
+		}
+
+		return re.Emit(&res)
+	},
+	Type: &MinerSectorsPiecesResult{},
+}
+
+var minerScrubCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Check the integrity of the sealed sectors of this node's miner",
+		ShortDescription: `
+Shows the report of the last check of the sealed sectors of the miner, or checks
+them now with --now. Each sector due for PoSt and not yet declared faulty is
+proven with a window PoSt on random challenges and the proof verified against
+its sealed CID on chain. This is a sampled check, not a full re-read of the
+sectors: it detects sector files that are missing, but only the corruptions
+that the challenges happen to hit. Sectors failing the check are declared
+faulty so that the miner is not penalized for missing their PoSt deadline,
+except those in deadlines past their fault declaration cutoff, which are
+reported as not declared. The node checks the sectors in the background every
+mining.scrubIntervalSeconds.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("now", "Check the sectors now and wait for the report"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		now, _ := req.Options["now"].(bool)
+		report, err := GetPorcelainAPI(env).MinerScrubSectors(req.Context, now)
+		if err != nil {
+			return err
+		}
+		if report == nil {
+			return errors.New("no scrub completed yet, check the sectors with --now")
+		}
+		return re.Emit(report)
+	},
+	Type: &scrubber.Report{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, r *scrubber.Report) error {
+			if _, err := fmt.Fprintf(w, "Checked %d sectors at %s, skipped %d declared faulty, %d failed\n",
+				r.Checked, r.End.Format(time.RFC3339), r.Skipped, len(r.Faults)); err != nil {
+				return err
+			}
+			for _, f := range r.Faults {
+				declared := ""
+				if !f.Declared {
+					declared = ", not declared"
+				}
+				if _, err := fmt.Fprintf(w, "sector %d (deadline %d%s): %s\n", f.Sector, f.Deadline, declared, f.Error); err != nil {
+					return err
+				}
+			}
+			var err error
+			if r.DeclarationError != "" {
+				_, err = fmt.Fprintf(w, "Failed to declare faults: %s\n", r.DeclarationError)
+			} else if r.Declaration.Defined() {
+				_, err = fmt.Fprintf(w, "Faults declared in message %s\n", r.Declaration)
+			}
+			return err
+		}),
+	},
+}
+
+var minerEarningsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Report the income and expenditure of a miner",
+		ShortDescription: `
+Reports what a miner earned and spent in the tipsets of the head chain from
+height --since: the rewards of the blocks it won, the payments of its storage
+deals, the gas paid by its owner and worker for PoSts, sector commitments and
+other messages, the funds lost to slashing and the funds withdrawn by its
+owner. Amounts are in attoFIL. Entries are recorded as the chain grows when the
+node mines, and computed from the chain state otherwise.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.Uint64Option("since", "The first height of the report").WithDefault(uint64(0)),
+		cmdkit.StringOption("miner", "The miner address, defaults to the miner of this node"),
+		cmdkit.BoolOption("csv", "Output an entry per tipset as CSV"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		var maddr address.Address
+		var err error
+		if s, ok := req.Options["miner"].(string); ok && s != "" {
+			maddr, err = address.NewFromString(s)
+		} else {
+			maddr, err = GetBlockAPI(env).MinerAddress()
+		}
+		if err != nil {
+			return err
+		}
+
+		since, _ := req.Options["since"].(uint64)
+		earnings, err := GetPorcelainAPI(env).MinerEarnings(req.Context, maddr, abi.ChainEpoch(since))
+		if err != nil {
+			return err
+		}
+		return re.Emit(earnings)
+	},
+	Type: &ledger.Earnings{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, earnings *ledger.Earnings) error {
+			if asCSV, _ := req.Options["csv"].(bool); asCSV {
+				return writeEarningsCSV(w, earnings)
+			}
+
+			t := earnings.Total
+			_, err := fmt.Fprintf(w, `Miner:          %s
+Heights:        %d-%d
+Blocks won:     %d
+Block rewards:  %s
+Deal payments:  %s
+PoSt gas:       %s
+Commitment gas: %s
+Other gas:      %s
+Slashing:       %s
+Net:            %s
+Withdrawn:      %s
+`, earnings.Miner, earnings.Since, earnings.Until, t.BlocksWon, t.BlockRewards, t.DealPayments,
+				t.PoStGas, t.CommitmentGas, t.OtherGas, t.Slashing, t.Net(), t.Withdrawn)
+			return err
+		}),
+	},
+}
+
+func writeEarningsCSV(w io.Writer, earnings *ledger.Earnings) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"height", "blocks_won", "block_rewards", "deal_payments", "post_gas", "commitment_gas", "other_gas", "slashing", "withdrawn", "net"}) // nolint: errcheck
+	for _, e := range earnings.Entries {
+		out.Write([]string{ // nolint: errcheck
+			e.Height.String(),
+			strconv.FormatUint(e.BlocksWon, 10),
+			e.BlockRewards.String(),
+			e.DealPayments.String(),
+			e.PoStGas.String(),
+			e.CommitmentGas.String(),
+			e.OtherGas.String(),
+			e.Slashing.String(),
+			e.Withdrawn.String(),
+			e.Net().String(),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+var minerBidsCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the bids of the miner on storage requests",
+		ShortDescription: `
+Lists the unexpired bids the miner made on the storage requests published by
+clients. The miner bids with its ask when mining.autoBid is set in the config
+and it can take the request. Prices are in attoFIL per GiB per epoch.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		bids, err := GetStorageAPI(env).MinerBids()
+		if err != nil {
+			return err
+		}
+		for _, b := range bids {
+			if err := re.Emit(b.Bid); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Type: bidsub.Bid{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, b *bidsub.Bid) error {
+			_, err := fmt.Fprintf(w, "%s\tprice %s\tuntil epoch %d\n", b.Request, types.NewAttoFIL(b.Price.Int), b.Expiry)
+			return err
+		}),
+	},
+}
@@ -71,3 +71,14 @@ func TestSetPrice(t *testing.T) {
 	assert.Equal(t, abi.NewTokenAmount(1000), asks[0].Ask.Price)
 	assert.Equal(t, abi.ChainEpoch(400), asks[0].Ask.Expiry)
 }
+
+func TestMinerSectorsPieces(t *testing.T) {
+	tf.IntegrationTest(t)
+	ctx := context.Background()
+	builder := test.NewNodeBuilder(t)
+
+	_, cmdClient, done := builder.BuildAndStartAPI(ctx)
+	defer done()
+
+	cmdClient.RunFail(ctx, "must be mining to list pieces", "miner", "sectors", "pieces")
+}
@@ -5,6 +5,8 @@ import (
 	"github.com/ipfs/go-cid"
 	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
 	cmds "github.com/ipfs/go-ipfs-cmds"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/mining"
 )
 
 var miningCmd = &cmds.Command{
@@ -14,6 +16,7 @@ var miningCmd = &cmds.Command{
 	Subcommands: map[string]*cmds.Command{
 		"address":       miningAddrCmd,
 		"once":          miningOnceCmd,
+		"preview":       miningPreviewCmd,
 		"start":         miningStartCmd,
 		"status":        miningStatusCmd,
 		"stop":          miningStopCmd,
@@ -50,6 +53,26 @@ var miningOnceCmd = &cmds.Command{
 	Type: cid.Cid{},
 }
 
+var miningPreviewCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the block this node would mine in the current epoch without mining it",
+		ShortDescription: `
+Runs the election for the current epoch on top of the chain head and reports
+whether this node would win, the pending messages it would include in its block
+and the pending messages it would leave out, with the reason. The gas revenue
+reported is an upper bound: messages only pay for the gas they use.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		preview, err := GetBlockAPI(env).MiningPreview(req.Context)
+		if err != nil {
+			return err
+		}
+		return re.Emit(preview)
+	},
+	Type: mining.Preview{},
+}
+
 var miningSetupCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Prepare node to receive storage deals without starting the mining scheduler",
@@ -13,6 +13,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/fixtures/fortest"
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/node/test"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/mining"
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 	"github.com/filecoin-project/go-filecoin/tools/fast"
@@ -109,3 +110,32 @@ func TestMiningAddPieceAndSealNow(t *testing.T) {
 	}
 	assert.Fail(t, "timed out waiting for miner to gain power from sealing")
 }
+
+func TestMiningPreview(t *testing.T) {
+	tf.IntegrationTest(t)
+	ctx := context.Background()
+	builder := test.NewNodeBuilder(t)
+	buildWithMiner(t, builder)
+
+	_, cmdClient, done := builder.BuildAndStartAPI(ctx)
+	defer done()
+
+	msgCid := cmdClient.RunSuccess(
+		ctx,
+		"message", "send",
+		"--from", fortest.TestAddresses[0].String(),
+		"--gas-price", "2", "--gas-limit", "300",
+		"--value=10",
+		fortest.TestAddresses[1].String(),
+	).ReadStdoutTrimNewlines()
+
+	var preview mining.Preview
+	cmdClient.RunMarshaledJSON(ctx, &preview, "mining", "preview")
+
+	t.Log("the pending message is selected for the block")
+	require.Len(t, preview.Included, 1)
+	assert.Equal(t, msgCid, preview.Included[0].Cid.String())
+	assert.Empty(t, preview.Excluded)
+	assert.Equal(t, types.NewGasPrice(600), preview.MaxGasRevenue)
+	assert.True(t, preview.Epoch > 0)
+}
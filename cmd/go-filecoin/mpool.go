@@ -2,12 +2,15 @@ package commands
 
 import (
 	"fmt"
+	"io"
+	"sync"
 
 	"github.com/ipfs/go-cid"
 	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
 	cmds "github.com/ipfs/go-ipfs-cmds"
 	"github.com/pkg/errors"
 
+	"github.com/filecoin-project/go-filecoin/internal/pkg/message"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 )
 
@@ -16,9 +19,10 @@ var mpoolCmd = &cmds.Command{
 		Tagline: "Manage the message pool",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"ls":   mpoolLsCmd,
-		"show": mpoolShowCmd,
-		"rm":   mpoolRemoveCmd,
+		"ls":    mpoolLsCmd,
+		"show":  mpoolShowCmd,
+		"rm":    mpoolRemoveCmd,
+		"watch": mpoolWatchCmd,
 	},
 }
 
@@ -82,3 +86,73 @@ var mpoolRemoveCmd = &cmds.Command{
 		return nil
 	},
 }
+
+var mpoolWatchCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Stream the messages added to and removed from the pool",
+		ShortDescription: `
+Prints the outstanding messages of the pool as pending, then each message as it
+is added to or removed from the pool, until interrupted. Messages are removed
+when they are mined, expire or are deleted.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		api := GetPorcelainAPI(env)
+
+		// Queue the events so that the watcher never blocks the pool.
+		var lk sync.Mutex
+		var events []message.PoolEvent
+		changed := make(chan struct{}, 1)
+		unwatch := api.MessagePoolWatch(func(e message.PoolEvent) {
+			lk.Lock()
+			events = append(events, e)
+			lk.Unlock()
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		})
+		defer unwatch()
+
+		for _, msg := range api.MessagePoolPending() {
+			c, err := msg.Cid()
+			if err != nil {
+				return err
+			}
+			if err := re.Emit(message.PoolEvent{Type: mpoolPending, Cid: c, Message: msg}); err != nil {
+				return err
+			}
+		}
+		for {
+			select {
+			case <-req.Context.Done():
+				return nil
+			case <-changed:
+			}
+
+			lk.Lock()
+			updates := events
+			events = nil
+			lk.Unlock()
+
+			for _, e := range updates {
+				if err := re.Emit(e); err != nil {
+					return err
+				}
+			}
+		}
+	},
+	Type: message.PoolEvent{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, e *message.PoolEvent) error {
+			m := e.Message.Message
+			_, err := fmt.Fprintf(w, "%s\t%s\tfrom %s\tto %s\tnonce %d\tmethod %d\tvalue %s\n",
+				e.Type, e.Cid, m.From, m.To, m.CallSeqNum, m.Method, m.Value)
+			return err
+		}),
+	},
+}
+
+// mpoolPending is the type of the events of the messages already in the pool
+// when it is watched.
+const mpoolPending = message.PoolEventType("pending")
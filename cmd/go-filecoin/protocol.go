@@ -1,6 +1,9 @@
 package commands
 
 import (
+	"fmt"
+	"io"
+
 	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
 	cmds "github.com/ipfs/go-ipfs-cmds"
 
@@ -11,6 +14,9 @@ var protocolCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Show protocol parameter details",
 	},
+	Subcommands: map[string]*cmds.Command{
+		"upgrades": protocolUpgradesCmd,
+	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		params, err := GetPorcelainAPI(env).ProtocolParameters(env.Context())
 		if err != nil {
@@ -20,3 +26,39 @@ var protocolCmd = &cmds.Command{
 	},
 	Type: porcelain.ProtocolParams{},
 }
+
+var protocolUpgradesCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the protocol upgrades of the network",
+		ShortDescription: `
+Lists the protocol versions of the network by the height they take effect at,
+from genesis to the upgrades scheduled in the future. The version in effect at the
+chain head is active.
+
+Private networks schedule upgrades changing network parameters in the
+parameters.Upgrades config, which must be the same on every node of the network.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		upgrades, err := GetPorcelainAPI(env).ProtocolUpgrades(req.Context)
+		if err != nil {
+			return err
+		}
+		return re.Emit(upgrades)
+	},
+	Type: []porcelain.ProtocolUpgrade{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, upgrades *[]porcelain.ProtocolUpgrade) error {
+			for _, upgrade := range *upgrades {
+				name := upgrade.Name
+				if name == "" {
+					name = "-"
+				}
+				if _, err := fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\n", upgrade.Version, upgrade.EffectiveAt, upgrade.Status, name, upgrade.Description); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
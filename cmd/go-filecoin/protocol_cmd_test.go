@@ -4,10 +4,12 @@ import (
 	"context"
 	"testing"
 
+	"github.com/filecoin-project/specs-actors/actors/abi"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/node/test"
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 )
@@ -33,3 +35,38 @@ func TestProtocol(t *testing.T) {
 	assert.Contains(t, out, "\"Network\": \"gfctest\"")
 	assert.Contains(t, out, "\"AutoSealInterval\": 120")
 }
+
+func TestProtocolUpgrades(t *testing.T) {
+	tf.IntegrationTest(t)
+	ctx := context.Background()
+
+	b := test.NewNodeBuilder(t)
+	node := b.
+		WithConfig(func(c *config.Config) {
+			c.NetworkParams.Upgrades = []config.NetworkUpgradeConfig{{
+				Version:                1,
+				Height:                 100,
+				Name:                   "raise-min-power",
+				ConsensusMinerMinPower: 2048,
+			}}
+		}).
+		Build(ctx)
+	require.NoError(t, node.Chain().ChainReader.Load(ctx))
+
+	cmd, stop := test.RunNodeAPI(ctx, node, t)
+	defer stop()
+
+	var upgrades []porcelain.ProtocolUpgrade
+	cmd.RunMarshaledJSON(ctx, &upgrades, "protocol", "upgrades")
+	require.Len(t, upgrades, 2)
+	assert.Equal(t, uint64(0), upgrades[0].Version)
+	assert.Equal(t, porcelain.UpgradeStatusActive, upgrades[0].Status)
+	assert.Equal(t, uint64(1), upgrades[1].Version)
+	assert.Equal(t, abi.ChainEpoch(100), upgrades[1].EffectiveAt)
+	assert.Equal(t, "raise-min-power", upgrades[1].Name)
+	assert.Equal(t, "consensus miner min power 2048", upgrades[1].Description)
+	assert.Equal(t, porcelain.UpgradeStatusScheduled, upgrades[1].Status)
+
+	out := cmd.RunSuccess(ctx, "protocol", "upgrades", "--enc=text").ReadStdout()
+	assert.Contains(t, out, "1\t100\tscheduled\traise-min-power\tconsensus miner min power 2048")
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"strings"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/pkg/errors"
+)
+
+// readOnlyCmds are the daemon commands served by a read-only API. They query
+// the chain, state and deals without changing anything or signing. Commands are
+// rejected unless listed so that a new command is not exposed by public query
+// nodes before it has been reviewed.
+var readOnlyCmds = map[string]bool{
+	"actor ls":                   true,
+	"actor state":                true,
+	"bitswap stat":               true,
+	"chain head":                 true,
+	"chain ls":                   true,
+	"chain status":               true,
+	"client cached-asks":         true,
+	"client deal-key":            true,
+	"client list-asks":           true,
+	"client ls-pins":             true,
+	"client query-storage-deal":  true,
+	"client replication-status":  true,
+	"client storage-bids":        true,
+	"client transfers":           true,
+	"client verify-storage-deal": true,
+	"dag get":                    true,
+	"deals list":                 true,
+	"deals show":                 true,
+	"id":                         true,
+	"leb128 decode":              true,
+	"leb128 encode":              true,
+	"message status":             true,
+	"message wait":               true,
+	"miner bids":                 true,
+	"miner earnings":             true,
+	"miner sectors pieces":       true,
+	"miner status":               true,
+	"mining address":             true,
+	"mining status":              true,
+	"mpool ls":                   true,
+	"mpool show":                 true,
+	"mpool watch":                true,
+	"protocol":                   true,
+	"protocol upgrades":          true,
+	"show block":                 true,
+	"show header":                true,
+	"show messages":              true,
+	"show receipts":              true,
+	"state power":                true,
+	"state power-history":        true,
+	"state read-actor":           true,
+	"sync trusted":               true,
+	"version":                    true,
+	"wallet balance":             true,
+	"wallet verify-data":         true,
+}
+
+// readOnlyCmd returns a copy of the command tree under `root` in which every
+// command not listed in readOnlyCmds fails without running.
+func readOnlyCmd(root *cmds.Command) *cmds.Command {
+	return restrictCmd(root, nil)
+}
+
+func restrictCmd(cmd *cmds.Command, path []string) *cmds.Command {
+	restricted := *cmd
+	restricted.Subcommands = make(map[string]*cmds.Command, len(cmd.Subcommands))
+	for name, sub := range cmd.Subcommands {
+		restricted.Subcommands[name] = restrictCmd(sub, append(append([]string{}, path...), name))
+	}
+
+	name := strings.Join(path, " ")
+	if cmd.Run != nil && !readOnlyCmds[name] {
+		restricted.Run = func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+			return errors.Errorf("%s is not available: the API is read-only", name)
+		}
+	}
+	return &restricted
+}
@@ -0,0 +1,29 @@
+package commands_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/node/test"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestReadOnlyAPI(t *testing.T) {
+	tf.IntegrationTest(t)
+	ctx := context.Background()
+
+	builder := test.NewNodeBuilder(t)
+	builder.WithConfig(func(c *config.Config) {
+		c.API.ReadOnly = true
+	})
+	_, cmdClient, done := builder.BuildAndStartAPI(ctx)
+	defer done()
+
+	cmdClient.RunSuccess(ctx, "chain", "head")
+	cmdClient.RunSuccess(ctx, "id")
+
+	cmdClient.RunFail(ctx, "the API is read-only", "address", "new")
+	cmdClient.RunFail(ctx, "the API is read-only", "config", "api.readOnly", "false")
+	cmdClient.RunFail(ctx, "the API is read-only", "wallet", "export", "t0100")
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/stretchr/testify/assert"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestReadOnlyCmdsExist(t *testing.T) {
+	tf.UnitTest(t)
+
+	for name := range readOnlyCmds {
+		cmd := rootCmdDaemon
+		for _, sub := range strings.Fields(name) {
+			cmd = cmd.Subcommands[sub]
+			if cmd == nil {
+				break
+			}
+		}
+		if assert.NotNil(t, cmd, name) {
+			assert.NotNil(t, cmd.Run, name)
+		}
+	}
+}
+
+func TestReadOnlyCmd(t *testing.T) {
+	tf.UnitTest(t)
+
+	root := readOnlyCmd(rootCmdDaemon)
+	runOf := func(c *cmds.Command) uintptr { return reflect.ValueOf(c.Run).Pointer() }
+
+	t.Log("listed commands are unchanged")
+	head := root.Subcommands["chain"].Subcommands["head"]
+	assert.Equal(t, runOf(rootCmdDaemon.Subcommands["chain"].Subcommands["head"]), runOf(head))
+
+	t.Log("other commands fail without running")
+	imp := root.Subcommands["wallet"].Subcommands["import"]
+	assert.NotEqual(t, runOf(rootCmdDaemon.Subcommands["wallet"].Subcommands["import"]), runOf(imp))
+	err := imp.Run(&cmds.Request{}, nil, nil)
+	assert.EqualError(t, err, "wallet import is not available: the API is read-only")
+}
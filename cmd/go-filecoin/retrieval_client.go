@@ -1,8 +1,20 @@
 package commands
 
 import (
+	"fmt"
+	"io"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
 	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
 	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/retrieval"
 )
 
 var retrievalClientCmd = &cmds.Command{
@@ -10,10 +22,137 @@ var retrievalClientCmd = &cmds.Command{
 		Tagline: "Manage retrieval client operations",
 	},
 	Subcommands: map[string]*cmds.Command{
+		"retrieve":       clientRetrieveCmd,
 		"retrieve-piece": clientRetrievePieceCmd,
 	},
 }
 
+// RetrieveResult is the result of a retrieval deal.
+type RetrieveResult struct {
+	DealID  retrievalmarket.DealID
+	Miner   address.Address
+	Payload cid.Cid
+	// Root is the CID of the block the retrieved path ends in, the payload
+	// when no path was given.
+	Root          cid.Cid
+	BytesReceived uint64
+	FundsSpent    abi.TokenAmount
+}
+
+var clientRetrieveCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Retrieve all or part of a DAG stored by a miner",
+		ShortDescription: `
+Makes a retrieval deal for the DAG rooted at <cid>, paid from the default wallet
+address or --from. The miner is --miner, or one of the miners the node made a
+storage deal for the data with.
+
+By default the whole DAG is retrieved. --path retrieves only the sub-DAG at an
+IPLD data model path from the root, e.g. Links/1/Hash for the second link of a
+dag-pb (unixfs) node, links being ordered by name, and --selector only the blocks picked by a dag-json
+encoded IPLD selector. The retrieved blocks are stored locally, and the result
+gives the CID of the block the path ends in, which can be read out with
+'go-filecoin client cat'.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("cid", true, false, "Content identifier of the root of the DAG to retrieve"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("miner", "Retrieval miner actor address"),
+		cmdkit.StringOption("path", "IPLD data model path of the sub-DAG to retrieve"),
+		cmdkit.StringOption("selector", "dag-json encoded IPLD selector of the blocks to retrieve"),
+		cmdkit.StringOption("from", "Address or label to pay for the retrieval from"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		payload, err := cid.Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		path, _ := req.Options["path"].(string)
+		selJSON, _ := req.Options["selector"].(string)
+		if path != "" && selJSON != "" {
+			return errors.New("only one of --path and --selector may be given")
+		}
+		var sel ipld.Node
+		if selJSON != "" {
+			if sel, err = retrieval.DecodeSelector([]byte(selJSON)); err != nil {
+				return err
+			}
+		} else {
+			sel = retrieval.PathSelector(path)
+		}
+
+		wallet, err := fromAddrOrDefault(req, env)
+		if err != nil {
+			return err
+		}
+
+		miner, mpid, err := retrievalMiner(req, env, payload)
+		if err != nil {
+			return err
+		}
+
+		state, err := retrieval.Retrieve(req.Context, GetRetrievalAPI(env).Client(), miner, mpid, wallet, payload, sel)
+		if err != nil {
+			return err
+		}
+
+		// Selectors can pick blocks anywhere in the DAG, only paths end in one.
+		root := payload
+		if path != "" {
+			if root, err = GetPorcelainAPI(env).DAGResolveDataModelPath(req.Context, payload, path); err != nil {
+				return err
+			}
+		}
+
+		return re.Emit(&RetrieveResult{
+			DealID:        state.ID,
+			Miner:         miner,
+			Payload:       payload,
+			Root:          root,
+			BytesReceived: state.TotalReceived,
+			FundsSpent:    state.FundsSpent,
+		})
+	},
+	Type: RetrieveResult{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, res *RetrieveResult) error {
+			_, err := fmt.Fprintf(w, "%s\nretrieved %d bytes from %s in deal %s for %s\n", res.Root, res.BytesReceived, res.Miner, res.DealID, res.FundsSpent)
+			return err
+		}),
+	},
+}
+
+// retrievalMiner returns the miner given with --miner, or else the first miner
+// known to store payload, with its peer ID.
+func retrievalMiner(req *cmds.Request, env cmds.Environment, payload cid.Cid) (address.Address, peer.ID, error) {
+	var miner address.Address
+	var mpid peer.ID
+	if m, ok := req.Options["miner"].(string); ok {
+		var err error
+		if miner, err = address.NewFromString(m); err != nil {
+			return address.Undef, "", errors.Wrap(err, "invalid miner address")
+		}
+	} else {
+		providers := GetRetrievalAPI(env).Client().FindProviders(payload)
+		if len(providers) == 0 {
+			return address.Undef, "", errors.Errorf("no known miner stores %s, use --miner", payload)
+		}
+		miner, mpid = providers[0].Address, providers[0].ID
+	}
+
+	if mpid == "" {
+		status, err := GetPorcelainAPI(env).MinerGetStatus(req.Context, miner, GetPorcelainAPI(env).ChainHeadKey())
+		if err != nil {
+			return address.Undef, "", err
+		}
+		mpid = status.PeerID
+	}
+	return miner, mpid, nil
+}
+
 var clientRetrievePieceCmd = &cmds.Command{
 	Helptext: cmdkit.HelpText{
 		Tagline: "Read out piece data stored by a miner on the network",
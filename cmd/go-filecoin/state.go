@@ -0,0 +1,203 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
+)
+
+var stateCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Query the state of the chain",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"power":         statePowerCmd,
+		"power-history": statePowerHistoryCmd,
+		"read-actor":    stateReadActorCmd,
+	},
+}
+
+var statePowerCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the power table at a tipset",
+		ShortDescription: `
+Lists the miners of the power table at the tipset of the given block CIDs, or at
+the head, ranked by quality adjusted power, with their raw and quality adjusted
+power and the percentage of the network's quality adjusted power they hold.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("cids", false, true, "CIDs of the blocks of the tipset, defaults to the head"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		blockCids, err := cidsFromSlice(req.Arguments)
+		if err != nil {
+			return err
+		}
+		table, err := GetPorcelainAPI(env).PowerTable(req.Context, block.NewTipSetKey(blockCids...))
+		if err != nil {
+			return err
+		}
+		return re.Emit(table)
+	},
+	Type: porcelain.PowerTable{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, table *porcelain.PowerTable) error {
+			_, err := fmt.Fprintf(w, "Height:         %d\nTipset:         %s\nNetwork power:  %s raw, %s quality adjusted\n",
+				table.Height, table.Tipset, table.NetworkRawPower, table.NetworkQualityAdjustedPower)
+			if err != nil {
+				return err
+			}
+			for _, m := range table.Miners {
+				_, err := fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%.4f%%\n", m.Rank, m.Miner, m.RawPower, m.QualityAdjustedPower, m.Percentage)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
+
+var statePowerHistoryCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the power of a miner over a height range",
+		ShortDescription: `
+Lists the raw and quality adjusted power of a miner, and its percentage of the
+network's quality adjusted power, at each tipset of the head chain from height
+--from to height --to, which defaults to the height of the head.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("miner", true, false, "A miner actor address"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.Uint64Option("from", "The first height of the range").WithDefault(uint64(0)),
+		cmdkit.Uint64Option("to", "The last height of the range, defaults to the head"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		minerAddr, err := addressFromString(env, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		api := GetPorcelainAPI(env)
+		from, _ := req.Options["from"].(uint64)
+		to, ok := req.Options["to"].(uint64)
+		if !ok {
+			head, err := api.ChainHead()
+			if err != nil {
+				return err
+			}
+			height, err := head.Height()
+			if err != nil {
+				return err
+			}
+			to = uint64(height)
+		}
+
+		history, err := api.MinerPowerHistory(req.Context, minerAddr, abi.ChainEpoch(from), abi.ChainEpoch(to))
+		if err != nil {
+			return err
+		}
+		return re.Emit(history)
+	},
+	Type: []porcelain.PowerHistoryEntry{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, history []porcelain.PowerHistoryEntry) error {
+			for _, e := range history {
+				_, err := fmt.Fprintf(w, "%d\t%s\t%s\t%.4f%%\n", e.Height, e.RawPower, e.QualityAdjustedPower, e.Percentage)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
+
+var stateReadActorCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show the decoded state of an actor",
+		ShortDescription: `
+Decodes the state of an actor at the head with the structures of its code:
+miners, the storage market, payment channels, accounts, the init, power and
+reward actors and multisigs. The entries of the AMTs and HAMTs of the state,
+such as the sectors of a miner or the deals of the market, are listed from
+--offset, --limit per collection.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("addr", true, false, "The address of the actor"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.UintOption("offset", "The first entry of collections to list").WithDefault(uint(0)),
+		cmdkit.UintOption("limit", "The number of entries of collections to list, 0 for all").WithDefault(uint(20)),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		addr, err := addressFromString(env, req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		offset, _ := req.Options["offset"].(uint)
+		limit, _ := req.Options["limit"].(uint)
+
+		api := GetPorcelainAPI(env)
+		view, err := api.StateView(api.ChainHeadKey())
+		if err != nil {
+			return err
+		}
+		report, err := view.ActorStateRead(req.Context, addr, int(offset), int(limit))
+		if err != nil {
+			return err
+		}
+		return re.Emit(report)
+	},
+	Type: state.ActorStateReport{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, report *state.ActorStateReport) error {
+			name := report.Name
+			if name == "" {
+				name = "unknown actor"
+			}
+			_, err := fmt.Fprintf(w, "Address:  %s\nCode:     %s (%s)\nHead:     %s\nNonce:    %d\nBalance:  %s\n",
+				report.Address, report.Code, name, report.Head, report.Nonce, report.Balance)
+			if err != nil {
+				return err
+			}
+			if report.State == nil {
+				return nil
+			}
+			st, err := json.MarshalIndent(report.State, "", "  ")
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "State:\n%s\n", st); err != nil {
+				return err
+			}
+			for _, c := range report.Collections {
+				if _, err := fmt.Fprintf(w, "%s (%d entries, %s):\n", c.Name, c.Count, c.Root); err != nil {
+					return err
+				}
+				for _, entry := range c.Entries {
+					value, err := json.Marshal(entry.Value)
+					if err != nil {
+						return err
+					}
+					if _, err := fmt.Fprintf(w, "  %s\t%s\n", entry.Key, value); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}),
+	},
+}
@@ -1,6 +1,10 @@
 package commands
 
 import (
+	"fmt"
+	"io"
+	"time"
+
 	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
 	cmds "github.com/ipfs/go-ipfs-cmds"
 	"github.com/libp2p/go-libp2p-core/peer"
@@ -19,8 +23,12 @@ libp2p peers on the internet.
 `,
 	},
 	Subcommands: map[string]*cmds.Command{
-		"connect": swarmConnectCmd,
-		"peers":   swarmPeersCmd,
+		"ban":       swarmBanCmd,
+		"connect":   swarmConnectCmd,
+		"ls-banned": swarmLsBannedCmd,
+		"peers":     swarmPeersCmd,
+		"throttle":  swarmThrottleCmd,
+		"unban":     swarmUnbanCmd,
 	},
 }
 
@@ -84,3 +92,155 @@ go-filecoin swarm connect /ip4/104.131.131.82/tcp/4001/ipfs/QmaCpDMGvV2BGHeYERUE
 	},
 	Type: peer.ID(""),
 }
+
+var swarmThrottleCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Show or change the rate limits of data transfers.",
+		ShortDescription: `
+'go-filecoin swarm throttle' shows the limits of the rates at which storage
+deal piece transfers and retrievals send and receive data, in bytes per second,
+across all peers and with any single peer. Zero means unlimited. Options change
+the given limits, including for the transfers running, until the node stops.
+The limits the node starts with are set in the transfer section of the config.
+`,
+	},
+	Options: []cmdkit.Option{
+		cmdkit.Uint64Option("upload", "Limit of the upload rate across all peers"),
+		cmdkit.Uint64Option("download", "Limit of the download rate across all peers"),
+		cmdkit.Uint64Option("peer-upload", "Limit of the upload rate to any single peer"),
+		cmdkit.Uint64Option("peer-download", "Limit of the download rate from any single peer"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		api := GetPorcelainAPI(env)
+		limits := api.NetworkTransferLimits()
+		changed := false
+		for name, limit := range map[string]*uint64{
+			"upload":        &limits.Upload,
+			"download":      &limits.Download,
+			"peer-upload":   &limits.PeerUpload,
+			"peer-download": &limits.PeerDownload,
+		} {
+			if v, ok := req.Options[name].(uint64); ok {
+				*limit = v
+				changed = true
+			}
+		}
+		if changed {
+			api.NetworkSetTransferLimits(limits)
+		}
+		return re.Emit(limits)
+	},
+	Type: net.TransferLimits{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, limits *net.TransferLimits) error {
+			_, err := fmt.Fprintf(w, "upload:         %s\ndownload:       %s\npeer upload:    %s\npeer download:  %s\n",
+				formatRateLimit(limits.Upload), formatRateLimit(limits.Download),
+				formatRateLimit(limits.PeerUpload), formatRateLimit(limits.PeerDownload))
+			return err
+		}),
+	},
+}
+
+func formatRateLimit(bytesPerSecond uint64) string {
+	if bytesPerSecond == 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d B/s", bytesPerSecond)
+}
+
+var swarmBanCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Ban a peer.",
+		ShortDescription: `
+'go-filecoin swarm ban' disconnects from a peer and refuses its connections,
+for the given duration or until it is unbanned. Bans survive restarts.
+
+Peers are also banned automatically for the swarm.banDuration of the config
+when they keep misbehaving, e.g. by serving invalid blocks, breaking protocols
+or not answering in time.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("peer", true, false, "ID of the peer to ban"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.StringOption("duration", "How long to ban the peer, e.g. 24h, until unbanned when unset"),
+		cmdkit.StringOption("reason", "Why the peer is banned"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		p, err := peer.Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		var duration time.Duration
+		if d, ok := req.Options["duration"].(string); ok {
+			duration, err = time.ParseDuration(d)
+			if err != nil {
+				return err
+			}
+			if duration <= 0 {
+				return fmt.Errorf("duration must be positive")
+			}
+		}
+		reason, _ := req.Options["reason"].(string)
+		if reason == "" {
+			reason = "banned by operator"
+		}
+		if err := GetPorcelainAPI(env).NetworkBan(p, duration, reason); err != nil {
+			return err
+		}
+		return re.Emit(p)
+	},
+	Type: peer.ID(""),
+}
+
+var swarmUnbanCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Lift the ban of a peer.",
+		ShortDescription: `
+'go-filecoin swarm unban' lifts the ban of a peer and forgets its offenses.
+`,
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("peer", true, false, "ID of the peer to unban"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		p, err := peer.Decode(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		if err := GetPorcelainAPI(env).NetworkUnban(p); err != nil {
+			return err
+		}
+		return re.Emit(p)
+	},
+	Type: peer.ID(""),
+}
+
+var swarmLsBannedCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List banned peers.",
+		ShortDescription: `
+'go-filecoin swarm ls-banned' lists the banned peers, when their bans end and
+why they were banned.
+`,
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		return re.Emit(GetPorcelainAPI(env).NetworkBanned())
+	},
+	Type: []net.BannedPeer{},
+	Encoders: cmds.EncoderMap{
+		cmds.Text: cmds.MakeTypedEncoder(func(req *cmds.Request, w io.Writer, banned *[]net.BannedPeer) error {
+			for _, b := range *banned {
+				until := "until unbanned"
+				if !b.Until.IsZero() {
+					until = "until " + b.Until.Format(time.RFC3339)
+				}
+				if _, err := fmt.Fprintf(w, "%s  %s  %s\n", b.Peer, until, b.Reason); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	},
+}
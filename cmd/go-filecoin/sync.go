@@ -0,0 +1,106 @@
+package commands
+
+import (
+	cmdkit "github.com/ipfs/go-ipfs-cmdkit"
+	cmds "github.com/ipfs/go-ipfs-cmds"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
+)
+
+// SyncTrustedResult is the result of the sync trust commands.
+type SyncTrustedResult struct {
+	Peers       []peer.ID
+	TrustedOnly bool
+}
+
+var syncCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Control which peers the chain syncer follows",
+		ShortDescription: `
+The syncer prefers chain heads sent by trusted peers over heads from all other
+peers. In exclusive mode chain heads from untrusted peers are ignored altogether,
+which is useful for permissioned networks and for recovering nodes that keep
+following a bad fork. Changes take effect immediately and are saved to the
+"sync" section of the config.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"set-trusted":   syncSetTrustedCmd,
+		"unset-trusted": syncUnsetTrustedCmd,
+		"trusted":       syncTrustedCmd,
+	},
+}
+
+var syncSetTrustedCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Trust chain heads from the given peers",
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("peer", true, true, "ID of the peer to trust"),
+	},
+	Options: []cmdkit.Option{
+		cmdkit.BoolOption("exclusive", "Only accept chain heads from trusted peers (--exclusive=false to accept all peers again)"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		return runSetTrusted(req, re, env, true)
+	},
+	Type: &SyncTrustedResult{},
+}
+
+var syncUnsetTrustedCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "Stop trusting chain heads from the given peers",
+	},
+	Arguments: []cmdkit.Argument{
+		cmdkit.StringArg("peer", true, true, "ID of the peer to stop trusting"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		return runSetTrusted(req, re, env, false)
+	},
+	Type: &SyncTrustedResult{},
+}
+
+var syncTrustedCmd = &cmds.Command{
+	Helptext: cmdkit.HelpText{
+		Tagline: "List the peers trusted by the syncer",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		return re.Emit(syncTrustedResult(env))
+	},
+	Type: &SyncTrustedResult{},
+}
+
+func runSetTrusted(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment, trusted bool) error {
+	api := GetPorcelainAPI(env)
+
+	pids := make([]peer.ID, len(req.Arguments))
+	for i, arg := range req.Arguments {
+		pid, err := peer.Decode(arg)
+		if err != nil {
+			return errors.Wrapf(err, "invalid peer id %s", arg)
+		}
+		pids[i] = pid
+	}
+
+	for _, pid := range pids {
+		if err := api.SyncSetTrusted(pid, trusted); err != nil {
+			return err
+		}
+	}
+
+	if exclusive, ok := req.Options["exclusive"].(bool); ok {
+		if err := api.SyncRestrictToTrusted(exclusive); err != nil {
+			return err
+		}
+	}
+
+	return re.Emit(syncTrustedResult(env))
+}
+
+func syncTrustedResult(env cmds.Environment) *SyncTrustedResult {
+	api := GetPorcelainAPI(env)
+	return &SyncTrustedResult{
+		Peers:       api.SyncTrustedPeers(),
+		TrustedOnly: api.SyncTrustedOnly(),
+	}
+}
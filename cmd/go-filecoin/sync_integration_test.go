@@ -0,0 +1,48 @@
+package commands_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	commands "github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/node/test"
+	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestSyncSetTrusted(t *testing.T) {
+	tf.IntegrationTest(t)
+	ctx := context.Background()
+	builder := test.NewNodeBuilder(t)
+
+	n, cmdClient, done := builder.BuildAndStartAPI(ctx)
+	defer done()
+
+	pid := th.RequireRandomPeerID(t)
+
+	var result commands.SyncTrustedResult
+	cmdClient.RunMarshaledJSON(ctx, &result, "sync", "set-trusted", pid.Pretty(), "--exclusive")
+	assert.Equal(t, pid, result.Peers[0])
+	assert.True(t, result.TrustedOnly)
+
+	assert.True(t, n.Discovery.PeerTracker.IsTrusted(pid))
+	assert.True(t, n.Discovery.PeerTracker.TrustedOnly())
+
+	t.Log("the trusted peers are persisted in the config")
+	cfg := n.Repo.Config()
+	require.Len(t, cfg.Sync.TrustedPeers, 1)
+	assert.Equal(t, pid.Pretty(), cfg.Sync.TrustedPeers[0])
+	assert.True(t, cfg.Sync.TrustedOnly)
+
+	cmdClient.RunMarshaledJSON(ctx, &result, "sync", "unset-trusted", pid.Pretty())
+	assert.Empty(t, result.Peers)
+	assert.True(t, result.TrustedOnly)
+
+	cmdClient.RunMarshaledJSON(ctx, &result, "sync", "trusted")
+	assert.Empty(t, result.Peers)
+
+	cmdClient.RunFail(ctx, "invalid peer id", "sync", "set-trusted", "notapeer")
+}
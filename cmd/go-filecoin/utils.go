@@ -113,15 +113,21 @@ func optionalSectorSizeWithDefault(o interface{}, def abi.SectorSize) (abi.Secto
 	return def, nil
 }
 
+// addressFromString parses s as an address, falling back to resolving it as
+// a label in the wallet address book.
+func addressFromString(env cmds.Environment, s string) (address.Address, error) {
+	return GetPorcelainAPI(env).WalletResolveAddress(s)
+}
+
 func fromAddrOrDefault(req *cmds.Request, env cmds.Environment) (address.Address, error) {
-	addr, err := optionalAddr(req.Options["from"])
-	if err != nil {
-		return address.Undef, err
-	}
-	if addr.Empty() {
-		return GetPorcelainAPI(env).WalletDefaultAddress()
+	if o, ok := req.Options["from"].(string); ok {
+		addr, err := addressFromString(env, o)
+		if err != nil {
+			return address.Undef, errors.Wrap(err, "invalid from address")
+		}
+		return addr, nil
 	}
-	return addr, nil
+	return GetPorcelainAPI(env).WalletDefaultAddress()
 }
 
 func cidsFromSlice(args []string) ([]cid.Cid, error) {
@@ -16,6 +16,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/connectors"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/errcode"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 )
 
@@ -66,7 +67,7 @@ func (r *RetrievalClientConnector) GetOrCreatePaymentChannel(ctx context.Context
 
 		filAmt := types.NewAttoFIL(clientFundsAvailable.Int)
 		if bal.LessThan(filAmt) {
-			return address.Undef, cid.Undef, xerrors.New("not enough funds in wallet")
+			return address.Undef, cid.Undef, errcode.New(errcode.InsufficientFunds, "not enough funds in wallet")
 		}
 
 		return r.paychMgr.CreatePaymentChannel(clientAddress, minerAddress, clientFundsAvailable)
@@ -93,7 +94,7 @@ func (r *RetrievalClientConnector) CreatePaymentVoucher(ctx context.Context, pay
 		return nil, err
 	}
 	if amount.GreaterThan(bal) {
-		return nil, xerrors.New("insufficient funds for voucher amount")
+		return nil, errcode.New(errcode.InsufficientFunds, "insufficient funds for voucher amount")
 	}
 
 	chinfo, err := r.paychMgr.GetPaymentChannelInfo(paychAddr)
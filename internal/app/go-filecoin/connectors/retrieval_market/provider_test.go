@@ -35,7 +35,7 @@ import (
 func TestNewRetrievalProviderNodeConnector(t *testing.T) {
 	tf.UnitTest(t)
 	rmnet := gfmtut.NewTestRetrievalMarketNetwork(gfmtut.TestNetworkParams{})
-	pm := piecemanager.NewFiniteStateMachineBackEnd(nil, nil)
+	pm := piecemanager.NewFiniteStateMachineBackEnd(nil, nil, nil)
 	bs := blockstore.NewBlockstore(dss.MutexWrap(datastore.NewMapDatastore()))
 
 	pchMgr, _ := makePaychMgr(context.Background(), t,
@@ -113,7 +113,7 @@ func TestRetrievalProviderConnector_SavePaymentVoucher(t *testing.T) {
 	ctx := context.Background()
 
 	rmnet := gfmtut.NewTestRetrievalMarketNetwork(gfmtut.TestNetworkParams{})
-	pm := piecemanager.NewFiniteStateMachineBackEnd(nil, nil)
+	pm := piecemanager.NewFiniteStateMachineBackEnd(nil, nil, nil)
 
 	bs := blockstore.NewBlockstore(dss.MutexWrap(datastore.NewMapDatastore()))
 	pchan := specst.NewIDAddr(t, 100)
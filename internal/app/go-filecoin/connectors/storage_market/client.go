@@ -10,7 +10,6 @@ import (
 	"github.com/filecoin-project/go-fil-markets/storagemarket"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
 	"github.com/filecoin-project/specs-actors/actors/abi"
-	"github.com/filecoin-project/specs-actors/actors/abi/big"
 	"github.com/filecoin-project/specs-actors/actors/builtin"
 	"github.com/filecoin-project/specs-actors/actors/builtin/market"
 	spaminer "github.com/filecoin-project/specs-actors/actors/builtin/miner"
@@ -24,6 +23,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/message"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/escrow"
 	appstate "github.com/filecoin-project/go-filecoin/internal/pkg/state"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 )
@@ -34,6 +34,7 @@ type StorageClientNodeConnector struct {
 
 	clientAddr ClientAddressGetter
 	cborStore  cbor.IpldStore
+	escrow     *escrow.Reservations
 }
 
 type ClientAddressGetter func() (address.Address, error)
@@ -49,15 +50,19 @@ func NewStorageClientNodeConnector(
 	ob *message.Outbox,
 	ca ClientAddressGetter,
 	sv *appstate.Viewer,
+	er *escrow.Reservations,
 ) *StorageClientNodeConnector {
 	return &StorageClientNodeConnector{
 		connectorCommon: connectorCommon{cs, sv, w, s, ob},
 		cborStore:       cbor,
 		clientAddr:      ca,
+		escrow:          er,
 	}
 }
 
-// AddFunds adds storage market funds for a storage client
+// AddFunds adds storage market funds for a storage client, paid from the
+// client address. The funds may be added to the escrow of another address,
+// such as a key dedicated to signing deals.
 func (s *StorageClientNodeConnector) AddFunds(ctx context.Context, addr address.Address, amount abi.TokenAmount) (cid.Cid, error) {
 	clientAddr, err := s.clientAddr()
 	if err != nil {
@@ -66,18 +71,42 @@ func (s *StorageClientNodeConnector) AddFunds(ctx context.Context, addr address.
 	return s.addFunds(ctx, clientAddr, addr, amount)
 }
 
-// EnsureFunds compares the passed amount to the available balance for an address, and will add funds if necessary
+// EnsureFunds compares the passed amount to the available balance for an address, and will add funds if necessary.
+// The amount is reserved for the deal until it is published, so that the funds
+// available are not counted for several deals proposed at once.
 func (s *StorageClientNodeConnector) EnsureFunds(ctx context.Context, addr, walletAddr address.Address, amount abi.TokenAmount, tok shared.TipSetToken) (cid.Cid, error) {
 	balance, err := s.GetBalance(ctx, addr, tok)
 	if err != nil {
 		return cid.Undef, err
 	}
 
-	if balance.Available.LessThan(amount) {
-		return s.AddFunds(ctx, addr, big.Sub(amount, balance.Available))
-	}
+	mcid := cid.Undef
+	err = s.escrow.Ensure(addr, balance.Available, amount, func(missing abi.TokenAmount) error {
+		mcid, err = s.AddFunds(ctx, addr, missing)
+		return err
+	})
+	return mcid, err
+}
 
-	return cid.Undef, err
+// TrackFunds follows the escrow reserved for a deal by EnsureFunds, releasing
+// it once the deal has been published, when the market actor locks it, or has
+// failed.
+func (s *StorageClientNodeConnector) TrackFunds(event storagemarket.ClientEvent, deal storagemarket.ClientDeal) {
+	switch event {
+	case storagemarket.ClientEventFundsEnsured:
+		s.escrow.Assign(deal.ProposalCid, deal.Proposal.Client, deal.Proposal.ClientBalanceRequirement())
+	case storagemarket.ClientEventEnsureFundsFailed:
+		// funds were reserved only if adding them was initiated
+		if deal.AddFundsCid != nil {
+			s.escrow.Cancel(deal.Proposal.Client, deal.Proposal.ClientBalanceRequirement())
+		}
+	case storagemarket.ClientEventDealPublished,
+		storagemarket.ClientEventFailed,
+		storagemarket.ClientEventWriteProposalFailed,
+		storagemarket.ClientEventReadResponseFailed,
+		storagemarket.ClientEventStreamCloseError:
+		s.escrow.Release(deal.ProposalCid)
+	}
 }
 
 // ListClientDeals returns all deals published on chain for the given account
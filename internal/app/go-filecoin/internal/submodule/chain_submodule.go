@@ -4,13 +4,17 @@ import (
 	"context"
 
 	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/plumbing/cst"
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/slashing"
 	appstate "github.com/filecoin-project/go-filecoin/internal/pkg/state"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/actor/builtin"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vmsupport"
 )
@@ -24,8 +28,16 @@ type ChainSubmodule struct {
 	Sampler    *chain.Sampler
 	ActorState *appstate.TipSetStateViewer
 	Processor  *consensus.DefaultProcessor
+	Syscalls   vm.SyscallsImpl
 
 	StatusReporter *chain.StatusReporter
+
+	// Pruner discards old chain state on start and as the syncer sets new
+	// heads, nil unless pruning is enabled.
+	Pruner *chain.Pruner
+	// ReceiptIndex indexes the receipts of all messages on chain, nil unless
+	// archival mode is enabled.
+	ReceiptIndex *msg.ReceiptIndex
 }
 
 // xxx go back to using an interface here
@@ -43,6 +55,7 @@ type ChainSubmodule struct {
 */
 type chainRepo interface {
 	ChainDatastore() repo.Datastore
+	Config() *config.Config
 }
 
 type chainConfig interface {
@@ -55,12 +68,32 @@ func NewChainSubmodule(config chainConfig, repo chainRepo, blockstore *Blockstor
 	chainStatusReporter := chain.NewStatusReporter()
 	chainStore := chain.NewStore(repo.ChainDatastore(), blockstore.CborStore, chainStatusReporter, config.GenesisCid())
 
+	// networks enabling devnet actors run them in the VM
+	actors := builtin.DefaultActors
+	if params := repo.Config().NetworkParams; params != nil && params.DevnetActors {
+		actors = builtin.DevnetActors
+	}
+
 	actorState := appstate.NewTipSetStateViewer(chainStore, blockstore.CborStore)
 	messageStore := chain.NewMessageStore(blockstore.Blockstore)
-	chainState := cst.NewChainStateReadWriter(chainStore, messageStore, blockstore.Blockstore, builtin.DefaultActors)
+	chainState := cst.NewChainStateReadWriter(chainStore, messageStore, blockstore.Blockstore, actors)
 	faultChecker := slashing.NewFaultChecker(chainState)
 	syscalls := vmsupport.NewSyscalls(faultChecker, verifier.ProofVerifier)
-	processor := consensus.NewDefaultProcessor(syscalls, chainState)
+	processor := consensus.NewConfiguredProcessor(actors, syscalls, chainState)
+
+	var pruner *chain.Pruner
+	if chainCfg := repo.Config().Chain; chainCfg != nil && chainCfg.Prune {
+		var err error
+		pruner, err = chain.NewPruner(chainStore, blockstore.Blockstore, repo.ChainDatastore(), chainCfg.PruneDepth)
+		if err != nil {
+			return ChainSubmodule{}, err
+		}
+	}
+
+	var receiptIndex *msg.ReceiptIndex
+	if chainCfg := repo.Config().Chain; chainCfg != nil && chainCfg.Archive {
+		receiptIndex = msg.NewReceiptIndex(chainStore, messageStore, repo.ChainDatastore())
+	}
 
 	return ChainSubmodule{
 		ChainReader:    chainStore,
@@ -68,7 +101,10 @@ func NewChainSubmodule(config chainConfig, repo chainRepo, blockstore *Blockstor
 		ActorState:     actorState,
 		State:          chainState,
 		Processor:      processor,
+		Syscalls:       syscalls,
 		StatusReporter: chainStatusReporter,
+		Pruner:         pruner,
+		ReceiptIndex:   receiptIndex,
 	}, nil
 }
 
@@ -76,7 +112,18 @@ type chainNode interface {
 	Chain() ChainSubmodule
 }
 
-// Start loads the chain from disk.
+// Start loads the chain from disk and, if enabled, prunes state below the
+// configured depth. Pruning happens here, before the syncer is started, since it
+// must not race with chain processing; afterwards the syncer prunes as it sets
+// new heads.
 func (c *ChainSubmodule) Start(ctx context.Context, node chainNode) error {
-	return node.Chain().ChainReader.Load(ctx)
+	chn := node.Chain()
+	if err := chn.ChainReader.Load(ctx); err != nil {
+		return err
+	}
+	if chn.Pruner == nil {
+		return nil
+	}
+	_, err := chn.Pruner.Prune(ctx)
+	return errors.Wrap(err, "failed to prune chain")
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/util/moresync"
 	"github.com/ipfs/go-cid"
 	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/pkg/errors"
 )
 
@@ -33,7 +34,7 @@ type discoveryConfig interface {
 }
 
 // NewDiscoverySubmodule creates a new discovery submodule.
-func NewDiscoverySubmodule(ctx context.Context, config discoveryConfig, bsConfig *config.BootstrapConfig, network *NetworkSubmodule) (DiscoverySubmodule, error) {
+func NewDiscoverySubmodule(ctx context.Context, config discoveryConfig, bsConfig *config.BootstrapConfig, syncConfig *config.SyncConfig, network *NetworkSubmodule) (DiscoverySubmodule, error) {
 	periodStr := bsConfig.Period
 	period, err := time.ParseDuration(periodStr)
 	if err != nil {
@@ -53,13 +54,22 @@ func NewDiscoverySubmodule(ctx context.Context, config discoveryConfig, bsConfig
 	bootstrapper := discovery.NewBootstrapper(bpi, network.Host, network.Host.Network(), network.Router, minPeerThreshold, period)
 
 	// set up peer tracking
-	peerTracker := discovery.NewPeerTracker(network.Host.ID())
+	var trusted []peer.ID
+	for _, p := range syncConfig.TrustedPeers {
+		pid, err := peer.Decode(p)
+		if err != nil {
+			return DiscoverySubmodule{}, errors.Wrapf(err, "couldn't parse trusted peer %s", p)
+		}
+		trusted = append(trusted, pid)
+	}
+	peerTracker := discovery.NewPeerTracker(network.Host.ID(), trusted...)
+	peerTracker.SetTrustedOnly(syncConfig.TrustedOnly)
 
 	return DiscoverySubmodule{
 		Bootstrapper:   bootstrapper,
 		BootstrapReady: moresync.NewLatch(uint(minPeerThreshold)),
 		PeerTracker:    peerTracker,
-		HelloHandler:   discovery.NewHelloProtocolHandler(network.Host, config.GenesisCid(), network.NetworkName),
+		HelloHandler:   discovery.NewHelloProtocolHandler(network.Host, config.GenesisCid(), network.NetworkName, network.PeerScores.Penalize),
 	}, nil
 }
 
@@ -12,7 +12,6 @@ import (
 	graphsyncimpl "github.com/ipfs/go-graphsync/impl"
 	gsnet "github.com/ipfs/go-graphsync/network"
 	gsstoreutil "github.com/ipfs/go-graphsync/storeutil"
-	exchange "github.com/ipfs/go-ipfs-exchange-interface"
 	offroute "github.com/ipfs/go-ipfs-routing/offline"
 	cbor "github.com/ipfs/go-ipld-cbor"
 	"github.com/libp2p/go-libp2p"
@@ -30,6 +29,7 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/discovery"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/net"
@@ -48,13 +48,25 @@ type NetworkSubmodule struct {
 	pubsub *libp2pps.PubSub
 
 	// TODO: split chain bitswap from storage bitswap (issue: ???)
-	Bitswap exchange.Interface
+	Bitswap *bitswap.Bitswap
 
 	Network *net.Network
 
 	GraphExchange graphsync.GraphExchange
+
+	// TransferHost is the host data transfers open and handle their streams
+	// through, throttled by TransferThrottle.
+	TransferHost     host.Host
+	TransferThrottle *net.Throttle
+
+	// PeerScores scores the offenses of peers and bans abusive ones.
+	PeerScores *net.PeerScores
 }
 
+// defaultBanDuration is how long peers are banned when the config leaves it
+// unset.
+const defaultBanDuration = time.Hour
+
 type blankValidator struct{}
 
 func (blankValidator) Validate(_ string, _ []byte) error        { return nil }
@@ -127,15 +139,43 @@ func NewNetworkSubmodule(ctx context.Context, config networkConfig, repo network
 	}
 
 	// set up bitswap
+	bitswapCfg := repo.Config().Bitswap
 	nwork := bsnet.NewFromIpfsHost(peerHost, router)
 	//nwork := bsnet.NewFromIpfsHost(innerHost, router)
-	bswap := bitswap.New(ctx, nwork, blockstore.Blockstore)
+	if bitswapCfg.PeerBandwidthLimit > 0 {
+		nwork = net.NewLimitedBitswapNetwork(nwork, bitswapCfg.PeerBandwidthLimit, clock.NewSystemClock())
+	}
+	bswap := bitswap.New(ctx, nwork, blockstore.Blockstore, bitswap.ProvideEnabled(providesAll(bitswapCfg))).(*bitswap.Bitswap)
 
 	// set up pinger
 	pingService := ping.NewPingService(peerHost)
 
+	// set up the throttle of data transfers
+	transferCfg := repo.Config().Transfer
+	throttle := net.NewThrottle(net.TransferLimits{
+		Upload:       transferCfg.UploadLimit,
+		Download:     transferCfg.DownloadLimit,
+		PeerUpload:   transferCfg.PeerUploadLimit,
+		PeerDownload: transferCfg.PeerDownloadLimit,
+	}, clock.NewSystemClock())
+	transferHost := net.NewThrottledHost(peerHost, throttle)
+
+	// set up the scores and bans of peers
+	banDuration := defaultBanDuration
+	if d := repo.Config().Swarm.BanDuration; d != "" {
+		banDuration, err = time.ParseDuration(d)
+		if err != nil {
+			return NetworkSubmodule{}, errors.Wrapf(err, "invalid swarm.banDuration %s", d)
+		}
+	}
+	peerScores, err := net.NewPeerScores(repo.Datastore(), banDuration, clock.NewSystemClock())
+	if err != nil {
+		return NetworkSubmodule{}, errors.Wrap(err, "failed to load peer scores")
+	}
+	peerScores.Enforce(peerHost)
+
 	// set up graphsync
-	graphsyncNetwork := gsnet.NewFromLibp2pHost(peerHost)
+	graphsyncNetwork := gsnet.NewFromLibp2pHost(transferHost)
 	loader := gsstoreutil.LoaderForBlockstore(blockstore.Blockstore)
 	storer := gsstoreutil.StorerForBlockstore(blockstore.Blockstore)
 	gsync := graphsyncimpl.New(ctx, graphsyncNetwork, loader, storer, graphsyncimpl.RejectAllRequestsByDefault())
@@ -144,16 +184,25 @@ func NewNetworkSubmodule(ctx context.Context, config networkConfig, repo network
 	network := net.New(peerHost, net.NewRouter(router), bandwidthTracker, net.NewPinger(peerHost, pingService))
 	// build the network submdule
 	return NetworkSubmodule{
-		NetworkName:   networkName,
-		Host:          peerHost,
-		Router:        router,
-		pubsub:        gsub,
-		Bitswap:       bswap,
-		GraphExchange: gsync,
-		Network:       network,
+		NetworkName:      networkName,
+		Host:             peerHost,
+		Router:           router,
+		pubsub:           gsub,
+		Bitswap:          bswap,
+		GraphExchange:    gsync,
+		Network:          network,
+		TransferHost:     transferHost,
+		TransferThrottle: throttle,
+		PeerScores:       peerScores,
 	}, nil
 }
 
+// providesAll returns whether bitswap should announce every block as it is added.
+// Root announcements for the "roots" strategy are made on client import.
+func providesAll(cfg *config.BitswapConfig) bool {
+	return cfg.ProvideStrategy == "" || cfg.ProvideStrategy == config.ProvideAll
+}
+
 func retrieveNetworkName(ctx context.Context, genCid cid.Cid, cborStore cbor.IpldStore) (string, error) {
 	var genesis block.Block
 	err := cborStore.Get(ctx, genCid, &genesis)
@@ -15,7 +15,6 @@ import (
 
 	retmkt "github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/connectors/retrieval_market"
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/plumbing/cst"
-	"github.com/filecoin-project/go-filecoin/internal/pkg/piecemanager"
 )
 
 // RetrievalProviderDSPrefix is a prefix for all datastore keys related to the retrieval provider
@@ -43,13 +42,13 @@ func NewRetrievalProtocolSubmodule(
 	providerAddr address.Address,
 	signer retmkt.RetrievalSigner,
 	pchMgrAPI retmkt.PaychMgrAPI,
-	pieceManager piecemanager.PieceManager,
+	unsealer retmkt.UnsealerAPI,
 ) (*RetrievalProtocolSubmodule, error) {
 
 	retrievalDealPieceStore := piecestore.NewPieceStore(namespace.Wrap(ds, datastore.NewKey(PieceStoreDSPrefix)))
 
 	netwk := network.NewFromLibp2pHost(host)
-	pnode := retmkt.NewRetrievalProviderConnector(netwk, pieceManager, bs, pchMgrAPI, nil)
+	pnode := retmkt.NewRetrievalProviderConnector(netwk, unsealer, bs, pchMgrAPI, nil)
 
 	marketProvider, err := impl.NewProvider(providerAddr, pnode, netwk, retrievalDealPieceStore, bs, namespace.Wrap(ds, datastore.NewKey(RetrievalProviderDSPrefix)))
 	if err != nil {
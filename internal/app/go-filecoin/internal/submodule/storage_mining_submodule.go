@@ -3,6 +3,7 @@ package submodule
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/filecoin-project/go-address"
 	sectorstorage "github.com/filecoin-project/sector-storage"
@@ -18,13 +19,16 @@ import (
 	fsmnodeconnector "github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/connectors/fsm_node"
 	fsmstorage "github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/connectors/fsm_storage"
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/connectors/sectors"
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/paths"
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chainsampler"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/journal"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/piecemanager"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/poster"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/postgenerator"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/scrubber"
 	appstate "github.com/filecoin-project/go-filecoin/internal/pkg/state"
 )
 
@@ -39,9 +43,14 @@ type StorageMiningSubmodule struct {
 	// PoStGenerator generates election PoSts
 	PoStGenerator postgenerator.PoStGenerator
 
-	hs     *chainsampler.HeightThresholdScheduler
-	fsm    *fsm.Sealing
-	poster *poster.Poster
+	// Scrubber checks the sealed sectors ahead of their PoSt deadlines.
+	Scrubber *scrubber.Scrubber
+
+	hs          *chainsampler.HeightThresholdScheduler
+	fsm         *fsm.Sealing
+	poster      *poster.Poster
+	repo        repo.Repo
+	scrubCancel context.CancelFunc
 }
 
 // NewStorageMiningSubmodule creates a new storage mining submodule.
@@ -55,6 +64,8 @@ func NewStorageMiningSubmodule(
 	sealProofType abi.RegisteredProof,
 	r repo.Repo,
 	postGeneratorOverride postgenerator.PoStGenerator,
+	verifier ffiwrapper.Verifier,
+	jrl journal.Journal,
 ) (*StorageMiningSubmodule, error) {
 	chainThresholdScheduler := chainsampler.NewHeightThresholdScheduler(c.ChainReader)
 
@@ -94,13 +105,28 @@ func NewStorageMiningSubmodule(
 	fsmConnector := fsmeventsconnector.New(chainThresholdScheduler, c.State)
 	fsm := fsm.New(ncn, fsmConnector, minerAddrID, ds, mgr, sid, ffiwrapper.ProofVerifier, &pcp)
 
-	bke := piecemanager.NewFiniteStateMachineBackEnd(fsm, sid)
+	repoPath, err := r.Path()
+	if err != nil {
+		return nil, err
+	}
+	sectorPath, err := paths.GetSectorPath(r.Config().SectorBase.RootDirPath, repoPath)
+	if err != nil {
+		return nil, err
+	}
+	pieces, err := piecemanager.NewPieceStore(paths.PieceStoreDir(sectorPath))
+	if err != nil {
+		return nil, err
+	}
+
+	bke := piecemanager.NewFiniteStateMachineBackEnd(fsm, sid, pieces)
 
 	modu := &StorageMiningSubmodule{
 		PieceManager: &bke,
 		hs:           chainThresholdScheduler,
 		fsm:          fsm,
 		poster:       poster.NewPoster(minerAddr, m.Outbox, mgr, c.State, stateViewer, mw),
+		Scrubber:     scrubber.NewScrubber(minerAddrID, c.State, mgr, verifier, m.Outbox, jrl.Topic("scrubber")),
+		repo:         r,
 	}
 
 	// allow the caller to provide a thing which generates fake PoSts
@@ -127,6 +153,12 @@ func (s *StorageMiningSubmodule) Start(ctx context.Context) error {
 		return err
 	}
 
+	if interval := time.Duration(s.repo.Config().Mining.ScrubIntervalSeconds) * time.Second; interval > 0 {
+		var scrubCtx context.Context
+		scrubCtx, s.scrubCancel = context.WithCancel(context.Background())
+		go s.Scrubber.Run(scrubCtx, interval)
+	}
+
 	s.started = true
 	return nil
 }
@@ -146,6 +178,10 @@ func (s *StorageMiningSubmodule) Stop(ctx context.Context) error {
 	}
 
 	s.poster.StopPoSting()
+	if s.scrubCancel != nil {
+		s.scrubCancel()
+		s.scrubCancel = nil
+	}
 	s.started = false
 	return nil
 }
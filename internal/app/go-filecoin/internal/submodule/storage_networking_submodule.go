@@ -3,18 +3,83 @@ package submodule
 import (
 	"context"
 
+	"github.com/filecoin-project/specs-actors/actors/abi"
 	exchange "github.com/ipfs/go-ipfs-exchange-interface"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/asksub"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/bidsub"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/pubsub"
 )
 
 // StorageNetworkingSubmodule enhances the `Node` with data transfer capabilities.
 type StorageNetworkingSubmodule struct {
 	// Exchange is the interface for fetching data from other nodes.
 	Exchange exchange.Interface
+
+	// AskTopic is the topic on which miners announce their asks.
+	AskTopic *pubsub.Topic
+	AskSub   pubsub.Subscription
+	// AskCache holds the asks announced on the ask topic.
+	AskCache *asksub.Cache
+
+	// RequestTopic is the topic on which clients publish storage requests.
+	RequestTopic *pubsub.Topic
+	RequestSub   pubsub.Subscription
+	// BidTopic is the topic on which miners bid on storage requests.
+	BidTopic *pubsub.Topic
+	BidSub   pubsub.Subscription
 }
 
 // NewStorgeNetworkingSubmodule creates a new storage networking submodule.
-func NewStorgeNetworkingSubmodule(ctx context.Context, network *NetworkSubmodule) (StorageNetworkingSubmodule, error) {
+func NewStorgeNetworkingSubmodule(ctx context.Context, network *NetworkSubmodule, chain *ChainSubmodule) (StorageNetworkingSubmodule, error) {
+	// register ask validation on pubsub
+	head := func() (abi.ChainEpoch, error) {
+		ts, err := chain.ChainReader.GetTipSet(chain.ChainReader.GetHead())
+		if err != nil {
+			return 0, err
+		}
+		return ts.Height()
+	}
+	atv := asksub.NewAskTopicValidator(func() (asksub.StateView, error) {
+		return chain.State.StateView(chain.ChainReader.GetHead())
+	}, head)
+	if err := network.pubsub.RegisterTopicValidator(atv.Topic(network.NetworkName), atv.Validator(), atv.Opts()...); err != nil {
+		return StorageNetworkingSubmodule{}, errors.Wrap(err, "failed to register ask validator")
+	}
+	topic, err := network.pubsub.Join(asksub.Topic(network.NetworkName))
+	if err != nil {
+		return StorageNetworkingSubmodule{}, err
+	}
+
+	// register storage request and bid validation on pubsub
+	headView := func() (bidsub.StateView, error) {
+		return chain.State.StateView(chain.ChainReader.GetHead())
+	}
+	rtv := bidsub.NewRequestTopicValidator(headView)
+	if err := network.pubsub.RegisterTopicValidator(rtv.Topic(network.NetworkName), rtv.Validator(), rtv.Opts()...); err != nil {
+		return StorageNetworkingSubmodule{}, errors.Wrap(err, "failed to register storage request validator")
+	}
+	requestTopic, err := network.pubsub.Join(bidsub.RequestTopic(network.NetworkName))
+	if err != nil {
+		return StorageNetworkingSubmodule{}, err
+	}
+	btv := bidsub.NewBidTopicValidator(headView)
+	if err := network.pubsub.RegisterTopicValidator(btv.Topic(network.NetworkName), btv.Validator(), btv.Opts()...); err != nil {
+		return StorageNetworkingSubmodule{}, errors.Wrap(err, "failed to register bid validator")
+	}
+	bidTopic, err := network.pubsub.Join(bidsub.BidTopic(network.NetworkName))
+	if err != nil {
+		return StorageNetworkingSubmodule{}, err
+	}
+
 	return StorageNetworkingSubmodule{
 		Exchange: network.Bitswap,
+		AskTopic: pubsub.NewTopic(topic),
+		// AskSub: nil,
+		AskCache: asksub.NewCache(),
+
+		RequestTopic: pubsub.NewTopic(requestTopic),
+		BidTopic:     pubsub.NewTopic(bidTopic),
 	}, nil
 }
@@ -8,7 +8,6 @@ import (
 	"github.com/filecoin-project/go-storedcounter"
 
 	"github.com/filecoin-project/go-address"
-	datatransfer "github.com/filecoin-project/go-data-transfer"
 	graphsyncimpl "github.com/filecoin-project/go-data-transfer/impl/graphsync"
 	"github.com/filecoin-project/go-fil-markets/filestore"
 	"github.com/filecoin-project/go-fil-markets/piecestore"
@@ -19,6 +18,7 @@ import (
 	"github.com/filecoin-project/go-fil-markets/storagemarket/impl/storedask"
 	smnetwork "github.com/filecoin-project/go-fil-markets/storagemarket/network"
 	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/crypto"
 	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-datastore/namespace"
 	"github.com/ipfs/go-graphsync"
@@ -29,8 +29,17 @@ import (
 	storagemarketconnector "github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/connectors/storage_market"
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/paths"
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/plumbing/msg"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/cborutil"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/asksub"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/bidsub"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/piecemanager"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/escrow"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/pin"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/reconcile"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/replication"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/transfer"
 	appstate "github.com/filecoin-project/go-filecoin/internal/pkg/state"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 )
@@ -58,15 +67,28 @@ const AskDSKey = "/deals/latest-ask"
 type StorageProtocolSubmodule struct {
 	StorageClient    iface.StorageClient
 	StorageProvider  iface.StorageProvider
-	dataTransfer     datatransfer.Manager
+	dataTransfer     *transfer.Manager
+	replication      *replication.Manager
+	pins             *pin.Manager
 	requestValidator *smvalid.UnifiedRequestValidator
 	pieceManager     piecemanager.PieceManager
+	asks             *asksub.Cache
+	announcer        *asksub.Announcer
+	requests         *bidsub.Book
+	bidder           *bidsub.Bidder
+	escrow           *escrow.Reservations
+	reconciler       *reconcile.Reconciler
+	dealStatus       *reconcile.Server
 }
 
 // NewStorageProtocolSubmodule creates a new storage protocol submodule.
+// Deals are proposed and signed by the address returned by dealAddr, and the
+// market escrow of that address is funded from the address returned by
+// clientAddr.
 func NewStorageProtocolSubmodule(
 	ctx context.Context,
 	clientAddr storagemarketconnector.ClientAddressGetter,
+	dealAddr storagemarketconnector.ClientAddressGetter,
 	c *ChainSubmodule,
 	m *MessagingSubmodule,
 	mw *msg.Waiter,
@@ -76,10 +98,16 @@ func NewStorageProtocolSubmodule(
 	bs blockstore.Blockstore,
 	gsync graphsync.GraphExchange,
 	stateViewer *appstate.Viewer,
+	transferRestarts uint,
+	replicationCfg *config.ReplicationConfig,
+	asks *asksub.Cache,
+	requestTopic bidsub.Publisher,
 ) (*StorageProtocolSubmodule, error) {
-	cnode := storagemarketconnector.NewStorageClientNodeConnector(cborutil.NewIpldStore(bs), c.State, mw, s, m.Outbox, clientAddr, stateViewer)
+	reservations := escrow.NewReservations()
+	cnode := storagemarketconnector.NewStorageClientNodeConnector(cborutil.NewIpldStore(bs), c.State, mw, s, m.Outbox, clientAddr, stateViewer, reservations)
 	dtStoredCounter := storedcounter.New(ds, datastore.NewKey(DTCounterDSKey))
-	dt := graphsyncimpl.NewGraphSyncDataTransfer(h, gsync, dtStoredCounter)
+	gsdt := graphsyncimpl.NewGraphSyncDataTransfer(h, gsync, dtStoredCounter)
+	dt := transfer.NewManager(h.ID(), gsdt, gsync, int(transferRestarts))
 	clientDs := namespace.Wrap(ds, datastore.NewKey(ClientDSPrefix))
 	validator := smvalid.NewUnifiedRequestValidator(nil, statestore.New(clientDs))
 	err := dt.RegisterVoucherType(&smvalid.StorageDataTransferVoucher{}, validator)
@@ -93,12 +121,52 @@ func NewStorageProtocolSubmodule(
 		return nil, errors.Wrap(err, "error creating storage client")
 	}
 
+	sealProofType := func(ctx context.Context, maddr address.Address, tsk block.TipSetKey) (abi.RegisteredProof, error) {
+		view, err := c.ActorState.StateView(tsk)
+		if err != nil {
+			return 0, err
+		}
+		info, err := view.MinerInfo(ctx, maddr)
+		if err != nil {
+			return 0, err
+		}
+		return info.SealProofType, nil
+	}
+	replicator, err := replication.NewManager(client, c.State, sealProofType, dealAddr, ds, replicationCfg.CheckInterval, replicationCfg.StartDelay)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating replication manager")
+	}
+	pins, err := pin.NewManager(bs, client, replicator, ds)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating client data manager")
+	}
+
+	head := func() (abi.ChainEpoch, error) {
+		ts, err := c.ChainReader.GetTipSet(c.ChainReader.GetHead())
+		if err != nil {
+			return 0, err
+		}
+		return ts.Height()
+	}
+	requests := bidsub.NewBook(requestTopic, dealAddr, head, func(ctx context.Context, signer address.Address, data []byte) (crypto.Signature, error) {
+		return s.SignBytes(ctx, data, signer)
+	})
+
 	sm := &StorageProtocolSubmodule{
 		StorageClient:    client,
 		dataTransfer:     dt,
+		replication:      replicator,
+		pins:             pins,
 		requestValidator: validator,
+		asks:             asks,
+		requests:         requests,
+		escrow:           reservations,
+		reconciler: reconcile.NewReconciler(h, client, func() (reconcile.ChainView, error) {
+			return c.ActorState.StateView(c.ChainReader.GetHead())
+		}),
 	}
 	sm.StorageClient.SubscribeToEvents(cnode.EventLogger)
+	sm.StorageClient.SubscribeToEvents(cnode.TrackFunds)
 	return sm, nil
 }
 
@@ -117,6 +185,9 @@ func (sm *StorageProtocolSubmodule) AddStorageProvider(
 	repoPath string,
 	sealProofType abi.RegisteredProof,
 	stateViewer *appstate.Viewer,
+	askTopic asksub.Publisher,
+	bidTopic bidsub.Publisher,
+	capacity func() uint64,
 ) error {
 	sm.pieceManager = pm
 
@@ -146,10 +217,49 @@ func (sm *StorageProtocolSubmodule) AddStorageProvider(
 		return err
 	}
 	sm.StorageProvider, err = impl.NewProvider(smnetwork.NewFromLibp2pHost(h), providerDs, bs, fs, ps, sm.dataTransfer, pnode, minerAddr, sealProofType, storedAsk)
-	if err == nil {
-		sm.StorageProvider.SubscribeToEvents(pnode.EventLogger)
+	if err != nil {
+		return err
 	}
-	return err
+	sm.StorageProvider.SubscribeToEvents(pnode.EventLogger)
+	sm.dealStatus = reconcile.NewServer(h, sm.StorageProvider.ListLocalDeals, pm.ListPieces, pm.SealingProgress)
+	sm.dealStatus.Start()
+
+	ask := func() *iface.SignedStorageAsk {
+		asks := sm.StorageProvider.ListAsks(minerAddr)
+		if len(asks) == 0 {
+			return nil
+		}
+		return asks[len(asks)-1]
+	}
+	head := func() (abi.ChainEpoch, error) {
+		ts, err := c.ChainReader.GetTipSet(c.ChainReader.GetHead())
+		if err != nil {
+			return 0, err
+		}
+		return ts.Height()
+	}
+	sign := func(ctx context.Context, data []byte) (crypto.Signature, error) {
+		tok, _, err := pnode.GetChainHead(ctx)
+		if err != nil {
+			return crypto.Signature{}, err
+		}
+		worker, err := pnode.GetMinerWorkerAddress(ctx, minerAddr, tok)
+		if err != nil {
+			return crypto.Signature{}, err
+		}
+		sig, err := pnode.SignBytes(ctx, worker, data)
+		if err != nil {
+			return crypto.Signature{}, err
+		}
+		return *sig, nil
+	}
+	sm.announcer = asksub.NewAnnouncer(askTopic, ask, h.ID(), capacity, head, sign)
+	sectorSize, err := sealProofType.SectorSize()
+	if err != nil {
+		return err
+	}
+	sm.bidder = bidsub.NewBidder(bidTopic, minerAddr, h.ID(), sectorSize, ask, capacity, head, sign)
+	return nil
 }
 
 func (sm *StorageProtocolSubmodule) Provider() (iface.StorageProvider, error) {
@@ -163,6 +273,62 @@ func (sm *StorageProtocolSubmodule) Client() iface.StorageClient {
 	return sm.StorageClient
 }
 
+// Replication returns the manager of the pieces replicated with several miners.
+func (sm *StorageProtocolSubmodule) Replication() *replication.Manager {
+	return sm.replication
+}
+
+// Pins returns the manager of the client data, pinned or collected.
+func (sm *StorageProtocolSubmodule) Pins() *pin.Manager {
+	return sm.pins
+}
+
+// Transfers returns the data transfers of deal payloads.
+func (sm *StorageProtocolSubmodule) Transfers() *transfer.Manager {
+	return sm.dataTransfer
+}
+
+// Escrow returns the market escrow reserved by the deals proposed by this node.
+func (sm *StorageProtocolSubmodule) Escrow() *escrow.Reservations {
+	return sm.escrow
+}
+
+// AskCache returns the cache of the asks announced by miners.
+func (sm *StorageProtocolSubmodule) AskCache() *asksub.Cache {
+	return sm.asks
+}
+
+// AskAnnouncer returns the announcer of the miner's ask, nil until mining
+// has been set up.
+func (sm *StorageProtocolSubmodule) AskAnnouncer() *asksub.Announcer {
+	return sm.announcer
+}
+
+// StorageRequests returns the book of the storage requests published by this
+// node and the bids on them.
+func (sm *StorageProtocolSubmodule) StorageRequests() *bidsub.Book {
+	return sm.requests
+}
+
+// Bidder returns the bidder on the storage requests of clients, nil until
+// mining has been set up.
+func (sm *StorageProtocolSubmodule) Bidder() *bidsub.Bidder {
+	return sm.bidder
+}
+
+// Reconciler returns the reconciler of the deals of the client with miners.
+func (sm *StorageProtocolSubmodule) Reconciler() *reconcile.Reconciler {
+	return sm.reconciler
+}
+
+// AnnounceAsk announces the miner's current ask and capacity on the ask topic.
+func (sm *StorageProtocolSubmodule) AnnounceAsk(ctx context.Context) error {
+	if sm.announcer == nil {
+		return errors.New("Mining has not been started so asks cannot be announced")
+	}
+	return sm.announcer.Announce(ctx)
+}
+
 func (sm *StorageProtocolSubmodule) PieceManager() (piecemanager.PieceManager, error) {
 	if sm.StorageProvider == nil {
 		return nil, errors.New("Mining has not been started so piece manager is not available")
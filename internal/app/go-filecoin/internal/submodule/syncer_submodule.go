@@ -8,6 +8,7 @@ import (
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-graphsync"
 	"github.com/libp2p/go-libp2p-core/peer"
+	libp2pps "github.com/libp2p/go-libp2p-pubsub"
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
@@ -17,6 +18,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/drand"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/net/blocksub"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/net/pubsub"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/slashing"
@@ -25,8 +27,13 @@ import (
 
 // SyncerSubmodule enhances the node with chain syncing capabilities
 type SyncerSubmodule struct {
-	BlockTopic       *pubsub.Topic
-	BlockSub         pubsub.Subscription
+	BlockTopic *pubsub.Topic
+	BlockSub   pubsub.Subscription
+	// BlockRelay rebuilds blocks announced on the block topic from local
+	// messages.
+	BlockRelay *blocksub.Reconstructor
+	// BlockSyntax validates the syntax of block headers.
+	BlockSyntax      consensus.BlockSyntaxValidator
 	ChainSelector    nodeChainSelector
 	Consensus        consensus.Protocol
 	FaultDetector    slashing.ConsensusFaultDetector
@@ -63,8 +70,19 @@ func NewSyncerSubmodule(ctx context.Context, config syncerConfig, blockstore *Bl
 	}
 
 	// register block validation on pubsub
+	// peers forwarding invalid blocks are penalized.
 	btv := blocksub.NewBlockTopicValidator(blkValid)
-	if err := network.pubsub.RegisterTopicValidator(btv.Topic(network.NetworkName), btv.Validator(), btv.Opts()...); err != nil {
+	validateBlock := btv.Validator()
+	penalizingValidator := func(ctx context.Context, p peer.ID, msg *libp2pps.Message) bool {
+		if !validateBlock(ctx, p, msg) {
+			if p != network.Host.ID() {
+				network.PeerScores.Penalize(p, net.OffenseInvalidBlock)
+			}
+			return false
+		}
+		return true
+	}
+	if err := network.pubsub.RegisterTopicValidator(btv.Topic(network.NetworkName), penalizingValidator, btv.Opts()...); err != nil {
 		return SyncerSubmodule{}, errors.Wrap(err, "failed to register block validator")
 	}
 
@@ -104,14 +122,18 @@ func NewSyncerSubmodule(ctx context.Context, config syncerConfig, blockstore *Bl
 	faultCh := make(chan slashing.ConsensusFault)
 	faultDetector := slashing.NewConsensusFaultDetector(faultCh)
 
-	chainSyncManager, err := chainsync.NewManager(nodeConsensus, blkValid, nodeChainSelector, chn.ChainReader, chn.MessageStore, fetcher, config.ChainClock(), faultDetector)
+	chainSyncManager, err := chainsync.NewManager(nodeConsensus, blkValid, nodeChainSelector, chn.ChainReader, chn.MessageStore, fetcher, config.ChainClock(), faultDetector, discovery.PeerTracker)
 	if err != nil {
 		return SyncerSubmodule{}, err
 	}
+	if chn.Pruner != nil {
+		chainSyncManager.SetPruner(chn.Pruner)
+	}
 
 	return SyncerSubmodule{
 		BlockTopic: pubsub.NewTopic(topic),
 		// BlockSub: nil,
+		BlockSyntax:      blkValid,
 		Consensus:        nodeConsensus,
 		ChainSelector:    nodeChainSelector,
 		ChainSyncManager: &chainSyncManager,
@@ -3,6 +3,9 @@ package submodule
 import (
 	"context"
 
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+
 	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
@@ -12,11 +15,13 @@ import (
 
 // WalletSubmodule enhances the `Node` with a "Wallet" and FIL transfer capabilities.
 type WalletSubmodule struct {
-	Wallet *wallet.Wallet
-	Signer types.Signer
+	Wallet      *wallet.Wallet
+	Signer      types.Signer
+	AddressBook *wallet.AddressBook
 }
 
 type walletRepo interface {
+	Datastore() datastore.Batching
 	WalletDatastore() repo.Datastore
 }
 
@@ -28,8 +33,14 @@ func NewWalletSubmodule(ctx context.Context, repo walletRepo, chain *ChainSubmod
 	}
 	fcWallet := wallet.New(backend)
 
+	addressBook, err := wallet.NewAddressBook(namespace.Wrap(repo.Datastore(), datastore.NewKey(wallet.AddressBookDSPrefix)))
+	if err != nil {
+		return WalletSubmodule{}, errors.Wrap(err, "failed to load address book")
+	}
+
 	return WalletSubmodule{
-		Wallet: fcWallet,
-		Signer: state.NewSigner(chain.ActorState, chain.ChainReader, fcWallet),
+		Wallet:      fcWallet,
+		Signer:      state.NewSigner(chain.ActorState, chain.ChainReader, fcWallet),
+		AddressBook: addressBook,
 	}, nil
 }
@@ -0,0 +1,21 @@
+package node
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/asksub"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/pubsub"
+)
+
+// handleAskSub caches the asks miners announce on the ask topic. Announcements
+// have been validated by the topic validator.
+func (node *Node) handleAskSub(ctx context.Context, msg pubsub.Message) error {
+	a, err := asksub.DecodePayload(msg.GetData())
+	if err != nil {
+		return err
+	}
+	if node.StorageNetworking.AskCache.Update(a) {
+		log.Debugf("cached ask %d of miner %s from peer %s", a.Ask.Ask.SeqNo, a.Ask.Ask.Miner, msg.GetSender())
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package node
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/bidsub"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/pubsub"
+)
+
+// handleStorageRequestSub bids on the storage requests of clients when the
+// node is a miner with auto bidding enabled. Requests have been validated by
+// the topic validator.
+func (node *Node) handleStorageRequestSub(ctx context.Context, msg pubsub.Message) error {
+	bidder := node.StorageProtocol.Bidder()
+	if bidder == nil || !node.Repo.Config().Mining.AutoBid {
+		return nil
+	}
+	r, err := bidsub.DecodeRequestPayload(msg.GetData())
+	if err != nil {
+		return err
+	}
+	bid, err := bidder.HandleRequest(ctx, r)
+	if err != nil {
+		log.Debugf("not bidding on storage request %s from peer %s: %s", r.ID, msg.GetSender(), err)
+		return nil
+	}
+	log.Infof("bid %s on storage request %s of client %s", bid.Bid.Price, r.ID, r.Request.Client)
+	return nil
+}
+
+// handleBidSub keeps the bids of miners on the storage requests of the node.
+// Bids have been validated by the topic validator.
+func (node *Node) handleBidSub(ctx context.Context, msg pubsub.Message) error {
+	b, err := bidsub.DecodeBidPayload(msg.GetData())
+	if err != nil {
+		return err
+	}
+	if node.StorageProtocol.StorageRequests().AddBid(b) {
+		log.Debugf("received bid of miner %s on storage request %s from peer %s", b.Bid.Miner, b.Bid.Request, msg.GetSender())
+	}
+	return nil
+}
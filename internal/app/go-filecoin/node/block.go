@@ -69,12 +69,18 @@ func (node *Node) handleBlockSub(ctx context.Context, msg pubsub.Message) (err e
 	log.Infof("Received new block %s from peer %s", header.Cid(), sender)
 	log.Debugf("Received new block sender: %s source: %s, %s", sender, source, header)
 
-	// The block we went to all that effort decoding is dropped on the floor!
-	// Don't be too quick to change that, though: the syncer re-fetching the block
-	// is currently critical to reliable validation.
-	// See https://github.com/filecoin-project/go-filecoin/issues/2962
+	// Rebuild the block from the messages known locally, fetching only those
+	// missing, so that the syncer finds it in the store rather than fetching
+	// the whole block from the network. The fetch is bounded so as not to hold
+	// up the topic: the syncer fetches the blocks not reconstructed, and still
+	// loads and validates the block from the store.
 	// TODO Implement principled trusting of ChainInfo's
 	// to address in #2674
+	if node.syncer.BlockRelay != nil {
+		if err := node.syncer.BlockRelay.Reconstruct(ctx, &payload); err != nil {
+			log.Infof("failed to reconstruct block %s, the syncer will fetch it: %s", header.Cid(), err)
+		}
+	}
 	chainInfo := block.NewChainInfo(source, sender, block.NewTipSetKey(header.Cid()), header.Height)
 	err = node.syncer.ChainSyncManager.BlockProposer().SendGossipBlock(chainInfo)
 	if err != nil {
@@ -21,10 +21,14 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/plumbing/dag"
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/conformance"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/drand"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/journal"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/ledger"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/blocksub"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/postgenerator"
 	drandapi "github.com/filecoin-project/go-filecoin/internal/pkg/protocol/drand"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage"
@@ -35,18 +39,20 @@ import (
 
 // Builder is a helper to aid in the construction of a filecoin node.
 type Builder struct {
-	blockTime   time.Duration
-	libp2pOpts  []libp2p.Option
-	offlineMode bool
-	verifier    ffiwrapper.Verifier
-	postGen     postgenerator.PoStGenerator
-	propDelay   time.Duration
-	repo        repo.Repo
-	journal     journal.Journal
-	isRelay     bool
-	chainClock  clock.ChainEpochClock
-	genCid      cid.Cid
-	drand       drand.IFace
+	blockTime    time.Duration
+	libp2pOpts   []libp2p.Option
+	offlineMode  bool
+	verifier     ffiwrapper.Verifier
+	postGen      postgenerator.PoStGenerator
+	propDelay    time.Duration
+	clockSkew    time.Duration
+	drainTimeout time.Duration
+	repo         repo.Repo
+	journal      journal.Journal
+	isRelay      bool
+	chainClock   clock.ChainEpochClock
+	genCid       cid.Cid
+	drand        drand.IFace
 }
 
 // BuilderOpt is an option for building a filecoin node.
@@ -84,6 +90,23 @@ func PropagationDelay(propDelay time.Duration) BuilderOpt {
 	}
 }
 
+// ClockSkew offsets the time of the node's chain clock, for testing.
+func ClockSkew(skew time.Duration) BuilderOpt {
+	return func(c *Builder) error {
+		c.clockSkew = skew
+		return nil
+	}
+}
+
+// DrainTimeout bounds each step of draining the node when it stops. Zero, the
+// default, leaves the steps unbounded.
+func DrainTimeout(timeout time.Duration) BuilderOpt {
+	return func(c *Builder) error {
+		c.drainTimeout = timeout
+		return nil
+	}
+}
+
 // Libp2pOptions returns a builder option that sets up the libp2p node
 func Libp2pOptions(opts ...libp2p.Option) BuilderOpt {
 	return func(b *Builder) error {
@@ -145,12 +168,8 @@ func MonkeyPatchNetworkParamsOption(params *config.NetworkParamsConfig) BuilderO
 			power.ConsensusMinerMinPower = big.NewIntUnsigned(params.ConsensusMinerMinPower)
 		}
 		if len(params.ReplaceProofTypes) > 0 {
-			newSupportedTypes := make(map[abi.RegisteredProof]struct{})
-			for _, proofType := range params.ReplaceProofTypes {
-				newSupportedTypes[abi.RegisteredProof(proofType)] = struct{}{}
-			}
 			// Switch reference rather than mutate in place to avoid concurrent map mutation (in tests).
-			miner.SupportedProofTypes = newSupportedTypes
+			miner.SupportedProofTypes = proofTypeSet(params.ReplaceProofTypes)
 		}
 		return nil
 	}
@@ -210,8 +229,10 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 
 	// create the node
 	nd := &Node{
-		OfflineMode: b.offlineMode,
-		Repo:        b.repo,
+		OfflineMode:  b.offlineMode,
+		Repo:         b.repo,
+		journal:      b.journal,
+		drainTimeout: b.drainTimeout,
 	}
 
 	nd.Blockstore, err = submodule.NewBlockstoreSubmodule(ctx, b.repo)
@@ -224,12 +245,13 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 		return nil, errors.Wrap(err, "failed to build node.Network")
 	}
 
-	nd.Discovery, err = submodule.NewDiscoverySubmodule(ctx, (*builder)(b), b.repo.Config().Bootstrap, &nd.network)
+	nd.Discovery, err = submodule.NewDiscoverySubmodule(ctx, (*builder)(b), b.repo.Config().Bootstrap, b.repo.Config().Sync, &nd.network)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to build node.Discovery")
 	}
 
-	nd.VersionTable, err = version.ConfigureProtocolVersions(nd.network.NetworkName)
+	networkParams := b.repo.Config().NetworkParams
+	nd.VersionTable, err = version.ConfigureProtocolVersions(nd.network.NetworkName, scheduledUpgrades(networkParams)...)
 	if err != nil {
 		return nil, err
 	}
@@ -245,6 +267,13 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to build node.Chain")
 	}
+	gasSchedules, err := readGasSchedules(networkParams)
+	if err != nil {
+		return nil, err
+	}
+	nd.networkParams = newNetworkParamsSchedule(networkParams, gasSchedules)
+	nd.chain.Processor.SetParameterSchedule(nd.networkParams)
+	nd.chain.Processor.SetMessageWorkers(b.repo.Config().Sync.MessageWorkers)
 	if b.drand == nil {
 		genBlk, err := nd.chain.ChainReader.GetGenesisBlock(ctx)
 		if err != nil {
@@ -263,7 +292,7 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 		if err != nil {
 			return nil, err
 		}
-		b.chainClock = clock.NewChainClock(geneBlk.Timestamp, b.blockTime, b.propDelay)
+		b.chainClock = clock.NewChainClockFromClock(geneBlk.Timestamp, b.blockTime, b.propDelay, clock.NewSkewedClock(clock.NewSystemClock(), b.clockSkew))
 	}
 	nd.ChainClock = b.chainClock
 
@@ -281,8 +310,9 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to build node.Messaging")
 	}
+	nd.syncer.BlockRelay = blocksub.NewReconstructor(nd.Messaging.Inbox.Pool(), nd.Blockstore.Blockstore, nd.Blockservice.Blockservice, nd.chain.MessageStore, nd.syncer.BlockSyntax)
 
-	nd.StorageNetworking, err = submodule.NewStorgeNetworkingSubmodule(ctx, &nd.network)
+	nd.StorageNetworking, err = submodule.NewStorgeNetworkingSubmodule(ctx, &nd.network, &nd.chain)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to build node.StorageNetworking")
 	}
@@ -293,25 +323,43 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 	}
 
 	waiter := msg.NewWaiter(nd.chain.ChainReader, nd.chain.MessageStore, nd.Blockstore.Blockstore, nd.Blockstore.CborStore)
+	if nd.chain.ReceiptIndex != nil {
+		waiter.UseReceiptIndex(nd.chain.ReceiptIndex)
+	}
+
+	nd.Ledger = ledger.New(nd.chain.ChainReader, nd.chain.MessageStore, func(key block.TipSetKey) (ledger.StateView, error) {
+		return nd.chain.State.StateView(key)
+	}, nd.Repo.Datastore())
 
 	nd.PorcelainAPI = porcelain.New(plumbing.New(&plumbing.APIDeps{
-		Chain:        nd.chain.State,
-		Sync:         cst.NewChainSyncProvider(nd.syncer.ChainSyncManager),
-		Config:       cfg.NewConfig(b.repo),
-		DAG:          dag.NewDAG(merkledag.NewDAGService(nd.Blockservice.Blockservice)),
-		Expected:     nd.syncer.Consensus,
-		MsgPool:      nd.Messaging.MsgPool,
-		MsgPreviewer: msg.NewPreviewer(nd.chain.ChainReader, nd.Blockstore.CborStore, nd.Blockstore.Blockstore, nd.chain.Processor),
-		MsgWaiter:    waiter,
-		Network:      nd.network.Network,
-		Outbox:       nd.Messaging.Outbox,
-		PieceManager: nd.PieceManager,
-		Wallet:       nd.Wallet.Wallet,
+		AddressBook:   nd.Wallet.AddressBook,
+		Bitswap:       nd.network.Bitswap,
+		Chain:         nd.chain.State,
+		Sync:          cst.NewChainSyncProvider(nd.syncer.ChainSyncManager),
+		Config:        cfg.NewConfig(b.repo),
+		DAG:           dag.NewDAG(merkledag.NewDAGService(nd.Blockservice.Blockservice)),
+		Expected:      nd.syncer.Consensus,
+		Ledger:        nd.Ledger,
+		MsgPool:       nd.Messaging.MsgPool,
+		MsgPreviewer:  msg.NewPreviewer(nd.chain.ChainReader, nd.Blockstore.CborStore, nd.Blockstore.Blockstore, nd.chain.Processor),
+		MsgWaiter:     waiter,
+		Network:       nd.network.Network,
+		NetworkParams: nd.networkParams,
+		Outbox:        nd.Messaging.Outbox,
+		PeerScores:    nd.network.PeerScores,
+		PeerTracker:   nd.Discovery.PeerTracker,
+		PieceManager:  nd.PieceManager,
+		Scrubber:      nd.Scrubber,
+		Throttle:      nd.network.TransferThrottle,
+		Vectors:       conformance.NewExporter(nd.chain.ChainReader, nd.chain.MessageStore, nd.Blockstore.Blockstore, nd.chain.Syscalls, nd.chain.State),
+		VersionTable:  nd.VersionTable,
+		Wallet:        nd.Wallet.Wallet,
 	}))
 
 	nd.StorageProtocol, err = submodule.NewStorageProtocolSubmodule(
 		ctx,
 		nd.PorcelainAPI.WalletDefaultAddress,
+		nd.PorcelainAPI.WalletDealAddress,
 		&nd.chain,
 		&nd.Messaging,
 		waiter,
@@ -321,6 +369,10 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 		nd.Blockstore.Blockstore,
 		nd.network.GraphExchange,
 		state.NewViewer(nd.Blockstore.CborStore),
+		b.repo.Config().Mining.DealTransferRestarts,
+		b.repo.Config().Replication,
+		nd.StorageNetworking.AskCache,
+		nd.StorageNetworking.RequestTopic,
 	)
 	if err != nil {
 		return nil, err
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"runtime"
+	"time"
 
 	"github.com/filecoin-project/go-address"
 	fbig "github.com/filecoin-project/specs-actors/actors/abi/big"
@@ -14,7 +15,9 @@ import (
 	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/pkg/errors"
 
+	retmkt "github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/connectors/retrieval_market"
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/internal/submodule"
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/paths"
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/paymentchannel"
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
@@ -23,15 +26,19 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/journal"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/ledger"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/message"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/metrics"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/mining"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/net/pubsub"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/piecemanager"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/proofs"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/drand"
 	mining_protocol "github.com/filecoin-project/go-filecoin/internal/pkg/protocol/mining"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/scrubber"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/version"
 )
@@ -56,6 +63,13 @@ type Node struct {
 	// It contains all persistent artifacts of the filecoin node.
 	Repo repo.Repo
 
+	// journal records the events of the node's subsystems.
+	journal journal.Journal
+
+	// drainTimeout bounds each step of draining the node in Stop, zero for
+	// the steps to be bounded by the context of Stop only.
+	drainTimeout time.Duration
+
 	PorcelainAPI *porcelain.API
 	DrandAPI     *drand.API
 	StorageAPI   *storage.API
@@ -86,6 +100,7 @@ type Node struct {
 	Messaging         submodule.MessagingSubmodule
 	StorageNetworking submodule.StorageNetworkingSubmodule
 	ProofVerification submodule.ProofVerificationSubmodule
+	Ledger            *ledger.Ledger
 
 	//
 	// Protocols
@@ -94,6 +109,9 @@ type Node struct {
 	VersionTable      *version.ProtocolVersionTable
 	StorageProtocol   *submodule.StorageProtocolSubmodule
 	RetrievalProtocol *submodule.RetrievalProtocolSubmodule
+
+	// networkParams are the network parameters and gas schedules by height.
+	networkParams *networkParamsSchedule
 }
 
 // Start boots up the node.
@@ -135,6 +153,10 @@ func (node *Node) Start(ctx context.Context) error {
 		return errors.Wrap(err, "failed to get chain head")
 	}
 	go node.handleNewChainHeads(syncCtx, head)
+	if node.chain.ReceiptIndex != nil {
+		// index the chain loaded from disk, up to its head
+		node.chain.ReceiptIndex.HandleNewHead(syncCtx, head)
+	}
 
 	if !node.OfflineMode {
 
@@ -154,6 +176,23 @@ func (node *Node) Start(ctx context.Context) error {
 			return err
 		}
 
+		// Subscribe to the ask pubsub topic to keep the cache of miners' asks.
+		node.StorageNetworking.AskSub, err = node.pubsubscribe(syncCtx, node.StorageNetworking.AskTopic, node.handleAskSub)
+		if err != nil {
+			return err
+		}
+
+		// Subscribe to the storage request and bid topics to bid on requests
+		// and learn the bids on the node's own requests.
+		node.StorageNetworking.RequestSub, err = node.pubsubscribe(syncCtx, node.StorageNetworking.RequestTopic, node.handleStorageRequestSub)
+		if err != nil {
+			return err
+		}
+		node.StorageNetworking.BidSub, err = node.pubsubscribe(syncCtx, node.StorageNetworking.BidTopic, node.handleBidSub)
+		if err != nil {
+			return err
+		}
+
 		// Start node discovery
 		if err := node.Discovery.Start(node); err != nil {
 			return err
@@ -243,6 +282,18 @@ func (node *Node) handleNewChainHeads(ctx context.Context, firstHead block.TipSe
 				}
 			}
 
+			if maddr, err := node.MiningAddress(); err == nil {
+				node.Ledger.HandleNewHead(ctx, maddr, newHead)
+			}
+
+			if err := node.StorageProtocol.Replication().HandleNewHead(ctx, newHead); err != nil {
+				log.Error(err)
+			}
+
+			if node.chain.ReceiptIndex != nil {
+				node.chain.ReceiptIndex.HandleNewHead(ctx, newHead)
+			}
+
 			log.Debugf("message pool handling new head")
 			if err := handler.HandleNewHead(ctx, newHead); err != nil {
 				log.Error(err)
@@ -267,21 +318,45 @@ func (node *Node) cancelSubscriptions() {
 		node.Messaging.MessageSub.Cancel()
 		node.Messaging.MessageSub = nil
 	}
+
+	if node.StorageNetworking.AskSub != nil {
+		node.StorageNetworking.AskSub.Cancel()
+		node.StorageNetworking.AskSub = nil
+	}
+
+	if node.StorageNetworking.RequestSub != nil {
+		node.StorageNetworking.RequestSub.Cancel()
+		node.StorageNetworking.RequestSub = nil
+	}
+
+	if node.StorageNetworking.BidSub != nil {
+		node.StorageNetworking.BidSub.Cancel()
+		node.StorageNetworking.BidSub = nil
+	}
 }
 
-// Stop initiates the shutdown of the node.
+// Stop initiates the shutdown of the node. Mining stops first and the sealing
+// and deal state machines are stopped, saving their state, then the messages
+// being published are flushed before the network and the repo are closed.
+// Each of these steps is given the drain timeout of the node, and all of them
+// are given until ctx is done, after which the node closes regardless.
 func (node *Node) Stop(ctx context.Context) {
-	node.StopMining(ctx)
+	stepCtx, cancel := node.drainStep(ctx)
+	node.StopMining(stepCtx)
+	cancel()
 
-	node.cancelSubscriptions()
-	node.chain.ChainReader.Stop()
+	stepCtx, cancel = node.drainStep(ctx)
+	node.stopStorageMarket(stepCtx)
+	cancel()
 
-	if node.StorageMining != nil {
-		if err := node.StorageMining.Stop(ctx); err != nil {
-			fmt.Printf("error stopping storage miner: %s\n", err)
-		}
-		node.StorageMining = nil
+	stepCtx, cancel = node.drainStep(ctx)
+	if err := node.Messaging.Outbox.Flush(stepCtx); err != nil {
+		fmt.Printf("error flushing outbox: %s\n", err)
 	}
+	cancel()
+
+	node.cancelSubscriptions()
+	node.chain.ChainReader.Stop()
 
 	if err := node.Host().Close(); err != nil {
 		fmt.Printf("error closing host: %s\n", err)
@@ -296,6 +371,35 @@ func (node *Node) Stop(ctx context.Context) {
 	fmt.Println("stopping filecoin :(")
 }
 
+// drainStep returns the context of a step of draining the node in Stop, bounded
+// by the drain timeout unless it is zero.
+func (node *Node) drainStep(ctx context.Context) (context.Context, context.CancelFunc) {
+	if node.drainTimeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, node.drainTimeout)
+}
+
+// stopStorageMarket stops the storage market state machines, waiting for the
+// deal events being handled to be saved.
+func (node *Node) stopStorageMarket(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if node.StorageProtocol.StorageProvider != nil {
+			if err := node.StorageProtocol.StorageProvider.Stop(); err != nil {
+				fmt.Printf("error stopping storage provider: %s\n", err)
+			}
+		}
+		node.StorageProtocol.Client().Stop()
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		fmt.Printf("error stopping storage market: %s\n", ctx.Err())
+	}
+}
+
 func (node *Node) addNewlyMinedBlock(ctx context.Context, o mining.FullBlock) {
 	log.Debugf("Got a newly mined block from the mining worker: %s", o.Header)
 	if err := node.AddNewBlock(ctx, o); err != nil {
@@ -327,6 +431,11 @@ func (node *Node) MiningAddress() (address.Address, error) {
 // SetupMining initializes all the functionality the node needs to start mining.
 // This method is idempotent.
 func (node *Node) SetupMining(ctx context.Context) error {
+	// mining seals sectors and generates their proofs of spacetime
+	if !proofs.SealingSupported() {
+		return proofs.ErrSealingUnsupported
+	}
+
 	// ensure we have a miner actor before we even consider mining
 	minerAddr, err := node.MiningAddress()
 	if err != nil {
@@ -402,7 +511,7 @@ func (node *Node) setupStorageMining(ctx context.Context) error {
 	// TODO: rework these modules so they can be at least partially constructed during the building phase #3738
 	stateViewer := state.NewViewer(cborStore)
 
-	node.StorageMining, err = submodule.NewStorageMiningSubmodule(minerAddr, node.Repo.Datastore(), &node.chain, &node.Messaging, waiter, stateViewer, sealProofType, node.Repo, node.BlockMining.PoStGenerator)
+	node.StorageMining, err = submodule.NewStorageMiningSubmodule(minerAddr, node.Repo.Datastore(), &node.chain, &node.Messaging, waiter, stateViewer, sealProofType, node.Repo, node.BlockMining.PoStGenerator, node.ProofVerification.ProofVerifier, node.journal)
 	if err != nil {
 		return err
 	}
@@ -422,6 +531,9 @@ func (node *Node) setupStorageMining(ctx context.Context) error {
 		repoPath,
 		sealProofType,
 		stateViewer,
+		node.StorageNetworking.AskTopic,
+		node.StorageNetworking.BidTopic,
+		func() uint64 { return node.Repo.Config().Mining.AnnouncedCapacity },
 	)
 }
 
@@ -441,15 +553,32 @@ func (node *Node) setupRetrievalMining(ctx context.Context) error {
 		node.Messaging.Outbox,
 		mgrStateViewer)
 
+	var unsealer retmkt.UnsealerAPI = node.PieceManager()
+	sectorCfg := node.Repo.Config().SectorBase
+	if sectorCfg.UnsealCacheMaxBytes > 0 {
+		repoPath, err := node.Repo.Path()
+		if err != nil {
+			return err
+		}
+		sectorPath, err := paths.GetSectorPath(sectorCfg.RootDirPath, repoPath)
+		if err != nil {
+			return err
+		}
+		unsealer, err = piecemanager.NewUnsealCache(node.PieceManager(), paths.UnsealCacheDir(sectorPath), sectorCfg.UnsealCacheMaxBytes)
+		if err != nil {
+			return errors.Wrap(err, "failed to build unseal cache")
+		}
+	}
+
 	rp, err := submodule.NewRetrievalProtocolSubmodule(
 		node.Blockstore.Blockstore,
 		node.Repo.Datastore(),
 		node.chain.State,
-		node.Host(),
+		node.network.TransferHost,
 		providerAddr,
 		node.Wallet.Signer,
 		paychMgr,
-		node.PieceManager(),
+		unsealer,
 	)
 	if err != nil {
 		return errors.Wrap(err, "failed to build node.RetrievalProtocol")
@@ -519,6 +648,14 @@ func (node *Node) StartMining(ctx context.Context) error {
 	node.BlockMining.MiningDoneWg.Add(1)
 	go node.handleNewMiningOutput(miningCtx, outCh)
 
+	// Announce the ask periodically so that clients joining the network learn it.
+	if announcer := node.StorageProtocol.AskAnnouncer(); announcer != nil && !node.OfflineMode {
+		interval := time.Duration(node.Repo.Config().Mining.AskAnnounceIntervalSeconds) * time.Second
+		if interval > 0 {
+			go announcer.Run(miningCtx, interval)
+		}
+	}
+
 	node.setIsMining(true)
 
 	return nil
@@ -618,6 +755,7 @@ func (node *Node) CreateMiningWorker(ctx context.Context) (*mining.DefaultWorker
 	}
 	sampler := chain.NewSampler(node.Chain().ChainReader, genBlk.Ticket)
 
+	miningCfg := node.Repo.Config().Mining
 	return mining.NewDefaultWorker(mining.WorkerParameters{
 		API: node.PorcelainAPI,
 
@@ -639,6 +777,9 @@ func (node *Node) CreateMiningWorker(ctx context.Context) (*mining.DefaultWorker
 		Poster:           poster,
 		ChainState:       node.chain.ChainReader,
 		Drand:            node.Syncer().Drand,
+
+		PrioritizeOwnMessages: miningCfg.PrioritizeOwnMessages,
+		PriorityAddresses:     miningCfg.PriorityAddresses,
 	}), nil
 }
 
@@ -668,11 +809,22 @@ func (node *Node) Host() host.Host {
 	return node.network.Host
 }
 
-// PieceManager returns the node's PieceManager.
+// PieceManager returns the node's PieceManager, or nil if it is not mining.
 func (node *Node) PieceManager() piecemanager.PieceManager {
+	if node.StorageMining == nil {
+		return nil
+	}
 	return node.StorageMining.PieceManager
 }
 
+// Scrubber returns the node's sector scrubber, or nil if it is not mining.
+func (node *Node) Scrubber() *scrubber.Scrubber {
+	if node.StorageMining == nil {
+		return nil
+	}
+	return node.StorageMining.Scrubber
+}
+
 // BlockService returns the nodes blockservice.
 func (node *Node) BlockService() bserv.BlockService {
 	return node.Blockservice.Blockservice
@@ -230,6 +230,7 @@ func TestNodeConfig(t *testing.T) {
 	assert.Equal(t, true, n.OfflineMode)
 	assert.Equal(t, defaultCfg.Mining, cfg.Mining)
 	assert.Equal(t, &config.SwarmConfig{
-		Address: "/ip4/127.0.0.1/tcp/0",
+		Address:     "/ip4/127.0.0.1/tcp/0",
+		BanDuration: "1h",
 	}, cfg.Swarm)
 }
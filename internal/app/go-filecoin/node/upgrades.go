@@ -0,0 +1,140 @@
+package node
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/power"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/version"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
+)
+
+// networkParamsSchedule holds the network parameters of the protocol upgrades
+// configured for a private network and the gas schedules of the VM, looked up
+// by height. It is immutable once built, so lookups are safe from any goroutine
+// and for any tipset, whatever the head.
+type networkParamsSchedule struct {
+	// heights and params are sorted by height, the first entry holds the
+	// parameters the node was built with, effective from genesis.
+	heights []abi.ChainEpoch
+	params  []consensus.NetworkParams
+}
+
+var _ consensus.ParameterSchedule = (*networkParamsSchedule)(nil)
+
+// newNetworkParamsSchedule returns a schedule for the configured upgrades and
+// the gas schedules, starting from the current values of the parameters. The
+// gas schedules must be valid.
+func newNetworkParamsSchedule(cfg *config.NetworkParamsConfig, gasSchedules []vm.GasSchedule) *networkParamsSchedule {
+	upgrades := make([]config.NetworkUpgradeConfig, len(cfg.Upgrades))
+	copy(upgrades, cfg.Upgrades)
+	sort.SliceStable(upgrades, func(i, j int) bool { return upgrades[i].Height < upgrades[j].Height })
+
+	// an entry starts at each upgrade and at each gas schedule
+	var heights []abi.ChainEpoch
+	for _, upgrade := range upgrades {
+		heights = append(heights, abi.ChainEpoch(upgrade.Height))
+	}
+	for _, schedule := range gasSchedules[1:] {
+		heights = append(heights, schedule.Epoch)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+
+	current := consensus.NetworkParams{
+		ConsensusMinerMinPower: power.ConsensusMinerMinPower,
+		SupportedProofTypes:    miner.SupportedProofTypes,
+		GasPricelist:           vm.NewGasPricelist(gasSchedules[0]),
+	}
+	s := &networkParamsSchedule{
+		heights: []abi.ChainEpoch{0},
+		params:  []consensus.NetworkParams{current},
+	}
+	nextUpgrade, nextGas := 0, 1
+	for _, height := range heights {
+		for ; nextUpgrade < len(upgrades) && abi.ChainEpoch(upgrades[nextUpgrade].Height) <= height; nextUpgrade++ {
+			upgrade := upgrades[nextUpgrade]
+			if upgrade.ConsensusMinerMinPower > 0 {
+				current.ConsensusMinerMinPower = big.NewIntUnsigned(upgrade.ConsensusMinerMinPower)
+			}
+			if len(upgrade.ReplaceProofTypes) > 0 {
+				current.SupportedProofTypes = proofTypeSet(upgrade.ReplaceProofTypes)
+			}
+		}
+		for ; nextGas < len(gasSchedules) && gasSchedules[nextGas].Epoch <= height; nextGas++ {
+			current.GasPricelist = vm.NewGasPricelist(gasSchedules[nextGas])
+		}
+		if last := len(s.heights) - 1; s.heights[last] == height {
+			s.params[last] = current
+			continue
+		}
+		s.heights = append(s.heights, height)
+		s.params = append(s.params, current)
+	}
+	return s
+}
+
+// ParamsAt implements consensus.ParameterSchedule.
+func (s *networkParamsSchedule) ParamsAt(epoch abi.ChainEpoch) consensus.NetworkParams {
+	// index of the last entry in effect at the epoch
+	idx := sort.Search(len(s.heights), func(i int) bool { return epoch < s.heights[i] }) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return s.params[idx]
+}
+
+// readGasSchedules reads the gas schedules of the configured file, or returns
+// the default schedules when none is configured.
+func readGasSchedules(cfg *config.NetworkParamsConfig) ([]vm.GasSchedule, error) {
+	if cfg.GasScheduleFile == "" {
+		return vm.DefaultGasSchedules, nil
+	}
+	f, err := os.Open(cfg.GasScheduleFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open gas schedules")
+	}
+	defer f.Close() // nolint: errcheck
+	schedules, err := vm.ReadGasSchedules(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid gas schedules %s", cfg.GasScheduleFile)
+	}
+	return schedules, nil
+}
+
+// scheduledUpgrades describes the configured upgrades for the protocol version table.
+func scheduledUpgrades(cfg *config.NetworkParamsConfig) []version.Upgrade {
+	var upgrades []version.Upgrade
+	for _, upgrade := range cfg.Upgrades {
+		var changes []string
+		if upgrade.ConsensusMinerMinPower > 0 {
+			changes = append(changes, fmt.Sprintf("consensus miner min power %d", upgrade.ConsensusMinerMinPower))
+		}
+		if len(upgrade.ReplaceProofTypes) > 0 {
+			changes = append(changes, fmt.Sprintf("proof types %v", upgrade.ReplaceProofTypes))
+		}
+		upgrades = append(upgrades, version.Upgrade{
+			Version:     upgrade.Version,
+			EffectiveAt: abi.ChainEpoch(upgrade.Height),
+			Name:        upgrade.Name,
+			Description: strings.Join(changes, ", "),
+		})
+	}
+	return upgrades
+}
+
+func proofTypeSet(proofTypes []int64) map[abi.RegisteredProof]struct{} {
+	set := make(map[abi.RegisteredProof]struct{}, len(proofTypes))
+	for _, proofType := range proofTypes {
+		set[abi.RegisteredProof(proofType)] = struct{}{}
+	}
+	return set
+}
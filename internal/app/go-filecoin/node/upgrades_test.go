@@ -0,0 +1,73 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/power"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
+)
+
+func TestNetworkParamsSchedule(t *testing.T) {
+	tf.UnitTest(t)
+
+	minPower, proofTypes := power.ConsensusMinerMinPower, miner.SupportedProofTypes
+	upgradedGas := vm.DefaultGasSchedules[0]
+	upgradedGas.Version = 1
+	upgradedGas.Epoch = 150
+	upgradedGas.IpldGet = vm.GasLinearCost{Base: gas.NewGas(30), PerUnit: gas.NewGas(4)}
+
+	schedule := newNetworkParamsSchedule(&config.NetworkParamsConfig{
+		Upgrades: []config.NetworkUpgradeConfig{
+			{Version: 2, Height: 200, ReplaceProofTypes: []int64{int64(abi.RegisteredProof_StackedDRG512MiBSeal)}},
+			{Version: 1, Height: 100, ConsensusMinerMinPower: 2048},
+		},
+	}, []vm.GasSchedule{vm.DefaultGasSchedules[0], upgradedGas})
+
+	t.Log("epochs before the upgrades have the parameters the node was built with")
+	params := schedule.ParamsAt(99)
+	assert.Equal(t, minPower, params.ConsensusMinerMinPower)
+	assert.Equal(t, proofTypes, params.SupportedProofTypes)
+	assert.Equal(t, gas.NewGas(12), params.GasPricelist.OnIpldGet(2))
+
+	t.Log("parameters of later upgrades build on those of earlier ones")
+	params = schedule.ParamsAt(150)
+	assert.Equal(t, big.NewInt(2048), params.ConsensusMinerMinPower)
+	assert.Equal(t, proofTypes, params.SupportedProofTypes)
+
+	params = schedule.ParamsAt(200)
+	assert.Equal(t, big.NewInt(2048), params.ConsensusMinerMinPower)
+	assert.Equal(t, map[abi.RegisteredProof]struct{}{abi.RegisteredProof_StackedDRG512MiBSeal: {}}, params.SupportedProofTypes)
+
+	t.Log("gas schedules are looked up with the upgrades")
+	assert.Equal(t, gas.NewGas(12), schedule.ParamsAt(149).GasPricelist.OnIpldGet(2))
+	assert.Equal(t, gas.NewGas(38), schedule.ParamsAt(150).GasPricelist.OnIpldGet(2))
+	assert.Equal(t, gas.NewGas(38), schedule.ParamsAt(200).GasPricelist.OnIpldGet(2))
+
+	t.Log("looking up an epoch depends on nothing looked up before, and leaves the actors alone")
+	assert.Equal(t, minPower, schedule.ParamsAt(0).ConsensusMinerMinPower)
+	assert.Equal(t, minPower, power.ConsensusMinerMinPower)
+	assert.Equal(t, proofTypes, miner.SupportedProofTypes)
+}
+
+func TestScheduledUpgrades(t *testing.T) {
+	tf.UnitTest(t)
+
+	upgrades := scheduledUpgrades(&config.NetworkParamsConfig{
+		Upgrades: []config.NetworkUpgradeConfig{
+			{Version: 1, Height: 100, Name: "faster", ConsensusMinerMinPower: 2048, ReplaceProofTypes: []int64{3}},
+		},
+	})
+	assert.Len(t, upgrades, 1)
+	assert.Equal(t, uint64(1), upgrades[0].Version)
+	assert.Equal(t, abi.ChainEpoch(100), upgrades[0].EffectiveAt)
+	assert.Equal(t, "faster", upgrades[0].Name)
+	assert.Equal(t, "consensus miner min power 2048, proof types [3]", upgrades[0].Description)
+}
@@ -15,6 +15,8 @@ const defaultRepoDir = "~/.filecoin"
 const filSectorPathVar = "FIL_SECTOR_PATH"
 const defaultSectorDir = ".filecoin_sectors"
 const defaultPieceStagingDir = "pieces"
+const unsealCacheDir = "unseal-cache"
+const pieceStoreDir = "piece-store"
 
 // GetRepoPath returns the path of the filecoin repo from a potential override
 // string, the FIL_PATH environment variable and a default of ~/.filecoin/repo.
@@ -53,3 +55,15 @@ func GetSectorPath(override, repoPath string) (string, error) {
 func PieceStagingDir(repoPath string) (string, error) {
 	return homedir.Expand(filepath.Join(repoPath, "../", defaultPieceStagingDir))
 }
+
+// UnsealCacheDir returns the path to the directory holding unsealed copies of
+// sectors within the sector storage path
+func UnsealCacheDir(sectorPath string) string {
+	return filepath.Join(sectorPath, unsealCacheDir)
+}
+
+// PieceStoreDir returns the path to the directory holding the deal pieces
+// within the sector storage path
+func PieceStoreDir(sectorPath string) string {
+	return filepath.Join(sectorPath, pieceStoreDir)
+}
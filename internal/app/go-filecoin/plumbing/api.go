@@ -3,11 +3,13 @@ package plumbing
 import (
 	"context"
 	"io"
+	"sort"
 	"time"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/specs-actors/actors/abi"
 	acrypto "github.com/filecoin-project/specs-actors/actors/crypto"
+	"github.com/ipfs/go-bitswap"
 	"github.com/ipfs/go-cid"
 	ipld "github.com/ipfs/go-ipld-format"
 	logging "github.com/ipfs/go-log/v2"
@@ -15,6 +17,7 @@ import (
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
 	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/plumbing/cfg"
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/plumbing/cst"
@@ -23,13 +26,19 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chainsync/status"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/conformance"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/discovery"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/ledger"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/message"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/net"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/piecemanager"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/scrubber"
 	appstate "github.com/filecoin-project/go-filecoin/internal/pkg/state"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/version"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/actor"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
@@ -45,52 +54,82 @@ import (
 type API struct {
 	logger logging.EventLogger
 
-	chain        *cst.ChainStateReadWriter
-	syncer       *cst.ChainSyncProvider
-	config       *cfg.Config
-	dag          *dag.DAG
-	expected     consensus.Protocol
-	msgPool      *message.Pool
-	msgPreviewer *msg.Previewer
-	msgWaiter    *msg.Waiter
-	network      *net.Network
-	outbox       *message.Outbox
-	pieceManager func() piecemanager.PieceManager
-	wallet       *wallet.Wallet
+	addressBook   *wallet.AddressBook
+	bitswap       *bitswap.Bitswap
+	chain         *cst.ChainStateReadWriter
+	syncer        *cst.ChainSyncProvider
+	config        *cfg.Config
+	dag           *dag.DAG
+	expected      consensus.Protocol
+	ledger        *ledger.Ledger
+	msgPool       *message.Pool
+	msgPreviewer  *msg.Previewer
+	msgWaiter     *msg.Waiter
+	network       *net.Network
+	networkParams consensus.ParameterSchedule
+	outbox        *message.Outbox
+	peerScores    *net.PeerScores
+	peerTracker   *discovery.PeerTracker
+	pieceManager  func() piecemanager.PieceManager
+	scrubber      func() *scrubber.Scrubber
+	throttle      *net.Throttle
+	vectors       *conformance.Exporter
+	versionTable  *version.ProtocolVersionTable
+	wallet        *wallet.Wallet
 }
 
 // APIDeps contains all the API's dependencies
 type APIDeps struct {
-	Chain        *cst.ChainStateReadWriter
-	Sync         *cst.ChainSyncProvider
-	Config       *cfg.Config
-	DAG          *dag.DAG
-	Expected     consensus.Protocol
-	MsgPool      *message.Pool
-	MsgPreviewer *msg.Previewer
-	MsgWaiter    *msg.Waiter
-	Network      *net.Network
-	Outbox       *message.Outbox
-	PieceManager func() piecemanager.PieceManager
-	Wallet       *wallet.Wallet
+	AddressBook   *wallet.AddressBook
+	Bitswap       *bitswap.Bitswap
+	Chain         *cst.ChainStateReadWriter
+	Sync          *cst.ChainSyncProvider
+	Config        *cfg.Config
+	DAG           *dag.DAG
+	Expected      consensus.Protocol
+	Ledger        *ledger.Ledger
+	MsgPool       *message.Pool
+	MsgPreviewer  *msg.Previewer
+	MsgWaiter     *msg.Waiter
+	Network       *net.Network
+	NetworkParams consensus.ParameterSchedule
+	Outbox        *message.Outbox
+	PeerScores    *net.PeerScores
+	PeerTracker   *discovery.PeerTracker
+	PieceManager  func() piecemanager.PieceManager
+	Scrubber      func() *scrubber.Scrubber
+	Throttle      *net.Throttle
+	Vectors       *conformance.Exporter
+	VersionTable  *version.ProtocolVersionTable
+	Wallet        *wallet.Wallet
 }
 
 // New constructs a new instance of the API.
 func New(deps *APIDeps) *API {
 	return &API{
-		logger:       logging.Logger("porcelain"),
-		chain:        deps.Chain,
-		syncer:       deps.Sync,
-		config:       deps.Config,
-		dag:          deps.DAG,
-		expected:     deps.Expected,
-		msgPool:      deps.MsgPool,
-		msgPreviewer: deps.MsgPreviewer,
-		msgWaiter:    deps.MsgWaiter,
-		network:      deps.Network,
-		outbox:       deps.Outbox,
-		pieceManager: deps.PieceManager,
-		wallet:       deps.Wallet,
+		logger:        logging.Logger("porcelain"),
+		addressBook:   deps.AddressBook,
+		bitswap:       deps.Bitswap,
+		chain:         deps.Chain,
+		syncer:        deps.Sync,
+		config:        deps.Config,
+		dag:           deps.DAG,
+		expected:      deps.Expected,
+		ledger:        deps.Ledger,
+		msgPool:       deps.MsgPool,
+		msgPreviewer:  deps.MsgPreviewer,
+		msgWaiter:     deps.MsgWaiter,
+		network:       deps.Network,
+		networkParams: deps.NetworkParams,
+		outbox:        deps.Outbox,
+		peerScores:    deps.PeerScores,
+		peerTracker:   deps.PeerTracker,
+		pieceManager:  deps.PieceManager,
+		scrubber:      deps.Scrubber,
+		throttle:      deps.Throttle,
+		vectors:       deps.Vectors,
+		versionTable:  deps.VersionTable,
+		wallet:        deps.Wallet,
 	}
 }
 
@@ -111,11 +150,49 @@ func (api *API) ActorLs(ctx context.Context) (<-chan state.GetAllActorsResult, e
 	return api.chain.LsActors(ctx)
 }
 
+// BitswapStat returns aggregated statistics about the node's bitswap exchange.
+func (api *API) BitswapStat() (*bitswap.Stat, error) {
+	return api.bitswap.Stat()
+}
+
 // BlockTime returns the block time used by the consensus protocol.
 func (api *API) BlockTime() time.Duration {
 	return api.expected.BlockTime()
 }
 
+// ProtocolSealProofTypes returns the seal proof types new miners may commit
+// sectors with under the protocol version of the chain head, by sector size.
+func (api *API) ProtocolSealProofTypes() ([]abi.RegisteredProof, error) {
+	head, err := api.chain.GetTipSet(api.chain.Head())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get chain head")
+	}
+	height, err := head.Height()
+	if err != nil {
+		return nil, err
+	}
+	supported := api.networkParams.ParamsAt(height).SupportedProofTypes
+
+	proofTypes := make([]abi.RegisteredProof, 0, len(supported))
+	for proofType := range supported {
+		proofTypes = append(proofTypes, proofType)
+	}
+	sort.Slice(proofTypes, func(i, j int) bool {
+		si, _ := proofTypes[i].SectorSize()
+		sj, _ := proofTypes[j].SectorSize()
+		if si != sj {
+			return si < sj
+		}
+		return proofTypes[i] < proofTypes[j]
+	})
+	return proofTypes, nil
+}
+
+// ProtocolVersions returns the protocol versions of the network, sorted by effective height.
+func (api *API) ProtocolVersions() []version.Upgrade {
+	return api.versionTable.Upgrades()
+}
+
 // ConfigSet sets the given parameters at the given path in the local config.
 // The given path may be either a single field name, or a dotted path to a field.
 // The JSON value may be either a single value or a whole data structure to be replace.
@@ -152,6 +229,12 @@ func (api *API) ChainHeadKey() block.TipSetKey {
 	return api.chain.Head()
 }
 
+// ChainWatchHead calls `watcher` with each new head of the chain until the
+// returned function is called. The watcher must not block.
+func (api *API) ChainWatchHead(watcher func(block.TipSet)) func() {
+	return api.chain.WatchHead(watcher)
+}
+
 // ChainSetHead sets `key` as the new head of this chain iff it exists in the nodes chain store.
 func (api *API) ChainSetHead(ctx context.Context, key block.TipSetKey) error {
 	return api.chain.SetHead(ctx, key)
@@ -182,11 +265,43 @@ func (api *API) ChainSyncHandleNewTipSet(ci *block.ChainInfo) error {
 	return api.syncer.HandleNewTipSet(ci)
 }
 
+// SyncTrustPeer adds a peer to the set of peers whose chain heads the syncer prefers.
+func (api *API) SyncTrustPeer(pid peer.ID) {
+	api.peerTracker.Trust(pid)
+}
+
+// SyncUntrustPeer removes a peer from the set of trusted peers.
+func (api *API) SyncUntrustPeer(pid peer.ID) {
+	api.peerTracker.Untrust(pid)
+}
+
+// SyncTrustedPeers returns the peers whose chain heads the syncer prefers.
+func (api *API) SyncTrustedPeers() []peer.ID {
+	return api.peerTracker.TrustedPeers()
+}
+
+// SyncSetTrustedOnly sets whether the syncer ignores chain heads from untrusted peers.
+func (api *API) SyncSetTrustedOnly(only bool) {
+	api.peerTracker.SetTrustedOnly(only)
+}
+
+// SyncTrustedOnly returns true if the syncer ignores chain heads from untrusted peers.
+func (api *API) SyncTrustedOnly() bool {
+	return api.peerTracker.TrustedOnly()
+}
+
 // ChainExport exports the chain from `head` up to and including the genesis block to `out`
 func (api *API) ChainExport(ctx context.Context, head block.TipSetKey, out io.Writer) error {
 	return api.chain.ChainExport(ctx, head, out)
 }
 
+// ChainExportVector re-applies the messages of the tipset `key` and returns
+// them as a test vector expecting the state root and receipts of the chain,
+// and whether re-applying them reproduced those.
+func (api *API) ChainExportVector(ctx context.Context, key block.TipSetKey) (*conformance.Vector, bool, error) {
+	return api.vectors.Export(ctx, key)
+}
+
 // ChainImport imports a chain from `in`.
 func (api *API) ChainImport(ctx context.Context, in io.Reader) (block.TipSetKey, error) {
 	return api.chain.ChainImport(ctx, in)
@@ -207,6 +322,12 @@ func (api *API) OutboxQueueClear(ctx context.Context, sender address.Address) {
 	api.outbox.Queue().Clear(ctx, sender)
 }
 
+// MinerEarnings returns the income and expenditure of a miner from the
+// tipsets of the head chain from height `since`.
+func (api *API) MinerEarnings(ctx context.Context, maddr address.Address, since abi.ChainEpoch) (*ledger.Earnings, error) {
+	return api.ledger.Earnings(ctx, maddr, api.chain.Head(), since)
+}
+
 // MessagePoolPending lists messages un-mined in the pool
 func (api *API) MessagePoolPending() []*types.SignedMessage {
 	return api.msgPool.Pending()
@@ -217,6 +338,13 @@ func (api *API) MessagePoolGet(cid cid.Cid) (value *types.SignedMessage, ok bool
 	return api.msgPool.Get(cid)
 }
 
+// MessagePoolWatch calls `watcher` each time a message is added to or removed
+// from the message pool, until the returned function is called. The watcher
+// must not block.
+func (api *API) MessagePoolWatch(watcher func(message.PoolEvent)) func() {
+	return api.msgPool.Watch(watcher)
+}
+
 // MessagePoolRemove removes a message from the message pool.
 func (api *API) MessagePoolRemove(cid cid.Cid) {
 	api.msgPool.Remove(cid)
@@ -228,6 +356,42 @@ func (api *API) MessagePreview(ctx context.Context, from, to address.Address, me
 	return api.msgPreviewer.Preview(ctx, from, to, method, params...)
 }
 
+// MessageDryRun builds and signs the message MessageSend would send and applies
+// it to the state of the head, after the messages of the sender waiting in the
+// outbox, without sending it. The message is checked against the state and its
+// gas use measured, but nothing is queued or broadcast.
+func (api *API) MessageDryRun(ctx context.Context, from, to address.Address, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit gas.Unit, method abi.MethodNum, params interface{}) (*msg.DryRun, error) {
+	encodedParams, err := encoding.Encode(params)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid params")
+	}
+	smsg, err := api.outbox.Prepare(ctx, from, to, value, gasPrice, gasLimit, method, encodedParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var msgs []*types.SignedMessage
+	for _, queued := range api.outbox.Queue().List(from) {
+		msgs = append(msgs, queued.Msg)
+	}
+	msgs = append(msgs, smsg)
+	receipts, err := api.msgPreviewer.Simulate(ctx, msgs)
+	if err != nil {
+		return nil, err
+	}
+
+	var c cid.Cid
+	if from.Protocol() == address.BLS {
+		c, err = smsg.Message.Cid()
+	} else {
+		c, err = smsg.Cid()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &msg.DryRun{Message: smsg, Cid: c, Receipt: receipts[len(receipts)-1]}, nil
+}
+
 // StateView loads the state view for a tipset, i.e. the state *after* the application of the tipset's messages.
 func (api *API) StateView(baseKey block.TipSetKey) (*appstate.View, error) {
 	return api.chain.StateView(baseKey)
@@ -257,11 +421,46 @@ func (api *API) MessageWait(ctx context.Context, msgCid cid.Cid, lookback uint64
 	return api.msgWaiter.Wait(ctx, msgCid, lookback, cb)
 }
 
+// MessageLookup returns a message on chain with its block and receipt from the
+// receipts index, and whether it was found. Messages are found only when
+// archival mode is enabled.
+func (api *API) MessageLookup(ctx context.Context, msgCid cid.Cid) (*msg.ChainMessage, bool, error) {
+	return api.msgWaiter.Lookup(ctx, msgCid)
+}
+
 // NetworkGetBandwidthStats gets stats on the current bandwidth usage of the network
 func (api *API) NetworkGetBandwidthStats() metrics.Stats {
 	return api.network.GetBandwidthStats()
 }
 
+// NetworkTransferLimits returns the limits of the rates of the node's data
+// transfers.
+func (api *API) NetworkTransferLimits() net.TransferLimits {
+	return api.throttle.Limits()
+}
+
+// NetworkSetTransferLimits changes the limits of the rates of the node's data
+// transfers, including those running.
+func (api *API) NetworkSetTransferLimits(limits net.TransferLimits) {
+	api.throttle.SetLimits(limits)
+}
+
+// NetworkBan bans peer `p` for `duration`, or until it is unbanned when
+// `duration` is zero, disconnecting it.
+func (api *API) NetworkBan(p peer.ID, duration time.Duration, reason string) error {
+	return api.peerScores.Ban(p, duration, reason)
+}
+
+// NetworkUnban lifts the ban of peer `p`.
+func (api *API) NetworkUnban(p peer.ID) error {
+	return api.peerScores.Unban(p)
+}
+
+// NetworkBanned lists the banned peers.
+func (api *API) NetworkBanned() []net.BannedPeer {
+	return api.peerScores.Banned()
+}
+
 // NetworkGetPeerAddresses gets the current addresses of the node
 func (api *API) NetworkGetPeerAddresses() []ma.Multiaddr {
 	return api.network.GetPeerAddresses()
@@ -277,6 +476,12 @@ func (api *API) NetworkFindProvidersAsync(ctx context.Context, key cid.Cid, coun
 	return api.network.Router.FindProvidersAsync(ctx, key, count)
 }
 
+// NetworkProvide announces to the filecoin network content router that this node
+// can provide the given key.
+func (api *API) NetworkProvide(ctx context.Context, key cid.Cid) error {
+	return api.network.Router.Provide(ctx, key)
+}
+
 // NetworkGetClosestPeers issues a getClosestPeers query to the filecoin network.
 func (api *API) NetworkGetClosestPeers(ctx context.Context, key string) (<-chan peer.ID, error) {
 	return api.network.GetClosestPeers(ctx, key)
@@ -322,6 +527,41 @@ func (api *API) WalletExport(addrs []address.Address) ([]*crypto.KeyInfo, error)
 	return api.wallet.Export(addrs)
 }
 
+// WalletSignData signs `data` for `domain` with the key of `addr`, see
+// crypto.DataSigningPayload.
+func (api *API) WalletSignData(addr address.Address, domain string, data []byte) (crypto.Signature, error) {
+	payload, err := crypto.DataSigningPayload(domain, data)
+	if err != nil {
+		return crypto.Signature{}, err
+	}
+	return api.wallet.SignBytes(payload, addr)
+}
+
+// WalletSetLabel assigns a human readable label to an address.
+func (api *API) WalletSetLabel(addr address.Address, label string) error {
+	return api.addressBook.SetLabel(addr, label)
+}
+
+// WalletRemoveLabel removes a label from the address book.
+func (api *API) WalletRemoveLabel(label string) error {
+	return api.addressBook.RemoveLabel(label)
+}
+
+// WalletLabel returns the label assigned to an address, if any.
+func (api *API) WalletLabel(addr address.Address) (string, bool) {
+	return api.addressBook.Label(addr)
+}
+
+// WalletLabels lists all labels in the address book.
+func (api *API) WalletLabels() []wallet.AddressBookEntry {
+	return api.addressBook.Entries()
+}
+
+// WalletResolveAddress parses an address string or looks it up as a label.
+func (api *API) WalletResolveAddress(s string) (address.Address, error) {
+	return api.addressBook.Resolve(s)
+}
+
 // DAGGetNode returns the associated DAG node for the passed in CID.
 func (api *API) DAGGetNode(ctx context.Context, ref string) (interface{}, error) {
 	return api.dag.GetNode(ctx, ref)
@@ -338,6 +578,12 @@ func (api *API) DAGCat(ctx context.Context, c cid.Cid) (io.Reader, error) {
 	return api.dag.Cat(ctx, c)
 }
 
+// DAGResolveDataModelPath returns the CID of the block an IPLD data model path
+// from the root of a DAG ends in.
+func (api *API) DAGResolveDataModelPath(ctx context.Context, root cid.Cid, path string) (cid.Cid, error) {
+	return api.dag.ResolveDataModelPath(ctx, root, path)
+}
+
 // DAGImportData adds data from an io reader to the merkledag and returns the
 // Cid of the given data. Once the data is in the DAG, it can fetched from the
 // node via Bitswap and a copy will be kept in the blockstore.
@@ -349,3 +595,12 @@ func (api *API) DAGImportData(ctx context.Context, data io.Reader) (ipld.Node, e
 func (api *API) PieceManager() piecemanager.PieceManager {
 	return api.pieceManager()
 }
+
+// SectorScrubber returns the scrubber of the sealed sectors of the miner, nil
+// if the node is not mining.
+func (api *API) SectorScrubber() *scrubber.Scrubber {
+	if api.scrubber == nil {
+		return nil
+	}
+	return api.scrubber()
+}
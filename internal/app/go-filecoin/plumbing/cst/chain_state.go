@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sync"
 
+	"github.com/cskr/pubsub"
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/specs-actors/actors/abi"
 	"github.com/filecoin-project/specs-actors/actors/builtin"
@@ -26,6 +28,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/scrubber"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/slashing"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
@@ -44,6 +47,7 @@ type chainReadWriter interface {
 	GetTipSetStateRoot(block.TipSetKey) (cid.Cid, error)
 	SetHead(context.Context, block.TipSet) error
 	ReadOnlyStateStore() cborutil.ReadOnlyIpldStore
+	HeadEvents() *pubsub.PubSub
 }
 
 // ChainStateReadWriter composes a:
@@ -119,6 +123,36 @@ func (chn *ChainStateReadWriter) GetTipSet(key block.TipSetKey) (block.TipSet, e
 	return chn.readWriter.GetTipSet(key)
 }
 
+// WatchHead calls `watcher` with each new head of the chain until the returned
+// function is called. The watcher is called from the goroutine draining the head
+// events and must not block, heads are published while the chain is updated.
+func (chn *ChainStateReadWriter) WatchHead(watcher func(block.TipSet)) func() {
+	ch := chn.readWriter.HeadEvents().Sub(chain.NewHeadTopic)
+	done := make(chan struct{})
+	go func() {
+		// The channel is drained until it is closed by Unsub, which would
+		// otherwise deadlock with a head being published.
+		for event := range ch {
+			select {
+			case <-done:
+				continue
+			default:
+			}
+			if ts, ok := event.(block.TipSet); ok {
+				watcher(ts)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			chn.readWriter.HeadEvents().Unsub(ch, chain.NewHeadTopic)
+		})
+	}
+}
+
 // Ls returns an iterator over tipsets from head to genesis.
 func (chn *ChainStateReadWriter) Ls(ctx context.Context) (*chain.TipsetIterator, error) {
 	ts, err := chn.readWriter.GetTipSet(chn.readWriter.GetHead())
@@ -347,3 +381,7 @@ func (chn *ChainStateReadWriter) AccountStateView(key block.TipSetKey) (state.Ac
 func (chn *ChainStateReadWriter) FaultStateView(key block.TipSetKey) (slashing.FaultStateView, error) {
 	return chn.StateView(key)
 }
+
+func (chn *ChainStateReadWriter) ScrubberStateView(key block.TipSetKey) (scrubber.StateView, error) {
+	return chn.StateView(key)
+}
@@ -0,0 +1,70 @@
+package dag
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/ipfs/go-cid"
+	ipldprime "github.com/ipld/go-ipld-prime"
+	dagpb "github.com/ipld/go-ipld-prime-proto"
+	cidlink "github.com/ipld/go-ipld-prime/linking/cid"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/pkg/errors"
+)
+
+// ResolveDataModelPath follows an IPLD data model path, e.g. `Links/0/Hash`,
+// from the root block of a DAG and returns the CID of the block the path ends
+// in. Links met along the path, including a link at its end, are followed.
+func (dag *DAG) ResolveDataModelPath(ctx context.Context, root cid.Cid, path string) (cid.Cid, error) {
+	chooser := dagpb.AddDagPBSupportToChooser(func(ipldprime.Link, ipldprime.LinkContext) (ipldprime.NodeStyle, error) {
+		return basicnode.Style.Any, nil
+	})
+	loader := func(lnk ipldprime.Link, _ ipldprime.LinkContext) (io.Reader, error) {
+		nd, err := dag.dserv.Get(ctx, lnk.(cidlink.Link).Cid)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(nd.RawData()), nil
+	}
+	load := func(c cid.Cid) (ipldprime.Node, error) {
+		lnk := cidlink.Link{Cid: c}
+		style, err := chooser(lnk, ipldprime.LinkContext{})
+		if err != nil {
+			return nil, err
+		}
+		nb := style.NewBuilder()
+		if err := lnk.Load(ctx, ipldprime.LinkContext{}, nb, loader); err != nil {
+			return nil, errors.Wrapf(err, "failed to load %s", c)
+		}
+		return nb.Build(), nil
+	}
+
+	current := root
+	nd, err := load(current)
+	if err != nil {
+		return cid.Undef, err
+	}
+	for _, seg := range ipldprime.ParsePath(path).Segments() {
+		nd, err = nd.LookupSegment(seg)
+		if err != nil {
+			return cid.Undef, errors.Wrapf(err, "failed to resolve %s in %s", seg, current)
+		}
+		if nd.ReprKind() != ipldprime.ReprKind_Link {
+			continue
+		}
+		lnk, err := nd.AsLink()
+		if err != nil {
+			return cid.Undef, err
+		}
+		cl, ok := lnk.(cidlink.Link)
+		if !ok {
+			return cid.Undef, errors.Errorf("unsupported link %s in %s", lnk, current)
+		}
+		current = cl.Cid
+		if nd, err = load(current); err != nil {
+			return cid.Undef, err
+		}
+	}
+	return current, nil
+}
@@ -0,0 +1,59 @@
+package dag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-datastore"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestResolveDataModelPath(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	bs := blockstore.NewBlockstore(datastore.NewMapDatastore())
+	dserv := merkledag.NewDAGService(blockservice.New(bs, offline.Exchange(bs)))
+	dag := NewDAG(dserv)
+
+	other := merkledag.NewRawNode([]byte("other"))
+	file := merkledag.NewRawNode([]byte("file"))
+	dir := &merkledag.ProtoNode{}
+	require.NoError(t, dir.AddNodeLink("other", other))
+	require.NoError(t, dir.AddNodeLink("file", file))
+	root := &merkledag.ProtoNode{}
+	require.NoError(t, root.AddNodeLink("dir", dir))
+	require.NoError(t, dserv.AddMany(ctx, []format.Node{other, file, dir, root}))
+
+	t.Run("empty path is the root", func(t *testing.T) {
+		c, err := dag.ResolveDataModelPath(ctx, root.Cid(), "")
+		require.NoError(t, err)
+		assert.Equal(t, root.Cid(), c)
+	})
+
+	t.Run("follows links along the path", func(t *testing.T) {
+		// dag-pb links are encoded sorted by name
+		c, err := dag.ResolveDataModelPath(ctx, root.Cid(), "Links/0/Hash/Links/0/Hash")
+		require.NoError(t, err)
+		assert.Equal(t, file.Cid(), c)
+	})
+
+	t.Run("path ending inside a block is that block", func(t *testing.T) {
+		c, err := dag.ResolveDataModelPath(ctx, root.Cid(), "Links/0/Hash/Links/1/Name")
+		require.NoError(t, err)
+		assert.Equal(t, dir.Cid(), c)
+	})
+
+	t.Run("missing segment fails", func(t *testing.T) {
+		_, err := dag.ResolveDataModelPath(ctx, root.Cid(), "Links/3/Hash")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,309 @@
+package msg
+
+import (
+	"context"
+	"sync"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	e "github.com/filecoin-project/go-filecoin/internal/pkg/enccid"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
+)
+
+// ReceiptIndexDSPrefix is the prefix of the chain datastore keys of the
+// receipts index.
+const ReceiptIndexDSPrefix = "/chain/receipts"
+
+var (
+	indexedHeadKey    = datastore.NewKey("/head")
+	indexedMessageKey = datastore.NewKey("/messages")
+)
+
+// Abstracts over the chain store for the index.
+type indexChainReader interface {
+	GetTipSet(block.TipSetKey) (block.TipSet, error)
+	GetTipSetReceiptsRoot(block.TipSetKey) (cid.Cid, error)
+}
+
+// IndexedReceipt is a message on chain with the tipset including it and its
+// receipt, as recorded by the receipts index.
+type IndexedReceipt struct {
+	_       struct{} `cbor:",toarray"`
+	Message *types.SignedMessage
+	TipSet  block.TipSetKey
+	Height  abi.ChainEpoch
+	// Block is the block of the tipset in which the message appears first.
+	Block   e.Cid
+	Receipt vm.MessageReceipt
+}
+
+// ReceiptIndex maps the CIDs of the messages on chain to their receipt and the
+// tipset including them. It is updated with each new head, following reorgs,
+// and indexes the whole chain when first updated. Its methods are thread safe.
+type ReceiptIndex struct {
+	chain    indexChainReader
+	messages chain.MessageProvider
+	chainDs  repo.Datastore
+	ds       datastore.Batching
+
+	lk       sync.Mutex
+	pending  block.TipSet
+	indexing bool
+}
+
+// NewReceiptIndex creates a receipts index of the chain in `chainDs`.
+func NewReceiptIndex(chainReader indexChainReader, messages chain.MessageProvider, chainDs repo.Datastore) *ReceiptIndex {
+	return &ReceiptIndex{
+		chain:    chainReader,
+		messages: messages,
+		chainDs:  chainDs,
+		ds:       namespace.Wrap(chainDs, datastore.NewKey(ReceiptIndexDSPrefix)),
+	}
+}
+
+// Lookup returns the indexed receipt of the message with CID `msgCid`, as
+// the message appears on chain, and whether it was found.
+func (ix *ReceiptIndex) Lookup(msgCid cid.Cid) (*IndexedReceipt, bool, error) {
+	raw, err := ix.ds.Get(indexedMessageKey.ChildString(msgCid.String()))
+	if err == datastore.ErrNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to read indexed receipt")
+	}
+	var entry IndexedReceipt
+	if err := encoding.Decode(raw, &entry); err != nil {
+		return nil, false, errors.Wrapf(err, "failed to decode indexed receipt of %s", msgCid)
+	}
+	return &entry, true, nil
+}
+
+// HandleNewHead updates the index to a new head in the background. Heads
+// arriving while the index is updated are indexed after. It does not block.
+func (ix *ReceiptIndex) HandleNewHead(ctx context.Context, head block.TipSet) {
+	ix.lk.Lock()
+	defer ix.lk.Unlock()
+	ix.pending = head
+	if ix.indexing {
+		return
+	}
+	ix.indexing = true
+	go func() {
+		for {
+			ix.lk.Lock()
+			head := ix.pending
+			ix.pending = block.UndefTipSet
+			if !head.Defined() || ctx.Err() != nil {
+				ix.indexing = false
+				ix.lk.Unlock()
+				return
+			}
+			ix.lk.Unlock()
+
+			if err := ix.Update(ctx, head); err != nil {
+				log.Warnf("failed to index receipts up to %s: %s", head.Key(), err)
+			}
+		}
+	}()
+}
+
+// Update indexes the messages of the tipsets up to `head` not indexed yet and
+// removes those of the tipsets no longer on the chain of `head`.
+func (ix *ReceiptIndex) Update(ctx context.Context, head block.TipSet) error {
+	indexed, err := ix.indexedHead()
+	if err != nil {
+		return err
+	}
+
+	var dropped, added []block.TipSet
+	if indexed.Empty() {
+		added, err = ix.unindexedChain(ctx, head)
+	} else {
+		var prev block.TipSet
+		if prev, err = ix.chain.GetTipSet(indexed); err != nil {
+			return errors.Wrap(err, "failed to load indexed head")
+		}
+		dropped, added, err = chain.CollectTipsToCommonAncestor(ctx, ix.chain, prev, head)
+	}
+	if err != nil {
+		return err
+	}
+
+	batch, err := ix.ds.Batch()
+	if err != nil {
+		return err
+	}
+	removed := make(map[cid.Cid]bool)
+	for _, ts := range dropped {
+		entries, err := ix.tipSetEntries(ctx, ts)
+		if err != nil {
+			return err
+		}
+		for c := range entries {
+			current, found, err := ix.Lookup(c)
+			if err != nil {
+				return err
+			}
+			if found && current.TipSet.Equals(ts.Key()) {
+				if err := batch.Delete(indexedMessageKey.ChildString(c.String())); err != nil {
+					return err
+				}
+				removed[c] = true
+			}
+		}
+	}
+	// Added tipsets are ordered by decreasing height. A message included more
+	// than once is indexed at its first inclusion, which may be indexed already.
+	indexedNow := make(map[cid.Cid]bool)
+	for i := len(added) - 1; i >= 0; i-- {
+		entries, err := ix.tipSetEntries(ctx, added[i])
+		if err != nil {
+			return err
+		}
+		for c, entry := range entries {
+			if indexedNow[c] {
+				continue
+			}
+			if _, found, err := ix.Lookup(c); err != nil {
+				return err
+			} else if found && !removed[c] {
+				continue
+			}
+			indexedNow[c] = true
+			raw, err := encoding.Encode(entry)
+			if err != nil {
+				return err
+			}
+			if err := batch.Put(indexedMessageKey.ChildString(c.String()), raw); err != nil {
+				return err
+			}
+		}
+	}
+	raw, err := encoding.Encode(head.Key())
+	if err != nil {
+		return err
+	}
+	if err := batch.Put(indexedHeadKey, raw); err != nil {
+		return err
+	}
+	return batch.Commit()
+}
+
+// unindexedChain returns the tipsets of the chain of `head` whose messages
+// have not been pruned, ordered by decreasing height.
+func (ix *ReceiptIndex) unindexedChain(ctx context.Context, head block.TipSet) ([]block.TipSet, error) {
+	prunedHeight, err := chain.ReadPrunedHeight(ix.chainDs)
+	if err != nil {
+		return nil, err
+	}
+	var tips []block.TipSet
+	for it := chain.IterAncestors(ctx, ix.chain, head); !it.Complete(); err = it.Next() {
+		if err != nil {
+			return nil, err
+		}
+		h, err := it.Value().Height()
+		if err != nil {
+			return nil, err
+		}
+		if h > 0 && h <= prunedHeight {
+			log.Infof("receipts of messages at and below height %d were pruned and are not indexed", prunedHeight)
+			break
+		}
+		tips = append(tips, it.Value())
+	}
+	return tips, nil
+}
+
+func (ix *ReceiptIndex) indexedHead() (block.TipSetKey, error) {
+	raw, err := ix.ds.Get(indexedHeadKey)
+	if err == datastore.ErrNotFound {
+		return block.TipSetKey{}, nil
+	}
+	if err != nil {
+		return block.TipSetKey{}, errors.Wrap(err, "failed to read indexed head")
+	}
+	var key block.TipSetKey
+	if err := encoding.Decode(raw, &key); err != nil {
+		return block.TipSetKey{}, errors.Wrap(err, "failed to decode indexed head")
+	}
+	return key, nil
+}
+
+// tipSetEntries returns the entries of the messages of `ts`, keyed by the CIDs
+// of the messages as they appear on chain. Receipts are ordered as the
+// de-duplicated messages of the blocks, BLS messages before SECP messages
+// within each block.
+func (ix *ReceiptIndex) tipSetEntries(ctx context.Context, ts block.TipSet) (map[cid.Cid]*IndexedReceipt, error) {
+	height, err := ts.Height()
+	if err != nil {
+		return nil, err
+	}
+	receiptsRoot, err := ix.chain.GetTipSetReceiptsRoot(ts.Key())
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := ix.messages.LoadReceipts(ctx, receiptsRoot)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load receipts of tipset at height %d", height)
+	}
+
+	entries := make(map[cid.Cid]*IndexedReceipt)
+	receiptIndexes := make(map[cid.Cid]int)
+	for i := 0; i < ts.Len(); i++ {
+		blk := ts.At(i)
+		secpMsgs, blsMsgs, err := ix.messages.LoadMessages(ctx, blk.Messages.Cid)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load messages of block %s", blk.Cid())
+		}
+
+		wrapped := make([]*types.SignedMessage, 0, len(blsMsgs)+len(secpMsgs))
+		for _, msg := range blsMsgs {
+			wrapped = append(wrapped, &types.SignedMessage{Message: *msg})
+		}
+		wrapped = append(wrapped, secpMsgs...)
+
+		for j, msg := range wrapped {
+			// BLS messages appear on chain unsigned
+			original, err := msg.Cid()
+			if j < len(blsMsgs) {
+				original, err = msg.Message.Cid()
+			}
+			if err != nil {
+				return nil, err
+			}
+			unwrapped, err := msg.Message.Cid()
+			if err != nil {
+				return nil, err
+			}
+
+			idx, ok := receiptIndexes[unwrapped]
+			if !ok {
+				idx = len(receiptIndexes)
+				receiptIndexes[unwrapped] = idx
+			}
+			if _, ok := entries[original]; ok {
+				continue
+			}
+			if idx >= len(receipts) {
+				return nil, errors.Errorf("could not find message receipt at index %d", idx)
+			}
+			entries[original] = &IndexedReceipt{
+				Message: msg,
+				TipSet:  ts.Key(),
+				Height:  height,
+				Block:   e.NewCid(blk.Cid()),
+				Receipt: receipts[idx],
+			}
+		}
+	}
+	return entries, nil
+}
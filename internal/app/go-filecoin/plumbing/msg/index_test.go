@@ -0,0 +1,100 @@
+package msg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
+	gengen "github.com/filecoin-project/go-filecoin/tools/gengen/util"
+)
+
+func TestReceiptIndex(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	d := requiredCommonDeps(t, gengen.DefaultGenesis)
+	index := NewReceiptIndex(d.chainStore, d.messages, d.repo.ChainDatastore())
+	waiter := NewWaiter(d.chainStore, d.messages, d.blockstore, d.cst)
+	waiter.UseReceiptIndex(index)
+
+	putChain := func(tips []block.TipSet) block.TipSet {
+		for _, ts := range tips[1:] {
+			require.NoError(t, d.chainStore.PutTipSetMetadata(ctx, &chain.TipSetMetadata{
+				TipSet:          ts,
+				TipSetStateRoot: ts.At(0).StateRoot.Cid,
+				TipSetReceipts:  ts.At(0).MessageReceipts.Cid,
+			}))
+		}
+		head := tips[len(tips)-1]
+		require.NoError(t, d.chainStore.SetHead(ctx, head))
+		return head
+	}
+	requireLookup := func(m *types.SignedMessage) (*IndexedReceipt, bool) {
+		c, err := m.Cid()
+		require.NoError(t, err)
+		entry, found, err := index.Lookup(c)
+		require.NoError(t, err)
+		return entry, found
+	}
+
+	genesis, err := d.chainStore.GetTipSet(d.chainStore.GetHead())
+	require.NoError(t, err)
+	m1, m2, m3, m4 := newSignedMessage(), newSignedMessage(), newSignedMessage(), newSignedMessage()
+	m5, m6 := newSignedMessage(), newSignedMessage()
+
+	t.Log("the whole chain is indexed on the first update")
+	tips := newChainWithMessages(d.cst, d.messages, genesis, smsgsSet{smsgs{m1}}, smsgsSet{smsgs{m5}}, smsgsSet{smsgs{m2}, smsgs{m3}})
+	head := putChain(tips)
+	require.NoError(t, index.Update(ctx, head))
+
+	entry, found := requireLookup(m1)
+	require.True(t, found)
+	assert.Equal(t, tips[1].Key(), entry.TipSet)
+	assert.Equal(t, tips[1].At(0).Cid(), entry.Block.Cid)
+	assert.True(t, types.SmsgCidsEqual(m1, entry.Message))
+	c1, err := m1.Cid()
+	require.NoError(t, err)
+	assert.Equal(t, c1.Bytes(), entry.Receipt.ReturnValue)
+	entry, found = requireLookup(m3)
+	require.True(t, found)
+	assert.Equal(t, tips[3].Key(), entry.TipSet)
+	c3, err := m3.Cid()
+	require.NoError(t, err)
+	assert.Equal(t, c3.Bytes(), entry.Receipt.ReturnValue)
+
+	t.Log("the waiter finds indexed messages beyond its lookback")
+	found = false
+	err = waiter.Wait(ctx, c1, 1, func(blk *block.Block, msg *types.SignedMessage, receipt *vm.MessageReceipt) error {
+		found = true
+		assert.Equal(t, tips[1].At(0).Cid(), blk.Cid())
+		assert.Equal(t, c1.Bytes(), receipt.ReturnValue)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	t.Log("reorgs remove the messages of the tipsets no longer on chain")
+	fork := newChainWithMessages(d.cst, d.messages, tips[1], smsgsSet{smsgs{m2}}, smsgsSet{smsgs{m4}}, smsgsSet{smsgs{m6}})
+	head = putChain(fork)
+	require.NoError(t, index.Update(ctx, head))
+
+	_, found = requireLookup(m1)
+	assert.True(t, found)
+	entry, found = requireLookup(m2)
+	require.True(t, found)
+	assert.Equal(t, fork[1].Key(), entry.TipSet)
+	_, found = requireLookup(m3)
+	assert.False(t, found)
+	_, found = requireLookup(m5)
+	assert.False(t, found)
+	entry, found = requireLookup(m4)
+	require.True(t, found)
+	assert.Equal(t, fork[2].Key(), entry.TipSet)
+}
@@ -5,10 +5,15 @@ import (
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
 	bstore "github.com/ipfs/go-ipfs-blockstore"
 	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/state"
 )
@@ -16,12 +21,13 @@ import (
 // Abstracts over a store of blockchain state.
 type previewerChainReader interface {
 	GetHead() block.TipSetKey
-	GetTipSetState(context.Context, block.TipSetKey) (state.Tree, error)
+	GetTipSetStateRoot(block.TipSetKey) (cid.Cid, error)
 	GetTipSet(block.TipSetKey) (block.TipSet, error)
 }
 
-// Dragons: delete
+// Applies messages to a state without committing it.
 type messagePreviewer interface {
+	PreviewMessages(ctx context.Context, st state.Tree, vms vm.Storage, ts block.TipSet, msgs []*types.SignedMessage) ([]vm.MessageReceipt, error)
 }
 
 // Previewer calculates the amount of Gas needed for a command
@@ -43,5 +49,84 @@ func NewPreviewer(chainReader previewerChainReader, cst cbor.IpldStore, bs bstor
 
 // Preview sends a read-only message to an actor.
 func (p *Previewer) Preview(ctx context.Context, optFrom, to address.Address, method abi.MethodNum, params ...interface{}) (gas.Unit, error) {
-	panic("unimplemented")
+	var encodedParams []byte
+	switch len(params) {
+	case 0:
+		encodedParams = []byte{}
+	case 1:
+		var err error
+		encodedParams, err = encoding.Encode(params[0])
+		if err != nil {
+			return gas.Zero, errors.Wrap(err, "invalid params")
+		}
+	default:
+		return gas.Zero, errors.New("a message takes a single parameter")
+	}
+
+	st, ts, err := p.headState(ctx)
+	if err != nil {
+		return gas.Zero, err
+	}
+	fromActor, found, err := st.GetActor(ctx, optFrom)
+	if err != nil {
+		return gas.Zero, err
+	}
+	if !found {
+		return gas.Zero, errors.Errorf("no actor at address %s", optFrom)
+	}
+
+	// the message is charged no gas, so that the sender need not hold the funds
+	msg := types.NewMeteredMessage(optFrom, to, fromActor.CallSeqNum, types.ZeroAttoFIL, method, encodedParams, types.ZeroAttoFIL, types.BlockGasLimit)
+	receipts, err := p.processor.PreviewMessages(ctx, st, vm.NewStorage(p.bs), ts, []*types.SignedMessage{{Message: *msg}})
+	if err != nil {
+		return gas.Zero, err
+	}
+	receipt := receipts[0]
+	if receipt.ExitCode.IsError() {
+		return receipt.GasUsed, errors.Errorf("message failed with exit code %d", receipt.ExitCode)
+	}
+	return receipt.GasUsed, nil
+}
+
+// DryRun is a message that was built, signed and applied to the state of the
+// head, but not sent.
+type DryRun struct {
+	Message *types.SignedMessage
+	// Cid is the CID the message would have on chain.
+	Cid     cid.Cid
+	Receipt vm.MessageReceipt
+}
+
+// Simulate applies messages in order to the state of the head, together with
+// their signatures, as if they were included in the next block, and returns
+// their receipts. Nothing is stored: the state is discarded.
+func (p *Previewer) Simulate(ctx context.Context, msgs []*types.SignedMessage) ([]vm.MessageReceipt, error) {
+	st, ts, err := p.headState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.processor.PreviewMessages(ctx, st, vm.NewStorage(p.bs), ts, msgs)
+}
+
+// headState returns a fork of the state of the head, keeping its writes in
+// memory.
+func (p *Previewer) headState(ctx context.Context) (*state.State, block.TipSet, error) {
+	head := p.chainReader.GetHead()
+	ts, err := p.chainReader.GetTipSet(head)
+	if err != nil {
+		return nil, block.UndefTipSet, err
+	}
+	root, err := p.chainReader.GetTipSetStateRoot(head)
+	if err != nil {
+		return nil, block.UndefTipSet, err
+	}
+	st, err := state.LoadState(ctx, p.cst, root)
+	if err != nil {
+		return nil, block.UndefTipSet, err
+	}
+	fork, err := st.Fork(ctx)
+	if err != nil {
+		return nil, block.UndefTipSet, err
+	}
+	return fork, ts, nil
 }
@@ -37,6 +37,8 @@ type Waiter struct {
 	messageProvider chain.MessageProvider
 	cst             cbor.IpldStore
 	bs              bstore.Blockstore
+	// index finds messages at any depth when set, see UseReceiptIndex.
+	index *ReceiptIndex
 }
 
 // ChainMessage is an on-chain message with its block and receipt.
@@ -59,6 +61,34 @@ func NewWaiter(chainStore waiterChainReader, messages chain.MessageProvider, bs
 	}
 }
 
+// UseReceiptIndex makes the waiter look messages up in `index` before
+// searching the blockchain.
+func (w *Waiter) UseReceiptIndex(index *ReceiptIndex) {
+	w.index = index
+}
+
+// Lookup returns the message with the given cid from the receipts index, and
+// whether it was found. It is never found when the waiter uses no index.
+func (w *Waiter) Lookup(ctx context.Context, msgCid cid.Cid) (*ChainMessage, bool, error) {
+	if w.index == nil {
+		return nil, false, nil
+	}
+	entry, found, err := w.index.Lookup(msgCid)
+	if err != nil || !found {
+		return nil, false, err
+	}
+	ts, err := w.chainReader.GetTipSet(entry.TipSet)
+	if err != nil {
+		return nil, false, err
+	}
+	for i := 0; i < ts.Len(); i++ {
+		if blk := ts.At(i); blk.Cid().Equals(entry.Block.Cid) {
+			return &ChainMessage{Message: entry.Message, Block: blk, Receipt: &entry.Receipt}, true, nil
+		}
+	}
+	return nil, false, errors.Errorf("indexed block %s of message %s is not in tipset %s", entry.Block.Cid, msgCid, entry.TipSet)
+}
+
 // Find searches the blockchain history (but doesn't wait).
 func (w *Waiter) Find(ctx context.Context, lookback uint64, pred WaitPredicate) (*ChainMessage, bool, error) {
 	headTipSet, err := w.chainReader.GetTipSet(w.chainReader.GetHead())
@@ -112,6 +142,16 @@ func (w *Waiter) WaitPredicate(ctx context.Context, lookback uint64, pred WaitPr
 func (w *Waiter) Wait(ctx context.Context, msgCid cid.Cid, lookback uint64, cb func(*block.Block, *types.SignedMessage, *vm.MessageReceipt) error) error {
 	log.Infof("Calling Waiter.Wait CID: %s", msgCid.String())
 
+	// The index answers for messages at any depth, more recent messages may not
+	// be indexed yet.
+	chainMsg, found, err := w.Lookup(ctx, msgCid)
+	if err != nil {
+		return err
+	}
+	if found {
+		return cb(chainMsg.Block, chainMsg.Message, chainMsg.Receipt)
+	}
+
 	pred := func(msg *types.SignedMessage, c cid.Cid) bool {
 		return c.Equals(msgCid)
 	}
@@ -8,11 +8,15 @@ import (
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/specs-actors/actors/abi"
 	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
 	"github.com/libp2p/go-libp2p-core/peer"
 
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/plumbing"
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/plumbing/msg"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/piecemanager"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/scrubber"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
@@ -85,16 +89,53 @@ func (a *API) MinerPreviewCreate(
 	return MinerPreviewCreate(ctx, a, fromAddr, sectorSize, pid)
 }
 
+// MinerCreateDryRun builds the message creating a miner and applies it to the
+// current state without sending it.
+func (a *API) MinerCreateDryRun(
+	ctx context.Context,
+	accountAddr address.Address,
+	gasPrice types.AttoFIL,
+	gasLimit gas.Unit,
+	sealProofType abi.RegisteredProof,
+	pid peer.ID,
+	collateral types.AttoFIL,
+) (*msg.DryRun, address.Address, error) {
+	return MinerCreateDryRun(ctx, a, accountAddr, gasPrice, gasLimit, sealProofType, pid, collateral)
+}
+
 // MinerGetStatus queries for status of a miner.
 func (a *API) MinerGetStatus(ctx context.Context, minerAddr address.Address, baseKey block.TipSetKey) (MinerStatus, error) {
 	return MinerGetStatus(ctx, a, minerAddr, baseKey)
 }
 
+// PowerTable returns the power of all miners at a tipset, ranked by quality
+// adjusted power. The head is used when the key is empty.
+func (a *API) PowerTable(ctx context.Context, key block.TipSetKey) (*PowerTable, error) {
+	return PowerTableAt(ctx, a, key)
+}
+
+// MinerPowerHistory returns the power of a miner at each tipset of the head
+// chain with a height from `from` to `to`.
+func (a *API) MinerPowerHistory(ctx context.Context, minerAddr address.Address, from, to abi.ChainEpoch) ([]PowerHistoryEntry, error) {
+	return MinerPowerHistory(ctx, a, minerAddr, from, to)
+}
+
 // ProtocolParameters fetches the current protocol configuration parameters.
 func (a *API) ProtocolParameters(ctx context.Context) (*ProtocolParams, error) {
 	return ProtocolParameters(ctx, a)
 }
 
+// SupportedSealProofType returns the seal proof type of sectors of `sectorSize`
+// when new miners may commit them.
+func (a *API) SupportedSealProofType(sectorSize abi.SectorSize) (abi.RegisteredProof, error) {
+	return SupportedSealProofType(a, sectorSize)
+}
+
+// ProtocolUpgrades lists the protocol upgrades of the network and their status.
+func (a *API) ProtocolUpgrades(ctx context.Context) ([]ProtocolUpgrade, error) {
+	return ProtocolUpgrades(ctx, a)
+}
+
 // WalletBalance returns the current balance of the given wallet address.
 func (a *API) WalletBalance(ctx context.Context, address address.Address) (abi.TokenAmount, error) {
 	return WalletBalance(ctx, a, address)
@@ -106,9 +147,26 @@ func (a *API) WalletDefaultAddress() (address.Address, error) {
 	return WalletDefaultAddress(a)
 }
 
+// WalletDealAddress returns the address that proposes and signs storage deals,
+// the default wallet address unless a deal key is set in the config.
+func (a *API) WalletDealAddress() (address.Address, error) {
+	return WalletDealAddress(a)
+}
+
 // SealPieceIntoNewSector writes the provided piece into a new sector
-func (a *API) SealPieceIntoNewSector(ctx context.Context, dealID abi.DealID, dealStart, dealEnd abi.ChainEpoch, pieceSize abi.UnpaddedPieceSize, pieceReader io.Reader) error {
-	return SealPieceIntoNewSector(ctx, a, dealID, dealStart, dealEnd, pieceSize, pieceReader)
+func (a *API) SealPieceIntoNewSector(ctx context.Context, dealID abi.DealID, dealStart, dealEnd abi.ChainEpoch, pieceCID cid.Cid, pieceSize abi.UnpaddedPieceSize, pieceReader io.Reader) error {
+	return SealPieceIntoNewSector(ctx, a, dealID, dealStart, dealEnd, pieceCID, pieceSize, pieceReader)
+}
+
+// MinerListPieces lists the deal pieces stored in the miner's sectors
+func (a *API) MinerListPieces(ctx context.Context) ([]piecemanager.PieceInfo, error) {
+	return MinerListPieces(ctx, a)
+}
+
+// MinerScrubSectors checks the sealed sectors of the node's miner, or returns
+// the report of the last check
+func (a *API) MinerScrubSectors(ctx context.Context, now bool) (*scrubber.Report, error) {
+	return MinerScrubSectors(ctx, a, now)
 }
 
 // PingMinerWithTimeout pings a storage or retrieval miner, waiting the given
@@ -126,6 +184,16 @@ func (a *API) MinerSetWorkerAddress(ctx context.Context, toAddr address.Address,
 	return MinerSetWorkerAddress(ctx, a, toAddr, gasPrice, gasLimit)
 }
 
+// MinerProposeWorker proposes to change the worker of the node's miner
+func (a *API) MinerProposeWorker(ctx context.Context, newWorker address.Address, funds types.AttoFIL, gasPrice types.AttoFIL, gasLimit gas.Unit) (MinerWorkerRotation, error) {
+	return MinerProposeWorker(ctx, a, newWorker, funds, gasPrice, gasLimit)
+}
+
+// MinerConfirmWorker confirms the change of the worker of the node's miner
+func (a *API) MinerConfirmWorker(ctx context.Context, proposal cid.Cid) (MinerWorkerRotation, error) {
+	return MinerConfirmWorker(ctx, a, proposal)
+}
+
 // MessageWaitDone blocks until the message is on chain
 func (a *API) MessageWaitDone(ctx context.Context, msgCid cid.Cid) (*vm.MessageReceipt, error) {
 	return MessageWaitDone(ctx, a, msgCid)
@@ -146,3 +214,24 @@ func (a *API) FaultsStateView(baseKey block.TipSetKey) (consensus.FaultStateView
 func (a *API) ProtocolStateView(baseKey block.TipSetKey) (ProtocolStateView, error) {
 	return a.StateView(baseKey)
 }
+
+func (a *API) PowerTableStateView(baseKey block.TipSetKey) (PowerTableStateView, error) {
+	return a.StateView(baseKey)
+}
+
+// SyncSetTrusted marks a peer as trusted or untrusted by the syncer and persists the change.
+func (a *API) SyncSetTrusted(pid peer.ID, trusted bool) error {
+	return SyncSetTrusted(a, pid, trusted)
+}
+
+// SyncRestrictToTrusted sets whether the syncer only accepts chain heads from trusted peers
+// and persists the change.
+func (a *API) SyncRestrictToTrusted(only bool) error {
+	return SyncRestrictToTrusted(a, only)
+}
+
+// ClientImportData imports data into the node's dag, announcing its root if the
+// node's provide strategy calls for it.
+func (a *API) ClientImportData(ctx context.Context, data io.Reader) (ipld.Node, error) {
+	return ClientImportData(ctx, a, data)
+}
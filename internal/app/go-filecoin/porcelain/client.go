@@ -1,12 +1,21 @@
 package porcelain
 
 import (
+	"context"
+	"io"
+
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	logging "github.com/ipfs/go-log/v2"
 
+	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 )
 
+var log = logging.Logger("porcelain")
+
 // Ask is a result of querying for an ask, it may contain an error
 type Ask struct {
 	Miner  address.Address
@@ -16,3 +25,33 @@ type Ask struct {
 
 	Error error
 }
+
+type clientImportPlumbing interface {
+	ConfigGet(dottedPath string) (interface{}, error)
+	DAGImportData(ctx context.Context, data io.Reader) (ipld.Node, error)
+	NetworkProvide(ctx context.Context, key cid.Cid) error
+}
+
+// ClientImportData imports data into the node's merkledag. When the node's
+// bitswap provide strategy is "roots", the root of the imported data is
+// announced to the network so that other nodes can find it.
+func ClientImportData(ctx context.Context, plumbing clientImportPlumbing, data io.Reader) (ipld.Node, error) {
+	nd, err := plumbing.DAGImportData(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	strategy, err := plumbing.ConfigGet("bitswap.provideStrategy")
+	if err != nil {
+		return nil, err
+	}
+	if strategy == config.ProvideRoots {
+		// Data is available locally whether or not the announcement succeeds,
+		// e.g. an offline node cannot provide.
+		if err := plumbing.NetworkProvide(ctx, nd.Cid()); err != nil {
+			log.Warnf("failed to provide imported data %s: %s", nd.Cid(), err)
+		}
+	}
+
+	return nd, nil
+}
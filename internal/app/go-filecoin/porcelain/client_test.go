@@ -0,0 +1,74 @@
+package porcelain_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+type clientImportPlumbing struct {
+	strategy   string
+	provideErr error
+	provided   []cid.Cid
+}
+
+func (cip *clientImportPlumbing) ConfigGet(dottedPath string) (interface{}, error) {
+	if dottedPath != "bitswap.provideStrategy" {
+		return nil, errors.New("unexpected config key")
+	}
+	return cip.strategy, nil
+}
+
+func (cip *clientImportPlumbing) DAGImportData(ctx context.Context, data io.Reader) (ipld.Node, error) {
+	raw, err := ioutil.ReadAll(data)
+	if err != nil {
+		return nil, err
+	}
+	return merkledag.NewRawNode(raw), nil
+}
+
+func (cip *clientImportPlumbing) NetworkProvide(ctx context.Context, key cid.Cid) error {
+	cip.provided = append(cip.provided, key)
+	return cip.provideErr
+}
+
+func TestClientImportData(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+	data := []byte("some client data")
+
+	t.Run("roots strategy provides the imported root", func(t *testing.T) {
+		plumbing := &clientImportPlumbing{strategy: config.ProvideRoots}
+		nd, err := porcelain.ClientImportData(ctx, plumbing, bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, []cid.Cid{nd.Cid()}, plumbing.provided)
+	})
+
+	t.Run("failing to provide does not fail the import", func(t *testing.T) {
+		plumbing := &clientImportPlumbing{strategy: config.ProvideRoots, provideErr: errors.New("offline")}
+		_, err := porcelain.ClientImportData(ctx, plumbing, bytes.NewReader(data))
+		require.NoError(t, err)
+	})
+
+	t.Run("other strategies make no explicit announcement", func(t *testing.T) {
+		for _, strategy := range []string{config.ProvideAll, config.ProvideNone} {
+			plumbing := &clientImportPlumbing{strategy: strategy}
+			_, err := porcelain.ClientImportData(ctx, plumbing, bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.Empty(t, plumbing.provided)
+		}
+	})
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/actor"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
 )
 
@@ -43,6 +44,7 @@ type MinerStateView interface {
 	PowerNetworkTotal(ctx context.Context) (*state.NetworkPower, error)
 	MinerClaimedPower(ctx context.Context, miner address.Address) (raw, qa abi.StoragePower, err error)
 	MinerInfo(ctx context.Context, maddr address.Address) (miner.MinerInfo, error)
+	AccountSignerAddress(ctx context.Context, a address.Address) (address.Address, error)
 }
 
 // MinerCreate creates a new miner actor for the given account and returns its address.
@@ -160,6 +162,71 @@ func MinerPreviewCreate(
 	return usedGas, nil
 }
 
+// mcdrAPI is the subset of the plumbing.API that MinerCreateDryRun uses.
+type mcdrAPI interface {
+	ConfigGet(dottedPath string) (interface{}, error)
+	MessageDryRun(ctx context.Context, from, to address.Address, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit gas.Unit, method abi.MethodNum, params interface{}) (*msg.DryRun, error)
+	WalletDefaultAddress() (address.Address, error)
+}
+
+// MinerCreateDryRun builds the message MinerCreate would send and applies it to
+// the current state without sending it. It returns the address the miner
+// would be created at when the message succeeds.
+func MinerCreateDryRun(
+	ctx context.Context,
+	plumbing mcdrAPI,
+	minerOwnerAddr address.Address,
+	gasPrice types.AttoFIL,
+	gasLimit gas.Unit,
+	sealProofType abi.RegisteredProof,
+	pid peer.ID,
+	collateral types.AttoFIL,
+) (_ *msg.DryRun, _ address.Address, err error) {
+	if minerOwnerAddr == (address.Address{}) {
+		minerOwnerAddr, err = plumbing.WalletDefaultAddress()
+		if err != nil {
+			return nil, address.Undef, err
+		}
+	}
+
+	addr, err := plumbing.ConfigGet("mining.minerAddress")
+	if err != nil {
+		return nil, address.Undef, err
+	}
+	if addr != address.Undef {
+		return nil, address.Undef, fmt.Errorf("can only have one miner per node")
+	}
+
+	params := power.CreateMinerParams{
+		Worker:        minerOwnerAddr,
+		Owner:         minerOwnerAddr,
+		Peer:          pid,
+		SealProofType: sealProofType,
+	}
+	dryRun, err := plumbing.MessageDryRun(
+		ctx,
+		minerOwnerAddr,
+		builtin.StoragePowerActorAddr,
+		collateral,
+		gasPrice,
+		gasLimit,
+		builtin.MethodsPower.CreateMiner,
+		&params,
+	)
+	if err != nil {
+		return nil, address.Undef, err
+	}
+	if dryRun.Receipt.ExitCode != exitcode.Ok {
+		return dryRun, address.Undef, nil
+	}
+
+	var result power.CreateMinerReturn
+	if err := encoding.Decode(dryRun.Receipt.ReturnValue, &result); err != nil {
+		return nil, address.Undef, err
+	}
+	return dryRun, result.RobustAddress, nil
+}
+
 // MinerSetPriceResponse collects relevant stats from the set price process
 type MinerSetPriceResponse struct {
 	MinerAddr address.Address
@@ -185,6 +252,9 @@ type MinerStatus struct {
 	OwnerAddress  address.Address
 	WorkerAddress address.Address
 	PeerID        peer.ID
+	// PendingWorker is the change of worker proposed by the owner, which takes
+	// effect at its height, nil when there is none.
+	PendingWorker *miner.WorkerKeyChange `json:",omitempty"`
 
 	SealProofType              abi.RegisteredProof
 	SectorSize                 abi.SectorSize
@@ -226,6 +296,7 @@ func MinerGetStatus(ctx context.Context, plumbing minerStatusPlumbing, minerAddr
 		OwnerAddress:  minerInfo.Owner,
 		WorkerAddress: minerInfo.Worker,
 		PeerID:        minerInfo.PeerId,
+		PendingWorker: minerInfo.PendingWorkerKey,
 
 		SealProofType:              minerInfo.SealProofType,
 		SectorSize:                 minerInfo.SectorSize,
@@ -285,6 +356,245 @@ func MinerSetWorkerAddress(
 		gasPrice,
 		gasLimit,
 		builtin.MethodsMiner.ChangeWorkerAddress,
-		&workerAddr)
+		&miner.ChangeWorkerAddressParams{NewWorker: workerAddr})
 	return c, err
 }
+
+// MinerWorkerRotation describes the change of the worker of a miner to a new
+// key, as proposed by its owner or once confirmed.
+type MinerWorkerRotation struct {
+	Miner address.Address
+	Owner address.Address
+	// Worker is the ID address of the worker actor, which is the former worker
+	// until the change takes effect.
+	Worker address.Address
+	// NewWorker is the key address of the new worker.
+	NewWorker address.Address
+	// EffectiveAt is the height at which the new worker replaces the former,
+	// only set by the proposal, and Message the CID of the message proposing
+	// the change.
+	EffectiveAt abi.ChainEpoch
+	Message     cid.Cid
+}
+
+// rotateWorkerAPI is the subset of the plumbing.API that MinerProposeWorker
+// and MinerConfirmWorker use.
+type rotateWorkerAPI interface {
+	ActorGet(ctx context.Context, addr address.Address) (*actor.Actor, error)
+	ConfigGet(dottedPath string) (interface{}, error)
+	ChainHeadKey() block.TipSetKey
+	ChainTipSet(key block.TipSetKey) (block.TipSet, error)
+	MinerStateView(baseKey block.TipSetKey) (MinerStateView, error)
+	MessageSend(ctx context.Context, from, to address.Address, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit gas.Unit, method abi.MethodNum, params interface{}) (cid.Cid, chan error, error)
+	MessageWait(ctx context.Context, msgCid cid.Cid, lookback uint64, cb func(*block.Block, *types.SignedMessage, *vm.MessageReceipt) error) error
+	WalletAddresses() []address.Address
+	WalletNewAddress(protocol address.Protocol) (address.Address, error)
+}
+
+// MinerProposeWorker proposes to change the worker of the node's miner to the
+// BLS key `newWorker`, which must be in the wallet, or to a new key created in
+// the wallet when `newWorker` is undefined. The owner first sends `funds` to
+// the new worker, which creates its account actor when it has none yet, then
+// sends the change to the miner actor, which makes it take effect after the
+// miner.WorkerKeyChangeDelay. The former worker signs blocks and messages of the
+// miner until then.
+func MinerProposeWorker(
+	ctx context.Context,
+	plumbing rotateWorkerAPI,
+	newWorker address.Address,
+	funds types.AttoFIL,
+	gasPrice types.AttoFIL,
+	gasLimit gas.Unit,
+) (MinerWorkerRotation, error) {
+	minerAddr, err := configuredMinerAddress(plumbing)
+	if err != nil {
+		return MinerWorkerRotation{}, err
+	}
+
+	if newWorker.Empty() {
+		newWorker, err = plumbing.WalletNewAddress(address.BLS)
+		if err != nil {
+			return MinerWorkerRotation{}, errors.Wrap(err, "could not create the new worker key")
+		}
+	} else if newWorker.Protocol() != address.BLS {
+		return MinerWorkerRotation{}, fmt.Errorf("new worker %s must be a BLS key address", newWorker)
+	} else if !walletHasAddress(plumbing, newWorker) {
+		return MinerWorkerRotation{}, fmt.Errorf("new worker %s is not in the wallet, import its key first", newWorker)
+	}
+
+	view, err := plumbing.MinerStateView(plumbing.ChainHeadKey())
+	if err != nil {
+		return MinerWorkerRotation{}, err
+	}
+	info, err := view.MinerInfo(ctx, minerAddr)
+	if err != nil {
+		return MinerWorkerRotation{}, errors.Wrap(err, "could not get miner control addresses")
+	}
+	ownerKey, err := view.AccountSignerAddress(ctx, info.Owner)
+	if err != nil {
+		return MinerWorkerRotation{}, errors.Wrap(err, "could not get miner owner key")
+	}
+	if !walletHasAddress(plumbing, ownerKey) {
+		return MinerWorkerRotation{}, fmt.Errorf("owner %s of miner %s is not in the wallet", ownerKey, minerAddr)
+	}
+
+	if _, err := plumbing.ActorGet(ctx, newWorker); err != nil || funds.GreaterThan(types.ZeroAttoFIL) {
+		transfer, _, err := plumbing.MessageSend(ctx, ownerKey, newWorker, funds, gasPrice, gasLimit, builtin.MethodSend, nil)
+		if err != nil {
+			return MinerWorkerRotation{}, err
+		}
+		if err := waitSuccess(ctx, plumbing, transfer); err != nil {
+			return MinerWorkerRotation{}, errors.Wrap(err, "could not fund the new worker")
+		}
+	}
+
+	change, _, err := plumbing.MessageSend(
+		ctx,
+		ownerKey,
+		minerAddr,
+		types.ZeroAttoFIL,
+		gasPrice,
+		gasLimit,
+		builtin.MethodsMiner.ChangeWorkerAddress,
+		&miner.ChangeWorkerAddressParams{NewWorker: newWorker},
+	)
+	if err != nil {
+		return MinerWorkerRotation{}, err
+	}
+	if err := waitSuccess(ctx, plumbing, change); err != nil {
+		return MinerWorkerRotation{}, errors.Wrap(err, "could not change the worker")
+	}
+
+	view, err = plumbing.MinerStateView(plumbing.ChainHeadKey())
+	if err != nil {
+		return MinerWorkerRotation{}, err
+	}
+	info, err = view.MinerInfo(ctx, minerAddr)
+	if err != nil {
+		return MinerWorkerRotation{}, err
+	}
+	rotation := MinerWorkerRotation{
+		Miner:     minerAddr,
+		Owner:     info.Owner,
+		Worker:    info.Worker,
+		NewWorker: newWorker,
+		Message:   change,
+	}
+	if info.PendingWorkerKey != nil {
+		rotation.EffectiveAt = info.PendingWorkerKey.EffectiveAt
+	}
+	return rotation, nil
+}
+
+// MinerConfirmWorker confirms the change of the worker of the node's miner
+// proposed by the message `proposal`. It waits for the receipt of the message,
+// then checks that the change has taken effect and that the wallet holds the
+// new key, so that the node signs the blocks and messages of the miner with
+// it. The miner keeps its power and sectors, and the former worker key may be
+// discarded.
+func MinerConfirmWorker(ctx context.Context, plumbing rotateWorkerAPI, proposal cid.Cid) (MinerWorkerRotation, error) {
+	minerAddr, err := configuredMinerAddress(plumbing)
+	if err != nil {
+		return MinerWorkerRotation{}, err
+	}
+
+	var proposed *types.SignedMessage
+	var receipt *vm.MessageReceipt
+	err = plumbing.MessageWait(ctx, proposal, msg.DefaultMessageWaitLookback, func(_ *block.Block, smsg *types.SignedMessage, rcpt *vm.MessageReceipt) error {
+		proposed, receipt = smsg, rcpt
+		return nil
+	})
+	if err != nil {
+		return MinerWorkerRotation{}, errors.Wrapf(err, "could not wait for the proposal %s", proposal)
+	}
+	if proposed.Message.To != minerAddr || proposed.Message.Method != builtin.MethodsMiner.ChangeWorkerAddress {
+		return MinerWorkerRotation{}, fmt.Errorf("message %s does not change the worker of miner %s", proposal, minerAddr)
+	}
+	if receipt.ExitCode != exitcode.Ok {
+		return MinerWorkerRotation{}, fmt.Errorf("the proposal %s failed with exit code %d", proposal, receipt.ExitCode)
+	}
+	var params miner.ChangeWorkerAddressParams
+	if err := encoding.Decode(proposed.Message.Params, &params); err != nil {
+		return MinerWorkerRotation{}, errors.Wrapf(err, "could not decode the proposal %s", proposal)
+	}
+	newWorker := params.NewWorker
+
+	head, err := plumbing.ChainTipSet(plumbing.ChainHeadKey())
+	if err != nil {
+		return MinerWorkerRotation{}, err
+	}
+	height, err := head.Height()
+	if err != nil {
+		return MinerWorkerRotation{}, err
+	}
+	view, err := plumbing.MinerStateView(head.Key())
+	if err != nil {
+		return MinerWorkerRotation{}, err
+	}
+	info, err := view.MinerInfo(ctx, minerAddr)
+	if err != nil {
+		return MinerWorkerRotation{}, errors.Wrap(err, "could not get miner control addresses")
+	}
+
+	if pending := info.PendingWorkerKey; pending != nil {
+		return MinerWorkerRotation{}, fmt.Errorf("the change of worker of miner %s to %s takes effect at height %d, the head is at height %d",
+			minerAddr, pending.NewWorker, pending.EffectiveAt, height)
+	}
+	workerKey, err := view.AccountSignerAddress(ctx, info.Worker)
+	if err != nil {
+		return MinerWorkerRotation{}, errors.Wrap(err, "could not get miner worker key")
+	}
+	if workerKey != newWorker {
+		return MinerWorkerRotation{}, fmt.Errorf("the worker of miner %s is %s, not %s", minerAddr, workerKey, newWorker)
+	}
+	if !walletHasAddress(plumbing, workerKey) {
+		return MinerWorkerRotation{}, fmt.Errorf("worker %s of miner %s is not in the wallet, import its key to keep mining", workerKey, minerAddr)
+	}
+
+	return MinerWorkerRotation{
+		Miner:     minerAddr,
+		Owner:     info.Owner,
+		Worker:    info.Worker,
+		NewWorker: workerKey,
+		Message:   proposal,
+	}, nil
+}
+
+func configuredMinerAddress(plumbing interface {
+	ConfigGet(dottedPath string) (interface{}, error)
+}) (address.Address, error) {
+	retVal, err := plumbing.ConfigGet("mining.minerAddress")
+	if err != nil {
+		return address.Undef, err
+	}
+	minerAddr, ok := retVal.(address.Address)
+	if !ok {
+		return address.Undef, errors.New("problem converting miner address")
+	}
+	if minerAddr.Empty() {
+		return address.Undef, errors.New("node has no miner")
+	}
+	return minerAddr, nil
+}
+
+func walletHasAddress(plumbing interface {
+	WalletAddresses() []address.Address
+}, addr address.Address) bool {
+	for _, a := range plumbing.WalletAddresses() {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func waitSuccess(ctx context.Context, plumbing waitPlumbing, msgCid cid.Cid) error {
+	receipt, err := MessageWaitDone(ctx, plumbing, msgCid)
+	if err != nil {
+		return err
+	}
+	if receipt.ExitCode != exitcode.Ok {
+		return fmt.Errorf("message %s failed with exit code %d", msgCid, receipt.ExitCode)
+	}
+	return nil
+}
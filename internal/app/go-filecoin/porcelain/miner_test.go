@@ -9,6 +9,8 @@ import (
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/specs-actors/actors/abi"
 	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
 	"github.com/filecoin-project/specs-actors/actors/builtin/power"
 	"github.com/filecoin-project/specs-actors/actors/runtime/exitcode"
 	"github.com/ipfs/go-cid"
@@ -25,6 +27,7 @@ import (
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/actor"
 	vmaddr "github.com/filecoin-project/go-filecoin/internal/pkg/vm/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/wallet"
@@ -288,3 +291,184 @@ func TestMinerSetWorkerAddress(t *testing.T) {
 		})
 	}
 }
+
+type rotateWorkerPlumbing struct {
+	t      *testing.T
+	height abi.ChainEpoch
+	miner  *state.FakeMinerState
+	wallet []address.Address
+	actors map[address.Address]bool
+
+	minerAddr address.Address
+	sent      []abi.MethodNum
+	messages  map[cid.Cid]*types.SignedMessage
+	exitCode  exitcode.ExitCode
+}
+
+func (p *rotateWorkerPlumbing) ActorGet(ctx context.Context, addr address.Address) (*actor.Actor, error) {
+	if !p.actors[addr] {
+		return nil, types.ErrNotFound
+	}
+	return &actor.Actor{}, nil
+}
+
+func (p *rotateWorkerPlumbing) ConfigGet(dottedKey string) (interface{}, error) {
+	if dottedKey == "mining.minerAddress" {
+		return p.minerAddr, nil
+	}
+	return nil, fmt.Errorf("unknown config %s", dottedKey)
+}
+
+func (p *rotateWorkerPlumbing) ChainHeadKey() block.TipSetKey {
+	return block.NewTipSetKey()
+}
+
+func (p *rotateWorkerPlumbing) ChainTipSet(key block.TipSetKey) (block.TipSet, error) {
+	return block.NewTipSet(&block.Block{Height: p.height})
+}
+
+func (p *rotateWorkerPlumbing) MinerStateView(baseKey block.TipSetKey) (MinerStateView, error) {
+	return &state.FakeStateView{
+		Miners: map[address.Address]*state.FakeMinerState{p.minerAddr: p.miner},
+	}, nil
+}
+
+func (p *rotateWorkerPlumbing) MessageSend(ctx context.Context, from, to address.Address, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit gas.Unit, method abi.MethodNum, params interface{}) (cid.Cid, chan error, error) {
+	assert.Equal(p.t, p.miner.Owner, from)
+	p.sent = append(p.sent, method)
+	encoded := []byte{}
+	if params != nil {
+		var err error
+		encoded, err = encoding.Encode(params)
+		require.NoError(p.t, err)
+	}
+	msgCid := types.CidFromString(p.t, fmt.Sprintf("message-%d", len(p.messages)))
+	p.messages[msgCid] = &types.SignedMessage{Message: types.UnsignedMessage{From: from, To: to, Method: method, Params: encoded}}
+	switch method {
+	case builtin.MethodSend:
+		p.actors[to] = true
+	case builtin.MethodsMiner.ChangeWorkerAddress:
+		assert.True(p.t, p.actors[params.(*miner.ChangeWorkerAddressParams).NewWorker])
+		p.miner.PendingWorker = &miner.WorkerKeyChange{
+			NewWorker:   params.(*miner.ChangeWorkerAddressParams).NewWorker,
+			EffectiveAt: p.height + miner.WorkerKeyChangeDelay,
+		}
+	}
+	return msgCid, nil, nil
+}
+
+func (p *rotateWorkerPlumbing) MessageWait(ctx context.Context, msgCid cid.Cid, lookback uint64, cb func(*block.Block, *types.SignedMessage, *vm.MessageReceipt) error) error {
+	smsg, ok := p.messages[msgCid]
+	if !ok {
+		return fmt.Errorf("message %s not found", msgCid)
+	}
+	return cb(&block.Block{}, smsg, &vm.MessageReceipt{ExitCode: p.exitCode})
+}
+
+func (p *rotateWorkerPlumbing) WalletAddresses() []address.Address {
+	return p.wallet
+}
+
+func (p *rotateWorkerPlumbing) WalletNewAddress(protocol address.Protocol) (address.Address, error) {
+	require.Equal(p.t, address.BLS, protocol)
+	addr := requireBLSAddress(p.t, byte(len(p.wallet)+1))
+	p.wallet = append(p.wallet, addr)
+	return addr, nil
+}
+
+func requireBLSAddress(t *testing.T, b byte) address.Address {
+	pubkey := make([]byte, 48)
+	pubkey[0] = b
+	addr, err := address.NewBLSAddress(pubkey)
+	require.NoError(t, err)
+	return addr
+}
+
+func TestMinerRotateWorker(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	owner := requireBLSAddress(t, 100)
+	worker := requireBLSAddress(t, 101)
+	newPlumbing := func() *rotateWorkerPlumbing {
+		return &rotateWorkerPlumbing{
+			t:         t,
+			height:    10,
+			miner:     &state.FakeMinerState{Owner: owner, Worker: worker},
+			wallet:    []address.Address{owner, worker},
+			actors:    map[address.Address]bool{owner: true, worker: true},
+			minerAddr: vmaddr.RequireIDAddress(t, 1000),
+			messages:  map[cid.Cid]*types.SignedMessage{},
+		}
+	}
+
+	t.Run("proposes a new key, creating its account", func(t *testing.T) {
+		plumbing := newPlumbing()
+		rotation, err := MinerProposeWorker(ctx, plumbing, address.Undef, types.ZeroAttoFIL, types.ZeroAttoFIL, gas.NewGas(0))
+		require.NoError(t, err)
+
+		assert.Equal(t, []abi.MethodNum{builtin.MethodSend, builtin.MethodsMiner.ChangeWorkerAddress}, plumbing.sent)
+		assert.Contains(t, plumbing.wallet, rotation.NewWorker)
+		assert.Equal(t, worker, rotation.Worker)
+		assert.Equal(t, 10+miner.WorkerKeyChangeDelay, rotation.EffectiveAt)
+		assert.Equal(t, types.CidFromString(t, "message-1"), rotation.Message)
+
+		t.Log("confirming fails until the change takes effect")
+		_, err = MinerConfirmWorker(ctx, plumbing, rotation.Message)
+		assert.EqualError(t, err, fmt.Sprintf("the change of worker of miner %s to %s takes effect at height %d, the head is at height 10",
+			plumbing.minerAddr, rotation.NewWorker, rotation.EffectiveAt))
+
+		plumbing.miner.Worker = plumbing.miner.PendingWorker.NewWorker
+		plumbing.miner.PendingWorker = nil
+		confirmed, err := MinerConfirmWorker(ctx, plumbing, rotation.Message)
+		require.NoError(t, err)
+		assert.Equal(t, rotation.NewWorker, confirmed.NewWorker)
+		assert.Equal(t, rotation.Message, confirmed.Message)
+
+		t.Log("confirming checks the receipt of the proposal")
+		_, err = MinerConfirmWorker(ctx, plumbing, types.CidFromString(t, "message-0"))
+		assert.EqualError(t, err, fmt.Sprintf("message %s does not change the worker of miner %s", types.CidFromString(t, "message-0"), plumbing.minerAddr))
+		plumbing.exitCode = exitcode.ErrForbidden
+		_, err = MinerConfirmWorker(ctx, plumbing, rotation.Message)
+		assert.EqualError(t, err, fmt.Sprintf("the proposal %s failed with exit code %d", rotation.Message, exitcode.ErrForbidden))
+	})
+
+	t.Run("proposes a key of the wallet with an account", func(t *testing.T) {
+		plumbing := newPlumbing()
+		newWorker := requireBLSAddress(t, 102)
+		plumbing.wallet = append(plumbing.wallet, newWorker)
+		plumbing.actors[newWorker] = true
+
+		rotation, err := MinerProposeWorker(ctx, plumbing, newWorker, types.ZeroAttoFIL, types.ZeroAttoFIL, gas.NewGas(0))
+		require.NoError(t, err)
+		assert.Equal(t, []abi.MethodNum{builtin.MethodsMiner.ChangeWorkerAddress}, plumbing.sent)
+		assert.Equal(t, newWorker, rotation.NewWorker)
+
+		t.Log("and funds it when asked")
+		plumbing.sent = nil
+		_, err = MinerProposeWorker(ctx, plumbing, newWorker, types.NewAttoFILFromFIL(1), types.ZeroAttoFIL, gas.NewGas(0))
+		require.NoError(t, err)
+		assert.Equal(t, []abi.MethodNum{builtin.MethodSend, builtin.MethodsMiner.ChangeWorkerAddress}, plumbing.sent)
+	})
+
+	t.Run("rejects keys it cannot sign with", func(t *testing.T) {
+		plumbing := newPlumbing()
+		_, err := MinerProposeWorker(ctx, plumbing, requireBLSAddress(t, 102), types.ZeroAttoFIL, types.ZeroAttoFIL, gas.NewGas(0))
+		assert.Error(t, err)
+
+		_, err = MinerProposeWorker(ctx, plumbing, vmaddr.RequireIDAddress(t, 102), types.ZeroAttoFIL, types.ZeroAttoFIL, gas.NewGas(0))
+		assert.Error(t, err)
+
+		plumbing.wallet = []address.Address{worker}
+		_, err = MinerProposeWorker(ctx, plumbing, worker, types.ZeroAttoFIL, types.ZeroAttoFIL, gas.NewGas(0))
+		assert.Error(t, err)
+		assert.Empty(t, plumbing.sent)
+	})
+
+	t.Run("fails when the change is not applied", func(t *testing.T) {
+		plumbing := newPlumbing()
+		plumbing.exitCode = exitcode.ErrForbidden
+		_, err := MinerProposeWorker(ctx, plumbing, worker, types.ZeroAttoFIL, types.ZeroAttoFIL, gas.NewGas(0))
+		assert.Error(t, err)
+	})
+}
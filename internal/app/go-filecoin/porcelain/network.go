@@ -2,12 +2,13 @@ package porcelain
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
 	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/errcode"
 )
 
 type netPlumbing interface {
@@ -26,12 +27,15 @@ func PingMinerWithTimeout(ctx context.Context, minerPID peer.ID, timeout time.Du
 	}
 
 	select {
-	case _, ok := <-res:
+	case r, ok := <-res:
 		if !ok {
-			return errors.New("couldn't establish connection to miner: ping channel closed")
+			return errcode.New(errcode.MinerOffline, "couldn't establish connection to miner: ping channel closed")
+		}
+		if r.Error != nil {
+			return errcode.Wrap(errcode.MinerOffline, errors.Wrap(r.Error, "couldn't establish connection to miner"))
 		}
 		return nil
 	case <-ctx.Done():
-		return fmt.Errorf("couldn't establish connection to miner: %s, timed out after %s", ctx.Err(), timeout.String())
+		return errcode.Errorf(errcode.MinerOffline, "couldn't establish connection to miner: %s, timed out after %s", ctx.Err(), timeout.String())
 	}
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	. "github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/errcode"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/net"
 	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
 	"github.com/libp2p/go-libp2p/p2p/protocol/ping"
@@ -65,5 +66,7 @@ func TestPingTimeout(t *testing.T) {
 	pid := th.RequireRandomPeerID(t)
 	ctx := context.Background()
 
-	assert.Error(t, PingMinerWithTimeout(ctx, pid, 100*time.Millisecond, plumbing))
+	err := PingMinerWithTimeout(ctx, pid, 100*time.Millisecond, plumbing)
+	assert.Error(t, err)
+	assert.Equal(t, errcode.MinerOffline, errcode.CodeOf(err))
 }
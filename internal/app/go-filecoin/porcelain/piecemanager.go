@@ -5,6 +5,7 @@ import (
 	"io"
 
 	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/piecemanager"
@@ -15,10 +16,20 @@ type pmPlumbing interface {
 }
 
 // SealPieceIntoNewSector writes the provided piece-bytes into a new sector.
-func SealPieceIntoNewSector(ctx context.Context, p pmPlumbing, dealID abi.DealID, dealStart, dealEnd abi.ChainEpoch, pieceSize abi.UnpaddedPieceSize, pieceReader io.Reader) error {
+func SealPieceIntoNewSector(ctx context.Context, p pmPlumbing, dealID abi.DealID, dealStart, dealEnd abi.ChainEpoch, pieceCID cid.Cid, pieceSize abi.UnpaddedPieceSize, pieceReader io.Reader) error {
 	if p.PieceManager() == nil {
 		return errors.New("must be mining to add piece")
 	}
 
-	return p.PieceManager().SealPieceIntoNewSector(ctx, dealID, dealStart, dealEnd, pieceSize, pieceReader)
+	return p.PieceManager().SealPieceIntoNewSector(ctx, dealID, dealStart, dealEnd, pieceCID, pieceSize, pieceReader)
+}
+
+// MinerListPieces lists the deal pieces stored in the miner's sectors along
+// with the deals and sectors referencing each of them.
+func MinerListPieces(ctx context.Context, p pmPlumbing) ([]piecemanager.PieceInfo, error) {
+	if p.PieceManager() == nil {
+		return nil, errors.New("must be mining to list pieces")
+	}
+
+	return p.PieceManager().ListPieces(ctx)
 }
@@ -0,0 +1,198 @@
+package porcelain
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	fbig "github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+// PowerTableStateView is the state the power table is read from.
+type PowerTableStateView interface {
+	PowerNetworkTotal(ctx context.Context) (*state.NetworkPower, error)
+	PowerClaimsForEach(ctx context.Context, f func(miner address.Address, raw, qa abi.StoragePower) error) error
+	MinerClaimedPower(ctx context.Context, miner address.Address) (raw, qa abi.StoragePower, err error)
+}
+
+type powerTablePlumbing interface {
+	ChainHeadKey() block.TipSetKey
+	ChainTipSet(key block.TipSetKey) (block.TipSet, error)
+	PowerTableStateView(baseKey block.TipSetKey) (PowerTableStateView, error)
+}
+
+// MinerPower is the power of a miner in the power table.
+type MinerPower struct {
+	// Rank is the position of the miner in the table, from 1 for the miner
+	// with the most quality adjusted power.
+	Rank                 int
+	Miner                address.Address
+	RawPower             abi.StoragePower
+	QualityAdjustedPower abi.StoragePower
+	// Percentage is the share of the network's quality adjusted power that
+	// the miner holds, in percent.
+	Percentage float64
+}
+
+// PowerTable is the power of all miners at a tipset, ranked by quality
+// adjusted power.
+type PowerTable struct {
+	Tipset                      block.TipSetKey
+	Height                      abi.ChainEpoch
+	NetworkRawPower             abi.StoragePower
+	NetworkQualityAdjustedPower abi.StoragePower
+	Miners                      []MinerPower
+}
+
+// PowerHistoryEntry is the power of a miner at a tipset.
+type PowerHistoryEntry struct {
+	Tipset                      block.TipSetKey
+	Height                      abi.ChainEpoch
+	RawPower                    abi.StoragePower
+	QualityAdjustedPower        abi.StoragePower
+	NetworkQualityAdjustedPower abi.StoragePower
+	Percentage                  float64
+}
+
+// PowerTableAt returns the power table at the tipset `key`, or at the head when
+// the key is empty.
+func PowerTableAt(ctx context.Context, plumbing powerTablePlumbing, key block.TipSetKey) (*PowerTable, error) {
+	if key.Empty() {
+		key = plumbing.ChainHeadKey()
+	}
+	ts, err := plumbing.ChainTipSet(key)
+	if err != nil {
+		return nil, err
+	}
+	height, err := ts.Height()
+	if err != nil {
+		return nil, err
+	}
+	view, err := plumbing.PowerTableStateView(key)
+	if err != nil {
+		return nil, err
+	}
+	total, err := view.PowerNetworkTotal(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load network power")
+	}
+
+	var miners []MinerPower
+	err = view.PowerClaimsForEach(ctx, func(miner address.Address, raw, qa abi.StoragePower) error {
+		miners = append(miners, MinerPower{
+			Miner:                miner,
+			RawPower:             raw,
+			QualityAdjustedPower: qa,
+			Percentage:           powerPercentage(qa, total.QualityAdjustedPower),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load power claims")
+	}
+	sort.Slice(miners, func(i, j int) bool {
+		if !miners[i].QualityAdjustedPower.Equals(miners[j].QualityAdjustedPower) {
+			return miners[i].QualityAdjustedPower.GreaterThan(miners[j].QualityAdjustedPower)
+		}
+		if !miners[i].RawPower.Equals(miners[j].RawPower) {
+			return miners[i].RawPower.GreaterThan(miners[j].RawPower)
+		}
+		return miners[i].Miner.String() < miners[j].Miner.String()
+	})
+	for i := range miners {
+		miners[i].Rank = i + 1
+	}
+
+	return &PowerTable{
+		Tipset:                      key,
+		Height:                      height,
+		NetworkRawPower:             total.RawBytePower,
+		NetworkQualityAdjustedPower: total.QualityAdjustedPower,
+		Miners:                      miners,
+	}, nil
+}
+
+// MinerPowerHistory returns the power of a miner at each tipset of the head
+// chain with a height from `from` to `to`, inclusive, lowest first. A miner
+// has no power at the heights before it claimed any.
+func MinerPowerHistory(ctx context.Context, plumbing powerTablePlumbing, miner address.Address, from, to abi.ChainEpoch) ([]PowerHistoryEntry, error) {
+	if to < from {
+		return nil, errors.Errorf("height range %d to %d is empty", from, to)
+	}
+
+	var history []PowerHistoryEntry
+	key := plumbing.ChainHeadKey()
+	for !key.Empty() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ts, err := plumbing.ChainTipSet(key)
+		if err != nil {
+			return nil, err
+		}
+		height, err := ts.Height()
+		if err != nil {
+			return nil, err
+		}
+		if height < from {
+			break
+		}
+		if height <= to {
+			entry, err := minerPowerAt(ctx, plumbing, miner, key, height)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to load power at height %d", height)
+			}
+			history = append(history, entry)
+		}
+		if key, err = ts.Parents(); err != nil {
+			return nil, err
+		}
+	}
+
+	// The chain is walked from the head, reverse to list the lowest first.
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+	return history, nil
+}
+
+func minerPowerAt(ctx context.Context, plumbing powerTablePlumbing, miner address.Address, key block.TipSetKey, height abi.ChainEpoch) (PowerHistoryEntry, error) {
+	view, err := plumbing.PowerTableStateView(key)
+	if err != nil {
+		return PowerHistoryEntry{}, err
+	}
+	total, err := view.PowerNetworkTotal(ctx)
+	if err != nil {
+		return PowerHistoryEntry{}, err
+	}
+	raw, qa, err := view.MinerClaimedPower(ctx, miner)
+	if errors.Cause(err) == types.ErrNotFound {
+		raw, qa = fbig.Zero(), fbig.Zero()
+	} else if err != nil {
+		return PowerHistoryEntry{}, err
+	}
+	return PowerHistoryEntry{
+		Tipset:                      key,
+		Height:                      height,
+		RawPower:                    raw,
+		QualityAdjustedPower:        qa,
+		NetworkQualityAdjustedPower: total.QualityAdjustedPower,
+		Percentage:                  powerPercentage(qa, total.QualityAdjustedPower),
+	}, nil
+}
+
+// powerPercentage returns the share of `total` that `power` is, in percent.
+func powerPercentage(power, total abi.StoragePower) float64 {
+	if total.Int == nil || total.Sign() <= 0 || power.Int == nil {
+		return 0
+	}
+	share, _ := new(big.Rat).SetFrac(power.Int, total.Int).Float64()
+	return share * 100
+}
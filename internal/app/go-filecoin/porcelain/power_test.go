@@ -0,0 +1,123 @@
+package porcelain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	e "github.com/filecoin-project/go-filecoin/internal/pkg/enccid"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	vmaddr "github.com/filecoin-project/go-filecoin/internal/pkg/vm/address"
+)
+
+type fakePowerPlumbing struct {
+	head    block.TipSetKey
+	tipsets map[string]block.TipSet
+	views   map[string]*state.FakeStateView
+}
+
+func (p *fakePowerPlumbing) ChainHeadKey() block.TipSetKey {
+	return p.head
+}
+
+func (p *fakePowerPlumbing) ChainTipSet(key block.TipSetKey) (block.TipSet, error) {
+	ts, ok := p.tipsets[key.String()]
+	if !ok {
+		return block.UndefTipSet, errors.Errorf("no tipset %s", key)
+	}
+	return ts, nil
+}
+
+func (p *fakePowerPlumbing) PowerTableStateView(key block.TipSetKey) (porcelain.PowerTableStateView, error) {
+	return p.views[key.String()], nil
+}
+
+// extend adds a tipset at `height` on top of the head with the power `view`.
+func (p *fakePowerPlumbing) extend(t *testing.T, height abi.ChainEpoch, view *state.FakeStateView) {
+	ts, err := block.NewTipSet(&block.Block{Height: height, Parents: p.head, Messages: e.NewCid(types.EmptyTxMetaCID)})
+	require.NoError(t, err)
+	p.head = ts.Key()
+	p.tipsets[ts.Key().String()] = ts
+	p.views[ts.Key().String()] = view
+}
+
+func powerView(miners map[address.Address]int64) *state.FakeStateView {
+	var total int64
+	for _, p := range miners {
+		total += p
+	}
+	view := state.NewFakeStateView(abi.NewStoragePower(total), abi.NewStoragePower(total), int64(len(miners)), 0)
+	for miner, p := range miners {
+		view.Miners[miner] = &state.FakeMinerState{
+			ClaimedRawPower: abi.NewStoragePower(p),
+			ClaimedQAPower:  abi.NewStoragePower(p),
+		}
+	}
+	return view
+}
+
+func TestPowerTable(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	m1, m2, m3 := vmaddr.RequireIDAddress(t, 101), vmaddr.RequireIDAddress(t, 102), vmaddr.RequireIDAddress(t, 103)
+	plumbing := &fakePowerPlumbing{tipsets: map[string]block.TipSet{}, views: map[string]*state.FakeStateView{}}
+	plumbing.extend(t, 0, powerView(map[address.Address]int64{m1: 10}))
+	genesis := plumbing.head
+	plumbing.extend(t, 1, powerView(map[address.Address]int64{m1: 10, m2: 30, m3: 10}))
+
+	table, err := porcelain.PowerTableAt(ctx, plumbing, block.TipSetKey{})
+	require.NoError(t, err)
+	assert.Equal(t, plumbing.head, table.Tipset)
+	assert.Equal(t, abi.ChainEpoch(1), table.Height)
+	assert.Equal(t, "50", table.NetworkQualityAdjustedPower.String())
+	require.Len(t, table.Miners, 3)
+	// Ranked by power, then by address.
+	assert.Equal(t, []address.Address{m2, m1, m3},
+		[]address.Address{table.Miners[0].Miner, table.Miners[1].Miner, table.Miners[2].Miner})
+	assert.Equal(t, []int{1, 2, 3}, []int{table.Miners[0].Rank, table.Miners[1].Rank, table.Miners[2].Rank})
+	assert.InDelta(t, 60, table.Miners[0].Percentage, 1e-9)
+	assert.InDelta(t, 20, table.Miners[1].Percentage, 1e-9)
+
+	table, err = porcelain.PowerTableAt(ctx, plumbing, genesis)
+	require.NoError(t, err)
+	assert.Equal(t, abi.ChainEpoch(0), table.Height)
+	require.Len(t, table.Miners, 1)
+	assert.InDelta(t, 100, table.Miners[0].Percentage, 1e-9)
+}
+
+func TestMinerPowerHistory(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	m1, m2 := vmaddr.RequireIDAddress(t, 101), vmaddr.RequireIDAddress(t, 102)
+	plumbing := &fakePowerPlumbing{tipsets: map[string]block.TipSet{}, views: map[string]*state.FakeStateView{}}
+	plumbing.extend(t, 0, powerView(map[address.Address]int64{m1: 10}))
+	plumbing.extend(t, 1, powerView(map[address.Address]int64{m1: 10, m2: 10}))
+	// Height 2 is a null round.
+	plumbing.extend(t, 3, powerView(map[address.Address]int64{m1: 10, m2: 30}))
+	plumbing.extend(t, 4, powerView(map[address.Address]int64{m1: 10, m2: 40}))
+
+	history, err := porcelain.MinerPowerHistory(ctx, plumbing, m2, 0, 3)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, []abi.ChainEpoch{0, 1, 3}, []abi.ChainEpoch{history[0].Height, history[1].Height, history[2].Height})
+	// At genesis the miner has no power claim yet.
+	assert.Equal(t, "0", history[0].QualityAdjustedPower.String())
+	assert.Equal(t, "10", history[1].QualityAdjustedPower.String())
+	assert.InDelta(t, 50, history[1].Percentage, 1e-9)
+	assert.Equal(t, "30", history[2].QualityAdjustedPower.String())
+	assert.InDelta(t, 75, history[2].Percentage, 1e-9)
+
+	_, err = porcelain.MinerPowerHistory(ctx, plumbing, m2, 3, 1)
+	assert.Error(t, err)
+}
@@ -2,6 +2,8 @@ package porcelain
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/filecoin-project/specs-actors/actors/abi"
@@ -9,13 +11,14 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
-	"github.com/filecoin-project/go-filecoin/internal/pkg/constants"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/version"
 )
 
 // SectorInfo provides information about a sector construction
 type SectorInfo struct {
-	Size         abi.SectorSize
-	MaxPieceSize abi.UnpaddedPieceSize
+	Size          abi.SectorSize
+	MaxPieceSize  abi.UnpaddedPieceSize
+	SealProofType abi.RegisteredProof
 }
 
 // ProtocolParams contains parameters that modify the filecoin nodes protocol
@@ -23,6 +26,8 @@ type ProtocolParams struct {
 	Network          string
 	AutoSealInterval uint
 	BlockTime        time.Duration
+	// SupportedSectors are the sectors new miners may commit, smallest first.
+	// Miners of a network may each commit sectors of a different size.
 	SupportedSectors []SectorInfo
 }
 
@@ -30,6 +35,7 @@ type protocolParamsPlumbing interface {
 	ConfigGet(string) (interface{}, error)
 	ChainHeadKey() block.TipSetKey
 	ProtocolStateView(baseKey block.TipSetKey) (ProtocolStateView, error)
+	ProtocolSealProofTypes() ([]abi.RegisteredProof, error)
 	BlockTime() time.Duration
 }
 
@@ -54,12 +60,9 @@ func ProtocolParameters(ctx context.Context, plumbing protocolParamsPlumbing) (*
 		return nil, errors.Wrap(err, "could not retrieve network name")
 	}
 
-	sectorSizes := []abi.SectorSize{constants.DevSectorSize, constants.FiveHundredTwelveMiBSectorSize}
-
-	var supportedSectors []SectorInfo
-	for _, sectorSize := range sectorSizes {
-		maxUserBytes := abi.PaddedPieceSize(sectorSize).Unpadded()
-		supportedSectors = append(supportedSectors, SectorInfo{sectorSize, maxUserBytes})
+	supportedSectors, err := supportedSectors(plumbing)
+	if err != nil {
+		return nil, err
 	}
 
 	return &ProtocolParams{
@@ -70,6 +73,45 @@ func ProtocolParameters(ctx context.Context, plumbing protocolParamsPlumbing) (*
 	}, nil
 }
 
+type sealProofTypesPlumbing interface {
+	ProtocolSealProofTypes() ([]abi.RegisteredProof, error)
+}
+
+func supportedSectors(plumbing sealProofTypesPlumbing) ([]SectorInfo, error) {
+	proofTypes, err := plumbing.ProtocolSealProofTypes()
+	if err != nil {
+		return nil, err
+	}
+	var sectors []SectorInfo
+	for _, proofType := range proofTypes {
+		sectorSize, err := proofType.SectorSize()
+		if err != nil {
+			return nil, err
+		}
+		maxUserBytes := abi.PaddedPieceSize(sectorSize).Unpadded()
+		sectors = append(sectors, SectorInfo{sectorSize, maxUserBytes, proofType})
+	}
+	return sectors, nil
+}
+
+// SupportedSealProofType returns the seal proof type of the sectors of size
+// `sectorSize`, which fails when new miners may not commit sectors of that
+// size.
+func SupportedSealProofType(plumbing sealProofTypesPlumbing, sectorSize abi.SectorSize) (abi.RegisteredProof, error) {
+	sectors, err := supportedSectors(plumbing)
+	if err != nil {
+		return 0, err
+	}
+	sizes := make([]string, len(sectors))
+	for i, sector := range sectors {
+		if sector.Size == sectorSize {
+			return sector.SealProofType, nil
+		}
+		sizes[i] = fmt.Sprintf("%d", sector.Size)
+	}
+	return 0, errors.Errorf("sector size %d is not supported, supported sizes are %s", sectorSize, strings.Join(sizes, ", "))
+}
+
 func getNetworkName(ctx context.Context, plumbing protocolParamsPlumbing) (string, error) {
 	view, err := plumbing.ProtocolStateView(plumbing.ChainHeadKey())
 	if err != nil {
@@ -77,3 +119,49 @@ func getNetworkName(ctx context.Context, plumbing protocolParamsPlumbing) (strin
 	}
 	return view.InitNetworkName(ctx)
 }
+
+// Statuses of a protocol upgrade relative to the chain head.
+const (
+	UpgradeStatusPast      = "past"
+	UpgradeStatusActive    = "active"
+	UpgradeStatusScheduled = "scheduled"
+)
+
+// ProtocolUpgrade is a protocol version and its status at the chain head.
+type ProtocolUpgrade struct {
+	version.Upgrade
+	Status string
+}
+
+type protocolUpgradesPlumbing interface {
+	ChainHeadKey() block.TipSetKey
+	ChainTipSet(key block.TipSetKey) (block.TipSet, error)
+	ProtocolVersions() []version.Upgrade
+}
+
+// ProtocolUpgrades returns the protocol versions of the network, from genesis
+// to the scheduled upgrades, with the one in effect at the chain head active.
+func ProtocolUpgrades(ctx context.Context, plumbing protocolUpgradesPlumbing) ([]ProtocolUpgrade, error) {
+	head, err := plumbing.ChainTipSet(plumbing.ChainHeadKey())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get chain head")
+	}
+	height, err := head.Height()
+	if err != nil {
+		return nil, err
+	}
+
+	upgrades := plumbing.ProtocolVersions()
+	out := make([]ProtocolUpgrade, len(upgrades))
+	for i, upgrade := range upgrades {
+		status := UpgradeStatusScheduled
+		if upgrade.EffectiveAt <= height {
+			status = UpgradeStatusActive
+			if i+1 < len(upgrades) && upgrades[i+1].EffectiveAt <= height {
+				status = UpgradeStatusPast
+			}
+		}
+		out[i] = ProtocolUpgrade{Upgrade: upgrade, Status: status}
+	}
+	return out, nil
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/constants"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/version"
 )
 
 const protocolTestParamBlockTime = time.Second
@@ -32,6 +34,10 @@ func (tppp *testProtocolParamsPlumbing) ChainHeadKey() block.TipSetKey {
 	return block.NewTipSetKey()
 }
 
+func (tppp *testProtocolParamsPlumbing) ProtocolSealProofTypes() ([]abi.RegisteredProof, error) {
+	return []abi.RegisteredProof{constants.DevSealProofType, abi.RegisteredProof_StackedDRG512MiBSeal}, nil
+}
+
 func (tppp *testProtocolParamsPlumbing) BlockTime() time.Duration {
 	return protocolTestParamBlockTime
 }
@@ -57,8 +63,8 @@ func TestProtocolParams(t *testing.T) {
 			AutoSealInterval: 120,
 			Network:          "protocolTest",
 			SupportedSectors: []porcelain.SectorInfo{
-				{constants.DevSectorSize, abi.PaddedPieceSize(constants.DevSectorSize).Unpadded()},
-				{constants.FiveHundredTwelveMiBSectorSize, abi.PaddedPieceSize(constants.FiveHundredTwelveMiBSectorSize).Unpadded()},
+				{constants.DevSectorSize, abi.PaddedPieceSize(constants.DevSectorSize).Unpadded(), constants.DevSealProofType},
+				{constants.FiveHundredTwelveMiBSectorSize, abi.PaddedPieceSize(constants.FiveHundredTwelveMiBSectorSize).Unpadded(), abi.RegisteredProof_StackedDRG512MiBSeal},
 			},
 			BlockTime: protocolTestParamBlockTime,
 		}
@@ -69,3 +75,53 @@ func TestProtocolParams(t *testing.T) {
 		assert.Equal(t, expected, out)
 	})
 }
+
+func TestSupportedSealProofType(t *testing.T) {
+	tf.UnitTest(t)
+
+	plumbing := &testProtocolParamsPlumbing{testing: t}
+	proofType, err := porcelain.SupportedSealProofType(plumbing, constants.FiveHundredTwelveMiBSectorSize)
+	require.NoError(t, err)
+	assert.Equal(t, abi.RegisteredProof_StackedDRG512MiBSeal, proofType)
+
+	_, err = porcelain.SupportedSealProofType(plumbing, abi.SectorSize(32<<30))
+	assert.EqualError(t, err, "sector size 34359738368 is not supported, supported sizes are 2048, 536870912")
+}
+
+type testProtocolUpgradesPlumbing struct {
+	height   abi.ChainEpoch
+	versions []version.Upgrade
+}
+
+func (p *testProtocolUpgradesPlumbing) ChainHeadKey() block.TipSetKey {
+	return block.NewTipSetKey()
+}
+
+func (p *testProtocolUpgradesPlumbing) ChainTipSet(_ block.TipSetKey) (block.TipSet, error) {
+	return block.NewTipSet(&block.Block{Height: p.height})
+}
+
+func (p *testProtocolUpgradesPlumbing) ProtocolVersions() []version.Upgrade {
+	return p.versions
+}
+
+func TestProtocolUpgrades(t *testing.T) {
+	tf.UnitTest(t)
+
+	plumbing := &testProtocolUpgradesPlumbing{
+		height: 150,
+		versions: []version.Upgrade{
+			{Version: 0, EffectiveAt: 0},
+			{Version: 1, EffectiveAt: 100},
+			{Version: 2, EffectiveAt: 200},
+		},
+	}
+
+	upgrades, err := porcelain.ProtocolUpgrades(context.Background(), plumbing)
+	require.NoError(t, err)
+	require.Len(t, upgrades, 3)
+	assert.Equal(t, porcelain.UpgradeStatusPast, upgrades[0].Status)
+	assert.Equal(t, porcelain.UpgradeStatusActive, upgrades[1].Status)
+	assert.Equal(t, porcelain.UpgradeStatusScheduled, upgrades[2].Status)
+	assert.Equal(t, uint64(2), upgrades[2].Version)
+}
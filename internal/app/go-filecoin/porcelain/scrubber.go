@@ -0,0 +1,27 @@
+package porcelain
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/scrubber"
+)
+
+type scrubPlumbing interface {
+	SectorScrubber() *scrubber.Scrubber
+}
+
+// MinerScrubSectors checks the sealed sectors of the node's miner now if `now`
+// is set and returns the report of the last scrub otherwise, nil when no
+// scrub completed yet.
+func MinerScrubSectors(ctx context.Context, p scrubPlumbing, now bool) (*scrubber.Report, error) {
+	s := p.SectorScrubber()
+	if s == nil {
+		return nil, errors.New("must be mining to scrub sectors")
+	}
+	if now {
+		return s.Scrub(ctx)
+	}
+	return s.LastReport(), nil
+}
@@ -0,0 +1,49 @@
+package porcelain
+
+import (
+	"encoding/json"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
+)
+
+type syncTrustPlumbing interface {
+	ConfigSet(dottedPath string, paramJSON string) error
+	SyncTrustPeer(pid peer.ID)
+	SyncUntrustPeer(pid peer.ID)
+	SyncTrustedPeers() []peer.ID
+	SyncSetTrustedOnly(only bool)
+}
+
+// SyncSetTrusted marks a peer as trusted (or no longer trusted) by the syncer
+// and persists the resulting set of trusted peers in the node's config.
+func SyncSetTrusted(plumbing syncTrustPlumbing, pid peer.ID, trusted bool) error {
+	if trusted {
+		plumbing.SyncTrustPeer(pid)
+	} else {
+		plumbing.SyncUntrustPeer(pid)
+	}
+
+	peers := []string{}
+	for _, p := range plumbing.SyncTrustedPeers() {
+		peers = append(peers, p.Pretty())
+	}
+
+	peersJSON, err := json.Marshal(peers)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(plumbing.ConfigSet("sync.trustedPeers", string(peersJSON)), "failed to persist trusted peers")
+}
+
+// SyncRestrictToTrusted sets whether the syncer ignores chain heads from peers
+// that are not trusted and persists the setting in the node's config.
+func SyncRestrictToTrusted(plumbing syncTrustPlumbing, only bool) error {
+	plumbing.SyncSetTrustedOnly(only)
+
+	onlyJSON, err := json.Marshal(only)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(plumbing.ConfigSet("sync.trustedOnly", string(onlyJSON)), "failed to persist trusted only setting")
+}
@@ -0,0 +1,78 @@
+package porcelain_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
+	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+type syncTrustPlumbing struct {
+	config      map[string]string
+	trusted     map[peer.ID]struct{}
+	trustedOnly bool
+}
+
+func newSyncTrustPlumbing() *syncTrustPlumbing {
+	return &syncTrustPlumbing{
+		config:  make(map[string]string),
+		trusted: make(map[peer.ID]struct{}),
+	}
+}
+
+func (stp *syncTrustPlumbing) ConfigSet(dottedPath string, paramJSON string) error {
+	stp.config[dottedPath] = paramJSON
+	return nil
+}
+
+func (stp *syncTrustPlumbing) SyncTrustPeer(pid peer.ID) {
+	stp.trusted[pid] = struct{}{}
+}
+
+func (stp *syncTrustPlumbing) SyncUntrustPeer(pid peer.ID) {
+	delete(stp.trusted, pid)
+}
+
+func (stp *syncTrustPlumbing) SyncTrustedPeers() []peer.ID {
+	var out []peer.ID
+	for p := range stp.trusted {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+func (stp *syncTrustPlumbing) SyncSetTrustedOnly(only bool) {
+	stp.trustedOnly = only
+}
+
+func TestSyncSetTrusted(t *testing.T) {
+	tf.UnitTest(t)
+
+	plumbing := newSyncTrustPlumbing()
+	pid := th.RequireIntPeerID(t, 1)
+
+	require.NoError(t, porcelain.SyncSetTrusted(plumbing, pid, true))
+	assert.Contains(t, plumbing.trusted, pid)
+	assert.Equal(t, `["`+pid.Pretty()+`"]`, plumbing.config["sync.trustedPeers"])
+
+	require.NoError(t, porcelain.SyncSetTrusted(plumbing, pid, false))
+	assert.NotContains(t, plumbing.trusted, pid)
+	assert.Equal(t, `[]`, plumbing.config["sync.trustedPeers"])
+}
+
+func TestSyncRestrictToTrusted(t *testing.T) {
+	tf.UnitTest(t)
+
+	plumbing := newSyncTrustPlumbing()
+
+	require.NoError(t, porcelain.SyncRestrictToTrusted(plumbing, true))
+	assert.True(t, plumbing.trustedOnly)
+	assert.Equal(t, "true", plumbing.config["sync.trustedOnly"])
+}
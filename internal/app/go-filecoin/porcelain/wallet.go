@@ -62,3 +62,16 @@ func WalletDefaultAddress(plumbing wdaPlumbing) (address.Address, error) {
 
 	return address.Undef, ErrNoDefaultFromAddress
 }
+
+// WalletDealAddress returns the address that proposes and signs storage deals.
+// This is the deal key in the config, or the default address if none is set.
+func WalletDealAddress(plumbing wdaPlumbing) (address.Address, error) {
+	ret, err := plumbing.ConfigGet("wallet.dealAddress")
+	if err != nil {
+		return address.Undef, err
+	}
+	if addr := ret.(address.Address); !addr.Empty() {
+		return addr, nil
+	}
+	return WalletDefaultAddress(plumbing)
+}
@@ -113,6 +113,37 @@ func TestWalletDefaultAddress(t *testing.T) {
 	})
 }
 
+func TestWalletDealAddress(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("it returns the default address if no deal key is configured", func(t *testing.T) {
+		wdatp := newWdaTestPlumbing(t)
+
+		_, err := wdatp.WalletNewAddress()
+		require.NoError(t, err)
+
+		expected, err := porcelain.WalletDefaultAddress(wdatp)
+		require.NoError(t, err)
+		got, err := porcelain.WalletDealAddress(wdatp)
+		require.NoError(t, err)
+		assert.Equal(t, expected, got)
+	})
+
+	t.Run("it returns the configured deal key", func(t *testing.T) {
+		wdatp := newWdaTestPlumbing(t)
+
+		_, err := wdatp.WalletNewAddress()
+		require.NoError(t, err)
+		dealKey, err := wdatp.WalletNewAddress()
+		require.NoError(t, err)
+		require.NoError(t, wdatp.ConfigSet("wallet.dealAddress", dealKey.String()))
+
+		got, err := porcelain.WalletDealAddress(wdatp)
+		require.NoError(t, err)
+		assert.Equal(t, dealKey, got)
+	})
+}
+
 func isInList(needle address.Address, haystack []address.Address) bool {
 	for _, a := range haystack {
 		if a == needle {
@@ -0,0 +1,244 @@
+package chain
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
+)
+
+// PrunedHeightKey is the key at which the height up to which the chain has been pruned
+// is written in the datastore.
+var PrunedHeightKey = datastore.NewKey("/chain/prunedHeight")
+
+// PruneResult reports the outcome of a pruning pass.
+type PruneResult struct {
+	// PrunedHeight is the height at and below which state has been pruned.
+	PrunedHeight abi.ChainEpoch
+	// Tipsets is the number of tipsets whose state was pruned in this pass.
+	Tipsets int
+	// BlocksDeleted is the number of ipld blocks removed from the blockstore.
+	BlocksDeleted int
+}
+
+// Pruner discards state trees, receipts and message bodies of tipsets that are
+// more than a fixed depth below the chain head. Block headers are never pruned,
+// so the chain remains traversable, and the genesis state is always kept.
+//
+// Pruning must not run concurrently with chain processing: ipld blocks that are
+// only referenced by pruned tipsets may be written again by a state transition
+// in progress and be deleted from under it.
+type Pruner struct {
+	store *Store
+	bs    blockstore.Blockstore
+	ds    repo.Datastore
+	depth abi.ChainEpoch
+}
+
+// NewPruner creates a pruner that retains full state for the `depth` epochs
+// at the head of the chain in `store`. The depth may not be less than the chain
+// finality, since a reorg may switch to a fork from any tipset above it.
+func NewPruner(store *Store, bs blockstore.Blockstore, ds repo.Datastore, depth abi.ChainEpoch) (*Pruner, error) {
+	if depth < miner.ChainFinalityish {
+		return nil, errors.Errorf("prune depth %d is less than the chain finality of %d epochs", depth, miner.ChainFinalityish)
+	}
+	return &Pruner{
+		store: store,
+		bs:    bs,
+		ds:    ds,
+		depth: depth,
+	}, nil
+}
+
+// PrunedHeight returns the height at and below which state has been pruned, or
+// zero if the chain has never been pruned.
+func (p *Pruner) PrunedHeight() (abi.ChainEpoch, error) {
+	return ReadPrunedHeight(p.ds)
+}
+
+// ReadPrunedHeight returns the height at and below which the state of the
+// chain in `ds` has been pruned, or zero if the chain has never been pruned.
+func ReadPrunedHeight(ds repo.Datastore) (abi.ChainEpoch, error) {
+	val, err := ds.Get(PrunedHeightKey)
+	if err == datastore.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read pruned height")
+	}
+
+	h, err := strconv.ParseInt(string(val), 10, 64)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to decode pruned height")
+	}
+	return abi.ChainEpoch(h), nil
+}
+
+// Prune deletes the state, receipts and messages of all tipsets at least `depth`
+// epochs below the current head that have not been pruned before. Ipld blocks
+// that are still referenced from a retained tipset are kept.
+func (p *Pruner) Prune(ctx context.Context) (PruneResult, error) {
+	head, err := p.store.GetTipSet(p.store.GetHead())
+	if err != nil {
+		return PruneResult{}, errors.Wrap(err, "failed to load head")
+	}
+	headHeight, err := head.Height()
+	if err != nil {
+		return PruneResult{}, err
+	}
+
+	prunedHeight, err := p.PrunedHeight()
+	if err != nil {
+		return PruneResult{}, err
+	}
+	result := PruneResult{PrunedHeight: prunedHeight}
+
+	cutoff := headHeight - p.depth
+	if cutoff <= prunedHeight {
+		return result, nil
+	}
+
+	// Split the chain into retained tipsets and tipsets to prune. Everything at
+	// or below prunedHeight was pruned by an earlier pass.
+	var retained, expired []block.TipSet
+	for it := IterAncestors(ctx, p.store, head); !it.Complete(); err = it.Next() {
+		if err != nil {
+			return result, err
+		}
+		h, err := it.Value().Height()
+		if err != nil {
+			return result, err
+		}
+		switch {
+		case h == 0:
+			// genesis state is always kept
+			retained = append(retained, it.Value())
+		case h > cutoff:
+			retained = append(retained, it.Value())
+		case h > prunedHeight:
+			expired = append(expired, it.Value())
+		}
+	}
+
+	live := cid.NewSet()
+	for _, ts := range retained {
+		roots, err := p.tipSetRoots(ts)
+		if err != nil {
+			return result, err
+		}
+		for _, root := range roots {
+			if err := p.walk(ctx, root, live.Visit); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	dead := cid.NewSet()
+	for _, ts := range expired {
+		roots, err := p.tipSetRoots(ts)
+		if err != nil {
+			return result, err
+		}
+		for _, root := range roots {
+			err := p.walk(ctx, root, func(c cid.Cid) bool {
+				return !live.Has(c) && dead.Visit(c)
+			})
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+
+	err = dead.ForEach(func(c cid.Cid) error {
+		has, err := p.bs.Has(c)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return nil
+		}
+		if err := p.bs.DeleteBlock(c); err != nil {
+			return errors.Wrapf(err, "failed to delete %s", c)
+		}
+		result.BlocksDeleted++
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if err := p.ds.Put(PrunedHeightKey, []byte(strconv.FormatInt(int64(cutoff), 10))); err != nil {
+		return result, errors.Wrap(err, "failed to write pruned height")
+	}
+
+	result.PrunedHeight = cutoff
+	result.Tipsets = len(expired)
+	logStore.Infof("pruned state of %d tipsets up to height %d, deleted %d blocks", result.Tipsets, cutoff, result.BlocksDeleted)
+	return result, nil
+}
+
+// tipSetRoots returns the roots of the prunable data of a tipset: its state
+// tree, its receipts and the messages of each of its blocks.
+func (p *Pruner) tipSetRoots(ts block.TipSet) ([]cid.Cid, error) {
+	stateRoot, err := p.store.GetTipSetStateRoot(ts.Key())
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := p.store.GetTipSetReceiptsRoot(ts.Key())
+	if err != nil {
+		return nil, err
+	}
+
+	roots := []cid.Cid{stateRoot, receipts}
+	for i := 0; i < ts.Len(); i++ {
+		roots = append(roots, ts.At(i).Messages.Cid)
+	}
+	return roots, nil
+}
+
+// walk visits the ipld dag below root, descending into the links of any block
+// for which visit returns true. Blocks missing from the blockstore (e.g. pruned
+// by an earlier pass) are skipped.
+func (p *Pruner) walk(ctx context.Context, root cid.Cid, visit func(cid.Cid) bool) error {
+	stack := []cid.Cid{root}
+	for len(stack) > 0 {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		c := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !visit(c) {
+			continue
+		}
+
+		if c.Prefix().Codec != cid.DagCBOR {
+			continue
+		}
+		blk, err := p.bs.Get(c)
+		if err == blockstore.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to load %s", c)
+		}
+
+		nd, err := cbor.DecodeBlock(blk)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode %s", c)
+		}
+		for _, l := range nd.Links() {
+			stack = append(stack, l.Cid)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package chain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	syncds "github.com/ipfs/go-datastore/sync"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+func TestPrunerPrune(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	bs := bstore.NewBlockstore(syncds.MutexWrap(datastore.NewMapDatastore()))
+	cst := cbor.NewCborStore(bs)
+
+	builder := chain.NewBuilder(t, address.Undef)
+	genTS := builder.NewGenesis()
+	depth := miner.ChainFinalityish
+	head := builder.AppendManyOn(int(depth)+7, genTS)
+
+	r := repo.NewInMemoryRepo()
+	store := chain.NewStore(r.ChainDatastore(), cst, chain.NewStatusReporter(), genTS.At(0).Cid())
+
+	// Every tipset's state links to a node shared by all states and to a node
+	// of its own.
+	shared, err := cst.Put(ctx, map[string]string{"shared": "state"})
+	require.NoError(t, err)
+
+	own := make(map[abi.ChainEpoch]cid.Cid)
+	tips := append(builder.RequireTipSets(head.Key(), int(depth)+7), genTS)
+	for _, ts := range tips {
+		h, err := ts.Height()
+		require.NoError(t, err)
+
+		own[h], err = cst.Put(ctx, map[string]int64{"height": int64(h)})
+		require.NoError(t, err)
+		root, err := cst.Put(ctx, map[string]cid.Cid{"shared": shared, "own": own[h]})
+		require.NoError(t, err)
+
+		require.NoError(t, store.PutTipSetMetadata(ctx, &chain.TipSetMetadata{
+			TipSet:          ts,
+			TipSetStateRoot: root,
+			TipSetReceipts:  types.EmptyReceiptsCID,
+		}))
+	}
+	require.NoError(t, store.SetHead(ctx, head))
+
+	t.Log("depths below finality are rejected")
+	_, err = chain.NewPruner(store, bs, r.ChainDatastore(), depth-1)
+	assert.Error(t, err)
+
+	pruner, err := chain.NewPruner(store, bs, r.ChainDatastore(), depth)
+	require.NoError(t, err)
+	result, err := pruner.Prune(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, abi.ChainEpoch(7), result.PrunedHeight)
+	assert.Equal(t, 7, result.Tipsets)
+	// a state root and an own node for each of heights 1 through 7
+	assert.Equal(t, 14, result.BlocksDeleted)
+
+	requireHas := func(c cid.Cid) bool {
+		has, err := bs.Has(c)
+		require.NoError(t, err)
+		return has
+	}
+	assert.True(t, requireHas(shared))
+	for h, c := range own {
+		pruned := h > 0 && h <= 7
+		assert.Equal(t, !pruned, requireHas(c), "state at height %d", h)
+	}
+
+	t.Log("pruning again without the head advancing does nothing")
+	result, err = pruner.Prune(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, abi.ChainEpoch(7), result.PrunedHeight)
+	assert.Equal(t, 0, result.BlocksDeleted)
+
+	prunedHeight, err := pruner.PrunedHeight()
+	require.NoError(t, err)
+	assert.Equal(t, abi.ChainEpoch(7), prunedHeight)
+}
@@ -20,6 +20,12 @@ type BlockProposer interface {
 	WaiterForTarget(wk block.TipSetKey) func() error
 }
 
+// PeerTrust decides which peers' chain heads the syncer prefers or accepts.
+type PeerTrust = dispatcher.PeerTrust
+
+// Pruner discards chain state below a depth from the head.
+type Pruner = syncer.Pruner
+
 // Manager sync the chain.
 type Manager struct {
 	syncer       *syncer.Syncer
@@ -28,13 +34,14 @@ type Manager struct {
 }
 
 // NewManager creates a new chain sync manager.
-func NewManager(fv syncer.FullBlockValidator, hv syncer.BlockValidator, cs syncer.ChainSelector, s syncer.ChainReaderWriter, m *chain.MessageStore, f syncer.Fetcher, c clock.Clock, detector *slashing.ConsensusFaultDetector) (Manager, error) {
+func NewManager(fv syncer.FullBlockValidator, hv syncer.BlockValidator, cs syncer.ChainSelector, s syncer.ChainReaderWriter, m *chain.MessageStore, f syncer.Fetcher, c clock.Clock, detector *slashing.ConsensusFaultDetector, trust PeerTrust) (Manager, error) {
 	syncer, err := syncer.NewSyncer(fv, hv, cs, s, m, f, status.NewReporter(), c, detector)
 	if err != nil {
 		return Manager{}, err
 	}
 	gapTransitioner := dispatcher.NewGapTransitioner(s, syncer)
 	dispatcher := dispatcher.NewDispatcher(syncer, gapTransitioner)
+	dispatcher.SetPeerTrust(trust)
 	return Manager{
 		syncer:       syncer,
 		dispatcher:   dispatcher,
@@ -48,6 +55,12 @@ func (m *Manager) Start(ctx context.Context) error {
 	return m.syncer.InitStaged()
 }
 
+// SetPruner sets the pruner run after the syncer sets each new head. It must be
+// called before Start.
+func (m *Manager) SetPruner(p Pruner) {
+	m.syncer.SetPruner(p)
+}
+
 // BlockProposer returns the block proposer.
 func (m *Manager) BlockProposer() BlockProposer {
 	return m.dispatcher
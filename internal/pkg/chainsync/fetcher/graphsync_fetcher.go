@@ -157,7 +157,16 @@ func (gsf *GraphSyncFetcher) fetchTipSetsCommon(ctx context.Context, tsKey block
 }
 
 func (gsf *GraphSyncFetcher) fetchFirstTipset(ctx context.Context, tsKey block.TipSetKey, loadAndVerify func(context.Context, block.TipSetKey) (block.TipSet, []cid.Cid, error), selGen func() ipld.Node, rpf *requestPeerFinder) (block.TipSet, error) {
-	blocksToFetch := tsKey.ToSlice()
+	// The tipset may already be stored, e.g. reconstructed from a block
+	// announcement, in which case there is nothing to request.
+	verifiedTip, blocksToFetch, err := loadAndVerify(ctx, tsKey)
+	if err != nil {
+		return block.UndefTipSet, err
+	}
+	if len(blocksToFetch) == 0 {
+		return verifiedTip, nil
+	}
+
 	for {
 		peer := rpf.CurrentPeer()
 		logGraphsyncFetcher.Infof("fetching initial tipset %s from peer %s", tsKey, peer)
@@ -168,7 +177,6 @@ func (gsf *GraphSyncFetcher) fetchFirstTipset(ctx context.Context, tsKey block.T
 			logGraphsyncFetcher.Infof("request failed: %s", err)
 		}
 
-		var verifiedTip block.TipSet
 		verifiedTip, blocksToFetch, err = loadAndVerify(ctx, tsKey)
 		if err != nil {
 			return block.UndefTipSet, err
@@ -6,6 +6,7 @@ import (
 	"runtime/debug"
 
 	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p-core/peer"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/util/moresync"
@@ -32,6 +33,20 @@ type transitionSyncer interface {
 	SetStagedHead(context.Context) error
 }
 
+// PeerTrust reports which peers chain heads are trusted from.
+type PeerTrust interface {
+	// IsTrusted returns true if chain heads sent by the peer are preferred.
+	IsTrusted(peer.ID) bool
+	// TrustedOnly returns true if chain heads from untrusted peers should be dropped.
+	TrustedOnly() bool
+}
+
+// trustNone is the default PeerTrust, it trusts no peer and accepts chain heads from all of them.
+type trustNone struct{}
+
+func (trustNone) IsTrusted(peer.ID) bool { return false }
+func (trustNone) TrustedOnly() bool      { return false }
+
 // chainHeadState is the interface for determining the head of the chain
 type chainHeadState interface {
 	GetHead() block.TipSetKey
@@ -50,6 +65,7 @@ func NewDispatcherWithSizes(syncer dispatchSyncer, trans Transitioner, workQueue
 		workQueueSize: workQueueSize,
 		syncer:        syncer,
 		transitioner:  trans,
+		trust:         trustNone{},
 		incoming:      make(chan Target, inQueueSize),
 		control:       make(chan interface{}, 1),
 		registeredCb:  func(t Target, err error) {},
@@ -88,6 +104,8 @@ type Dispatcher struct {
 	catchup bool
 	// transitioner wraps logic for transitioning between catchup and follow states.
 	transitioner Transitioner
+	// trust decides which peers' chain heads are preferred or accepted at all.
+	trust PeerTrust
 
 	// registeredCb is a callback registered over the control channel.  It
 	// is called after every successful sync.
@@ -99,23 +117,40 @@ type Dispatcher struct {
 	syncTargetCount uint64
 }
 
+// SetPeerTrust sets the policy used to prefer or restrict chain heads by the
+// peer that sent them. It must be called before Start.
+func (d *Dispatcher) SetPeerTrust(trust PeerTrust) {
+	d.trust = trust
+}
+
 // SendHello handles chain information from bootstrap peers.
 func (d *Dispatcher) SendHello(ci *block.ChainInfo) error {
-	return d.enqueue(ci)
+	return d.enqueuePeer(ci)
 }
 
 // SendOwnBlock handles chain info from a node's own mining system
 func (d *Dispatcher) SendOwnBlock(ci *block.ChainInfo) error {
-	return d.enqueue(ci)
+	return d.enqueue(Target{ChainInfo: *ci})
 }
 
 // SendGossipBlock handles chain info from new blocks sent on pubsub
 func (d *Dispatcher) SendGossipBlock(ci *block.ChainInfo) error {
-	return d.enqueue(ci)
+	return d.enqueuePeer(ci)
 }
 
-func (d *Dispatcher) enqueue(ci *block.ChainInfo) error {
-	d.incoming <- Target{ChainInfo: *ci}
+// enqueuePeer enqueues chain info received from another peer, dropping it
+// when only trusted peers are accepted and the sender is not one of them.
+func (d *Dispatcher) enqueuePeer(ci *block.ChainInfo) error {
+	trusted := d.trust.IsTrusted(ci.Sender)
+	if !trusted && d.trust.TrustedOnly() {
+		log.Debugf("dropping chain info from untrusted peer %s", ci)
+		return nil
+	}
+	return d.enqueue(Target{ChainInfo: *ci, Trusted: trusted})
+}
+
+func (d *Dispatcher) enqueue(t Target) error {
+	d.incoming <- t
 	return nil
 }
 
@@ -265,6 +300,8 @@ func (d *Dispatcher) processCtrl(ctrlMsg interface{}) {
 // syncing job against given inputs.
 type Target struct {
 	block.ChainInfo
+	// Trusted is true if the chain info was sent by a trusted peer.
+	Trusted bool
 }
 
 // Transitioner determines whether the caller should move between catchup and
@@ -401,8 +438,8 @@ func (tq *TargetQueue) Len() int {
 
 // targetQueue orders targets by a policy.
 //
-// The current simple policy is to order syncing requests by claimed chain
-// height.
+// The current simple policy is to order syncing requests from trusted
+// sources ahead of all others, and then by claimed chain height.
 //
 // `targetQueue` can panic so it shouldn't be used unwrapped
 type targetQueue []Target
@@ -411,6 +448,9 @@ type targetQueue []Target
 func (rq targetQueue) Len() int { return len(rq) }
 
 func (rq targetQueue) Less(i, j int) bool {
+	if rq[i].Trusted != rq[j].Trusted {
+		return rq[i].Trusted
+	}
 	// We want Pop to give us the highest priority so we use greater than
 	return rq[i].Height > rq[j].Height
 }
@@ -9,9 +9,11 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chainsync/internal/dispatcher"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/util/moresync"
 )
@@ -112,6 +114,70 @@ func TestDispatcherDropsWhenFull(t *testing.T) {
 	finished.Wait()
 }
 
+type mockTrust struct {
+	trusted     map[peer.ID]bool
+	trustedOnly bool
+}
+
+func (mt *mockTrust) IsTrusted(p peer.ID) bool { return mt.trusted[p] }
+func (mt *mockTrust) TrustedOnly() bool        { return mt.trustedOnly }
+
+func TestDispatcherTrustedOnly(t *testing.T) {
+	tf.UnitTest(t)
+	s := &mockSyncer{
+		headsCalled: make([]block.TipSetKey, 0),
+	}
+	nt := &noopTransitioner{}
+	testDispatch := dispatcher.NewDispatcher(s, nt)
+
+	trustedPeer := th.RequireIntPeerID(t, 1)
+	untrustedPeer := th.RequireIntPeerID(t, 2)
+	testDispatch.SetPeerTrust(&mockTrust{
+		trusted:     map[peer.ID]bool{trustedPeer: true},
+		trustedOnly: true,
+	})
+
+	untrusted := chainInfoFromHeight(t, 20)
+	untrusted.Sender = untrustedPeer
+	trusted := chainInfoFromHeight(t, 10)
+	trusted.Sender = trustedPeer
+	own := chainInfoFromHeight(t, 5)
+
+	allDone := moresync.NewLatch(2)
+	testDispatch.RegisterCallback(func(t dispatcher.Target, _ error) { allDone.Done() })
+
+	// Untrusted chain info is dropped before it reaches the queue.
+	assert.NoError(t, testDispatch.SendGossipBlock(untrusted))
+	assert.NoError(t, testDispatch.SendHello(trusted))
+	assert.NoError(t, testDispatch.SendOwnBlock(own))
+
+	testDispatch.Start(context.Background())
+	allDone.Wait()
+
+	assert.Equal(t, []block.TipSetKey{trusted.Head, own.Head}, s.headsCalled)
+}
+
+func TestQueueTrustedFirst(t *testing.T) {
+	tf.UnitTest(t)
+	testQ := dispatcher.NewTargetQueue()
+
+	sR3 := dispatcher.Target{ChainInfo: *(chainInfoFromHeight(t, 3))}
+	sR47 := dispatcher.Target{ChainInfo: *(chainInfoFromHeight(t, 47))}
+	sR1Trusted := dispatcher.Target{ChainInfo: *(chainInfoFromHeight(t, 1)), Trusted: true}
+	sR2Trusted := dispatcher.Target{ChainInfo: *(chainInfoFromHeight(t, 2)), Trusted: true}
+
+	testQ.Push(sR3)
+	testQ.Push(sR1Trusted)
+	testQ.Push(sR47)
+	testQ.Push(sR2Trusted)
+
+	// Trusted targets pop first, each group ordered by height
+	assert.Equal(t, abi.ChainEpoch(2), requirePop(t, testQ).ChainInfo.Height)
+	assert.Equal(t, abi.ChainEpoch(1), requirePop(t, testQ).ChainInfo.Height)
+	assert.Equal(t, abi.ChainEpoch(47), requirePop(t, testQ).ChainInfo.Height)
+	assert.Equal(t, abi.ChainEpoch(3), requirePop(t, testQ).ChainInfo.Height)
+}
+
 func TestQueueHappy(t *testing.T) {
 	tf.UnitTest(t)
 	testQ := dispatcher.NewTargetQueue()
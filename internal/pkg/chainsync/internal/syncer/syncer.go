@@ -63,6 +63,10 @@ type Syncer struct {
 
 	// Reporter is used by the syncer to update the current status of the chain.
 	reporter status.Reporter
+
+	// pruner discards old chain state each time the syncer sets the head, nil
+	// unless pruning is enabled.
+	pruner Pruner
 }
 
 // Fetcher defines an interface that may be used to fetch data from the network.
@@ -121,6 +125,11 @@ type FullBlockValidator interface {
 	RunStateTransition(ctx context.Context, ts block.TipSet, blsMessages [][]*types.UnsignedMessage, secpMessages [][]*types.SignedMessage, parentWeight fbig.Int, stateID cid.Cid, receiptRoot cid.Cid) (cid.Cid, []vm.MessageReceipt, error)
 }
 
+// Pruner discards chain state below a depth from the head.
+type Pruner interface {
+	Prune(ctx context.Context) (chain.PruneResult, error)
+}
+
 // faultDetector tracks data for detecting consensus faults and emits faults
 // upon detection.
 type faultDetector interface {
@@ -180,6 +189,13 @@ func (syncer *Syncer) InitStaged() error {
 	return nil
 }
 
+// SetPruner sets the pruner run after each new head is set. Pruning runs in
+// HandleNewTipSet, so it never races with the processing of tipsets. It must be
+// called before the syncer is started.
+func (syncer *Syncer) SetPruner(p Pruner) {
+	syncer.pruner = p
+}
+
 // SetStagedHead sets the syncer's internal staged tipset to the chain's head.
 func (syncer *Syncer) SetStagedHead(ctx context.Context) error {
 	return syncer.chainStore.SetHead(ctx, syncer.staged)
@@ -460,7 +476,16 @@ func (syncer *Syncer) HandleNewTipSet(ctx context.Context, ci *block.ChainInfo,
 	if catchup {
 		return nil
 	}
-	return syncer.SetStagedHead(ctx)
+	if err := syncer.SetStagedHead(ctx); err != nil {
+		return err
+	}
+	if syncer.pruner != nil {
+		// A failure to prune leaves the chain valid, so it does not fail the sync.
+		if _, err := syncer.pruner.Prune(ctx); err != nil {
+			logSyncer.Errorf("failed to prune chain: %s", err)
+		}
+	}
+	return nil
 }
 
 func (syncer *Syncer) handleNewTipSet(ctx context.Context, ci *block.ChainInfo) (err error) {
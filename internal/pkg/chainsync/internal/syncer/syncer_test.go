@@ -542,6 +542,43 @@ func TestStoresMessageReceipts(t *testing.T) {
 	assert.Len(t, receipts, 4)
 }
 
+func TestPrunesOnNewHead(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+	builder, store, syncer := setup(ctx, t)
+	genesis := builder.RequireTipSet(store.GetHead())
+	pruner := &countingPruner{}
+	syncer.SetPruner(pruner)
+
+	t.Log("syncing in catchup does not set the head, so does not prune")
+	t1 := builder.AppendOn(genesis, 1)
+	require.NoError(t, syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), "", t1.Key(), heightFromTip(t, t1)), true))
+	assert.Equal(t, 0, pruner.calls)
+
+	t.Log("setting the head prunes")
+	t2 := builder.AppendOn(t1, 1)
+	require.NoError(t, syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), "", t2.Key(), heightFromTip(t, t2)), false))
+	verifyHead(t, store, t2)
+	assert.Equal(t, 1, pruner.calls)
+
+	t.Log("failing to prune does not fail the sync")
+	pruner.err = errors.New("prune failed")
+	t3 := builder.AppendOn(t2, 1)
+	require.NoError(t, syncer.HandleNewTipSet(ctx, block.NewChainInfo(peer.ID(""), "", t3.Key(), heightFromTip(t, t3)), false))
+	verifyHead(t, store, t3)
+	assert.Equal(t, 2, pruner.calls)
+}
+
+type countingPruner struct {
+	calls int
+	err   error
+}
+
+func (p *countingPruner) Prune(context.Context) (chain.PruneResult, error) {
+	p.calls++
+	return chain.PruneResult{}, p.err
+}
+
 ///// Set-up /////
 
 // Initializes a chain builder, store and syncer.
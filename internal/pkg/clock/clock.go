@@ -54,3 +54,24 @@ func (rc *realClock) AfterFunc(d time.Duration, f func()) Timer {
 func NewSystemClock() Clock {
 	return &realClock{}
 }
+
+// skewedClock is a Clock whose time is offset from the time of another clock.
+type skewedClock struct {
+	Clock
+	skew time.Duration
+}
+
+// NewSkewedClock returns a Clock telling the time of `c` offset by `skew`.
+// Durations are not affected. It lets tests run a node whose clock is out of
+// sync with the rest of the network.
+func NewSkewedClock(c Clock, skew time.Duration) Clock {
+	return &skewedClock{Clock: c, skew: skew}
+}
+
+func (sc *skewedClock) Now() time.Time {
+	return sc.Clock.Now().Add(sc.skew)
+}
+
+func (sc *skewedClock) Since(t time.Time) time.Duration {
+	return sc.Now().Sub(t)
+}
@@ -0,0 +1,24 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestSkewedClock(t *testing.T) {
+	tf.UnitTest(t)
+	fc := clock.NewFake(startTime)
+	sc := clock.NewSkewedClock(fc, -3*time.Second)
+
+	assert.Equal(t, startTime.Add(-3*time.Second), sc.Now())
+	assert.Equal(t, -3*time.Second, sc.Since(startTime))
+
+	fc.Advance(5 * time.Second)
+	assert.Equal(t, startTime.Add(2*time.Second), sc.Now())
+	assert.Equal(t, 7*time.Second, sc.Since(startTime.Add(-5*time.Second)))
+}
@@ -8,9 +8,12 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	logging "github.com/ipfs/go-log/v2"
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
@@ -19,15 +22,20 @@ import (
 // Config is an in memory representation of the filecoin configuration file
 type Config struct {
 	API           *APIConfig           `json:"api"`
+	Bitswap       *BitswapConfig       `json:"bitswap"`
 	Bootstrap     *BootstrapConfig     `json:"bootstrap"`
+	Chain         *ChainConfig         `json:"chain"`
 	Datastore     *DatastoreConfig     `json:"datastore"`
 	Drand         *DrandConfig         `json:"drand"`
 	Mining        *MiningConfig        `json:"mining"`
 	Mpool         *MessagePoolConfig   `json:"mpool"`
 	NetworkParams *NetworkParamsConfig `json:"parameters"`
 	Observability *ObservabilityConfig `json:"observability"`
+	Replication   *ReplicationConfig   `json:"replication"`
 	SectorBase    *SectorBaseConfig    `json:"sectorbase"`
 	Swarm         *SwarmConfig         `json:"swarm"`
+	Sync          *SyncConfig          `json:"sync"`
+	Transfer      *TransferConfig      `json:"transfer"`
 	Wallet        *WalletConfig        `json:"wallet"`
 }
 
@@ -37,6 +45,27 @@ type APIConfig struct {
 	AccessControlAllowOrigin      []string `json:"accessControlAllowOrigin"`
 	AccessControlAllowCredentials bool     `json:"accessControlAllowCredentials"`
 	AccessControlAllowMethods     []string `json:"accessControlAllowMethods"`
+	// ReadOnly restricts the api to commands that query the chain, state and
+	// deals, rejecting any that change state or sign.
+	ReadOnly bool `json:"readOnly"`
+	// DrainTimeout bounds each step of shutting down the daemon: completing
+	// the api requests in flight, then saving outbound messages and deal and
+	// sealing state. Past it the repo is closed regardless. A duration such as
+	// "30s", "0s" for no bound, or empty for the default of 30s.
+	DrainTimeout string `json:"drainTimeout"`
+	// RateLimit is the rate of requests per second served across all clients
+	// and ClientRateLimit that served to each client, identified by its IP
+	// address. Clients sending one of the tokens in TokenRateLimits with their
+	// requests are instead identified by it and get its rate. Zero means
+	// unlimited.
+	RateLimit       float64            `json:"rateLimit"`
+	ClientRateLimit float64            `json:"clientRateLimit"`
+	TokenRateLimits map[string]float64 `json:"tokenRateLimits,omitempty"`
+	// RateLimitQueue is the number of requests over the rate limits waiting
+	// to be served, and RateLimitWait the longest any of them waits, a duration
+	// such as "5s". Requests past either bound are rejected.
+	RateLimitQueue uint   `json:"rateLimitQueue"`
+	RateLimitWait  string `json:"rateLimitWait"`
 }
 
 func newDefaultAPIConfig() *APIConfig {
@@ -49,6 +78,9 @@ func newDefaultAPIConfig() *APIConfig {
 			"https://127.0.0.1:8080",
 		},
 		AccessControlAllowMethods: []string{"GET", "POST", "PUT"},
+		DrainTimeout:              "30s",
+		RateLimitQueue:            64,
+		RateLimitWait:             "5s",
 	}
 }
 
@@ -65,7 +97,14 @@ type DatastoreConfig struct {
 // the given key and value are valid. Validators will only be run if a property
 // being set matches the name given in this map.
 var Validators = map[string]func(string, string) error{
-	"heartbeat.nickname": validateLettersOnly,
+	"heartbeat.nickname":               validateLettersOnly,
+	"bitswap.provideStrategy":          validateProvideStrategy,
+	"observability.log.levels":         validateLogLevels,
+	"observability.log.format":         validateLogFormat,
+	"observability.log.rotationPeriod": validateDuration,
+	"api.drainTimeout":                 validateDuration,
+	"swarm.banDuration":                validateDuration,
+	"chain.pruneDepth":                 validatePruneDepth,
 }
 
 func newDefaultDatastoreConfig() *DatastoreConfig {
@@ -75,15 +114,46 @@ func newDefaultDatastoreConfig() *DatastoreConfig {
 	}
 }
 
+// Bitswap provide strategies.
+const (
+	// ProvideAll announces every block added to the node.
+	ProvideAll = "all"
+	// ProvideRoots announces only the roots of data imported by the client.
+	ProvideRoots = "roots"
+	// ProvideNone never announces blocks.
+	ProvideNone = "none"
+)
+
+// BitswapConfig holds all configuration options related to bitswap.
+type BitswapConfig struct {
+	// ProvideStrategy determines which blocks are announced to the content
+	// routing system, one of "all", "roots" or "none".
+	ProvideStrategy string `json:"provideStrategy"`
+	// PeerBandwidthLimit is the maximum rate in bytes per second at which
+	// bitswap sends data to any single peer. Zero means unlimited.
+	PeerBandwidthLimit uint64 `json:"peerBandwidthLimit"`
+}
+
+func newDefaultBitswapConfig() *BitswapConfig {
+	return &BitswapConfig{
+		ProvideStrategy:    ProvideAll,
+		PeerBandwidthLimit: 0,
+	}
+}
+
 // SwarmConfig holds all configuration options related to the swarm.
 type SwarmConfig struct {
 	Address            string `json:"address"`
 	PublicRelayAddress string `json:"public_relay_address,omitempty"`
+	// BanDuration is how long peers whose offenses reach the ban threshold
+	// stay banned, e.g. for serving invalid blocks.
+	BanDuration string `json:"banDuration"`
 }
 
 func newDefaultSwarmConfig() *SwarmConfig {
 	return &SwarmConfig{
-		Address: "/ip4/0.0.0.0/tcp/6000",
+		Address:     "/ip4/0.0.0.0/tcp/6000",
+		BanDuration: "1h",
 	}
 }
 
@@ -108,24 +178,136 @@ type MiningConfig struct {
 	MinerAddress            address.Address `json:"minerAddress"`
 	AutoSealIntervalSeconds uint            `json:"autoSealIntervalSeconds"`
 	StoragePrice            types.AttoFIL   `json:"storagePrice"`
+	// PrioritizeOwnMessages places messages sent by the miner's owner and worker,
+	// such as PoSts and sector commitments, in blocks before any other message.
+	PrioritizeOwnMessages bool `json:"prioritizeOwnMessages"`
+	// PriorityAddresses are further senders whose messages are placed in blocks
+	// before those of senders without priority.
+	PriorityAddresses []address.Address `json:"priorityAddresses"`
+	// DealTransferRestarts is the number of times the transfer of the payload
+	// of a deal is started again after failing, before the deal fails.
+	DealTransferRestarts uint `json:"dealTransferRestarts"`
+	// AnnouncedCapacity is the number of bytes of deal data the miner announces
+	// it accepts along with its ask, zero when unspecified.
+	AnnouncedCapacity uint64 `json:"announcedCapacity"`
+	// AskAnnounceIntervalSeconds is the interval at which the miner announces
+	// its ask on the ask topic.
+	AskAnnounceIntervalSeconds uint `json:"askAnnounceIntervalSeconds"`
+	// AutoBid makes the miner bid with its ask on the storage requests
+	// clients publish, when it can take them.
+	AutoBid bool `json:"autoBid"`
+	// ScrubIntervalSeconds is the interval at which the sealed sectors of the
+	// miner are proven on random challenges, a sampled check detecting missing
+	// or corrupted sector files before their PoSt deadline, zero to only scrub
+	// on demand.
+	ScrubIntervalSeconds uint `json:"scrubIntervalSeconds"`
 }
 
 func newDefaultMiningConfig() *MiningConfig {
 	return &MiningConfig{
-		MinerAddress:            address.Undef,
-		AutoSealIntervalSeconds: 120,
-		StoragePrice:            types.ZeroAttoFIL,
+		MinerAddress:               address.Undef,
+		AutoSealIntervalSeconds:    120,
+		StoragePrice:               types.ZeroAttoFIL,
+		PrioritizeOwnMessages:      true,
+		PriorityAddresses:          []address.Address{},
+		DealTransferRestarts:       3,
+		AnnouncedCapacity:          0,
+		AskAnnounceIntervalSeconds: 300,
+		AutoBid:                    false,
+		ScrubIntervalSeconds:       6 * 60 * 60,
+	}
+}
+
+// ChainConfig holds all configuration options related to the chain store.
+type ChainConfig struct {
+	// Prune enables discarding state trees, receipts and messages of tipsets
+	// more than PruneDepth epochs below the head. Block headers are kept.
+	Prune bool `json:"prune"`
+	// PruneDepth is the number of epochs below the head for which full state is
+	// retained. It may not be less than the chain finality, since tipsets above
+	// finality can still be reorged onto and need their parent state.
+	PruneDepth abi.ChainEpoch `json:"pruneDepth"`
+	// Archive enables an index of the receipts of all messages on chain, so that
+	// the receipts of old messages are found without scanning the chain. The
+	// messages of tipsets pruned before it is enabled are not indexed.
+	Archive bool `json:"archive"`
+}
+
+func newDefaultChainConfig() *ChainConfig {
+	return &ChainConfig{
+		Prune:      false,
+		PruneDepth: miner.ChainFinalityish,
+		Archive:    false,
+	}
+}
+
+// ReplicationConfig holds all configuration options related to the replication
+// of pieces stored by the client with several miners.
+type ReplicationConfig struct {
+	// CheckInterval is the number of epochs between two checks of the deals of
+	// replicated pieces.
+	CheckInterval abi.ChainEpoch `json:"checkInterval"`
+	// StartDelay is the number of epochs between proposing a deal and its start,
+	// leaving time to transfer and seal the piece.
+	StartDelay abi.ChainEpoch `json:"startDelay"`
+}
+
+func newDefaultReplicationConfig() *ReplicationConfig {
+	return &ReplicationConfig{
+		CheckInterval: 10,
+		StartDelay:    1000,
 	}
 }
 
+// SyncConfig holds all configuration options related to chain syncing.
+type SyncConfig struct {
+	// TrustedPeers are the IDs of peers whose chain heads the syncer prefers.
+	TrustedPeers []string `json:"trustedPeers"`
+	// TrustedOnly causes chain heads from peers that are not trusted to be ignored.
+	TrustedOnly bool `json:"trustedOnly"`
+	// MessageWorkers is the number of goroutines applying the messages of a
+	// block from independent senders in parallel while validating tipsets.
+	// Messages are applied serially when not greater than 1.
+	MessageWorkers int `json:"messageWorkers"`
+}
+
+func newDefaultSyncConfig() *SyncConfig {
+	return &SyncConfig{
+		TrustedPeers:   []string{},
+		TrustedOnly:    false,
+		MessageWorkers: 1,
+	}
+}
+
+// TransferConfig holds the limits of the rates at which storage deal piece
+// transfers and retrievals send and receive data, in bytes per second. Zero
+// means unlimited. The limits are set when the node starts and may be changed
+// while it runs with "swarm throttle".
+type TransferConfig struct {
+	UploadLimit       uint64 `json:"uploadLimit"`
+	DownloadLimit     uint64 `json:"downloadLimit"`
+	PeerUploadLimit   uint64 `json:"peerUploadLimit"`
+	PeerDownloadLimit uint64 `json:"peerDownloadLimit"`
+}
+
+func newDefaultTransferConfig() *TransferConfig {
+	return &TransferConfig{}
+}
+
 // WalletConfig holds all configuration options related to the wallet.
 type WalletConfig struct {
 	DefaultAddress address.Address `json:"defaultAddress,omitempty"`
+	// DealAddress is the key that proposes and signs storage deals, holding
+	// the market escrow of the deals. The escrow is funded from the default
+	// address, so a deal key needs no funds of its own. Deals are made with
+	// the default address when it is unset.
+	DealAddress address.Address `json:"dealAddress,omitempty"`
 }
 
 func newDefaultWalletConfig() *WalletConfig {
 	return &WalletConfig{
 		DefaultAddress: address.Undef,
+		DealAddress:    address.Undef,
 	}
 }
 
@@ -175,12 +357,14 @@ type HeartbeatConfig struct {
 type ObservabilityConfig struct {
 	Metrics *MetricsConfig `json:"metrics"`
 	Tracing *TraceConfig   `json:"tracing"`
+	Log     *LogConfig     `json:"log"`
 }
 
 func newDefaultObservabilityConfig() *ObservabilityConfig {
 	return &ObservabilityConfig{
 		Metrics: newDefaultMetricsConfig(),
 		Tracing: newDefaultTraceConfig(),
+		Log:     newDefaultLogConfig(),
 	}
 }
 
@@ -221,6 +405,37 @@ func newDefaultTraceConfig() *TraceConfig {
 	}
 }
 
+// LogConfig holds all configuration options related to the daemon log output.
+type LogConfig struct {
+	// Levels sets the level of subsystems when the daemon starts, keyed by subsystem
+	// name as listed by 'go-filecoin log ls'. Other subsystems keep the default level.
+	Levels map[string]string `json:"levels"`
+	// File is the path of a file the daemon writes its log to in addition to stderr,
+	// relative to the repo directory unless absolute. No file is written when empty.
+	File string `json:"file"`
+	// Format is the format of the log file, "console" or "json". The format of
+	// stderr is set by the GOLOG_LOG_FMT environment variable.
+	Format string `json:"format"`
+	// MaxSizeMB is the size in megabytes above which the log file is rotated, 0 for no limit.
+	MaxSizeMB uint `json:"maxSizeMB"`
+	// RotationPeriod is how long the log file is written before it is rotated, empty
+	// for no limit. Golang duration units are accepted.
+	RotationPeriod string `json:"rotationPeriod"`
+	// MaxBackups is the number of rotated log files kept, 0 to keep them all.
+	MaxBackups uint `json:"maxBackups"`
+}
+
+func newDefaultLogConfig() *LogConfig {
+	return &LogConfig{
+		Levels:         map[string]string{},
+		File:           "",
+		Format:         "console",
+		MaxSizeMB:      100,
+		RotationPeriod: "24h",
+		MaxBackups:     7,
+	}
+}
+
 // MessagePoolConfig holds all configuration options related to nodes message pool (mpool).
 type MessagePoolConfig struct {
 	// MaxPoolSize is the maximum number of pending messages will will allow in the message pool at any time
@@ -239,6 +454,28 @@ func newDefaultMessagePoolConfig() *MessagePoolConfig {
 type NetworkParamsConfig struct {
 	ConsensusMinerMinPower uint64 // uint64 goes up to 18 EiB
 	ReplaceProofTypes      []int64
+	// Upgrades schedules protocol upgrades of a private network. Every node of the
+	// network must be configured with the same upgrades.
+	Upgrades []NetworkUpgradeConfig
+	// DevnetActors enables the experimental actors deployable by users, for
+	// application prototyping on devnets. Every node of the network must agree on it.
+	DevnetActors bool
+	// GasScheduleFile is the path of a JSON file of gas schedules replacing the
+	// default gas costs of the VM, as suggested by the gas-calibration tool.
+	// Every node of the network must use the same schedules.
+	GasScheduleFile string
+}
+
+// NetworkUpgradeConfig is a protocol upgrade changing network parameters from a height.
+type NetworkUpgradeConfig struct {
+	// Version is the protocol version from the height, greater than that of any earlier upgrade.
+	Version uint64
+	Height  int64
+	Name    string
+	// ConsensusMinerMinPower and ReplaceProofTypes replace the values of the
+	// previous version, the previous values are kept when 0 or empty.
+	ConsensusMinerMinPower uint64
+	ReplaceProofTypes      []int64
 }
 
 func newDefaultNetworkParamsConfig() *NetworkParamsConfig {
@@ -250,6 +487,7 @@ func newDefaultNetworkParamsConfig() *NetworkParamsConfig {
 			int64(abi.RegisteredProof_StackedDRG32GiBSeal),
 			int64(abi.RegisteredProof_StackedDRG64GiBSeal),
 		},
+		Upgrades: []NetworkUpgradeConfig{},
 	}
 }
 
@@ -264,12 +502,17 @@ type SectorBaseConfig struct {
 	// pre-sealed sector files and corresponding metadata JSON.
 	// If empty, it is assumed that no pre-sealed sectors exist.
 	PreSealedSectorsDirPath string `json:"preSealedSectorsDir"`
+
+	// UnsealCacheMaxBytes bounds the space used to keep unsealed copies of
+	// sectors for retrieval. Zero disables the cache.
+	UnsealCacheMaxBytes uint64 `json:"unsealCacheMaxBytes"`
 }
 
 func newDefaultSectorbaseConfig() *SectorBaseConfig {
 	return &SectorBaseConfig{
 		RootDirPath:             "",
 		PreSealedSectorsDirPath: "",
+		UnsealCacheMaxBytes:     1 << 30,
 	}
 }
 
@@ -278,15 +521,20 @@ func newDefaultSectorbaseConfig() *SectorBaseConfig {
 func NewDefaultConfig() *Config {
 	return &Config{
 		API:           newDefaultAPIConfig(),
+		Bitswap:       newDefaultBitswapConfig(),
 		Bootstrap:     newDefaultBootstrapConfig(),
+		Chain:         newDefaultChainConfig(),
 		Datastore:     newDefaultDatastoreConfig(),
 		Drand:         newDefaultDrandConfig(),
 		Mining:        newDefaultMiningConfig(),
 		Mpool:         newDefaultMessagePoolConfig(),
 		NetworkParams: newDefaultNetworkParamsConfig(),
 		Observability: newDefaultObservabilityConfig(),
+		Replication:   newDefaultReplicationConfig(),
 		SectorBase:    newDefaultSectorbaseConfig(),
 		Swarm:         newDefaultSwarmConfig(),
+		Sync:          newDefaultSyncConfig(),
+		Transfer:      newDefaultTransferConfig(),
 		Wallet:        newDefaultWalletConfig(),
 	}
 }
@@ -391,8 +639,9 @@ func validate(dottedKey string, jsonString string) error {
 	if err := json.Unmarshal([]byte(jsonString), &obj); err != nil {
 		return err
 	}
-	// recursively validate sub-keys by partially unmarshalling
-	if reflect.ValueOf(obj).Kind() == reflect.Map {
+	// recursively validate sub-keys by partially unmarshalling, unless the
+	// object is validated as a whole
+	if _, present := Validators[dottedKey]; !present && reflect.ValueOf(obj).Kind() == reflect.Map {
 		var obj map[string]json.RawMessage
 		if err := json.Unmarshal([]byte(jsonString), &obj); err != nil {
 			return err
@@ -412,6 +661,70 @@ func validate(dottedKey string, jsonString string) error {
 	return nil
 }
 
+// validateProvideStrategy validates that a given value is a JSON string naming
+// one of the provide strategies. If it is not, an error is returned using the
+// given key for the message.
+func validateProvideStrategy(key string, value string) error {
+	var strategy string
+	if err := json.Unmarshal([]byte(value), &strategy); err != nil {
+		return errors.Wrapf(err, `"%s" must be a string`, key)
+	}
+	switch strategy {
+	case ProvideAll, ProvideRoots, ProvideNone:
+		return nil
+	}
+	return errors.Errorf(`"%s" must be one of "%s", "%s" or "%s"`, key, ProvideAll, ProvideRoots, ProvideNone)
+}
+
+func validateLogLevels(key string, value string) error {
+	var levels map[string]string
+	if err := json.Unmarshal([]byte(value), &levels); err != nil {
+		return errors.Wrapf(err, `"%s" must be an object of subsystem names to levels`, key)
+	}
+	for subsystem, level := range levels {
+		if _, err := logging.LevelFromString(level); err != nil {
+			return errors.Errorf(`"%s.%s": unknown log level "%s"`, key, subsystem, level)
+		}
+	}
+	return nil
+}
+
+func validateLogFormat(key string, value string) error {
+	var format string
+	if err := json.Unmarshal([]byte(value), &format); err != nil {
+		return errors.Wrapf(err, `"%s" must be a string`, key)
+	}
+	if format != "console" && format != "json" {
+		return errors.Errorf(`"%s" must be one of "console" or "json"`, key)
+	}
+	return nil
+}
+
+func validateDuration(key string, value string) error {
+	var duration string
+	if err := json.Unmarshal([]byte(value), &duration); err != nil {
+		return errors.Wrapf(err, `"%s" must be a string`, key)
+	}
+	if duration == "" {
+		return nil
+	}
+	if _, err := time.ParseDuration(duration); err != nil {
+		return errors.Errorf(`"%s" must be a duration such as "24h"`, key)
+	}
+	return nil
+}
+
+func validatePruneDepth(key string, value string) error {
+	var depth abi.ChainEpoch
+	if err := json.Unmarshal([]byte(value), &depth); err != nil {
+		return errors.Wrapf(err, `"%s" must be a number of epochs`, key)
+	}
+	if depth < miner.ChainFinalityish {
+		return errors.Errorf(`"%s" must be at least the chain finality of %d epochs`, key, miner.ChainFinalityish)
+	}
+	return nil
+}
+
 // validateLettersOnly validates that a given value contains only letters. If it
 // does not, an error is returned using the given key for the message.
 func validateLettersOnly(key string, value string) error {
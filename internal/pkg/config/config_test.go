@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -204,6 +205,19 @@ func TestConfigSet(t *testing.T) {
 		err = cfg.Set("api.accessControlAllowOrigin", `["http://localroast:7854"]`)
 		assert.NoError(t, err)
 		assert.Equal(t, cfg.API.AccessControlAllowOrigin, []string{"http://localroast:7854"})
+
+		// set validated value
+		err = cfg.Set("bitswap.provideStrategy", ProvideRoots)
+		assert.NoError(t, err)
+		assert.Equal(t, ProvideRoots, cfg.Bitswap.ProvideStrategy)
+
+		err = cfg.Set("observability.log.levels", `{"chainsync": "debug"}`)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"chainsync": "debug"}, cfg.Observability.Log.Levels)
+
+		err = cfg.Set("chain.pruneDepth", `2000`)
+		assert.NoError(t, err)
+		assert.Equal(t, abi.ChainEpoch(2000), cfg.Chain.PruneDepth)
 	})
 
 	t.Run("set table value", func(t *testing.T) {
@@ -266,6 +280,26 @@ path = "mushroom-mushroom"}`
 
 		err = cfg.Set("wallet.defaultAddress", "corruptandtooshort")
 		assert.Contains(t, err.Error(), address.ErrUnknownNetwork.Error())
+
+		// unknown provide strategy
+		err = cfg.Set("bitswap.provideStrategy", `"sometimes"`)
+		assert.Error(t, err)
+		err = cfg.Set("bitswap", `{"provideStrategy": "sometimes"}`)
+		assert.Error(t, err)
+
+		// bad log settings
+		err = cfg.Set("observability.log.levels", `{"chainsync": "chatty"}`)
+		assert.Error(t, err)
+		err = cfg.Set("observability.log.format", `"xml"`)
+		assert.Error(t, err)
+		err = cfg.Set("observability.log.rotationPeriod", `"daily"`)
+		assert.Error(t, err)
+
+		// pruning above finality
+		err = cfg.Set("chain.pruneDepth", `10`)
+		assert.Error(t, err)
+		err = cfg.Set("chain", `{"prune": true, "pruneDepth": 10}`)
+		assert.Error(t, err)
 	})
 
 	t.Run("setting leaves does not interfere with neighboring leaves", func(t *testing.T) {
@@ -0,0 +1,259 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	acrypto "github.com/filecoin-project/specs-actors/actors/crypto"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/cborutil"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
+	e "github.com/filecoin-project/go-filecoin/internal/pkg/enccid"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/state"
+)
+
+// Capture applies the messages of `blks` at `epoch` on top of the tipset
+// `parent` to the state `preRoot` read from `bs`, and returns the vector of
+// the application. The vector expects the state and receipts computed. `bs`
+// is not modified.
+func Capture(ctx context.Context, bs blockstore.Blockstore, syscalls vm.SyscallsImpl, rnd crypto.RandomnessSource,
+	preRoot cid.Cid, parent block.TipSetKey, epoch abi.ChainEpoch, blks []vm.BlockMessagesInfo) (*Vector, error) {
+	v := &Vector{
+		Version:      VectorVersion,
+		Epoch:        epoch,
+		Parent:       parent,
+		PreStateRoot: e.NewCid(preRoot),
+	}
+	for _, blk := range blks {
+		v.Blocks = append(v.Blocks, BlockMessages{Miner: blk.Miner, BLSMessages: blk.BLSMessages, SECPMessages: blk.SECPMessages})
+	}
+
+	tracer := newTracingBlockstore(bs)
+	recorder := &recordingRandomness{source: rnd}
+	root, receipts, err := apply(ctx, tracer, syscalls, recorder, v)
+	if err != nil {
+		return nil, err
+	}
+	v.PostStateRoot = e.NewCid(root)
+	v.Receipts = receipts
+	v.Randomness = recorder.records
+	v.State = tracer.readBlocks()
+	return v, nil
+}
+
+// apply applies the messages of `v` to its pre-state in `bs`, returning the
+// root of the state after them and their receipts.
+func apply(ctx context.Context, bs blockstore.Blockstore, syscalls vm.SyscallsImpl, rnd crypto.RandomnessSource, v *Vector) (cid.Cid, []vm.MessageReceipt, error) {
+	st, err := state.LoadState(ctx, cborutil.NewIpldStore(bs), v.PreStateRoot.Cid)
+	if err != nil {
+		return cid.Undef, nil, errors.Wrapf(err, "failed to load pre-state %s", v.PreStateRoot)
+	}
+	vms := vm.NewStorage(bs)
+	receipts, err := vm.NewVM(st, &vms, syscalls).ApplyTipSetMessages(v.blockMessages(), v.Parent, v.Epoch, rnd)
+	if err != nil {
+		return cid.Undef, nil, errors.Wrap(err, "failed to apply messages")
+	}
+	if err := vms.Flush(); err != nil {
+		return cid.Undef, nil, err
+	}
+	root, err := st.Commit(ctx)
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+	return root, receipts, nil
+}
+
+type exporterChain interface {
+	GetTipSet(block.TipSetKey) (block.TipSet, error)
+	GetTipSetStateRoot(block.TipSetKey) (cid.Cid, error)
+	GetTipSetReceiptsRoot(block.TipSetKey) (cid.Cid, error)
+}
+
+type exporterMessages interface {
+	LoadMessages(context.Context, cid.Cid) ([]*types.SignedMessage, []*types.UnsignedMessage, error)
+	LoadReceipts(context.Context, cid.Cid) ([]vm.MessageReceipt, error)
+}
+
+// ChainRandomness draws randomness from a chain.
+type ChainRandomness interface {
+	SampleChainRandomness(ctx context.Context, head block.TipSetKey, tag acrypto.DomainSeparationTag, epoch abi.ChainEpoch, entropy []byte) (abi.Randomness, error)
+}
+
+// Exporter captures the vectors of the tipsets of a chain.
+type Exporter struct {
+	chain    exporterChain
+	messages exporterMessages
+	bs       blockstore.Blockstore
+	syscalls vm.SyscallsImpl
+	rnd      ChainRandomness
+}
+
+// NewExporter creates an exporter of the vectors of the tipsets of `chain`,
+// whose state is in `bs`.
+func NewExporter(chain exporterChain, messages exporterMessages, bs blockstore.Blockstore, syscalls vm.SyscallsImpl, rnd ChainRandomness) *Exporter {
+	return &Exporter{chain: chain, messages: messages, bs: bs, syscalls: syscalls, rnd: rnd}
+}
+
+// Export returns the vector of the application of the messages of the tipset
+// `key`, expecting the state root and receipts recorded in the chain. The
+// messages are applied again to capture the state they read, reproduced is
+// false when that gives a different result than the chain.
+func (ex *Exporter) Export(ctx context.Context, key block.TipSetKey) (v *Vector, reproduced bool, err error) {
+	ts, err := ex.chain.GetTipSet(key)
+	if err != nil {
+		return nil, false, err
+	}
+	epoch, err := ts.Height()
+	if err != nil {
+		return nil, false, err
+	}
+	parent, err := ts.Parents()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var blks []vm.BlockMessagesInfo
+	for i := 0; i < ts.Len(); i++ {
+		blk := ts.At(i)
+		secp, bls, err := ex.messages.LoadMessages(ctx, blk.Messages.Cid)
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "failed to load messages of block %s", blk.Cid())
+		}
+		blks = append(blks, vm.BlockMessagesInfo{Miner: blk.Miner, BLSMessages: bls, SECPMessages: secp})
+	}
+
+	postRoot, err := ex.chain.GetTipSetStateRoot(key)
+	if err != nil {
+		return nil, false, err
+	}
+	receiptsRoot, err := ex.chain.GetTipSetReceiptsRoot(key)
+	if err != nil {
+		return nil, false, err
+	}
+	receipts, err := ex.messages.LoadReceipts(ctx, receiptsRoot)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to load receipts")
+	}
+
+	rnd := &headRandomness{chain: ex.rnd, head: parent}
+	v, err = Capture(ctx, ex.bs, ex.syscalls, rnd, ts.At(0).StateRoot.Cid, parent, epoch, blks)
+	if err != nil {
+		return nil, false, err
+	}
+	reproduced = v.PostStateRoot.Equals(postRoot) && len(diffReceipts(receipts, v.Receipts)) == 0
+	v.PostStateRoot = e.NewCid(postRoot)
+	v.Receipts = receipts
+	return v, reproduced, nil
+}
+
+type headRandomness struct {
+	chain ChainRandomness
+	head  block.TipSetKey
+}
+
+func (h *headRandomness) Randomness(ctx context.Context, tag acrypto.DomainSeparationTag, epoch abi.ChainEpoch, entropy []byte) (abi.Randomness, error) {
+	return h.chain.SampleChainRandomness(ctx, h.head, tag, epoch, entropy)
+}
+
+// recordingRandomness records the randomness drawn from a source.
+type recordingRandomness struct {
+	source  crypto.RandomnessSource
+	lk      sync.Mutex
+	records []RandomnessRecord
+}
+
+func (r *recordingRandomness) Randomness(ctx context.Context, tag acrypto.DomainSeparationTag, epoch abi.ChainEpoch, entropy []byte) (abi.Randomness, error) {
+	value, err := r.source.Randomness(ctx, tag, epoch, entropy)
+	if err != nil {
+		return nil, err
+	}
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.records = append(r.records, RandomnessRecord{Tag: tag, Epoch: epoch, Entropy: entropy, Value: value})
+	return value, nil
+}
+
+// replayedRandomness returns the randomness recorded in a vector.
+type replayedRandomness []RandomnessRecord
+
+func (r replayedRandomness) Randomness(_ context.Context, tag acrypto.DomainSeparationTag, epoch abi.ChainEpoch, entropy []byte) (abi.Randomness, error) {
+	for _, record := range r {
+		if record.Tag == tag && record.Epoch == epoch && bytes.Equal(record.Entropy, entropy) {
+			return record.Value, nil
+		}
+	}
+	return nil, errors.Errorf("no randomness recorded for tag %d at epoch %d", tag, epoch)
+}
+
+// tracingBlockstore reads from a base blockstore, recording the blocks read,
+// and keeps the blocks written in memory.
+type tracingBlockstore struct {
+	blockstore.Blockstore
+	base blockstore.Blockstore
+
+	lk   sync.Mutex
+	read map[cid.Cid][]byte
+	// order of the blocks read, for deterministic vectors
+	order []cid.Cid
+}
+
+func newTracingBlockstore(base blockstore.Blockstore) *tracingBlockstore {
+	return &tracingBlockstore{
+		Blockstore: blockstore.NewBlockstore(dssync.MutexWrap(datastore.NewMapDatastore())),
+		base:       base,
+		read:       make(map[cid.Cid][]byte),
+	}
+}
+
+func (t *tracingBlockstore) Get(c cid.Cid) (blocks.Block, error) {
+	if blk, err := t.Blockstore.Get(c); err == nil {
+		return blk, nil
+	}
+	blk, err := t.base.Get(c)
+	if err != nil {
+		return nil, err
+	}
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	if _, ok := t.read[c]; !ok {
+		t.read[c] = blk.RawData()
+		t.order = append(t.order, c)
+	}
+	return blk, nil
+}
+
+func (t *tracingBlockstore) Has(c cid.Cid) (bool, error) {
+	if has, err := t.Blockstore.Has(c); err != nil || has {
+		return has, err
+	}
+	return t.base.Has(c)
+}
+
+func (t *tracingBlockstore) GetSize(c cid.Cid) (int, error) {
+	blk, err := t.Get(c)
+	if err != nil {
+		return -1, err
+	}
+	return len(blk.RawData()), nil
+}
+
+func (t *tracingBlockstore) readBlocks() []StateBlock {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	blks := make([]StateBlock, len(t.order))
+	for i, c := range t.order {
+		blks[i] = StateBlock{Cid: e.NewCid(c), Data: t.read[c]}
+	}
+	return blks
+}
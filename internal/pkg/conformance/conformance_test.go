@@ -0,0 +1,188 @@
+package conformance_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	acrypto "github.com/filecoin-project/specs-actors/actors/crypto"
+	"github.com/ipfs/go-cid"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/cborutil"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/conformance"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/constants"
+	e "github.com/filecoin-project/go-filecoin/internal/pkg/enccid"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
+	gengen "github.com/filecoin-project/go-filecoin/tools/gengen/util"
+)
+
+func TestCaptureAndRun(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	bs, genesis, accounts := requireGenesis(ctx, t, 3)
+	blks := []vm.BlockMessagesInfo{{
+		Miner: genesis.Miner,
+		BLSMessages: []*types.UnsignedMessage{
+			send(accounts[0], accounts[1], 0),
+			send(accounts[1], accounts[2], 0),
+			// fails for a bad nonce
+			send(accounts[2], accounts[0], 3),
+		},
+	}}
+
+	v, err := conformance.Capture(ctx, bs, &vm.FakeSyscalls{}, fakeRandomness{}, genesis.StateRoot.Cid, block.NewTipSetKey(genesis.Cid()), genesis.Height+1, blks)
+	require.NoError(t, err)
+	assert.Equal(t, 3, v.Messages())
+	require.Len(t, v.Receipts, 3)
+	assert.True(t, v.Receipts[0].ExitCode.IsSuccess())
+	assert.True(t, v.Receipts[2].ExitCode.IsError())
+	assert.NotEqual(t, genesis.StateRoot, v.PostStateRoot)
+	assert.NotEmpty(t, v.State)
+
+	t.Run("vectors run without the chain", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, conformance.WriteVector(&buf, v))
+		read, err := conformance.ReadVector(&buf)
+		require.NoError(t, err)
+
+		result, err := conformance.Run(ctx, read, &vm.FakeSyscalls{})
+		require.NoError(t, err)
+		assert.True(t, result.Passed(), result.Failures)
+		assert.Equal(t, v.PostStateRoot.Cid, result.PostStateRoot)
+	})
+
+	t.Run("regressions are reported", func(t *testing.T) {
+		wrong := *v
+		wrong.PostStateRoot = genesis.StateRoot
+		wrong.Receipts = append([]vm.MessageReceipt{}, v.Receipts...)
+		wrong.Receipts[1].GasUsed++
+
+		result, err := conformance.Run(ctx, &wrong, &vm.FakeSyscalls{})
+		require.NoError(t, err)
+		assert.False(t, result.Passed())
+		assert.Len(t, result.Failures, 2)
+	})
+}
+
+func TestExport(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	bs, genesis, accounts := requireGenesis(ctx, t, 2)
+	msgs := []*types.UnsignedMessage{send(accounts[0], accounts[1], 0)}
+	ts := block.RequireNewTipSet(t, &block.Block{
+		Miner:     genesis.Miner,
+		Height:    genesis.Height + 1,
+		Parents:   block.NewTipSetKey(genesis.Cid()),
+		StateRoot: genesis.StateRoot,
+		Messages:  e.NewCid(types.EmptyMessagesCID),
+		Timestamp: genesis.Timestamp + 1,
+	})
+
+	expected, err := conformance.Capture(ctx, bs, &vm.FakeSyscalls{}, fakeRandomness{}, genesis.StateRoot.Cid, ts.Key(), genesis.Height+1, []vm.BlockMessagesInfo{{Miner: genesis.Miner, BLSMessages: msgs}})
+	require.NoError(t, err)
+
+	chn := &fakeChain{ts: ts, root: expected.PostStateRoot.Cid, receipts: expected.Receipts, msgs: msgs}
+	exporter := conformance.NewExporter(chn, chn, bs, &vm.FakeSyscalls{}, fakeRandomness{})
+
+	v, reproduced, err := exporter.Export(ctx, ts.Key())
+	require.NoError(t, err)
+	assert.True(t, reproduced)
+	assert.Equal(t, genesis.StateRoot, v.PreStateRoot)
+	assert.Equal(t, expected.PostStateRoot, v.PostStateRoot)
+	assert.Equal(t, 1, v.Messages())
+
+	t.Log("vectors expect the results of the chain")
+	chn.root = genesis.StateRoot.Cid
+	v, reproduced, err = exporter.Export(ctx, ts.Key())
+	require.NoError(t, err)
+	assert.False(t, reproduced)
+	assert.Equal(t, genesis.StateRoot, v.PostStateRoot)
+	result, err := conformance.Run(ctx, v, &vm.FakeSyscalls{})
+	require.NoError(t, err)
+	assert.False(t, result.Passed())
+}
+
+func send(from, to address.Address, nonce uint64) *types.UnsignedMessage {
+	return types.NewMeteredMessage(from, to, nonce, types.NewAttoFILFromFIL(1), builtin.MethodSend, nil, types.NewGasPrice(1), gas.NewGas(10000))
+}
+
+type fakeRandomness struct{}
+
+func (fakeRandomness) Randomness(_ context.Context, _ acrypto.DomainSeparationTag, epoch abi.ChainEpoch, _ []byte) (abi.Randomness, error) {
+	return []byte{byte(epoch)}, nil
+}
+
+func (fakeRandomness) SampleChainRandomness(ctx context.Context, _ block.TipSetKey, tag acrypto.DomainSeparationTag, epoch abi.ChainEpoch, entropy []byte) (abi.Randomness, error) {
+	return fakeRandomness{}.Randomness(ctx, tag, epoch, entropy)
+}
+
+type fakeChain struct {
+	ts       block.TipSet
+	root     cid.Cid
+	receipts []vm.MessageReceipt
+	msgs     []*types.UnsignedMessage
+}
+
+func (f *fakeChain) GetTipSet(block.TipSetKey) (block.TipSet, error) {
+	return f.ts, nil
+}
+
+func (f *fakeChain) GetTipSetStateRoot(block.TipSetKey) (cid.Cid, error) {
+	return f.root, nil
+}
+
+func (f *fakeChain) GetTipSetReceiptsRoot(block.TipSetKey) (cid.Cid, error) {
+	return types.EmptyReceiptsCID, nil
+}
+
+func (f *fakeChain) LoadMessages(context.Context, cid.Cid) ([]*types.SignedMessage, []*types.UnsignedMessage, error) {
+	return nil, f.msgs, nil
+}
+
+func (f *fakeChain) LoadReceipts(context.Context, cid.Cid) ([]vm.MessageReceipt, error) {
+	return f.receipts, nil
+}
+
+func requireGenesis(ctx context.Context, t *testing.T, numAccounts int) (bstore.Blockstore, *block.Block, []address.Address) {
+	r := repo.NewInMemoryRepo()
+	bs := bstore.NewBlockstore(r.Datastore())
+	cst := cborutil.NewIpldStore(bs)
+
+	commCfgs, err := gengen.MakeCommitCfgs(1)
+	require.NoError(t, err)
+	genCfg := &gengen.GenesisCfg{}
+	require.NoError(t, gengen.NetworkName("conformancetest")(genCfg))
+	require.NoError(t, gengen.GenKeys(numAccounts+1, "1000000")(genCfg))
+	require.NoError(t, gengen.MinerConfigs([]*gengen.CreateStorageMinerConfig{{
+		Owner:            numAccounts,
+		CommittedSectors: commCfgs,
+		SealProofType:    constants.DevSealProofType,
+	}})(genCfg))
+
+	info, err := gengen.GenGen(ctx, genCfg, bs)
+	require.NoError(t, err)
+
+	var genesis block.Block
+	require.NoError(t, cst.Get(ctx, info.GenesisCid, &genesis))
+	genesis.Miner = info.Miners[0].Address
+
+	accounts := make([]address.Address, numAccounts)
+	for i := range accounts {
+		accounts[i], err = info.Keys[i].Address()
+		require.NoError(t, err)
+	}
+	return bs, &genesis, accounts
+}
@@ -0,0 +1,77 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
+)
+
+// Result is the outcome of running a vector.
+type Result struct {
+	PostStateRoot cid.Cid
+	Receipts      []vm.MessageReceipt
+	// Failures describe how the outcome differs from the expectations of the
+	// vector.
+	Failures []string
+}
+
+// Passed returns whether the outcome meets the expectations of the vector.
+func (r *Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Run applies the messages of `v` to its state and compares the outcome with
+// its expectations. The randomness drawn is the one recorded in the vector.
+func Run(ctx context.Context, v *Vector, syscalls vm.SyscallsImpl) (*Result, error) {
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(datastore.NewMapDatastore()))
+	for _, sb := range v.State {
+		blk, err := blocks.NewBlockWithCid(sb.Data, sb.Cid.Cid)
+		if err != nil {
+			return nil, fmt.Errorf("invalid state block %s: %s", sb.Cid, err)
+		}
+		if err := bs.Put(blk); err != nil {
+			return nil, err
+		}
+	}
+
+	root, receipts, err := apply(ctx, bs, syscalls, replayedRandomness(v.Randomness), v)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{PostStateRoot: root, Receipts: receipts}
+	if !v.PostStateRoot.Equals(root) {
+		result.Failures = append(result.Failures, fmt.Sprintf("state root %s, expected %s", root, v.PostStateRoot))
+	}
+	result.Failures = append(result.Failures, diffReceipts(v.Receipts, receipts)...)
+	return result, nil
+}
+
+// diffReceipts describes the differences of the receipts `actual` with those
+// `expected`.
+func diffReceipts(expected, actual []vm.MessageReceipt) []string {
+	if len(expected) != len(actual) {
+		return []string{fmt.Sprintf("%d receipts, expected %d", len(actual), len(expected))}
+	}
+	var diffs []string
+	for i := range expected {
+		exp, act := expected[i], actual[i]
+		switch {
+		case exp.ExitCode != act.ExitCode:
+			diffs = append(diffs, fmt.Sprintf("receipt %d: exit code %d, expected %d", i, act.ExitCode, exp.ExitCode))
+		case exp.GasUsed != act.GasUsed:
+			diffs = append(diffs, fmt.Sprintf("receipt %d: gas used %d, expected %d", i, act.GasUsed, exp.GasUsed))
+		case !bytes.Equal(exp.ReturnValue, act.ReturnValue):
+			diffs = append(diffs, fmt.Sprintf("receipt %d: return value %x, expected %x", i, act.ReturnValue, exp.ReturnValue))
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,127 @@
+// Package conformance captures the application of the messages of a tipset
+// as standalone test vectors, and re-executes them against the VM. A vector
+// holds the messages, the randomness they drew, the blocks of the state they
+// read and the resulting state root and receipts, so that it can be replayed
+// without a chain, by this or another implementation.
+package conformance
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/crypto"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	e "github.com/filecoin-project/go-filecoin/internal/pkg/enccid"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
+)
+
+// VectorVersion is the version of the encoding of the vectors written.
+const VectorVersion = 1
+
+// Vector is the application of the messages of a tipset to a state.
+type Vector struct {
+	_       struct{} `cbor:",toarray"`
+	Version uint64
+	// Epoch is the epoch of the tipset.
+	Epoch abi.ChainEpoch
+	// Parent is the key of the parent of the tipset, from which randomness is
+	// drawn.
+	Parent block.TipSetKey
+	// Blocks are the messages of the blocks of the tipset, in order.
+	Blocks []BlockMessages
+	// Randomness is the randomness drawn while applying the messages.
+	Randomness []RandomnessRecord
+	// PreStateRoot is the root of the state the messages are applied to.
+	PreStateRoot e.Cid
+	// PostStateRoot is the expected root of the state after the messages.
+	PostStateRoot e.Cid
+	// Receipts are the expected receipts of the messages.
+	Receipts []vm.MessageReceipt
+	// State holds the blocks of the pre-state read while applying the
+	// messages.
+	State []StateBlock
+}
+
+// BlockMessages are the messages of a block and its miner.
+type BlockMessages struct {
+	_            struct{} `cbor:",toarray"`
+	Miner        address.Address
+	BLSMessages  []*types.UnsignedMessage
+	SECPMessages []*types.SignedMessage
+}
+
+// RandomnessRecord is a randomness drawn by the VM.
+type RandomnessRecord struct {
+	_       struct{} `cbor:",toarray"`
+	Tag     crypto.DomainSeparationTag
+	Epoch   abi.ChainEpoch
+	Entropy []byte
+	Value   abi.Randomness
+}
+
+// StateBlock is a raw block of state.
+type StateBlock struct {
+	_    struct{} `cbor:",toarray"`
+	Cid  e.Cid
+	Data []byte
+}
+
+// Messages returns the number of messages of the vector.
+func (v *Vector) Messages() int {
+	n := 0
+	for _, blk := range v.Blocks {
+		n += len(blk.BLSMessages) + len(blk.SECPMessages)
+	}
+	return n
+}
+
+func (v *Vector) blockMessages() []vm.BlockMessagesInfo {
+	infos := make([]vm.BlockMessagesInfo, len(v.Blocks))
+	for i, blk := range v.Blocks {
+		// messages are normalized while applied, so the vector's are left as read
+		bls := make([]*types.UnsignedMessage, len(blk.BLSMessages))
+		for j, msg := range blk.BLSMessages {
+			m := *msg
+			bls[j] = &m
+		}
+		secp := make([]*types.SignedMessage, len(blk.SECPMessages))
+		for j, msg := range blk.SECPMessages {
+			m := *msg
+			secp[j] = &m
+		}
+		infos[i] = vm.BlockMessagesInfo{Miner: blk.Miner, BLSMessages: bls, SECPMessages: secp}
+	}
+	return infos
+}
+
+// WriteVector writes the CBOR encoding of `v` to `w`.
+func WriteVector(w io.Writer, v *Vector) error {
+	raw, err := encoding.Encode(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(raw)
+	return err
+}
+
+// ReadVector reads a vector written by WriteVector.
+func ReadVector(r io.Reader) (*Vector, error) {
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var v Vector
+	if err := encoding.Decode(raw, &v); err != nil {
+		return nil, fmt.Errorf("invalid test vector: %s", err)
+	}
+	if v.Version != VectorVersion {
+		return nil, fmt.Errorf("unsupported test vector version %d", v.Version)
+	}
+	return &v, nil
+}
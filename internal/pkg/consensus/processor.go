@@ -2,13 +2,17 @@ package consensus
 
 import (
 	"context"
+	"sync"
 
 	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/power"
 	"github.com/filecoin-project/specs-actors/actors/crypto"
 	"go.opencensus.io/trace"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/metrics/tracing"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/state"
 )
@@ -32,11 +36,32 @@ type ChainRandomness interface {
 	SampleChainRandomness(ctx context.Context, head block.TipSetKey, tag crypto.DomainSeparationTag, epoch abi.ChainEpoch, entropy []byte) (abi.Randomness, error)
 }
 
+// NetworkParams are the network parameters in effect at an epoch.
+type NetworkParams struct {
+	ConsensusMinerMinPower abi.StoragePower
+	SupportedProofTypes    map[abi.RegisteredProof]struct{}
+	// GasPricelist prices the operations of the VM.
+	GasPricelist vm.GasPricelist
+}
+
+// ParameterSchedule looks up the network parameters in effect at an epoch.
+type ParameterSchedule interface {
+	ParamsAt(epoch abi.ChainEpoch) NetworkParams
+}
+
+// actorParamsLk serializes the applications of messages by all processors, as
+// the actors read some network parameters from their package variables.
+var actorParamsLk sync.Mutex
+
 // DefaultProcessor handles all block processing.
 type DefaultProcessor struct {
 	actors   vm.ActorCodeLoader
 	syscalls vm.SyscallsImpl
 	rnd      ChainRandomness
+	schedule ParameterSchedule
+	// messageWorkers is the number of goroutines applying the messages of a
+	// block, they are applied serially when not greater than 1.
+	messageWorkers int
 }
 
 var _ Processor = (*DefaultProcessor)(nil)
@@ -55,6 +80,19 @@ func NewConfiguredProcessor(actors vm.ActorCodeLoader, syscalls vm.SyscallsImpl,
 	}
 }
 
+// SetParameterSchedule configures the processor to apply messages with the
+// network parameters in effect at their epoch. Without a schedule the
+// parameters are those set when the node was built.
+func (p *DefaultProcessor) SetParameterSchedule(schedule ParameterSchedule) {
+	p.schedule = schedule
+}
+
+// SetMessageWorkers configures the processor to apply the messages of a block
+// from independent senders on up to n goroutines.
+func (p *DefaultProcessor) SetMessageWorkers(n int) {
+	p.messageWorkers = n
+}
+
 // ProcessTipSet computes the state transition specified by the messages in all blocks in a TipSet.
 func (p *DefaultProcessor) ProcessTipSet(ctx context.Context, st state.Tree, vms vm.Storage, ts block.TipSet, msgs []vm.BlockMessagesInfo) (results []vm.MessageReceipt, err error) {
 	ctx, span := trace.StartSpan(ctx, "DefaultProcessor.ProcessTipSet")
@@ -77,11 +115,56 @@ func (p *DefaultProcessor) ProcessTipSet(ctx context.Context, st state.Tree, vms
 		chain: p.rnd,
 		head:  parent,
 	}
-	v := vm.NewVM(st, &vms, p.syscalls)
+	prices, done := p.useParamsAt(epoch)
+	defer done()
+	v := vm.NewParallelVM(p.actors, prices, st, &vms, p.syscalls, p.messageWorkers)
 
 	return v.ApplyTipSetMessages(msgs, parent, epoch, &rnd)
 }
 
+// PreviewMessages applies messages in order to the state st after the tipset
+// ts, as if they were included in the next block, and returns their receipts.
+// st and vms are modified and must be discarded.
+func (p *DefaultProcessor) PreviewMessages(ctx context.Context, st state.Tree, vms vm.Storage, ts block.TipSet, msgs []*types.SignedMessage) ([]vm.MessageReceipt, error) {
+	height, err := ts.Height()
+	if err != nil {
+		return nil, err
+	}
+	epoch := height + 1
+	rnd := headRandomness{
+		chain: p.rnd,
+		head:  ts.Key(),
+	}
+	prices, done := p.useParamsAt(epoch)
+	defer done()
+	return vm.PreviewMessages(p.actors, prices, st, &vms, p.syscalls, msgs, ts.Key(), epoch, &rnd)
+}
+
+// useParamsAt sets the package variables of the actors to the network
+// parameters in effect at the epoch until done is called, and returns the gas
+// prices of the schedule, nil for the default gas schedules. Other
+// applications of messages wait until done is called. The variables are
+// restored by done, so that they hold the parameters the node was built with
+// whenever no messages are applied.
+func (p *DefaultProcessor) useParamsAt(epoch abi.ChainEpoch) (prices vm.GasPrices, done func()) {
+	actorParamsLk.Lock()
+	if p.schedule == nil {
+		return nil, actorParamsLk.Unlock
+	}
+
+	params := p.schedule.ParamsAt(epoch)
+	// Switch references rather than mutate in place to avoid concurrent map mutation.
+	minPower, proofTypes := power.ConsensusMinerMinPower, miner.SupportedProofTypes
+	power.ConsensusMinerMinPower, miner.SupportedProofTypes = params.ConsensusMinerMinPower, params.SupportedProofTypes
+	prices = func(e abi.ChainEpoch) vm.GasPricelist {
+		return p.schedule.ParamsAt(e).GasPricelist
+	}
+	return prices, func() {
+		power.ConsensusMinerMinPower, miner.SupportedProofTypes = minPower, proofTypes
+		actorParamsLk.Unlock()
+	}
+}
+
 // A chain randomness source with a fixed head tipset key.
 type headRandomness struct {
 	chain ChainRandomness
@@ -0,0 +1,324 @@
+package consensus_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/actors/runtime/exitcode"
+	"github.com/ipfs/go-cid"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/cborutil"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/constants"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
+	appstate "github.com/filecoin-project/go-filecoin/internal/pkg/state"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/actor/builtin/kv"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/state"
+	gengen "github.com/filecoin-project/go-filecoin/tools/gengen/util"
+)
+
+func TestProcessTipSetParallel(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	bs, genesis, accounts := requireProcessorGenesis(ctx, t, 6)
+
+	sendFIL := func(from, to address.Address, nonce uint64, fil uint64) *types.UnsignedMessage {
+		return types.NewMeteredMessage(from, to, nonce, types.NewAttoFILFromFIL(fil), builtin.MethodSend, nil, types.NewGasPrice(1), gas.NewGas(10000))
+	}
+	send := func(from, to address.Address, nonce uint64) *types.UnsignedMessage {
+		return sendFIL(from, to, nonce, 1)
+	}
+
+	t.Run("independent senders", func(t *testing.T) {
+		msgs := []*types.UnsignedMessage{
+			send(accounts[0], accounts[3], 0),
+			send(accounts[1], accounts[4], 0),
+			send(accounts[0], accounts[3], 1),
+			send(accounts[2], accounts[5], 0),
+			// fails for a bad nonce, still paid for
+			send(accounts[1], accounts[4], 7),
+		}
+		parallel, serial := vm.ParallelApplications()
+		requireSameProcessing(ctx, t, bs, genesis, msgs)
+
+		t.Log("the messages were applied in parallel")
+		afterParallel, afterSerial := vm.ParallelApplications()
+		assert.Equal(t, parallel+1, afterParallel)
+		assert.Equal(t, serial, afterSerial)
+	})
+
+	t.Run("conflicting senders fall back to serial", func(t *testing.T) {
+		// each sender pays the next, accounts[1] spending more than its
+		// genesis balance of 1000000 FIL, as received earlier in the block
+		msgs := []*types.UnsignedMessage{
+			sendFIL(accounts[0], accounts[1], 0, 500000),
+			sendFIL(accounts[1], accounts[2], 0, 1200000),
+			send(accounts[2], accounts[0], 0),
+		}
+		parallel, serial := vm.ParallelApplications()
+		receipts := requireSameProcessing(ctx, t, bs, genesis, msgs)
+
+		t.Log("the messages were applied serially, in block order")
+		afterParallel, afterSerial := vm.ParallelApplications()
+		assert.Equal(t, parallel, afterParallel)
+		assert.Equal(t, serial+1, afterSerial)
+		for _, receipt := range receipts {
+			assert.True(t, receipt.ExitCode.IsSuccess())
+		}
+	})
+}
+
+func TestProcessTipSetDevnetActors(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	bs, genesis, accounts := requireProcessorGenesis(ctx, t, 1)
+	ts := block.RequireNewTipSet(t, &block.Block{
+		Miner:     genesis.Miner,
+		Height:    genesis.Height + 1,
+		Parents:   block.NewTipSetKey(genesis.Cid()),
+		StateRoot: genesis.StateRoot,
+		Timestamp: genesis.Timestamp + 1,
+	})
+
+	ctorParams, err := encoding.Encode(&kv.ConstructorParams{Owner: accounts[0], Writers: []address.Address{}})
+	require.NoError(t, err)
+	execParams, err := encoding.Encode(&init_.ExecParams{CodeCID: kv.CodeID, ConstructorParams: ctorParams})
+	require.NoError(t, err)
+
+	deploy := func(actors vm.ActorCodeLoader) (cid.Cid, vm.MessageReceipt) {
+		msg := types.NewMeteredMessage(accounts[0], builtin.InitActorAddr, 0, types.ZeroAttoFIL, builtin.MethodsInit.Exec, execParams, types.NewGasPrice(1), gas.NewGas(1000000))
+		blkMsgs := []vm.BlockMessagesInfo{{Miner: genesis.Miner, BLSMessages: []*types.UnsignedMessage{msg}}}
+
+		st, err := state.LoadState(ctx, cborutil.NewIpldStore(bs), genesis.StateRoot.Cid)
+		require.NoError(t, err)
+		processor := consensus.NewConfiguredProcessor(actors, &vm.FakeSyscalls{}, &consensus.FakeChainRandomness{})
+		receipts, err := processor.ProcessTipSet(ctx, st, vm.NewStorage(bs), ts, blkMsgs)
+		require.NoError(t, err)
+		require.Len(t, receipts, 1)
+		root, err := st.Commit(ctx)
+		require.NoError(t, err)
+		return root, receipts[0]
+	}
+
+	t.Log("the init actor of the specs does not exec devnet actors")
+	_, receipt := deploy(vm.DefaultActors)
+	assert.Equal(t, exitcode.ErrForbidden, receipt.ExitCode)
+
+	t.Log("networks enabling devnet actors exec them")
+	root, receipt := deploy(vm.DevnetActors)
+	require.Equal(t, exitcode.Ok, receipt.ExitCode)
+	var ret init_.ExecReturn
+	require.NoError(t, ret.UnmarshalCBOR(bytes.NewReader(receipt.ReturnValue)))
+	assert.Equal(t, address.ID, ret.IDAddress.Protocol())
+	assert.Equal(t, address.Actor, ret.RobustAddress.Protocol())
+
+	kvState, err := appstate.NewView(cborutil.NewIpldStore(bs), root).DevnetKVState(ctx, ret.IDAddress)
+	require.NoError(t, err)
+	assert.Equal(t, address.ID, kvState.Owner.Protocol())
+	assert.Empty(t, kvState.Entries)
+}
+
+// upgradeSchedule switches network parameters at an epoch.
+type upgradeSchedule struct {
+	at            abi.ChainEpoch
+	before, after consensus.NetworkParams
+}
+
+func (s *upgradeSchedule) ParamsAt(epoch abi.ChainEpoch) consensus.NetworkParams {
+	if epoch < s.at {
+		return s.before
+	}
+	return s.after
+}
+
+func TestProcessTipSetParameterSchedule(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	bs, genesis, accounts := requireProcessorGenesis(ctx, t, 2)
+	minPower, proofTypes := power.ConsensusMinerMinPower, miner.SupportedProofTypes
+
+	costly := vm.DefaultGasSchedules[0]
+	costly.SendBase = gas.NewGas(500)
+	schedule := &upgradeSchedule{
+		at: genesis.Height + 5,
+		before: consensus.NetworkParams{
+			ConsensusMinerMinPower: minPower,
+			SupportedProofTypes:    proofTypes,
+			GasPricelist:           vm.NewGasPricelist(vm.DefaultGasSchedules[0]),
+		},
+		after: consensus.NetworkParams{
+			ConsensusMinerMinPower: big.NewInt(1),
+			SupportedProofTypes:    proofTypes,
+			GasPricelist:           vm.NewGasPricelist(costly),
+		},
+	}
+
+	process := func(processor *consensus.DefaultProcessor, height abi.ChainEpoch) vm.MessageReceipt {
+		ts := block.RequireNewTipSet(t, &block.Block{
+			Miner:     genesis.Miner,
+			Height:    height,
+			Parents:   block.NewTipSetKey(genesis.Cid()),
+			StateRoot: genesis.StateRoot,
+			Timestamp: genesis.Timestamp + uint64(height),
+		})
+		msg := types.NewMeteredMessage(accounts[0], accounts[1], 0, types.NewAttoFILFromFIL(1), builtin.MethodSend, nil, types.NewGasPrice(1), gas.NewGas(10000))
+		blkMsgs := []vm.BlockMessagesInfo{{Miner: genesis.Miner, BLSMessages: []*types.UnsignedMessage{msg}}}
+
+		st, err := state.LoadState(ctx, cborutil.NewIpldStore(bs), genesis.StateRoot.Cid)
+		require.NoError(t, err)
+		receipts, err := processor.ProcessTipSet(ctx, st, vm.NewStorage(bs), ts, blkMsgs)
+		require.NoError(t, err)
+		require.Len(t, receipts, 1)
+		require.True(t, receipts[0].ExitCode.IsSuccess())
+		return receipts[0]
+	}
+
+	processor := consensus.NewDefaultProcessor(&vm.FakeSyscalls{}, &consensus.FakeChainRandomness{})
+	processor.SetParameterSchedule(schedule)
+
+	t.Log("messages are priced by the gas schedule of their epoch, whatever was processed before")
+	late := process(processor, genesis.Height+10)
+	early := process(processor, genesis.Height+1)
+	assert.Equal(t, gas.NewGas(495), late.GasUsed-early.GasUsed)
+	assert.Equal(t, early, process(consensus.NewDefaultProcessor(&vm.FakeSyscalls{}, &consensus.FakeChainRandomness{}), genesis.Height+1))
+
+	t.Log("the parameters of the actors are restored once the messages are applied")
+	assert.Equal(t, minPower, power.ConsensusMinerMinPower)
+	assert.Equal(t, proofTypes, miner.SupportedProofTypes)
+}
+
+func TestPreviewMessages(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	bs, genesis, accounts := requireProcessorGenesis(ctx, t, 2)
+	genTs := block.RequireNewTipSet(t, genesis)
+	ts := block.RequireNewTipSet(t, &block.Block{
+		Miner:     genesis.Miner,
+		Height:    genesis.Height + 1,
+		Parents:   genTs.Key(),
+		StateRoot: genesis.StateRoot,
+		Timestamp: genesis.Timestamp + 1,
+	})
+
+	var msgs []*types.SignedMessage
+	blkMsgs := []vm.BlockMessagesInfo{{Miner: genesis.Miner}}
+	// the last message fails for a bad nonce
+	for _, nonce := range []uint64{0, 1, 5} {
+		smsg := &types.SignedMessage{Message: *types.NewMeteredMessage(accounts[0], accounts[1], nonce, types.NewAttoFILFromFIL(1), builtin.MethodSend, []byte{}, types.NewGasPrice(1), gas.NewGas(10000))}
+		msgs = append(msgs, smsg)
+		if accounts[0].Protocol() == address.BLS {
+			msg := smsg.Message
+			blkMsgs[0].BLSMessages = append(blkMsgs[0].BLSMessages, &msg)
+		} else {
+			blkMsgs[0].SECPMessages = append(blkMsgs[0].SECPMessages, smsg)
+		}
+	}
+
+	processor := consensus.NewDefaultProcessor(&vm.FakeSyscalls{}, &consensus.FakeChainRandomness{})
+
+	st, err := state.LoadState(ctx, cborutil.NewIpldStore(bs), genesis.StateRoot.Cid)
+	require.NoError(t, err)
+	previewed, err := processor.PreviewMessages(ctx, st, vm.NewStorage(bs), genTs, msgs)
+	require.NoError(t, err)
+	require.Len(t, previewed, 3)
+	assert.True(t, previewed[0].ExitCode.IsSuccess())
+	assert.True(t, previewed[2].ExitCode.IsError())
+
+	st, err = state.LoadState(ctx, cborutil.NewIpldStore(bs), genesis.StateRoot.Cid)
+	require.NoError(t, err)
+	applied, err := processor.ProcessTipSet(ctx, st, vm.NewStorage(bs), ts, blkMsgs)
+	require.NoError(t, err)
+	assert.Equal(t, applied, previewed)
+}
+
+// requireSameProcessing applies the messages serially and in parallel from
+// the genesis state, and checks that both produce the same receipts and state.
+// requireSameProcessing processes the messages serially and with message
+// workers, requires the same receipts and state, and returns the receipts.
+func requireSameProcessing(ctx context.Context, t *testing.T, bs bstore.Blockstore, genesis *block.Block, msgs []*types.UnsignedMessage) []vm.MessageReceipt {
+	ts := block.RequireNewTipSet(t, &block.Block{
+		Miner:     genesis.Miner,
+		Height:    genesis.Height + 1,
+		Parents:   block.NewTipSetKey(genesis.Cid()),
+		StateRoot: genesis.StateRoot,
+		Timestamp: genesis.Timestamp + 1,
+	})
+
+	process := func(workers int) (cid.Cid, []vm.MessageReceipt) {
+		// messages are normalized while applied
+		blkMsgs := []vm.BlockMessagesInfo{{Miner: genesis.Miner}}
+		for _, m := range msgs {
+			msg := *m
+			blkMsgs[0].BLSMessages = append(blkMsgs[0].BLSMessages, &msg)
+		}
+
+		st, err := state.LoadState(ctx, cborutil.NewIpldStore(bs), genesis.StateRoot.Cid)
+		require.NoError(t, err)
+		processor := consensus.NewDefaultProcessor(&vm.FakeSyscalls{}, &consensus.FakeChainRandomness{})
+		processor.SetMessageWorkers(workers)
+		receipts, err := processor.ProcessTipSet(ctx, st, vm.NewStorage(bs), ts, blkMsgs)
+		require.NoError(t, err)
+		root, err := st.Commit(ctx)
+		require.NoError(t, err)
+		return root, receipts
+	}
+
+	serialRoot, serialReceipts := process(1)
+	parallelRoot, parallelReceipts := process(4)
+	assert.Equal(t, serialReceipts, parallelReceipts)
+	assert.Equal(t, serialRoot, parallelRoot)
+	assert.NotEqual(t, genesis.StateRoot.Cid, parallelRoot)
+	return parallelReceipts
+}
+
+func requireProcessorGenesis(ctx context.Context, t *testing.T, numAccounts int) (bstore.Blockstore, *block.Block, []address.Address) {
+	r := repo.NewInMemoryRepo()
+	bs := bstore.NewBlockstore(r.Datastore())
+	cst := cborutil.NewIpldStore(bs)
+
+	commCfgs, err := gengen.MakeCommitCfgs(1)
+	require.NoError(t, err)
+	genCfg := &gengen.GenesisCfg{}
+	require.NoError(t, gengen.NetworkName("processortest")(genCfg))
+	require.NoError(t, gengen.GenKeys(numAccounts+1, "1000000")(genCfg))
+	require.NoError(t, gengen.MinerConfigs([]*gengen.CreateStorageMinerConfig{{
+		Owner:            numAccounts,
+		CommittedSectors: commCfgs,
+		SealProofType:    constants.DevSealProofType,
+	}})(genCfg))
+
+	info, err := gengen.GenGen(ctx, genCfg, bs)
+	require.NoError(t, err)
+
+	var genesis block.Block
+	require.NoError(t, cst.Get(ctx, info.GenesisCid, &genesis))
+	genesis.Miner = info.Miners[0].Address
+
+	accounts := make([]address.Address, numAccounts)
+	for i := range accounts {
+		accounts[i], err = info.Keys[i].Address()
+		require.NoError(t, err)
+	}
+	return bs, &genesis, accounts
+}
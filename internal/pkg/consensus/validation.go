@@ -12,6 +12,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/errcode"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/metrics"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
@@ -95,7 +96,7 @@ func (v *MessagePenaltyChecker) PenaltyCheck(ctx context.Context, msg *types.Uns
 	// Avoid processing messages for actors that cannot pay.
 	if !canCoverGasLimit(msg, fromActor) {
 		dropInsufficientGasCt.Inc(ctx, 1)
-		return fmt.Errorf("insufficient funds from sender %s to cover value and gas cost: %s ", msg.From, msg)
+		return errcode.Errorf(errcode.InsufficientFunds, "insufficient funds from sender %s to cover value and gas cost: %s ", msg.From, msg)
 	}
 
 	if msg.CallSeqNum < fromActor.CallSeqNum {
@@ -202,7 +203,7 @@ func (v *DefaultMessageSyntaxValidator) validateMessageSyntaxShared(ctx context.
 	minMsgGas := onChainMessageBase + onChainMessagePerByte*gas.Unit(msgLen)
 	if msg.GasLimit < minMsgGas {
 		invGasBelowMinimumCt.Inc(ctx, 1)
-		return fmt.Errorf("gas limit %d below minimum %d to cover message size: %s", msg.GasLimit, minMsgGas, msg)
+		return errcode.Errorf(errcode.GasTooLow, "gas limit %d below minimum %d to cover message size: %s", msg.GasLimit, minMsgGas, msg)
 	}
 	if msg.GasLimit > types.BlockGasLimit {
 		invGasAboveBlockLimitCt.Inc(ctx, 1)
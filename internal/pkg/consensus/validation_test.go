@@ -14,6 +14,7 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
 	e "github.com/filecoin-project/go-filecoin/internal/pkg/enccid"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/errcode"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
@@ -70,7 +71,9 @@ func TestMessagePenaltyChecker(t *testing.T) {
 		assert.Errorf(t, checker.PenaltyCheck(ctx, msg), "funds")
 
 		msg = newMessage(t, alice, bob, 100, 5, 100000, 200) // lots of expensive gas
-		assert.Errorf(t, checker.PenaltyCheck(ctx, msg), "funds")
+		err := checker.PenaltyCheck(ctx, msg)
+		assert.Errorf(t, err, "funds")
+		assert.Equal(t, errcode.InsufficientFunds, errcode.CodeOf(err))
 	})
 
 	t.Run("low nonce", func(t *testing.T) {
@@ -85,3 +85,27 @@ func TestBLSSigning(t *testing.T) {
 	require.False(t, valid)
 
 }
+
+func TestDataSignatureDomainSeparation(t *testing.T) {
+	tf.UnitTest(t)
+
+	ki, err := crypto.NewSecpKeyFromSeed(bytes.NewReader(bytes.Repeat([]byte{7}, 512)))
+	require.NoError(t, err)
+	addr, err := ki.Address()
+	require.NoError(t, err)
+
+	data := []byte("login to example.com")
+	payload, err := crypto.DataSigningPayload("example.com", data)
+	require.NoError(t, err)
+	sig, err := crypto.Sign(payload, ki.Key(), crypto.SigTypeSecp256k1)
+	require.NoError(t, err)
+
+	assert.NoError(t, crypto.ValidateDataSignature("example.com", data, addr, sig))
+	assert.Error(t, crypto.ValidateDataSignature("example.org", data, addr, sig))
+	assert.Error(t, crypto.ValidateDataSignature("example.com", []byte("other"), addr, sig))
+	// not a signature of the data itself
+	assert.Error(t, crypto.ValidateSignature(data, addr, sig))
+
+	_, err = crypto.DataSigningPayload("", data)
+	assert.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"encoding/binary"
 	"fmt"
 
 	"github.com/filecoin-project/go-address"
@@ -84,3 +85,37 @@ func ValidateBlsSignature(data []byte, addr address.Address, signature []byte) e
 	}
 	return nil
 }
+
+//
+// Domain-separated data signatures
+//
+
+// signedDataPrefix starts the payloads of data signatures. No message nor
+// block signing payload, which are CIDs, starts with 0x19.
+const signedDataPrefix = "\x19Filecoin Signed Data:\n"
+
+// DataSigningPayload returns the bytes signed to sign `data` for `domain`:
+// the signed data prefix, the length-prefixed domain and the blake2b-256 hash
+// of the data. Signatures of data can be replayed neither as message or block
+// signatures, nor as signatures for another domain.
+func DataSigningPayload(domain string, data []byte) ([]byte, error) {
+	if domain == "" {
+		return nil, fmt.Errorf("data signing domain must not be empty")
+	}
+	payload := []byte(signedDataPrefix)
+	length := make([]byte, binary.MaxVarintLen64)
+	payload = append(payload, length[:binary.PutUvarint(length, uint64(len(domain)))]...)
+	payload = append(payload, domain...)
+	hash := blake2b.Sum256(data)
+	return append(payload, hash[:]...), nil
+}
+
+// ValidateDataSignature verifies that `sig` is a signature of `data` for
+// `domain` with the key of `addr`.
+func ValidateDataSignature(domain string, data []byte, addr address.Address, sig Signature) error {
+	payload, err := DataSigningPayload(domain, data)
+	if err != nil {
+		return err
+	}
+	return ValidateSignature(payload, addr, sig)
+}
@@ -2,8 +2,11 @@ package discovery
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
 	"time"
 
 	"github.com/filecoin-project/specs-actors/actors/abi"
@@ -20,12 +23,13 @@ import (
 	e "github.com/filecoin-project/go-filecoin/internal/pkg/enccid"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/metrics"
+	fnet "github.com/filecoin-project/go-filecoin/internal/pkg/net"
 )
 
 var log = logging.Logger("/fil/hello")
 
-// helloProtocolID is the libp2p protocol identifier for the hello protocol.
-const helloProtocolID = "/fil/hello/1.0.0"
+// HelloProtocolID is the libp2p protocol identifier for the hello protocol.
+const HelloProtocolID = "/fil/hello/1.0.0"
 
 var genesisErrCt = metrics.NewInt64Counter("hello_genesis_error", "Number of errors encountered in hello protocol due to incorrect genesis block")
 var helloMsgErrCt = metrics.NewInt64Counter("hello_message_error", "Number of errors encountered in hello protocol due to malformed message")
@@ -65,6 +69,9 @@ type HelloProtocolHandler struct {
 	// for filling out our hello messages.
 	getHeaviestTipSet getTipSetFunc
 
+	// penalize records the offenses of peers breaking the protocol
+	penalize penalizeFunc
+
 	networkName string
 }
 
@@ -72,12 +79,16 @@ type peerDiscoveredCallback func(ci *block.ChainInfo)
 
 type getTipSetFunc func() (block.TipSet, error)
 
+type penalizeFunc func(p peer.ID, offense fnet.Offense) bool
+
 // NewHelloProtocolHandler creates a new instance of the hello protocol `Handler` and registers it to
-// the given `host.Host`.
-func NewHelloProtocolHandler(h host.Host, gen cid.Cid, networkName string) *HelloProtocolHandler {
+// the given `host.Host`. Peers sending malformed messages or a different genesis block, or not
+// answering in time, are penalized with `penalize`.
+func NewHelloProtocolHandler(h host.Host, gen cid.Cid, networkName string, penalize penalizeFunc) *HelloProtocolHandler {
 	return &HelloProtocolHandler{
 		host:        h,
 		genesis:     gen,
+		penalize:    penalize,
 		networkName: networkName,
 	}
 }
@@ -89,7 +100,7 @@ func (h *HelloProtocolHandler) Register(peerDiscoveredCallback peerDiscoveredCal
 	h.getHeaviestTipSet = getHeaviestTipSet
 
 	// register a handle for when a new connection against someone is created
-	h.host.SetStreamHandler(helloProtocolID, h.handleNewStream)
+	h.host.SetStreamHandler(HelloProtocolID, h.handleNewStream)
 
 	// register for connection notifications
 	h.host.Network().Notify((*helloProtocolNotifiee)(h))
@@ -104,7 +115,12 @@ func (h *HelloProtocolHandler) handleNewStream(s net.Stream) {
 		log.Debugf("failed to receive hello message:%s", err)
 		// can't process a hello received in error, but leave this connection
 		// open because we connections are innocent until proven guilty
-		// (with bad genesis)
+		// (with bad genesis), the peer is banned on repeated malformed
+		// or cut short messages, streams failing before any bytes are not its
+		// offense
+		if errors.Is(err, errMalformedHello) {
+			h.penalize(s.Conn().RemotePeer(), fnet.OffenseProtocolViolation)
+		}
 		return
 	}
 	latencyMsg := &LatencyMessage{TArrival: time.Now().UnixNano()}
@@ -121,6 +137,7 @@ func (h *HelloProtocolHandler) handleNewStream(s net.Stream) {
 	case err == ErrBadGenesis:
 		log.Debugf("peer genesis cid: %s does not match ours: %s, disconnecting from peer: %s", &hello.GenesisHash, h.genesis, from)
 		genesisErrCt.Inc(context.Background(), 1)
+		h.penalize(from, fnet.OffenseProtocolViolation)
 		_ = s.Conn().Close()
 		return
 	default:
@@ -141,6 +158,10 @@ func (h *HelloProtocolHandler) handleNewStream(s net.Stream) {
 // ErrBadGenesis is the error returned when a mismatch in genesis blocks happens.
 var ErrBadGenesis = fmt.Errorf("bad genesis block")
 
+// errMalformedHello is wrapped by the errors decoding hello messages, as
+// opposed to those reading them from the stream.
+var errMalformedHello = fmt.Errorf("malformed hello message")
+
 func (h *HelloProtocolHandler) processHelloMessage(from peer.ID, msg *HelloMessage) (*block.ChainInfo, error) {
 	if !msg.GenesisHash.Equals(h.genesis) {
 		return nil, ErrBadGenesis
@@ -172,12 +193,33 @@ func (h *HelloProtocolHandler) getOurHelloMessage() (*HelloMessage, error) {
 	}, nil
 }
 
+// receiveHello reads a hello message from stream `s`. Errors reading a stream
+// that failed before sending any bytes are returned as is, any other error
+// wraps errMalformedHello: the bytes read, cut short or not, are no hello.
 func (h *HelloProtocolHandler) receiveHello(ctx context.Context, s net.Stream) (*HelloMessage, error) {
 	var hello HelloMessage
 	// Read cbor bytes from stream into hello message
-	mr := cborutil.NewMsgReader(s)
-	err := mr.ReadMsg(&hello)
-	return &hello, err
+	r := &countingReader{r: s}
+	mr := cborutil.NewMsgReader(r)
+	if err := mr.ReadMsg(&hello); err != nil {
+		if r.n == 0 {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %s", errMalformedHello, err)
+	}
+	return &hello, nil
+}
+
+// countingReader counts the bytes read from `r`.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += n
+	return n, err
 }
 
 func (h *HelloProtocolHandler) receiveLatency(ctx context.Context, s net.Stream) (*LatencyMessage, error) {
@@ -253,12 +295,15 @@ func (hn *helloProtocolNotifiee) Connected(n net.Network, c net.Conn) {
 		// add timeout
 		ctx, cancel := context.WithTimeout(context.Background(), helloTimeout)
 		defer cancel()
-		s, err := hn.asHandler().host.NewStream(ctx, c.RemotePeer(), helloProtocolID)
+		s, err := hn.asHandler().host.NewStream(ctx, c.RemotePeer(), HelloProtocolID)
 		if err != nil {
 			// If peer does not do hello keep connection open
 			return
 		}
 		defer func() { _ = s.Close() }()
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = s.SetDeadline(deadline)
+		}
 		// send out the hello message
 		err = hn.asHandler().sendHello(s)
 		if err != nil {
@@ -271,6 +316,9 @@ func (hn *helloProtocolNotifiee) Connected(n net.Network, c net.Conn) {
 		_, err = hn.asHandler().receiveLatency(ctx, s)
 		if err != nil {
 			log.Debugf("failed to receive hello latency msg from peer %s: %s", c.RemotePeer(), err)
+			if os.IsTimeout(err) || ctx.Err() != nil {
+				hn.asHandler().penalize(c.RemotePeer(), fnet.OffenseTimeout)
+			}
 			return
 		}
 
@@ -2,11 +2,13 @@ package discovery_test
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/libp2p/go-libp2p-core/peer"
 	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
 
 	"github.com/stretchr/testify/assert"
@@ -16,6 +18,8 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/discovery"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	fnet "github.com/filecoin-project/go-filecoin/internal/pkg/net"
 	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 )
@@ -36,6 +40,10 @@ func (mhg *mockHeaviestGetter) getHeaviestTipSet() (block.TipSet, error) {
 	return mhg.heaviest, nil
 }
 
+func ignoreOffense(peer.ID, fnet.Offense) bool {
+	return false
+}
+
 func TestHelloHandshake(t *testing.T) {
 	tf.UnitTest(t)
 
@@ -56,8 +64,8 @@ func TestHelloHandshake(t *testing.T) {
 	msc1, msc2 := new(mockHelloCallback), new(mockHelloCallback)
 	hg1, hg2 := &mockHeaviestGetter{heavy1}, &mockHeaviestGetter{heavy2}
 
-	discovery.NewHelloProtocolHandler(a, genesisA.Cid(), "").Register(msc1.HelloCallback, hg1.getHeaviestTipSet)
-	discovery.NewHelloProtocolHandler(b, genesisA.Cid(), "").Register(msc2.HelloCallback, hg2.getHeaviestTipSet)
+	discovery.NewHelloProtocolHandler(a, genesisA.Cid(), "", ignoreOffense).Register(msc1.HelloCallback, hg1.getHeaviestTipSet)
+	discovery.NewHelloProtocolHandler(b, genesisA.Cid(), "", ignoreOffense).Register(msc2.HelloCallback, hg2.getHeaviestTipSet)
 
 	msc1.On("HelloCallback", b.ID(), heavy2.Key(), abi.ChainEpoch(3)).Return()
 	msc2.On("HelloCallback", a.ID(), heavy1.Key(), abi.ChainEpoch(2)).Return()
@@ -112,8 +120,8 @@ func TestHelloBadGenesis(t *testing.T) {
 	msc1, msc2 := new(mockHelloCallback), new(mockHelloCallback)
 	hg1, hg2 := &mockHeaviestGetter{heavy1}, &mockHeaviestGetter{heavy2}
 
-	discovery.NewHelloProtocolHandler(a, genesisA.Cid(), "").Register(msc1.HelloCallback, hg1.getHeaviestTipSet)
-	discovery.NewHelloProtocolHandler(b, genesisB.Cid(), "").Register(msc2.HelloCallback, hg2.getHeaviestTipSet)
+	discovery.NewHelloProtocolHandler(a, genesisA.Cid(), "", ignoreOffense).Register(msc1.HelloCallback, hg1.getHeaviestTipSet)
+	discovery.NewHelloProtocolHandler(b, genesisB.Cid(), "", ignoreOffense).Register(msc2.HelloCallback, hg2.getHeaviestTipSet)
 
 	msc1.On("HelloCallback", mock.Anything, mock.Anything, mock.Anything).Return()
 	msc2.On("HelloCallback", mock.Anything, mock.Anything, mock.Anything).Return()
@@ -151,8 +159,8 @@ func TestHelloMultiBlock(t *testing.T) {
 	msc1, msc2 := new(mockHelloCallback), new(mockHelloCallback)
 	hg1, hg2 := &mockHeaviestGetter{heavy1}, &mockHeaviestGetter{heavy2}
 
-	discovery.NewHelloProtocolHandler(a, genesisTipset.At(0).Cid(), "").Register(msc1.HelloCallback, hg1.getHeaviestTipSet)
-	discovery.NewHelloProtocolHandler(b, genesisTipset.At(0).Cid(), "").Register(msc2.HelloCallback, hg2.getHeaviestTipSet)
+	discovery.NewHelloProtocolHandler(a, genesisTipset.At(0).Cid(), "", ignoreOffense).Register(msc1.HelloCallback, hg1.getHeaviestTipSet)
+	discovery.NewHelloProtocolHandler(b, genesisTipset.At(0).Cid(), "", ignoreOffense).Register(msc2.HelloCallback, hg2.getHeaviestTipSet)
 
 	msc1.On("HelloCallback", b.ID(), heavy2.Key(), abi.ChainEpoch(3)).Return()
 	msc2.On("HelloCallback", a.ID(), heavy1.Key(), abi.ChainEpoch(2)).Return()
@@ -165,3 +173,79 @@ func TestHelloMultiBlock(t *testing.T) {
 	msc1.AssertExpectations(t)
 	msc2.AssertExpectations(t)
 }
+
+func TestHelloPenalizesMalformedMessages(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn, err := mocknet.WithNPeers(ctx, 2)
+	require.NoError(t, err)
+
+	a := mn.Hosts()[0]
+	b := mn.Hosts()[1]
+
+	genesis := &block.Block{}
+	heavy := block.RequireNewTipSet(t, &block.Block{Height: 2, Ticket: block.Ticket{VRFProof: []byte{0}}})
+
+	var lk sync.Mutex
+	var offenses []fnet.Offense
+	penalize := func(p peer.ID, offense fnet.Offense) bool {
+		lk.Lock()
+		defer lk.Unlock()
+		assert.Equal(t, a.ID(), p)
+		offenses = append(offenses, offense)
+		return false
+	}
+	penalized := func() []fnet.Offense {
+		lk.Lock()
+		defer lk.Unlock()
+		return append([]fnet.Offense{}, offenses...)
+	}
+
+	msc := new(mockHelloCallback)
+	hg := &mockHeaviestGetter{heavy}
+	discovery.NewHelloProtocolHandler(b, genesis.Cid(), "", penalize).Register(msc.HelloCallback, hg.getHeaviestTipSet)
+
+	require.NoError(t, mn.LinkAll())
+	require.NoError(t, mn.ConnectAllButSelf())
+
+	t.Log("streams failing before sending any bytes are not penalized")
+	s, err := a.NewStream(ctx, b.ID(), discovery.HelloProtocolID)
+	require.NoError(t, err)
+	require.NoError(t, s.Reset())
+
+	s, err = a.NewStream(ctx, b.ID(), discovery.HelloProtocolID)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	time.Sleep(time.Millisecond * 50)
+	assert.Empty(t, penalized())
+
+	t.Log("messages cut short are penalized")
+	s, err = a.NewStream(ctx, b.ID(), discovery.HelloProtocolID)
+	require.NoError(t, err)
+	_, err = s.Write([]byte{0x84}) // the header of an array missing its elements
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	require.NoError(t, th.WaitForIt(10, 50*time.Millisecond, func() (bool, error) {
+		return len(penalized()) > 0, nil
+	}))
+	assert.Equal(t, []fnet.Offense{fnet.OffenseProtocolViolation}, penalized())
+
+	t.Log("and malformed messages")
+	s, err = a.NewStream(ctx, b.ID(), discovery.HelloProtocolID)
+	require.NoError(t, err)
+	msg, err := encoding.Encode("not a hello message")
+	require.NoError(t, err)
+	_, err = s.Write(msg)
+	require.NoError(t, err)
+
+	require.NoError(t, th.WaitForIt(10, 50*time.Millisecond, func() (bool, error) {
+		return len(penalized()) > 1, nil
+	}))
+	assert.Equal(t, []fnet.Offense{fnet.OffenseProtocolViolation, fnet.OffenseProtocolViolation}, penalized())
+	_ = s.Close()
+}
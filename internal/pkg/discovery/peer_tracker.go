@@ -17,7 +17,7 @@ var logPeerTracker = logging.Logger("peer-tracker")
 // It is designed to plug directly into libp2p disconnect notifications to
 // automatically register dropped connections.
 type PeerTracker struct {
-	// mu protects peers
+	// mu protects peers, trusted and trustedOnly
 	mu sync.RWMutex
 
 	// self tracks the ID of the peer tracker's owner
@@ -26,6 +26,9 @@ type PeerTracker struct {
 	// peers maps peer.IDs to info about their chains
 	peers   map[peer.ID]*block.ChainInfo
 	trusted map[peer.ID]struct{}
+
+	// trustedOnly is true when chain heads should only be accepted from trusted peers
+	trustedOnly bool
 }
 
 // NewPeerTracker creates a peer tracker.
@@ -112,16 +115,62 @@ func (tracker *PeerTracker) RegisterDisconnect(ntwk network.Network) {
 	ntwk.Notify(notifee)
 }
 
-// trustedPeers returns a slice of peers trusted by the PeerTracker. trustedPeers remain constant after
-// the PeerTracker has been initialized.
-func (tracker *PeerTracker) trustedPeers() []peer.ID {
+// Trust adds a peer to the set of peers trusted by the PeerTracker.
+func (tracker *PeerTracker) Trust(pid peer.ID) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.trusted[pid] = struct{}{}
+	logPeerTracker.Infow("Trust peer", "peer", pid.Pretty())
+}
+
+// Untrust removes a peer from the set of peers trusted by the PeerTracker.
+func (tracker *PeerTracker) Untrust(pid peer.ID) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	delete(tracker.trusted, pid)
+	logPeerTracker.Infow("Untrust peer", "peer", pid.Pretty())
+}
+
+// IsTrusted returns true if the peer is trusted by the PeerTracker.
+func (tracker *PeerTracker) IsTrusted(pid peer.ID) bool {
+	tracker.mu.RLock()
+	defer tracker.mu.RUnlock()
+
+	_, trusted := tracker.trusted[pid]
+	return trusted
+}
+
+// TrustedPeers returns the peers trusted by the PeerTracker, sorted by ID.
+func (tracker *PeerTracker) TrustedPeers() []peer.ID {
+	tracker.mu.RLock()
+	defer tracker.mu.RUnlock()
+
 	var peers []peer.ID
 	for p := range tracker.trusted {
 		peers = append(peers, p)
 	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i] < peers[j] })
 	return peers
 }
 
+// SetTrustedOnly controls whether chain heads are exclusively accepted from trusted peers.
+func (tracker *PeerTracker) SetTrustedOnly(only bool) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.trustedOnly = only
+}
+
+// TrustedOnly returns true if chain heads should only be accepted from trusted peers.
+func (tracker *PeerTracker) TrustedOnly() bool {
+	tracker.mu.RLock()
+	defer tracker.mu.RUnlock()
+
+	return tracker.trustedOnly
+}
+
 // listTrusted returns the chain info of the trusted tracked peers. The info tracked by the tracker can
 // change arbitrarily after this is called -- there is no guarantee that the peers returned will be
 // tracked when they are used by the caller and no guarantee that the chain info is up to date.
@@ -137,3 +137,37 @@ func TestPeerTrackerNetworkDisconnect(t *testing.T) {
 	tracked := tracker.List()
 	assert.Equal(t, []*block.ChainInfo{bCI}, tracked)
 }
+
+func TestPeerTrackerTrust(t *testing.T) {
+	tf.UnitTest(t)
+
+	pid0 := th.RequireIntPeerID(t, 0)
+	pid1 := th.RequireIntPeerID(t, 1)
+
+	ci0 := block.NewChainInfo(pid0, pid0, block.NewTipSetKey(types.CidFromString(t, "somecid0")), 6)
+	ci1 := block.NewChainInfo(pid1, pid1, block.NewTipSetKey(types.CidFromString(t, "somecid1")), 10)
+
+	tracker := discovery.NewPeerTracker(peer.ID(""))
+	tracker.Track(ci0)
+	tracker.Track(ci1)
+
+	_, err := tracker.SelectHead()
+	assert.Error(t, err)
+
+	tracker.Trust(pid0)
+	assert.True(t, tracker.IsTrusted(pid0))
+	assert.False(t, tracker.IsTrusted(pid1))
+	assert.Equal(t, []peer.ID{pid0}, tracker.TrustedPeers())
+
+	head, err := tracker.SelectHead()
+	require.NoError(t, err)
+	assert.Equal(t, ci0.Head, head.Head)
+
+	tracker.Untrust(pid0)
+	assert.False(t, tracker.IsTrusted(pid0))
+	assert.Empty(t, tracker.TrustedPeers())
+
+	assert.False(t, tracker.TrustedOnly())
+	tracker.SetTrustedOnly(true)
+	assert.True(t, tracker.TrustedOnly())
+}
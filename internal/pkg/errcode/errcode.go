@@ -0,0 +1,110 @@
+// Package errcode classifies errors with stable, machine-readable codes. Errors
+// are tagged with a code where they originate and keep it through wrapping, so
+// that the command API can report the code and tools can branch on the class
+// of a failure instead of matching error messages.
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Code is the class of a failure. Codes are part of the API, they must not be
+// renamed.
+type Code string
+
+const (
+	// Unknown is the code of errors that are not tagged.
+	Unknown = Code("")
+	// InsufficientFunds is a balance too low to pay for a message, deal or
+	// voucher.
+	InsufficientFunds = Code("insufficient-funds")
+	// UnknownDeal is a deal that is not tracked by the node.
+	UnknownDeal = Code("unknown-deal")
+	// MinerOffline is a miner that could not be reached.
+	MinerOffline = Code("miner-offline")
+	// GasTooLow is a gas limit below the minimum cost of a message.
+	GasTooLow = Code("gas-too-low")
+)
+
+// codes are the known codes, to parse them.
+var codes = map[Code]struct{}{
+	InsufficientFunds: {},
+	UnknownDeal:       {},
+	MinerOffline:      {},
+	GasTooLow:         {},
+}
+
+// Error is an error tagged with a code. Its message is that of the error it
+// tags, the code is reported separately.
+type Error struct {
+	Code Code
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the tagged error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the tagged error, for github.com/pkg/errors.
+func (e *Error) Cause() error {
+	return e.Err
+}
+
+// New returns an error with message `msg` tagged with `code`.
+func New(code Code, msg string) error {
+	return &Error{Code: code, Err: errors.New(msg)}
+}
+
+// Errorf returns an error formatted as fmt.Errorf tagged with `code`.
+func Errorf(code Code, format string, args ...interface{}) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// Wrap tags `err` with `code`. It returns nil when `err` is nil.
+func Wrap(code Code, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// CodeOf returns the code of the outermost tag of `err` or of the errors it
+// wraps, Unknown when there is none.
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return Unknown
+}
+
+// Format returns the message of `err` prefixed with its code, as reported by
+// the command API, or only the message when `err` is not tagged.
+func Format(err error) string {
+	code := CodeOf(err)
+	if code == Unknown {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s: %s", code, err)
+}
+
+// Parse splits a message formatted by Format into its code and message. The
+// code is Unknown when the message has no known code prefix.
+func Parse(msg string) (Code, string) {
+	idx := strings.Index(msg, ": ")
+	if idx < 0 {
+		return Unknown, msg
+	}
+	code := Code(msg[:idx])
+	if _, ok := codes[code]; !ok {
+		return Unknown, msg
+	}
+	return code, msg[idx+2:]
+}
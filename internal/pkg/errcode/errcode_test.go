@@ -0,0 +1,47 @@
+package errcode
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/xerrors"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestCodeOf(t *testing.T) {
+	tf.UnitTest(t)
+
+	err := Errorf(InsufficientFunds, "balance %d too low", 3)
+	assert.Equal(t, "balance 3 too low", err.Error())
+	assert.Equal(t, InsufficientFunds, CodeOf(err))
+
+	t.Log("codes are kept through wrapping")
+	assert.Equal(t, InsufficientFunds, CodeOf(errors.Wrap(err, "invalid message")))
+	assert.Equal(t, InsufficientFunds, CodeOf(xerrors.Errorf("failed to send: %w", err)))
+	assert.Equal(t, InsufficientFunds, CodeOf(fmt.Errorf("failed to send: %w", errors.WithMessage(err, "oops"))))
+
+	t.Log("errors not tagged have no code")
+	assert.Equal(t, Unknown, CodeOf(errors.New("boom")))
+	assert.Equal(t, Unknown, CodeOf(nil))
+	assert.Nil(t, Wrap(GasTooLow, nil))
+}
+
+func TestFormatParse(t *testing.T) {
+	tf.UnitTest(t)
+
+	err := errors.Wrap(New(UnknownDeal, "no deal bafy"), "failed to get deal")
+	msg := Format(err)
+	assert.Equal(t, "unknown-deal: failed to get deal: no deal bafy", msg)
+	code, rest := Parse(msg)
+	assert.Equal(t, UnknownDeal, code)
+	assert.Equal(t, "failed to get deal: no deal bafy", rest)
+
+	t.Log("messages without a known code prefix are not parsed")
+	assert.Equal(t, "failed to get deal: boom", Format(errors.New("failed to get deal: boom")))
+	code, rest = Parse("failed to get deal: boom")
+	assert.Equal(t, Unknown, code)
+	assert.Equal(t, "failed to get deal: boom", rest)
+}
@@ -0,0 +1,89 @@
+package ledger
+
+import (
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+)
+
+// Entry is the income and expenditure of a miner in a tipset. Amounts are in attoFIL.
+type Entry struct {
+	_      struct{} `cbor:",toarray"`
+	Height abi.ChainEpoch
+	// BlocksWon is the number of blocks of the tipset mined by the miner.
+	BlocksWon uint64
+	// BlockRewards are the rewards of the blocks won, including the gas paid
+	// by their messages.
+	BlockRewards abi.TokenAmount
+	// DealPayments are the payments earned by the active storage deals of the
+	// miner over the epochs of the tipset.
+	DealPayments abi.TokenAmount
+	// PoStGas is the gas paid by the owner and worker for window PoSts.
+	PoStGas abi.TokenAmount
+	// CommitmentGas is the gas paid by the owner and worker for sector pre-commitments and commitments.
+	CommitmentGas abi.TokenAmount
+	// OtherGas is the gas paid by the owner and worker for other messages.
+	OtherGas abi.TokenAmount
+	// Slashing is the provider collateral of the deals slashed, and the funds
+	// of the miner actor burnt as penalties.
+	Slashing abi.TokenAmount
+	// Withdrawn are the funds withdrawn from the miner actor by its owner.
+	Withdrawn abi.TokenAmount
+}
+
+func newEntry(height abi.ChainEpoch) Entry {
+	return Entry{
+		Height:        height,
+		BlockRewards:  big.Zero(),
+		DealPayments:  big.Zero(),
+		PoStGas:       big.Zero(),
+		CommitmentGas: big.Zero(),
+		OtherGas:      big.Zero(),
+		Slashing:      big.Zero(),
+		Withdrawn:     big.Zero(),
+	}
+}
+
+// Income returns the block rewards and deal payments.
+func (e Entry) Income() abi.TokenAmount {
+	return big.Add(e.BlockRewards, e.DealPayments)
+}
+
+// Expenditure returns the gas paid and the funds lost to slashing.
+func (e Entry) Expenditure() abi.TokenAmount {
+	return big.Add(big.Add(e.PoStGas, e.CommitmentGas), big.Add(e.OtherGas, e.Slashing))
+}
+
+// Net returns the income minus the expenditure.
+func (e Entry) Net() abi.TokenAmount {
+	return big.Sub(e.Income(), e.Expenditure())
+}
+
+// IsZero is true when nothing was earned nor spent.
+func (e Entry) IsZero() bool {
+	return e.BlocksWon == 0 && e.Income().Sign() == 0 && e.Expenditure().Sign() == 0 && e.Withdrawn.Sign() == 0
+}
+
+func (e *Entry) add(other Entry) {
+	e.BlocksWon += other.BlocksWon
+	e.BlockRewards = big.Add(e.BlockRewards, other.BlockRewards)
+	e.DealPayments = big.Add(e.DealPayments, other.DealPayments)
+	e.PoStGas = big.Add(e.PoStGas, other.PoStGas)
+	e.CommitmentGas = big.Add(e.CommitmentGas, other.CommitmentGas)
+	e.OtherGas = big.Add(e.OtherGas, other.OtherGas)
+	e.Slashing = big.Add(e.Slashing, other.Slashing)
+	e.Withdrawn = big.Add(e.Withdrawn, other.Withdrawn)
+}
+
+// Earnings are the income and expenditure of a miner over a range of epochs.
+type Earnings struct {
+	Miner address.Address
+	// Since and Until are the first and last epochs of the range.
+	Since abi.ChainEpoch
+	Until abi.ChainEpoch
+	// Entries are the entries of the tipsets in which the miner earned or
+	// spent anything, by height.
+	Entries []Entry
+	// Total sums the entries, its height is Until.
+	Total Entry
+}
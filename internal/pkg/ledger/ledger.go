@@ -0,0 +1,409 @@
+// Package ledger accounts for the income and expenditure of miners from the
+// chain: block rewards, deal payments, gas and slashing.
+package ledger
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/actors/runtime/exitcode"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
+)
+
+var log = logging.Logger("ledger")
+
+// DSPrefix is the prefix of the datastore keys of the recorded entries.
+const DSPrefix = "/ledger"
+
+// Chain provides the tipsets and their receipts.
+type Chain interface {
+	GetTipSet(block.TipSetKey) (block.TipSet, error)
+	GetTipSetReceiptsRoot(block.TipSetKey) (cid.Cid, error)
+}
+
+// MessageProvider loads the messages and receipts of tipsets.
+type MessageProvider interface {
+	LoadMessages(context.Context, cid.Cid) ([]*types.SignedMessage, []*types.UnsignedMessage, error)
+	LoadReceipts(context.Context, cid.Cid) ([]vm.MessageReceipt, error)
+}
+
+// StateView is the state read by the ledger, it is implemented by state.View.
+type StateView interface {
+	InitResolveAddress(ctx context.Context, a address.Address) (address.Address, error)
+	MinerControlAddresses(ctx context.Context, maddr address.Address) (owner, worker address.Address, err error)
+	ActorBalance(ctx context.Context, a address.Address) (abi.TokenAmount, error)
+	RewardLastPerEpochReward(ctx context.Context) (abi.TokenAmount, error)
+	MarketDealStatesForEach(ctx context.Context, f func(id abi.DealID, state *market.DealState) error) error
+	MarketDealProposal(ctx context.Context, dealID abi.DealID) (market.DealProposal, error)
+}
+
+var _ StateView = (*state.View)(nil)
+
+// StateLoader returns the state resulting from the execution of a tipset.
+type StateLoader func(key block.TipSetKey) (StateView, error)
+
+// Ledger computes the entries of miners from the chain, and records them
+// so that each tipset is processed once.
+type Ledger struct {
+	chain    Chain
+	messages MessageProvider
+	states   StateLoader
+	ds       datastore.Datastore
+
+	lk        sync.Mutex
+	recording bool
+}
+
+// New returns a ledger recording entries in `ds`.
+func New(chain Chain, messages MessageProvider, states StateLoader, ds datastore.Batching) *Ledger {
+	return &Ledger{
+		chain:    chain,
+		messages: messages,
+		states:   states,
+		ds:       namespace.Wrap(ds, datastore.NewKey(DSPrefix)),
+	}
+}
+
+// Earnings returns the entries of the miner for the tipsets from `since`
+// to `head`. The state of the tipsets must not have been pruned.
+func (l *Ledger) Earnings(ctx context.Context, maddr address.Address, head block.TipSetKey, since abi.ChainEpoch) (*Earnings, error) {
+	ts, err := l.chain.GetTipSet(head)
+	if err != nil {
+		return nil, err
+	}
+	until, err := ts.Height()
+	if err != nil {
+		return nil, err
+	}
+	if since > until {
+		return nil, errors.Errorf("height %d is above the head of the chain, %d", since, until)
+	}
+
+	earnings := &Earnings{Miner: maddr, Since: since, Until: until, Entries: []Entry{}, Total: newEntry(until)}
+	for {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		height, err := ts.Height()
+		if err != nil {
+			return nil, err
+		}
+		if height < since {
+			break
+		}
+
+		entry, err := l.Entry(ctx, maddr, ts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to account for tipset at height %d", height)
+		}
+		if !entry.IsZero() {
+			earnings.Entries = append(earnings.Entries, entry)
+			earnings.Total.add(entry)
+		}
+
+		parents, err := ts.Parents()
+		if err != nil {
+			return nil, err
+		}
+		if parents.Empty() {
+			break
+		}
+		if ts, err = l.chain.GetTipSet(parents); err != nil {
+			return nil, err
+		}
+	}
+
+	// entries were collected from the head down
+	for i, j := 0, len(earnings.Entries)-1; i < j; i, j = i+1, j-1 {
+		earnings.Entries[i], earnings.Entries[j] = earnings.Entries[j], earnings.Entries[i]
+	}
+	return earnings, nil
+}
+
+// HandleNewHead records the entry of the miner for a new head in the
+// background, unless an entry is being recorded already. It does not block.
+func (l *Ledger) HandleNewHead(ctx context.Context, maddr address.Address, head block.TipSet) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+	if l.recording {
+		return
+	}
+	l.recording = true
+	go func() {
+		if _, err := l.Entry(ctx, maddr, head); err != nil {
+			log.Warnf("failed to record entry of %s for %s: %s", maddr, head.Key(), err)
+		}
+		l.lk.Lock()
+		l.recording = false
+		l.lk.Unlock()
+	}()
+}
+
+// Entry returns the entry of the miner for a tipset, recording it if it was not.
+func (l *Ledger) Entry(ctx context.Context, maddr address.Address, ts block.TipSet) (Entry, error) {
+	key := entryKey(maddr, ts.Key())
+	raw, err := l.ds.Get(key)
+	if err == nil {
+		var entry Entry
+		if err := encoding.Decode(raw, &entry); err != nil {
+			return Entry{}, errors.Wrap(err, "failed to decode recorded entry")
+		}
+		return entry, nil
+	}
+	if err != datastore.ErrNotFound {
+		return Entry{}, err
+	}
+
+	entry, err := l.compute(ctx, maddr, ts)
+	if err != nil {
+		return Entry{}, err
+	}
+	raw, err = encoding.Encode(entry)
+	if err != nil {
+		return Entry{}, err
+	}
+	if err := l.ds.Put(key, raw); err != nil {
+		return Entry{}, errors.Wrap(err, "failed to record entry")
+	}
+	return entry, nil
+}
+
+func entryKey(maddr address.Address, tsk block.TipSetKey) datastore.Key {
+	cids := make([]string, 0, tsk.Len())
+	for _, c := range tsk.ToSlice() {
+		cids = append(cids, c.String())
+	}
+	return datastore.KeyWithNamespaces([]string{maddr.String(), strings.Join(cids, ",")})
+}
+
+// compute accounts for a tipset from its messages, receipts and the states
+// before and after its execution.
+func (l *Ledger) compute(ctx context.Context, maddr address.Address, ts block.TipSet) (Entry, error) {
+	height, err := ts.Height()
+	if err != nil {
+		return Entry{}, err
+	}
+	entry := newEntry(height)
+	parentKey, err := ts.Parents()
+	if err != nil {
+		return Entry{}, err
+	}
+	if parentKey.Empty() {
+		return entry, nil
+	}
+	parent, err := l.chain.GetTipSet(parentKey)
+	if err != nil {
+		return Entry{}, err
+	}
+	parentHeight, err := parent.Height()
+	if err != nil {
+		return Entry{}, err
+	}
+
+	before, err := l.states(parentKey)
+	if err != nil {
+		return Entry{}, err
+	}
+	after, err := l.states(ts.Key())
+	if err != nil {
+		return Entry{}, err
+	}
+
+	owner, worker, err := before.MinerControlAddresses(ctx, maddr)
+	if err != nil {
+		// the miner may have been created by the tipset
+		if owner, worker, err = after.MinerControlAddresses(ctx, maddr); err != nil {
+			return entry, nil
+		}
+	}
+
+	inflow, withdrew, err := l.accountMessages(ctx, &entry, maddr, owner, worker, ts, before)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	// Penalties are burnt by the miner actor without a message: they are the
+	// decrease of its balance that messages and rewards do not explain.
+	balanceBefore, err := before.ActorBalance(ctx, maddr)
+	if err != nil {
+		balanceBefore = big.Zero()
+	}
+	balanceAfter, err := after.ActorBalance(ctx, maddr)
+	if err != nil {
+		return Entry{}, err
+	}
+	unexplained := big.Sub(big.Sub(balanceAfter, balanceBefore), big.Add(entry.BlockRewards, inflow))
+	if unexplained.Sign() < 0 {
+		if withdrew {
+			entry.Withdrawn = unexplained.Neg()
+		} else {
+			entry.Slashing = big.Add(entry.Slashing, unexplained.Neg())
+		}
+	}
+
+	if err := accountDeals(ctx, &entry, maddr, parentHeight, height, after); err != nil {
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+// accountMessages accounts for the block rewards and gas of the messages of
+// a tipset, in the order they are executed. It returns the value sent to the
+// miner actor by the messages, and whether its owner withdrew funds.
+func (l *Ledger) accountMessages(ctx context.Context, entry *Entry, maddr, owner, worker address.Address, ts block.TipSet, before StateView) (abi.TokenAmount, bool, error) {
+	receiptsRoot, err := l.chain.GetTipSetReceiptsRoot(ts.Key())
+	if err != nil {
+		return big.Zero(), false, err
+	}
+	receipts, err := l.messages.LoadReceipts(ctx, receiptsRoot)
+	if err != nil {
+		return big.Zero(), false, err
+	}
+	perEpochReward, err := before.RewardLastPerEpochReward(ctx)
+	if err != nil {
+		return big.Zero(), false, err
+	}
+	blockReward := big.Div(perEpochReward, big.NewInt(builtin.ExpectedLeadersPerEpoch))
+
+	resolved := map[address.Address]address.Address{}
+	resolve := func(a address.Address) address.Address {
+		if a.Protocol() == address.ID {
+			return a
+		}
+		if id, ok := resolved[a]; ok {
+			return id
+		}
+		id, err := before.InitResolveAddress(ctx, a)
+		if err != nil {
+			id = a
+		}
+		resolved[a] = id
+		return id
+	}
+
+	inflow := big.Zero()
+	withdrew := false
+	seen := map[cid.Cid]bool{}
+	next := 0
+	for i := 0; i < ts.Len(); i++ {
+		blk := ts.At(i)
+		secpMsgs, blsMsgs, err := l.messages.LoadMessages(ctx, blk.Messages.Cid)
+		if err != nil {
+			return big.Zero(), false, err
+		}
+		msgs := make([]*types.UnsignedMessage, 0, len(blsMsgs)+len(secpMsgs))
+		msgs = append(msgs, blsMsgs...)
+		for _, m := range secpMsgs {
+			msgs = append(msgs, &m.Message)
+		}
+
+		gasReward := big.Zero()
+		for _, m := range msgs {
+			c, err := m.Cid()
+			if err != nil {
+				return big.Zero(), false, err
+			}
+			if seen[c] {
+				continue
+			}
+			seen[c] = true
+			if next >= len(receipts) {
+				return big.Zero(), false, errors.Errorf("missing receipt of message %s", c)
+			}
+			receipt := receipts[next]
+			next++
+
+			gasCost := receipt.GasUsed.ToTokens(m.GasPrice)
+			gasReward = big.Add(gasReward, gasCost)
+			from, to := resolve(m.From), resolve(m.To)
+			if from == owner || from == worker {
+				switch {
+				case to == maddr && m.Method == builtin.MethodsMiner.SubmitWindowedPoSt:
+					entry.PoStGas = big.Add(entry.PoStGas, gasCost)
+				case to == maddr && (m.Method == builtin.MethodsMiner.PreCommitSector || m.Method == builtin.MethodsMiner.ProveCommitSector):
+					entry.CommitmentGas = big.Add(entry.CommitmentGas, gasCost)
+				default:
+					entry.OtherGas = big.Add(entry.OtherGas, gasCost)
+				}
+			}
+			if to == maddr && receipt.ExitCode == exitcode.Ok {
+				inflow = big.Add(inflow, m.Value)
+				withdrew = withdrew || m.Method == builtin.MethodsMiner.WithdrawBalance
+			}
+		}
+
+		if blk.Miner == maddr {
+			entry.BlocksWon++
+			entry.BlockRewards = big.Add(entry.BlockRewards, big.Add(blockReward, gasReward))
+		}
+	}
+	return inflow, withdrew, nil
+}
+
+// accountDeals accounts for the payments earned by the active deals of the
+// provider over the epochs after `parentHeight` up to `height`, and for the
+// collateral of the deals slashed over these epochs.
+func accountDeals(ctx context.Context, entry *Entry, provider address.Address, parentHeight, height abi.ChainEpoch, after StateView) error {
+	type dealState struct {
+		id    abi.DealID
+		state market.DealState
+	}
+	var states []dealState
+	err := after.MarketDealStatesForEach(ctx, func(id abi.DealID, state *market.DealState) error {
+		if state.SectorStartEpoch >= 0 {
+			states = append(states, dealState{id: id, state: *state})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, ds := range states {
+		proposal, err := after.MarketDealProposal(ctx, ds.id)
+		if err != nil {
+			return err
+		}
+		if proposal.Provider != provider {
+			continue
+		}
+
+		end := proposal.EndEpoch
+		if ds.state.SlashEpoch >= 0 && ds.state.SlashEpoch < end {
+			end = ds.state.SlashEpoch
+			if ds.state.SlashEpoch > parentHeight && ds.state.SlashEpoch <= height {
+				entry.Slashing = big.Add(entry.Slashing, proposal.ProviderCollateral)
+			}
+		}
+		// payments are due for the epochs from the start of the deal to its end
+		from := proposal.StartEpoch
+		if from < parentHeight+1 {
+			from = parentHeight + 1
+		}
+		to := end
+		if to > height+1 {
+			to = height + 1
+		}
+		if to > from {
+			paid := big.Mul(proposal.StoragePricePerEpoch, big.NewInt(int64(to-from)))
+			entry.DealPayments = big.Add(entry.DealPayments, paid)
+		}
+	}
+	return nil
+}
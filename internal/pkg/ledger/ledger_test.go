@@ -0,0 +1,237 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/actors/runtime/exitcode"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	e "github.com/filecoin-project/go-filecoin/internal/pkg/enccid"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
+)
+
+type fakeView struct {
+	owner, worker address.Address
+	balance       abi.TokenAmount
+	reward        abi.TokenAmount
+	proposals     map[abi.DealID]market.DealProposal
+	states        map[abi.DealID]market.DealState
+}
+
+func (v *fakeView) InitResolveAddress(_ context.Context, a address.Address) (address.Address, error) {
+	return a, nil
+}
+
+func (v *fakeView) MinerControlAddresses(context.Context, address.Address) (address.Address, address.Address, error) {
+	return v.owner, v.worker, nil
+}
+
+func (v *fakeView) ActorBalance(context.Context, address.Address) (abi.TokenAmount, error) {
+	return v.balance, nil
+}
+
+func (v *fakeView) RewardLastPerEpochReward(context.Context) (abi.TokenAmount, error) {
+	return v.reward, nil
+}
+
+func (v *fakeView) MarketDealStatesForEach(_ context.Context, f func(abi.DealID, *market.DealState) error) error {
+	for id, state := range v.states {
+		state := state
+		if err := f(id, &state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *fakeView) MarketDealProposal(_ context.Context, id abi.DealID) (market.DealProposal, error) {
+	return v.proposals[id], nil
+}
+
+// fakeChain holds tipsets of a single block, with their messages, receipts
+// and the state after their execution.
+type fakeChain struct {
+	t         *testing.T
+	tipsets   map[string]block.TipSet
+	views     map[string]*fakeView
+	messages  map[cid.Cid][]*types.UnsignedMessage
+	receipts  map[cid.Cid][]vm.MessageReceipt
+	head      block.TipSet
+	nextBlock int
+}
+
+func newFakeChain(t *testing.T, genesis *fakeView) *fakeChain {
+	c := &fakeChain{
+		t:        t,
+		tipsets:  map[string]block.TipSet{},
+		views:    map[string]*fakeView{},
+		messages: map[cid.Cid][]*types.UnsignedMessage{},
+		receipts: map[cid.Cid][]vm.MessageReceipt{},
+	}
+	c.add(&block.Block{Height: 0}, nil, nil, genesis)
+	return c
+}
+
+// add appends a tipset with the block, executing `msgs` with `receipts` and
+// resulting in `view`.
+func (c *fakeChain) add(blk *block.Block, msgs []*types.UnsignedMessage, receipts []vm.MessageReceipt, view *fakeView) block.TipSet {
+	c.nextBlock++
+	messages := types.CidFromString(c.t, fmt.Sprintf("messages%d", c.nextBlock))
+	c.messages[messages] = msgs
+	blk.Messages = e.NewCid(messages)
+	blk.MessageReceipts = e.NewCid(types.CidFromString(c.t, fmt.Sprintf("receipts%d", c.nextBlock)))
+	if c.head.Defined() {
+		blk.Parents = c.head.Key()
+	}
+	ts, err := block.NewTipSet(blk)
+	require.NoError(c.t, err)
+	c.receipts[blk.MessageReceipts.Cid] = receipts
+	c.tipsets[ts.Key().String()] = ts
+	c.views[ts.Key().String()] = view
+	c.head = ts
+	return ts
+}
+
+func (c *fakeChain) GetTipSet(key block.TipSetKey) (block.TipSet, error) {
+	ts, ok := c.tipsets[key.String()]
+	if !ok {
+		return block.UndefTipSet, fmt.Errorf("no tipset %s", key)
+	}
+	return ts, nil
+}
+
+// GetTipSetReceiptsRoot returns the receipts of the messages of the tipset,
+// which the fake chain stores in the block as a shortcut.
+func (c *fakeChain) GetTipSetReceiptsRoot(key block.TipSetKey) (cid.Cid, error) {
+	ts, err := c.GetTipSet(key)
+	if err != nil {
+		return cid.Undef, err
+	}
+	return ts.At(0).MessageReceipts.Cid, nil
+}
+
+func (c *fakeChain) LoadMessages(_ context.Context, messages cid.Cid) ([]*types.SignedMessage, []*types.UnsignedMessage, error) {
+	return []*types.SignedMessage{}, c.messages[messages], nil
+}
+
+func (c *fakeChain) LoadReceipts(_ context.Context, receipts cid.Cid) ([]vm.MessageReceipt, error) {
+	return c.receipts[receipts], nil
+}
+
+func (c *fakeChain) states(key block.TipSetKey) (StateView, error) {
+	view, ok := c.views[key.String()]
+	if !ok {
+		return nil, fmt.Errorf("no state for %s", key)
+	}
+	return view, nil
+}
+
+func idAddr(t *testing.T, id uint64) address.Address {
+	addr, err := address.NewIDAddress(id)
+	require.NoError(t, err)
+	return addr
+}
+
+func message(from, to address.Address, value int64, method abi.MethodNum, price int64) *types.UnsignedMessage {
+	return types.NewMeteredMessage(from, to, 0, abi.NewTokenAmount(value), method, []byte{}, abi.NewTokenAmount(price), gas.NewGas(1000))
+}
+
+func TestEarnings(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	maddr, otherMiner := idAddr(t, 1000), idAddr(t, 1001)
+	owner, worker, stranger := idAddr(t, 100), idAddr(t, 101), idAddr(t, 102)
+	reward := big.Mul(abi.NewTokenAmount(5), big.NewInt(builtin.ExpectedLeadersPerEpoch))
+
+	deal := market.DealProposal{Provider: maddr, StartEpoch: 1, EndEpoch: 10, StoragePricePerEpoch: abi.NewTokenAmount(3), ProviderCollateral: abi.NewTokenAmount(50)}
+	otherDeal := market.DealProposal{Provider: otherMiner, StartEpoch: 1, EndEpoch: 10, StoragePricePerEpoch: abi.NewTokenAmount(1000)}
+	pendingDeal := market.DealProposal{Provider: maddr, StartEpoch: 1, EndEpoch: 10, StoragePricePerEpoch: abi.NewTokenAmount(1000)}
+	proposals := map[abi.DealID]market.DealProposal{1: deal, 2: otherDeal, 3: pendingDeal}
+	view := func(balance int64, states map[abi.DealID]market.DealState) *fakeView {
+		return &fakeView{owner: owner, worker: worker, balance: abi.NewTokenAmount(balance), reward: reward, proposals: proposals, states: states}
+	}
+	active := market.DealState{SectorStartEpoch: 0, LastUpdatedEpoch: -1, SlashEpoch: -1}
+	pending := market.DealState{SectorStartEpoch: -1, LastUpdatedEpoch: -1, SlashEpoch: -1}
+	slashed := market.DealState{SectorStartEpoch: 0, LastUpdatedEpoch: -1, SlashEpoch: 3}
+
+	chain := newFakeChain(t, view(100, map[abi.DealID]market.DealState{}))
+
+	t.Log("the miner wins a block at height 1, in which a fault penalty of 4 is burnt")
+	msgs := []*types.UnsignedMessage{
+		message(worker, maddr, 0, builtin.MethodsMiner.SubmitWindowedPoSt, 2),
+		message(worker, maddr, 0, builtin.MethodsMiner.PreCommitSector, 2),
+		message(owner, stranger, 0, builtin.MethodSend, 1),
+		message(stranger, maddr, 7, builtin.MethodSend, 1),
+	}
+	receipts := []vm.MessageReceipt{
+		{ExitCode: exitcode.Ok, GasUsed: gas.NewGas(10)},
+		{ExitCode: exitcode.Ok, GasUsed: gas.NewGas(5)},
+		{ExitCode: exitcode.Ok, GasUsed: gas.NewGas(1)},
+		{ExitCode: exitcode.Ok, GasUsed: gas.NewGas(3)},
+	}
+	// rewards: 5 per block and 34 of gas
+	states := map[abi.DealID]market.DealState{1: active, 2: active, 3: pending}
+	chain.add(&block.Block{Height: 1, Miner: maddr}, msgs, receipts, view(100+39+7-4, states))
+
+	t.Log("another miner wins the block at height 4, in which the owner withdraws 20 and a deal is slashed")
+	msgs = []*types.UnsignedMessage{message(owner, maddr, 0, builtin.MethodsMiner.WithdrawBalance, 1)}
+	receipts = []vm.MessageReceipt{{ExitCode: exitcode.Ok, GasUsed: gas.NewGas(2)}}
+	states = map[abi.DealID]market.DealState{1: slashed, 2: active, 3: pending}
+	head := chain.add(&block.Block{Height: 4, Miner: otherMiner}, msgs, receipts, view(142-20, states))
+
+	ledger := New(chain, chain, chain.states, dssync.MutexWrap(datastore.NewMapDatastore()))
+	earnings, err := ledger.Earnings(ctx, maddr, head.Key(), 0)
+	require.NoError(t, err)
+
+	require.Len(t, earnings.Entries, 2)
+	first, second := earnings.Entries[0], earnings.Entries[1]
+	assert.Equal(t, abi.ChainEpoch(1), first.Height)
+	assert.Equal(t, uint64(1), first.BlocksWon)
+	assert.Equal(t, abi.NewTokenAmount(39), first.BlockRewards)
+	assert.Equal(t, abi.NewTokenAmount(3), first.DealPayments)
+	assert.Equal(t, abi.NewTokenAmount(20), first.PoStGas)
+	assert.Equal(t, abi.NewTokenAmount(10), first.CommitmentGas)
+	assert.Equal(t, abi.NewTokenAmount(1), first.OtherGas)
+	assert.Equal(t, abi.NewTokenAmount(4), first.Slashing)
+	assert.Equal(t, abi.NewTokenAmount(0), first.Withdrawn)
+
+	assert.Equal(t, abi.ChainEpoch(4), second.Height)
+	assert.Equal(t, uint64(0), second.BlocksWon)
+	// the slashed deal is paid for epoch 2 only
+	assert.Equal(t, abi.NewTokenAmount(3), second.DealPayments)
+	assert.Equal(t, abi.NewTokenAmount(2), second.OtherGas)
+	assert.Equal(t, abi.NewTokenAmount(50), second.Slashing)
+	assert.Equal(t, abi.NewTokenAmount(20), second.Withdrawn)
+
+	assert.Equal(t, abi.ChainEpoch(4), earnings.Until)
+	assert.Equal(t, uint64(1), earnings.Total.BlocksWon)
+	assert.Equal(t, abi.NewTokenAmount(45), earnings.Total.Income())
+	assert.Equal(t, abi.NewTokenAmount(87), earnings.Total.Expenditure())
+	assert.Equal(t, abi.NewTokenAmount(-42), earnings.Total.Net())
+
+	t.Log("entries are recorded")
+	chain.views[head.Key().String()].balance = abi.NewTokenAmount(0)
+	again, err := ledger.Earnings(ctx, maddr, head.Key(), 2)
+	require.NoError(t, err)
+	require.Len(t, again.Entries, 1)
+	assert.Equal(t, second, again.Entries[0])
+
+	_, err = ledger.Earnings(ctx, maddr, head.Key(), 5)
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package logfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Formats of the log file.
+const (
+	// FormatConsole writes entries as the console encoding of go-log, without colors.
+	FormatConsole = "console"
+	// FormatJSON writes one JSON object per entry, with the keys of the json
+	// encoding of go-log.
+	FormatJSON = "json"
+)
+
+// maxLineSize bounds the size of a single log entry.
+const maxLineSize = 1 << 20
+
+var colorCode = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// Copy reads log output from r, as written by go-log to its pipe readers, and
+// writes it to w in the given format until r is closed.
+//
+// The encoding of go-log loggers is fixed when they are created, from the
+// GOLOG_LOG_FMT environment variable, so entries are re-encoded here: console
+// entries are converted to JSON and colors are removed. Output that is already
+// JSON is copied as it is.
+func Copy(w io.Writer, r io.Reader, format string) error {
+	if format != FormatConsole && format != FormatJSON {
+		return errors.Errorf("unknown log format %s", format)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		var out []byte
+		if format == FormatJSON {
+			out = consoleToJSON(line)
+		} else {
+			out = []byte(colorCode.ReplaceAllString(line, ""))
+		}
+		if _, err := w.Write(append(out, '\n')); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil && err != io.ErrClosedPipe {
+		return err
+	}
+	return nil
+}
+
+// consoleToJSON converts one line of console encoded output to a JSON object.
+// A console entry is the time, level, logger name, caller and message separated
+// by tabs, followed by a JSON object of the fields logged with it if any. Lines
+// that are not entries, e.g. the continuation of a multi-line message, become
+// an object holding just the message.
+func consoleToJSON(line string) []byte {
+	if strings.HasPrefix(line, "{") && json.Valid([]byte(line)) {
+		return []byte(line)
+	}
+
+	entry := map[string]interface{}{}
+	parts := strings.SplitN(line, "\t", 5)
+	if len(parts) < 5 {
+		entry["msg"] = colorCode.ReplaceAllString(line, "")
+		return marshalEntry(entry)
+	}
+
+	msg := parts[4]
+	if i := strings.LastIndex(msg, "\t"); i >= 0 && strings.HasPrefix(msg[i+1:], "{") {
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(msg[i+1:]), &fields); err == nil {
+			for k, v := range fields {
+				entry[k] = v
+			}
+			msg = msg[:i]
+		}
+	}
+	entry["ts"] = parts[0]
+	entry["level"] = strings.ToLower(colorCode.ReplaceAllString(parts[1], ""))
+	entry["logger"] = parts[2]
+	entry["caller"] = parts[3]
+	entry["msg"] = msg
+	return marshalEntry(entry)
+}
+
+func marshalEntry(entry map[string]interface{}) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(entry) // values are strings or were decoded from JSON
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
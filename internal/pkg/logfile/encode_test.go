@@ -0,0 +1,69 @@
+package logfile_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/logfile"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestCopy(t *testing.T) {
+	tf.UnitTest(t)
+
+	input := strings.Join([]string{
+		"2020-05-20T10:00:00.000Z\t\x1b[34mINFO\x1b[0m\tchainsync\tchainsync/syncer.go:42\tsyncing\tchain",
+		"2020-05-20T10:00:01.000Z\tWARN\tmining\tmining/worker.go:10\tlate block\t{\"epoch\": 12, \"miner\": \"t01000\"}",
+		"goroutine 1 [running]:",
+		`{"level":"info","ts":"2020-05-20T10:00:02.000Z","logger":"net","msg":"up"}`,
+	}, "\n") + "\n"
+
+	t.Run("console output has no colors", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, logfile.Copy(&out, strings.NewReader(input), logfile.FormatConsole))
+		lines := strings.Split(out.String(), "\n")
+		assert.Equal(t, "2020-05-20T10:00:00.000Z\tINFO\tchainsync\tchainsync/syncer.go:42\tsyncing\tchain", lines[0])
+	})
+
+	t.Run("json output has an object per line", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, logfile.Copy(&out, strings.NewReader(input), logfile.FormatJSON))
+
+		var entries []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			var entry map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+			entries = append(entries, entry)
+		}
+		require.Len(t, entries, 4)
+
+		assert.Equal(t, map[string]interface{}{
+			"ts":     "2020-05-20T10:00:00.000Z",
+			"level":  "info",
+			"logger": "chainsync",
+			"caller": "chainsync/syncer.go:42",
+			"msg":    "syncing\tchain",
+		}, entries[0])
+
+		t.Log("fields logged with the entry become keys of the object")
+		assert.Equal(t, "late block", entries[1]["msg"])
+		assert.Equal(t, "warn", entries[1]["level"])
+		assert.Equal(t, float64(12), entries[1]["epoch"])
+		assert.Equal(t, "t01000", entries[1]["miner"])
+
+		t.Log("lines that are not entries keep just the message")
+		assert.Equal(t, map[string]interface{}{"msg": "goroutine 1 [running]:"}, entries[2])
+
+		t.Log("json entries are copied as they are")
+		assert.Equal(t, "up", entries[3]["msg"])
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		assert.Error(t, logfile.Copy(&bytes.Buffer{}, strings.NewReader(input), "xml"))
+	})
+}
@@ -0,0 +1,161 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
+)
+
+// backupTimeFormat is the layout of the timestamp suffixed to rotated files.
+// It sorts lexically in time order.
+const backupTimeFormat = "20060102T150405.000"
+
+// Writer is an io.WriteCloser appending to a file that is rotated once it
+// reaches a size or has been written for a period. Rotated files are renamed
+// with the time of the rotation appended to their name.
+type Writer struct {
+	path       string
+	maxSize    int64
+	period     time.Duration
+	maxBackups int
+	clock      clock.Clock
+
+	lk     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// NewWriter opens, or creates, the file at `path` for appending. A zero
+// `maxSize` or `period` disables rotation on size or time respectively, and a
+// zero `maxBackups` keeps every rotated file.
+func NewWriter(path string, maxSize int64, period time.Duration, maxBackups int, clk clock.Clock) (*Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create log directory")
+	}
+	w := &Writer{
+		path:       path,
+		maxSize:    maxSize,
+		period:     period,
+		maxBackups: maxBackups,
+		clock:      clk,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write appends p to the file, rotating it first if p does not fit or the
+// rotation period has elapsed. Writes are never split across files.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	if w.file == nil {
+		return 0, errors.New("log file is closed")
+	}
+	if w.shouldRotate(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file.
+func (w *Writer) Close() error {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *Writer) shouldRotate(n int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.maxSize > 0 && w.size+n > w.maxSize {
+		return true
+	}
+	return w.period > 0 && w.clock.Since(w.opened) >= w.period
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.Wrap(err, "failed to open log file")
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return errors.Wrap(err, "failed to stat log file")
+	}
+	w.file = f
+	w.size = info.Size()
+	w.opened = w.clock.Now()
+	return nil
+}
+
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return errors.Wrap(err, "failed to close log file")
+	}
+	w.file = nil
+
+	backup := w.path + "." + w.clock.Now().UTC().Format(backupTimeFormat)
+	if err := os.Rename(w.path, backup); err != nil {
+		return errors.Wrap(err, "failed to rotate log file")
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	return w.prune()
+}
+
+// prune removes the oldest rotated files beyond maxBackups.
+func (w *Writer) prune() error {
+	if w.maxBackups == 0 {
+		return nil
+	}
+	backups, err := w.Backups()
+	if err != nil {
+		return err
+	}
+	for len(backups) > w.maxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			return errors.Wrap(err, "failed to remove rotated log file")
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// Backups lists the rotated files, oldest first.
+func (w *Writer) Backups() ([]string, error) {
+	matches, err := filepath.Glob(w.path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, m := range matches {
+		if _, err := time.Parse(backupTimeFormat, m[len(w.path)+1:]); err == nil {
+			backups = append(backups, m)
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
@@ -0,0 +1,115 @@
+package logfile_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/logfile"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestWriter(t *testing.T) {
+	tf.UnitTest(t)
+
+	newWriter := func(t *testing.T, maxSize int64, period time.Duration, maxBackups int) (*logfile.Writer, clock.Fake, string, func()) {
+		dir, err := ioutil.TempDir("", "logfile")
+		require.NoError(t, err)
+
+		clk := clock.NewFake(time.Unix(1234567890, 0))
+		path := filepath.Join(dir, "logs", "daemon.log")
+		w, err := logfile.NewWriter(path, maxSize, period, maxBackups, clk)
+		require.NoError(t, err)
+		return w, clk, path, func() {
+			_ = w.Close()
+			_ = os.RemoveAll(dir)
+		}
+	}
+
+	readFile := func(t *testing.T, path string) string {
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("rotates when a write does not fit", func(t *testing.T) {
+		w, clk, path, cleanup := newWriter(t, 10, 0, 0)
+		defer cleanup()
+
+		_, err := w.Write([]byte("12345\n"))
+		require.NoError(t, err)
+		_, err = w.Write([]byte("123\n"))
+		require.NoError(t, err)
+		backups, err := w.Backups()
+		require.NoError(t, err)
+		assert.Empty(t, backups)
+
+		clk.Advance(time.Second)
+		_, err = w.Write([]byte("1\n"))
+		require.NoError(t, err)
+
+		backups, err = w.Backups()
+		require.NoError(t, err)
+		require.Len(t, backups, 1)
+		assert.Equal(t, "12345\n123\n", readFile(t, backups[0]))
+		assert.Equal(t, "1\n", readFile(t, path))
+	})
+
+	t.Run("rotates when the period has elapsed", func(t *testing.T) {
+		w, clk, path, cleanup := newWriter(t, 0, time.Hour, 0)
+		defer cleanup()
+
+		_, err := w.Write([]byte("first\n"))
+		require.NoError(t, err)
+		clk.Advance(59 * time.Minute)
+		_, err = w.Write([]byte("second\n"))
+		require.NoError(t, err)
+		clk.Advance(time.Minute)
+		_, err = w.Write([]byte("third\n"))
+		require.NoError(t, err)
+
+		backups, err := w.Backups()
+		require.NoError(t, err)
+		require.Len(t, backups, 1)
+		assert.Equal(t, "first\nsecond\n", readFile(t, backups[0]))
+		assert.Equal(t, "third\n", readFile(t, path))
+	})
+
+	t.Run("keeps the newest backups", func(t *testing.T) {
+		w, clk, _, cleanup := newWriter(t, 1, 0, 2)
+		defer cleanup()
+
+		for _, line := range []string{"a\n", "b\n", "c\n", "d\n"} {
+			_, err := w.Write([]byte(line))
+			require.NoError(t, err)
+			clk.Advance(time.Second)
+		}
+
+		backups, err := w.Backups()
+		require.NoError(t, err)
+		require.Len(t, backups, 2)
+		assert.Equal(t, "b\n", readFile(t, backups[0]))
+		assert.Equal(t, "c\n", readFile(t, backups[1]))
+	})
+
+	t.Run("appends to an existing file", func(t *testing.T) {
+		w, clk, path, cleanup := newWriter(t, 0, 0, 0)
+		defer cleanup()
+		_, err := w.Write([]byte("before\n"))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		w, err = logfile.NewWriter(path, 0, 0, 0, clk)
+		require.NoError(t, err)
+		defer func() { _ = w.Close() }()
+		_, err = w.Write([]byte("after\n"))
+		require.NoError(t, err)
+		assert.Equal(t, "before\nafter\n", readFile(t, path))
+	})
+}
@@ -0,0 +1,84 @@
+package message
+
+import (
+	"context"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/actor"
+)
+
+// nonceTracker assigns nonces to the messages sent from the outbox. Callers
+// sending from the same address are serialized, so that concurrent API calls
+// are given consecutive nonces, while senders from different addresses do not
+// wait for each other.
+type nonceTracker struct {
+	queue *Queue
+
+	lk      sync.Mutex
+	senders map[address.Address]*senderLock
+}
+
+// senderLock is the lock of an address, counting the callers holding or
+// waiting for it so it is released once unused.
+type senderLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newNonceTracker(queue *Queue) *nonceTracker {
+	return &nonceTracker{
+		queue:   queue,
+		senders: make(map[address.Address]*senderLock),
+	}
+}
+
+// lock waits for the other callers sending from `sender` and returns the
+// function unlocking it. Nonces of `sender` must be assigned and the message
+// enqueued before unlocking.
+func (nt *nonceTracker) lock(sender address.Address) func() {
+	nt.lk.Lock()
+	l, ok := nt.senders[sender]
+	if !ok {
+		l = &senderLock{}
+		nt.senders[sender] = l
+	}
+	l.refs++
+	nt.lk.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		nt.lk.Lock()
+		defer nt.lk.Unlock()
+		l.refs--
+		if l.refs == 0 {
+			delete(nt.senders, sender)
+		}
+	}
+}
+
+// next returns the nonce of the next message from `sender`, whose actor at the
+// head is `act`. This is one greater than the largest nonce queued, unless the
+// actor has already used the queued nonces, e.g. when the messages were mined
+// by another node using the same key or when a re-org included them in a
+// different order. These messages can no longer be mined and are dropped from
+// the queue, so that the next message follows the actor's nonce rather than
+// being rejected by the network. Callers must hold the lock of `sender`.
+func (nt *nonceTracker) next(ctx context.Context, act *actor.Actor, sender address.Address) (uint64, error) {
+	actorNonce, err := actor.NextNonce(act)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, msg := range nt.queue.DropBelow(ctx, sender, actorNonce) {
+		log.Warnf("Dropped outbound message %v with nonce %d, already used by %s", msg, msg.Message.CallSeqNum, sender)
+	}
+
+	queueNonce, found := nt.queue.LargestNonce(sender)
+	if found && queueNonce >= actorNonce {
+		return queueNonce + 1, nil
+	}
+	return actorNonce, nil
+}
@@ -6,11 +6,13 @@ import (
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
 	"github.com/ipfs/go-cid"
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/errcode"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/journal"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/metrics"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
@@ -39,8 +41,10 @@ type Outbox struct {
 	chains chainProvider
 	actors actorProvider
 
-	// Protects the "next nonce" calculation to avoid collisions.
-	nonceLock sync.Mutex
+	// Assigns nonces, serializing the senders from each address to avoid collisions.
+	nonces *nonceTracker
+	// Counts messages being published.
+	publishing sync.WaitGroup
 
 	journal journal.Writer
 }
@@ -72,6 +76,7 @@ func NewOutbox(signer types.Signer, validator messageValidator, queue *Queue,
 		policy:    policy,
 		chains:    chains,
 		actors:    actors,
+		nonces:    newNonceTracker(queue),
 		journal:   jw,
 	}
 }
@@ -107,43 +112,63 @@ func (ob *Outbox) SendEncoded(ctx context.Context, from, to address.Address, val
 			"encodedParams", encodedParams, "error", err, "cid", out.String())
 	}()
 
+	// Lock to avoid a race inspecting the actor state and message queue to calculate next nonce.
+	defer ob.nonces.lock(from)()
+
+	signed, err := ob.prepare(ctx, from, to, value, gasPrice, gasLimit, method, encodedParams)
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+	if err := ob.checkFunds(ctx, &signed.Message); err != nil {
+		return cid.Undef, nil, err
+	}
+
+	return sendSignedMsg(ctx, ob, signed, bcast)
+}
+
+// Prepare builds and signs the message SendEncoded would send, with the next
+// nonce of the sender, without queueing or publishing it.
+func (ob *Outbox) Prepare(ctx context.Context, from, to address.Address, value types.AttoFIL,
+	gasPrice types.AttoFIL, gasLimit gas.Unit, method abi.MethodNum, encodedParams []byte) (*types.SignedMessage, error) {
+	defer ob.nonces.lock(from)()
+	return ob.prepare(ctx, from, to, value, gasPrice, gasLimit, method, encodedParams)
+}
+
+// prepare builds and signs a message. Callers must hold the nonce lock of `from`.
+func (ob *Outbox) prepare(ctx context.Context, from, to address.Address, value types.AttoFIL,
+	gasPrice types.AttoFIL, gasLimit gas.Unit, method abi.MethodNum, encodedParams []byte) (*types.SignedMessage, error) {
 	// The spec's message syntax validation rules restricts empty parameters
 	//  to be encoded as an empty byte string not cbor null
 	if encodedParams == nil {
 		encodedParams = []byte{}
 	}
 
-	// Lock to avoid a race inspecting the actor state and message queue to calculate next nonce.
-	ob.nonceLock.Lock()
-	defer ob.nonceLock.Unlock()
-
 	head := ob.chains.GetHead()
 
 	fromActor, err := ob.actors.GetActorAt(ctx, head, from)
 	if err != nil {
-		return cid.Undef, nil, errors.Wrapf(err, "no actor at address %s", from)
+		return nil, errors.Wrapf(err, "no actor at address %s", from)
 	}
 
-	nonce, err := nextNonce(fromActor, ob.queue, from)
+	nonce, err := ob.nonces.next(ctx, fromActor, from)
 	if err != nil {
-		return cid.Undef, nil, errors.Wrapf(err, "failed calculating nonce for actor at %s", from)
+		return nil, errors.Wrapf(err, "failed calculating nonce for actor at %s", from)
 	}
 
 	rawMsg := types.NewMeteredMessage(from, to, nonce, value, method, encodedParams, gasPrice, gasLimit)
 	signed, err := types.NewSignedMessage(ctx, *rawMsg, ob.signer)
 
 	if err != nil {
-		return cid.Undef, nil, errors.Wrap(err, "failed to sign message")
+		return nil, errors.Wrap(err, "failed to sign message")
 	}
 
 	// Slightly awkward: it would be better validate before signing but the MeteredMessage construction
 	// is hidden inside NewSignedMessage.
 	err = ob.validator.ValidateSignedMessageSyntax(ctx, signed)
 	if err != nil {
-		return cid.Undef, nil, errors.Wrap(err, "invalid message")
+		return nil, errors.Wrap(err, "invalid message")
 	}
-
-	return sendSignedMsg(ctx, ob, signed, bcast)
+	return signed, nil
 }
 
 // SignedSend send a signed message, retaining it in the outbound message queue.
@@ -155,9 +180,31 @@ func (ob *Outbox) SignedSend(ctx context.Context, signed *types.SignedMessage, b
 		}
 	}()
 
+	// The message is enqueued after those being sent from the same address.
+	defer ob.nonces.lock(signed.Message.From)()
+	if err := ob.checkFunds(ctx, &signed.Message); err != nil {
+		return cid.Undef, nil, err
+	}
 	return sendSignedMsg(ctx, ob, signed, bcast)
 }
 
+// checkFunds checks that the balance of the sender of `msg` at the head covers
+// the value and the gas limit of the message, so that messages failing for
+// insufficient funds are rejected with an InsufficientFunds error rather than
+// when applied.
+func (ob *Outbox) checkFunds(ctx context.Context, msg *types.UnsignedMessage) error {
+	fromActor, err := ob.actors.GetActorAt(ctx, ob.chains.GetHead(), msg.From)
+	if err != nil {
+		return errors.Wrapf(err, "no actor at address %s", msg.From)
+	}
+	cost := big.Add(msg.Value, big.Mul(msg.GasPrice, big.NewInt(int64(msg.GasLimit))))
+	if fromActor.Balance.LessThan(cost) {
+		return errcode.Errorf(errcode.InsufficientFunds, "insufficient funds from sender %s, balance %s does not cover value and gas cost %s",
+			msg.From, fromActor.Balance, cost)
+	}
+	return nil
+}
+
 // sendSignedMsg add signed message in pool and return cid
 func sendSignedMsg(ctx context.Context, ob *Outbox, signed *types.SignedMessage, bcast bool) (cid.Cid, chan error, error) {
 	head := ob.chains.GetHead()
@@ -184,11 +231,14 @@ func sendSignedMsg(ctx context.Context, ob *Outbox, signed *types.SignedMessage,
 	}
 	pubErrCh := make(chan error)
 
+	ob.publishing.Add(1)
 	go func() {
 		err = ob.publisher.Publish(ctx, signed, height, bcast)
 		if err != nil {
 			log.Errorf("error: %s publishing message %s", err, c.String())
 		}
+		// done before reporting, as callers need not receive the error
+		ob.publishing.Done()
 		pubErrCh <- err
 		close(pubErrCh)
 	}()
@@ -196,24 +246,46 @@ func sendSignedMsg(ctx context.Context, ob *Outbox, signed *types.SignedMessage,
 	return c, pubErrCh, nil
 }
 
+// Flush waits for the messages being published to be handed to the network, so
+// that messages sent just before the node stops are not lost. It returns the
+// error of ctx if it is done first.
+func (ob *Outbox) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		ob.publishing.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // HandleNewHead maintains the message queue in response to a new head tipset.
+// Messages of abandoned tipsets are only returned to the queue when sent from
+// addresses of this node's signer.
 func (ob *Outbox) HandleNewHead(ctx context.Context, oldTips, newTips []block.TipSet) error {
-	return ob.policy.HandleNewHead(ctx, ob.queue, oldTips, newTips)
+	return ob.policy.HandleNewHead(ctx, &signerTarget{ob.queue, ob.signer}, oldTips, newTips)
+}
+
+// signerTarget is a policy target ignoring the messages requeued from senders
+// the signer has no key for, which this node did not send.
+type signerTarget struct {
+	*Queue
+	signer types.Signer
 }
 
-// nextNonce returns the next expected nonce value for an account actor. This is the larger
-// of the actor's nonce value, or one greater than the largest nonce from the actor found in the message queue.
-func nextNonce(act *actor.Actor, queue *Queue, address address.Address) (uint64, error) {
-	actorNonce, err := actor.NextNonce(act)
+func (t *signerTarget) Requeue(ctx context.Context, msg *types.SignedMessage, stamp uint64) error {
+	has, err := t.signer.HasAddress(ctx, msg.Message.From)
 	if err != nil {
-		return 0, err
+		return err
 	}
-
-	poolNonce, found := queue.LargestNonce(address)
-	if found && poolNonce >= actorNonce {
-		return poolNonce + 1, nil
+	if !has {
+		return nil
 	}
-	return actorNonce, nil
+	return t.Queue.Requeue(ctx, msg, stamp)
 }
 
 func tipsetHeight(provider chainProvider, key block.TipSetKey) (abi.ChainEpoch, error) {
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/specs-actors/actors/abi"
 	"github.com/filecoin-project/specs-actors/actors/builtin"
 	"github.com/filecoin-project/specs-actors/actors/util/adt"
@@ -16,10 +17,12 @@ import (
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/errcode"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/journal"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/message"
 	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/actor"
 	vmaddr "github.com/filecoin-project/go-filecoin/internal/pkg/vm/address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
@@ -29,6 +32,16 @@ func newOutboxTestJournal(t *testing.T) journal.Writer {
 	return journal.NewInMemoryJournal(t, clock.NewFake(time.Unix(1234567890, 0))).Topic("outbox")
 }
 
+// keyedSigner is a mock signer having only the addresses it has keys for.
+type keyedSigner struct {
+	types.MockSigner
+}
+
+func (s keyedSigner) HasAddress(_ context.Context, addr address.Address) (bool, error) {
+	_, ok := s.AddrKeyInfo[addr]
+	return ok, nil
+}
+
 func TestOutbox(t *testing.T) {
 	tf.UnitTest(t)
 
@@ -144,6 +157,109 @@ func TestOutbox(t *testing.T) {
 		}
 	})
 
+	t.Run("senders from different addresses are given independent nonces", func(t *testing.T) {
+		ctx := context.Background()
+		w := types.NewMockSigner(append(types.MustGenerateKeyInfo(1, 42), types.MustGenerateKeyInfo(1, 7)...))
+		require.NotEqual(t, w.Addresses[0], w.Addresses[1])
+		toAddr := vmaddr.NewForTestGetter()()
+		queue := message.NewQueue()
+		publisher := &message.MockPublisher{}
+		provider := message.NewFakeProvider(t)
+
+		head := provider.NewGenesis()
+		provider.SetHead(head.Key())
+		for _, sender := range w.Addresses {
+			provider.SetActor(sender, actor.NewActor(builtin.AccountActorCodeID, abi.NewTokenAmount(0), cid.Undef))
+		}
+
+		ob := message.NewOutbox(w, message.FakeValidator{}, queue, publisher, message.NullPolicy{}, provider, provider, newOutboxTestJournal(t))
+
+		var wg sync.WaitGroup
+		for _, sender := range w.Addresses {
+			for i := 0; i < 2; i++ {
+				wg.Add(1)
+				go func(sender address.Address) {
+					defer wg.Done()
+					for j := 0; j < 10; j++ {
+						_, _, err := ob.Send(ctx, sender, toAddr, types.ZeroAttoFIL, types.NewGasPrice(0), gas.NewGas(0), true, builtin.MethodSend, adt.Empty)
+						require.NoError(t, err)
+					}
+				}(sender)
+			}
+		}
+		wg.Wait()
+
+		for _, sender := range w.Addresses {
+			enqueued := queue.List(sender)
+			require.Len(t, enqueued, 20)
+			for i, qm := range enqueued {
+				assert.Equal(t, uint64(i), qm.Msg.Message.CallSeqNum)
+			}
+		}
+	})
+
+	t.Run("send message skips nonces already used by the actor", func(t *testing.T) {
+		ctx := context.Background()
+		w, _ := types.NewMockSignersAndKeyInfo(1)
+		sender := w.Addresses[0]
+		toAddr := vmaddr.NewForTestGetter()()
+		queue := message.NewQueue()
+		publisher := &message.MockPublisher{}
+		provider := message.NewFakeProvider(t)
+
+		head := provider.NewGenesis()
+		actr := actor.NewActor(builtin.AccountActorCodeID, abi.NewTokenAmount(0), cid.Undef)
+		provider.SetHeadAndActor(t, head.Key(), sender, actr)
+
+		ob := message.NewOutbox(w, message.FakeValidator{}, queue, publisher, message.NullPolicy{}, provider, provider, newOutboxTestJournal(t))
+		for i := 0; i < 3; i++ {
+			_, _, err := ob.Send(ctx, sender, toAddr, types.ZeroAttoFIL, types.NewGasPrice(0), gas.NewGas(0), true, builtin.MethodSend, adt.Empty)
+			require.NoError(t, err)
+		}
+
+		// The first two nonces are used by messages from elsewhere.
+		actr.CallSeqNum = 2
+		_, _, err := ob.Send(ctx, sender, toAddr, types.ZeroAttoFIL, types.NewGasPrice(0), gas.NewGas(0), true, builtin.MethodSend, adt.Empty)
+		require.NoError(t, err)
+		enqueued := queue.List(sender)
+		require.Len(t, enqueued, 2)
+		assert.Equal(t, uint64(2), enqueued[0].Msg.Message.CallSeqNum)
+		assert.Equal(t, uint64(3), enqueued[1].Msg.Message.CallSeqNum)
+
+		// All queued nonces have been used.
+		actr.CallSeqNum = 7
+		_, _, err = ob.Send(ctx, sender, toAddr, types.ZeroAttoFIL, types.NewGasPrice(0), gas.NewGas(0), true, builtin.MethodSend, adt.Empty)
+		require.NoError(t, err)
+		enqueued = queue.List(sender)
+		require.Len(t, enqueued, 1)
+		assert.Equal(t, uint64(7), enqueued[0].Msg.Message.CallSeqNum)
+	})
+
+	t.Run("new head requeues only messages from the signer's addresses", func(t *testing.T) {
+		ctx := context.Background()
+		w, ki := types.NewMockSignersAndKeyInfo(1)
+		sender := w.Addresses[0]
+		queue := message.NewQueue()
+		provider := message.NewFakeProvider(t)
+		policy := message.NewMessageQueuePolicy(provider, 10)
+
+		// The message maker also signs for an address the outbox's signer has no key for.
+		mm := vm.NewMessageMaker(t, append(ki, types.MustGenerateKeyInfo(1, 7)...))
+		other := mm.Addresses()[1]
+		require.NotEqual(t, sender, other)
+
+		root := provider.NewGenesis()
+		oldTip := provider.BuildOneOn(root, func(b *chain.BlockBuilder) {
+			b.AddMessages([]*types.SignedMessage{mm.NewSignedMessage(sender, 0), mm.NewSignedMessage(other, 0)}, []*types.UnsignedMessage{})
+		})
+		newTip := provider.AppendOn(root, 1)
+
+		ob := message.NewOutbox(keyedSigner{w}, message.FakeValidator{}, queue, &message.MockPublisher{}, policy, provider, provider, newOutboxTestJournal(t))
+		require.NoError(t, ob.HandleNewHead(ctx, []block.TipSet{oldTip}, []block.TipSet{newTip}))
+		assert.Len(t, queue.List(sender), 1)
+		assert.Empty(t, queue.List(other))
+	})
+
 	t.Run("fails with non-account actor", func(t *testing.T) {
 		w, _ := types.NewMockSignersAndKeyInfo(1)
 		sender := w.Addresses[0]
@@ -162,4 +278,86 @@ func TestOutbox(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "account or empty")
 	})
+
+	t.Run("messages the sender cannot pay for are rejected", func(t *testing.T) {
+		ctx := context.Background()
+		w, _ := types.NewMockSignersAndKeyInfo(1)
+		sender := w.Addresses[0]
+		toAddr := vmaddr.NewForTestGetter()()
+		queue := message.NewQueue()
+		publisher := &message.MockPublisher{}
+		provider := message.NewFakeProvider(t)
+
+		head := provider.NewGenesis()
+		actr := actor.NewActor(builtin.AccountActorCodeID, types.NewAttoFILFromFIL(1), cid.Undef)
+		provider.SetHeadAndActor(t, head.Key(), sender, actr)
+
+		ob := message.NewOutbox(w, message.FakeValidator{}, queue, publisher, message.NullPolicy{}, provider, provider, newOutboxTestJournal(t))
+		_, _, err := ob.Send(ctx, sender, toAddr, types.NewAttoFILFromFIL(2), types.NewGasPrice(0), gas.NewGas(0), true, builtin.MethodSend, adt.Empty)
+		require.Error(t, err)
+		assert.Equal(t, errcode.InsufficientFunds, errcode.CodeOf(err))
+		assert.Empty(t, queue.List(sender))
+
+		_, _, err = ob.Send(ctx, sender, toAddr, types.NewAttoFILFromFIL(1), types.NewGasPrice(0), gas.NewGas(0), true, builtin.MethodSend, adt.Empty)
+		assert.NoError(t, err)
+	})
+
+	t.Run("prepare signs the next message without queueing it", func(t *testing.T) {
+		ctx := context.Background()
+		w, _ := types.NewMockSignersAndKeyInfo(1)
+		sender := w.Addresses[0]
+		toAddr := vmaddr.NewForTestGetter()()
+		queue := message.NewQueue()
+		publisher := &message.MockPublisher{}
+		provider := message.NewFakeProvider(t)
+
+		head := provider.BuildOneOn(block.UndefTipSet, func(b *chain.BlockBuilder) {
+			b.IncHeight(1000)
+		})
+		actr := actor.NewActor(builtin.AccountActorCodeID, abi.NewTokenAmount(0), cid.Undef)
+		actr.CallSeqNum = 42
+		provider.SetHeadAndActor(t, head.Key(), sender, actr)
+
+		ob := message.NewOutbox(w, message.FakeValidator{}, queue, publisher, message.NullPolicy{}, provider, provider, newOutboxTestJournal(t))
+		_, _, err := ob.Send(ctx, sender, toAddr, types.ZeroAttoFIL, types.NewGasPrice(0), gas.NewGas(0), true, builtin.MethodSend, adt.Empty)
+		require.NoError(t, err)
+
+		smsg, err := ob.Prepare(ctx, sender, toAddr, types.NewAttoFILFromFIL(2), types.NewGasPrice(1), gas.NewGas(1000), builtin.MethodSend, nil)
+		require.NoError(t, err)
+		assert.Equal(t, actr.CallSeqNum+1, smsg.Message.CallSeqNum)
+		assert.Equal(t, []byte{}, smsg.Message.Params)
+		assert.NotEmpty(t, smsg.Signature.Data)
+		assert.Len(t, queue.List(sender), 1)
+
+		// the prepared nonce is not taken
+		smsg2, err := ob.Prepare(ctx, sender, toAddr, types.ZeroAttoFIL, types.NewGasPrice(1), gas.NewGas(1000), builtin.MethodSend, nil)
+		require.NoError(t, err)
+		assert.Equal(t, smsg.Message.CallSeqNum, smsg2.Message.CallSeqNum)
+	})
+
+	t.Run("flush waits for messages being published", func(t *testing.T) {
+		ctx := context.Background()
+		w, _ := types.NewMockSignersAndKeyInfo(1)
+		sender := w.Addresses[0]
+		toAddr := vmaddr.NewForTestGetter()()
+		queue := message.NewQueue()
+		publisher := &message.MockPublisher{}
+		provider := message.NewFakeProvider(t)
+
+		head := provider.BuildOneOn(block.UndefTipSet, func(b *chain.BlockBuilder) {
+			b.IncHeight(1000)
+		})
+		actr := actor.NewActor(builtin.AccountActorCodeID, abi.NewTokenAmount(0), cid.Undef)
+		provider.SetHeadAndActor(t, head.Key(), sender, actr)
+
+		ob := message.NewOutbox(w, message.FakeValidator{}, queue, publisher, message.NullPolicy{}, provider, provider, newOutboxTestJournal(t))
+		// the publication error is never received
+		_, _, err := ob.Send(ctx, sender, toAddr, types.ZeroAttoFIL, types.NewGasPrice(0), gas.NewGas(0), true, builtin.MethodSend, adt.Empty)
+		require.NoError(t, err)
+
+		flushCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		require.NoError(t, ob.Flush(flushCtx))
+		assert.NotNil(t, publisher.Message)
+	})
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
 	logging "github.com/ipfs/go-log/v2"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
@@ -40,8 +41,9 @@ type PolicyTarget interface {
 // Messages are removed from the queue as soon as they appear in a block that's part of a heaviest chain.
 // At this point, messages are highly likely to be valid and known to a large number of nodes,
 // even if the block ends up as an abandoned fork.
-// There is no special handling for re-orgs and messages do not revert to the queue if the block
-// ends up childless (in contrast to the message pool).
+// On a re-org, messages of the abandoned tipsets revert to the queue unless also mined in the new
+// chain, but messages do not revert to the queue if the block ends up childless (in contrast to the
+// message pool).
 type DefaultQueuePolicy struct {
 	// Provides messages collections from cids.
 	messageProvider messageProvider
@@ -62,48 +64,63 @@ func (p *DefaultQueuePolicy) HandleNewHead(ctx context.Context, target PolicyTar
 		return err
 	}
 
-	// Remove all messages in the new chain from the queue since they have been mined into blocks.
-	// Rearrange the tipsets into ascending height order so messages are discovered in nonce order.
-	chain.Reverse(newTips)
-	for _, tipset := range newTips {
-		for i := 0; i < tipset.Len(); i++ {
+	// Return messages from the old chain back to the queue. This is necessary so that the next nonce
+	// implied by the queue+state matches that of the message pool (which will also have the un-mined
+	// message re-instated).
+	// This is done before removing the messages of the new chain, so that messages included in both
+	// chains end up removed.
+	// Note that the target may be given messages that were never sent by this node since the queue
+	// doesn't keep track of "allowed" senders. Such messages expire harmlessly, or are ignored by the
+	// outbox.
+	// See discussion in https://github.com/filecoin-project/go-filecoin/issues/3052
+	// Traverse these in descending height order and, within a tipset, in reverse so that each sender's
+	// messages are requeued in descending nonce order.
+	requeued := make(map[cid.Cid]struct{})
+	for _, tipset := range oldTips {
+		for i := tipset.Len() - 1; i >= 0; i-- {
 			secpMsgs, _, err := p.messageProvider.LoadMessages(ctx, tipset.At(i).Messages.Cid)
 			if err != nil {
 				return err
 			}
-			for _, minedMsg := range secpMsgs {
-				removed, found, err := target.RemoveNext(ctx, minedMsg.Message.From, minedMsg.Message.CallSeqNum)
+			for j := len(secpMsgs) - 1; j >= 0; j-- {
+				restoredMsg := secpMsgs[j]
+				c, err := restoredMsg.Cid()
 				if err != nil {
 					return err
 				}
-				if found && !minedMsg.Equals(removed) {
-					log.Warnf("Queued message %v differs from mined message %v with same sender & nonce", removed, minedMsg)
+				// The blocks of a tipset may include the same message.
+				if _, ok := requeued[c]; ok {
+					continue
+				}
+				requeued[c] = struct{}{}
+				// A message that does not precede the queued messages of its sender would leave a gap
+				// in the nonces, skip it rather than failing to handle the rest of the re-org.
+				if err := target.Requeue(ctx, restoredMsg, uint64(chainHeight)); err != nil {
+					log.Warnf("Not requeueing message %s from abandoned tipset: %s", c, err)
 				}
-				// Else if not found, the message was not sent by this node, or has already been removed
-				// from the queue (e.g. a blockchain re-org).
 			}
 		}
 	}
 
-	// Return messages from the old chain back to the queue. This is necessary so that the next nonce
-	// implied by the queue+state matches that of the message pool (which will also have the un-mined
-	// message re-instated).
-	// Note that this will include messages that were never sent by this node since the queue doesn't
-	// keep track of "allowed" senders. However, messages from other addresses will expire
-	// harmlessly.
-	// See discussion in https://github.com/filecoin-project/go-filecoin/issues/3052
-	// Traverse these in descending height order.
-	for _, tipset := range oldTips {
+	// Remove all messages in the new chain from the queue since they have been mined into blocks.
+	// Rearrange the tipsets into ascending height order so messages are discovered in nonce order.
+	chain.Reverse(newTips)
+	for _, tipset := range newTips {
 		for i := 0; i < tipset.Len(); i++ {
 			secpMsgs, _, err := p.messageProvider.LoadMessages(ctx, tipset.At(i).Messages.Cid)
 			if err != nil {
 				return err
 			}
-			for _, restoredMsg := range secpMsgs {
-				err := target.Requeue(ctx, restoredMsg, uint64(chainHeight))
+			for _, minedMsg := range secpMsgs {
+				removed, found, err := target.RemoveNext(ctx, minedMsg.Message.From, minedMsg.Message.CallSeqNum)
 				if err != nil {
 					return err
 				}
+				if found && !minedMsg.Equals(removed) {
+					log.Warnf("Queued message %v differs from mined message %v with same sender & nonce", removed, minedMsg)
+				}
+				// Else if not found, the message was not sent by this node, or has already been removed
+				// from the queue (e.g. a blockchain re-org).
 			}
 		}
 	}
@@ -173,6 +173,42 @@ func TestMessageQueuePolicy(t *testing.T) {
 		assert.Equal(t, qm(msgs[3], 200), q.List(bob)[0]) // Bob's remain
 	})
 
+	t.Run("requeues messages of abandoned tipsets", func(t *testing.T) {
+		blocks := chain.NewBuilder(t, alice)
+		q := message.NewQueue()
+		policy := message.NewMessageQueuePolicy(blocks, 10)
+
+		fromAlice := []*types.SignedMessage{
+			mm.NewSignedMessage(alice, 1),
+			mm.NewSignedMessage(alice, 2),
+			mm.NewSignedMessage(alice, 3),
+		}
+		requireEnqueue(q, fromAlice[2], 100)
+
+		root := blocks.BuildOneOn(block.UndefTipSet, func(b *chain.BlockBuilder) {
+			b.IncHeight(100)
+		})
+		// Both blocks of the abandoned tipset include alice's second message.
+		oldTip := blocks.BuildOn(root, 2, func(b *chain.BlockBuilder, i int) {
+			msgs := []*types.SignedMessage{fromAlice[1]}
+			if i == 0 {
+				msgs = []*types.SignedMessage{fromAlice[0], fromAlice[1]}
+			}
+			b.AddMessages(msgs, []*types.UnsignedMessage{})
+		})
+		newTip := blocks.BuildOneOn(root, func(b *chain.BlockBuilder) {
+			b.AddMessages([]*types.SignedMessage{fromAlice[0]}, []*types.UnsignedMessage{})
+		})
+
+		err := policy.HandleNewHead(ctx, q, []block.TipSet{oldTip}, []block.TipSet{newTip})
+		require.NoError(t, err)
+		// The message mined in both chains is removed, the others are queued in nonce order.
+		queued := q.List(alice)
+		require.Len(t, queued, 2)
+		assert.Equal(t, fromAlice[1], queued[0].Msg)
+		assert.Equal(t, fromAlice[2], queued[1].Msg)
+	})
+
 	t.Run("fails when messages out of nonce order", func(t *testing.T) {
 		blocks := chain.NewBuilder(t, alice)
 		messages := blocks
@@ -35,6 +35,26 @@ type Pool struct {
 	validator     PoolValidator
 	pending       map[cid.Cid]*timedmessage // all pending messages
 	addressNonces map[addressNonce]bool     // set of address nonce pairs used to efficiently validate duplicate nonces
+	watchers      map[int]func(PoolEvent)
+	nextWatcher   int
+}
+
+// PoolEventType tells whether a message was added to or removed from the pool.
+type PoolEventType string
+
+const (
+	// PoolAdded is the type of the event of a message added to the pool.
+	PoolAdded = PoolEventType("added")
+	// PoolRemoved is the type of the event of a message removed from the pool,
+	// because it was mined, expired or removed by the user.
+	PoolRemoved = PoolEventType("removed")
+)
+
+// PoolEvent is a change of the messages of the pool.
+type PoolEvent struct {
+	Type    PoolEventType
+	Cid     cid.Cid
+	Message *types.SignedMessage
 }
 
 type timedmessage struct {
@@ -58,6 +78,7 @@ func NewPool(cfg *config.MessagePoolConfig, validator PoolValidator) *Pool {
 		validator:     validator,
 		pending:       make(map[cid.Cid]*timedmessage),
 		addressNonces: make(map[addressNonce]bool),
+		watchers:      make(map[int]func(PoolEvent)),
 	}
 }
 
@@ -85,9 +106,26 @@ func (pool *Pool) Add(ctx context.Context, msg *types.SignedMessage, height abi.
 	pool.pending[c] = &timedmessage{message: msg, addedAt: height}
 	pool.addressNonces[newAddressNonce(msg)] = true
 	mpSize.Set(ctx, int64(len(pool.pending)))
+	pool.notifyLocked(PoolEvent{Type: PoolAdded, Cid: c, Message: msg})
 	return c, nil
 }
 
+// Watch calls `watcher` each time a message is added to or removed from the
+// pool, until the returned function is called. The watcher is called while the
+// pool is locked and must not block.
+func (pool *Pool) Watch(watcher func(PoolEvent)) func() {
+	pool.lk.Lock()
+	defer pool.lk.Unlock()
+	id := pool.nextWatcher
+	pool.nextWatcher++
+	pool.watchers[id] = watcher
+	return func() {
+		pool.lk.Lock()
+		defer pool.lk.Unlock()
+		delete(pool.watchers, id)
+	}
+}
+
 // Pending returns all pending messages.
 func (pool *Pool) Pending() []*types.SignedMessage {
 	pool.lk.Lock()
@@ -122,6 +160,7 @@ func (pool *Pool) Remove(c cid.Cid) {
 	if ok {
 		delete(pool.addressNonces, newAddressNonce(msg.message))
 		delete(pool.pending, c)
+		pool.notifyLocked(PoolEvent{Type: PoolRemoved, Cid: c, Message: msg.message})
 	}
 
 	mpSize.Set(context.TODO(), int64(len(pool.pending)))
@@ -155,6 +194,12 @@ func (pool *Pool) PendingBefore(minimumHeight abi.ChainEpoch) []cid.Cid {
 	return cids
 }
 
+func (pool *Pool) notifyLocked(event PoolEvent) {
+	for _, w := range pool.watchers {
+		w(event)
+	}
+}
+
 // validateMessage validates that too many messages aren't added to the pool and the ones that are
 // have a high probability of making it through processing.
 func (pool *Pool) validateMessage(ctx context.Context, message *types.SignedMessage) error {
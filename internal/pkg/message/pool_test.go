@@ -60,6 +60,39 @@ func TestMessagePoolAddRemove(t *testing.T) {
 	assert.Len(t, pool.Pending(), 0)
 }
 
+func TestMessagePoolWatch(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	pool := message.NewPool(config.NewDefaultConfig().Mpool, th.NewMockMessagePoolValidator())
+	msg1 := newSignedMessage()
+	msg2 := mustSetNonce(mockSigner, newSignedMessage(), 1)
+
+	var events []message.PoolEvent
+	unwatch := pool.Watch(func(e message.PoolEvent) {
+		events = append(events, e)
+	})
+
+	c1, err := pool.Add(ctx, msg1, 0)
+	require.NoError(t, err)
+	// Adding a message twice or removing one not in the pool changes nothing.
+	_, err = pool.Add(ctx, msg1, 0)
+	require.NoError(t, err)
+	c2, err := msg2.Cid()
+	require.NoError(t, err)
+	pool.Remove(c2)
+	pool.Remove(c1)
+
+	assert.Equal(t, []message.PoolEvent{
+		{Type: message.PoolAdded, Cid: c1, Message: msg1},
+		{Type: message.PoolRemoved, Cid: c1, Message: msg1},
+	}, events)
+
+	unwatch()
+	reqAdd(t, pool, 0, msg2)
+	assert.Len(t, events, 2)
+}
+
 func TestMessagePoolValidate(t *testing.T) {
 	tf.UnitTest(t)
 
@@ -134,6 +134,32 @@ func (mq *Queue) Clear(ctx context.Context, sender address.Address) bool {
 	return len(q) > 0
 }
 
+// DropBelow removes the messages of a sender with nonces less than `nonce`, which can no longer be
+// mined because the sender's actor has already used those nonces.
+// Returns the removed messages.
+func (mq *Queue) DropBelow(ctx context.Context, sender address.Address, nonce uint64) []*types.SignedMessage {
+	defer func() {
+		mqSizeGa.Set(ctx, mq.Size())
+		mqOldestGa.Set(ctx, int64(mq.Oldest()))
+	}()
+
+	mq.lk.Lock()
+	defer mq.lk.Unlock()
+
+	q := mq.queues[sender]
+	var dropped []*types.SignedMessage
+	for len(q) > 0 && q[0].Msg.Message.CallSeqNum < nonce {
+		dropped = append(dropped, q[0].Msg)
+		q = q[1:]
+	}
+	if len(q) > 0 {
+		mq.queues[sender] = q
+	} else {
+		delete(mq.queues, sender)
+	}
+	return dropped
+}
+
 // ExpireBefore clears the queue of any sender where the first message in the queue has a stamp less than `stamp`.
 // Returns a map containing any expired address queues.
 func (mq *Queue) ExpireBefore(ctx context.Context, stamp uint64) map[address.Address][]*types.SignedMessage {
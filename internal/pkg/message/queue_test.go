@@ -234,6 +234,28 @@ func TestMessageQueue(t *testing.T) {
 		assertLargestNonce(q, alice, 1)
 	})
 
+	t.Run("drop below", func(t *testing.T) {
+		msgs := []*types.SignedMessage{
+			mm.NewSignedMessage(alice, 0),
+			mm.NewSignedMessage(alice, 1),
+			mm.NewSignedMessage(alice, 2),
+		}
+
+		q := message.NewQueue()
+		requireEnqueue(q, msgs[0], 0)
+		requireEnqueue(q, msgs[1], 0)
+		requireEnqueue(q, msgs[2], 0)
+
+		assert.Empty(t, q.DropBelow(ctx, alice, 0))
+		assert.Equal(t, []*types.SignedMessage{msgs[0], msgs[1]}, q.DropBelow(ctx, alice, 2))
+		assert.Equal(t, msgs[2], requireRemoveNext(q, alice, 2))
+
+		requireEnqueue(q, msgs[0], 0)
+		assert.Len(t, q.DropBelow(ctx, alice, 5), 1)
+		assertNoNonce(q, alice)
+		assert.Empty(t, q.Queues())
+	})
+
 	t.Run("independent addresses", func(t *testing.T) {
 		fromAlice := []*types.SignedMessage{
 			mm.NewSignedMessage(alice, 0),
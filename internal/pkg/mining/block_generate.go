@@ -50,10 +50,7 @@ func (w *DefaultWorker) Generate(
 
 	// Construct list of message candidates for inclusion.
 	// These messages will be processed, and those that fail excluded from the block.
-	pending := w.messageSource.Pending()
-	mq := NewMessageQueue(pending)
-	candidateMsgs := orderMessageCandidates(mq.Drain(block.BlockMessageLimit))
-	candidateMsgs = w.filterPenalizableMessages(ctx, candidateMsgs)
+	candidateMsgs, _ := w.selectMessages(ctx, baseTipSet)
 	if len(candidateMsgs) > block.BlockMessageLimit {
 		return nil, errors.Errorf("too many messages returned from mq.Drain: %d", len(candidateMsgs))
 	}
@@ -187,16 +184,73 @@ func orderMessageCandidates(messages []*types.SignedMessage) []*types.SignedMess
 	return append(blsMessages, secpMessages...)
 }
 
-func (w *DefaultWorker) filterPenalizableMessages(ctx context.Context, messages []*types.SignedMessage) []*types.SignedMessage {
+// excludedMessage is a pending message left out of a block and the reason why.
+type excludedMessage struct {
+	msg    *types.SignedMessage
+	reason string
+}
+
+// selectMessages chooses the pending messages to include in a block on top of
+// the base, in block order, and reports the pending messages that are left out.
+// Messages from priority senders are chosen first, then the remaining messages
+// by decreasing gas price.
+func (w *DefaultWorker) selectMessages(ctx context.Context, base block.TipSet) ([]*types.SignedMessage, []excludedMessage) {
+	priority := w.prioritySenders(ctx, base)
+	pending := w.messageSource.Pending()
+	mq := NewPriorityMessageQueue(pending, priority)
+	candidateMsgs := orderMessageCandidates(mq.Drain(block.BlockMessageLimit))
+	included, excluded := w.filterPenalizableMessages(ctx, candidateMsgs)
+	for _, msg := range mq.Drain(-1) {
+		excluded = append(excluded, excludedMessage{msg: msg, reason: "exceeds block message limit"})
+	}
+	return included, excluded
+}
+
+// prioritySenders returns the senders whose messages are selected before any
+// others: the configured priority addresses and, unless disabled, the miner's
+// owner and worker under both their ID and key addresses. Failing to read the
+// miner's addresses from the state is logged and only leaves its own messages
+// without priority, it never keeps a block from being mined.
+func (w *DefaultWorker) prioritySenders(ctx context.Context, base block.TipSet) []address.Address {
+	senders := append([]address.Address{}, w.priorityAddrs...)
+	if !w.prioritizeOwnMessages {
+		return senders
+	}
+
+	view, err := w.api.PowerStateView(base.Key())
+	if err != nil {
+		log.Warnf("selecting messages without priority for the miner's own: failed to read state view: %s", err)
+		return senders
+	}
+	owner, worker, err := view.MinerControlAddresses(ctx, w.minerAddr)
+	if err != nil {
+		log.Warnf("selecting messages without priority for the miner's own: failed to read miner control addresses: %s", err)
+		return senders
+	}
+	own := make([]address.Address, 0, 4)
+	for _, addr := range []address.Address{owner, worker} {
+		signer, err := view.AccountSignerAddress(ctx, addr)
+		if err != nil {
+			log.Warnf("selecting messages without priority for the miner's own: failed to resolve signing address of %s: %s", addr, err)
+			return senders
+		}
+		own = append(own, addr, signer)
+	}
+	return append(senders, own...)
+}
+
+func (w *DefaultWorker) filterPenalizableMessages(ctx context.Context, messages []*types.SignedMessage) ([]*types.SignedMessage, []excludedMessage) {
 	var goodMessages []*types.SignedMessage
+	var penalized []excludedMessage
 	for _, msg := range messages {
 		err := w.penaltyChecker.PenaltyCheck(ctx, &msg.Message)
 		if err != nil {
 			mCid, _ := msg.Cid()
 			log.Debugf("Msg: %s excluded in block because penalized with err %s", mCid, err)
+			penalized = append(penalized, excludedMessage{msg: msg, reason: "penalized: " + err.Error()})
 			continue
 		}
 		goodMessages = append(goodMessages, msg)
 	}
-	return goodMessages
+	return goodMessages, penalized
 }
@@ -13,7 +13,8 @@ import (
 
 // MessageQueue is a priority queue of messages from different actors. Messages are ordered
 // by decreasing gas price, subject to the constraint that messages from a single actor are
-// always in increasing nonce order.
+// always in increasing nonce order. Messages from priority senders are ordered before all
+// others.
 // All messages for a queue are inserted at construction, after which messages may only
 // be popped.
 // Potential improvements include:
@@ -27,6 +28,18 @@ type MessageQueue struct {
 
 // NewMessageQueue allocates and initializes a message queue.
 func NewMessageQueue(msgs []*types.SignedMessage) MessageQueue {
+	return NewPriorityMessageQueue(msgs, nil)
+}
+
+// NewPriorityMessageQueue allocates and initializes a message queue in which the messages
+// sent from `priority` addresses come first. Priority is per sender rather than per message
+// because a sender's messages can only be included in nonce order.
+func NewPriorityMessageQueue(msgs []*types.SignedMessage, priority []address.Address) MessageQueue {
+	prioritized := make(map[address.Address]bool, len(priority))
+	for _, addr := range priority {
+		prioritized[addr] = true
+	}
+
 	// Group messages by sender.
 	bySender := make(map[address.Address]nonceQueue)
 	for _, m := range msgs {
@@ -36,9 +49,9 @@ func NewMessageQueue(msgs []*types.SignedMessage) MessageQueue {
 	// Order each sender queue by nonce and initialize heap structure.
 	addrHeap := make(queueHeap, len(bySender))
 	heapIdx := 0
-	for _, nq := range bySender {
+	for from, nq := range bySender {
 		sort.Slice(nq, func(i, j int) bool { return nq[i].Message.CallSeqNum < nq[j].Message.CallSeqNum })
-		addrHeap[heapIdx] = nq
+		addrHeap[heapIdx] = senderQueue{msgs: nq, priority: prioritized[from]}
 		heapIdx++
 	}
 	heap.Init(&addrHeap)
@@ -60,14 +73,14 @@ func (mq *MessageQueue) Pop() (*types.SignedMessage, bool) {
 	bestQueue := &mq.senderQueues[0]
 
 	// Pop first message off that actor's queue
-	msg := (*bestQueue)[0]
-	if len(*bestQueue) == 1 {
+	msg := bestQueue.msgs[0]
+	if len(bestQueue.msgs) == 1 {
 		// If the actor's queue will become empty, remove it from the heap.
 		heap.Pop(&mq.senderQueues)
 	} else {
 		// If the actor's queue still has elements, remove the first and relocate the queue in the heap
 		// according to the gas price of its next message.
-		*bestQueue = (*bestQueue)[1:]
+		bestQueue.msgs = bestQueue.msgs[1:]
 		heap.Fix(&mq.senderQueues, 0)
 	}
 	return msg, true
@@ -76,8 +89,9 @@ func (mq *MessageQueue) Pop() (*types.SignedMessage, bool) {
 // Drain removes and returns all messages in a slice.  If max is < 0 returns all
 func (mq *MessageQueue) Drain(nToPop int) []*types.SignedMessage {
 	var out []*types.SignedMessage
-	for msg, hasMore := mq.Pop(); hasMore; msg, hasMore = mq.Pop() {
-		if nToPop == 0 {
+	for nToPop != 0 {
+		msg, hasMore := mq.Pop()
+		if !hasMore {
 			break
 		}
 		nToPop--
@@ -89,23 +103,33 @@ func (mq *MessageQueue) Drain(nToPop int) []*types.SignedMessage {
 // A slice of messages ordered by CallSeqNum (for a single sender).
 type nonceQueue []*types.SignedMessage
 
+// The queue of a single sender and whether that sender has priority.
+type senderQueue struct {
+	msgs     nonceQueue
+	priority bool
+}
+
 // Implements heap.Interface to hold a priority queue of nonce-ordered queues, one per sender.
-// Heap priority is given by the gas price of the first message for each queue.
+// Heap priority is given by the sender's priority, then the gas price of the first message
+// for each queue.
 // Each sender queue is expected to be ordered by increasing nonce.
 // Implementation is simplified from https://golang.org/pkg/container/heap/#example__priorityQueue.
-type queueHeap []nonceQueue
+type queueHeap []senderQueue
 
 func (pq queueHeap) Len() int { return len(pq) }
 
-// Less implements Heap.Interface.Less to compare items on gas price and sender address.
+// Less implements Heap.Interface.Less to compare items on priority, gas price and sender address.
 func (pq queueHeap) Less(i, j int) bool {
-	delta := specsbig.Sub(pq[i][0].Message.GasPrice, pq[j][0].Message.GasPrice)
+	if pq[i].priority != pq[j].priority {
+		return pq[i].priority
+	}
+	delta := specsbig.Sub(pq[i].msgs[0].Message.GasPrice, pq[j].msgs[0].Message.GasPrice)
 	if !delta.IsZero() {
 		// We want Pop to give us the highest gas price, so use GreaterThan.
 		return delta.GreaterThan(types.ZeroAttoFIL)
 	}
 	// Secondarily order by address to give a stable ordering.
-	return bytes.Compare(pq[i][0].Message.From.Bytes(), pq[j][0].Message.From.Bytes()) < 0
+	return bytes.Compare(pq[i].msgs[0].Message.From.Bytes(), pq[j].msgs[0].Message.From.Bytes()) < 0
 }
 
 func (pq queueHeap) Swap(i, j int) {
@@ -113,7 +137,7 @@ func (pq queueHeap) Swap(i, j int) {
 }
 
 func (pq *queueHeap) Push(x interface{}) {
-	item := x.(nonceQueue)
+	item := x.(senderQueue)
 	*pq = append(*pq, item)
 }
 
@@ -116,4 +116,30 @@ func TestMessageQueueOrder(t *testing.T) {
 		assert.Equal(t, expected, actual)
 		assert.False(t, q.Empty())
 	})
+
+	t.Run("priority senders come first", func(t *testing.T) {
+		msgs := []*types.SignedMessage{
+			sign(a0, to, 0, 0, 3),
+			sign(a1, to, 0, 0, 1),
+			sign(a1, to, 1, 0, 5),
+			sign(a2, to, 0, 0, 2),
+		}
+		expected := []*types.SignedMessage{msgs[1], msgs[2], msgs[0], msgs[3]}
+
+		q := NewPriorityMessageQueue(msgs, []address.Address{a1})
+		actual := q.Drain(-1)
+		assert.Equal(t, expected, actual)
+		assert.True(t, q.Empty())
+	})
+
+	t.Run("drain keeps the messages it does not take", func(t *testing.T) {
+		msgs := []*types.SignedMessage{
+			sign(a0, to, 0, 0, 2),
+			sign(a1, to, 0, 0, 3),
+			sign(a2, to, 0, 0, 1),
+		}
+		q := NewMessageQueue(msgs)
+		assert.Equal(t, []*types.SignedMessage{msgs[1]}, q.Drain(1))
+		assert.Equal(t, []*types.SignedMessage{msgs[0], msgs[2]}, q.Drain(-1))
+	})
 }
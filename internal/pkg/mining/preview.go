@@ -0,0 +1,101 @@
+package mining
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
+)
+
+// Preview describes the block a miner would produce in an epoch without
+// producing it.
+type Preview struct {
+	Epoch        abi.ChainEpoch
+	Wins         bool
+	MinerPower   abi.StoragePower
+	NetworkPower abi.StoragePower
+	// Included are the messages that would be placed in the block, in block order.
+	Included []PreviewMessage
+	// Excluded are the pending messages that would be left out, with the reason.
+	Excluded []PreviewMessage
+	// MaxGasRevenue is the sum of gas price times gas limit of the included
+	// messages, an upper bound on the gas fees collected for the block since
+	// messages are only charged for the gas they use.
+	MaxGasRevenue types.AttoFIL
+}
+
+// PreviewMessage is a pending message considered for inclusion in a previewed block.
+type PreviewMessage struct {
+	Cid      cid.Cid
+	From     address.Address
+	Nonce    uint64
+	GasPrice types.AttoFIL
+	GasLimit gas.Unit
+	// Priority is set for messages from senders selected before all others.
+	Priority bool
+	Reason   string `json:",omitempty"`
+}
+
+// Preview runs the election for the epoch `nullBlkCount` epochs after the base
+// and selects messages for a block as Mine would. No winning PoSt is
+// generated and nothing is signed or stored.
+func (w *DefaultWorker) Preview(ctx context.Context, base block.TipSet, nullBlkCount uint64) (*Preview, error) {
+	if !base.Defined() {
+		return nil, errors.New("bad input tipset with no blocks sent to Preview()")
+	}
+	baseEpoch, err := base.Height()
+	if err != nil {
+		return nil, err
+	}
+
+	election, err := w.runElection(ctx, base, nullBlkCount)
+	if err != nil {
+		return nil, err
+	}
+
+	priority := w.prioritySenders(ctx, base)
+	prioritized := make(map[address.Address]bool, len(priority))
+	for _, addr := range priority {
+		prioritized[addr] = true
+	}
+
+	included, excluded := w.selectMessages(ctx, base)
+
+	preview := &Preview{
+		Epoch:         baseEpoch + abi.ChainEpoch(1) + abi.ChainEpoch(nullBlkCount),
+		Wins:          election.wins,
+		MinerPower:    election.minerPower,
+		NetworkPower:  election.networkPower,
+		Included:      []PreviewMessage{},
+		Excluded:      []PreviewMessage{},
+		MaxGasRevenue: types.ZeroAttoFIL,
+	}
+	for _, msg := range included {
+		preview.Included = append(preview.Included, newPreviewMessage(msg, prioritized, ""))
+		preview.MaxGasRevenue = big.Add(preview.MaxGasRevenue, msg.Message.GasLimit.ToTokens(msg.Message.GasPrice))
+	}
+	for _, ex := range excluded {
+		preview.Excluded = append(preview.Excluded, newPreviewMessage(ex.msg, prioritized, ex.reason))
+	}
+	return preview, nil
+}
+
+func newPreviewMessage(msg *types.SignedMessage, prioritized map[address.Address]bool, reason string) PreviewMessage {
+	mCid, _ := msg.Cid()
+	return PreviewMessage{
+		Cid:      mCid,
+		From:     msg.Message.From,
+		Nonce:    msg.Message.CallSeqNum,
+		GasPrice: msg.Message.GasPrice,
+		GasLimit: msg.Message.GasLimit,
+		Priority: prioritized[msg.Message.From],
+		Reason:   reason,
+	}
+}
@@ -0,0 +1,123 @@
+package mining
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	vmaddr "github.com/filecoin-project/go-filecoin/internal/pkg/vm/address"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
+)
+
+type fakeMessageSource []*types.SignedMessage
+
+func (s fakeMessageSource) Pending() []*types.SignedMessage { return s }
+func (s fakeMessageSource) Remove(cid.Cid)                  {}
+
+type penalizeSender address.Address
+
+func (p penalizeSender) PenaltyCheck(_ context.Context, msg *types.UnsignedMessage) error {
+	if msg.From == address.Address(p) {
+		return errors.New("nonce too high")
+	}
+	return nil
+}
+
+func TestSelectMessages(t *testing.T) {
+	tf.UnitTest(t)
+
+	good := vmaddr.RequireIDAddress(t, 100)
+	bad := vmaddr.RequireIDAddress(t, 101)
+	local := vmaddr.RequireIDAddress(t, 102)
+	to := vmaddr.RequireIDAddress(t, 103)
+
+	newMsg := func(from address.Address, nonce uint64, price int64) *types.SignedMessage {
+		return &types.SignedMessage{Message: types.UnsignedMessage{
+			From:       from,
+			To:         to,
+			CallSeqNum: nonce,
+			GasPrice:   types.NewGasPrice(price),
+			GasLimit:   gas.NewGas(100),
+		}}
+	}
+
+	var pending fakeMessageSource
+	for i := 0; i < block.BlockMessageLimit+2; i++ {
+		pending = append(pending, newMsg(good, uint64(i), 2))
+	}
+	penalized := newMsg(bad, 7, 3)
+	pending = append(pending, penalized)
+	// cheaper than everything else and only included because of its priority
+	operational := newMsg(local, 0, 1)
+	pending = append(pending, operational)
+
+	w := &DefaultWorker{
+		messageSource:  pending,
+		penaltyChecker: penalizeSender(bad),
+		priorityAddrs:  []address.Address{local},
+	}
+	included, excluded := w.selectMessages(context.Background(), block.UndefTipSet)
+
+	t.Log("messages are included up to the block limit, priority messages first, less the penalized ones")
+	var penalizedIncluded, operationalIncluded bool
+	for _, msg := range included {
+		penalizedIncluded = penalizedIncluded || msg == penalized
+		operationalIncluded = operationalIncluded || msg == operational
+	}
+	assert.False(t, penalizedIncluded)
+	assert.True(t, operationalIncluded)
+	assert.Len(t, included, block.BlockMessageLimit-1)
+
+	t.Log("every pending message left out is reported with a reason")
+	require.Len(t, excluded, len(pending)-len(included))
+	reasons := map[string]int{}
+	for _, ex := range excluded {
+		if ex.msg == penalized {
+			assert.Equal(t, "penalized: nonce too high", ex.reason)
+		}
+		reasons[ex.reason]++
+	}
+	assert.Equal(t, 1, reasons["penalized: nonce too high"])
+	assert.Equal(t, 4, reasons["exceeds block message limit"])
+}
+
+// failingStateAPI fails to read the state of any tipset.
+type failingStateAPI struct {
+	workerPorcelainAPI
+}
+
+func (failingStateAPI) PowerStateView(block.TipSetKey) (consensus.PowerStateView, error) {
+	return nil, errors.New("state not found")
+}
+
+func TestSelectMessagesWithoutState(t *testing.T) {
+	tf.UnitTest(t)
+
+	local := vmaddr.RequireIDAddress(t, 100)
+	other := vmaddr.RequireIDAddress(t, 101)
+	pending := fakeMessageSource{
+		{Message: types.UnsignedMessage{From: other, GasPrice: types.NewGasPrice(2), GasLimit: gas.NewGas(100)}},
+		{Message: types.UnsignedMessage{From: local, GasPrice: types.NewGasPrice(1), GasLimit: gas.NewGas(100)}},
+	}
+	w := &DefaultWorker{
+		api:                   failingStateAPI{},
+		messageSource:         pending,
+		penaltyChecker:        penalizeSender(vmaddr.RequireIDAddress(t, 102)),
+		priorityAddrs:         []address.Address{local},
+		prioritizeOwnMessages: true,
+	}
+
+	t.Log("messages are selected when the miner's addresses cannot be read, the configured priority first")
+	included, excluded := w.selectMessages(context.Background(), block.UndefTipSet)
+	assert.Empty(t, excluded)
+	assert.Equal(t, []*types.SignedMessage{pending[1], pending[0]}, included)
+}
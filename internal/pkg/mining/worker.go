@@ -105,6 +105,9 @@ type DefaultWorker struct {
 	poster         postgenerator.PoStGenerator
 	chainState     chain.TipSetProvider
 	drand          drand.IFace
+
+	prioritizeOwnMessages bool
+	priorityAddrs         []address.Address
 }
 
 // WorkerParameters use for NewDefaultWorker parameters
@@ -131,6 +134,10 @@ type WorkerParameters struct {
 	Clock         clock.ChainEpochClock
 	Poster        postgenerator.PoStGenerator
 	ChainState    chain.TipSetProvider
+
+	// message selection
+	PrioritizeOwnMessages bool
+	PriorityAddresses     []address.Address
 }
 
 // NewDefaultWorker instantiates a new Worker.
@@ -153,6 +160,9 @@ func NewDefaultWorker(parameters WorkerParameters) *DefaultWorker {
 		poster:         parameters.Poster,
 		chainState:     parameters.ChainState,
 		drand:          parameters.Drand,
+
+		prioritizeOwnMessages: parameters.PrioritizeOwnMessages,
+		priorityAddrs:         parameters.PriorityAddresses,
 	}
 }
 
@@ -177,6 +187,56 @@ func (w *DefaultWorker) Mine(ctx context.Context, base block.TipSet, nullBlkCoun
 		return nil, ctx.Err()
 	}
 
+	election, err := w.runElection(ctx, base, nullBlkCount)
+	if err != nil {
+		return nil, err
+	}
+	if !election.wins {
+		// no winners we are done
+		return nil, nil
+	}
+
+	// we have a winning block
+	sectorSetAncestor, err := w.lookbackTipset(ctx, base, nullBlkCount, consensus.WinningPoStSectorSetLookback)
+	if err != nil {
+		log.Errorf("Worker.Mine couldn't get ancestor tipset: %s", err.Error())
+		return nil, err
+	}
+	sectorStateView, err := w.api.PowerStateView(sectorSetAncestor.Key())
+	if err != nil {
+		log.Errorf("Worker.Mine couldn't get snapshot for tipset: %s", err.Error())
+		return nil, err
+	}
+
+	posts, err := w.election.GenerateWinningPoSt(ctx, election.entry, currEpoch, w.poster, w.minerAddr, sectorStateView)
+	if err != nil {
+		log.Warnf("Worker.Mine failed to generate post")
+		return nil, err
+	}
+
+	return w.Generate(ctx, base, election.ticket, election.proof, abi.ChainEpoch(nullBlkCount), posts, election.drandEntries)
+}
+
+// electionResult holds the outcome of running the leader election for an epoch.
+type electionResult struct {
+	ticket       block.Ticket
+	proof        crypto.VRFPi
+	entry        *drand.Entry
+	drandEntries []*drand.Entry
+	minerPower   abi.StoragePower
+	networkPower abi.StoragePower
+	wins         bool
+}
+
+// runElection generates the ticket and election proof of the miner for the
+// epoch `nullBlkCount` epochs after the base and checks whether they win.
+func (w *DefaultWorker) runElection(ctx context.Context, base block.TipSet, nullBlkCount uint64) (*electionResult, error) {
+	baseEpoch, err := base.Height()
+	if err != nil {
+		return nil, err
+	}
+	currEpoch := baseEpoch + abi.ChainEpoch(1) + abi.ChainEpoch(nullBlkCount)
+
 	// Read uncached worker address
 	keyView, err := w.api.PowerStateView(base.Key())
 	if err != nil {
@@ -244,30 +304,16 @@ func (w *DefaultWorker) Mine(ctx context.Context, base block.TipSet, nullBlkCoun
 		return nil, err
 	}
 	wins := w.election.IsWinner(electionVRFDigest[:], minerPower, networkPower)
-	if !wins {
-		// no winners we are done
-		return nil, nil
-	}
-
-	// we have a winning block
-	sectorSetAncestor, err := w.lookbackTipset(ctx, base, nullBlkCount, consensus.WinningPoStSectorSetLookback)
-	if err != nil {
-		log.Errorf("Worker.Mine couldn't get ancestor tipset: %s", err.Error())
-		return nil, err
-	}
-	sectorStateView, err := w.api.PowerStateView(sectorSetAncestor.Key())
-	if err != nil {
-		log.Errorf("Worker.Mine couldn't get snapshot for tipset: %s", err.Error())
-		return nil, err
-	}
-
-	posts, err := w.election.GenerateWinningPoSt(ctx, electionEntry, currEpoch, w.poster, w.minerAddr, sectorStateView)
-	if err != nil {
-		log.Warnf("Worker.Mine failed to generate post")
-		return nil, err
-	}
 
-	return w.Generate(ctx, base, nextTicket, electionVRFProof, abi.ChainEpoch(nullBlkCount), posts, drandEntries)
+	return &electionResult{
+		ticket:       nextTicket,
+		proof:        electionVRFProof,
+		entry:        electionEntry,
+		drandEntries: drandEntries,
+		minerPower:   minerPower,
+		networkPower: networkPower,
+		wins:         wins,
+	}, nil
 }
 
 func (w *DefaultWorker) getPowerTable(powerKey, faultsKey block.TipSetKey) (consensus.PowerTableView, error) {
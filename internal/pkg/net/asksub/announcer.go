@@ -0,0 +1,91 @@
+package asksub
+
+import (
+	"context"
+	"time"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
+)
+
+// Publisher publishes on a pubsub topic.
+type Publisher interface {
+	Publish(ctx context.Context, data []byte) error
+}
+
+// Announcer publishes the current ask and capacity of a miner on the ask
+// topic.
+type Announcer struct {
+	topic    Publisher
+	ask      func() *storagemarket.SignedStorageAsk
+	peer     peer.ID
+	capacity func() uint64
+	head     func() (abi.ChainEpoch, error)
+	sign     func(ctx context.Context, data []byte) (crypto.Signature, error)
+}
+
+// NewAnnouncer creates an announcer of the asks returned by ask, nil when the
+// miner has none, and the capacity returned by capacity. Announcements are
+// signed by sign with the key of the miner's worker.
+func NewAnnouncer(topic Publisher, ask func() *storagemarket.SignedStorageAsk, pid peer.ID, capacity func() uint64,
+	head func() (abi.ChainEpoch, error), sign func(ctx context.Context, data []byte) (crypto.Signature, error)) *Announcer {
+	return &Announcer{
+		topic:    topic,
+		ask:      ask,
+		peer:     pid,
+		capacity: capacity,
+		head:     head,
+		sign:     sign,
+	}
+}
+
+// Announce publishes the current ask and capacity.
+func (a *Announcer) Announce(ctx context.Context) error {
+	ask := a.ask()
+	if ask == nil {
+		return errors.New("miner has no ask to announce")
+	}
+	epoch, err := a.head()
+	if err != nil {
+		return err
+	}
+	announcement := &Announcement{
+		Ask:      ask,
+		Peer:     a.peer,
+		Capacity: a.capacity(),
+		Epoch:    epoch,
+	}
+	body, err := announcement.Bytes()
+	if err != nil {
+		return err
+	}
+	announcement.Signature, err = a.sign(ctx, body)
+	if err != nil {
+		return errors.Wrap(err, "failed to sign announcement")
+	}
+	payload, err := MakePayload(announcement)
+	if err != nil {
+		return err
+	}
+	return a.topic.Publish(ctx, payload)
+}
+
+// Run announces every interval until ctx is done, so that nodes joining the
+// network learn the ask.
+func (a *Announcer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.Announce(ctx); err != nil {
+				askTopicLogger.Debugf("failed to announce ask: %s", err)
+			}
+		}
+	}
+}
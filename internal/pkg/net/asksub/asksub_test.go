@@ -0,0 +1,204 @@
+package asksub_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsubpb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/asksub"
+	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+func TestPayloadRoundTrip(t *testing.T) {
+	tf.UnitTest(t)
+
+	signer, _ := types.NewMockSignersAndKeyInfo(1)
+	a := &asksub.Announcement{
+		Ask:      signAsk(t, signer, signer.Addresses[0], testAsk(address.NewForTestGetter()(), 1, 10, 100)),
+		Peer:     th.RequireIntPeerID(t, 1),
+		Capacity: 1 << 30,
+		Epoch:    7,
+	}
+	signAnnouncement(t, signer, signer.Addresses[0], a)
+	raw, err := asksub.MakePayload(a)
+	require.NoError(t, err)
+	decoded, err := asksub.DecodePayload(raw)
+	require.NoError(t, err)
+	assert.Equal(t, a, decoded)
+
+	_, err = asksub.DecodePayload([]byte("not an ask"))
+	assert.Error(t, err)
+}
+
+func TestAskTopicValidator(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	signer := types.NewMockSigner(append(types.MustGenerateKeyInfo(1, 42), types.MustGenerateKeyInfo(1, 43)...))
+	worker, other := signer.Addresses[0], signer.Addresses[1]
+	require.NotEqual(t, worker, other)
+	miner, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	view := &fakeView{workers: map[address.Address]address.Address{miner: worker}}
+	head := abi.ChainEpoch(10)
+	tv := asksub.NewAskTopicValidator(func() (asksub.StateView, error) { return view, nil }, func() (abi.ChainEpoch, error) { return head, nil })
+	validator := tv.Validator()
+	pid := th.RequireIntPeerID(t, 1)
+
+	network := "gfctest"
+	assert.Equal(t, asksub.Topic(network), tv.Topic(network))
+	announce := func(ask *storagemarket.SignedStorageAsk, key address.Address, epoch abi.ChainEpoch) *asksub.Announcement {
+		a := &asksub.Announcement{Ask: ask, Peer: pid, Capacity: 1 << 30, Epoch: epoch}
+		signAnnouncement(t, signer, key, a)
+		return a
+	}
+
+	good := announce(signAsk(t, signer, worker, testAsk(miner, 1, 10, 100)), worker, head)
+	assert.True(t, validator(ctx, pid, toPubSub(t, good)))
+
+	// asks signed by a key other than the worker's
+	forged := announce(signAsk(t, signer, other, testAsk(miner, 1, 10, 100)), worker, head)
+	assert.False(t, validator(ctx, pid, toPubSub(t, forged)))
+
+	// announcements signed by a key other than the worker's
+	forged = announce(signAsk(t, signer, worker, testAsk(miner, 1, 10, 100)), other, head)
+	assert.False(t, validator(ctx, pid, toPubSub(t, forged)))
+
+	// announcements rewritten by a relaying peer
+	for _, rewrite := range []func(a *asksub.Announcement){
+		func(a *asksub.Announcement) { a.Peer = th.RequireIntPeerID(t, 2) },
+		func(a *asksub.Announcement) { a.Capacity = 1 },
+		func(a *asksub.Announcement) { a.Epoch = head - 1 },
+	} {
+		rewritten := *good
+		rewrite(&rewritten)
+		assert.False(t, validator(ctx, pid, toPubSub(t, &rewritten)))
+	}
+
+	// announcements made past the head, beyond the drift
+	assert.True(t, validator(ctx, pid, toPubSub(t, announce(good.Ask, worker, head+asksub.MaxEpochDrift))))
+	assert.False(t, validator(ctx, pid, toPubSub(t, announce(good.Ask, worker, head+asksub.MaxEpochDrift+1))))
+
+	// ask of a miner that does not exist
+	unknown, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	missing := announce(signAsk(t, signer, worker, testAsk(unknown, 1, 10, 100)), worker, head)
+	assert.False(t, validator(ctx, pid, toPubSub(t, missing)))
+
+	assert.False(t, validator(ctx, pid, &pubsub.Message{Message: &pubsubpb.Message{Data: []byte("garbage")}}))
+}
+
+func TestCache(t *testing.T) {
+	tf.UnitTest(t)
+
+	m1, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	m2, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+	pid := th.RequireIntPeerID(t, 1)
+	announce := func(ask *storagemarket.StorageAsk, epoch abi.ChainEpoch) *asksub.Announcement {
+		return &asksub.Announcement{Ask: &storagemarket.SignedStorageAsk{Ask: ask}, Peer: pid, Epoch: epoch}
+	}
+
+	t.Run("keeps the latest ask of each miner", func(t *testing.T) {
+		c := asksub.NewCache()
+		assert.True(t, c.Update(announce(testAsk(m1, 2, 10, 100), 5)))
+		assert.False(t, c.Update(announce(testAsk(m1, 1, 5, 100), 6)))
+		assert.False(t, c.Update(announce(testAsk(m1, 2, 10, 100), 5)))
+
+		// a later announcement of the same ask updates the capacity
+		later := announce(testAsk(m1, 2, 10, 100), 8)
+		later.Capacity = 42
+		assert.True(t, c.Update(later))
+
+		cached, ok := c.Get(m1)
+		require.True(t, ok)
+		assert.Equal(t, uint64(2), cached.Ask.SeqNo)
+		assert.Equal(t, uint64(42), cached.Capacity)
+
+		assert.True(t, c.Update(announce(testAsk(m1, 3, 20, 100), 9)))
+		cached, ok = c.Get(m1)
+		require.True(t, ok)
+		assert.Equal(t, uint64(3), cached.Ask.SeqNo)
+
+		_, ok = c.Get(m2)
+		assert.False(t, ok)
+	})
+
+	t.Run("lists unexpired asks cheapest first", func(t *testing.T) {
+		c := asksub.NewCache()
+		c.Update(announce(testAsk(m1, 1, 20, 100), 1))
+		c.Update(announce(testAsk(m2, 1, 10, 50), 1))
+
+		asks := c.Asks(10)
+		require.Len(t, asks, 2)
+		assert.Equal(t, m2, asks[0].Ask.Miner)
+		assert.Equal(t, m1, asks[1].Ask.Miner)
+
+		asks = c.Asks(50)
+		require.Len(t, asks, 1)
+		assert.Equal(t, m1, asks[0].Ask.Miner)
+		_, ok := c.Get(m2)
+		assert.False(t, ok)
+	})
+}
+
+func testAsk(miner address.Address, seq uint64, price int64, expiry abi.ChainEpoch) *storagemarket.StorageAsk {
+	return &storagemarket.StorageAsk{
+		Price:        big.NewInt(price),
+		MinPieceSize: 256,
+		Miner:        miner,
+		Timestamp:    1,
+		Expiry:       expiry,
+		SeqNo:        seq,
+	}
+}
+
+func signAsk(t *testing.T, signer types.MockSigner, key address.Address, ask *storagemarket.StorageAsk) *storagemarket.SignedStorageAsk {
+	data, err := encoding.Encode(ask)
+	require.NoError(t, err)
+	sig, err := signer.SignBytes(context.Background(), data, key)
+	require.NoError(t, err)
+	return &storagemarket.SignedStorageAsk{Ask: ask, Signature: &sig}
+}
+
+func signAnnouncement(t *testing.T, signer types.MockSigner, key address.Address, a *asksub.Announcement) {
+	data, err := a.Bytes()
+	require.NoError(t, err)
+	a.Signature, err = signer.SignBytes(context.Background(), data, key)
+	require.NoError(t, err)
+}
+
+func toPubSub(t *testing.T, a *asksub.Announcement) *pubsub.Message {
+	data, err := asksub.MakePayload(a)
+	require.NoError(t, err)
+	return &pubsub.Message{Message: &pubsubpb.Message{Data: data}}
+}
+
+type fakeView struct {
+	workers map[address.Address]address.Address
+}
+
+func (v *fakeView) AccountSignerAddress(_ context.Context, a address.Address) (address.Address, error) {
+	return a, nil
+}
+
+func (v *fakeView) MinerControlAddresses(_ context.Context, maddr address.Address) (address.Address, address.Address, error) {
+	worker, ok := v.workers[maddr]
+	if !ok {
+		return address.Undef, address.Undef, errors.Errorf("no miner %s", maddr)
+	}
+	return worker, worker, nil
+}
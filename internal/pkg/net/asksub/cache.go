@@ -0,0 +1,86 @@
+package asksub
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+// CachedAsk is the latest announced ask of a miner.
+type CachedAsk struct {
+	Ask      storagemarket.StorageAsk
+	Peer     peer.ID
+	Capacity uint64
+	// Epoch is the chain height of the announcement.
+	Epoch abi.ChainEpoch
+}
+
+// Cache keeps the latest ask announced by each miner, so that clients find
+// the asks of the network without querying every miner.
+type Cache struct {
+	lk   sync.RWMutex
+	asks map[address.Address]CachedAsk
+}
+
+// NewCache creates an empty ask cache.
+func NewCache() *Cache {
+	return &Cache{asks: map[address.Address]CachedAsk{}}
+}
+
+// Update caches the ask of an announcement, unless a later ask of the miner
+// is cached. Announcements of the same ask are ordered by their epoch, which
+// the topic validator checks is signed by the miner and not past the head.
+// It returns true if the cache changed.
+func (c *Cache) Update(a *Announcement) bool {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	ask := a.Ask.Ask
+	if prev, ok := c.asks[ask.Miner]; ok {
+		if ask.SeqNo < prev.Ask.SeqNo || (ask.SeqNo == prev.Ask.SeqNo && a.Epoch <= prev.Epoch) {
+			return false
+		}
+	}
+	c.asks[ask.Miner] = CachedAsk{
+		Ask:      *ask,
+		Peer:     a.Peer,
+		Capacity: a.Capacity,
+		Epoch:    a.Epoch,
+	}
+	return true
+}
+
+// Asks returns the cached asks that have not expired at epoch, cheapest
+// first, dropping those that have.
+func (c *Cache) Asks(epoch abi.ChainEpoch) []CachedAsk {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	out := make([]CachedAsk, 0, len(c.asks))
+	for miner, ask := range c.asks {
+		if ask.Ask.Expiry <= epoch {
+			delete(c.asks, miner)
+			continue
+		}
+		out = append(out, ask)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Ask.Price.Equals(out[j].Ask.Price) {
+			return out[i].Ask.Price.LessThan(out[j].Ask.Price)
+		}
+		return out[i].Ask.Miner.String() < out[j].Ask.Miner.String()
+	})
+	return out
+}
+
+// Get returns the cached ask of a miner.
+func (c *Cache) Get(miner address.Address) (CachedAsk, bool) {
+	c.lk.RLock()
+	defer c.lk.RUnlock()
+	ask, ok := c.asks[miner]
+	return ask, ok
+}
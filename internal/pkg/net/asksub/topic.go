@@ -0,0 +1,108 @@
+package asksub
+
+import (
+	"fmt"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+)
+
+// Topic returns the network pubsub topic identifier on which miners announce
+// their asks and capacity.
+func Topic(networkName string) string {
+	return fmt.Sprintf("/fil/asks/%s", networkName)
+}
+
+// Payload is the encoding of a signed announcement on the ask topic.
+type Payload struct {
+	_ struct{} `cbor:",toarray"`
+	// Body is the encoded announcement, which is signed.
+	Body      []byte
+	Signature crypto.Signature
+}
+
+type announcementBody struct {
+	_ struct{} `cbor:",toarray"`
+	// Ask is the encoded signed ask of the miner.
+	Ask      []byte
+	Peer     []byte
+	Capacity uint64
+	Epoch    abi.ChainEpoch
+}
+
+// Announcement is a miner's announcement of its ask and capacity. The whole
+// announcement is signed by the worker of the miner, so that the peers
+// relaying it cannot change any of it.
+type Announcement struct {
+	Ask *storagemarket.SignedStorageAsk
+	// Peer is the peer ID the miner takes deals on.
+	Peer peer.ID
+	// Capacity is the number of bytes of deal data the miner accepts, zero
+	// when unspecified.
+	Capacity uint64
+	// Epoch is the chain height at which the announcement was made, ordering
+	// announcements of the same ask.
+	Epoch     abi.ChainEpoch
+	Signature crypto.Signature
+}
+
+// Bytes returns the encoding of the announcement signed by the miner's worker.
+func (a *Announcement) Bytes() ([]byte, error) {
+	if a.Ask == nil || a.Ask.Ask == nil {
+		return nil, errors.New("announcement has no ask")
+	}
+	ask, err := encoding.Encode(a.Ask)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode ask")
+	}
+	return encoding.Encode(announcementBody{
+		Ask:      ask,
+		Peer:     []byte(a.Peer),
+		Capacity: a.Capacity,
+		Epoch:    a.Epoch,
+	})
+}
+
+// MakePayload encodes a signed announcement for the ask topic.
+func MakePayload(a *Announcement) ([]byte, error) {
+	body, err := a.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return encoding.Encode(Payload{Body: body, Signature: a.Signature})
+}
+
+// DecodePayload decodes a signed announcement of the ask topic.
+func DecodePayload(raw []byte) (*Announcement, error) {
+	var payload Payload
+	if err := encoding.Decode(raw, &payload); err != nil {
+		return nil, err
+	}
+	var body announcementBody
+	if err := encoding.Decode(payload.Body, &body); err != nil {
+		return nil, errors.Wrap(err, "failed to decode announcement")
+	}
+	ask := &storagemarket.SignedStorageAsk{}
+	if err := encoding.Decode(body.Ask, ask); err != nil {
+		return nil, errors.Wrap(err, "failed to decode ask")
+	}
+	if ask.Ask == nil || ask.Signature == nil {
+		return nil, errors.New("announcement has no signed ask")
+	}
+	pid, err := peer.IDFromBytes(body.Peer)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode peer ID")
+	}
+	return &Announcement{
+		Ask:       ask,
+		Peer:      pid,
+		Capacity:  body.Capacity,
+		Epoch:     body.Epoch,
+		Signature: payload.Signature,
+	}, nil
+}
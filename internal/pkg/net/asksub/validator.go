@@ -0,0 +1,109 @@
+package asksub
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-pubsub"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/metrics"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
+)
+
+var askTopicLogger = log.Logger("net/ask_validator")
+var mDecodeAskFail = metrics.NewInt64Counter("net/pubsub_ask_decode_failure", "Number of asks that fail to decode seen on ask pubsub channel")
+var mInvalidAsk = metrics.NewInt64Counter("net/pubsub_invalid_ask", "Number of asks that fail signature validation seen on ask pubsub channel")
+
+// MaxEpochDrift is how many epochs past the head of the validating node an
+// announcement may be made at, for miners whose head is slightly ahead.
+// Announcements from further ahead are rejected, so that a forged epoch cannot
+// hold an announcement in the cache of their receivers.
+const MaxEpochDrift = abi.ChainEpoch(5)
+
+// StateView is the state an ask signature is verified against.
+type StateView interface {
+	state.AccountStateView
+	MinerControlAddresses(ctx context.Context, maddr address.Address) (owner, worker address.Address, err error)
+}
+
+// AskTopicValidator may be registered on go-libp2p-pubsub to validate asksub payloads.
+type AskTopicValidator struct {
+	validator pubsub.Validator
+	opts      []pubsub.ValidatorOpt
+}
+
+// NewAskTopicValidator returns an AskTopicValidator checking that announcements
+// are signed by the worker of their miner in the state returned by headView,
+// and made no later than MaxEpochDrift past the height returned by head.
+func NewAskTopicValidator(headView func() (StateView, error), head func() (abi.ChainEpoch, error), opts ...pubsub.ValidatorOpt) *AskTopicValidator {
+	return &AskTopicValidator{
+		opts: opts,
+		validator: func(ctx context.Context, p peer.ID, msg *pubsub.Message) bool {
+			a, err := DecodePayload(msg.GetData())
+			if err != nil {
+				askTopicLogger.Debugf("ask from peer %s failed to decode: %s", p.String(), err.Error())
+				mDecodeAskFail.Inc(ctx, 1)
+				return false
+			}
+			view, err := headView()
+			if err != nil {
+				askTopicLogger.Debugf("failed to load state to validate ask from peer %s: %s", p.String(), err.Error())
+				return false
+			}
+			height, err := head()
+			if err != nil {
+				askTopicLogger.Debugf("failed to load head to validate ask from peer %s: %s", p.String(), err.Error())
+				return false
+			}
+			if err := VerifyAnnouncement(ctx, view, height, a); err != nil {
+				askTopicLogger.Debugf("ask of miner %s from peer %s failed to validate: %s", a.Ask.Ask.Miner, p.String(), err.Error())
+				mInvalidAsk.Inc(ctx, 1)
+				return false
+			}
+			return true
+		},
+	}
+}
+
+// VerifyAnnouncement checks that an announcement and its ask are signed by the
+// worker of the ask's miner, and that the announcement is made no later than
+// MaxEpochDrift past the head height.
+func VerifyAnnouncement(ctx context.Context, view StateView, head abi.ChainEpoch, a *Announcement) error {
+	if a.Epoch > head+MaxEpochDrift {
+		return errors.Errorf("announcement made at %d, past the head at %d", a.Epoch, head)
+	}
+	_, worker, err := view.MinerControlAddresses(ctx, a.Ask.Ask.Miner)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load worker of miner %s", a.Ask.Ask.Miner)
+	}
+	sigValidator := state.NewSignatureValidator(view)
+	data, err := encoding.Encode(a.Ask.Ask)
+	if err != nil {
+		return err
+	}
+	if err := sigValidator.ValidateSignature(ctx, data, worker, *a.Ask.Signature); err != nil {
+		return errors.Wrap(err, "invalid ask signature")
+	}
+	body, err := a.Bytes()
+	if err != nil {
+		return err
+	}
+	return sigValidator.ValidateSignature(ctx, body, worker, a.Signature)
+}
+
+func (atv *AskTopicValidator) Topic(network string) string {
+	return Topic(network)
+}
+
+func (atv *AskTopicValidator) Validator() pubsub.Validator {
+	return atv.validator
+}
+
+func (atv *AskTopicValidator) Opts() []pubsub.ValidatorOpt {
+	return atv.opts
+}
@@ -0,0 +1,156 @@
+package bidsub
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/filecoin-project/specs-actors/actors/crypto"
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
+)
+
+var bigZero = big.Zero()
+
+// Publisher publishes on a pubsub topic.
+type Publisher interface {
+	Publish(ctx context.Context, data []byte) error
+}
+
+// Bidder bids on the storage requests a miner can take with tailored asks,
+// published on the bid topic.
+type Bidder struct {
+	topic Publisher
+	miner address.Address
+	peer  peer.ID
+	// sectorSize is the size of the sectors of the miner, which pieces must fit in
+	sectorSize abi.SectorSize
+	ask        func() *storagemarket.SignedStorageAsk
+	capacity   func() uint64
+	head       func() (abi.ChainEpoch, error)
+	sign       func(ctx context.Context, data []byte) (crypto.Signature, error)
+
+	lk   sync.Mutex
+	bids map[cid.Cid]SignedBid
+}
+
+// NewBidder creates a bidder for a miner committing sectors of `sectorSize`
+// with the ask returned by ask, nil when the miner has none, and the capacity
+// returned by capacity. Bids are signed by sign with the key of the miner's
+// worker.
+func NewBidder(topic Publisher, miner address.Address, pid peer.ID, sectorSize abi.SectorSize, ask func() *storagemarket.SignedStorageAsk, capacity func() uint64,
+	head func() (abi.ChainEpoch, error), sign func(ctx context.Context, data []byte) (crypto.Signature, error)) *Bidder {
+	return &Bidder{
+		topic:      topic,
+		miner:      miner,
+		peer:       pid,
+		sectorSize: sectorSize,
+		ask:        ask,
+		capacity:   capacity,
+		head:       head,
+		sign:       sign,
+		bids:       map[cid.Cid]SignedBid{},
+	}
+}
+
+// HandleRequest bids on a request when the miner can take it, and returns the
+// bid published. It fails with the reason when the miner does not bid.
+func (b *Bidder) HandleRequest(ctx context.Context, r *SignedRequest) (*SignedBid, error) {
+	epoch, err := b.head()
+	if err != nil {
+		return nil, err
+	}
+	bid, err := Tailor(b.miner, b.peer, b.sectorSize, b.ask(), b.capacity(), epoch, r)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := bid.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := b.sign(ctx, body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign bid")
+	}
+	signed := SignedBid{Bid: bid, Signature: sig}
+	payload, err := MakeBidPayload(&signed)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.topic.Publish(ctx, payload); err != nil {
+		return nil, errors.Wrap(err, "failed to publish bid")
+	}
+
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	b.bids[r.ID] = signed
+	return &signed, nil
+}
+
+// Bids returns the bids made that have not expired, dropping those that have.
+func (b *Bidder) Bids() ([]SignedBid, error) {
+	epoch, err := b.head()
+	if err != nil {
+		return nil, err
+	}
+
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	out := make([]SignedBid, 0, len(b.bids))
+	for id, bid := range b.bids {
+		if bid.Bid.Expiry <= epoch {
+			delete(b.bids, id)
+			continue
+		}
+		out = append(out, bid)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Bid.Request.String() < out[j].Bid.Request.String() })
+	return out, nil
+}
+
+// Tailor returns the bid of a miner with sectors of `sectorSize`, ask `ask` and
+// capacity `capacity`, zero when unspecified, on a request at epoch. It fails
+// when the miner cannot take the request, as when the piece does not fit in
+// its sectors. Bids are made at the ask price, as the miner rejects deals
+// below it; the bid holds the price for the request until the ask expires.
+func Tailor(miner address.Address, pid peer.ID, sectorSize abi.SectorSize, ask *storagemarket.SignedStorageAsk, capacity uint64, epoch abi.ChainEpoch, r *SignedRequest) (Bid, error) {
+	req := r.Request
+	if ask == nil || ask.Ask == nil {
+		return Bid{}, errors.New("miner has no ask")
+	}
+	if ask.Ask.Expiry <= epoch {
+		return Bid{}, errors.Errorf("ask expired at %d", ask.Ask.Expiry)
+	}
+	if req.Expiry <= epoch {
+		return Bid{}, errors.Errorf("request expired at %d", req.Expiry)
+	}
+	if req.Size < ask.Ask.MinPieceSize {
+		return Bid{}, errors.Errorf("size %d is below the minimum piece size %d", req.Size, ask.Ask.MinPieceSize)
+	}
+	if ask.Ask.MaxPieceSize != 0 && req.Size > ask.Ask.MaxPieceSize {
+		return Bid{}, errors.Errorf("size %d is above the maximum piece size %d", req.Size, ask.Ask.MaxPieceSize)
+	}
+	if sectorSize != 0 && req.Size > abi.PaddedPieceSize(sectorSize) {
+		return Bid{}, errors.Errorf("size %d does not fit in sectors of %d bytes", req.Size, sectorSize)
+	}
+	if capacity != 0 && uint64(req.Size) > capacity {
+		return Bid{}, errors.Errorf("size %d is above the capacity %d", req.Size, capacity)
+	}
+	if req.MaxPrice.LessThan(ask.Ask.Price) {
+		return Bid{}, errors.Errorf("max price %s is below the ask price %s", req.MaxPrice, ask.Ask.Price)
+	}
+	return Bid{
+		Request: r.ID,
+		Miner:   miner,
+		Peer:    pid,
+		Price:   ask.Ask.Price,
+		Expiry:  ask.Ask.Expiry,
+	}, nil
+}
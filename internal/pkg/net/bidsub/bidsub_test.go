@@ -0,0 +1,284 @@
+package bidsub_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/filecoin-project/specs-actors/actors/crypto"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsubpb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/bidsub"
+	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+func TestPayloadRoundTrip(t *testing.T) {
+	tf.UnitTest(t)
+
+	signer := types.NewMockSigner(types.MustGenerateKeyInfo(1, 42))
+	r := signRequest(t, signer, testRequest(signer.Addresses[0], 10))
+	raw, err := bidsub.MakeRequestPayload(r)
+	require.NoError(t, err)
+	decoded, err := bidsub.DecodeRequestPayload(raw)
+	require.NoError(t, err)
+	assert.Equal(t, r, decoded)
+
+	miner, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	b := signBid(t, signer, signer.Addresses[0], testBid(t, r, miner))
+	raw, err = bidsub.MakeBidPayload(b)
+	require.NoError(t, err)
+	decodedBid, err := bidsub.DecodeBidPayload(raw)
+	require.NoError(t, err)
+	assert.Equal(t, b, decodedBid)
+
+	_, err = bidsub.DecodeRequestPayload([]byte("not a request"))
+	assert.Error(t, err)
+	_, err = bidsub.DecodeBidPayload([]byte("not a bid"))
+	assert.Error(t, err)
+}
+
+func TestTopicValidators(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	signer := types.NewMockSigner(append(types.MustGenerateKeyInfo(1, 42), types.MustGenerateKeyInfo(1, 43)...))
+	key, other := signer.Addresses[0], signer.Addresses[1]
+	miner, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	view := &fakeView{workers: map[address.Address]address.Address{miner: key}}
+	headView := func() (bidsub.StateView, error) { return view, nil }
+	pid := th.RequireIntPeerID(t, 1)
+	network := "gfctest"
+
+	t.Run("requests", func(t *testing.T) {
+		tv := bidsub.NewRequestTopicValidator(headView)
+		validator := tv.Validator()
+		assert.Equal(t, bidsub.RequestTopic(network), tv.Topic(network))
+
+		good := signRequest(t, signer, testRequest(key, 10))
+		assert.True(t, validator(ctx, pid, requestMessage(t, good)))
+
+		// signed by a key other than the client's
+		forged := signRequest(t, signer, testRequest(key, 10))
+		forged.Signature = signRequest(t, signer, testRequest(other, 10)).Signature
+		assert.False(t, validator(ctx, pid, requestMessage(t, forged)))
+
+		expired := testRequest(key, 10)
+		expired.Expiry = expired.Epoch
+		assert.False(t, validator(ctx, pid, requestMessage(t, signRequest(t, signer, expired))))
+
+		empty := testRequest(key, 10)
+		empty.Size = 0
+		assert.False(t, validator(ctx, pid, requestMessage(t, signRequest(t, signer, empty))))
+
+		assert.False(t, validator(ctx, pid, &pubsub.Message{Message: &pubsubpb.Message{Data: []byte("garbage")}}))
+	})
+
+	t.Run("bids", func(t *testing.T) {
+		tv := bidsub.NewBidTopicValidator(headView)
+		validator := tv.Validator()
+		assert.Equal(t, bidsub.BidTopic(network), tv.Topic(network))
+		r := signRequest(t, signer, testRequest(key, 10))
+
+		good := signBid(t, signer, key, testBid(t, r, miner))
+		assert.True(t, validator(ctx, pid, bidMessage(t, good)))
+
+		// signed by a key other than the worker's
+		forged := signBid(t, signer, other, testBid(t, r, miner))
+		assert.False(t, validator(ctx, pid, bidMessage(t, forged)))
+
+		// bid of a miner that does not exist
+		unknown, err := address.NewIDAddress(1001)
+		require.NoError(t, err)
+		missing := signBid(t, signer, key, testBid(t, r, unknown))
+		assert.False(t, validator(ctx, pid, bidMessage(t, missing)))
+	})
+}
+
+func TestTailor(t *testing.T) {
+	tf.UnitTest(t)
+
+	signer := types.NewMockSigner(types.MustGenerateKeyInfo(1, 42))
+	miner, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	pid := th.RequireIntPeerID(t, 1)
+	ask := &storagemarket.SignedStorageAsk{Ask: &storagemarket.StorageAsk{
+		Price:        big.NewInt(10),
+		MinPieceSize: 256,
+		MaxPieceSize: 1 << 20,
+		Miner:        miner,
+		Expiry:       100,
+	}}
+
+	r := signRequest(t, signer, testRequest(signer.Addresses[0], 20))
+	bid, err := bidsub.Tailor(miner, pid, 2048, ask, 0, 5, r)
+	require.NoError(t, err)
+	assert.Equal(t, bidsub.Bid{Request: r.ID, Miner: miner, Peer: pid, Price: big.NewInt(10), Expiry: 100}, bid)
+
+	_, err = bidsub.Tailor(miner, pid, 2048, nil, 0, 5, r)
+	assert.EqualError(t, err, "miner has no ask")
+	_, err = bidsub.Tailor(miner, pid, 2048, ask, 0, 100, r)
+	assert.EqualError(t, err, "ask expired at 100")
+	_, err = bidsub.Tailor(miner, pid, 2048, ask, 512, 5, r)
+	assert.EqualError(t, err, "size 1024 is above the capacity 512")
+
+	cheap := signRequest(t, signer, testRequest(signer.Addresses[0], 9))
+	_, err = bidsub.Tailor(miner, pid, 2048, ask, 0, 5, cheap)
+	assert.EqualError(t, err, "max price 9 is below the ask price 10")
+
+	small := testRequest(signer.Addresses[0], 20)
+	small.Size = 128
+	_, err = bidsub.Tailor(miner, pid, 2048, ask, 0, 5, signRequest(t, signer, small))
+	assert.EqualError(t, err, "size 128 is below the minimum piece size 256")
+
+	// the piece of the request does not fit in sectors of the miner
+	_, err = bidsub.Tailor(miner, pid, 512, ask, 0, 5, r)
+	assert.EqualError(t, err, "size 1024 does not fit in sectors of 512 bytes")
+}
+
+func TestBidding(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	signer := types.NewMockSigner(append(types.MustGenerateKeyInfo(1, 42), types.MustGenerateKeyInfo(1, 43)...))
+	client, worker := signer.Addresses[0], signer.Addresses[1]
+	miner, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	epoch := abi.ChainEpoch(5)
+	head := func() (abi.ChainEpoch, error) { return epoch, nil }
+
+	requests := &topic{}
+	book := bidsub.NewBook(requests, func() (address.Address, error) { return client, nil }, head,
+		func(ctx context.Context, key address.Address, data []byte) (crypto.Signature, error) {
+			return signer.SignBytes(ctx, data, key)
+		})
+	r, err := book.Publish(ctx, 1024, 100, big.NewInt(20), 10)
+	require.NoError(t, err)
+	require.Len(t, requests.published, 1)
+	assert.Equal(t, abi.ChainEpoch(15), r.Request.Expiry)
+
+	bids := &topic{}
+	ask := &storagemarket.SignedStorageAsk{Ask: &storagemarket.StorageAsk{Price: big.NewInt(10), Miner: miner, Expiry: 100}}
+	bidder := bidsub.NewBidder(bids, miner, th.RequireIntPeerID(t, 1), 2048, func() *storagemarket.SignedStorageAsk { return ask },
+		func() uint64 { return 0 }, head, func(ctx context.Context, data []byte) (crypto.Signature, error) {
+			return signer.SignBytes(ctx, data, worker)
+		})
+
+	received, err := bidsub.DecodeRequestPayload(requests.published[0])
+	require.NoError(t, err)
+	made, err := bidder.HandleRequest(ctx, received)
+	require.NoError(t, err)
+	require.Len(t, bids.published, 1)
+	madeBids, err := bidder.Bids()
+	require.NoError(t, err)
+	assert.Equal(t, []bidsub.SignedBid{*made}, madeBids)
+
+	bid, err := bidsub.DecodeBidPayload(bids.published[0])
+	require.NoError(t, err)
+	assert.True(t, book.AddBid(bid))
+	booked, err := book.Bids(r.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []bidsub.SignedBid{*bid}, booked)
+
+	// Bids above the max price and bids on unknown requests are dropped.
+	expensive := *bid
+	expensive.Bid.Price = big.NewInt(21)
+	assert.False(t, book.AddBid(&expensive))
+	unknown := *bid
+	unknown.Bid.Request = types.CidFromString(t, "unknown")
+	assert.False(t, book.AddBid(&unknown))
+
+	// Expired bids are no longer listed.
+	epoch = 100
+	madeBids, err = bidder.Bids()
+	require.NoError(t, err)
+	assert.Empty(t, madeBids)
+	booked, err = book.Bids(r.ID)
+	require.NoError(t, err)
+	assert.Empty(t, booked)
+}
+
+type topic struct {
+	published [][]byte
+}
+
+func (p *topic) Publish(_ context.Context, data []byte) error {
+	p.published = append(p.published, data)
+	return nil
+}
+
+func testRequest(client address.Address, maxPrice int64) bidsub.Request {
+	return bidsub.Request{
+		Client:   client,
+		Size:     1024,
+		Duration: 100,
+		MaxPrice: big.NewInt(maxPrice),
+		Epoch:    1,
+		Expiry:   11,
+		Nonce:    7,
+	}
+}
+
+func testBid(t *testing.T, r *bidsub.SignedRequest, miner address.Address) bidsub.Bid {
+	return bidsub.Bid{
+		Request: r.ID,
+		Miner:   miner,
+		Peer:    th.RequireIntPeerID(t, 1),
+		Price:   big.NewInt(10),
+		Expiry:  100,
+	}
+}
+
+func signRequest(t *testing.T, signer types.MockSigner, r bidsub.Request) *bidsub.SignedRequest {
+	signed, err := bidsub.SignRequest(r, func(data []byte) (crypto.Signature, error) {
+		return signer.SignBytes(context.Background(), data, r.Client)
+	})
+	require.NoError(t, err)
+	return signed
+}
+
+func signBid(t *testing.T, signer types.MockSigner, key address.Address, b bidsub.Bid) *bidsub.SignedBid {
+	data, err := b.Bytes()
+	require.NoError(t, err)
+	sig, err := signer.SignBytes(context.Background(), data, key)
+	require.NoError(t, err)
+	return &bidsub.SignedBid{Bid: b, Signature: sig}
+}
+
+func requestMessage(t *testing.T, r *bidsub.SignedRequest) *pubsub.Message {
+	data, err := bidsub.MakeRequestPayload(r)
+	require.NoError(t, err)
+	return &pubsub.Message{Message: &pubsubpb.Message{Data: data}}
+}
+
+func bidMessage(t *testing.T, b *bidsub.SignedBid) *pubsub.Message {
+	data, err := bidsub.MakeBidPayload(b)
+	require.NoError(t, err)
+	return &pubsub.Message{Message: &pubsubpb.Message{Data: data}}
+}
+
+type fakeView struct {
+	workers map[address.Address]address.Address
+}
+
+func (v *fakeView) AccountSignerAddress(_ context.Context, a address.Address) (address.Address, error) {
+	return a, nil
+}
+
+func (v *fakeView) MinerControlAddresses(_ context.Context, maddr address.Address) (address.Address, address.Address, error) {
+	worker, ok := v.workers[maddr]
+	if !ok {
+		return address.Undef, address.Undef, errors.Errorf("no miner %s", maddr)
+	}
+	return worker, worker, nil
+}
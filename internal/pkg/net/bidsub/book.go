@@ -0,0 +1,181 @@
+package bidsub
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/crypto"
+	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+)
+
+// Book publishes the storage requests of a client and keeps the bids made on
+// them. Requests are kept in memory until they and their bids expire.
+type Book struct {
+	topic  Publisher
+	client func() (address.Address, error)
+	head   func() (abi.ChainEpoch, error)
+	sign   func(ctx context.Context, signer address.Address, data []byte) (crypto.Signature, error)
+
+	lk       sync.Mutex
+	nonce    uint64
+	requests map[cid.Cid]*bookEntry
+}
+
+type bookEntry struct {
+	request SignedRequest
+	// bids holds the latest bid of each miner.
+	bids map[address.Address]SignedBid
+}
+
+// NewBook creates a book of the requests of the client whose address is
+// returned by client, signed by sign with the client's key.
+func NewBook(topic Publisher, client func() (address.Address, error), head func() (abi.ChainEpoch, error),
+	sign func(ctx context.Context, signer address.Address, data []byte) (crypto.Signature, error)) *Book {
+	return &Book{
+		topic:  topic,
+		client: client,
+		head:   head,
+		sign:   sign,
+		// Nonces start from the time so that they differ across restarts.
+		nonce:    uint64(time.Now().UnixNano()),
+		requests: map[cid.Cid]*bookEntry{},
+	}
+}
+
+// Publish publishes a request for bids on storing `size` bytes for `duration`
+// epochs at up to `maxPrice` per GiB per epoch. Miners bid on the request for
+// `validity` epochs.
+func (b *Book) Publish(ctx context.Context, size abi.PaddedPieceSize, duration abi.ChainEpoch, maxPrice abi.TokenAmount, validity abi.ChainEpoch) (SignedRequest, error) {
+	if validity <= 0 {
+		return SignedRequest{}, errors.New("request must be valid for at least one epoch")
+	}
+	client, err := b.client()
+	if err != nil {
+		return SignedRequest{}, err
+	}
+	epoch, err := b.head()
+	if err != nil {
+		return SignedRequest{}, err
+	}
+
+	b.lk.Lock()
+	b.nonce++
+	nonce := b.nonce
+	b.lk.Unlock()
+
+	signed, err := SignRequest(Request{
+		Client:   client,
+		Size:     size,
+		Duration: duration,
+		MaxPrice: maxPrice,
+		Epoch:    epoch,
+		Expiry:   epoch + validity,
+		Nonce:    nonce,
+	}, func(data []byte) (crypto.Signature, error) {
+		return b.sign(ctx, client, data)
+	})
+	if err != nil {
+		return SignedRequest{}, err
+	}
+
+	// The request is booked before it is published so that no bid is missed.
+	b.lk.Lock()
+	b.prune(epoch)
+	b.requests[signed.ID] = &bookEntry{request: *signed, bids: map[address.Address]SignedBid{}}
+	b.lk.Unlock()
+
+	payload, err := MakeRequestPayload(signed)
+	if err != nil {
+		return SignedRequest{}, err
+	}
+	if err := b.topic.Publish(ctx, payload); err != nil {
+		b.lk.Lock()
+		delete(b.requests, signed.ID)
+		b.lk.Unlock()
+		return SignedRequest{}, errors.Wrap(err, "failed to publish request")
+	}
+	return *signed, nil
+}
+
+// AddBid keeps a bid on a request of the book, unless it asks more than the
+// request's max price. It returns true if the bid was kept.
+func (b *Book) AddBid(bid *SignedBid) bool {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	entry, ok := b.requests[bid.Bid.Request]
+	if !ok {
+		return false
+	}
+	if entry.request.Request.MaxPrice.LessThan(bid.Bid.Price) {
+		return false
+	}
+	entry.bids[bid.Bid.Miner] = *bid
+	return true
+}
+
+// Request returns a request of the book.
+func (b *Book) Request(id cid.Cid) (SignedRequest, error) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	entry, ok := b.requests[id]
+	if !ok {
+		return SignedRequest{}, errors.Errorf("no storage request %s", id)
+	}
+	return entry.request, nil
+}
+
+// Bids returns the bids on a request that have not expired, cheapest first.
+func (b *Book) Bids(id cid.Cid) ([]SignedBid, error) {
+	epoch, err := b.head()
+	if err != nil {
+		return nil, err
+	}
+
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	entry, ok := b.requests[id]
+	if !ok {
+		return nil, errors.Errorf("no storage request %s", id)
+	}
+	out := make([]SignedBid, 0, len(entry.bids))
+	for _, bid := range entry.bids {
+		if bid.Bid.Expiry > epoch {
+			out = append(out, bid)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Bid.Price.Equals(out[j].Bid.Price) {
+			return out[i].Bid.Price.LessThan(out[j].Bid.Price)
+		}
+		return out[i].Bid.Miner.String() < out[j].Bid.Miner.String()
+	})
+	return out, nil
+}
+
+// prune drops the requests that have expired along with all their bids,
+// b.lk must be held.
+func (b *Book) prune(epoch abi.ChainEpoch) {
+	for id, entry := range b.requests {
+		if entry.request.Request.Expiry > epoch {
+			continue
+		}
+		live := false
+		for _, bid := range entry.bids {
+			if bid.Bid.Expiry > epoch {
+				live = true
+				break
+			}
+		}
+		if !live {
+			delete(b.requests, id)
+		}
+	}
+}
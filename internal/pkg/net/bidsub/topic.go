@@ -0,0 +1,185 @@
+package bidsub
+
+import (
+	"fmt"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/crypto"
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/constants"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+)
+
+// RequestTopic returns the network pubsub topic identifier on which clients
+// publish storage requests.
+func RequestTopic(networkName string) string {
+	return fmt.Sprintf("/fil/storage-requests/%s", networkName)
+}
+
+// BidTopic returns the network pubsub topic identifier on which miners bid on
+// storage requests.
+func BidTopic(networkName string) string {
+	return fmt.Sprintf("/fil/storage-bids/%s", networkName)
+}
+
+// Payload is the encoding of a signed request or bid on its topic.
+type Payload struct {
+	_ struct{} `cbor:",toarray"`
+	// Body is the encoded request or bid, which is signed.
+	Body      []byte
+	Signature crypto.Signature
+}
+
+// Request is a client's request for miners to bid on storing data.
+type Request struct {
+	_      struct{} `cbor:",toarray"`
+	Client address.Address
+	// Size is the padded size of the piece to store.
+	Size abi.PaddedPieceSize
+	// Duration is the number of epochs to store the piece for.
+	Duration abi.ChainEpoch
+	// MaxPrice is the highest price per GiB per epoch the client pays, in the
+	// unit of asks.
+	MaxPrice abi.TokenAmount
+	// Epoch is the chain height at which the request was made.
+	Epoch abi.ChainEpoch
+	// Expiry is the epoch from which miners no longer bid on the request.
+	Expiry abi.ChainEpoch
+	// Nonce tells apart requests of a client that are otherwise the same.
+	Nonce uint64
+}
+
+// Bytes returns the encoding of the request signed by the client.
+func (r *Request) Bytes() ([]byte, error) {
+	return encoding.Encode(r)
+}
+
+// SignedRequest is a request signed by its client.
+type SignedRequest struct {
+	// ID is the CID of the request, bids refer to it.
+	ID        cid.Cid
+	Request   Request
+	Signature crypto.Signature
+}
+
+// Bid is a miner's offer to store the data of a request.
+type Bid struct {
+	Request cid.Cid
+	Miner   address.Address
+	// Peer is the peer ID the miner takes deals on.
+	Peer peer.ID
+	// Price is the price per GiB per epoch a deal is made at, in the unit of
+	// asks.
+	Price abi.TokenAmount
+	// Expiry is the epoch until which the miner takes deals at the price.
+	Expiry abi.ChainEpoch
+}
+
+type bidBody struct {
+	_       struct{} `cbor:",toarray"`
+	Request cid.Cid
+	Miner   address.Address
+	Peer    []byte
+	Price   abi.TokenAmount
+	Expiry  abi.ChainEpoch
+}
+
+// Bytes returns the encoding of the bid signed by the miner's worker.
+func (b *Bid) Bytes() ([]byte, error) {
+	return encoding.Encode(bidBody{
+		Request: b.Request,
+		Miner:   b.Miner,
+		Peer:    []byte(b.Peer),
+		Price:   b.Price,
+		Expiry:  b.Expiry,
+	})
+}
+
+// SignedBid is a bid signed by the worker of its miner.
+type SignedBid struct {
+	Bid       Bid
+	Signature crypto.Signature
+}
+
+// MakeRequestPayload encodes a signed request for the request topic.
+func MakeRequestPayload(r *SignedRequest) ([]byte, error) {
+	body, err := r.Request.Bytes()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode request")
+	}
+	return encoding.Encode(Payload{Body: body, Signature: r.Signature})
+}
+
+// DecodeRequestPayload decodes a signed request of the request topic.
+func DecodeRequestPayload(raw []byte) (*SignedRequest, error) {
+	var payload Payload
+	if err := encoding.Decode(raw, &payload); err != nil {
+		return nil, err
+	}
+	var req Request
+	if err := encoding.Decode(payload.Body, &req); err != nil {
+		return nil, errors.Wrap(err, "failed to decode request")
+	}
+	id, err := constants.DefaultCidBuilder.Sum(payload.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedRequest{ID: id, Request: req, Signature: payload.Signature}, nil
+}
+
+// MakeBidPayload encodes a signed bid for the bid topic.
+func MakeBidPayload(b *SignedBid) ([]byte, error) {
+	body, err := b.Bid.Bytes()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode bid")
+	}
+	return encoding.Encode(Payload{Body: body, Signature: b.Signature})
+}
+
+// DecodeBidPayload decodes a signed bid of the bid topic.
+func DecodeBidPayload(raw []byte) (*SignedBid, error) {
+	var payload Payload
+	if err := encoding.Decode(raw, &payload); err != nil {
+		return nil, err
+	}
+	var body bidBody
+	if err := encoding.Decode(payload.Body, &body); err != nil {
+		return nil, errors.Wrap(err, "failed to decode bid")
+	}
+	pid, err := peer.IDFromBytes(body.Peer)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode peer ID")
+	}
+	return &SignedBid{
+		Bid: Bid{
+			Request: body.Request,
+			Miner:   body.Miner,
+			Peer:    pid,
+			Price:   body.Price,
+			Expiry:  body.Expiry,
+		},
+		Signature: payload.Signature,
+	}, nil
+}
+
+// SignRequest signs a request with sign, which signs bytes with the key of
+// the request's client.
+func SignRequest(r Request, sign func([]byte) (crypto.Signature, error)) (*SignedRequest, error) {
+	body, err := r.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	id, err := constants.DefaultCidBuilder.Sum(body)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := sign(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign request")
+	}
+	return &SignedRequest{ID: id, Request: r, Signature: sig}, nil
+}
@@ -0,0 +1,135 @@
+package bidsub
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-pubsub"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/metrics"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
+)
+
+var bidTopicLogger = log.Logger("net/bid_validator")
+var mInvalidRequest = metrics.NewInt64Counter("net/pubsub_invalid_storage_request", "Number of storage requests that fail to decode or validate seen on storage request pubsub channel")
+var mInvalidBid = metrics.NewInt64Counter("net/pubsub_invalid_storage_bid", "Number of bids that fail to decode or validate seen on storage bid pubsub channel")
+
+// StateView is the state request and bid signatures are verified against.
+type StateView interface {
+	state.AccountStateView
+	MinerControlAddresses(ctx context.Context, maddr address.Address) (owner, worker address.Address, err error)
+}
+
+// TopicValidator may be registered on go-libp2p-pubsub to validate the
+// payloads of the request or bid topic.
+type TopicValidator struct {
+	topic     func(network string) string
+	validator pubsub.Validator
+	opts      []pubsub.ValidatorOpt
+}
+
+// NewRequestTopicValidator returns a TopicValidator checking that requests
+// are well formed and signed by their client in the state returned by headView.
+func NewRequestTopicValidator(headView func() (StateView, error), opts ...pubsub.ValidatorOpt) *TopicValidator {
+	return &TopicValidator{
+		topic: RequestTopic,
+		opts:  opts,
+		validator: func(ctx context.Context, p peer.ID, msg *pubsub.Message) bool {
+			r, err := DecodeRequestPayload(msg.GetData())
+			if err != nil {
+				bidTopicLogger.Debugf("storage request from peer %s failed to decode: %s", p.String(), err.Error())
+				mInvalidRequest.Inc(ctx, 1)
+				return false
+			}
+			view, err := headView()
+			if err != nil {
+				bidTopicLogger.Debugf("failed to load state to validate storage request from peer %s: %s", p.String(), err.Error())
+				return false
+			}
+			if err := VerifyRequest(ctx, view, r); err != nil {
+				bidTopicLogger.Debugf("storage request %s from peer %s failed to validate: %s", r.ID, p.String(), err.Error())
+				mInvalidRequest.Inc(ctx, 1)
+				return false
+			}
+			return true
+		},
+	}
+}
+
+// NewBidTopicValidator returns a TopicValidator checking that bids are signed
+// by the worker of their miner in the state returned by headView.
+func NewBidTopicValidator(headView func() (StateView, error), opts ...pubsub.ValidatorOpt) *TopicValidator {
+	return &TopicValidator{
+		topic: BidTopic,
+		opts:  opts,
+		validator: func(ctx context.Context, p peer.ID, msg *pubsub.Message) bool {
+			b, err := DecodeBidPayload(msg.GetData())
+			if err != nil {
+				bidTopicLogger.Debugf("bid from peer %s failed to decode: %s", p.String(), err.Error())
+				mInvalidBid.Inc(ctx, 1)
+				return false
+			}
+			view, err := headView()
+			if err != nil {
+				bidTopicLogger.Debugf("failed to load state to validate bid from peer %s: %s", p.String(), err.Error())
+				return false
+			}
+			if err := VerifyBid(ctx, view, b); err != nil {
+				bidTopicLogger.Debugf("bid of miner %s from peer %s failed to validate: %s", b.Bid.Miner, p.String(), err.Error())
+				mInvalidBid.Inc(ctx, 1)
+				return false
+			}
+			return true
+		},
+	}
+}
+
+// VerifyRequest checks that a request is well formed and signed by its client.
+func VerifyRequest(ctx context.Context, view StateView, r *SignedRequest) error {
+	req := r.Request
+	if req.Size == 0 {
+		return errors.New("request has no size")
+	}
+	if req.Duration <= 0 {
+		return errors.New("request has no duration")
+	}
+	if req.MaxPrice.Nil() || req.MaxPrice.LessThan(bigZero) {
+		return errors.New("request has an invalid max price")
+	}
+	if req.Expiry <= req.Epoch {
+		return errors.Errorf("request expires at %d, before it was made at %d", req.Expiry, req.Epoch)
+	}
+	data, err := req.Bytes()
+	if err != nil {
+		return err
+	}
+	return state.NewSignatureValidator(view).ValidateSignature(ctx, data, req.Client, r.Signature)
+}
+
+// VerifyBid checks that a bid is signed by the worker of its miner.
+func VerifyBid(ctx context.Context, view StateView, b *SignedBid) error {
+	_, worker, err := view.MinerControlAddresses(ctx, b.Bid.Miner)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load worker of miner %s", b.Bid.Miner)
+	}
+	data, err := b.Bid.Bytes()
+	if err != nil {
+		return err
+	}
+	return state.NewSignatureValidator(view).ValidateSignature(ctx, data, worker, b.Signature)
+}
+
+func (tv *TopicValidator) Topic(network string) string {
+	return tv.topic(network)
+}
+
+func (tv *TopicValidator) Validator() pubsub.Validator {
+	return tv.validator
+}
+
+func (tv *TopicValidator) Opts() []pubsub.ValidatorOpt {
+	return tv.opts
+}
@@ -0,0 +1,157 @@
+package net
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	bsmsg "github.com/ipfs/go-bitswap/message"
+	bsnet "github.com/ipfs/go-bitswap/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
+)
+
+// NewLimitedBitswapNetwork wraps a bitswap network so that bitswap messages sent
+// to any single peer are throttled to at most `bytesPerSecond` on average. Each
+// peer may burst up to one second worth of data.
+func NewLimitedBitswapNetwork(inner bsnet.BitSwapNetwork, bytesPerSecond uint64, clk clock.Clock) bsnet.BitSwapNetwork {
+	return &limitedBitswapNetwork{
+		BitSwapNetwork: inner,
+		limiter:        newPeerLimiter(bytesPerSecond, clk),
+	}
+}
+
+type limitedBitswapNetwork struct {
+	bsnet.BitSwapNetwork
+	limiter *peerLimiter
+}
+
+func (n *limitedBitswapNetwork) SendMessage(ctx context.Context, p peer.ID, msg bsmsg.BitSwapMessage) error {
+	if err := n.limiter.wait(ctx, p, msg.Size()); err != nil {
+		return err
+	}
+	return n.BitSwapNetwork.SendMessage(ctx, p, msg)
+}
+
+func (n *limitedBitswapNetwork) NewMessageSender(ctx context.Context, p peer.ID) (bsnet.MessageSender, error) {
+	sender, err := n.BitSwapNetwork.NewMessageSender(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedMessageSender{MessageSender: sender, peer: p, limiter: n.limiter}, nil
+}
+
+// SetDelegate registers the receiver, intercepting disconnect notifications to
+// discard the state kept for disconnected peers.
+func (n *limitedBitswapNetwork) SetDelegate(r bsnet.Receiver) {
+	n.BitSwapNetwork.SetDelegate(&limitedReceiver{Receiver: r, limiter: n.limiter})
+}
+
+type limitedMessageSender struct {
+	bsnet.MessageSender
+	peer    peer.ID
+	limiter *peerLimiter
+}
+
+func (s *limitedMessageSender) SendMsg(ctx context.Context, msg bsmsg.BitSwapMessage) error {
+	if err := s.limiter.wait(ctx, s.peer, msg.Size()); err != nil {
+		return err
+	}
+	return s.MessageSender.SendMsg(ctx, msg)
+}
+
+type limitedReceiver struct {
+	bsnet.Receiver
+	limiter *peerLimiter
+}
+
+func (r *limitedReceiver) PeerDisconnected(p peer.ID) {
+	r.limiter.forget(p)
+	r.Receiver.PeerDisconnected(p)
+}
+
+// peerLimiter is a token bucket per peer. Sends larger than the bucket are
+// allowed to take it into debt, so a single large message is delayed rather
+// than rejected.
+type peerLimiter struct {
+	rate  float64
+	clock clock.Clock
+
+	lk      sync.Mutex
+	buckets map[peer.ID]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newPeerLimiter(bytesPerSecond uint64, clk clock.Clock) *peerLimiter {
+	return &peerLimiter{
+		rate:    float64(bytesPerSecond),
+		clock:   clk,
+		buckets: make(map[peer.ID]*bucket),
+	}
+}
+
+// reserve takes `size` bytes from the peer's bucket and returns how long the
+// caller must wait before sending them. Nothing is reserved at a zero rate,
+// which is unlimited.
+func (l *peerLimiter) reserve(p peer.ID, size int) time.Duration {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	if l.rate == 0 {
+		return 0
+	}
+	now := l.clock.Now()
+	b, ok := l.buckets[p]
+	if !ok {
+		b = &bucket{tokens: l.rate, last: now}
+		l.buckets[p] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.rate {
+		b.tokens = l.rate
+	}
+	b.last = now
+
+	b.tokens -= float64(size)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / l.rate * float64(time.Second))
+}
+
+func (l *peerLimiter) wait(ctx context.Context, p peer.ID, size int) error {
+	return sleep(ctx, l.clock, l.reserve(p, size))
+}
+
+func (l *peerLimiter) forget(p peer.ID) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+	delete(l.buckets, p)
+}
+
+// setRate changes the rate of the limiter, refilling the buckets of all peers.
+func (l *peerLimiter) setRate(bytesPerSecond uint64) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+	l.rate = float64(bytesPerSecond)
+	l.buckets = make(map[peer.ID]*bucket)
+}
+
+func sleep(ctx context.Context, clk clock.Clock, delay time.Duration) error {
+	if delay == 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-clk.After(delay):
+		return nil
+	}
+}
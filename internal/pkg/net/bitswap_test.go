@@ -0,0 +1,70 @@
+package net
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestPeerLimiterReserve(t *testing.T) {
+	tf.UnitTest(t)
+
+	clk := clock.NewFake(time.Unix(1234567890, 0))
+	l := newPeerLimiter(1000, clk)
+	p1 := peer.ID("peer1")
+	p2 := peer.ID("peer2")
+
+	t.Log("a fresh peer may burst one second worth of data")
+	assert.Equal(t, time.Duration(0), l.reserve(p1, 1000))
+
+	t.Log("sending beyond the burst incurs a proportional delay")
+	assert.Equal(t, 500*time.Millisecond, l.reserve(p1, 500))
+
+	t.Log("peers are limited independently")
+	assert.Equal(t, time.Duration(0), l.reserve(p2, 1000))
+
+	t.Log("the bucket refills over time")
+	clk.Advance(2 * time.Second)
+	assert.Equal(t, time.Duration(0), l.reserve(p1, 1000))
+
+	t.Log("the bucket never holds more than the burst")
+	clk.Advance(time.Minute)
+	assert.Equal(t, time.Second, l.reserve(p2, 2000))
+
+	t.Log("forgotten peers start with a full bucket")
+	l.forget(p2)
+	assert.Equal(t, time.Duration(0), l.reserve(p2, 1000))
+}
+
+func TestPeerLimiterWait(t *testing.T) {
+	tf.UnitTest(t)
+
+	clk := clock.NewFake(time.Unix(1234567890, 0))
+	l := newPeerLimiter(1000, clk)
+	p := peer.ID("peer")
+
+	require.NoError(t, l.wait(context.Background(), p, 1000))
+
+	done := make(chan error)
+	go func() {
+		done <- l.wait(context.Background(), p, 1000)
+	}()
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+	require.NoError(t, <-done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		done <- l.wait(ctx, p, 1000)
+	}()
+	clk.BlockUntil(1)
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+}
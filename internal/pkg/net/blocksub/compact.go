@@ -0,0 +1,228 @@
+package blocksub
+
+import (
+	"context"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dss "github.com/ipfs/go-datastore/sync"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/consensus"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/metrics"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+var mReconstructedBlk = metrics.NewInt64Counter("net/pubsub_block_reconstructed", "Number of announced blocks reconstructed from local messages")
+var mIncompleteBlk = metrics.NewInt64Counter("net/pubsub_block_incomplete", "Number of announced blocks not reconstructed because messages could not be fetched")
+var mFetchedMsg = metrics.NewInt64Counter("net/pubsub_block_fetched_messages", "Number of messages of announced blocks fetched from the network")
+
+// messageFetchTimeout bounds the fetch of the messages of an announced block
+// missing locally. It is short against the block time, so that a block whose
+// messages cannot be found does not hold up the topic for long.
+const messageFetchTimeout = 5 * time.Second
+
+// MessagePool gives the pending messages known to the node.
+type MessagePool interface {
+	Get(c cid.Cid) (*types.SignedMessage, bool)
+	Pending() []*types.SignedMessage
+}
+
+// MessageFetcher fetches the ipld blocks of messages from the network.
+type MessageFetcher interface {
+	GetBlocks(ctx context.Context, ks []cid.Cid) <-chan blocks.Block
+}
+
+// MessageStorer stores the messages of a block, returning the cid of their
+// collection.
+type MessageStorer interface {
+	StoreMessages(ctx context.Context, secpMessages []*types.SignedMessage, blsMessages []*types.UnsignedMessage) (cid.Cid, error)
+}
+
+// Reconstructor rebuilds announced blocks from their header and message CIDs.
+//
+// Announcements carry only the CIDs of the messages of a block, most of which
+// a node already holds in its message pool. The messages missing locally are
+// fetched by their CID from the network, so that only those are transferred
+// rather than the whole block. Blocks whose messages are all found are stored
+// so that the syncer finds them complete; the syncer fetches the others as it
+// would otherwise. Nothing is fetched or stored unless the header is
+// syntactically valid, and the block is not stored unless the messages match
+// it. Fetched messages are content addressed, so the exchange may keep them
+// even when they do not match the header.
+type Reconstructor struct {
+	pool      MessagePool
+	store     bstore.Blockstore
+	fetcher   MessageFetcher
+	messages  MessageStorer
+	validator consensus.BlockSyntaxValidator
+}
+
+// NewReconstructor creates a reconstructor of announced blocks. The fetcher
+// may be nil, in which case blocks with messages missing locally are not
+// reconstructed.
+func NewReconstructor(pool MessagePool, store bstore.Blockstore, fetcher MessageFetcher, messages MessageStorer, validator consensus.BlockSyntaxValidator) *Reconstructor {
+	return &Reconstructor{
+		pool:      pool,
+		store:     store,
+		fetcher:   fetcher,
+		messages:  messages,
+		validator: validator,
+	}
+}
+
+// Reconstruct rebuilds the block of an announcement from local messages and
+// the missing ones fetched from the network, and stores it with its messages.
+// It fails if the header is invalid, if messages cannot be fetched, or if the
+// messages do not match the header.
+func (r *Reconstructor) Reconstruct(ctx context.Context, payload *Payload) error {
+	if err := r.validator.ValidateSyntax(ctx, &payload.Header); err != nil {
+		return errors.Wrapf(err, "invalid block %s", payload.Header.Cid())
+	}
+
+	// the messages missing locally, by cid, each decoded in place once fetched
+	missing := map[cid.Cid]interface{}{}
+	secpMsgs := make([]*types.SignedMessage, len(payload.SECPMsgCids))
+	for i, c := range payload.SECPMsgCids {
+		if msg, ok := r.pool.Get(c.Cid); ok {
+			secpMsgs[i] = msg
+			continue
+		}
+		if msg, ok := missing[c.Cid].(*types.SignedMessage); ok {
+			secpMsgs[i] = msg
+			continue
+		}
+		msg := &types.SignedMessage{}
+		ok, err := r.load(c.Cid, msg)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			missing[c.Cid] = msg
+		}
+		secpMsgs[i] = msg
+	}
+
+	// the pool holds BLS messages signed, indexed by the CID of the signed
+	// message, where announcements name them by their unsigned CID
+	var blsPending map[cid.Cid]*types.UnsignedMessage
+	if len(payload.BLSMsgCids) > 0 {
+		blsPending = r.pendingUnsigned()
+	}
+	blsMsgs := make([]*types.UnsignedMessage, len(payload.BLSMsgCids))
+	for i, c := range payload.BLSMsgCids {
+		if msg, ok := blsPending[c.Cid]; ok {
+			blsMsgs[i] = msg
+			continue
+		}
+		if msg, ok := missing[c.Cid].(*types.UnsignedMessage); ok {
+			blsMsgs[i] = msg
+			continue
+		}
+		msg := &types.UnsignedMessage{}
+		ok, err := r.load(c.Cid, msg)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			missing[c.Cid] = msg
+		}
+		blsMsgs[i] = msg
+	}
+	if len(missing) > 0 {
+		if err := r.fetch(ctx, missing); err != nil {
+			mIncompleteBlk.Inc(ctx, 1)
+			return errors.Wrapf(err, "messages of block %s are missing", payload.Header.Cid())
+		}
+	}
+
+	// the collection of the messages is computed in memory first, so that
+	// messages not matching the header are not stored
+	metaCid, err := chain.NewMessageStore(bstore.NewBlockstore(dss.MutexWrap(datastore.NewMapDatastore()))).StoreMessages(ctx, secpMsgs, blsMsgs)
+	if err != nil {
+		return errors.Wrapf(err, "failed to compute messages of block %s", payload.Header.Cid())
+	}
+	if !metaCid.Equals(payload.Header.Messages.Cid) {
+		return errors.Errorf("messages of block %s do not match its header, collection %s, expected %s", payload.Header.Cid(), metaCid, payload.Header.Messages.Cid)
+	}
+	if _, err := r.messages.StoreMessages(ctx, secpMsgs, blsMsgs); err != nil {
+		return errors.Wrapf(err, "failed to store messages of block %s", payload.Header.Cid())
+	}
+	if err := r.putHeader(&payload.Header); err != nil {
+		return err
+	}
+
+	mReconstructedBlk.Inc(ctx, 1)
+	return nil
+}
+
+func (r *Reconstructor) pendingUnsigned() map[cid.Cid]*types.UnsignedMessage {
+	out := map[cid.Cid]*types.UnsignedMessage{}
+	for _, msg := range r.pool.Pending() {
+		c, err := msg.Message.Cid()
+		if err != nil {
+			continue
+		}
+		out[c] = &msg.Message
+	}
+	return out
+}
+
+// fetch fetches the messages in `missing` from the network, decoding each into
+// its value. It fails unless all of them arrive within messageFetchTimeout.
+func (r *Reconstructor) fetch(ctx context.Context, missing map[cid.Cid]interface{}) error {
+	if r.fetcher == nil {
+		return errors.Errorf("%d messages are missing locally", len(missing))
+	}
+	ctx, cancel := context.WithTimeout(ctx, messageFetchTimeout)
+	defer cancel()
+
+	ks := make([]cid.Cid, 0, len(missing))
+	for c := range missing {
+		ks = append(ks, c)
+	}
+	fetched := 0
+	for blk := range r.fetcher.GetBlocks(ctx, ks) {
+		out, ok := missing[blk.Cid()]
+		if !ok {
+			continue
+		}
+		if err := encoding.Decode(blk.RawData(), out); err != nil {
+			return errors.Wrapf(err, "failed to decode message %s", blk.Cid())
+		}
+		fetched++
+	}
+	mFetchedMsg.Inc(ctx, int64(fetched))
+	if fetched < len(missing) {
+		return errors.Errorf("fetched %d of %d messages missing locally", fetched, len(missing))
+	}
+	return nil
+}
+
+// load decodes the object c when it is stored locally.
+func (r *Reconstructor) load(c cid.Cid, out interface{}) (bool, error) {
+	blk, err := r.store.Get(c)
+	if err == bstore.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := encoding.Decode(blk.RawData(), out); err != nil {
+		return false, errors.Wrapf(err, "failed to decode message %s", c)
+	}
+	return true, nil
+}
+
+func (r *Reconstructor) putHeader(header *block.Block) error {
+	if err := r.store.Put(header.ToNode()); err != nil {
+		return errors.Wrapf(err, "failed to store block %s", header.Cid())
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package blocksub_test
+
+import (
+	"context"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dss "github.com/ipfs/go-datastore/sync"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
+	e "github.com/filecoin-project/go-filecoin/internal/pkg/enccid"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/blocksub"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
+)
+
+func TestReconstructor(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	mm := vm.NewMessageMaker(t, types.MustGenerateKeyInfo(2, 42))
+	alice, bob := mm.Addresses()[0], mm.Addresses()[1]
+
+	pooledSecp := mm.NewSignedMessage(alice, 1)
+	storedSecp := mm.NewSignedMessage(alice, 2)
+	pooledBLS := mm.NewSignedMessage(bob, 1)
+	storedBLS := mm.NewSignedMessage(bob, 2)
+
+	metaCid, err := chain.NewMessageStore(newStore()).StoreMessages(ctx,
+		[]*types.SignedMessage{pooledSecp, storedSecp},
+		[]*types.UnsignedMessage{&pooledBLS.Message, &storedBLS.Message})
+	require.NoError(t, err)
+	header := &block.Block{Miner: alice, Height: 1, Messages: e.NewCid(metaCid)}
+	raw, err := blocksub.MakePayload(header, []*types.SignedMessage{pooledBLS, storedBLS}, []*types.SignedMessage{pooledSecp, storedSecp})
+	require.NoError(t, err)
+	var payload blocksub.Payload
+	require.NoError(t, encoding.Decode(raw, &payload))
+
+	t.Run("reconstructs from the pool and the store", func(t *testing.T) {
+		local := newStore()
+		storeMessage(t, local, storedSecp)
+		storeMessage(t, local, &storedBLS.Message)
+		r := blocksub.NewReconstructor(newFakePool(pooledSecp, pooledBLS), local, nil, chain.NewMessageStore(local), &fakeValidator{})
+		require.NoError(t, r.Reconstruct(ctx, &payload))
+
+		has, err := local.Has(header.Cid())
+		require.NoError(t, err)
+		assert.True(t, has)
+		secp, bls, err := chain.NewMessageStore(local).LoadMessages(ctx, metaCid)
+		require.NoError(t, err)
+		assert.Equal(t, []*types.SignedMessage{pooledSecp, storedSecp}, secp)
+		assert.Equal(t, []*types.UnsignedMessage{&pooledBLS.Message, &storedBLS.Message}, bls)
+	})
+
+	t.Run("fetches only the messages missing locally", func(t *testing.T) {
+		local := newStore()
+		fetcher := newFakeFetcher(t, storedSecp, &storedBLS.Message, pooledSecp)
+		r := blocksub.NewReconstructor(newFakePool(pooledSecp, pooledBLS), local, fetcher, chain.NewMessageStore(local), &fakeValidator{})
+		require.NoError(t, r.Reconstruct(ctx, &payload))
+
+		storedSecpCid, err := storedSecp.Cid()
+		require.NoError(t, err)
+		storedBLSCid, err := storedBLS.Message.Cid()
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []cid.Cid{storedSecpCid, storedBLSCid}, fetcher.requested)
+
+		has, err := local.Has(header.Cid())
+		require.NoError(t, err)
+		assert.True(t, has)
+		secp, bls, err := chain.NewMessageStore(local).LoadMessages(ctx, metaCid)
+		require.NoError(t, err)
+		assert.Equal(t, []*types.SignedMessage{pooledSecp, storedSecp}, secp)
+		assert.Equal(t, []*types.UnsignedMessage{&pooledBLS.Message, &storedBLS.Message}, bls)
+	})
+
+	t.Run("fails on messages that cannot be fetched", func(t *testing.T) {
+		local := newStore()
+		fetcher := newFakeFetcher(t, storedSecp)
+		r := blocksub.NewReconstructor(newFakePool(pooledSecp, pooledBLS), local, fetcher, chain.NewMessageStore(local), &fakeValidator{})
+		assert.Error(t, r.Reconstruct(ctx, &payload))
+
+		has, err := local.Has(header.Cid())
+		require.NoError(t, err)
+		assert.False(t, has)
+	})
+
+	t.Run("fails on messages missing locally without a fetcher", func(t *testing.T) {
+		local := newStore()
+		r := blocksub.NewReconstructor(newFakePool(pooledSecp, pooledBLS), local, nil, chain.NewMessageStore(local), &fakeValidator{})
+		assert.Error(t, r.Reconstruct(ctx, &payload))
+
+		has, err := local.Has(header.Cid())
+		require.NoError(t, err)
+		assert.False(t, has)
+	})
+
+	t.Run("stores nothing for invalid headers", func(t *testing.T) {
+		local := newStore()
+		validator := &fakeValidator{err: errors.New("block from the future")}
+		r := blocksub.NewReconstructor(newFakePool(pooledSecp, pooledBLS, storedSecp, storedBLS), local, nil, chain.NewMessageStore(local), validator)
+		assert.Error(t, r.Reconstruct(ctx, &payload))
+
+		keys, err := local.AllKeysChan(ctx)
+		require.NoError(t, err)
+		_, stored := <-keys
+		assert.False(t, stored)
+	})
+
+	t.Run("stores nothing for messages not matching the header", func(t *testing.T) {
+		other := newFakePool(pooledSecp, pooledBLS, storedSecp, storedBLS)
+		badHeader := *header
+		badHeader.Messages = e.NewCid(types.EmptyMessagesCID)
+		badRaw, err := blocksub.MakePayload(&badHeader, []*types.SignedMessage{pooledBLS, storedBLS}, []*types.SignedMessage{pooledSecp, storedSecp})
+		require.NoError(t, err)
+		var badPayload blocksub.Payload
+		require.NoError(t, encoding.Decode(badRaw, &badPayload))
+
+		local := newStore()
+		r := blocksub.NewReconstructor(other, local, nil, chain.NewMessageStore(local), &fakeValidator{})
+		assert.Error(t, r.Reconstruct(ctx, &badPayload))
+
+		keys, err := local.AllKeysChan(ctx)
+		require.NoError(t, err)
+		_, stored := <-keys
+		assert.False(t, stored)
+	})
+}
+
+func newStore() bstore.Blockstore {
+	return bstore.NewBlockstore(dss.MutexWrap(datastore.NewMapDatastore()))
+}
+
+type fakePool struct {
+	msgs map[cid.Cid]*types.SignedMessage
+}
+
+func newFakePool(msgs ...*types.SignedMessage) *fakePool {
+	p := &fakePool{msgs: map[cid.Cid]*types.SignedMessage{}}
+	for _, m := range msgs {
+		c, err := m.Cid()
+		if err != nil {
+			panic(err)
+		}
+		p.msgs[c] = m
+	}
+	return p
+}
+
+func (p *fakePool) Get(c cid.Cid) (*types.SignedMessage, bool) {
+	m, ok := p.msgs[c]
+	return m, ok
+}
+
+func (p *fakePool) Pending() []*types.SignedMessage {
+	var out []*types.SignedMessage
+	for _, m := range p.msgs {
+		out = append(out, m)
+	}
+	return out
+}
+
+// fakeFetcher serves the blocks of messages held by other nodes.
+type fakeFetcher struct {
+	blocks    map[cid.Cid]blocks.Block
+	requested []cid.Cid
+}
+
+func newFakeFetcher(t *testing.T, msgs ...interface{ ToNode() (ipld.Node, error) }) *fakeFetcher {
+	f := &fakeFetcher{blocks: map[cid.Cid]blocks.Block{}}
+	for _, m := range msgs {
+		node, err := m.ToNode()
+		require.NoError(t, err)
+		f.blocks[node.Cid()] = node
+	}
+	return f
+}
+
+func (f *fakeFetcher) GetBlocks(_ context.Context, ks []cid.Cid) <-chan blocks.Block {
+	f.requested = append(f.requested, ks...)
+	out := make(chan blocks.Block, len(ks))
+	for _, c := range ks {
+		if blk, ok := f.blocks[c]; ok {
+			out <- blk
+		}
+	}
+	close(out)
+	return out
+}
+
+type fakeValidator struct {
+	err error
+}
+
+func (v *fakeValidator) ValidateSyntax(context.Context, *block.Block) error {
+	return v.err
+}
+
+// storeMessage stores a message as the announcing node would have relayed it.
+func storeMessage(t *testing.T, store bstore.Blockstore, m interface{ ToNode() (ipld.Node, error) }) {
+	node, err := m.ToNode()
+	require.NoError(t, err)
+	require.NoError(t, store.Put(node))
+}
@@ -0,0 +1,286 @@
+package net
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+)
+
+var logPeerScores = logging.Logger("net/peerscores")
+
+// PeerScoresDSPrefix is the prefix of the datastore keys of peer scores.
+const PeerScoresDSPrefix = "/peers/scores"
+
+// BanThreshold is the score at which a peer is banned for the ban duration.
+const BanThreshold = 100
+
+// scoreHalfLife is the time after which the score of a peer is halved, so
+// that occasional offenses are forgiven while repeated ones lead to a ban.
+const scoreHalfLife = time.Hour
+
+// Offense is a misbehaviour of a peer, raising its score.
+type Offense string
+
+const (
+	// OffenseInvalidBlock is a block failing validation served by the peer.
+	OffenseInvalidBlock = Offense("invalid-block")
+	// OffenseProtocolViolation is a malformed message or a message breaking
+	// the rules of a protocol sent by the peer.
+	OffenseProtocolViolation = Offense("protocol-violation")
+	// OffenseTimeout is a request the peer did not answer in time.
+	OffenseTimeout = Offense("timeout")
+)
+
+// offensePenalties are the scores of offenses.
+var offensePenalties = map[Offense]uint64{
+	OffenseInvalidBlock:      50,
+	OffenseProtocolViolation: 25,
+	OffenseTimeout:           5,
+}
+
+// BannedPeer is a peer whose connections are refused. Until is zero for
+// peers banned until they are unbanned.
+type BannedPeer struct {
+	Peer   peer.ID
+	Until  time.Time
+	Reason string
+}
+
+// peerScore is the persisted record of a peer.
+type peerScore struct {
+	_ struct{} `cbor:",toarray"`
+	// Score as of Updated, in unix seconds.
+	Score   uint64
+	Updated int64
+	// BannedUntil is the end of the ban in unix seconds, zero when the peer
+	// is not banned, unless Permanent.
+	BannedUntil int64
+	Permanent   bool
+	Reason      string
+}
+
+func (s *peerScore) banned(now time.Time) bool {
+	return s.Permanent || s.BannedUntil > now.Unix()
+}
+
+// PeerScores scores the offenses of peers and bans those reaching the
+// BanThreshold for a while. Operators may also ban and unban peers. Scores
+// and bans are persisted, so they survive restarts. Its methods are thread
+// safe.
+type PeerScores struct {
+	clock       clock.Clock
+	ds          datastore.Datastore
+	banDuration time.Duration
+
+	lk      sync.Mutex
+	peers   map[peer.ID]*peerScore
+	network network.Network
+}
+
+// NewPeerScores creates peer scores banning peers for `banDuration` and loads
+// the scores and bans persisted before in `ds`.
+func NewPeerScores(ds datastore.Batching, banDuration time.Duration, clk clock.Clock) (*PeerScores, error) {
+	ps := &PeerScores{
+		clock:       clk,
+		ds:          namespace.Wrap(ds, datastore.NewKey(PeerScoresDSPrefix)),
+		banDuration: banDuration,
+		peers:       map[peer.ID]*peerScore{},
+	}
+
+	res, err := ps.ds.Query(query.Query{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query peer scores")
+	}
+	defer res.Close() // nolint: errcheck
+	for entry := range res.Next() {
+		if entry.Error != nil {
+			return nil, errors.Wrap(entry.Error, "failed to load peer scores")
+		}
+		p, err := peer.Decode(datastore.RawKey(entry.Key).Name())
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid peer score key %s", entry.Key)
+		}
+		var s peerScore
+		if err := encoding.Decode(entry.Value, &s); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode score of peer %s", p)
+		}
+		ps.peers[p] = &s
+	}
+	return ps, nil
+}
+
+// Enforce closes the connections of banned peers to `h`, from then on as soon
+// as they connect or are banned.
+func (ps *PeerScores) Enforce(h host.Host) {
+	ps.lk.Lock()
+	ps.network = h.Network()
+	ps.lk.Unlock()
+
+	h.Network().Notify((*peerScoresNotifiee)(ps))
+	for _, p := range h.Network().Peers() {
+		if ps.IsBanned(p) {
+			ps.disconnect(p)
+		}
+	}
+}
+
+// Penalize raises the score of `p` for `offense`, banning it when the score
+// reaches the BanThreshold. It returns whether the peer is banned.
+func (ps *PeerScores) Penalize(p peer.ID, offense Offense) bool {
+	ps.lk.Lock()
+	defer ps.lk.Unlock()
+
+	now := ps.clock.Now()
+	s := ps.record(p, now)
+	if s.banned(now) {
+		return true
+	}
+	s.Score += offensePenalties[offense]
+	banned := s.Score >= BanThreshold
+	if banned {
+		logPeerScores.Warnw("banning peer", "peer", p, "offense", offense, "duration", ps.banDuration)
+		s.Score = 0
+		s.BannedUntil = now.Add(ps.banDuration).Unix()
+		s.Reason = "score reached after " + string(offense)
+	}
+	if err := ps.store(p, s); err != nil {
+		logPeerScores.Errorf("failed to store score of peer %s: %s", p, err)
+	}
+	if banned {
+		go ps.disconnect(p)
+	}
+	return banned
+}
+
+// Ban bans `p` for `duration`, unless it is zero in which case the peer stays
+// banned until it is unbanned.
+func (ps *PeerScores) Ban(p peer.ID, duration time.Duration, reason string) error {
+	ps.lk.Lock()
+	defer ps.lk.Unlock()
+
+	now := ps.clock.Now()
+	s := ps.record(p, now)
+	s.Permanent = duration == 0
+	s.BannedUntil = 0
+	if !s.Permanent {
+		s.BannedUntil = now.Add(duration).Unix()
+	}
+	s.Reason = reason
+	if err := ps.store(p, s); err != nil {
+		return err
+	}
+	go ps.disconnect(p)
+	return nil
+}
+
+// Unban lifts the ban of `p` and forgets its offenses.
+func (ps *PeerScores) Unban(p peer.ID) error {
+	ps.lk.Lock()
+	defer ps.lk.Unlock()
+
+	s, ok := ps.peers[p]
+	if !ok || !s.banned(ps.clock.Now()) {
+		return errors.Errorf("peer %s is not banned", p)
+	}
+	if err := ps.ds.Delete(datastore.NewKey(p.Pretty())); err != nil {
+		return errors.Wrapf(err, "failed to delete score of peer %s", p)
+	}
+	delete(ps.peers, p)
+	return nil
+}
+
+// IsBanned returns whether `p` is banned.
+func (ps *PeerScores) IsBanned(p peer.ID) bool {
+	ps.lk.Lock()
+	defer ps.lk.Unlock()
+	s, ok := ps.peers[p]
+	return ok && s.banned(ps.clock.Now())
+}
+
+// Banned lists the banned peers, ordered by peer id.
+func (ps *PeerScores) Banned() []BannedPeer {
+	ps.lk.Lock()
+	defer ps.lk.Unlock()
+
+	now := ps.clock.Now()
+	var out []BannedPeer
+	for p, s := range ps.peers {
+		if !s.banned(now) {
+			continue
+		}
+		banned := BannedPeer{Peer: p, Reason: s.Reason}
+		if !s.Permanent {
+			banned.Until = time.Unix(s.BannedUntil, 0)
+		}
+		out = append(out, banned)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Peer < out[j].Peer })
+	return out
+}
+
+// record returns the record of `p` with its score decayed to `now`.
+func (ps *PeerScores) record(p peer.ID, now time.Time) *peerScore {
+	s, ok := ps.peers[p]
+	if !ok {
+		s = &peerScore{Updated: now.Unix()}
+		ps.peers[p] = s
+	}
+	if halvings := now.Sub(time.Unix(s.Updated, 0)) / scoreHalfLife; halvings > 0 {
+		if halvings >= 64 {
+			s.Score = 0
+		} else {
+			s.Score >>= uint(halvings)
+		}
+		s.Updated = time.Unix(s.Updated, 0).Add(halvings * scoreHalfLife).Unix()
+	}
+	return s
+}
+
+func (ps *PeerScores) store(p peer.ID, s *peerScore) error {
+	raw, err := encoding.Encode(s)
+	if err != nil {
+		return err
+	}
+	return ps.ds.Put(datastore.NewKey(p.Pretty()), raw)
+}
+
+func (ps *PeerScores) disconnect(p peer.ID) {
+	ps.lk.Lock()
+	n := ps.network
+	ps.lk.Unlock()
+	if n == nil {
+		return
+	}
+	if err := n.ClosePeer(p); err != nil {
+		logPeerScores.Debugf("failed to disconnect banned peer %s: %s", p, err)
+	}
+}
+
+// Note: hide `network.Notifiee` impl using a new-type
+type peerScoresNotifiee PeerScores
+
+func (pn *peerScoresNotifiee) Connected(n network.Network, c network.Conn) {
+	if (*PeerScores)(pn).IsBanned(c.RemotePeer()) {
+		logPeerScores.Debugf("closing connection of banned peer %s", c.RemotePeer())
+		go func() { _ = c.Close() }()
+	}
+}
+
+func (pn *peerScoresNotifiee) Listen(n network.Network, a ma.Multiaddr)         { /* empty */ }
+func (pn *peerScoresNotifiee) ListenClose(n network.Network, a ma.Multiaddr)    { /* empty */ }
+func (pn *peerScoresNotifiee) Disconnected(n network.Network, c network.Conn)   { /* empty */ }
+func (pn *peerScoresNotifiee) OpenedStream(n network.Network, s network.Stream) { /* empty */ }
+func (pn *peerScoresNotifiee) ClosedStream(n network.Network, s network.Stream) { /* empty */ }
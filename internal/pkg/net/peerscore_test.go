@@ -0,0 +1,64 @@
+package net
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
+	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestPeerScores(t *testing.T) {
+	tf.UnitTest(t)
+
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	clk := clock.NewFake(time.Unix(1234567890, 0))
+	ps, err := NewPeerScores(ds, time.Hour, clk)
+	require.NoError(t, err)
+	p1 := th.RequireIntPeerID(t, 1)
+	p2 := th.RequireIntPeerID(t, 2)
+
+	t.Log("peers are banned once their score reaches the threshold")
+	assert.False(t, ps.Penalize(p1, OffenseInvalidBlock))
+	assert.False(t, ps.Penalize(p1, OffenseProtocolViolation))
+	assert.True(t, ps.Penalize(p1, OffenseProtocolViolation))
+	assert.True(t, ps.IsBanned(p1))
+	require.Len(t, ps.Banned(), 1)
+	assert.Equal(t, p1, ps.Banned()[0].Peer)
+	assert.Equal(t, clk.Now().Add(time.Hour).Unix(), ps.Banned()[0].Until.Unix())
+
+	t.Log("scores decay over time")
+	assert.False(t, ps.Penalize(p2, OffenseInvalidBlock))
+	clk.Advance(scoreHalfLife)
+	assert.False(t, ps.Penalize(p2, OffenseInvalidBlock))
+	assert.False(t, ps.IsBanned(p2))
+
+	t.Log("temporary bans expire")
+	clk.Advance(time.Hour)
+	assert.False(t, ps.IsBanned(p1))
+	assert.Empty(t, ps.Banned())
+
+	t.Log("manual bans last until unbanned")
+	require.NoError(t, ps.Ban(p2, 0, "spam"))
+	clk.Advance(1000 * time.Hour)
+	assert.True(t, ps.IsBanned(p2))
+	assert.Equal(t, []BannedPeer{{Peer: p2, Reason: "spam"}}, ps.Banned())
+
+	t.Log("bans are persisted")
+	reloaded, err := NewPeerScores(ds, time.Hour, clk)
+	require.NoError(t, err)
+	assert.True(t, reloaded.IsBanned(p2))
+
+	require.NoError(t, reloaded.Unban(p2))
+	assert.False(t, reloaded.IsBanned(p2))
+	assert.Error(t, reloaded.Unban(p2))
+	reloaded, err = NewPeerScores(ds, time.Hour, clk)
+	require.NoError(t, err)
+	assert.False(t, reloaded.IsBanned(p2))
+}
@@ -56,3 +56,8 @@ func (r *Router) GetClosestPeers(ctx context.Context, key string) (<-chan peer.I
 	}
 	return ipfsDHT.GetClosestPeers(ctx, key)
 }
+
+// Provide announces to the network that this node can provide the given key.
+func (r *Router) Provide(ctx context.Context, key cid.Cid) error {
+	return r.routing.Provide(ctx, key, true)
+}
@@ -0,0 +1,190 @@
+package net
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
+)
+
+// throttleChunk is the most bytes written to a throttled stream at once, so
+// that large writes are spread over time rather than delayed then sent at once.
+const throttleChunk = 64 << 10
+
+// allPeers is the key of the buckets of the limits across all peers.
+const allPeers = peer.ID("")
+
+// TransferLimits are the rates in bytes per second at which data transfers
+// send and receive data, across all peers and with any single peer. Zero means
+// unlimited.
+type TransferLimits struct {
+	Upload       uint64
+	Download     uint64
+	PeerUpload   uint64
+	PeerDownload uint64
+}
+
+// Throttle limits the rate of data transfers to the TransferLimits, which may
+// be changed while transfers are running. Each limit may burst up to one
+// second worth of data.
+type Throttle struct {
+	clock clock.Clock
+
+	lk     sync.Mutex
+	limits TransferLimits
+
+	upload       *peerLimiter
+	download     *peerLimiter
+	peerUpload   *peerLimiter
+	peerDownload *peerLimiter
+}
+
+// NewThrottle creates a throttle with the limits `limits`.
+func NewThrottle(limits TransferLimits, clk clock.Clock) *Throttle {
+	return &Throttle{
+		clock:        clk,
+		limits:       limits,
+		upload:       newPeerLimiter(limits.Upload, clk),
+		download:     newPeerLimiter(limits.Download, clk),
+		peerUpload:   newPeerLimiter(limits.PeerUpload, clk),
+		peerDownload: newPeerLimiter(limits.PeerDownload, clk),
+	}
+}
+
+// Limits returns the current limits.
+func (t *Throttle) Limits() TransferLimits {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	return t.limits
+}
+
+// SetLimits changes the limits, which apply to the transfers running from then.
+func (t *Throttle) SetLimits(limits TransferLimits) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	t.limits = limits
+	t.upload.setRate(limits.Upload)
+	t.download.setRate(limits.Download)
+	t.peerUpload.setRate(limits.PeerUpload)
+	t.peerDownload.setRate(limits.PeerDownload)
+}
+
+// reserveUpload takes `size` bytes from the upload limits and returns how long
+// the caller must wait before sending them to `p`.
+func (t *Throttle) reserveUpload(p peer.ID, size int) time.Duration {
+	return maxDelay(t.upload.reserve(allPeers, size), t.peerUpload.reserve(p, size))
+}
+
+// reserveDownload takes `size` bytes from the download limits and returns how
+// long the caller must wait before receiving more from `p`.
+func (t *Throttle) reserveDownload(p peer.ID, size int) time.Duration {
+	return maxDelay(t.download.reserve(allPeers, size), t.peerDownload.reserve(p, size))
+}
+
+func (t *Throttle) forget(p peer.ID) {
+	t.peerUpload.forget(p)
+	t.peerDownload.forget(p)
+}
+
+// NewThrottledHost wraps a host so that the streams opened or handled through
+// it are throttled by `t`. Protocols given this host, such as graphsync and
+// retrieval, transfer data at the limits of the throttle, while those given the
+// inner host are not limited.
+func NewThrottledHost(inner host.Host, t *Throttle) host.Host {
+	inner.Network().Notify(&network.NotifyBundle{
+		DisconnectedF: func(n network.Network, c network.Conn) {
+			if n.Connectedness(c.RemotePeer()) != network.Connected {
+				t.forget(c.RemotePeer())
+			}
+		},
+	})
+	return &throttledHost{Host: inner, throttle: t}
+}
+
+type throttledHost struct {
+	host.Host
+	throttle *Throttle
+}
+
+func (h *throttledHost) NewStream(ctx context.Context, p peer.ID, pids ...protocol.ID) (network.Stream, error) {
+	s, err := h.Host.NewStream(ctx, p, pids...)
+	if err != nil {
+		return nil, err
+	}
+	return h.wrap(s), nil
+}
+
+func (h *throttledHost) SetStreamHandler(pid protocol.ID, handler network.StreamHandler) {
+	h.Host.SetStreamHandler(pid, func(s network.Stream) {
+		handler(h.wrap(s))
+	})
+}
+
+func (h *throttledHost) SetStreamHandlerMatch(pid protocol.ID, match func(string) bool, handler network.StreamHandler) {
+	h.Host.SetStreamHandlerMatch(pid, match, func(s network.Stream) {
+		handler(h.wrap(s))
+	})
+}
+
+func (h *throttledHost) wrap(s network.Stream) network.Stream {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &throttledStream{Stream: s, throttle: h.throttle, peer: s.Conn().RemotePeer(), ctx: ctx, cancel: cancel}
+}
+
+// throttledStream waits for its throttle before writes and after reads. Waits
+// end when the stream is reset. Closing the stream only closes it for writing,
+// reads are still throttled.
+type throttledStream struct {
+	network.Stream
+	throttle *Throttle
+	peer     peer.ID
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+func (s *throttledStream) Read(p []byte) (int, error) {
+	n, err := s.Stream.Read(p)
+	if n > 0 {
+		// The data has been read already, delaying the next read holds back
+		// the sender.
+		_ = sleep(s.ctx, s.throttle.clock, s.throttle.reserveDownload(s.peer, n))
+	}
+	return n, err
+}
+
+func (s *throttledStream) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		chunk := p[written:]
+		if len(chunk) > throttleChunk {
+			chunk = chunk[:throttleChunk]
+		}
+		if err := sleep(s.ctx, s.throttle.clock, s.throttle.reserveUpload(s.peer, len(chunk))); err != nil {
+			return written, err
+		}
+		n, err := s.Stream.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+func (s *throttledStream) Reset() error {
+	s.cancel()
+	return s.Stream.Reset()
+}
+
+func maxDelay(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,98 @@
+package net
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/clock"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestThrottleReserve(t *testing.T) {
+	tf.UnitTest(t)
+
+	clk := clock.NewFake(time.Unix(1234567890, 0))
+	th := NewThrottle(TransferLimits{Upload: 1000, PeerUpload: 500}, clk)
+	p1 := peer.ID("peer1")
+	p2 := peer.ID("peer2")
+
+	t.Log("uploads wait for the limit of the peer")
+	assert.Equal(t, time.Duration(0), th.reserveUpload(p1, 500))
+	assert.Equal(t, time.Second, th.reserveUpload(p1, 500))
+
+	t.Log("and for the limit across all peers")
+	assert.Equal(t, 500*time.Millisecond, th.reserveUpload(p2, 500))
+
+	t.Log("downloads are not limited")
+	assert.Equal(t, time.Duration(0), th.reserveDownload(p1, 1<<20))
+
+	t.Log("limits are changed at runtime")
+	limits := TransferLimits{Download: 1000}
+	th.SetLimits(limits)
+	assert.Equal(t, limits, th.Limits())
+	assert.Equal(t, time.Duration(0), th.reserveUpload(p1, 1<<20))
+	assert.Equal(t, time.Duration(0), th.reserveDownload(p1, 1000))
+	assert.Equal(t, 2*time.Second, th.reserveDownload(p2, 2000))
+}
+
+func TestThrottledStream(t *testing.T) {
+	tf.UnitTest(t)
+
+	clk := clock.NewFake(time.Unix(1234567890, 0))
+	th := NewThrottle(TransferLimits{PeerUpload: 1000}, clk)
+	inner := &fakeStream{}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &throttledStream{Stream: inner, throttle: th, peer: peer.ID("peer"), ctx: ctx, cancel: cancel}
+
+	t.Log("large writes are split in chunks")
+	th.SetLimits(TransferLimits{})
+	n, err := s.Write(make([]byte, 3*throttleChunk+10))
+	require.NoError(t, err)
+	assert.Equal(t, 3*throttleChunk+10, n)
+	assert.Equal(t, []int{throttleChunk, throttleChunk, throttleChunk, 10}, inner.writes)
+
+	t.Log("writes wait for the throttle")
+	th.SetLimits(TransferLimits{PeerUpload: 1000})
+	_, err = s.Write(make([]byte, 1000))
+	require.NoError(t, err)
+	done := make(chan error)
+	go func() {
+		_, err := s.Write(make([]byte, 1000))
+		done <- err
+	}()
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+	require.NoError(t, <-done)
+
+	t.Log("resetting the stream ends the wait")
+	go func() {
+		_, err := s.Write(make([]byte, 1000))
+		done <- err
+	}()
+	clk.BlockUntil(1)
+	require.NoError(t, s.Reset())
+	assert.Equal(t, context.Canceled, <-done)
+	assert.True(t, inner.reset)
+}
+
+type fakeStream struct {
+	network.Stream
+	writes []int
+	reset  bool
+}
+
+func (s *fakeStream) Write(p []byte) (int, error) {
+	s.writes = append(s.writes, len(p))
+	return len(p), nil
+}
+
+func (s *fakeStream) Reset() error {
+	s.reset = true
+	return nil
+}
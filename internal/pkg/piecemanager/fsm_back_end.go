@@ -3,40 +3,74 @@ package piecemanager
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/specs-actors/actors/abi"
 	fsm "github.com/filecoin-project/storage-fsm"
+	"github.com/ipfs/go-cid"
 )
 
 var _ PieceManager = new(FiniteStateMachineBackEnd)
 
 type FiniteStateMachineBackEnd struct {
-	idc fsm.SectorIDCounter
-	fsm *fsm.Sealing
+	idc    fsm.SectorIDCounter
+	fsm    *fsm.Sealing
+	pieces *PieceStore
 }
 
-func NewFiniteStateMachineBackEnd(fsm *fsm.Sealing, idc fsm.SectorIDCounter) FiniteStateMachineBackEnd {
+// NewFiniteStateMachineBackEnd creates a piece manager sealing with `fsm`.
+// When `pieces` is not nil, deal pieces are kept in it, once for all the deals
+// made for the same piece.
+func NewFiniteStateMachineBackEnd(fsm *fsm.Sealing, idc fsm.SectorIDCounter, pieces *PieceStore) FiniteStateMachineBackEnd {
 	return FiniteStateMachineBackEnd{
-		idc: idc,
-		fsm: fsm,
+		idc:    idc,
+		fsm:    fsm,
+		pieces: pieces,
 	}
 }
 
-func (f *FiniteStateMachineBackEnd) SealPieceIntoNewSector(ctx context.Context, dealID abi.DealID, dealStart, dealEnd abi.ChainEpoch, pieceSize abi.UnpaddedPieceSize, pieceReader io.Reader) error {
+func (f *FiniteStateMachineBackEnd) SealPieceIntoNewSector(ctx context.Context, dealID abi.DealID, dealStart, dealEnd abi.ChainEpoch, pieceCID cid.Cid, pieceSize abi.UnpaddedPieceSize, pieceReader io.Reader) error {
 	sectorNumber, err := f.idc.Next()
 	if err != nil {
 		return err
 	}
 
-	return f.fsm.SealPiece(ctx, pieceSize, pieceReader, sectorNumber, fsm.DealInfo{
+	if f.pieces != nil {
+		// seal from the stored copy, which the bytes of a piece already
+		// stored for another deal are not written over
+		if _, err := f.pieces.Put(pieceCID, dealID, pieceReader); err != nil {
+			return errors.Wrapf(err, "failed to store piece of deal %d", dealID)
+		}
+		stored, err := f.pieces.Open(pieceCID)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = stored.Close() }()
+		pieceReader = stored
+	}
+
+	err = f.fsm.SealPiece(ctx, pieceSize, pieceReader, sectorNumber, fsm.DealInfo{
 		DealID: dealID,
 		DealSchedule: fsm.DealSchedule{
 			StartEpoch: dealStart,
 			EndEpoch:   dealEnd,
 		},
 	})
+	if err != nil && f.pieces != nil {
+		if rerr := f.pieces.Release(pieceCID, dealID); rerr != nil {
+			log.Warnf("failed to release piece of deal %d: %s", dealID, rerr)
+		}
+	}
+	return err
+}
+
+func (f *FiniteStateMachineBackEnd) ReleasePiece(ctx context.Context, pieceCID cid.Cid, dealID abi.DealID) error {
+	if f.pieces == nil {
+		return nil
+	}
+	return f.pieces.Release(pieceCID, dealID)
 }
 
 func (f *FiniteStateMachineBackEnd) PledgeSector(ctx context.Context) error {
@@ -74,3 +108,32 @@ func (f *FiniteStateMachineBackEnd) LocatePieceForDealWithinSector(ctx context.C
 
 	return 0, 0, 0, errors.Errorf("no encoded piece could be found corresponding to deal id: %d", dealID)
 }
+
+func (f *FiniteStateMachineBackEnd) ListPieces(ctx context.Context) ([]PieceInfo, error) {
+	sectors, err := f.fsm.ListSectors()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list sectors")
+	}
+
+	pieces := indexPieces(sectors)
+	if f.pieces != nil {
+		for i := range pieces {
+			pieces[i].Stored = f.pieces.RefCount(pieces[i].PieceCID) > 0
+		}
+	}
+	return pieces, nil
+}
+
+func (f *FiniteStateMachineBackEnd) SealingProgress(ctx context.Context) ([]SectorProgress, error) {
+	sectors, err := f.fsm.ListSectors()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list sectors")
+	}
+
+	now := time.Now()
+	out := make([]SectorProgress, len(sectors))
+	for i, sector := range sectors {
+		out[i] = sealingProgress(sector, now)
+	}
+	return out, nil
+}
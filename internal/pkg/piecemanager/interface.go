@@ -5,6 +5,7 @@ import (
 	"io"
 
 	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
 )
 
 // PieceManager is responsible for sealing pieces into sectors and progressing
@@ -17,8 +18,14 @@ type PieceManager interface {
 	// available, committed to the network. This method is fire-and-forget; any
 	// errors encountered during the pre-commit or commit flows (including
 	// message creation) are recorded in StorageMining metadata but not exposed
-	// through this API.
-	SealPieceIntoNewSector(ctx context.Context, dealID abi.DealID, dealStart, dealEnd abi.ChainEpoch, pieceSize abi.UnpaddedPieceSize, pieceReader io.Reader) error
+	// through this API. The piece is referenced by the deal until it is
+	// released, its bytes are kept once for all the deals referencing it.
+	SealPieceIntoNewSector(ctx context.Context, dealID abi.DealID, dealStart, dealEnd abi.ChainEpoch, pieceCID cid.Cid, pieceSize abi.UnpaddedPieceSize, pieceReader io.Reader) error
+
+	// ReleasePiece removes the reference of a deal to its piece, once the deal
+	// no longer needs the piece bytes. The bytes are removed with the last
+	// reference to the piece.
+	ReleasePiece(ctx context.Context, pieceCID cid.Cid, dealID abi.DealID) error
 
 	// PledgeSector behaves similarly to SealPieceIntoNewSector, but differs in
 	// that it does not require a deal having been made on-chain beforehand. It
@@ -34,4 +41,13 @@ type PieceManager interface {
 	// a deal's piece within a sealed sector, or an error if that piece does not
 	// exist within any sealed sectors.
 	LocatePieceForDealWithinSector(ctx context.Context, dealID uint64) (sectorID uint64, offset uint64, length uint64, err error)
+
+	// ListPieces produces the deal pieces stored in the miner's sectors,
+	// deduplicated by piece commitment and reference counted across the
+	// deals and sectors that include them.
+	ListPieces(ctx context.Context) ([]PieceInfo, error)
+
+	// SealingProgress produces the sealing progress of each of the miner's
+	// sectors.
+	SealingProgress(ctx context.Context) ([]SectorProgress, error)
 }
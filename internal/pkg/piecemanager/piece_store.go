@@ -0,0 +1,232 @@
+package piecemanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+)
+
+const (
+	pieceFilePrefix = "piece-"
+	refsFilePrefix  = "refs-"
+)
+
+// PieceStore keeps the bytes of deal pieces in a directory, once per piece
+// commitment however many deals reference it. The deals referencing each
+// piece are counted, and its bytes are kept until the last of them releases
+// it. References survive restarts.
+type PieceStore struct {
+	dir string
+
+	lk   sync.Mutex
+	refs map[cid.Cid][]abi.DealID
+}
+
+// NewPieceStore creates a store of pieces in `dir`, reusing the pieces and
+// references found there.
+func NewPieceStore(dir string) (*PieceStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create piece store directory")
+	}
+
+	s := &PieceStore{
+		dir:  dir,
+		refs: make(map[cid.Cid][]abi.DealID),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Put adds a reference of the deal to the piece. The bytes read from `r` are
+// written only if the piece is not stored yet, it returns whether they were.
+// Putting a reference the piece already has is a no-op.
+func (s *PieceStore) Put(pieceCID cid.Cid, dealID abi.DealID, r io.Reader) (bool, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	refs, stored := s.refs[pieceCID]
+	for _, ref := range refs {
+		if ref == dealID {
+			return false, nil
+		}
+	}
+
+	if !stored {
+		if err := s.write(pieceCID, r); err != nil {
+			return false, err
+		}
+	}
+	if err := s.setRefs(pieceCID, append(refs, dealID)); err != nil {
+		if !stored {
+			_ = os.Remove(s.path(pieceFilePrefix, pieceCID))
+		}
+		return false, err
+	}
+	return !stored, nil
+}
+
+// Open returns a reader of the bytes of a stored piece. The reader remains
+// usable if the piece is released while it is open.
+func (s *PieceStore) Open(pieceCID cid.Cid) (io.ReadCloser, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	if _, ok := s.refs[pieceCID]; !ok {
+		return nil, errors.Errorf("piece %s is not stored", pieceCID)
+	}
+	f, err := os.Open(s.path(pieceFilePrefix, pieceCID))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open piece %s", pieceCID)
+	}
+	return f, nil
+}
+
+// Release removes the reference of the deal to the piece, and the bytes of
+// the piece with its last reference.
+func (s *PieceStore) Release(pieceCID cid.Cid, dealID abi.DealID) error {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+
+	refs := s.refs[pieceCID]
+	remaining := make([]abi.DealID, 0, len(refs))
+	for _, ref := range refs {
+		if ref != dealID {
+			remaining = append(remaining, ref)
+		}
+	}
+	if len(remaining) == len(refs) {
+		return nil
+	}
+	if len(remaining) > 0 {
+		return s.setRefs(pieceCID, remaining)
+	}
+
+	delete(s.refs, pieceCID)
+	if err := os.Remove(s.path(refsFilePrefix, pieceCID)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to remove references of piece %s", pieceCID)
+	}
+	if err := os.Remove(s.path(pieceFilePrefix, pieceCID)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to remove piece %s", pieceCID)
+	}
+	return nil
+}
+
+// RefCount returns the number of deals referencing a stored piece.
+func (s *PieceStore) RefCount(pieceCID cid.Cid) int {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	return len(s.refs[pieceCID])
+}
+
+// write stores the bytes of the piece through a temporary file, so that an
+// interrupted write never leaves a partial piece.
+func (s *PieceStore) write(pieceCID cid.Cid, r io.Reader) error {
+	tmp, err := ioutil.TempFile(s.dir, "tmp-")
+	if err != nil {
+		return errors.Wrap(err, "failed to create piece file")
+	}
+	_, err = io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path(pieceFilePrefix, pieceCID))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.Wrapf(err, "failed to write piece %s", pieceCID)
+	}
+	return nil
+}
+
+// setRefs records the references of the piece on disk, then in memory.
+func (s *PieceStore) setRefs(pieceCID cid.Cid, refs []abi.DealID) error {
+	data, err := json.Marshal(refs)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(s.dir, "tmp-")
+	if err != nil {
+		return errors.Wrap(err, "failed to create references file")
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path(refsFilePrefix, pieceCID))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.Wrapf(err, "failed to write references of piece %s", pieceCID)
+	}
+	s.refs[pieceCID] = refs
+	return nil
+}
+
+// load registers the references left in the directory, and discards pieces
+// without references and leftovers of interrupted writes.
+func (s *PieceStore) load() error {
+	infos, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to read piece store directory")
+	}
+
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	for _, info := range infos {
+		name := info.Name()
+		if strings.HasPrefix(name, "tmp-") {
+			_ = os.Remove(filepath.Join(s.dir, name))
+			continue
+		}
+		if !strings.HasPrefix(name, refsFilePrefix) || info.IsDir() {
+			continue
+		}
+		pieceCID, err := cid.Decode(strings.TrimPrefix(name, refsFilePrefix))
+		if err != nil {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(s.dir, name))
+		if err != nil {
+			return errors.Wrapf(err, "failed to read references of piece %s", pieceCID)
+		}
+		var refs []abi.DealID
+		if err := json.Unmarshal(data, &refs); err != nil {
+			return errors.Wrapf(err, "failed to decode references of piece %s", pieceCID)
+		}
+		if len(refs) > 0 {
+			s.refs[pieceCID] = refs
+		}
+	}
+
+	for _, info := range infos {
+		name := info.Name()
+		if !strings.HasPrefix(name, pieceFilePrefix) || info.IsDir() {
+			continue
+		}
+		pieceCID, err := cid.Decode(strings.TrimPrefix(name, pieceFilePrefix))
+		if err != nil {
+			continue
+		}
+		if _, ok := s.refs[pieceCID]; !ok {
+			_ = os.Remove(filepath.Join(s.dir, name))
+		}
+	}
+	return nil
+}
+
+func (s *PieceStore) path(prefix string, pieceCID cid.Cid) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s%s", prefix, pieceCID))
+}
@@ -0,0 +1,116 @@
+package piecemanager
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+// failingReader fails the test reading it, for pieces that must not be written.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("piece bytes read again")
+}
+
+func requirePiece(t *testing.T, r io.ReadCloser, expected []byte) {
+	defer func() { require.NoError(t, r.Close()) }()
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, expected, data)
+}
+
+func TestPieceStoreSharesPieceBytes(t *testing.T) {
+	tf.UnitTest(t)
+
+	dir, err := ioutil.TempDir("", "piece-store")
+	require.NoError(t, err)
+	defer func() { require.NoError(t, os.RemoveAll(dir)) }()
+
+	s, err := NewPieceStore(dir)
+	require.NoError(t, err)
+	popular := types.CidFromString(t, "popular")
+	data := bytes.Repeat([]byte("p"), 100)
+
+	t.Log("the bytes of a piece are written for its first deal only")
+	written, err := s.Put(popular, 10, bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.True(t, written)
+	written, err = s.Put(popular, 11, failingReader{})
+	require.NoError(t, err)
+	assert.False(t, written)
+	assert.Equal(t, 2, s.RefCount(popular))
+
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	pieceFiles := 0
+	for _, info := range infos {
+		if info.Name() == pieceFilePrefix+popular.String() {
+			pieceFiles++
+			assert.Equal(t, int64(len(data)), info.Size())
+		}
+	}
+	assert.Equal(t, 1, pieceFiles)
+
+	t.Log("putting a reference twice counts it once")
+	_, err = s.Put(popular, 11, failingReader{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.RefCount(popular))
+
+	t.Log("references survive a restart")
+	s, err = NewPieceStore(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.RefCount(popular))
+
+	t.Log("the bytes are kept until the last reference is released")
+	require.NoError(t, s.Release(popular, 10))
+	assert.Equal(t, 1, s.RefCount(popular))
+	r, err := s.Open(popular)
+	require.NoError(t, err)
+	requirePiece(t, r, data)
+
+	r, err = s.Open(popular)
+	require.NoError(t, err)
+	require.NoError(t, s.Release(popular, 11))
+	assert.Equal(t, 0, s.RefCount(popular))
+	_, err = os.Stat(s.path(pieceFilePrefix, popular))
+	assert.True(t, os.IsNotExist(err))
+	_, err = s.Open(popular)
+	assert.Error(t, err)
+
+	t.Log("readers opened before the last release remain readable")
+	requirePiece(t, r, data)
+
+	t.Log("released pieces are not reloaded")
+	s, err = NewPieceStore(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 0, s.RefCount(popular))
+}
+
+func TestPieceStoreDiscardsUnreferencedPieces(t *testing.T) {
+	tf.UnitTest(t)
+
+	dir, err := ioutil.TempDir("", "piece-store")
+	require.NoError(t, err)
+	defer func() { require.NoError(t, os.RemoveAll(dir)) }()
+
+	orphan := types.CidFromString(t, "orphan")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, pieceFilePrefix+orphan.String()), []byte("o"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "tmp-1"), []byte("partial"), 0644))
+
+	_, err = NewPieceStore(dir)
+	require.NoError(t, err)
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, infos)
+}
@@ -0,0 +1,77 @@
+package piecemanager
+
+import (
+	"sort"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	fsm "github.com/filecoin-project/storage-fsm"
+	"github.com/ipfs/go-cid"
+)
+
+// PieceInfo describes a piece stored by the miner and every deal and sector
+// that references it. Pieces are identified by their piece commitment, so
+// deals made by different clients for the same data share a single entry.
+type PieceInfo struct {
+	PieceCID cid.Cid
+	Size     abi.UnpaddedPieceSize
+	// Deals are the ids of all deals for this piece.
+	Deals []abi.DealID
+	// Sectors are the numbers of the sectors holding a copy of this piece.
+	Sectors []abi.SectorNumber
+	// Stored is whether the bytes of the piece are kept in the piece store,
+	// once for all its deals.
+	Stored bool
+}
+
+// RefCount returns the number of deals referencing the piece.
+func (pi PieceInfo) RefCount() int {
+	return len(pi.Deals)
+}
+
+// RedundantBytes returns the number of bytes taken by sealed copies of the
+// piece beyond the first. Every deal must be proven in a sector whose data
+// commitment covers its piece, so each deal is sealed with its own copy even
+// though the piece store keeps the piece bytes once.
+func (pi PieceInfo) RedundantBytes() uint64 {
+	if len(pi.Deals) == 0 {
+		return 0
+	}
+	return uint64(pi.Size) * uint64(len(pi.Deals)-1)
+}
+
+// indexPieces groups the deal pieces of the given sectors by piece commitment.
+// Filler pieces, which are not part of any deal, are omitted. The result is
+// sorted by piece commitment.
+func indexPieces(sectors []fsm.SectorInfo) []PieceInfo {
+	byCid := make(map[cid.Cid]*PieceInfo)
+	for _, sector := range sectors {
+		for _, piece := range sector.Pieces {
+			if piece.DealInfo == nil {
+				continue
+			}
+
+			info, ok := byCid[piece.Piece.PieceCID]
+			if !ok {
+				info = &PieceInfo{
+					PieceCID: piece.Piece.PieceCID,
+					Size:     piece.Piece.Size.Unpadded(),
+				}
+				byCid[piece.Piece.PieceCID] = info
+			}
+
+			info.Deals = append(info.Deals, piece.DealInfo.DealID)
+			if len(info.Sectors) == 0 || info.Sectors[len(info.Sectors)-1] != sector.SectorNumber {
+				info.Sectors = append(info.Sectors, sector.SectorNumber)
+			}
+		}
+	}
+
+	out := make([]PieceInfo, 0, len(byCid))
+	for _, info := range byCid {
+		out = append(out, *info)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].PieceCID.KeyString() < out[j].PieceCID.KeyString()
+	})
+	return out
+}
@@ -0,0 +1,69 @@
+package piecemanager
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	fsm "github.com/filecoin-project/storage-fsm"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+func TestIndexPieces(t *testing.T) {
+	tf.UnitTest(t)
+
+	popular := types.CidFromString(t, "popular")
+	unique := types.CidFromString(t, "unique")
+	filler := types.CidFromString(t, "filler")
+	size := abi.PaddedPieceSize(1024)
+
+	piece := func(c cid.Cid, dealID abi.DealID) fsm.Piece {
+		return fsm.Piece{
+			Piece:    abi.PieceInfo{PieceCID: c, Size: size},
+			DealInfo: &fsm.DealInfo{DealID: dealID},
+		}
+	}
+
+	sectors := []fsm.SectorInfo{{
+		SectorNumber: 1,
+		Pieces: []fsm.Piece{
+			piece(popular, 10),
+			{Piece: abi.PieceInfo{PieceCID: filler, Size: size}},
+		},
+	}, {
+		SectorNumber: 2,
+		Pieces:       []fsm.Piece{piece(popular, 11), piece(popular, 12)},
+	}, {
+		SectorNumber: 3,
+		Pieces:       []fsm.Piece{piece(unique, 13)},
+	}}
+
+	pieces := indexPieces(sectors)
+	require.Len(t, pieces, 2)
+
+	byCid := map[cid.Cid]PieceInfo{}
+	for _, p := range pieces {
+		byCid[p.PieceCID] = p
+	}
+
+	t.Log("deals for the same piece share one entry")
+	p := byCid[popular]
+	assert.Equal(t, size.Unpadded(), p.Size)
+	assert.Equal(t, []abi.DealID{10, 11, 12}, p.Deals)
+	assert.Equal(t, []abi.SectorNumber{1, 2}, p.Sectors)
+	assert.Equal(t, 3, p.RefCount())
+	assert.Equal(t, 2*uint64(size.Unpadded()), p.RedundantBytes())
+
+	p = byCid[unique]
+	assert.Equal(t, 1, p.RefCount())
+	assert.Equal(t, []abi.SectorNumber{3}, p.Sectors)
+	assert.Equal(t, uint64(0), p.RedundantBytes())
+
+	t.Log("filler pieces are not reported")
+	_, ok := byCid[filler]
+	assert.False(t, ok)
+}
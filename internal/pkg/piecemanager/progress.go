@@ -0,0 +1,90 @@
+package piecemanager
+
+import (
+	"time"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	fsm "github.com/filecoin-project/storage-fsm"
+)
+
+// SectorProgress describes how far the sealing of a sector has come.
+type SectorProgress struct {
+	_      struct{} `cbor:",toarray"`
+	Sector abi.SectorNumber
+	// Phase is the state of the sector in the sealing state machine.
+	Phase string
+	// Percent is the share of the typical sealing time spent by the phases
+	// before Phase, 100 once the sector is proving.
+	Percent uint64
+	// PhaseStarted is when the sector entered Phase, in unix seconds.
+	PhaseStarted uint64
+	// EstimatedCompletion is when the sector is expected to be proving, in
+	// unix seconds, zero when unknown.
+	EstimatedCompletion uint64
+	// Failed is true when sealing failed, Message tells why.
+	Failed  bool
+	Message string
+}
+
+// sealingPhases are the phases of sealing a sector in order, with the share
+// of the sealing time they typically take. Replication in PreCommit1 is by far
+// the longest, waiting for the seed takes a fixed number of epochs.
+var sealingPhases = []struct {
+	state  fsm.SectorState
+	weight uint64
+}{
+	{fsm.Packing, 1},
+	{fsm.PreCommit1, 50},
+	{fsm.PreCommit2, 15},
+	{fsm.PreCommitting, 2},
+	{fsm.WaitSeed, 15},
+	{fsm.Committing, 15},
+	{fsm.CommitWait, 1},
+	{fsm.FinalizeSector, 1},
+}
+
+// sealingProgress returns the progress of `sector` at `now`. The completion
+// is estimated by extrapolating the time spent before the current phase.
+func sealingProgress(sector fsm.SectorInfo, now time.Time) SectorProgress {
+	progress := SectorProgress{
+		Sector:  sector.SectorNumber,
+		Phase:   string(sector.State),
+		Message: sector.LastErr,
+	}
+	var sealStart uint64
+	if len(sector.Log) > 0 {
+		sealStart = sector.Log[0].Timestamp
+		progress.PhaseStarted = sector.Log[len(sector.Log)-1].Timestamp
+	}
+
+	if sector.State == fsm.Proving {
+		progress.Percent = 100
+		return progress
+	}
+	var done uint64
+	known := false
+	for _, phase := range sealingPhases {
+		if phase.state == sector.State {
+			known = true
+			break
+		}
+		done += phase.weight
+	}
+	if !known {
+		// failure and fault states, or ones added to the state machine
+		progress.Failed = sector.State != fsm.Empty && sector.State != fsm.UndefinedSectorState
+		return progress
+	}
+	progress.Percent = done
+
+	if done > 0 && sealStart > 0 && progress.PhaseStarted > sealStart {
+		elapsed := progress.PhaseStarted - sealStart
+		estimate := sealStart + elapsed*100/done
+		if unixNow := uint64(now.Unix()); estimate < unixNow {
+			// slower than the phases before, completion is imminent at best
+			estimate = unixNow
+		}
+		progress.EstimatedCompletion = estimate
+	}
+	return progress
+}
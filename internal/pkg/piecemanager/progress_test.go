@@ -0,0 +1,67 @@
+package piecemanager
+
+import (
+	"testing"
+	"time"
+
+	fsm "github.com/filecoin-project/storage-fsm"
+	"github.com/stretchr/testify/assert"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestSealingProgress(t *testing.T) {
+	tf.UnitTest(t)
+
+	start := uint64(1000000)
+	now := time.Unix(int64(start)+3000, 0)
+	logs := func(timestamps ...uint64) []fsm.Log {
+		var out []fsm.Log
+		for _, ts := range timestamps {
+			out = append(out, fsm.Log{Timestamp: ts})
+		}
+		return out
+	}
+
+	t.Run("estimates completion from the phases done", func(t *testing.T) {
+		// packing and replication took 51% of the sealing time in 2040 seconds
+		progress := sealingProgress(fsm.SectorInfo{
+			SectorNumber: 3,
+			State:        fsm.PreCommit2,
+			Log:          logs(start, start+40, start+2040),
+		}, now)
+		assert.Equal(t, "PreCommit2", progress.Phase)
+		assert.Equal(t, uint64(51), progress.Percent)
+		assert.Equal(t, start+2040, progress.PhaseStarted)
+		assert.Equal(t, start+4000, progress.EstimatedCompletion)
+		assert.False(t, progress.Failed)
+	})
+
+	t.Run("completion is not estimated in the past", func(t *testing.T) {
+		progress := sealingProgress(fsm.SectorInfo{
+			State: fsm.FinalizeSector,
+			Log:   logs(start, start+10),
+		}, now)
+		assert.Equal(t, uint64(99), progress.Percent)
+		assert.Equal(t, uint64(now.Unix()), progress.EstimatedCompletion)
+	})
+
+	t.Run("first phase", func(t *testing.T) {
+		progress := sealingProgress(fsm.SectorInfo{State: fsm.Packing, Log: logs(start)}, now)
+		assert.Equal(t, uint64(0), progress.Percent)
+		assert.Equal(t, uint64(0), progress.EstimatedCompletion)
+	})
+
+	t.Run("proving", func(t *testing.T) {
+		progress := sealingProgress(fsm.SectorInfo{State: fsm.Proving, Log: logs(start, start+4000)}, now)
+		assert.Equal(t, uint64(100), progress.Percent)
+		assert.False(t, progress.Failed)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		progress := sealingProgress(fsm.SectorInfo{State: fsm.SealFailed, LastErr: "out of space", Log: logs(start, start+10)}, now)
+		assert.True(t, progress.Failed)
+		assert.Equal(t, "out of space", progress.Message)
+		assert.Equal(t, uint64(0), progress.EstimatedCompletion)
+	})
+}
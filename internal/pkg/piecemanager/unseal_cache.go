@@ -0,0 +1,263 @@
+package piecemanager
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/metrics"
+)
+
+var (
+	unsealCacheHits   = metrics.NewInt64Counter("piecemanager/unseal_cache_hits", "Number of retrievals served from an unsealed sector copy")
+	unsealCacheMisses = metrics.NewInt64Counter("piecemanager/unseal_cache_misses", "Number of retrievals that required unsealing a sector")
+)
+
+var log = logging.Logger("piecemanager")
+
+const unsealedFilePrefix = "unsealed-"
+
+// Unsealer produces the unsealed bytes of a sector.
+type Unsealer interface {
+	UnsealSector(ctx context.Context, sectorID uint64) (io.ReadCloser, error)
+}
+
+// UnsealCacheStats reports the usage of an UnsealCache.
+type UnsealCacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Entries int
+	Bytes   uint64
+}
+
+// HitRate returns the fraction of requests served without unsealing.
+func (s UnsealCacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// UnsealCache keeps unsealed copies of sectors in a directory so that repeated
+// retrievals from the same sector pay the unseal cost once. The directory is
+// bounded to a total size, the least recently used copies are evicted first.
+// Copies found in the directory on creation are reused.
+type UnsealCache struct {
+	inner    Unsealer
+	dir      string
+	maxBytes uint64
+
+	lk      sync.Mutex
+	lru     *list.List // of *cacheEntry, most recently used at the front
+	entries map[uint64]*list.Element
+	pending map[uint64]chan struct{}
+	bytes   uint64
+	hits    uint64
+	misses  uint64
+}
+
+type cacheEntry struct {
+	sectorID uint64
+	size     uint64
+}
+
+var _ Unsealer = (*UnsealCache)(nil)
+
+// NewUnsealCache creates a cache of sectors unsealed by `inner` in `dir`,
+// holding at most `maxBytes` of unsealed data.
+func NewUnsealCache(inner Unsealer, dir string, maxBytes uint64) (*UnsealCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "failed to create unseal cache directory")
+	}
+
+	c := &UnsealCache{
+		inner:    inner,
+		dir:      dir,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[uint64]*list.Element),
+		pending:  make(map[uint64]chan struct{}),
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// UnsealSector returns a reader of the unsealed bytes of the sector, from the
+// cache if present and otherwise by unsealing it into the cache.
+func (c *UnsealCache) UnsealSector(ctx context.Context, sectorID uint64) (io.ReadCloser, error) {
+	for {
+		c.lk.Lock()
+		if elem, ok := c.entries[sectorID]; ok {
+			f, err := os.Open(c.path(sectorID))
+			if err == nil {
+				// keep recency across restarts, copies are reloaded by modification time
+				now := time.Now()
+				_ = os.Chtimes(c.path(sectorID), now, now)
+				c.lru.MoveToFront(elem)
+				c.hits++
+				c.lk.Unlock()
+				unsealCacheHits.Inc(ctx, 1)
+				return f, nil
+			}
+			// the copy disappeared from under us, forget it and unseal again
+			c.remove(elem)
+		}
+
+		wait, inProgress := c.pending[sectorID]
+		if !inProgress {
+			done := make(chan struct{})
+			c.pending[sectorID] = done
+			c.misses++
+			c.lk.Unlock()
+			unsealCacheMisses.Inc(ctx, 1)
+
+			f, err := c.fill(ctx, sectorID)
+
+			c.lk.Lock()
+			delete(c.pending, sectorID)
+			close(done)
+			c.lk.Unlock()
+			return f, err
+		}
+		c.lk.Unlock()
+
+		// another request is unsealing this sector, wait for it to finish
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-wait:
+		}
+	}
+}
+
+// Stats returns the cache's usage counters.
+func (c *UnsealCache) Stats() UnsealCacheStats {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	return UnsealCacheStats{
+		Hits:    c.hits,
+		Misses:  c.misses,
+		Entries: c.lru.Len(),
+		Bytes:   c.bytes,
+	}
+}
+
+// fill unseals the sector into a temporary file, moves it into the cache and
+// opens it. A copy that alone exceeds the bound is not kept, and evicts no
+// other copy: it is removed once opened, as an open file remains readable.
+func (c *UnsealCache) fill(ctx context.Context, sectorID uint64) (io.ReadCloser, error) {
+	r, err := c.inner.UnsealSector(ctx, sectorID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+
+	tmp, err := ioutil.TempFile(c.dir, "tmp-")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create unsealed copy")
+	}
+	size, err := io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return nil, errors.Wrapf(err, "failed to write unsealed copy of sector %d", sectorID)
+	}
+	if err := os.Rename(tmp.Name(), c.path(sectorID)); err != nil {
+		_ = os.Remove(tmp.Name())
+		return nil, errors.Wrapf(err, "failed to store unsealed copy of sector %d", sectorID)
+	}
+	f, err := os.Open(c.path(sectorID))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open unsealed copy of sector %d", sectorID)
+	}
+
+	if uint64(size) > c.maxBytes {
+		log.Debugf("unsealed copy of sector %d exceeds the cache size, not keeping it", sectorID)
+		if err := os.Remove(c.path(sectorID)); err != nil {
+			log.Warnf("failed to remove unsealed copy of sector %d: %s", sectorID, err)
+		}
+		return f, nil
+	}
+
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	c.add(sectorID, uint64(size))
+	c.evict()
+	return f, nil
+}
+
+// evict removes least recently used copies until the cache fits its bound.
+func (c *UnsealCache) evict() {
+	for c.bytes > c.maxBytes && c.lru.Len() > 0 {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *UnsealCache) add(sectorID uint64, size uint64) {
+	if elem, ok := c.entries[sectorID]; ok {
+		c.remove(elem)
+	}
+	c.entries[sectorID] = c.lru.PushFront(&cacheEntry{sectorID: sectorID, size: size})
+	c.bytes += size
+}
+
+func (c *UnsealCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.sectorID)
+	c.bytes -= entry.size
+	if err := os.Remove(c.path(entry.sectorID)); err != nil && !os.IsNotExist(err) {
+		log.Warnf("failed to remove unsealed copy of sector %d: %s", entry.sectorID, err)
+	}
+}
+
+// load registers the copies left in the directory, oldest modified least
+// recently used, and discards leftovers of interrupted writes.
+func (c *UnsealCache) load() error {
+	infos, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to read unseal cache directory")
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	for _, info := range infos {
+		name := info.Name()
+		if strings.HasPrefix(name, "tmp-") {
+			_ = os.Remove(filepath.Join(c.dir, name))
+			continue
+		}
+		if !strings.HasPrefix(name, unsealedFilePrefix) || info.IsDir() {
+			continue
+		}
+		sectorID, err := strconv.ParseUint(strings.TrimPrefix(name, unsealedFilePrefix), 10, 64)
+		if err != nil {
+			continue
+		}
+		c.add(sectorID, uint64(info.Size()))
+	}
+	c.evict()
+	return nil
+}
+
+func (c *UnsealCache) path(sectorID uint64) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%s%d", unsealedFilePrefix, sectorID))
+}
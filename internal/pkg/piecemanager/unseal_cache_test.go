@@ -0,0 +1,157 @@
+package piecemanager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+type fakeUnsealer struct {
+	lk       sync.Mutex
+	size     int
+	sizes    map[uint64]int // of the sectors not of the default size
+	unsealed map[uint64]int
+	release  chan struct{}
+}
+
+func newFakeUnsealer(size int) *fakeUnsealer {
+	return &fakeUnsealer{size: size, unsealed: make(map[uint64]int)}
+}
+
+func (f *fakeUnsealer) UnsealSector(ctx context.Context, sectorID uint64) (io.ReadCloser, error) {
+	if f.release != nil {
+		<-f.release
+	}
+	f.lk.Lock()
+	f.unsealed[sectorID]++
+	size, ok := f.sizes[sectorID]
+	if !ok {
+		size = f.size
+	}
+	f.lk.Unlock()
+	return ioutil.NopCloser(bytes.NewReader(sectorBytes(sectorID, size))), nil
+}
+
+func (f *fakeUnsealer) count(sectorID uint64) int {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	return f.unsealed[sectorID]
+}
+
+func sectorBytes(sectorID uint64, size int) []byte {
+	return bytes.Repeat([]byte(fmt.Sprintf("%d", sectorID%10)), size)
+}
+
+func requireRead(t *testing.T, c *UnsealCache, sectorID uint64, size int) {
+	r, err := c.UnsealSector(context.Background(), sectorID)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, r.Close()) }()
+	data, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, sectorBytes(sectorID, size), data)
+}
+
+func TestUnsealCacheHitsAndEviction(t *testing.T) {
+	tf.UnitTest(t)
+
+	dir, err := ioutil.TempDir("", "unseal-cache")
+	require.NoError(t, err)
+	defer func() { require.NoError(t, os.RemoveAll(dir)) }()
+
+	inner := newFakeUnsealer(100)
+	c, err := NewUnsealCache(inner, dir, 250)
+	require.NoError(t, err)
+
+	t.Log("the first retrieval unseals, the next is served from the cache")
+	requireRead(t, c, 1, 100)
+	requireRead(t, c, 1, 100)
+	assert.Equal(t, 1, inner.count(1))
+	assert.Equal(t, UnsealCacheStats{Hits: 1, Misses: 1, Entries: 1, Bytes: 100}, c.Stats())
+	assert.Equal(t, 0.5, c.Stats().HitRate())
+
+	t.Log("the least recently used copy is evicted when the cache is full")
+	requireRead(t, c, 2, 100)
+	requireRead(t, c, 1, 100)
+	requireRead(t, c, 3, 100)
+	assert.Equal(t, 2, c.Stats().Entries)
+	assert.Equal(t, uint64(200), c.Stats().Bytes)
+
+	requireRead(t, c, 1, 100)
+	assert.Equal(t, 1, inner.count(1))
+	requireRead(t, c, 2, 100)
+	assert.Equal(t, 2, inner.count(2))
+
+	t.Log("copies are reused by a new cache on the same directory")
+	c2, err := NewUnsealCache(inner, dir, 250)
+	require.NoError(t, err)
+	assert.Equal(t, 2, c2.Stats().Entries)
+	requireRead(t, c2, 2, 100)
+	assert.Equal(t, 2, inner.count(2))
+}
+
+func TestUnsealCacheOversizedCopy(t *testing.T) {
+	tf.UnitTest(t)
+
+	dir, err := ioutil.TempDir("", "unseal-cache")
+	require.NoError(t, err)
+	defer func() { require.NoError(t, os.RemoveAll(dir)) }()
+
+	inner := newFakeUnsealer(100)
+	inner.sizes = map[uint64]int{9: 200}
+	c, err := NewUnsealCache(inner, dir, 150)
+	require.NoError(t, err)
+	requireRead(t, c, 1, 100)
+
+	t.Log("a copy larger than the cache can still be read, but is not kept")
+	requireRead(t, c, 9, 200)
+	assert.Equal(t, 1, c.Stats().Entries)
+	assert.Equal(t, uint64(100), c.Stats().Bytes)
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	assert.Equal(t, unsealedFilePrefix+"1", infos[0].Name())
+
+	t.Log("nor does it evict the copies that fit")
+	requireRead(t, c, 1, 100)
+	assert.Equal(t, 1, inner.count(1))
+	requireRead(t, c, 9, 200)
+	assert.Equal(t, 2, inner.count(9))
+}
+
+func TestUnsealCacheConcurrentMisses(t *testing.T) {
+	tf.UnitTest(t)
+
+	dir, err := ioutil.TempDir("", "unseal-cache")
+	require.NoError(t, err)
+	defer func() { require.NoError(t, os.RemoveAll(dir)) }()
+
+	inner := newFakeUnsealer(100)
+	inner.release = make(chan struct{})
+	c, err := NewUnsealCache(inner, dir, 1000)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			requireRead(t, c, 7, 100)
+		}()
+	}
+	close(inner.release)
+	wg.Wait()
+
+	t.Log("concurrent requests for a sector unseal it once")
+	assert.Equal(t, 1, inner.count(7))
+	assert.Equal(t, uint64(1), c.Stats().Misses)
+}
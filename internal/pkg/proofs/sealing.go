@@ -0,0 +1,15 @@
+package proofs
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// ErrSealingUnsupported is returned when setting up storage mining on a
+// platform whose proofs implementation cannot seal sectors.
+var ErrSealingUnsupported = fmt.Errorf("sealing sectors is not supported on %s/%s", runtime.GOOS, runtime.GOARCH)
+
+// SealingSupported returns whether sectors can be sealed on this platform.
+func SealingSupported() bool {
+	return sealingSupported
+}
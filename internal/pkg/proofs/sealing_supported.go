@@ -0,0 +1,5 @@
+// +build !windows,!arm64
+
+package proofs
+
+const sealingSupported = true
@@ -0,0 +1,10 @@
+// +build windows arm64
+
+package proofs
+
+// Sealing requires the native proofs build of filecoin-ffi, which is only
+// available for amd64. Sealing is refused here, but verification still links
+// against filecoin-ffi built from source.
+// TODO: a fallback proofs implementation or pure-Go verification mode, so that
+// these builds do not need filecoin-ffi.
+const sealingSupported = false
@@ -94,6 +94,31 @@ func (a *API) MiningOnce(ctx context.Context) (*block.Block, error) {
 	return res.Header, nil
 }
 
+// MiningPreview reports whether the node would win the current epoch on top of
+// the chain head and which pending messages it would include in its block.
+func (a *API) MiningPreview(ctx context.Context) (*mining.Preview, error) {
+	ts, err := a.chainReader.GetTipSet(a.chainReader.GetHead())
+	if err != nil {
+		return nil, err
+	}
+	headEpoch, err := ts.Height()
+	if err != nil {
+		return nil, err
+	}
+
+	miningWorker, err := a.getWorkerFunc(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Epochs between the head and the current epoch are null rounds.
+	var nullBlkCount uint64
+	if currEpoch := a.chainClock.EpochAtTime(a.chainClock.Now()); currEpoch > headEpoch+1 {
+		nullBlkCount = uint64(currEpoch - headEpoch - 1)
+	}
+	return miningWorker.Preview(ctx, ts, nullBlkCount)
+}
+
 // MiningSetup sets up a storage miner without running repeated tasks like mining
 func (a *API) MiningSetup(ctx context.Context) error {
 	return a.setupMiningFunc(ctx)
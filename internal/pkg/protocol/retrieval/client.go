@@ -0,0 +1,66 @@
+package retrieval
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-address"
+	iface "github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/ipfs/go-cid"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
+)
+
+// Retrieve makes a retrieval deal with a miner for the part of the DAG rooted
+// at payload picked by sel, and waits until the deal completes or ctx is done.
+// The retrieved blocks are stored in the client's blockstore. The client pays
+// from wallet at the price the miner quotes, and funds the deal for the whole
+// DAG, of which only the data sent is paid for.
+func Retrieve(ctx context.Context, client iface.RetrievalClient, miner address.Address, minerPeer peer.ID, wallet address.Address, payload cid.Cid, sel ipld.Node) (iface.ClientDealState, error) {
+	query, err := client.Query(ctx, iface.RetrievalPeer{Address: miner, ID: minerPeer}, payload, iface.QueryParams{})
+	if err != nil {
+		return iface.ClientDealState{}, errors.Wrapf(err, "failed to query miner %s", miner)
+	}
+	if query.Status != iface.QueryResponseAvailable {
+		return iface.ClientDealState{}, errors.Errorf("miner %s cannot serve %s: %s", miner, payload, query.Message)
+	}
+
+	params := iface.NewParamsV1(query.MinPricePerByte, query.MaxPaymentInterval, query.MaxPaymentIntervalIncrease, sel, nil)
+
+	// Events are subscribed to before the deal is made so that none is missed,
+	// those of other deals are dropped until the deal ID is known.
+	events := make(chan iface.ClientDealState, 16)
+	done := make(chan struct{})
+	unsubscribe := client.SubscribeToEvents(func(_ iface.ClientEvent, state iface.ClientDealState) {
+		select {
+		case events <- state:
+		case <-done:
+		}
+	})
+	defer unsubscribe()
+	// Unblock the subscriber before unsubscribing, events may be published
+	// while they are no longer read.
+	defer close(done)
+
+	dealID, err := client.Retrieve(ctx, payload, params, query.PieceRetrievalPrice(), minerPeer, wallet, query.PaymentAddress)
+	if err != nil {
+		return iface.ClientDealState{}, errors.Wrap(err, "failed to propose retrieval deal")
+	}
+
+	for {
+		select {
+		case state := <-events:
+			if state.ID != dealID {
+				continue
+			}
+			switch state.Status {
+			case iface.DealStatusCompleted:
+				return state, nil
+			case iface.DealStatusFailed, iface.DealStatusRejected, iface.DealStatusDealNotFound, iface.DealStatusErrored:
+				return state, errors.Errorf("retrieval deal %s %s: %s", dealID, iface.DealStatuses[state.Status], state.Message)
+			}
+		case <-ctx.Done():
+			return iface.ClientDealState{}, ctx.Err()
+		}
+	}
+}
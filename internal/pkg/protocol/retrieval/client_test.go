@@ -0,0 +1,103 @@
+package retrieval_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	iface "github.com/filecoin-project/go-fil-markets/retrievalmarket"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/retrieval"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	vmaddr "github.com/filecoin-project/go-filecoin/internal/pkg/vm/address"
+)
+
+func TestRetrieve(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	addrs := vmaddr.NewForTestGetter()
+	miner, wallet := addrs(), addrs()
+	payload := types.CidFromString(t, "payload")
+	sel := retrieval.PathSelector("Links/0/Hash")
+
+	t.Run("waits for the deal to complete", func(t *testing.T) {
+		client := newFakeClient(iface.QueryResponseAvailable, iface.DealStatusCompleted)
+		state, err := retrieval.Retrieve(ctx, client, miner, "miner", wallet, payload, sel)
+		require.NoError(t, err)
+		assert.Equal(t, iface.DealStatusCompleted, state.Status)
+		assert.Equal(t, client.dealID, state.ID)
+
+		assert.Equal(t, payload, client.payload)
+		assert.Equal(t, wallet, client.wallet)
+		require.NotNil(t, client.params.Selector)
+		assert.Equal(t, abi.NewTokenAmount(2), client.params.PricePerByte)
+		assert.Equal(t, abi.NewTokenAmount(2000), client.funds)
+	})
+
+	t.Run("fails with the deal", func(t *testing.T) {
+		client := newFakeClient(iface.QueryResponseAvailable, iface.DealStatusFailed)
+		_, err := retrieval.Retrieve(ctx, client, miner, "miner", wallet, payload, sel)
+		assert.Error(t, err)
+	})
+
+	t.Run("fails when the miner does not have the data", func(t *testing.T) {
+		client := newFakeClient(iface.QueryResponseUnavailable, iface.DealStatusCompleted)
+		_, err := retrieval.Retrieve(ctx, client, miner, "miner", wallet, payload, sel)
+		assert.Error(t, err)
+		assert.Nil(t, client.params.Selector)
+	})
+}
+
+// fakeClient answers queries with a status and publishes an event of another
+// deal, then one with the final status of the deal it is asked to make.
+type fakeClient struct {
+	iface.RetrievalClient
+
+	queryStatus iface.QueryResponseStatus
+	finalStatus iface.DealStatus
+	subscriber  iface.ClientSubscriber
+
+	dealID  iface.DealID
+	payload cid.Cid
+	params  iface.Params
+	funds   abi.TokenAmount
+	wallet  address.Address
+}
+
+func newFakeClient(queryStatus iface.QueryResponseStatus, finalStatus iface.DealStatus) *fakeClient {
+	return &fakeClient{queryStatus: queryStatus, finalStatus: finalStatus, dealID: 7}
+}
+
+func (c *fakeClient) Query(context.Context, iface.RetrievalPeer, cid.Cid, iface.QueryParams) (iface.QueryResponse, error) {
+	return iface.QueryResponse{
+		Status:          c.queryStatus,
+		Size:            1000,
+		MinPricePerByte: abi.NewTokenAmount(2),
+	}, nil
+}
+
+func (c *fakeClient) SubscribeToEvents(subscriber iface.ClientSubscriber) iface.Unsubscribe {
+	c.subscriber = subscriber
+	return func() {}
+}
+
+func (c *fakeClient) Retrieve(_ context.Context, payload cid.Cid, params iface.Params, funds abi.TokenAmount, _ peer.ID, wallet address.Address, _ address.Address) (iface.DealID, error) {
+	c.payload, c.params, c.funds, c.wallet = payload, params, funds, wallet
+	go func() {
+		other := iface.ClientDealState{Status: iface.DealStatusFailed}
+		other.ID = c.dealID + 1
+		c.subscriber(iface.ClientEventError, other)
+
+		state := iface.ClientDealState{Status: c.finalStatus}
+		state.ID = c.dealID
+		c.subscriber(iface.ClientEventComplete, state)
+	}()
+	return c.dealID, nil
+}
@@ -0,0 +1,52 @@
+package retrieval
+
+import (
+	"bytes"
+
+	"github.com/filecoin-project/go-fil-markets/shared"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/traversal/selector"
+	"github.com/ipld/go-ipld-prime/traversal/selector/builder"
+	"github.com/pkg/errors"
+)
+
+// PathSelector returns a selector of the whole sub-DAG found at an IPLD data
+// model path of a DAG, e.g. `Links/0/Hash` for the first link of a dag-pb
+// node. An empty path selects the whole DAG.
+func PathSelector(path string) ipld.Node {
+	segments := ipld.ParsePath(path).Segments()
+	if len(segments) == 0 {
+		return shared.AllSelector()
+	}
+
+	ssb := builder.NewSelectorSpecBuilder(basicnode.Style.Any)
+	spec := ssb.ExploreRecursive(selector.RecursionLimitNone(), ssb.ExploreAll(ssb.ExploreRecursiveEdge()))
+
+	// The selector is built from the innermost segment out. Fields are
+	// looked up as list indexes in lists, so numeric segments need no special
+	// casing.
+	for i := len(segments) - 1; i >= 0; i-- {
+		seg := segments[i].String()
+		next := spec
+		spec = ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) {
+			efsb.Insert(seg, next)
+		})
+	}
+	return spec.Node()
+}
+
+// DecodeSelector decodes a dag-json encoded selector and checks that it is
+// valid.
+func DecodeSelector(data []byte) (ipld.Node, error) {
+	nb := basicnode.Style.Any.NewBuilder()
+	if err := dagjson.Decoder(nb, bytes.NewReader(data)); err != nil {
+		return nil, errors.Wrap(err, "failed to decode selector")
+	}
+	sel := nb.Build()
+	if _, err := selector.ParseSelector(sel); err != nil {
+		return nil, errors.Wrap(err, "invalid selector")
+	}
+	return sel, nil
+}
@@ -0,0 +1,67 @@
+package retrieval_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/filecoin-project/go-fil-markets/shared"
+	"github.com/ipld/go-ipld-prime"
+	"github.com/ipld/go-ipld-prime/codec/dagjson"
+	basicnode "github.com/ipld/go-ipld-prime/node/basic"
+	"github.com/ipld/go-ipld-prime/traversal/selector"
+	"github.com/ipld/go-ipld-prime/traversal/selector/builder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/retrieval"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestPathSelector(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("empty path selects everything", func(t *testing.T) {
+		assert.Equal(t, encodeSelector(t, shared.AllSelector()), encodeSelector(t, retrieval.PathSelector("")))
+	})
+
+	t.Run("path explores its fields then everything", func(t *testing.T) {
+		sel := retrieval.PathSelector("/Links/1/Hash")
+		_, err := selector.ParseSelector(sel)
+		require.NoError(t, err)
+
+		ssb := builder.NewSelectorSpecBuilder(basicnode.Style.Any)
+		all := ssb.ExploreRecursive(selector.RecursionLimitNone(), ssb.ExploreAll(ssb.ExploreRecursiveEdge()))
+		field := func(name string, next builder.SelectorSpec) builder.SelectorSpec {
+			return ssb.ExploreFields(func(efsb builder.ExploreFieldsSpecBuilder) { efsb.Insert(name, next) })
+		}
+		expected := field("Links", field("1", field("Hash", all))).Node()
+		assert.Equal(t, encodeSelector(t, expected), encodeSelector(t, sel))
+	})
+}
+
+func TestDecodeSelector(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("decodes a selector", func(t *testing.T) {
+		encoded := encodeSelector(t, retrieval.PathSelector("Links/0/Hash"))
+		sel, err := retrieval.DecodeSelector([]byte(encoded))
+		require.NoError(t, err)
+		assert.Equal(t, encoded, encodeSelector(t, sel))
+	})
+
+	t.Run("rejects invalid json", func(t *testing.T) {
+		_, err := retrieval.DecodeSelector([]byte(`{"f":`))
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects what is not a selector", func(t *testing.T) {
+		_, err := retrieval.DecodeSelector([]byte(`{"nope":{}}`))
+		assert.Error(t, err)
+	})
+}
+
+func encodeSelector(t *testing.T, sel ipld.Node) string {
+	var buf bytes.Buffer
+	require.NoError(t, dagjson.Encoder(sel, &buf))
+	return buf.String()
+}
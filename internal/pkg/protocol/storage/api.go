@@ -4,10 +4,20 @@ import (
 	"context"
 
 	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/pkg/errors"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/errcode"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/asksub"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/bidsub"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/piecemanager"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/escrow"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/pin"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/reconcile"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/replication"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/transfer"
 	"github.com/filecoin-project/specs-actors/actors/abi"
 )
 
@@ -15,6 +25,15 @@ type storage interface {
 	Client() storagemarket.StorageClient
 	Provider() (storagemarket.StorageProvider, error)
 	PieceManager() (piecemanager.PieceManager, error)
+	Transfers() *transfer.Manager
+	Replication() *replication.Manager
+	Pins() *pin.Manager
+	AskCache() *asksub.Cache
+	StorageRequests() *bidsub.Book
+	Bidder() *bidsub.Bidder
+	Escrow() *escrow.Reservations
+	Reconciler() *reconcile.Reconciler
+	AnnounceAsk(ctx context.Context) error
 }
 
 // API is the storage API for the test environment
@@ -57,6 +76,62 @@ func (api *API) ListAsks(maddr address.Address) ([]*storagemarket.SignedStorageA
 	return provider.ListAsks(maddr), nil
 }
 
+// AnnounceAsk announces the miner's current ask and capacity to the network
+func (api *API) AnnounceAsk(ctx context.Context) error {
+	return api.storage.AnnounceAsk(ctx)
+}
+
+// CachedAsks lists the asks announced by miners that have not expired at epoch
+func (api *API) CachedAsks(epoch abi.ChainEpoch) []asksub.CachedAsk {
+	return api.storage.AskCache().Asks(epoch)
+}
+
+// RequestStorage publishes a request for miners to bid on storing size bytes
+// for duration epochs at up to maxPrice per GiB per epoch, for validity epochs
+func (api *API) RequestStorage(ctx context.Context, size abi.PaddedPieceSize, duration abi.ChainEpoch, maxPrice abi.TokenAmount, validity abi.ChainEpoch) (bidsub.SignedRequest, error) {
+	return api.storage.StorageRequests().Publish(ctx, size, duration, maxPrice, validity)
+}
+
+// StorageRequest returns a storage request published by this node
+func (api *API) StorageRequest(id cid.Cid) (bidsub.SignedRequest, error) {
+	return api.storage.StorageRequests().Request(id)
+}
+
+// StorageRequestBids lists the unexpired bids on a storage request published
+// by this node, cheapest first
+func (api *API) StorageRequestBids(id cid.Cid) ([]bidsub.SignedBid, error) {
+	return api.storage.StorageRequests().Bids(id)
+}
+
+// MinerBids lists the unexpired bids the miner made on storage requests
+func (api *API) MinerBids() ([]bidsub.SignedBid, error) {
+	bidder := api.storage.Bidder()
+	if bidder == nil {
+		return nil, errors.New("Mining has not been started so there are no bids")
+	}
+	return bidder.Bids()
+}
+
+// EscrowBalance returns the market escrow of a client and the part of it
+// reserved by the deals it proposed that are not yet published
+func (api *API) EscrowBalance(ctx context.Context, client address.Address) (escrow.Balance, error) {
+	balance, err := api.storage.Client().GetPaymentEscrow(ctx, client)
+	if err != nil {
+		return escrow.Balance{}, err
+	}
+	return escrow.Balance{
+		Available: balance.Available,
+		Locked:    balance.Locked,
+		Reserved:  api.storage.Escrow().Reserved(client),
+	}, nil
+}
+
+// AddEscrow adds funds from the default wallet address to the market escrow
+// of a client, and waits for the funds to be added
+func (api *API) AddEscrow(ctx context.Context, client address.Address, amount abi.TokenAmount) error {
+	return api.storage.Client().AddPaymentEscrow(ctx, client, amount)
+}
+
 // ProposeStorageDeal proposes a storage deal
 func (api *API) ProposeStorageDeal(
 	ctx context.Context,
@@ -74,7 +149,21 @@ func (api *API) ProposeStorageDeal(
 
 // GetStorageDeal retrieves information about an in-progress deal
 func (api *API) GetStorageDeal(ctx context.Context, c cid.Cid) (storagemarket.ClientDeal, error) {
-	return api.storage.Client().GetLocalDeal(ctx, c)
+	deal, err := api.storage.Client().GetLocalDeal(ctx, c)
+	if errors.Is(err, datastore.ErrNotFound) {
+		return storagemarket.ClientDeal{}, errcode.Errorf(errcode.UnknownDeal, "unknown deal %s", c)
+	}
+	return deal, err
+}
+
+// GetStorageDealSealing asks the miner of a deal for the sealing progress of
+// the sectors holding its piece
+func (api *API) GetStorageDealSealing(ctx context.Context, deal storagemarket.ClientDeal) ([]piecemanager.SectorProgress, error) {
+	status, err := api.storage.Reconciler().DealStatus(ctx, deal)
+	if err != nil {
+		return nil, err
+	}
+	return status.Sealing, nil
 }
 
 // GetClientDeals retrieves information about a in-progress deals on th miner side
@@ -90,3 +179,62 @@ func (api *API) GetProviderDeals(ctx context.Context) ([]storagemarket.MinerDeal
 	}
 	return provider.ListLocalDeals()
 }
+
+// ReconcileDeals compares the client deals with a miner with the miner's
+// records of them and with the commitments on chain
+func (api *API) ReconcileDeals(ctx context.Context, minerAddr address.Address) (reconcile.Report, error) {
+	return api.storage.Reconciler().Reconcile(ctx, minerAddr)
+}
+
+// ListTransfers lists the data transfers of deal payloads of this node
+func (api *API) ListTransfers() []transfer.Transfer {
+	return api.storage.Transfers().Transfers()
+}
+
+// WatchTransfers calls watcher with the state of a data transfer each time it
+// changes, until unsubscribed. The watcher must not block.
+func (api *API) WatchTransfers(watcher func(transfer.Transfer)) func() {
+	return api.storage.Transfers().Watch(watcher)
+}
+
+// Replicate keeps a piece of data stored by several miners
+func (api *API) Replicate(ctx context.Context, root cid.Cid, policy replication.Policy) (replication.Piece, error) {
+	return api.storage.Replication().Replicate(ctx, root, policy)
+}
+
+// ReplicationStatus returns the replicas of a replicated piece of data
+func (api *API) ReplicationStatus(root cid.Cid) (replication.Piece, error) {
+	return api.storage.Replication().Status(root)
+}
+
+// StopReplication stops replicating a piece of data
+func (api *API) StopReplication(root cid.Cid) error {
+	return api.storage.Replication().Stop(root)
+}
+
+// AddClientData runs add, which adds client data to the blockstore and
+// returns its root, and tracks the data, pinned if pinned is set
+func (api *API) AddClientData(pinned bool, add func() (cid.Cid, error)) (cid.Cid, error) {
+	return api.storage.Pins().Add(pinned, add)
+}
+
+// PinClientData keeps client data from being garbage collected
+func (api *API) PinClientData(root cid.Cid) error {
+	return api.storage.Pins().Pin(root)
+}
+
+// UnpinClientData lets client data be garbage collected once its deals are done
+func (api *API) UnpinClientData(root cid.Cid) error {
+	return api.storage.Pins().Unpin(root)
+}
+
+// ListPins lists the pinned client data
+func (api *API) ListPins() []pin.Data {
+	return api.storage.Pins().Pins()
+}
+
+// CollectGarbage removes the client data that is neither pinned nor needed by
+// deals in progress or replications
+func (api *API) CollectGarbage(ctx context.Context, dryRun bool) (pin.GCResult, error) {
+	return api.storage.Pins().CollectGarbage(ctx, dryRun)
+}
@@ -0,0 +1,120 @@
+// Package escrow tracks the storage market funds of clients that are locked to
+// deals that have been proposed but not yet published.
+package escrow
+
+import (
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/ipfs/go-cid"
+)
+
+// Balance is the market escrow of a client with the part reserved by its
+// pending deals.
+type Balance struct {
+	Available abi.TokenAmount
+	Locked    abi.TokenAmount
+	// Reserved is the part of Available locked to deals proposed by this node
+	// and not yet published.
+	Reserved abi.TokenAmount
+}
+
+// Reservations holds the escrow reserved by the deals a client proposed.
+//
+// The market actor locks a client's escrow only when the deal is published, so
+// a client proposing several deals at once would otherwise count the same
+// available funds for each of them, and all but the first deal published
+// would fail. Reserving at proposal time keeps the funds of each deal apart.
+// Reservations are kept in memory only.
+type Reservations struct {
+	lk       sync.Mutex
+	reserved map[address.Address]abi.TokenAmount
+	deals    map[cid.Cid]reservation
+}
+
+type reservation struct {
+	client address.Address
+	amount abi.TokenAmount
+}
+
+// NewReservations creates an empty set of reservations.
+func NewReservations() *Reservations {
+	return &Reservations{
+		reserved: map[address.Address]abi.TokenAmount{},
+		deals:    map[cid.Cid]reservation{},
+	}
+}
+
+// Ensure reserves amount of the escrow of client, given the funds available in
+// the escrow. When the available funds do not cover the reservations of the
+// client, add is called with the missing funds. The reservation is not made
+// if add fails.
+func (r *Reservations) Ensure(client address.Address, available, amount abi.TokenAmount, add func(abi.TokenAmount) error) error {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	needed := big.Add(r.reservedLocked(client), amount)
+	if available.LessThan(needed) {
+		if err := add(big.Sub(needed, available)); err != nil {
+			return err
+		}
+	}
+	r.reserved[client] = big.Add(r.reservedLocked(client), amount)
+	return nil
+}
+
+// Assign records that a reservation made by Ensure is held by the deal with
+// the given proposal, to be released with Release.
+func (r *Reservations) Assign(proposal cid.Cid, client address.Address, amount abi.TokenAmount) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.deals[proposal] = reservation{client: client, amount: amount}
+}
+
+// Release releases the reservation held by the deal with the given proposal,
+// once the deal is published or has failed. Releasing a deal holding no
+// reservation does nothing.
+func (r *Reservations) Release(proposal cid.Cid) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+
+	res, ok := r.deals[proposal]
+	if !ok {
+		return
+	}
+	delete(r.deals, proposal)
+	r.releaseLocked(res.client, res.amount)
+}
+
+// Cancel releases a reservation made by Ensure that was never assigned to a
+// deal, because adding the funds failed.
+func (r *Reservations) Cancel(client address.Address, amount abi.TokenAmount) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.releaseLocked(client, amount)
+}
+
+func (r *Reservations) releaseLocked(client address.Address, amount abi.TokenAmount) {
+	left := big.Sub(r.reservedLocked(client), amount)
+	if left.LessThanEqual(big.Zero()) {
+		delete(r.reserved, client)
+		return
+	}
+	r.reserved[client] = left
+}
+
+// Reserved returns the escrow of client reserved by pending deals.
+func (r *Reservations) Reserved(client address.Address) abi.TokenAmount {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	return r.reservedLocked(client)
+}
+
+func (r *Reservations) reservedLocked(client address.Address) abi.TokenAmount {
+	if amount, ok := r.reserved[client]; ok {
+		return amount
+	}
+	return big.Zero()
+}
@@ -0,0 +1,76 @@
+package escrow_test
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/escrow"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+func TestReservations(t *testing.T) {
+	tf.UnitTest(t)
+
+	client := address.NewForTestGetter()()
+	proposal1 := types.CidFromString(t, "proposal1")
+	proposal2 := types.CidFromString(t, "proposal2")
+
+	ensure := func(r *escrow.Reservations, available, amount int64) (abi.TokenAmount, error) {
+		added := abi.NewTokenAmount(0)
+		err := r.Ensure(client, abi.NewTokenAmount(available), abi.NewTokenAmount(amount), func(missing abi.TokenAmount) error {
+			added = missing
+			return nil
+		})
+		return added, err
+	}
+
+	t.Run("adds only funds missing for the reservations", func(t *testing.T) {
+		r := escrow.NewReservations()
+
+		added, err := ensure(r, 100, 60)
+		require.NoError(t, err)
+		assert.Equal(t, abi.NewTokenAmount(0), added)
+		r.Assign(proposal1, client, abi.NewTokenAmount(60))
+
+		// the available funds are already reserved for the first deal
+		added, err = ensure(r, 100, 60)
+		require.NoError(t, err)
+		assert.Equal(t, abi.NewTokenAmount(20), added)
+		r.Assign(proposal2, client, abi.NewTokenAmount(60))
+		assert.Equal(t, abi.NewTokenAmount(120), r.Reserved(client))
+
+		r.Release(proposal1)
+		assert.Equal(t, abi.NewTokenAmount(60), r.Reserved(client))
+		// releasing twice does nothing
+		r.Release(proposal1)
+		assert.Equal(t, abi.NewTokenAmount(60), r.Reserved(client))
+
+		r.Release(proposal2)
+		assert.Equal(t, abi.NewTokenAmount(0), r.Reserved(client))
+	})
+
+	t.Run("does not reserve when adding funds fails", func(t *testing.T) {
+		r := escrow.NewReservations()
+
+		err := r.Ensure(client, abi.NewTokenAmount(10), abi.NewTokenAmount(60), func(abi.TokenAmount) error {
+			return errors.New("no funds")
+		})
+		assert.Error(t, err)
+		assert.Equal(t, abi.NewTokenAmount(0), r.Reserved(client))
+	})
+
+	t.Run("cancels unassigned reservations", func(t *testing.T) {
+		r := escrow.NewReservations()
+
+		_, err := ensure(r, 0, 60)
+		require.NoError(t, err)
+		r.Cancel(client, abi.NewTokenAmount(60))
+		assert.Equal(t, abi.NewTokenAmount(0), r.Reserved(client))
+	})
+}
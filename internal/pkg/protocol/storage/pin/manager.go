@@ -0,0 +1,309 @@
+// Package pin tracks the client data of the node and collects the data that
+// is neither pinned nor needed by deals.
+package pin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	format "github.com/ipfs/go-ipld-format"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/ipfs/go-merkledag"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/replication"
+)
+
+var log = logging.Logger("pin")
+
+// DSPrefix is the prefix of the datastore keys of tracked client data.
+const DSPrefix = "/client/data"
+
+// Deals lists the storage deals of the client, it is implemented by storagemarket.StorageClient.
+type Deals interface {
+	ListLocalDeals(ctx context.Context) ([]storagemarket.ClientDeal, error)
+}
+
+// Replications lists the pieces replicated by the client, it is implemented by replication.Manager.
+type Replications interface {
+	Pieces() []replication.Piece
+}
+
+// Manager tracks the client data imported into or pinned on the node. Tracked
+// data that is not pinned is removed from the blockstore by a garbage
+// collection once no deal in progress or replication needs it. Blocks shared
+// with data that is kept are never removed. Data that is not tracked, such as
+// the chain, is left alone.
+type Manager struct {
+	bs           blockstore.Blockstore
+	dag          format.DAGService
+	deals        Deals
+	replications Replications
+	ds           datastore.Datastore
+
+	// gcLk is held for writing by collections and for reading while data is
+	// added, so that the blocks of data being added are not collected.
+	gcLk sync.RWMutex
+
+	lk   sync.Mutex
+	data map[cid.Cid]Data
+}
+
+// NewManager returns a manager of the client data stored in `bs`, and loads
+// the data tracked before from `ds`. Blocks are only read from `bs`, never
+// fetched from the network.
+func NewManager(bs blockstore.Blockstore, deals Deals, replications Replications, ds datastore.Batching) (*Manager, error) {
+	m := &Manager{
+		bs:           bs,
+		dag:          merkledag.NewDAGService(blockservice.New(bs, offline.Exchange(bs))),
+		deals:        deals,
+		replications: replications,
+		ds:           namespace.Wrap(ds, datastore.NewKey(DSPrefix)),
+		data:         map[cid.Cid]Data{},
+	}
+
+	res, err := m.ds.Query(query.Query{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query client data")
+	}
+	defer res.Close() // nolint: errcheck
+	for entry := range res.Next() {
+		if entry.Error != nil {
+			return nil, errors.Wrap(entry.Error, "failed to load client data")
+		}
+		var d Data
+		if err := encoding.Decode(entry.Value, &d); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode client data %s", entry.Key)
+		}
+		m.data[d.Root] = d
+	}
+	return m, nil
+}
+
+// Add runs `add`, which adds data to the blockstore and returns its root, and
+// tracks the data, pinned if `pinned` is set. No collection runs while the
+// data is added.
+func (m *Manager) Add(pinned bool, add func() (cid.Cid, error)) (cid.Cid, error) {
+	m.gcLk.RLock()
+	defer m.gcLk.RUnlock()
+
+	root, err := add()
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	// Importing pinned data again leaves it pinned.
+	if d, ok := m.data[root]; ok && d.Pinned {
+		return root, nil
+	}
+	return root, m.store(Data{Root: root, Pinned: pinned})
+}
+
+// Pin pins the data with root `root`, which must be in the blockstore. Data
+// not tracked yet, e.g. retrieved data, is tracked from then on.
+func (m *Manager) Pin(root cid.Cid) error {
+	m.gcLk.RLock()
+	defer m.gcLk.RUnlock()
+
+	has, err := m.bs.Has(root)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return errors.Errorf("data %s is not stored locally", root)
+	}
+
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	return m.store(Data{Root: root, Pinned: true})
+}
+
+// Unpin unpins the data with root `root`, it is collected by the next
+// collection after its deals are done.
+func (m *Manager) Unpin(root cid.Cid) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	d, ok := m.data[root]
+	if !ok || !d.Pinned {
+		return errors.Errorf("data %s is not pinned", root)
+	}
+	return m.store(Data{Root: root, Pinned: false})
+}
+
+// Pins returns the pinned data.
+func (m *Manager) Pins() []Data {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+
+	var pins []Data
+	for _, d := range m.data {
+		if d.Pinned {
+			pins = append(pins, d)
+		}
+	}
+	sort.Slice(pins, func(i, j int) bool { return pins[i].Root.String() < pins[j].Root.String() })
+	return pins
+}
+
+// CollectGarbage removes the tracked data that is neither pinned, nor the
+// payload of a deal in progress, nor replicated. With `dryRun`, the data that
+// would be removed is returned and nothing is removed.
+func (m *Manager) CollectGarbage(ctx context.Context, dryRun bool) (GCResult, error) {
+	m.gcLk.Lock()
+	defer m.gcLk.Unlock()
+
+	m.lk.Lock()
+	tracked := make([]Data, 0, len(m.data))
+	for _, d := range m.data {
+		tracked = append(tracked, d)
+	}
+	m.lk.Unlock()
+	sort.Slice(tracked, func(i, j int) bool { return tracked[i].Root.String() < tracked[j].Root.String() })
+
+	// The data of deals and replications is kept whether it is tracked or
+	// not, so that shared blocks are not removed.
+	keep := map[cid.Cid]string{}
+	for _, d := range tracked {
+		if d.Pinned {
+			keep[d.Root] = "pinned"
+		}
+	}
+	deals, err := m.deals.ListLocalDeals(ctx)
+	if err != nil {
+		return GCResult{}, errors.Wrap(err, "failed to list deals")
+	}
+	for _, deal := range deals {
+		if deal.DataRef == nil || !needsData(deal.State) {
+			continue
+		}
+		if _, ok := keep[deal.DataRef.Root]; !ok {
+			keep[deal.DataRef.Root] = fmt.Sprintf("deal %s is %s", deal.ProposalCid, storagemarket.DealStates[deal.State])
+		}
+	}
+	for _, p := range m.replications.Pieces() {
+		if _, ok := keep[p.Root]; !ok {
+			keep[p.Root] = "replicated"
+		}
+	}
+
+	var result GCResult
+	var collected []cid.Cid
+	for _, d := range tracked {
+		if reason, ok := keep[d.Root]; ok {
+			result.Kept = append(result.Kept, Kept{Root: d.Root, Reason: reason})
+			continue
+		}
+		collected = append(collected, d.Root)
+	}
+
+	kept := cid.NewSet()
+	for root := range keep {
+		if err := merkledag.Walk(ctx, m.links, root, kept.Visit); err != nil {
+			return GCResult{}, errors.Wrapf(err, "failed to walk kept data %s", root)
+		}
+	}
+	// The blocks under a kept block are kept too, so the walk stops there.
+	removed := cid.NewSet()
+	for _, root := range collected {
+		visit := func(c cid.Cid) bool {
+			return !kept.Has(c) && removed.Visit(c)
+		}
+		if err := merkledag.Walk(ctx, m.links, root, visit); err != nil {
+			return GCResult{}, errors.Wrapf(err, "failed to walk data %s", root)
+		}
+	}
+
+	// Blocks are removed once all walks are done, as walks read the links of
+	// the blocks they visit.
+	err = removed.ForEach(func(c cid.Cid) error {
+		size, err := m.bs.GetSize(c)
+		if err == blockstore.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !dryRun {
+			if err := m.bs.DeleteBlock(c); err != nil {
+				return errors.Wrapf(err, "failed to remove block %s", c)
+			}
+		}
+		result.Blocks++
+		result.Size += uint64(size)
+		return nil
+	})
+	if err != nil {
+		return GCResult{}, err
+	}
+
+	if !dryRun {
+		m.lk.Lock()
+		defer m.lk.Unlock()
+		for _, root := range collected {
+			if err := m.ds.Delete(datastore.NewKey(root.String())); err != nil {
+				return GCResult{}, errors.Wrapf(err, "failed to untrack data %s", root)
+			}
+			delete(m.data, root)
+		}
+		log.Infof("collected %d data roots, %d blocks, %d bytes", len(collected), result.Blocks, result.Size)
+	}
+	result.Collected = collected
+	return result, nil
+}
+
+// links returns the links of a block, blocks not stored locally have none.
+func (m *Manager) links(ctx context.Context, c cid.Cid) ([]*format.Link, error) {
+	has, err := m.bs.Has(c)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, nil
+	}
+	nd, err := m.dag.Get(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return nd.Links(), nil
+}
+
+// store persists tracked data, m.lk must be held.
+func (m *Manager) store(d Data) error {
+	raw, err := encoding.Encode(d)
+	if err != nil {
+		return err
+	}
+	if err := m.ds.Put(datastore.NewKey(d.Root.String()), raw); err != nil {
+		return errors.Wrapf(err, "failed to store client data %s", d.Root)
+	}
+	m.data[d.Root] = d
+	return nil
+}
+
+// needsData returns whether a deal in state `state` may still transfer its
+// payload to the miner.
+func needsData(state storagemarket.StorageDealStatus) bool {
+	switch state {
+	case storagemarket.StorageDealActive, storagemarket.StorageDealCompleted,
+		storagemarket.StorageDealFailing, storagemarket.StorageDealError,
+		storagemarket.StorageDealProposalRejected, storagemarket.StorageDealProposalNotFound,
+		storagemarket.StorageDealNotFound:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,195 @@
+package pin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/replication"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+type fakeDeals []storagemarket.ClientDeal
+
+func (f *fakeDeals) ListLocalDeals(context.Context) ([]storagemarket.ClientDeal, error) {
+	return *f, nil
+}
+
+func (f *fakeDeals) add(t *testing.T, root cid.Cid, state storagemarket.StorageDealStatus) {
+	*f = append(*f, storagemarket.ClientDeal{
+		ProposalCid: types.CidFromString(t, "proposal"),
+		State:       state,
+		DataRef:     &storagemarket.DataRef{Root: root},
+	})
+}
+
+type fakeReplications []replication.Piece
+
+func (f fakeReplications) Pieces() []replication.Piece {
+	return f
+}
+
+type testStore struct {
+	ds    datastore.Batching
+	bs    blockstore.Blockstore
+	dserv format.DAGService
+}
+
+func newTestStore() *testStore {
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	bs := blockstore.NewBlockstore(ds)
+	return &testStore{ds: ds, bs: bs, dserv: merkledag.NewDAGService(blockservice.New(bs, offline.Exchange(bs)))}
+}
+
+// node adds a dag-pb node holding `data` and linking to `children`.
+func (s *testStore) node(t *testing.T, data string, children ...format.Node) format.Node {
+	nd := merkledag.NodeWithData([]byte(data))
+	for _, child := range children {
+		require.NoError(t, nd.AddNodeLink(child.Cid().String(), child))
+	}
+	require.NoError(t, s.dserv.Add(context.Background(), nd))
+	return nd
+}
+
+func (s *testStore) has(t *testing.T, nd format.Node) bool {
+	has, err := s.bs.Has(nd.Cid())
+	require.NoError(t, err)
+	return has
+}
+
+func (s *testStore) manager(t *testing.T, deals Deals, replications Replications) *Manager {
+	m, err := NewManager(s.bs, deals, replications, s.ds)
+	require.NoError(t, err)
+	return m
+}
+
+func add(t *testing.T, m *Manager, pinned bool, nd format.Node) {
+	_, err := m.Add(pinned, func() (cid.Cid, error) { return nd.Cid(), nil })
+	require.NoError(t, err)
+}
+
+func TestPins(t *testing.T) {
+	tf.UnitTest(t)
+
+	s := newTestStore()
+	m := s.manager(t, &fakeDeals{}, fakeReplications{})
+	a := s.node(t, "a")
+	b := s.node(t, "b")
+
+	add(t, m, true, a)
+	add(t, m, false, b)
+	assert.Equal(t, []Data{{Root: a.Cid(), Pinned: true}}, m.Pins())
+
+	require.NoError(t, m.Pin(b.Cid()))
+	require.NoError(t, m.Unpin(a.Cid()))
+	assert.Error(t, m.Unpin(a.Cid()))
+	assert.Error(t, m.Pin(types.CidFromString(t, "missing")))
+
+	// Pins are loaded again by a new manager.
+	assert.Equal(t, []Data{{Root: b.Cid(), Pinned: true}}, s.manager(t, &fakeDeals{}, fakeReplications{}).Pins())
+}
+
+func TestCollectGarbage(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	t.Run("collects unpinned data and keeps shared blocks", func(t *testing.T) {
+		s := newTestStore()
+		m := s.manager(t, &fakeDeals{}, fakeReplications{})
+		shared := s.node(t, "shared")
+		own := s.node(t, "own")
+		unpinned := s.node(t, "unpinned", shared, own)
+		pinned := s.node(t, "pinned", shared)
+		add(t, m, false, unpinned)
+		add(t, m, true, pinned)
+
+		res, err := m.CollectGarbage(ctx, false)
+		require.NoError(t, err)
+		assert.Equal(t, []cid.Cid{unpinned.Cid()}, res.Collected)
+		assert.Equal(t, []Kept{{Root: pinned.Cid(), Reason: "pinned"}}, res.Kept)
+		assert.Equal(t, uint64(2), res.Blocks)
+		assert.Equal(t, uint64(len(unpinned.RawData())+len(own.RawData())), res.Size)
+
+		assert.False(t, s.has(t, unpinned))
+		assert.False(t, s.has(t, own))
+		assert.True(t, s.has(t, shared))
+		assert.True(t, s.has(t, pinned))
+
+		// Collected data is no longer tracked.
+		res, err = m.CollectGarbage(ctx, false)
+		require.NoError(t, err)
+		assert.Empty(t, res.Collected)
+	})
+
+	t.Run("keeps data of deals in progress and replicated data", func(t *testing.T) {
+		s := newTestStore()
+		deals := &fakeDeals{}
+		inProgress := s.node(t, "in progress")
+		active := s.node(t, "active")
+		failed := s.node(t, "failed")
+		replicated := s.node(t, "replicated")
+		deals.add(t, inProgress.Cid(), storagemarket.StorageDealTransferring)
+		deals.add(t, active.Cid(), storagemarket.StorageDealActive)
+		deals.add(t, failed.Cid(), storagemarket.StorageDealError)
+		m := s.manager(t, deals, fakeReplications{{Root: replicated.Cid()}})
+		for _, nd := range []format.Node{inProgress, active, failed, replicated} {
+			add(t, m, false, nd)
+		}
+
+		res, err := m.CollectGarbage(ctx, false)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []cid.Cid{active.Cid(), failed.Cid()}, res.Collected)
+		assert.ElementsMatch(t, []Kept{
+			{Root: inProgress.Cid(), Reason: "deal " + types.CidFromString(t, "proposal").String() + " is StorageDealTransferring"},
+			{Root: replicated.Cid(), Reason: "replicated"},
+		}, res.Kept)
+		assert.True(t, s.has(t, inProgress))
+		assert.True(t, s.has(t, replicated))
+		assert.False(t, s.has(t, active))
+		assert.False(t, s.has(t, failed))
+	})
+
+	t.Run("dry run removes nothing", func(t *testing.T) {
+		s := newTestStore()
+		m := s.manager(t, &fakeDeals{}, fakeReplications{})
+		nd := s.node(t, "data")
+		add(t, m, false, nd)
+
+		res, err := m.CollectGarbage(ctx, true)
+		require.NoError(t, err)
+		assert.Equal(t, []cid.Cid{nd.Cid()}, res.Collected)
+		assert.Equal(t, uint64(1), res.Blocks)
+		assert.True(t, s.has(t, nd))
+
+		res, err = m.CollectGarbage(ctx, false)
+		require.NoError(t, err)
+		assert.Equal(t, []cid.Cid{nd.Cid()}, res.Collected)
+		assert.False(t, s.has(t, nd))
+	})
+
+	t.Run("skips blocks not stored locally", func(t *testing.T) {
+		s := newTestStore()
+		m := s.manager(t, &fakeDeals{}, fakeReplications{})
+		missing := s.node(t, "missing")
+		nd := s.node(t, "partial", missing)
+		require.NoError(t, s.bs.DeleteBlock(missing.Cid()))
+		add(t, m, false, nd)
+
+		res, err := m.CollectGarbage(ctx, false)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), res.Blocks)
+		assert.False(t, s.has(t, nd))
+	})
+}
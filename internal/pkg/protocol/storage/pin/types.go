@@ -0,0 +1,31 @@
+package pin
+
+import (
+	"github.com/ipfs/go-cid"
+)
+
+// Data is a DAG of client data imported into or pinned on the node.
+type Data struct {
+	_    struct{} `cbor:",toarray"`
+	Root cid.Cid
+	// Pinned data is never garbage collected, unpinned data is once it is no
+	// longer needed by deals.
+	Pinned bool
+}
+
+// Kept is tracked data kept by a garbage collection, and why.
+type Kept struct {
+	Root   cid.Cid
+	Reason string
+}
+
+// GCResult is the outcome of a garbage collection of client data.
+type GCResult struct {
+	// Collected are the roots of the data removed.
+	Collected []cid.Cid
+	// Kept are the roots of the data still tracked.
+	Kept []Kept
+	// Blocks is the number of blocks removed, and Size their size in bytes.
+	Blocks uint64
+	Size   uint64
+}
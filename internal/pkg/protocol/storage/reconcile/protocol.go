@@ -0,0 +1,239 @@
+// Package reconcile compares the deal records of a storage client with those
+// of the miner and with the commitments on chain, and implements the deal
+// status protocol through which clients query the records of miners and the
+// sealing progress of their deals.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/cborutil"
+	e "github.com/filecoin-project/go-filecoin/internal/pkg/enccid"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/errcode"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/piecemanager"
+)
+
+var log = logging.Logger("reconcile")
+
+// DealStatusProtocolID is the libp2p protocol identifier of the deal status
+// protocol.
+const DealStatusProtocolID = "/fil/storage/dealstatus/1.1.0"
+
+// MaxQueryProposals is the most deals a query asks about.
+const MaxQueryProposals = 1024
+
+// DealStatusRequest asks a miner for its records of the deals of the given
+// proposals.
+type DealStatusRequest struct {
+	_         struct{} `cbor:",toarray"`
+	Proposals []e.Cid
+}
+
+// DealStatusResponse holds the records of the deals asked about, in the order
+// of the request.
+type DealStatusResponse struct {
+	_     struct{} `cbor:",toarray"`
+	Deals []DealStatus
+}
+
+// DealStatus is the miner's record of a deal. Found is false when the miner
+// has no deal for the proposal. Sectors are the sectors the miner stores the
+// piece of the deal in, Sealing the progress of those not yet proving.
+type DealStatus struct {
+	_        struct{} `cbor:",toarray"`
+	Proposal e.Cid
+	Found    bool
+	State    storagemarket.StorageDealStatus
+	DealID   abi.DealID
+	Sectors  []abi.SectorNumber
+	Message  string
+	Sealing  []piecemanager.SectorProgress
+}
+
+// Server answers the deal status queries of clients with the records of the
+// miner. The records of a deal are only given to those asking with the CID of
+// its proposal, which is only known to the parties of the deal.
+type Server struct {
+	host     host.Host
+	deals    func() ([]storagemarket.MinerDeal, error)
+	pieces   func(context.Context) ([]piecemanager.PieceInfo, error)
+	progress func(context.Context) ([]piecemanager.SectorProgress, error)
+}
+
+// NewServer creates a server of the deal status protocol listing the deals,
+// the pieces and the sealing progress of the sectors of the miner with
+// `deals`, `pieces` and `progress`.
+func NewServer(h host.Host, deals func() ([]storagemarket.MinerDeal, error), pieces func(context.Context) ([]piecemanager.PieceInfo, error),
+	progress func(context.Context) ([]piecemanager.SectorProgress, error)) *Server {
+	return &Server{host: h, deals: deals, pieces: pieces, progress: progress}
+}
+
+// Start handles the deal status queries sent to the host.
+func (s *Server) Start() {
+	s.host.SetStreamHandler(DealStatusProtocolID, s.handleStream)
+}
+
+// Stop stops handling queries.
+func (s *Server) Stop() {
+	s.host.RemoveStreamHandler(DealStatusProtocolID)
+}
+
+func (s *Server) handleStream(stream network.Stream) {
+	defer stream.Close() // nolint: errcheck
+
+	var req DealStatusRequest
+	if err := cborutil.NewMsgReader(stream).ReadMsg(&req); err != nil {
+		log.Debugf("failed to read deal status request from %s: %s", stream.Conn().RemotePeer(), err)
+		return
+	}
+	if len(req.Proposals) > MaxQueryProposals {
+		log.Debugf("rejected deal status request from %s for %d deals", stream.Conn().RemotePeer(), len(req.Proposals))
+		return
+	}
+
+	resp, err := s.statuses(context.Background(), req.Proposals)
+	if err != nil {
+		log.Errorf("failed to answer deal status request: %s", err)
+		return
+	}
+	if err := writeMsg(stream, resp); err != nil {
+		log.Debugf("failed to write deal status response to %s: %s", stream.Conn().RemotePeer(), err)
+	}
+}
+
+func (s *Server) statuses(ctx context.Context, proposals []e.Cid) (*DealStatusResponse, error) {
+	deals, err := s.deals()
+	if err != nil {
+		return nil, err
+	}
+	byProposal := make(map[cid.Cid]storagemarket.MinerDeal, len(deals))
+	for _, deal := range deals {
+		byProposal[deal.ProposalCid] = deal
+	}
+
+	pieces, err := s.pieces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sectors := make(map[abi.DealID][]abi.SectorNumber)
+	for _, piece := range pieces {
+		for _, id := range piece.Deals {
+			sectors[id] = append(sectors[id], piece.Sectors...)
+		}
+	}
+
+	var progress map[abi.SectorNumber]piecemanager.SectorProgress
+
+	resp := &DealStatusResponse{Deals: make([]DealStatus, len(proposals))}
+	for i, proposal := range proposals {
+		status := DealStatus{Proposal: proposal}
+		if deal, ok := byProposal[proposal.Cid]; ok {
+			status.Found = true
+			status.State = deal.State
+			status.DealID = deal.DealID
+			status.Message = deal.Message
+			if published(deal.State) {
+				status.Sectors = sectors[deal.DealID]
+			}
+			if !active(deal.State) && len(status.Sectors) > 0 {
+				if progress == nil {
+					if progress, err = s.sectorProgress(ctx); err != nil {
+						return nil, err
+					}
+				}
+				for _, num := range status.Sectors {
+					if p, ok := progress[num]; ok {
+						status.Sealing = append(status.Sealing, p)
+					}
+				}
+			}
+		}
+		resp.Deals[i] = status
+	}
+	return resp, nil
+}
+
+func (s *Server) sectorProgress(ctx context.Context) (map[abi.SectorNumber]piecemanager.SectorProgress, error) {
+	all, err := s.progress(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bySector := make(map[abi.SectorNumber]piecemanager.SectorProgress, len(all))
+	for _, p := range all {
+		bySector[p.Sector] = p
+	}
+	return bySector, nil
+}
+
+// Query asks the miner with peer id `p` for its records of the deals of
+// `proposals`.
+func Query(ctx context.Context, h host.Host, p peer.ID, proposals []cid.Cid) ([]DealStatus, error) {
+	var statuses []DealStatus
+	for start := 0; start < len(proposals); start += MaxQueryProposals {
+		end := start + MaxQueryProposals
+		if end > len(proposals) {
+			end = len(proposals)
+		}
+		batch, err := query(ctx, h, p, proposals[start:end])
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, batch...)
+	}
+	return statuses, nil
+}
+
+func query(ctx context.Context, h host.Host, p peer.ID, proposals []cid.Cid) ([]DealStatus, error) {
+	stream, err := h.NewStream(ctx, p, DealStatusProtocolID)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to open deal status stream to %s", p)
+		if h.Network().Connectedness(p) != network.Connected {
+			// the miner could not be dialed, rather than not speaking the protocol
+			err = errcode.Wrap(errcode.MinerOffline, err)
+		}
+		return nil, err
+	}
+	defer stream.Close() // nolint: errcheck
+
+	req := DealStatusRequest{Proposals: make([]e.Cid, len(proposals))}
+	for i, proposal := range proposals {
+		req.Proposals[i] = e.NewCid(proposal)
+	}
+	if err := writeMsg(stream, &req); err != nil {
+		return nil, err
+	}
+
+	var resp DealStatusResponse
+	if err := cborutil.NewMsgReader(stream).ReadMsg(&resp); err != nil {
+		return nil, errors.Wrapf(err, "failed to read deal status response from %s", p)
+	}
+	if len(resp.Deals) != len(proposals) {
+		return nil, fmt.Errorf("miner %s answered for %d deals of %d", p, len(resp.Deals), len(proposals))
+	}
+	for i, status := range resp.Deals {
+		if !status.Proposal.Equals(proposals[i]) {
+			return nil, fmt.Errorf("miner %s answered for deal %s instead of %s", p, status.Proposal, proposals[i])
+		}
+	}
+	return resp.Deals, nil
+}
+
+func writeMsg(stream network.Stream, msg interface{}) error {
+	raw, err := encoding.Encode(msg)
+	if err != nil {
+		return err
+	}
+	_, err = stream.Write(raw)
+	return err
+}
@@ -0,0 +1,246 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/pkg/errors"
+)
+
+// Kinds of mismatches between the records of a deal.
+const (
+	// MismatchUnknownToMiner is a deal the miner has no record of.
+	MismatchUnknownToMiner = "unknown-to-miner"
+	// MismatchFailed is a deal that failed for one party only.
+	MismatchFailed = "failed"
+	// MismatchDealID is a deal published with a different id.
+	MismatchDealID = "deal-id"
+	// MismatchNotCommitted is a deal reported active whose sector was never
+	// committed on chain.
+	MismatchNotCommitted = "not-committed"
+	// MismatchSectorMissing is a deal whose sectors reported by the miner do
+	// not hold it on chain.
+	MismatchSectorMissing = "sector-missing"
+	// MismatchSlashed is a deal reported active that was slashed on chain.
+	MismatchSlashed = "slashed"
+)
+
+// Mismatch describes a deal whose records disagree.
+type Mismatch struct {
+	ProposalCid cid.Cid
+	DealID      abi.DealID
+	Kind        string
+	ClientState string
+	MinerState  string
+	Detail      string
+}
+
+// Report is the result of reconciling the deals of a client with a miner.
+type Report struct {
+	Miner      address.Address
+	Deals      int
+	Mismatches []Mismatch
+}
+
+// ChainView is the state of the chain the deals are checked against.
+type ChainView interface {
+	MinerInfo(ctx context.Context, maddr address.Address) (miner.MinerInfo, error)
+	MinerGetSector(ctx context.Context, maddr address.Address, sectorNum abi.SectorNumber) (*miner.SectorOnChainInfo, bool, error)
+	MarketDealState(ctx context.Context, dealID abi.DealID) (*market.DealState, bool, error)
+}
+
+// Reconciler reconciles the deals of the storage client of the node with the
+// miners.
+type Reconciler struct {
+	host   host.Host
+	client storagemarket.StorageClient
+	view   func() (ChainView, error)
+}
+
+// NewReconciler creates a reconciler of the deals of `client`, querying the
+// miners through `h` and checking the deals against the head state of `view`.
+func NewReconciler(h host.Host, client storagemarket.StorageClient, view func() (ChainView, error)) *Reconciler {
+	return &Reconciler{host: h, client: client, view: view}
+}
+
+// Reconcile compares the records of the deals of the client with `minerAddr`
+// with those of the miner and with the chain.
+func (r *Reconciler) Reconcile(ctx context.Context, minerAddr address.Address) (Report, error) {
+	all, err := r.client.ListLocalDeals(ctx)
+	if err != nil {
+		return Report{}, errors.Wrap(err, "failed to list client deals")
+	}
+	var local []storagemarket.ClientDeal
+	var proposals []cid.Cid
+	for _, deal := range all {
+		if deal.Proposal.Provider == minerAddr {
+			local = append(local, deal)
+			proposals = append(proposals, deal.ProposalCid)
+		}
+	}
+	report := Report{Miner: minerAddr, Deals: len(local)}
+	if len(local) == 0 {
+		return report, nil
+	}
+
+	view, err := r.view()
+	if err != nil {
+		return Report{}, err
+	}
+	info, err := view.MinerInfo(ctx, minerAddr)
+	if err != nil {
+		return Report{}, errors.Wrapf(err, "failed to get peer id of miner %s", minerAddr)
+	}
+	remote, err := Query(ctx, r.host, info.PeerId, proposals)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report.Mismatches, err = Compare(ctx, view, minerAddr, local, remote)
+	if err != nil {
+		return Report{}, err
+	}
+	return report, nil
+}
+
+// DealStatus asks the miner of `deal` for its record of the deal, including
+// the sealing progress of its sectors.
+func (r *Reconciler) DealStatus(ctx context.Context, deal storagemarket.ClientDeal) (DealStatus, error) {
+	view, err := r.view()
+	if err != nil {
+		return DealStatus{}, err
+	}
+	info, err := view.MinerInfo(ctx, deal.Proposal.Provider)
+	if err != nil {
+		return DealStatus{}, errors.Wrapf(err, "failed to get peer id of miner %s", deal.Proposal.Provider)
+	}
+	statuses, err := Query(ctx, r.host, info.PeerId, []cid.Cid{deal.ProposalCid})
+	if err != nil {
+		return DealStatus{}, err
+	}
+	return statuses[0], nil
+}
+
+// Compare returns the mismatches between the records of the deals of the
+// client with `minerAddr`, those of the miner and the chain. Deals the miner
+// or the client consider active must be in a sector committed on chain, and
+// in one of the sectors the miner reports.
+func Compare(ctx context.Context, view ChainView, minerAddr address.Address, local []storagemarket.ClientDeal, remote []DealStatus) ([]Mismatch, error) {
+	byProposal := make(map[cid.Cid]DealStatus, len(remote))
+	for _, status := range remote {
+		byProposal[status.Proposal.Cid] = status
+	}
+
+	mismatches := []Mismatch{}
+	for _, deal := range local {
+		m := Mismatch{
+			ProposalCid: deal.ProposalCid,
+			DealID:      deal.DealID,
+			ClientState: storagemarket.DealStates[deal.State],
+		}
+		status, ok := byProposal[deal.ProposalCid]
+		if !ok || !status.Found {
+			if !failed(deal.State) {
+				m.Kind = MismatchUnknownToMiner
+				m.Detail = "the miner has no record of the deal"
+				mismatches = append(mismatches, m)
+			}
+			continue
+		}
+		m.MinerState = storagemarket.DealStates[status.State]
+		if m.DealID == 0 {
+			m.DealID = status.DealID
+		}
+
+		if failed(deal.State) != failed(status.State) {
+			m.Kind = MismatchFailed
+			m.Detail = status.Message
+			if failed(deal.State) {
+				m.Detail = deal.Message
+			}
+			mismatches = append(mismatches, m)
+			continue
+		}
+		if deal.DealID != 0 && status.DealID != 0 && deal.DealID != status.DealID {
+			m.Kind = MismatchDealID
+			m.Detail = fmt.Sprintf("the miner published the deal as %d", status.DealID)
+			mismatches = append(mismatches, m)
+			continue
+		}
+		if !active(deal.State) && !active(status.State) {
+			continue
+		}
+
+		kind, detail, err := checkCommitted(ctx, view, minerAddr, m.DealID, status.Sectors)
+		if err != nil {
+			return nil, err
+		}
+		if kind != "" {
+			m.Kind = kind
+			m.Detail = detail
+			mismatches = append(mismatches, m)
+		}
+	}
+	return mismatches, nil
+}
+
+// checkCommitted checks that the deal `dealID` is active on chain, in one of
+// the `sectors` of the miner when it reports any.
+func checkCommitted(ctx context.Context, view ChainView, minerAddr address.Address, dealID abi.DealID, sectors []abi.SectorNumber) (string, string, error) {
+	state, found, err := view.MarketDealState(ctx, dealID)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to get state of deal %d", dealID)
+	}
+	if !found || state.SectorStartEpoch < 0 {
+		return MismatchNotCommitted, "no sector holding the deal was committed on chain", nil
+	}
+	if state.SlashEpoch >= 0 {
+		return MismatchSlashed, fmt.Sprintf("the deal was slashed at height %d", state.SlashEpoch), nil
+	}
+	if len(sectors) == 0 {
+		return "", "", nil
+	}
+	for _, num := range sectors {
+		sector, found, err := view.MinerGetSector(ctx, minerAddr, num)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "failed to get sector %d", num)
+		}
+		if !found {
+			continue
+		}
+		for _, id := range sector.Info.DealIDs {
+			if id == dealID {
+				return "", "", nil
+			}
+		}
+	}
+	return MismatchSectorMissing, fmt.Sprintf("none of sectors %v of the miner holds the deal", sectors), nil
+}
+
+func failed(state storagemarket.StorageDealStatus) bool {
+	switch state {
+	case storagemarket.StorageDealProposalNotFound, storagemarket.StorageDealProposalRejected,
+		storagemarket.StorageDealFailing, storagemarket.StorageDealNotFound, storagemarket.StorageDealError:
+		return true
+	}
+	return false
+}
+
+func published(state storagemarket.StorageDealStatus) bool {
+	switch state {
+	case storagemarket.StorageDealStaged, storagemarket.StorageDealSealing, storagemarket.StorageDealActive, storagemarket.StorageDealCompleted:
+		return true
+	}
+	return false
+}
+
+func active(state storagemarket.StorageDealStatus) bool {
+	return state == storagemarket.StorageDealActive || state == storagemarket.StorageDealCompleted
+}
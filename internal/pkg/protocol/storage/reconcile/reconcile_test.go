@@ -0,0 +1,166 @@
+package reconcile_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/ipfs/go-cid"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	e "github.com/filecoin-project/go-filecoin/internal/pkg/enccid"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/piecemanager"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/reconcile"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	vmaddr "github.com/filecoin-project/go-filecoin/internal/pkg/vm/address"
+)
+
+type fakeChainView struct {
+	deals   map[abi.DealID]*market.DealState
+	sectors map[abi.SectorNumber]*miner.SectorOnChainInfo
+}
+
+func (v *fakeChainView) MinerInfo(_ context.Context, _ address.Address) (miner.MinerInfo, error) {
+	return miner.MinerInfo{}, nil
+}
+
+func (v *fakeChainView) MinerGetSector(_ context.Context, _ address.Address, num abi.SectorNumber) (*miner.SectorOnChainInfo, bool, error) {
+	sector, found := v.sectors[num]
+	return sector, found, nil
+}
+
+func (v *fakeChainView) MarketDealState(_ context.Context, id abi.DealID) (*market.DealState, bool, error) {
+	state, found := v.deals[id]
+	return state, found, nil
+}
+
+func TestCompare(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	minerAddr := vmaddr.RequireIDAddress(t, 100)
+	newCid := types.NewCidForTestGetter()
+
+	clientDeal := func(state storagemarket.StorageDealStatus, id abi.DealID) storagemarket.ClientDeal {
+		return storagemarket.ClientDeal{ProposalCid: newCid(), State: state, DealID: id}
+	}
+	minerStatus := func(deal storagemarket.ClientDeal, state storagemarket.StorageDealStatus, id abi.DealID, sectors ...abi.SectorNumber) reconcile.DealStatus {
+		return reconcile.DealStatus{Proposal: e.NewCid(deal.ProposalCid), Found: true, State: state, DealID: id, Sectors: sectors}
+	}
+	committed := &market.DealState{SectorStartEpoch: 10, LastUpdatedEpoch: -1, SlashEpoch: -1}
+	view := &fakeChainView{
+		deals: map[abi.DealID]*market.DealState{
+			1: committed,
+			2: {SectorStartEpoch: -1, LastUpdatedEpoch: -1, SlashEpoch: -1},
+			4: committed,
+			5: {SectorStartEpoch: 10, LastUpdatedEpoch: 20, SlashEpoch: 20},
+		},
+		sectors: map[abi.SectorNumber]*miner.SectorOnChainInfo{
+			7: {Info: miner.SectorPreCommitInfo{SectorNumber: 7, DealIDs: []abi.DealID{1}}},
+			8: {Info: miner.SectorPreCommitInfo{SectorNumber: 8, DealIDs: []abi.DealID{9}}},
+		},
+	}
+
+	healthy := clientDeal(storagemarket.StorageDealActive, 1)
+	unknown := clientDeal(storagemarket.StorageDealProposalAccepted, 0)
+	failedUnknown := clientDeal(storagemarket.StorageDealError, 0)
+	failedByMiner := clientDeal(storagemarket.StorageDealSealing, 3)
+	otherID := clientDeal(storagemarket.StorageDealActive, 6)
+	uncommitted := clientDeal(storagemarket.StorageDealSealing, 2)
+	missing := clientDeal(storagemarket.StorageDealActive, 4)
+	slashed := clientDeal(storagemarket.StorageDealActive, 5)
+	pending := clientDeal(storagemarket.StorageDealSealing, 10)
+
+	local := []storagemarket.ClientDeal{healthy, unknown, failedUnknown, failedByMiner, otherID, uncommitted, missing, slashed, pending}
+	remote := []reconcile.DealStatus{
+		minerStatus(healthy, storagemarket.StorageDealActive, 1, 7),
+		{Proposal: e.NewCid(unknown.ProposalCid)},
+		{Proposal: e.NewCid(failedUnknown.ProposalCid)},
+		minerStatus(failedByMiner, storagemarket.StorageDealFailing, 3),
+		minerStatus(otherID, storagemarket.StorageDealActive, 9, 8),
+		minerStatus(uncommitted, storagemarket.StorageDealCompleted, 2, 7),
+		minerStatus(missing, storagemarket.StorageDealActive, 4, 7, 8, 12),
+		minerStatus(slashed, storagemarket.StorageDealActive, 5),
+		minerStatus(pending, storagemarket.StorageDealSealing, 10),
+	}
+
+	mismatches, err := reconcile.Compare(ctx, view, minerAddr, local, remote)
+	require.NoError(t, err)
+
+	kinds := map[cid.Cid]string{}
+	for _, m := range mismatches {
+		kinds[m.ProposalCid] = m.Kind
+	}
+	assert.Equal(t, map[cid.Cid]string{
+		unknown.ProposalCid:       reconcile.MismatchUnknownToMiner,
+		failedByMiner.ProposalCid: reconcile.MismatchFailed,
+		otherID.ProposalCid:       reconcile.MismatchDealID,
+		uncommitted.ProposalCid:   reconcile.MismatchNotCommitted,
+		missing.ProposalCid:       reconcile.MismatchSectorMissing,
+		slashed.ProposalCid:       reconcile.MismatchSlashed,
+	}, kinds)
+
+	for _, m := range mismatches {
+		if m.ProposalCid == uncommitted.ProposalCid {
+			assert.Equal(t, "StorageDealSealing", m.ClientState)
+			assert.Equal(t, "StorageDealCompleted", m.MinerState)
+			assert.Equal(t, abi.DealID(2), m.DealID)
+		}
+	}
+}
+
+func TestDealStatusQuery(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mn, err := mocknet.WithNPeers(ctx, 2)
+	require.NoError(t, err)
+	client, provider := mn.Hosts()[0], mn.Hosts()[1]
+	require.NoError(t, mn.LinkAll())
+	require.NoError(t, mn.ConnectAllButSelf())
+
+	newCid := types.NewCidForTestGetter()
+	sealing := storagemarket.MinerDeal{ProposalCid: newCid(), State: storagemarket.StorageDealSealing, DealID: 4}
+	transferring := storagemarket.MinerDeal{ProposalCid: newCid(), State: storagemarket.StorageDealTransferring}
+	unknown := newCid()
+
+	server := reconcile.NewServer(provider, func() ([]storagemarket.MinerDeal, error) {
+		return []storagemarket.MinerDeal{sealing, transferring}, nil
+	}, func(context.Context) ([]piecemanager.PieceInfo, error) {
+		return []piecemanager.PieceInfo{{Deals: []abi.DealID{4}, Sectors: []abi.SectorNumber{2}}}, nil
+	}, func(context.Context) ([]piecemanager.SectorProgress, error) {
+		return []piecemanager.SectorProgress{
+			{Sector: 1, Phase: "Proving", Percent: 100},
+			{Sector: 2, Phase: "PreCommit2", Percent: 51, EstimatedCompletion: 4000},
+		}, nil
+	})
+	server.Start()
+	defer server.Stop()
+
+	statuses, err := reconcile.Query(ctx, client, provider.ID(), []cid.Cid{unknown, sealing.ProposalCid, transferring.ProposalCid})
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+
+	assert.False(t, statuses[0].Found)
+	assert.True(t, statuses[1].Proposal.Equals(sealing.ProposalCid))
+	assert.True(t, statuses[1].Found)
+	assert.Equal(t, storagemarket.StorageDealSealing, statuses[1].State)
+	assert.Equal(t, abi.DealID(4), statuses[1].DealID)
+	assert.Equal(t, []abi.SectorNumber{2}, statuses[1].Sectors)
+	require.Len(t, statuses[1].Sealing, 1)
+	assert.Equal(t, abi.SectorNumber(2), statuses[1].Sealing[0].Sector)
+	assert.Equal(t, "PreCommit2", statuses[1].Sealing[0].Phase)
+	assert.Equal(t, uint64(51), statuses[1].Sealing[0].Percent)
+	assert.Equal(t, uint64(4000), statuses[1].Sealing[0].EstimatedCompletion)
+	assert.True(t, statuses[2].Found)
+	assert.Empty(t, statuses[2].Sectors)
+}
@@ -0,0 +1,396 @@
+// Package replication keeps pieces of data stored by several miners, making
+// new storage deals when the deals holding a piece fail or expire.
+package replication
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+)
+
+var log = logging.Logger("replication")
+
+// DSPrefix is the prefix of the datastore keys of replicated pieces.
+const DSPrefix = "/deals/replication"
+
+// Client makes and queries storage deals, it is implemented by storagemarket.StorageClient.
+type Client interface {
+	ListProviders(ctx context.Context) (<-chan storagemarket.StorageProviderInfo, error)
+	GetAsk(ctx context.Context, info storagemarket.StorageProviderInfo) (*storagemarket.SignedStorageAsk, error)
+	ProposeStorageDeal(ctx context.Context, addr address.Address, info *storagemarket.StorageProviderInfo, data *storagemarket.DataRef, startEpoch abi.ChainEpoch, endEpoch abi.ChainEpoch, price abi.TokenAmount, collateral abi.TokenAmount, rt abi.RegisteredProof) (*storagemarket.ProposeStorageDealResult, error)
+	GetLocalDeal(ctx context.Context, cid cid.Cid) (storagemarket.ClientDeal, error)
+}
+
+// Chain provides the head of the chain.
+type Chain interface {
+	Head() block.TipSetKey
+	GetTipSet(block.TipSetKey) (block.TipSet, error)
+}
+
+// SealProofTypeGetter returns the seal proof type of a miner at a tipset.
+type SealProofTypeGetter func(ctx context.Context, maddr address.Address, tsk block.TipSetKey) (abi.RegisteredProof, error)
+
+// Manager replicates pieces with miners. The replicas of the pieces are
+// checked every few epochs, and when fewer deals than the replication factor
+// of a piece are in progress or active, deals are proposed to miners not
+// holding the piece yet.
+type Manager struct {
+	client        Client
+	chain         Chain
+	sealProofType SealProofTypeGetter
+	clientAddr    func() (address.Address, error)
+	ds            datastore.Datastore
+	checkInterval abi.ChainEpoch
+	startDelay    abi.ChainEpoch
+
+	// checkLk serializes the checks and replications, which make deals.
+	checkLk sync.Mutex
+
+	lk        sync.Mutex
+	pieces    map[cid.Cid]*Piece
+	lastCheck abi.ChainEpoch
+	checking  bool
+}
+
+// NewManager returns a manager replicating pieces with deals made by `client`
+// from the address returned by `clientAddr`, and loads the pieces replicated
+// before from `ds`. Replicas are checked every `checkInterval` epochs, and
+// deals start `startDelay` epochs after being proposed.
+func NewManager(client Client, chain Chain, sealProofType SealProofTypeGetter, clientAddr func() (address.Address, error), ds datastore.Batching, checkInterval, startDelay abi.ChainEpoch) (*Manager, error) {
+	m := &Manager{
+		client:        client,
+		chain:         chain,
+		sealProofType: sealProofType,
+		clientAddr:    clientAddr,
+		ds:            namespace.Wrap(ds, datastore.NewKey(DSPrefix)),
+		checkInterval: checkInterval,
+		startDelay:    startDelay,
+		pieces:        map[cid.Cid]*Piece{},
+	}
+
+	res, err := m.ds.Query(query.Query{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query replicated pieces")
+	}
+	defer res.Close() // nolint: errcheck
+	for entry := range res.Next() {
+		if entry.Error != nil {
+			return nil, errors.Wrap(entry.Error, "failed to load replicated pieces")
+		}
+		var p Piece
+		if err := encoding.Decode(entry.Value, &p); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode replicated piece %s", entry.Key)
+		}
+		m.pieces[p.Root] = &p
+	}
+	return m, nil
+}
+
+// Replicate starts keeping the piece of data with root `root` stored by
+// `policy.Factor` miners for `policy.Duration` epochs, and proposes the first
+// deals. The piece is replicated even if not enough miners accept it yet.
+func (m *Manager) Replicate(ctx context.Context, root cid.Cid, policy Policy) (Piece, error) {
+	if policy.Factor == 0 {
+		return Piece{}, errors.New("replication factor must be at least 1")
+	}
+	if policy.Duration <= m.startDelay {
+		return Piece{}, errors.Errorf("duration must be longer than the start delay of deals, %d epochs", m.startDelay)
+	}
+
+	m.checkLk.Lock()
+	defer m.checkLk.Unlock()
+
+	m.lk.Lock()
+	_, exists := m.pieces[root]
+	m.lk.Unlock()
+	if exists {
+		return Piece{}, errors.Errorf("piece %s is already replicated", root)
+	}
+
+	head, height, err := m.head()
+	if err != nil {
+		return Piece{}, err
+	}
+	p := Piece{
+		Root:     root,
+		Policy:   policy,
+		End:      height + policy.Duration,
+		Replicas: []Replica{},
+	}
+	m.repair(ctx, &p, head, height)
+	if err := m.store(p); err != nil {
+		return Piece{}, err
+	}
+	return p, nil
+}
+
+// Stop stops replicating a piece. Its deals are left as they are.
+func (m *Manager) Stop(root cid.Cid) error {
+	m.checkLk.Lock()
+	defer m.checkLk.Unlock()
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if _, ok := m.pieces[root]; !ok {
+		return errors.Errorf("piece %s is not replicated", root)
+	}
+	if err := m.ds.Delete(datastore.NewKey(root.String())); err != nil {
+		return err
+	}
+	delete(m.pieces, root)
+	return nil
+}
+
+// Status returns the replication of a piece as of the last check.
+func (m *Manager) Status(root cid.Cid) (Piece, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	p, ok := m.pieces[root]
+	if !ok {
+		return Piece{}, errors.Errorf("piece %s is not replicated", root)
+	}
+	return p.copy(), nil
+}
+
+// Pieces returns the replicated pieces, by root.
+func (m *Manager) Pieces() []Piece {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	pieces := make([]Piece, 0, len(m.pieces))
+	for _, p := range m.pieces {
+		pieces = append(pieces, p.copy())
+	}
+	sort.Slice(pieces, func(i, j int) bool { return pieces[i].Root.String() < pieces[j].Root.String() })
+	return pieces
+}
+
+// HandleNewHead checks the replicas of the pieces in the background when
+// the check interval has passed since the last check. It does not block.
+func (m *Manager) HandleNewHead(ctx context.Context, head block.TipSet) error {
+	height, err := head.Height()
+	if err != nil {
+		return err
+	}
+
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if m.checking || len(m.pieces) == 0 || height < m.lastCheck+m.checkInterval {
+		return nil
+	}
+	m.checking = true
+	m.lastCheck = height
+	go func() {
+		m.check(ctx, head.Key(), height)
+		m.lk.Lock()
+		m.checking = false
+		m.lk.Unlock()
+	}()
+	return nil
+}
+
+// check updates the state of the replicas of every piece and repairs the
+// pieces that lost replicas.
+func (m *Manager) check(ctx context.Context, head block.TipSetKey, height abi.ChainEpoch) {
+	m.checkLk.Lock()
+	defer m.checkLk.Unlock()
+
+	for _, p := range m.Pieces() {
+		if ctx.Err() != nil {
+			return
+		}
+		for i := range p.Replicas {
+			m.updateReplica(ctx, &p.Replicas[i], height)
+		}
+		if height < p.End {
+			m.repair(ctx, &p, head, height)
+		} else {
+			p.Message = "ended"
+		}
+		if err := m.store(p); err != nil {
+			log.Errorf("failed to store replication of %s: %s", p.Root, err)
+		}
+	}
+}
+
+// updateReplica updates the state of a replica from the state of its deal.
+func (m *Manager) updateReplica(ctx context.Context, r *Replica, height abi.ChainEpoch) {
+	if r.State == ReplicaFailed || r.State == ReplicaExpired {
+		return
+	}
+	if r.ProposalCid == nil {
+		return
+	}
+	deal, err := m.client.GetLocalDeal(ctx, *r.ProposalCid)
+	if err != nil {
+		log.Warnf("failed to get deal %s: %s", r.ProposalCid, err)
+		return
+	}
+	r.DealState = storagemarket.DealStates[deal.State]
+
+	switch deal.State {
+	case storagemarket.StorageDealActive, storagemarket.StorageDealCompleted:
+		r.State = ReplicaActive
+		if height >= r.EndEpoch {
+			r.State = ReplicaExpired
+			r.Message = "deal ended"
+		}
+	case storagemarket.StorageDealFailing, storagemarket.StorageDealError,
+		storagemarket.StorageDealProposalRejected, storagemarket.StorageDealProposalNotFound,
+		storagemarket.StorageDealNotFound:
+		r.State = ReplicaFailed
+		r.Message = deal.Message
+	default:
+		// A deal not active at its start epoch can no longer be activated.
+		if height >= r.StartEpoch {
+			r.State = ReplicaExpired
+			r.Message = "deal not active by its start epoch"
+		}
+	}
+}
+
+// repair proposes deals to miners not holding the piece yet until the piece
+// has as many healthy replicas as its replication factor. Miners that were
+// proposed a deal for the piece before are not proposed one again.
+func (m *Manager) repair(ctx context.Context, p *Piece, head block.TipSetKey, height abi.ChainEpoch) {
+	healthy := p.Healthy()
+	if healthy >= p.Policy.Factor {
+		p.Message = ""
+		return
+	}
+	start := height + m.startDelay
+	if start >= p.End {
+		p.Message = "too close to the end of the piece to make new deals"
+		return
+	}
+
+	clientAddr, err := m.clientAddr()
+	if err != nil {
+		p.Message = err.Error()
+		return
+	}
+	candidates, err := m.candidates(ctx, p, height)
+	if err != nil {
+		p.Message = err.Error()
+		return
+	}
+
+	data := &storagemarket.DataRef{TransferType: storagemarket.TTGraphsync, Root: p.Root}
+	for _, info := range candidates {
+		if healthy >= p.Policy.Factor {
+			break
+		}
+		r := Replica{Miner: info.Address, StartEpoch: start, EndEpoch: p.End, State: ReplicaProposed}
+		rt, err := m.sealProofType(ctx, info.Address, head)
+		if err == nil {
+			var res *storagemarket.ProposeStorageDealResult
+			res, err = m.client.ProposeStorageDeal(ctx, clientAddr, &info, data, start, p.End, p.Policy.Price, p.Policy.Collateral, rt)
+			if err == nil {
+				r.ProposalCid = &res.ProposalCid
+			}
+		}
+		if err != nil {
+			// The failed replica keeps the miner from being proposed a deal again.
+			log.Warnf("failed to propose a deal for %s to %s: %s", p.Root, info.Address, err)
+			r.State = ReplicaFailed
+			r.Message = err.Error()
+		} else {
+			log.Infof("proposed deal %s for %s to %s", *r.ProposalCid, p.Root, info.Address)
+			healthy++
+		}
+		p.Replicas = append(p.Replicas, r)
+	}
+
+	if healthy < p.Policy.Factor {
+		p.Message = "not enough miners accept the piece"
+	} else {
+		p.Message = ""
+	}
+}
+
+// candidates returns the miners that do not hold the piece and ask at most the
+// price of the piece, cheapest first.
+func (m *Manager) candidates(ctx context.Context, p *Piece, height abi.ChainEpoch) ([]storagemarket.StorageProviderInfo, error) {
+	tried := map[address.Address]bool{}
+	for _, r := range p.Replicas {
+		tried[r.Miner] = true
+	}
+
+	providers, err := m.client.ListProviders(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list storage miners")
+	}
+	type candidate struct {
+		info storagemarket.StorageProviderInfo
+		ask  *storagemarket.StorageAsk
+	}
+	var candidates []candidate
+	for info := range providers {
+		if tried[info.Address] {
+			continue
+		}
+		signed, err := m.client.GetAsk(ctx, info)
+		if err != nil {
+			log.Debugf("no ask from %s: %s", info.Address, err)
+			continue
+		}
+		ask := signed.Ask
+		if ask == nil || ask.Expiry < height || ask.Price.GreaterThan(p.Policy.Price) {
+			continue
+		}
+		candidates = append(candidates, candidate{info: info, ask: ask})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].ask.Price.Equals(candidates[j].ask.Price) {
+			return candidates[i].ask.Price.LessThan(candidates[j].ask.Price)
+		}
+		return bytes.Compare(candidates[i].info.Address.Bytes(), candidates[j].info.Address.Bytes()) < 0
+	})
+	infos := make([]storagemarket.StorageProviderInfo, len(candidates))
+	for i, c := range candidates {
+		infos[i] = c.info
+	}
+	return infos, nil
+}
+
+func (m *Manager) head() (block.TipSetKey, abi.ChainEpoch, error) {
+	key := m.chain.Head()
+	ts, err := m.chain.GetTipSet(key)
+	if err != nil {
+		return block.TipSetKey{}, 0, errors.Wrap(err, "failed to get chain head")
+	}
+	height, err := ts.Height()
+	if err != nil {
+		return block.TipSetKey{}, 0, err
+	}
+	return key, height, nil
+}
+
+// store persists a piece and makes it visible to the status queries.
+func (m *Manager) store(p Piece) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	raw, err := encoding.Encode(p)
+	if err != nil {
+		return err
+	}
+	if err := m.ds.Put(datastore.NewKey(p.Root.String()), raw); err != nil {
+		return errors.Wrapf(err, "failed to store replication of %s", p.Root)
+	}
+	stored := p.copy()
+	m.pieces[p.Root] = &stored
+	return nil
+}
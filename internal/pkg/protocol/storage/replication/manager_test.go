@@ -0,0 +1,206 @@
+package replication
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/constants"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+type fakeClient struct {
+	t         *testing.T
+	asks      map[address.Address]abi.TokenAmount
+	rejecting map[address.Address]bool
+	deals     map[cid.Cid]*storagemarket.ClientDeal
+	proposals int
+}
+
+func newFakeClient(t *testing.T) *fakeClient {
+	return &fakeClient{
+		t:         t,
+		asks:      map[address.Address]abi.TokenAmount{},
+		rejecting: map[address.Address]bool{},
+		deals:     map[cid.Cid]*storagemarket.ClientDeal{},
+	}
+}
+
+func (f *fakeClient) ListProviders(context.Context) (<-chan storagemarket.StorageProviderInfo, error) {
+	out := make(chan storagemarket.StorageProviderInfo, len(f.asks))
+	for miner := range f.asks {
+		out <- storagemarket.StorageProviderInfo{Address: miner}
+	}
+	close(out)
+	return out, nil
+}
+
+func (f *fakeClient) GetAsk(_ context.Context, info storagemarket.StorageProviderInfo) (*storagemarket.SignedStorageAsk, error) {
+	return &storagemarket.SignedStorageAsk{Ask: &storagemarket.StorageAsk{Miner: info.Address, Price: f.asks[info.Address], Expiry: 1000000}}, nil
+}
+
+func (f *fakeClient) ProposeStorageDeal(_ context.Context, _ address.Address, info *storagemarket.StorageProviderInfo, _ *storagemarket.DataRef, start, end abi.ChainEpoch, _, _ abi.TokenAmount, _ abi.RegisteredProof) (*storagemarket.ProposeStorageDealResult, error) {
+	if f.rejecting[info.Address] {
+		return nil, fmt.Errorf("miner %s is offline", info.Address)
+	}
+	f.proposals++
+	proposal := types.CidFromString(f.t, fmt.Sprintf("proposal%d", f.proposals))
+	deal := &storagemarket.ClientDeal{ProposalCid: proposal, State: storagemarket.StorageDealValidating}
+	deal.Proposal.Provider = info.Address
+	deal.Proposal.StartEpoch = start
+	deal.Proposal.EndEpoch = end
+	f.deals[proposal] = deal
+	return &storagemarket.ProposeStorageDealResult{ProposalCid: proposal}, nil
+}
+
+func (f *fakeClient) GetLocalDeal(_ context.Context, proposal cid.Cid) (storagemarket.ClientDeal, error) {
+	deal, ok := f.deals[proposal]
+	if !ok {
+		return storagemarket.ClientDeal{}, fmt.Errorf("no deal %s", proposal)
+	}
+	return *deal, nil
+}
+
+func (f *fakeClient) setDealState(miner address.Address, state storagemarket.StorageDealStatus) {
+	for _, deal := range f.deals {
+		if deal.Proposal.Provider == miner {
+			deal.State = state
+		}
+	}
+}
+
+type fakeChain struct {
+	t      *testing.T
+	height abi.ChainEpoch
+}
+
+func (f *fakeChain) tipSet() block.TipSet {
+	ts, err := block.NewTipSet(&block.Block{Height: f.height})
+	require.NoError(f.t, err)
+	return ts
+}
+
+func (f *fakeChain) Head() block.TipSetKey {
+	return f.tipSet().Key()
+}
+
+func (f *fakeChain) GetTipSet(block.TipSetKey) (block.TipSet, error) {
+	return f.tipSet(), nil
+}
+
+func newTestManager(t *testing.T, client *fakeClient, chain *fakeChain, ds datastore.Batching) *Manager {
+	sealProofType := func(context.Context, address.Address, block.TipSetKey) (abi.RegisteredProof, error) {
+		return constants.DevSealProofType, nil
+	}
+	clientAddr := func() (address.Address, error) { return minerAddr(t, 1), nil }
+	m, err := NewManager(client, chain, sealProofType, clientAddr, ds, 10, 100)
+	require.NoError(t, err)
+	return m
+}
+
+func minerAddr(t *testing.T, id uint64) address.Address {
+	addr, err := address.NewIDAddress(id)
+	require.NoError(t, err)
+	return addr
+}
+
+func TestReplicateProposesToCheapestMiners(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	client := newFakeClient(t)
+	client.asks[minerAddr(t, 100)] = abi.NewTokenAmount(3)
+	client.asks[minerAddr(t, 101)] = abi.NewTokenAmount(1)
+	client.asks[minerAddr(t, 102)] = abi.NewTokenAmount(2)
+	client.asks[minerAddr(t, 103)] = abi.NewTokenAmount(50)
+	chain := &fakeChain{t: t, height: 10}
+	m := newTestManager(t, client, chain, dssync.MutexWrap(datastore.NewMapDatastore()))
+
+	root := types.CidFromString(t, "piece")
+	policy := Policy{Factor: 2, Duration: 1000, Price: abi.NewTokenAmount(10), Collateral: abi.NewTokenAmount(0)}
+	p, err := m.Replicate(ctx, root, policy)
+	require.NoError(t, err)
+
+	require.Len(t, p.Replicas, 2)
+	assert.Equal(t, minerAddr(t, 101), p.Replicas[0].Miner)
+	assert.Equal(t, minerAddr(t, 102), p.Replicas[1].Miner)
+	assert.Equal(t, abi.ChainEpoch(110), p.Replicas[0].StartEpoch)
+	assert.Equal(t, abi.ChainEpoch(1010), p.Replicas[0].EndEpoch)
+	assert.Equal(t, uint64(2), p.Healthy())
+	assert.Empty(t, p.Message)
+
+	_, err = m.Replicate(ctx, root, policy)
+	assert.Error(t, err)
+}
+
+func TestCheckRepairsLostReplicas(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	client := newFakeClient(t)
+	for i := uint64(100); i < 104; i++ {
+		client.asks[minerAddr(t, i)] = abi.NewTokenAmount(int64(i))
+	}
+	chain := &fakeChain{t: t, height: 10}
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	m := newTestManager(t, client, chain, ds)
+
+	root := types.CidFromString(t, "piece")
+	_, err := m.Replicate(ctx, root, Policy{Factor: 2, Duration: 1000, Price: abi.NewTokenAmount(1000), Collateral: abi.NewTokenAmount(0)})
+	require.NoError(t, err)
+
+	t.Log("one deal becomes active and the other fails")
+	client.setDealState(minerAddr(t, 100), storagemarket.StorageDealActive)
+	client.setDealState(minerAddr(t, 101), storagemarket.StorageDealFailing)
+	chain.height = 20
+	m.check(ctx, chain.Head(), chain.height)
+
+	p, err := m.Status(root)
+	require.NoError(t, err)
+	require.Len(t, p.Replicas, 3)
+	assert.Equal(t, ReplicaActive, p.Replicas[0].State)
+	assert.Equal(t, ReplicaFailed, p.Replicas[1].State)
+	assert.Equal(t, minerAddr(t, 102), p.Replicas[2].Miner)
+	assert.Equal(t, ReplicaProposed, p.Replicas[2].State)
+	assert.Equal(t, abi.ChainEpoch(120), p.Replicas[2].StartEpoch)
+
+	t.Log("the repair deal is not active by its start epoch and the last miner is offline")
+	client.rejecting[minerAddr(t, 103)] = true
+	chain.height = 120
+	m.check(ctx, chain.Head(), chain.height)
+
+	p, err = m.Status(root)
+	require.NoError(t, err)
+	require.Len(t, p.Replicas, 4)
+	assert.Equal(t, ReplicaExpired, p.Replicas[2].State)
+	assert.Equal(t, ReplicaFailed, p.Replicas[3].State)
+	assert.Equal(t, uint64(1), p.Healthy())
+	assert.Equal(t, "not enough miners accept the piece", p.Message)
+
+	t.Log("the replication is loaded again by a new manager")
+	reloaded := newTestManager(t, client, chain, ds)
+	p2, err := reloaded.Status(root)
+	require.NoError(t, err)
+	assert.Equal(t, p, p2)
+
+	t.Log("no deal is made once the piece ends")
+	chain.height = 1010
+	client.setDealState(minerAddr(t, 100), storagemarket.StorageDealActive)
+	reloaded.check(ctx, chain.Head(), chain.height)
+	p, err = reloaded.Status(root)
+	require.NoError(t, err)
+	assert.Equal(t, ReplicaExpired, p.Replicas[0].State)
+	assert.Len(t, p.Replicas, 4)
+	assert.Equal(t, "ended", p.Message)
+}
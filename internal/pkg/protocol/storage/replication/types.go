@@ -0,0 +1,77 @@
+package replication
+
+import (
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
+)
+
+// State of a replica.
+const (
+	// ReplicaProposed is a replica whose deal is in progress.
+	ReplicaProposed = "proposed"
+	// ReplicaActive is a replica whose deal is active.
+	ReplicaActive = "active"
+	// ReplicaFailed is a replica whose deal could not be made or failed.
+	ReplicaFailed = "failed"
+	// ReplicaExpired is a replica whose deal ended, or was not active by its start epoch.
+	ReplicaExpired = "expired"
+)
+
+// Policy is how a piece is replicated.
+type Policy struct {
+	_ struct{} `cbor:",toarray"`
+	// Factor is the number of miners to store the piece with.
+	Factor uint64
+	// Duration is the number of epochs to store the piece for.
+	Duration abi.ChainEpoch
+	// Price is the price per epoch of each deal, miners asking more are not
+	// proposed deals.
+	Price abi.TokenAmount
+	// Collateral is the collateral of each deal.
+	Collateral abi.TokenAmount
+}
+
+// Replica is a deal storing a piece with a miner.
+type Replica struct {
+	_     struct{} `cbor:",toarray"`
+	Miner address.Address
+	// ProposalCid identifies the deal, it is nil if the deal could not be proposed.
+	ProposalCid *cid.Cid
+	StartEpoch  abi.ChainEpoch
+	EndEpoch    abi.ChainEpoch
+	State       string
+	// DealState is the state of the deal at the last check.
+	DealState string
+	Message   string
+}
+
+// Piece is a replicated piece of data and its replicas.
+type Piece struct {
+	_      struct{} `cbor:",toarray"`
+	Root   cid.Cid
+	Policy Policy
+	// End is the epoch until which the piece is replicated.
+	End      abi.ChainEpoch
+	Replicas []Replica
+	// Message explains why the piece has fewer replicas than its replication
+	// factor, if it does.
+	Message string
+}
+
+// Healthy returns the number of replicas of the piece in progress or active.
+func (p *Piece) Healthy() uint64 {
+	healthy := uint64(0)
+	for _, r := range p.Replicas {
+		if r.State == ReplicaProposed || r.State == ReplicaActive {
+			healthy++
+		}
+	}
+	return healthy
+}
+
+func (p *Piece) copy() Piece {
+	c := *p
+	c.Replicas = append([]Replica{}, p.Replicas...)
+	return c
+}
@@ -0,0 +1,303 @@
+// Package transfer tracks the graphsync data transfers moving deal payloads
+// between clients and miners, and restarts the transfers that fail.
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	graphsyncimpl "github.com/filecoin-project/go-data-transfer/impl/graphsync"
+	smvalid "github.com/filecoin-project/go-fil-markets/storagemarket/impl/requestvalidation"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-graphsync"
+	logging "github.com/ipfs/go-log/v2"
+	ipld "github.com/ipld/go-ipld-prime"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
+)
+
+var log = logging.Logger("transfer")
+
+// Status of a transfer.
+const (
+	StatusOngoing   = "ongoing"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Transfer is the state of a data transfer as seen by this node.
+type Transfer struct {
+	// ID identifies the transfer on this node, across its restarts.
+	ID        uint64
+	BaseCid   cid.Cid
+	Sender    peer.ID
+	Recipient peer.ID
+	// Deal is the proposal of the storage deal the payload is for, if any.
+	Deal cid.Cid
+	// BytesSent counts the bytes sent by this node, it is zero on the recipient.
+	BytesSent uint64
+	Status    string
+	Message   string
+	// Restarts counts the times the transfer failed and was started again.
+	Restarts int
+	Updated  time.Time
+}
+
+// channelKey identifies a data transfer channel. Channel states do not carry
+// the initiator of the channel, so channels are identified by their peers.
+type channelKey struct {
+	id        datatransfer.TransferID
+	sender    peer.ID
+	recipient peer.ID
+}
+
+func keyOf(chst datatransfer.ChannelState) channelKey {
+	return channelKey{id: chst.TransferID(), sender: chst.Sender(), recipient: chst.Recipient()}
+}
+
+// pull holds what is needed to open a pull channel again.
+type pull struct {
+	from     peer.ID
+	voucher  datatransfer.Voucher
+	baseCid  cid.Cid
+	selector ipld.Node
+}
+
+// Manager is a datatransfer.Manager tracking the transfers of another. When a
+// transfer this node pulls fails, the manager opens it again, up to a number
+// of restarts, before reporting the failure to its subscribers. A restarted
+// transfer starts over from its root.
+type Manager struct {
+	datatransfer.Manager
+
+	self        peer.ID
+	maxRestarts int
+
+	lk          sync.Mutex
+	nextID      uint64
+	transfers   map[uint64]*Transfer
+	channels    map[channelKey]uint64
+	pulls       map[uint64]pull
+	subscribers map[int]datatransfer.Subscriber
+	watchers    map[int]func(Transfer)
+	nextSub     int
+}
+
+// NewManager returns a manager wrapping `dt`, the data transfer manager of the
+// node `self`, and counting the bytes sent over graphsync by `gs`.
+func NewManager(self peer.ID, dt datatransfer.Manager, gs graphsync.GraphExchange, maxRestarts int) *Manager {
+	m := &Manager{
+		Manager:     dt,
+		self:        self,
+		maxRestarts: maxRestarts,
+		transfers:   map[uint64]*Transfer{},
+		channels:    map[channelKey]uint64{},
+		pulls:       map[uint64]pull{},
+		subscribers: map[int]datatransfer.Subscriber{},
+		watchers:    map[int]func(Transfer){},
+	}
+	dt.SubscribeToEvents(m.onEvent)
+	if gs != nil {
+		gs.RegisterOutgoingBlockHook(m.onOutgoingBlock)
+	}
+	return m
+}
+
+// OpenPullDataChannel opens a pull channel, which is restarted if it fails.
+func (m *Manager) OpenPullDataChannel(ctx context.Context, to peer.ID, voucher datatransfer.Voucher, baseCid cid.Cid, selector ipld.Node) (datatransfer.ChannelID, error) {
+	chid, err := m.Manager.OpenPullDataChannel(ctx, to, voucher, baseCid, selector)
+	if err != nil {
+		return chid, err
+	}
+
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	t := m.trackLocked(channelKey{id: chid.ID, sender: to, recipient: m.self}, baseCid, voucher)
+	m.pulls[t.ID] = pull{from: to, voucher: voucher, baseCid: baseCid, selector: selector}
+	return chid, nil
+}
+
+// SubscribeToEvents subscribes to the events of the transfers. Failures of
+// transfers that are restarted are not reported.
+func (m *Manager) SubscribeToEvents(subscriber datatransfer.Subscriber) datatransfer.Unsubscribe {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	id := m.nextSub
+	m.nextSub++
+	m.subscribers[id] = subscriber
+	return func() {
+		m.lk.Lock()
+		defer m.lk.Unlock()
+		delete(m.subscribers, id)
+	}
+}
+
+// Watch calls `watcher` with the state of a transfer each time it changes,
+// including when bytes are sent, until unsubscribed. The watcher is called
+// while the manager is locked and must not block.
+func (m *Manager) Watch(watcher func(Transfer)) datatransfer.Unsubscribe {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	id := m.nextSub
+	m.nextSub++
+	m.watchers[id] = watcher
+	return func() {
+		m.lk.Lock()
+		defer m.lk.Unlock()
+		delete(m.watchers, id)
+	}
+}
+
+// Transfers returns the transfers seen by this node, by ID.
+func (m *Manager) Transfers() []Transfer {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	transfers := make([]Transfer, 0, len(m.transfers))
+	for _, t := range m.transfers {
+		transfers = append(transfers, *t)
+	}
+	sort.Slice(transfers, func(i, j int) bool { return transfers[i].ID < transfers[j].ID })
+	return transfers
+}
+
+// Transfer returns the transfer with the ID.
+func (m *Manager) Transfer(id uint64) (Transfer, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	t, ok := m.transfers[id]
+	if !ok {
+		return Transfer{}, errors.Errorf("no transfer %d", id)
+	}
+	return *t, nil
+}
+
+func (m *Manager) onEvent(event datatransfer.Event, chst datatransfer.ChannelState) {
+	m.lk.Lock()
+	t := m.trackLocked(keyOf(chst), chst.BaseCID(), chst.Voucher())
+	t.Updated = event.Timestamp
+	t.Message = event.Message
+
+	forward := true
+	switch event.Code {
+	case datatransfer.Open:
+		t.Status = StatusOngoing
+	case datatransfer.Complete:
+		t.Status = StatusCompleted
+	case datatransfer.Error:
+		p, isPull := m.pulls[t.ID]
+		if isPull && t.Restarts < m.maxRestarts {
+			t.Restarts++
+			t.Status = StatusOngoing
+			forward = false
+			go m.restart(t.ID, p, event, chst)
+		} else {
+			t.Status = StatusFailed
+		}
+	}
+	m.notifyLocked(t)
+	subscribers := m.subscribersLocked()
+	m.lk.Unlock()
+
+	if forward {
+		for _, s := range subscribers {
+			s(event, chst)
+		}
+	}
+}
+
+// restart opens again a pull channel that failed, reporting the failure if
+// the channel cannot be opened.
+func (m *Manager) restart(id uint64, p pull, failure datatransfer.Event, chst datatransfer.ChannelState) {
+	log.Infof("restarting transfer %d of %s from %s after: %s", id, p.baseCid, p.from, failure.Message)
+	chid, err := m.Manager.OpenPullDataChannel(context.Background(), p.from, p.voucher, p.baseCid, p.selector)
+
+	m.lk.Lock()
+	t := m.transfers[id]
+	if err == nil {
+		// The open event of the new channel may have been tracked as a new transfer
+		key := channelKey{id: chid.ID, sender: p.from, recipient: m.self}
+		if stray, ok := m.channels[key]; ok && stray != id {
+			delete(m.transfers, stray)
+		}
+		m.channels[key] = id
+		m.lk.Unlock()
+		return
+	}
+
+	log.Warnf("failed to restart transfer %d: %s", id, err)
+	t.Status = StatusFailed
+	t.Message = err.Error()
+	m.notifyLocked(t)
+	subscribers := m.subscribersLocked()
+	m.lk.Unlock()
+	for _, s := range subscribers {
+		s(failure, chst)
+	}
+}
+
+// onOutgoingBlock counts the bytes of blocks sent by graphsync for data transfers.
+func (m *Manager) onOutgoingBlock(p peer.ID, request graphsync.RequestData, block graphsync.BlockData, _ graphsync.OutgoingBlockHookActions) {
+	raw, ok := request.Extension(graphsyncimpl.ExtensionDataTransfer)
+	if !ok {
+		return
+	}
+	var ext graphsyncimpl.ExtensionDataTransferData
+	if err := ext.UnmarshalCBOR(bytes.NewReader(raw)); err != nil {
+		return
+	}
+	// blocks are sent by this node to the peer requesting them
+	m.recordSent(channelKey{id: datatransfer.TransferID(ext.TransferID), sender: m.self, recipient: p}, block.BlockSizeOnWire())
+}
+
+func (m *Manager) recordSent(key channelKey, n uint64) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	id, ok := m.channels[key]
+	if !ok {
+		return
+	}
+	t := m.transfers[id]
+	t.BytesSent += n
+	t.Updated = time.Now()
+	m.notifyLocked(t)
+}
+
+// trackLocked returns the transfer of a channel, tracking it if it is new.
+func (m *Manager) trackLocked(key channelKey, baseCid cid.Cid, voucher datatransfer.Voucher) *Transfer {
+	if id, ok := m.channels[key]; ok {
+		return m.transfers[id]
+	}
+	m.nextID++
+	t := &Transfer{
+		ID:        m.nextID,
+		BaseCid:   baseCid,
+		Sender:    key.sender,
+		Recipient: key.recipient,
+		Status:    StatusOngoing,
+		Updated:   time.Now(),
+	}
+	if v, ok := voucher.(*smvalid.StorageDataTransferVoucher); ok {
+		t.Deal = v.Proposal
+	}
+	m.transfers[t.ID] = t
+	m.channels[key] = t.ID
+	return t
+}
+
+func (m *Manager) notifyLocked(t *Transfer) {
+	for _, w := range m.watchers {
+		w(*t)
+	}
+}
+
+func (m *Manager) subscribersLocked() []datatransfer.Subscriber {
+	subscribers := make([]datatransfer.Subscriber, 0, len(m.subscribers))
+	for _, s := range m.subscribers {
+		subscribers = append(subscribers, s)
+	}
+	return subscribers
+}
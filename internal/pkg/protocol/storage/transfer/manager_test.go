@@ -0,0 +1,133 @@
+package transfer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	datatransfer "github.com/filecoin-project/go-data-transfer"
+	"github.com/filecoin-project/go-fil-markets/shared"
+	smvalid "github.com/filecoin-project/go-fil-markets/storagemarket/impl/requestvalidation"
+	"github.com/ipfs/go-cid"
+	ipld "github.com/ipld/go-ipld-prime"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+)
+
+// fakeDataTransfer publishes an open event when a channel is opened, as the
+// graphsync implementation does, and lets tests publish the other events.
+type fakeDataTransfer struct {
+	datatransfer.Manager
+	self        peer.ID
+	next        datatransfer.TransferID
+	subscribers []datatransfer.Subscriber
+	opened      chan datatransfer.ChannelState
+}
+
+func (f *fakeDataTransfer) SubscribeToEvents(s datatransfer.Subscriber) datatransfer.Unsubscribe {
+	f.subscribers = append(f.subscribers, s)
+	return func() {}
+}
+
+func (f *fakeDataTransfer) OpenPullDataChannel(_ context.Context, to peer.ID, voucher datatransfer.Voucher, baseCid cid.Cid, selector ipld.Node) (datatransfer.ChannelID, error) {
+	f.next++
+	chst := datatransfer.ChannelState{Channel: datatransfer.NewChannel(f.next, baseCid, selector, voucher, to, f.self, 0)}
+	f.publish(datatransfer.Open, chst)
+	f.opened <- chst
+	return datatransfer.ChannelID{Initiator: f.self, ID: f.next}, nil
+}
+
+func (f *fakeDataTransfer) publish(code datatransfer.EventCode, chst datatransfer.ChannelState) {
+	for _, s := range f.subscribers {
+		s(datatransfer.Event{Code: code, Timestamp: time.Now()}, chst)
+	}
+}
+
+func newTestManager(t *testing.T, maxRestarts int) (*Manager, *fakeDataTransfer, *[]datatransfer.EventCode) {
+	fake := &fakeDataTransfer{self: th.RequireIntPeerID(t, 1), opened: make(chan datatransfer.ChannelState, 10)}
+	m := NewManager(fake.self, fake, nil, maxRestarts)
+	var forwarded []datatransfer.EventCode
+	m.SubscribeToEvents(func(event datatransfer.Event, _ datatransfer.ChannelState) {
+		forwarded = append(forwarded, event.Code)
+	})
+	return m, fake, &forwarded
+}
+
+func TestManagerRestartsFailedPulls(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	client := th.RequireIntPeerID(t, 2)
+	root := types.CidFromString(t, "payload")
+	voucher := &smvalid.StorageDataTransferVoucher{Proposal: types.CidFromString(t, "proposal")}
+
+	t.Run("restarted transfer completes", func(t *testing.T) {
+		m, fake, forwarded := newTestManager(t, 1)
+		_, err := m.OpenPullDataChannel(ctx, client, voucher, root, shared.AllSelector())
+		require.NoError(t, err)
+		first := <-fake.opened
+
+		fake.publish(datatransfer.Error, first)
+		second := <-fake.opened
+		assert.NotEqual(t, first.TransferID(), second.TransferID())
+
+		fake.publish(datatransfer.Complete, second)
+		assert.Equal(t, []datatransfer.EventCode{datatransfer.Open, datatransfer.Open, datatransfer.Complete}, *forwarded)
+
+		transfers := m.Transfers()
+		require.Len(t, transfers, 1)
+		assert.Equal(t, StatusCompleted, transfers[0].Status)
+		assert.Equal(t, 1, transfers[0].Restarts)
+		assert.Equal(t, voucher.Proposal, transfers[0].Deal)
+		assert.Equal(t, client, transfers[0].Sender)
+	})
+
+	t.Run("failure is reported once restarts are exhausted", func(t *testing.T) {
+		m, fake, forwarded := newTestManager(t, 0)
+		_, err := m.OpenPullDataChannel(ctx, client, voucher, root, shared.AllSelector())
+		require.NoError(t, err)
+		first := <-fake.opened
+
+		fake.publish(datatransfer.Error, first)
+		assert.Equal(t, []datatransfer.EventCode{datatransfer.Open, datatransfer.Error}, *forwarded)
+
+		transfer, err := m.Transfer(1)
+		require.NoError(t, err)
+		assert.Equal(t, StatusFailed, transfer.Status)
+	})
+}
+
+func TestManagerCountsBytesSent(t *testing.T) {
+	tf.UnitTest(t)
+
+	m, fake, _ := newTestManager(t, 1)
+	provider := th.RequireIntPeerID(t, 3)
+	var updates []Transfer
+	m.Watch(func(transfer Transfer) { updates = append(updates, transfer) })
+
+	t.Log("a provider pulls the payload from this node")
+	chst := datatransfer.ChannelState{Channel: datatransfer.NewChannel(7, types.CidFromString(t, "payload"), shared.AllSelector(), nil, fake.self, provider, 0)}
+	fake.publish(datatransfer.Open, chst)
+
+	m.recordSent(channelKey{id: 7, sender: fake.self, recipient: provider}, 100)
+	m.recordSent(channelKey{id: 7, sender: fake.self, recipient: provider}, 50)
+	m.recordSent(channelKey{id: 8, sender: fake.self, recipient: provider}, 1000)
+
+	transfers := m.Transfers()
+	require.Len(t, transfers, 1)
+	assert.Equal(t, uint64(150), transfers[0].BytesSent)
+	require.Len(t, updates, 3)
+	assert.Equal(t, uint64(100), updates[1].BytesSent)
+
+	t.Log("failures of transfers this node does not pull are not restarted")
+	fake.publish(datatransfer.Error, chst)
+	transfer, err := m.Transfer(transfers[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, transfer.Status)
+	assert.Zero(t, transfer.Restarts)
+}
@@ -0,0 +1,345 @@
+package scrubber
+
+import (
+	"context"
+	"crypto/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/journal"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
+)
+
+var log = logging.Logger("scrubber")
+
+// StateView is the view of the miner state needed to scrub its sectors.
+type StateView interface {
+	MinerSectorStates(ctx context.Context, maddr address.Address) (*state.MinerSectorStates, error)
+	MinerGetSector(ctx context.Context, maddr address.Address, sectorNum abi.SectorNumber) (*miner.SectorOnChainInfo, bool, error)
+	MinerControlAddresses(ctx context.Context, maddr address.Address) (owner, worker address.Address, err error)
+	MinerDeadlineInfo(ctx context.Context, maddr address.Address, epoch abi.ChainEpoch) (index uint64, open, close, challenge abi.ChainEpoch, _ error)
+}
+
+// ChainState provides views of the state at the head of the chain.
+type ChainState interface {
+	Head() block.TipSetKey
+	GetTipSet(key block.TipSetKey) (block.TipSet, error)
+	ScrubberStateView(key block.TipSetKey) (StateView, error)
+}
+
+// Prover generates window PoSts over the sealed sectors of a miner.
+type Prover interface {
+	GenerateWindowPoSt(ctx context.Context, minerID abi.ActorID, sectorInfo []abi.SectorInfo, randomness abi.PoStRandomness) ([]abi.PoStProof, error)
+}
+
+// Verifier verifies window PoSts against the commitments of the sectors.
+type Verifier interface {
+	VerifyWindowPoSt(ctx context.Context, info abi.WindowPoStVerifyInfo) (bool, error)
+}
+
+// Sender sends messages declaring faults.
+type Sender interface {
+	Send(ctx context.Context, from, to address.Address, value types.AttoFIL, gasPrice types.AttoFIL, gasLimit gas.Unit,
+		bcast bool, method abi.MethodNum, params interface{}) (cid.Cid, chan error, error)
+}
+
+// SectorFault is a sector which failed to be proven during a scrub. Declared
+// is false for the faults of deadlines past their fault declaration cutoff,
+// which are left for a later scrub to declare.
+type SectorFault struct {
+	Sector   abi.SectorNumber
+	Deadline uint64
+	Error    string
+	Declared bool
+}
+
+// Report is the outcome of a scrub of the sectors of a miner.
+type Report struct {
+	Start time.Time
+	End   time.Time
+	// Checked is the number of sectors proven, Skipped the number of sectors
+	// already declared faulty, which are not checked.
+	Checked int
+	Skipped int
+	Faults  []SectorFault
+	// Declaration is the message declaring the faults still declarable,
+	// cid.Undef when none was sent, and DeclarationError the reason it could
+	// not be sent.
+	Declaration      cid.Cid
+	DeclarationError string `json:",omitempty"`
+}
+
+// Scrubber checks the integrity of the sealed sectors of a miner ahead of its
+// PoSt deadlines. Each sector is checked by generating a window PoSt for it on
+// fresh randomness and verifying it against the sealed CID on chain. This is a
+// sampled check, like the PoSt itself it only reads the challenged parts of the
+// sector, so it detects missing sector files but only the corruptions that the
+// challenges happen to hit. Sectors failing a scrub are declared faulty, when
+// their deadline is still before its fault declaration cutoff, so the miner
+// is not penalized for missing the PoSt of their deadline.
+type Scrubber struct {
+	minerAddr address.Address
+	chain     ChainState
+	prover    Prover
+	verifier  Verifier
+	outbox    Sender
+	journal   journal.Writer
+
+	// scrubLk serializes scrubs.
+	scrubLk sync.Mutex
+	lk      sync.Mutex
+	last    *Report
+}
+
+// NewScrubber creates a scrubber of the sectors of the miner with ID address
+// `minerAddr`.
+func NewScrubber(minerAddr address.Address, chain ChainState, prover Prover, verifier Verifier, outbox Sender, jw journal.Writer) *Scrubber {
+	return &Scrubber{
+		minerAddr: minerAddr,
+		chain:     chain,
+		prover:    prover,
+		verifier:  verifier,
+		outbox:    outbox,
+		journal:   jw,
+	}
+}
+
+// Run scrubs every interval until ctx is done.
+func (s *Scrubber) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Scrub(ctx); err != nil && ctx.Err() == nil {
+				log.Errorf("failed to scrub sectors: %s", err)
+			}
+		}
+	}
+}
+
+// LastReport returns the report of the last complete scrub, nil if no scrub
+// completed yet.
+func (s *Scrubber) LastReport() *Report {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	return s.last
+}
+
+// Scrub checks the sectors in the deadlines of the miner at the head, except
+// those already declared faulty, and declares faulty those failing the check
+// whose deadline can still be declared.
+func (s *Scrubber) Scrub(ctx context.Context) (*Report, error) {
+	s.scrubLk.Lock()
+	defer s.scrubLk.Unlock()
+
+	report := &Report{Start: time.Now(), Declaration: cid.Undef}
+	minerID, err := address.IDFromAddress(s.minerAddr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid miner address %s", s.minerAddr)
+	}
+	head, err := s.chain.GetTipSet(s.chain.Head())
+	if err != nil {
+		return nil, err
+	}
+	epoch, err := head.Height()
+	if err != nil {
+		return nil, err
+	}
+	view, err := s.chain.ScrubberStateView(head.Key())
+	if err != nil {
+		return nil, err
+	}
+	sectorStates, err := view.MinerSectorStates(ctx, s.minerAddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load sector states")
+	}
+	declared, err := bitfield.SubtractBitField(sectorStates.Faults, sectorStates.Recoveries)
+	if err != nil {
+		return nil, err
+	}
+	declaredFaults, err := sectorSet(declared)
+	if err != nil {
+		return nil, err
+	}
+
+	for deadline, due := range sectorStates.Deadlines {
+		if due == nil {
+			continue
+		}
+		sectorNums, err := due.All(miner.SectorsMax)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read sectors of deadline %d", deadline)
+		}
+		for _, num := range sectorNums {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if declaredFaults[num] {
+				report.Skipped++
+				continue
+			}
+			info, found, err := view.MinerGetSector(ctx, s.minerAddr, abi.SectorNumber(num))
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to load sector %d", num)
+			}
+			if !found {
+				continue
+			}
+
+			report.Checked++
+			if err := s.checkSector(ctx, abi.ActorID(minerID), info.AsSectorInfo()); err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				fault := SectorFault{Sector: abi.SectorNumber(num), Deadline: uint64(deadline), Error: err.Error()}
+				log.Warnf("sector %d of deadline %d failed scrub: %s", num, deadline, err)
+				s.journal.Write("SectorFault", "sector", num, "deadline", deadline, "error", fault.Error)
+				report.Faults = append(report.Faults, fault)
+			}
+		}
+	}
+
+	if len(report.Faults) > 0 {
+		report.Declaration, err = s.declareFaults(ctx, view, epoch, report.Faults)
+		if err != nil {
+			log.Errorf("failed to declare %d faulty sectors: %s", len(report.Faults), err)
+			report.DeclarationError = err.Error()
+		}
+	}
+	report.End = time.Now()
+	s.journal.Write("Scrub", "checked", report.Checked, "skipped", report.Skipped, "faults", len(report.Faults),
+		"declaration", report.Declaration.String(), "duration", report.End.Sub(report.Start).String())
+
+	s.lk.Lock()
+	s.last = report
+	s.lk.Unlock()
+	return report, nil
+}
+
+// checkSector proves a single sector on random challenges.
+func (s *Scrubber) checkSector(ctx context.Context, minerID abi.ActorID, sector abi.SectorInfo) error {
+	randomness := make([]byte, 32)
+	if _, err := rand.Read(randomness); err != nil {
+		return err
+	}
+	// Randomness must be a valid field element.
+	randomness[31] &= 0x3f
+
+	sectors := []abi.SectorInfo{sector}
+	proofs, err := s.prover.GenerateWindowPoSt(ctx, minerID, sectors, randomness)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate proof")
+	}
+	ok, err := s.verifier.VerifyWindowPoSt(ctx, abi.WindowPoStVerifyInfo{
+		Randomness:        randomness,
+		Proofs:            proofs,
+		ChallengedSectors: sectors,
+		Prover:            minerID,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to verify proof")
+	}
+	if !ok {
+		return errors.Errorf("proof does not verify against sealed CID %s", sector.SealedCID)
+	}
+	return nil
+}
+
+// declareFaults sends a message from the worker of the miner declaring the
+// `faults` of the deadlines still before their fault declaration cutoff at
+// `epoch`, marking them declared, and returns its CID, cid.Undef if no
+// deadline can be declared. Faults in deadlines that already closed in the
+// current proving period are declared for their next occurrence, in the next
+// period. The chain rejects declarations for deadlines past their cutoff,
+// which includes the current deadline.
+func (s *Scrubber) declareFaults(ctx context.Context, view StateView, epoch abi.ChainEpoch, faults []SectorFault) (cid.Cid, error) {
+	current, open, _, _, err := view.MinerDeadlineInfo(ctx, s.minerAddr, epoch)
+	if err != nil {
+		return cid.Undef, errors.Wrap(err, "failed to get deadline info")
+	}
+
+	byDeadline := make(map[uint64][]uint64)
+	var declarable []int
+	for i, f := range faults {
+		ahead := int64(f.Deadline) - int64(current)
+		if ahead < 0 {
+			ahead += int64(miner.WPoStPeriodDeadlines)
+		}
+		deadlineOpen := open + abi.ChainEpoch(ahead)*miner.WPoStChallengeWindow
+		if epoch >= deadlineOpen-miner.FaultDeclarationCutoff {
+			continue
+		}
+		byDeadline[f.Deadline] = append(byDeadline[f.Deadline], uint64(f.Sector))
+		declarable = append(declarable, i)
+	}
+	if len(declarable) < len(faults) {
+		log.Warnf("%d faulty sectors are in deadlines past their fault declaration cutoff and are not declared", len(faults)-len(declarable))
+	}
+	if len(declarable) == 0 {
+		return cid.Undef, nil
+	}
+
+	params := &miner.DeclareFaultsParams{}
+	for deadline, sectors := range byDeadline {
+		params.Faults = append(params.Faults, miner.FaultDeclaration{
+			Deadline: deadline,
+			Sectors:  bitfield.NewFromSet(sectors),
+		})
+	}
+	sort.Slice(params.Faults, func(i, j int) bool { return params.Faults[i].Deadline < params.Faults[j].Deadline })
+
+	_, worker, err := view.MinerControlAddresses(ctx, s.minerAddr)
+	if err != nil {
+		return cid.Undef, errors.Wrap(err, "failed to get worker address")
+	}
+	mcid, errCh, err := s.outbox.Send(
+		ctx,
+		worker,
+		s.minerAddr,
+		types.ZeroAttoFIL,
+		types.NewGasPrice(1),
+		gas.NewGas(10000),
+		true,
+		builtin.MethodsMiner.DeclareFaults,
+		params,
+	)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if err := <-errCh; err != nil {
+		return cid.Undef, err
+	}
+	for _, i := range declarable {
+		faults[i].Declared = true
+	}
+	s.journal.Write("DeclareFaults", "sectors", len(declarable), "cid", mcid.String())
+	return mcid, nil
+}
+
+func sectorSet(bf *abi.BitField) (map[uint64]bool, error) {
+	nums, err := bf.All(miner.SectorsMax)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[uint64]bool, len(nums))
+	for _, n := range nums {
+		set[n] = true
+	}
+	return set, nil
+}
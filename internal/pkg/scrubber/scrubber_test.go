@@ -0,0 +1,195 @@
+package scrubber_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/journal"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/scrubber"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
+)
+
+func TestScrub(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	minerAddr, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	worker, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+
+	newCid := types.NewCidForTestGetter()
+	sealed := map[abi.SectorNumber]cid.Cid{}
+	var sectors []miner.SectorOnChainInfo
+	for _, num := range []abi.SectorNumber{1, 2, 3, 4} {
+		sealed[num] = newCid()
+		sectors = append(sectors, miner.SectorOnChainInfo{Info: miner.SectorPreCommitInfo{SectorNumber: num, SealedCID: sealed[num]}})
+	}
+	view := state.NewFakeStateView(abi.NewStoragePower(0), abi.NewStoragePower(0), 1, 1)
+	view.Miners[minerAddr] = &state.FakeMinerState{
+		Worker:    worker,
+		Sectors:   sectors,
+		Deadlines: []*abi.BitField{bitfield.NewFromSet([]uint64{1, 2}), abi.NewBitField(), bitfield.NewFromSet([]uint64{3, 4})},
+	}
+
+	prover := &fakeProver{missing: map[abi.SectorNumber]bool{}}
+	verifier := &fakeVerifier{corrupted: map[cid.Cid]bool{}}
+	outbox := &fakeSender{sent: newCid()}
+	// The head is in the first deadline, past its fault declaration cutoff.
+	head := block.RequireNewTipSet(t, &block.Block{Height: 0})
+	chain := &fakeChain{head: head, view: view}
+	s := scrubber.NewScrubber(minerAddr, chain, prover, verifier, outbox, journal.NewNoopJournal().Topic("scrubber"))
+	assert.Nil(t, s.LastReport())
+
+	t.Log("healthy sectors are proven and not declared faulty")
+	report, err := s.Scrub(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Checked)
+	assert.Empty(t, report.Faults)
+	assert.False(t, report.Declaration.Defined())
+	assert.Nil(t, outbox.params)
+	assert.Equal(t, report, s.LastReport())
+
+	t.Log("missing and corrupted sectors are declared faulty by deadline, unless past its cutoff")
+	prover.missing[2] = true
+	verifier.corrupted[sealed[4]] = true
+	report, err = s.Scrub(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Checked)
+	require.Len(t, report.Faults, 2)
+	assert.Equal(t, abi.SectorNumber(2), report.Faults[0].Sector)
+	assert.Equal(t, uint64(0), report.Faults[0].Deadline)
+	assert.Contains(t, report.Faults[0].Error, "no such file")
+	assert.False(t, report.Faults[0].Declared)
+	assert.Equal(t, abi.SectorNumber(4), report.Faults[1].Sector)
+	assert.Equal(t, uint64(2), report.Faults[1].Deadline)
+	assert.Contains(t, report.Faults[1].Error, sealed[4].String())
+	assert.True(t, report.Faults[1].Declared)
+
+	assert.Equal(t, outbox.sent, report.Declaration)
+	assert.Equal(t, worker, outbox.from)
+	assert.Equal(t, builtin.MethodsMiner.DeclareFaults, outbox.method)
+	require.Len(t, outbox.params.Faults, 1)
+	assert.Equal(t, uint64(2), outbox.params.Faults[0].Deadline)
+	declared, err := outbox.params.Faults[0].Sectors.All(miner.SectorsMax)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{4}, declared)
+
+	t.Log("no declaration is sent when all faults are past their cutoff")
+	delete(verifier.corrupted, sealed[4])
+	outbox.params = nil
+	report, err = s.Scrub(ctx)
+	require.NoError(t, err)
+	require.Len(t, report.Faults, 1)
+	assert.False(t, report.Faults[0].Declared)
+	assert.False(t, report.Declaration.Defined())
+	assert.Empty(t, report.DeclarationError)
+	assert.Nil(t, outbox.params)
+
+	t.Log("failures to declare faults are reported")
+	verifier.corrupted[sealed[4]] = true
+	outbox.err = errors.New("nonce too low")
+	report, err = s.Scrub(ctx)
+	require.NoError(t, err)
+	require.Len(t, report.Faults, 2)
+	assert.False(t, report.Faults[1].Declared)
+	assert.False(t, report.Declaration.Defined())
+	assert.Equal(t, "nonce too low", report.DeclarationError)
+
+	t.Log("faults in deadlines closed this proving period are declared for the next one")
+	delete(verifier.corrupted, sealed[4])
+	outbox.err = nil
+	outbox.params = nil
+	chain.head = block.RequireNewTipSet(t, &block.Block{Height: miner.WPoStChallengeWindow})
+	report, err = s.Scrub(ctx)
+	require.NoError(t, err)
+	require.Len(t, report.Faults, 1)
+	assert.Equal(t, uint64(0), report.Faults[0].Deadline)
+	assert.True(t, report.Faults[0].Declared)
+	require.NotNil(t, outbox.params)
+	require.Len(t, outbox.params.Faults, 1)
+	assert.Equal(t, uint64(0), outbox.params.Faults[0].Deadline)
+	declared, err = outbox.params.Faults[0].Sectors.All(miner.SectorsMax)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{2}, declared)
+}
+
+type fakeChain struct {
+	head block.TipSet
+	view scrubber.StateView
+}
+
+func (c *fakeChain) Head() block.TipSetKey {
+	return c.head.Key()
+}
+
+func (c *fakeChain) GetTipSet(block.TipSetKey) (block.TipSet, error) {
+	return c.head, nil
+}
+
+func (c *fakeChain) ScrubberStateView(block.TipSetKey) (scrubber.StateView, error) {
+	return c.view, nil
+}
+
+type fakeProver struct {
+	missing map[abi.SectorNumber]bool
+}
+
+func (p *fakeProver) GenerateWindowPoSt(_ context.Context, _ abi.ActorID, sectors []abi.SectorInfo, _ abi.PoStRandomness) ([]abi.PoStProof, error) {
+	var proofs []abi.PoStProof
+	for _, s := range sectors {
+		if p.missing[s.SectorNumber] {
+			return nil, errors.Errorf("open sealed sector %d: no such file or directory", s.SectorNumber)
+		}
+		proofs = append(proofs, abi.PoStProof{ProofBytes: s.SealedCID.Bytes()})
+	}
+	return proofs, nil
+}
+
+type fakeVerifier struct {
+	corrupted map[cid.Cid]bool
+}
+
+func (v *fakeVerifier) VerifyWindowPoSt(_ context.Context, info abi.WindowPoStVerifyInfo) (bool, error) {
+	for _, s := range info.ChallengedSectors {
+		if v.corrupted[s.SealedCID] {
+			return false, nil
+		}
+	}
+	return len(info.Proofs) == len(info.ChallengedSectors), nil
+}
+
+type fakeSender struct {
+	err    error
+	sent   cid.Cid
+	from   address.Address
+	method abi.MethodNum
+	params *miner.DeclareFaultsParams
+}
+
+func (s *fakeSender) Send(_ context.Context, from, _ address.Address, _ types.AttoFIL, _ types.AttoFIL, _ gas.Unit,
+	_ bool, method abi.MethodNum, params interface{}) (cid.Cid, chan error, error) {
+	if s.err != nil {
+		return cid.Undef, nil, s.err
+	}
+	s.from = from
+	s.method = method
+	s.params = params.(*miner.DeclareFaultsParams)
+	errCh := make(chan error, 1)
+	errCh <- nil
+	return s.sent, errCh, nil
+}
@@ -0,0 +1,212 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	addr "github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/account"
+	notinit "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/actors/builtin/multisig"
+	paychActor "github.com/filecoin-project/specs-actors/actors/builtin/paych"
+	"github.com/filecoin-project/specs-actors/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/actors/builtin/reward"
+	"github.com/filecoin-project/specs-actors/actors/runtime"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	"github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+// ActorStateReport is the state of an actor decoded with the structures of
+// its code.
+type ActorStateReport struct {
+	Address addr.Address
+	Code    cid.Cid
+	// Name is the name of the builtin actor of the code, empty if unknown.
+	Name    string
+	Head    cid.Cid
+	Nonce   uint64
+	Balance abi.TokenAmount
+	// State is the decoded state, nil when the code is not a known actor.
+	State interface{}
+	// Collections are the contents of the AMTs and HAMTs the state refers to.
+	Collections []ActorStateCollection
+}
+
+// ActorStateCollection is a page of the entries of an AMT or HAMT of an actor
+// state.
+type ActorStateCollection struct {
+	Name string
+	Root cid.Cid
+	// Count is the number of entries of the collection, of which Entries are
+	// those of the page requested.
+	Count   int
+	Entries []ActorStateEntry
+}
+
+// ActorStateEntry is an entry of an actor state collection.
+type ActorStateEntry struct {
+	Key   string
+	Value interface{}
+}
+
+// ActorStateRead loads the state of the actor at `a` and decodes it with the
+// structures of its code. The entries of its collections from `offset` are
+// included, up to `limit` per collection, or all of them when `limit` is zero.
+func (v *View) ActorStateRead(ctx context.Context, a addr.Address, offset, limit int) (*ActorStateReport, error) {
+	actr, err := v.loadActor(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	report := &ActorStateReport{
+		Address: a,
+		Code:    actr.Code.Cid,
+		Head:    actr.Head.Cid,
+		Nonce:   actr.CallSeqNum,
+		Balance: actr.Balance,
+	}
+	if !actr.Code.Defined() || !builtin.IsBuiltinActor(actr.Code.Cid) {
+		return report, nil
+	}
+	report.Name = builtin.ActorNameByCode(actr.Code.Cid)
+
+	p := page{offset: offset, limit: limit}
+	var collections []collectionSpec
+	switch actr.Code.Cid {
+	case builtin.StorageMinerActorCodeID:
+		var st miner.State
+		report.State = &st
+		collections = []collectionSpec{
+			{"PreCommittedSectors", &st.PreCommittedSectors, false, uintKey, func() runtime.CBORUnmarshaler { return new(miner.SectorPreCommitOnChainInfo) }},
+			{"Sectors", &st.Sectors, true, nil, func() runtime.CBORUnmarshaler { return new(miner.SectorOnChainInfo) }},
+			{"VestingFunds", &st.VestingFunds, true, nil, func() runtime.CBORUnmarshaler { return new(abi.TokenAmount) }},
+		}
+	case builtin.StorageMarketActorCodeID:
+		var st market.State
+		report.State = &st
+		collections = []collectionSpec{
+			{"Proposals", &st.Proposals, true, nil, func() runtime.CBORUnmarshaler { return new(market.DealProposal) }},
+			{"States", &st.States, true, nil, func() runtime.CBORUnmarshaler { return new(market.DealState) }},
+			{"EscrowTable", &st.EscrowTable, false, addrKey, func() runtime.CBORUnmarshaler { return new(abi.TokenAmount) }},
+			{"LockedTable", &st.LockedTable, false, addrKey, func() runtime.CBORUnmarshaler { return new(abi.TokenAmount) }},
+		}
+	case builtin.PaymentChannelActorCodeID:
+		report.State = &paychActor.State{}
+	case builtin.StoragePowerActorCodeID:
+		var st power.State
+		report.State = &st
+		collections = []collectionSpec{
+			{"Claims", &st.Claims, false, addrKey, func() runtime.CBORUnmarshaler { return new(power.Claim) }},
+		}
+	case builtin.InitActorCodeID:
+		var st notinit.State
+		report.State = &st
+		collections = []collectionSpec{
+			{"AddressMap", &st.AddressMap, false, addrKey, func() runtime.CBORUnmarshaler { return new(cbg.CborInt) }},
+		}
+	case builtin.AccountActorCodeID:
+		report.State = &account.State{}
+	case builtin.MultisigActorCodeID:
+		report.State = &multisig.State{}
+	case builtin.RewardActorCodeID:
+		report.State = &reward.State{}
+	default:
+		return report, nil
+	}
+
+	if err := v.ipldStore.Get(ctx, actr.Head.Cid, report.State); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode %s state", report.Name)
+	}
+	for _, spec := range collections {
+		collection, err := v.readCollection(ctx, spec, p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s", spec.name)
+		}
+		report.Collections = append(report.Collections, collection)
+	}
+	return report, nil
+}
+
+// collectionSpec describes an AMT or HAMT of an actor state.
+type collectionSpec struct {
+	name string
+	// root is read once the state is decoded
+	root *cid.Cid
+	// array is true for an AMT, false for a HAMT whose keys are decoded with key
+	array    bool
+	key      func(string) (string, error)
+	newValue func() runtime.CBORUnmarshaler
+}
+
+// page selects the entries of a collection.
+type page struct {
+	offset, limit int
+}
+
+func (p page) includes(i int) bool {
+	return i >= p.offset && (p.limit <= 0 || i < p.offset+p.limit)
+}
+
+func (v *View) readCollection(ctx context.Context, spec collectionSpec, p page) (ActorStateCollection, error) {
+	collection := ActorStateCollection{Name: spec.name, Root: *spec.root}
+	visit := func(raw *cbg.Deferred, key string) error {
+		i := collection.Count
+		collection.Count++
+		if !p.includes(i) {
+			return nil
+		}
+		value := spec.newValue()
+		if err := value.UnmarshalCBOR(bytes.NewReader(raw.Raw)); err != nil {
+			return err
+		}
+		collection.Entries = append(collection.Entries, ActorStateEntry{Key: key, Value: value})
+		return nil
+	}
+
+	var raw cbg.Deferred
+	if spec.array {
+		arr, err := v.asArray(ctx, *spec.root)
+		if err != nil {
+			return ActorStateCollection{}, err
+		}
+		err = arr.ForEach(&raw, func(i int64) error {
+			return visit(&raw, fmt.Sprintf("%d", i))
+		})
+		return collection, err
+	}
+
+	m, err := v.asMap(ctx, *spec.root)
+	if err != nil {
+		return ActorStateCollection{}, err
+	}
+	err = m.ForEach(&raw, func(k string) error {
+		key, err := spec.key(k)
+		if err != nil {
+			return err
+		}
+		return visit(&raw, key)
+	})
+	return collection, err
+}
+
+func uintKey(k string) (string, error) {
+	n, err := adt.ParseUIntKey(k)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d", n), nil
+}
+
+func addrKey(k string) (string, error) {
+	a, err := addr.NewFromBytes([]byte(k))
+	if err != nil {
+		return "", err
+	}
+	return a.String(), nil
+}
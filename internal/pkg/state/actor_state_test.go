@@ -0,0 +1,124 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/builtin/account"
+	"github.com/filecoin-project/specs-actors/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/cborutil"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/actor"
+	vmaddr "github.com/filecoin-project/go-filecoin/internal/pkg/vm/address"
+	vmstate "github.com/filecoin-project/go-filecoin/internal/pkg/vm/state"
+)
+
+func TestActorStateRead(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	cst := cborutil.NewIpldStore(blockstore.NewBlockstore(datastore.NewMapDatastore()))
+	store := StoreFromCbor(ctx, cst)
+	client, provider := vmaddr.RequireIDAddress(t, 100), vmaddr.RequireIDAddress(t, 101)
+
+	proposals := adt.MakeEmptyArray(store)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, proposals.Set(uint64(i), &market.DealProposal{
+			PieceCID:             types.CidFromString(t, "somecid"),
+			PieceSize:            abi.PaddedPieceSize(2048 << i),
+			Client:               client,
+			Provider:             provider,
+			StoragePricePerEpoch: abi.NewTokenAmount(1),
+			ProviderCollateral:   abi.NewTokenAmount(0),
+			ClientCollateral:     abi.NewTokenAmount(0),
+		}))
+	}
+	escrow := adt.MakeEmptyMap(store)
+	amount := abi.NewTokenAmount(500)
+	require.NoError(t, escrow.Put(adt.AddrKey(client), &amount))
+
+	marketState := market.State{
+		Proposals:      requireArrayRoot(t, proposals),
+		States:         requireArrayRoot(t, adt.MakeEmptyArray(store)),
+		EscrowTable:    requireMapRoot(t, escrow),
+		LockedTable:    requireMapRoot(t, adt.MakeEmptyMap(store)),
+		DealOpsByEpoch: requireMapRoot(t, adt.MakeEmptyMap(store)),
+	}
+	marketHead, err := cst.Put(ctx, &marketState)
+	require.NoError(t, err)
+	accountHead, err := cst.Put(ctx, &account.State{Address: vmaddr.RequireIDAddress(t, 102)})
+	require.NoError(t, err)
+
+	tree := vmstate.NewState(cst)
+	require.NoError(t, tree.SetActor(ctx, builtin.StorageMarketActorAddr, actor.NewActor(builtin.StorageMarketActorCodeID, abi.NewTokenAmount(500), marketHead)))
+	require.NoError(t, tree.SetActor(ctx, client, actor.NewActor(builtin.AccountActorCodeID, abi.NewTokenAmount(10), accountHead)))
+	root, err := tree.Commit(ctx)
+	require.NoError(t, err)
+	view := NewView(cst, root)
+
+	t.Run("decodes collections by page", func(t *testing.T) {
+		report, err := view.ActorStateRead(ctx, builtin.StorageMarketActorAddr, 1, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "fil/1/storagemarket", report.Name)
+		assert.Equal(t, marketHead, report.Head)
+		st, ok := report.State.(*market.State)
+		require.True(t, ok)
+		assert.Equal(t, marketState.Proposals, st.Proposals)
+
+		require.Len(t, report.Collections, 4)
+		props := report.Collections[0]
+		assert.Equal(t, "Proposals", props.Name)
+		assert.Equal(t, 3, props.Count)
+		require.Len(t, props.Entries, 1)
+		assert.Equal(t, "1", props.Entries[0].Key)
+		assert.Equal(t, abi.PaddedPieceSize(4096), props.Entries[0].Value.(*market.DealProposal).PieceSize)
+
+		// the page is past the single escrow entry
+		assert.Equal(t, 1, report.Collections[2].Count)
+		assert.Empty(t, report.Collections[2].Entries)
+	})
+
+	t.Run("decodes all entries without a limit", func(t *testing.T) {
+		report, err := view.ActorStateRead(ctx, builtin.StorageMarketActorAddr, 0, 0)
+		require.NoError(t, err)
+		assert.Len(t, report.Collections[0].Entries, 3)
+		escrowEntries := report.Collections[2].Entries
+		require.Len(t, escrowEntries, 1)
+		assert.Equal(t, client.String(), escrowEntries[0].Key)
+		assert.Equal(t, amount, *escrowEntries[0].Value.(*abi.TokenAmount))
+	})
+
+	t.Run("decodes states without collections", func(t *testing.T) {
+		report, err := view.ActorStateRead(ctx, client, 0, 0)
+		require.NoError(t, err)
+		assert.Equal(t, vmaddr.RequireIDAddress(t, 102), report.State.(*account.State).Address)
+		assert.Empty(t, report.Collections)
+	})
+
+	t.Run("unknown actor", func(t *testing.T) {
+		_, err := view.ActorStateRead(ctx, provider, 0, 0)
+		assert.Equal(t, types.ErrNotFound, err)
+	})
+}
+
+func requireArrayRoot(t *testing.T, a *adt.Array) cid.Cid {
+	root, err := a.Root()
+	require.NoError(t, err)
+	return root
+}
+
+func requireMapRoot(t *testing.T, m *adt.Map) cid.Cid {
+	root, err := m.Root()
+	require.NoError(t, err)
+	return root
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/ipfs/go-cid"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 )
 
 // FakeStateView is a fake state view.
@@ -37,6 +39,7 @@ type FakeMinerState struct {
 	SectorConfiguration *MinerSectorConfiguration
 	Owner               address.Address
 	Worker              address.Address
+	PendingWorker       *miner.WorkerKeyChange
 	PeerID              peer.ID
 	ProvingPeriodStart  abi.ChainEpoch
 	ProvingPeriodEnd    abi.ChainEpoch
@@ -133,6 +136,18 @@ func (v *FakeStateView) MinerProvingPeriod(ctx context.Context, maddr address.Ad
 	return m.ProvingPeriodStart, m.ProvingPeriodEnd, m.PoStFailures, nil
 }
 
+// MinerDeadlineInfo returns the deadline of the proving period starting at
+// the miner's ProvingPeriodStart that is open at `epoch`.
+func (v *FakeStateView) MinerDeadlineInfo(_ context.Context, maddr address.Address, epoch abi.ChainEpoch) (index uint64, open, close, challenge abi.ChainEpoch, _ error) {
+	m, ok := v.Miners[maddr]
+	if !ok {
+		return 0, 0, 0, 0, errors.Errorf("no miner %s", maddr)
+	}
+	index = uint64((epoch - m.ProvingPeriodStart) / miner.WPoStChallengeWindow)
+	open = m.ProvingPeriodStart + abi.ChainEpoch(index)*miner.WPoStChallengeWindow
+	return index, open, open + miner.WPoStChallengeWindow, open - miner.WPoStChallengeLookback, nil
+}
+
 func (v *FakeStateView) AccountSignerAddress(ctx context.Context, a address.Address) (address.Address, error) {
 	return a, nil
 }
@@ -144,11 +159,21 @@ func (v *FakeStateView) PowerNetworkTotal(_ context.Context) (*NetworkPower, err
 func (v *FakeStateView) MinerClaimedPower(ctx context.Context, miner address.Address) (abi.StoragePower, abi.StoragePower, error) {
 	m, ok := v.Miners[miner]
 	if !ok {
-		return big.Zero(), big.Zero(), errors.Errorf("no miner %s", miner)
+		// As the state, which has no claim for a miner that does not exist.
+		return big.Zero(), big.Zero(), errors.Wrapf(types.ErrNotFound, "no miner %s", miner)
 	}
 	return m.ClaimedRawPower, m.ClaimedQAPower, nil
 }
 
+func (v *FakeStateView) PowerClaimsForEach(_ context.Context, f func(miner address.Address, raw, qa abi.StoragePower) error) error {
+	for addr, m := range v.Miners {
+		if err := f(addr, m.ClaimedRawPower, m.ClaimedQAPower); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (v *FakeStateView) MinerPledgeCollateral(_ context.Context, maddr address.Address) (locked abi.TokenAmount, total abi.TokenAmount, err error) {
 	m, ok := v.Miners[maddr]
 	if !ok {
@@ -167,8 +192,9 @@ func (v *FakeStateView) MinerInfo(ctx context.Context, maddr address.Address) (m
 		return miner.MinerInfo{}, errors.Errorf("no miner %s", maddr)
 	}
 	return miner.MinerInfo{
-		Owner:  m.Owner,
-		Worker: m.Worker,
-		PeerId: m.PeerID,
+		Owner:            m.Owner,
+		Worker:           m.Worker,
+		PendingWorkerKey: m.PendingWorker,
+		PeerId:           m.PeerID,
 	}, nil
 }
@@ -17,6 +17,7 @@ import (
 	"github.com/filecoin-project/specs-actors/actors/builtin/miner"
 	paychActor "github.com/filecoin-project/specs-actors/actors/builtin/paych"
 	"github.com/filecoin-project/specs-actors/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/actors/builtin/reward"
 	"github.com/filecoin-project/specs-actors/actors/util/adt"
 	"github.com/ipfs/go-cid"
 	cbor "github.com/ipfs/go-ipld-cbor"
@@ -24,6 +25,7 @@ import (
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/actor"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/actor/builtin/kv"
 )
 
 // Viewer builds state views from state root CIDs.
@@ -422,6 +424,28 @@ func (v *View) MarketEscrowBalance(ctx context.Context, addr addr.Address) (foun
 	return
 }
 
+// ActorBalance returns the balance of an actor.
+func (v *View) ActorBalance(ctx context.Context, a addr.Address) (abi.TokenAmount, error) {
+	actr, err := v.loadActor(ctx, a)
+	if err != nil {
+		return abi.NewTokenAmount(0), err
+	}
+	return actr.Balance, nil
+}
+
+// RewardLastPerEpochReward returns the reward paid out to all the blocks of the last epoch.
+func (v *View) RewardLastPerEpochReward(ctx context.Context) (abi.TokenAmount, error) {
+	actr, err := v.loadActor(ctx, builtin.RewardActorAddr)
+	if err != nil {
+		return abi.NewTokenAmount(0), err
+	}
+	var state reward.State
+	if err := v.ipldStore.Get(ctx, actr.Head.Cid, &state); err != nil {
+		return abi.NewTokenAmount(0), err
+	}
+	return state.LastPerEpochReward, nil
+}
+
 // MarketComputeDataCommitment takes deal ids and uses associated commPs to compute commD for a sector that contains the deals
 func (v *View) MarketComputeDataCommitment(ctx context.Context, registeredProof abi.RegisteredProof, dealIDs []abi.DealID) (cid.Cid, error) {
 	marketState, err := v.loadMarketActor(ctx)
@@ -549,6 +573,28 @@ func (v *View) MinerClaimedPower(ctx context.Context, miner addr.Address) (raw,
 	return claim.RawBytePower, claim.QualityAdjPower, nil
 }
 
+// PowerClaimsForEach calls `f` with the power claimed by each miner of the
+// power table.
+func (v *View) PowerClaimsForEach(ctx context.Context, f func(miner addr.Address, raw, qa abi.StoragePower) error) error {
+	powerState, err := v.loadPowerActor(ctx)
+	if err != nil {
+		return err
+	}
+	claims, err := v.asMap(ctx, powerState.Claims)
+	if err != nil {
+		return err
+	}
+
+	var claim power.Claim
+	return claims.ForEach(&claim, func(key string) error {
+		miner, err := addr.NewFromBytes([]byte(key))
+		if err != nil {
+			return err
+		}
+		return f(miner, claim.RawBytePower, claim.QualityAdjPower)
+	})
+}
+
 // PaychActorParties returns the From and To addresses for the given payment channel
 func (v *View) PaychActorParties(ctx context.Context, paychAddr addr.Address) (from, to addr.Address, err error) {
 	a, err := v.loadActor(ctx, paychAddr)
@@ -563,6 +609,22 @@ func (v *View) PaychActorParties(ctx context.Context, paychAddr addr.Address) (f
 	return state.From, state.To, nil
 }
 
+// DevnetKVState returns the state of a devnet key-value actor.
+func (v *View) DevnetKVState(ctx context.Context, a addr.Address) (*kv.State, error) {
+	act, err := v.loadActor(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	if !act.Code.Equals(kv.CodeID) {
+		return nil, fmt.Errorf("actor %s is not a devnet key-value actor", a)
+	}
+	var state kv.State
+	if err := v.ipldStore.Get(ctx, act.Head.Cid, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
 func (v *View) loadPowerClaim(ctx context.Context, powerState *power.State, miner addr.Address) (*power.Claim, error) {
 	claims, err := v.asMap(ctx, powerState.Claims)
 	if err != nil {
@@ -8,17 +8,21 @@ import (
 	"github.com/pkg/errors"
 )
 
-// protocolVersion specifies that a particular protocol version goes into effect at a particular block height
-type protocolVersion struct {
+// Upgrade specifies that a particular protocol version goes into effect at a particular block height
+type Upgrade struct {
 	Version     uint64
 	EffectiveAt abi.ChainEpoch
+	// Name identifies the upgrade to operators, it may be empty.
+	Name string
+	// Description summarizes the changes made by the upgrade.
+	Description string
 }
 
 // ProtocolVersionTable is a data structure capable of specifying which protocol versions are active at which block heights.
 // It must be constructed with the ProtocolVersionTableBuilder which enforces that the table has at least one
 // entry at block height zero and that all the versions are sorted.
 type ProtocolVersionTable struct {
-	versions []protocolVersion
+	versions []Upgrade
 }
 
 // VersionAt returns the protocol versions at the given block height for this PVT's network.
@@ -41,6 +45,13 @@ func (pvt *ProtocolVersionTable) VersionAt(height abi.ChainEpoch) (uint64, error
 	return pvt.versions[idx-1].Version, nil
 }
 
+// Upgrades returns all protocol versions of the table, including the one at genesis, sorted by effective height.
+func (pvt *ProtocolVersionTable) Upgrades() []Upgrade {
+	upgrades := make([]Upgrade, len(pvt.versions))
+	copy(upgrades, pvt.versions)
+	return upgrades
+}
+
 // ProtocolVersionTableBuilder constructs a protocol version table
 type ProtocolVersionTableBuilder struct {
 	network  string
@@ -54,24 +65,32 @@ func NewProtocolVersionTableBuilder(network string) *ProtocolVersionTableBuilder
 
 	return &ProtocolVersionTableBuilder{
 		network:  networkPrefix,
-		versions: []protocolVersion{},
+		versions: []Upgrade{},
 	}
 }
 
 // Add configures an version for a network. If the network doesn't match the current network, this version will be ignored.
 func (pvtb *ProtocolVersionTableBuilder) Add(network string, version uint64, effectiveAt abi.ChainEpoch) *ProtocolVersionTableBuilder {
+	return pvtb.AddUpgrade(network, Upgrade{
+		Version:     version,
+		EffectiveAt: effectiveAt,
+	})
+}
+
+// AddUpgrade configures a named version for a network. If the network doesn't match the current network, this
+// version will be ignored.
+func (pvtb *ProtocolVersionTableBuilder) AddUpgrade(network string, upgrade Upgrade) *ProtocolVersionTableBuilder {
 	// ignore version if not part of our network
 	if network != pvtb.network {
 		return pvtb
 	}
+	return pvtb.Schedule(upgrade)
+}
 
-	protocolVersion := protocolVersion{
-		Version:     version,
-		EffectiveAt: effectiveAt,
-	}
-
-	pvtb.versions = append(pvtb.versions, protocolVersion)
-
+// Schedule configures a version for the current network, whatever its name. It is used for upgrades of private
+// networks that are not known in advance.
+func (pvtb *ProtocolVersionTableBuilder) Schedule(upgrade Upgrade) *ProtocolVersionTableBuilder {
+	pvtb.versions = append(pvtb.versions, upgrade)
 	return pvtb
 }
 
@@ -82,7 +101,7 @@ func (pvtb *ProtocolVersionTableBuilder) Build() (*ProtocolVersionTable, error)
 	sort.Sort(pvtb.versions)
 
 	// copy to insure an Add doesn't alter the table
-	versions := make([]protocolVersion, len(pvtb.versions))
+	versions := make([]Upgrade, len(pvtb.versions))
 	copy(versions, pvtb.versions)
 
 	// enforce that the current network has an entry at block height zero
@@ -106,8 +125,8 @@ func (pvtb *ProtocolVersionTableBuilder) Build() (*ProtocolVersionTable, error)
 	return &ProtocolVersionTable{versions: versions}, nil
 }
 
-// sort methods for protocolVersion slice
-type protocolVersionsByEffectiveAt []protocolVersion
+// sort methods for Upgrade slice
+type protocolVersionsByEffectiveAt []Upgrade
 
 func (a protocolVersionsByEffectiveAt) Len() int      { return len(a) }
 func (a protocolVersionsByEffectiveAt) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
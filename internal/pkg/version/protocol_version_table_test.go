@@ -135,4 +135,24 @@ func TestUpgradeTable(t *testing.T) {
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "protocol version 3 effective at 10 is not greater than previous version, 4")
 	})
+
+	t.Run("lists upgrades in effective order", func(t *testing.T) {
+		put, err := NewProtocolVersionTableBuilder(network).
+			AddUpgrade(network, Upgrade{Version: 1, EffectiveAt: abi.ChainEpoch(100), Name: "cheaper-gas"}).
+			Add(network, 0, abi.ChainEpoch(0)).
+			AddUpgrade("othernetwork", Upgrade{Version: 2, EffectiveAt: abi.ChainEpoch(150), Name: "other"}).
+			Schedule(Upgrade{Version: 2, EffectiveAt: abi.ChainEpoch(200), Name: "private"}).
+			Build()
+		require.NoError(t, err)
+
+		assert.Equal(t, []Upgrade{
+			{Version: 0, EffectiveAt: abi.ChainEpoch(0)},
+			{Version: 1, EffectiveAt: abi.ChainEpoch(100), Name: "cheaper-gas"},
+			{Version: 2, EffectiveAt: abi.ChainEpoch(200), Name: "private"},
+		}, put.Upgrades())
+
+		versionAtHeight, err := put.VersionAt(abi.ChainEpoch(200))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), versionAtHeight)
+	})
 }
@@ -10,15 +10,19 @@ const TEST = "gfctest"
 // Protocol0 is the first protocol version
 const Protocol0 = 0
 
-// ConfigureProtocolVersions configures all protocol upgrades for all known networks.
+// ConfigureProtocolVersions configures all protocol upgrades for all known networks, followed by the
+// `scheduled` upgrades of the network the node is running, e.g. those configured for a private network.
 // TODO: support arbitrary network names at "latest" protocol version so that only coordinated
 // network upgrades need to be represented here. See #3491.
-func ConfigureProtocolVersions(network string) (*ProtocolVersionTable, error) {
-	return NewProtocolVersionTableBuilder(network).
+func ConfigureProtocolVersions(network string, scheduled ...Upgrade) (*ProtocolVersionTable, error) {
+	builder := NewProtocolVersionTableBuilder(network).
 		Add("alpha2", Protocol0, abi.ChainEpoch(0)).
 		Add("interop", Protocol0, abi.ChainEpoch(0)).
 		Add("localnet", Protocol0, abi.ChainEpoch(0)).
 		Add("testnet", Protocol0, abi.ChainEpoch(0)).
-		Add(TEST, Protocol0, abi.ChainEpoch(0)).
-		Build()
+		Add(TEST, Protocol0, abi.ChainEpoch(0))
+	for _, upgrade := range scheduled {
+		builder.Schedule(upgrade)
+	}
+	return builder.Build()
 }
@@ -14,22 +14,34 @@ import (
 	"github.com/filecoin-project/specs-actors/actors/builtin/system"
 	"github.com/filecoin-project/specs-actors/actors/builtin/verifreg"
 
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/actor/builtin/kv"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/internal/dispatch"
 )
 
 // DefaultActors is list of all actors that ship with Filecoin.
 // They are indexed by their CID.
 // Dragons: add the rest of the actors
-var DefaultActors = dispatch.NewBuilder().
-	Add(specs.InitActorCodeID, &init_.Actor{}).
-	Add(specs.AccountActorCodeID, &account.Actor{}).
-	Add(specs.MultisigActorCodeID, &multisig.Actor{}).
-	Add(specs.PaymentChannelActorCodeID, &paych.Actor{}).
-	Add(specs.StoragePowerActorCodeID, &power.Actor{}).
-	Add(specs.StorageMarketActorCodeID, &market.Actor{}).
-	Add(specs.StorageMinerActorCodeID, &miner.Actor{}).
-	Add(specs.SystemActorCodeID, &system.Actor{}).
-	Add(specs.RewardActorCodeID, &reward.Actor{}).
-	Add(specs.CronActorCodeID, &cron.Actor{}).
-	Add(specs.VerifiedRegistryActorCodeID, &verifreg.Actor{}).
+var DefaultActors = defaultActors().Build()
+
+// DevnetActors are the default actors and the experimental devnet actors, for
+// networks enabling them in their network parameters. Their init actor creates
+// the devnet actors too.
+var DevnetActors = defaultActors().
+	Add(specs.InitActorCodeID, &kv.InitActor{}).
+	Add(kv.CodeID, &kv.Actor{}).
 	Build()
+
+func defaultActors() *dispatch.CodeLoaderBuilder {
+	return dispatch.NewBuilder().
+		Add(specs.InitActorCodeID, &init_.Actor{}).
+		Add(specs.AccountActorCodeID, &account.Actor{}).
+		Add(specs.MultisigActorCodeID, &multisig.Actor{}).
+		Add(specs.PaymentChannelActorCodeID, &paych.Actor{}).
+		Add(specs.StoragePowerActorCodeID, &power.Actor{}).
+		Add(specs.StorageMarketActorCodeID, &market.Actor{}).
+		Add(specs.StorageMinerActorCodeID, &miner.Actor{}).
+		Add(specs.SystemActorCodeID, &system.Actor{}).
+		Add(specs.RewardActorCodeID, &reward.Actor{}).
+		Add(specs.CronActorCodeID, &cron.Actor{}).
+		Add(specs.VerifiedRegistryActorCodeID, &verifreg.Actor{})
+}
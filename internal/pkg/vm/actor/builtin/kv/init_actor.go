@@ -0,0 +1,50 @@
+package kv
+
+import (
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/actors/builtin/init"
+	vmr "github.com/filecoin-project/specs-actors/actors/runtime"
+	"github.com/filecoin-project/specs-actors/actors/runtime/exitcode"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+)
+
+// InitActor is the init actor of networks enabling devnet actors. The init
+// actor of the specs only execs miner, payment channel and multisig actors;
+// this one execs key-value actors too, and is otherwise unchanged.
+type InitActor struct {
+	init_.Actor
+}
+
+// Exports implements dispatch.Actor.
+func (a InitActor) Exports() []interface{} {
+	exports := a.Actor.Exports()
+	exports[builtin.MethodsInit.Exec] = a.Exec
+	return exports
+}
+
+// Exec creates a key-value actor for any caller, and any other actor as the
+// init actor of the specs does.
+func (a InitActor) Exec(rt vmr.Runtime, params *init_.ExecParams) *init_.ExecReturn {
+	if !params.CodeCID.Equals(CodeID) {
+		return a.Actor.Exec(rt, params)
+	}
+	rt.ValidateImmediateCallerAcceptAny()
+
+	// the actor gets a robust address and an id address, as those created by
+	// the init actor of the specs
+	robustAddr := rt.NewActorAddress()
+	var st init_.State
+	idAddr := rt.State().Transaction(&st, func() interface{} {
+		idAddr, err := st.MapAddressToNewID(adt.AsStore(rt), robustAddr)
+		if err != nil {
+			rt.Abortf(exitcode.ErrIllegalState, "failed to allocate an id address: %s", err)
+		}
+		return idAddr
+	}).(address.Address)
+
+	rt.CreateActor(CodeID, idAddr)
+	_, code := rt.Send(idAddr, builtin.MethodConstructor, vmr.CBORBytes(params.ConstructorParams), rt.Message().ValueReceived())
+	builtin.RequireSuccess(rt, code, "failed to construct key-value actor")
+	return &init_.ExecReturn{IDAddress: idAddr, RobustAddress: robustAddr}
+}
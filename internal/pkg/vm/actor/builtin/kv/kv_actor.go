@@ -0,0 +1,398 @@
+// Package kv implements an experimental actor for devnets, holding key-value
+// state and making payments conditioned on it, so that application developers
+// can prototype on-chain logic with the built-in VM.
+//
+// The actor is only executable by networks enabling devnet actors in their
+// network parameters, which run the VM with the devnet actors. Other networks
+// have no code for it, and their init actor does not create it.
+package kv
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	vmr "github.com/filecoin-project/specs-actors/actors/runtime"
+	"github.com/filecoin-project/specs-actors/actors/runtime/exitcode"
+	"github.com/filecoin-project/specs-actors/actors/util/adt"
+	fxamackercbor "github.com/fxamacker/cbor/v2"
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+// CodeID is the code cid of the actor.
+var CodeID = func() cid.Cid {
+	c, err := cid.Prefix{Version: 1, Codec: cid.Raw, MhType: mh.IDENTITY, MhLength: -1}.Sum([]byte("fil/devnet/kv"))
+	if err != nil {
+		panic(err)
+	}
+	return c
+}()
+
+// MaxKeySize and MaxValueSize bound the entries of the state.
+const (
+	MaxKeySize   = 128
+	MaxValueSize = 1024
+)
+
+// Methods of the actor.
+var Methods = struct {
+	Constructor   abi.MethodNum
+	Set           abi.MethodNum
+	Delete        abi.MethodNum
+	AddPayment    abi.MethodNum
+	CancelPayment abi.MethodNum
+	Settle        abi.MethodNum
+	Get           abi.MethodNum
+}{builtin.MethodConstructor, 2, 3, 4, 5, 6, 7}
+
+// Actor is the devnet key-value actor.
+type Actor struct{}
+
+// Exports implements dispatch.Actor.
+func (a Actor) Exports() []interface{} {
+	return []interface{}{
+		builtin.MethodConstructor: a.Constructor,
+		2:                         a.Set,
+		3:                         a.Delete,
+		4:                         a.AddPayment,
+		5:                         a.CancelPayment,
+		6:                         a.Settle,
+		7:                         a.Get,
+	}
+}
+
+var _ abi.Invokee = Actor{}
+
+// State is the state of the actor.
+type State struct {
+	_ struct{} `cbor:",toarray"`
+	// Owner manages the writers and payments, and writes entries.
+	Owner address.Address
+	// Writers may write entries too.
+	Writers []address.Address
+	// Entries are sorted by key.
+	Entries []Entry
+	// Payments are sorted by id.
+	Payments      []Payment
+	NextPaymentID uint64
+}
+
+// Entry is a key-value pair of the state.
+type Entry struct {
+	_     struct{} `cbor:",toarray"`
+	Key   string
+	Value []byte
+}
+
+// Payment is a payment of the actor's funds made by any caller once the entry
+// Key holds Value.
+type Payment struct {
+	_      struct{} `cbor:",toarray"`
+	ID     uint64
+	Key    string
+	Value  []byte
+	To     address.Address
+	Amount abi.TokenAmount
+}
+
+// Get returns the value of a key.
+func (st *State) Get(key string) ([]byte, bool) {
+	i := sort.Search(len(st.Entries), func(i int) bool { return st.Entries[i].Key >= key })
+	if i < len(st.Entries) && st.Entries[i].Key == key {
+		return st.Entries[i].Value, true
+	}
+	return nil, false
+}
+
+func (st *State) set(key string, value []byte) {
+	i := sort.Search(len(st.Entries), func(i int) bool { return st.Entries[i].Key >= key })
+	if i < len(st.Entries) && st.Entries[i].Key == key {
+		st.Entries[i].Value = value
+		return
+	}
+	st.Entries = append(st.Entries, Entry{})
+	copy(st.Entries[i+1:], st.Entries[i:])
+	st.Entries[i] = Entry{Key: key, Value: value}
+}
+
+func (st *State) delete(key string) bool {
+	i := sort.Search(len(st.Entries), func(i int) bool { return st.Entries[i].Key >= key })
+	if i < len(st.Entries) && st.Entries[i].Key == key {
+		st.Entries = append(st.Entries[:i], st.Entries[i+1:]...)
+		return true
+	}
+	return false
+}
+
+func (st *State) payment(id uint64) (int, bool) {
+	i := sort.Search(len(st.Payments), func(i int) bool { return st.Payments[i].ID >= id })
+	return i, i < len(st.Payments) && st.Payments[i].ID == id
+}
+
+func (st *State) canWrite(caller address.Address) bool {
+	if caller == st.Owner {
+		return true
+	}
+	for _, w := range st.Writers {
+		if w == caller {
+			return true
+		}
+	}
+	return false
+}
+
+// ConstructorParams are the parameters of the constructor, the addresses are
+// resolved to ID addresses.
+type ConstructorParams struct {
+	_       struct{} `cbor:",toarray"`
+	Owner   address.Address
+	Writers []address.Address
+}
+
+// Constructor creates the actor through the init actor.
+func (a Actor) Constructor(rt vmr.Runtime, params *ConstructorParams) *adt.EmptyValue {
+	rt.ValidateImmediateCallerIs(builtin.InitActorAddr)
+
+	st := State{
+		Owner:    resolve(rt, params.Owner),
+		Writers:  []address.Address{},
+		Entries:  []Entry{},
+		Payments: []Payment{},
+	}
+	for _, w := range params.Writers {
+		st.Writers = append(st.Writers, resolve(rt, w))
+	}
+	rt.State().Create(&st)
+	return nil
+}
+
+// SetParams are the parameters of Set.
+type SetParams struct {
+	_     struct{} `cbor:",toarray"`
+	Key   string
+	Value []byte
+}
+
+// Set sets the value of a key, it is called by the owner or a writer.
+func (a Actor) Set(rt vmr.Runtime, params *SetParams) *adt.EmptyValue {
+	rt.ValidateImmediateCallerAcceptAny()
+	if len(params.Key) == 0 || len(params.Key) > MaxKeySize {
+		rt.Abortf(exitcode.ErrIllegalArgument, "key must have 1 to %d bytes", MaxKeySize)
+	}
+	if len(params.Value) > MaxValueSize {
+		rt.Abortf(exitcode.ErrIllegalArgument, "value must have at most %d bytes", MaxValueSize)
+	}
+
+	var st State
+	rt.State().Transaction(&st, func() interface{} {
+		if !st.canWrite(rt.Message().Caller()) {
+			rt.Abortf(exitcode.ErrForbidden, "%s may not write", rt.Message().Caller())
+		}
+		st.set(params.Key, params.Value)
+		return nil
+	})
+	return nil
+}
+
+// KeyParams are the parameters of the methods taking a key.
+type KeyParams struct {
+	_   struct{} `cbor:",toarray"`
+	Key string
+}
+
+// Delete deletes a key, it is called by the owner or a writer.
+func (a Actor) Delete(rt vmr.Runtime, params *KeyParams) *adt.EmptyValue {
+	rt.ValidateImmediateCallerAcceptAny()
+
+	var st State
+	rt.State().Transaction(&st, func() interface{} {
+		if !st.canWrite(rt.Message().Caller()) {
+			rt.Abortf(exitcode.ErrForbidden, "%s may not write", rt.Message().Caller())
+		}
+		if !st.delete(params.Key) {
+			rt.Abortf(exitcode.ErrNotFound, "no key %q", params.Key)
+		}
+		return nil
+	})
+	return nil
+}
+
+// AddPaymentParams are the parameters of AddPayment.
+type AddPaymentParams struct {
+	_      struct{} `cbor:",toarray"`
+	Key    string
+	Value  []byte
+	To     address.Address
+	Amount abi.TokenAmount
+}
+
+// PaymentIDReturn is the return of AddPayment, and the parameters of the
+// methods taking a payment.
+type PaymentIDReturn struct {
+	_  struct{} `cbor:",toarray"`
+	ID uint64
+}
+
+// PaymentIDParams are the parameters of the methods taking a payment.
+type PaymentIDParams = PaymentIDReturn
+
+// AddPayment adds a payment of the actor's funds made once the key holds
+// the value, it is called by the owner. The actor is funded by the value of
+// any message sent to it.
+func (a Actor) AddPayment(rt vmr.Runtime, params *AddPaymentParams) *PaymentIDReturn {
+	if params.Amount.LessThanEqual(big.Zero()) {
+		rt.Abortf(exitcode.ErrIllegalArgument, "payment amount must be positive")
+	}
+	to := resolve(rt, params.To)
+
+	var st State
+	rt.State().Readonly(&st)
+	rt.ValidateImmediateCallerIs(st.Owner)
+
+	var id uint64
+	rt.State().Transaction(&st, func() interface{} {
+		id = st.NextPaymentID
+		st.NextPaymentID++
+		st.Payments = append(st.Payments, Payment{ID: id, Key: params.Key, Value: params.Value, To: to, Amount: params.Amount})
+		return nil
+	})
+	return &PaymentIDReturn{ID: id}
+}
+
+// CancelPayment removes a payment, it is called by the owner.
+func (a Actor) CancelPayment(rt vmr.Runtime, params *PaymentIDParams) *adt.EmptyValue {
+	var st State
+	rt.State().Readonly(&st)
+	rt.ValidateImmediateCallerIs(st.Owner)
+
+	rt.State().Transaction(&st, func() interface{} {
+		i, ok := st.payment(params.ID)
+		if !ok {
+			rt.Abortf(exitcode.ErrNotFound, "no payment %d", params.ID)
+		}
+		st.Payments = append(st.Payments[:i], st.Payments[i+1:]...)
+		return nil
+	})
+	return nil
+}
+
+// Settle makes a payment whose condition holds, it is called by anyone.
+func (a Actor) Settle(rt vmr.Runtime, params *PaymentIDParams) *adt.EmptyValue {
+	rt.ValidateImmediateCallerAcceptAny()
+
+	var st State
+	payment := rt.State().Transaction(&st, func() interface{} {
+		i, ok := st.payment(params.ID)
+		if !ok {
+			rt.Abortf(exitcode.ErrNotFound, "no payment %d", params.ID)
+		}
+		p := st.Payments[i]
+		if value, ok := st.Get(p.Key); !ok || !bytes.Equal(value, p.Value) {
+			rt.Abortf(exitcode.ErrForbidden, "condition of payment %d does not hold", params.ID)
+		}
+		if rt.CurrentBalance().LessThan(p.Amount) {
+			rt.Abortf(exitcode.ErrInsufficientFunds, "balance %s is less than payment %s", rt.CurrentBalance(), p.Amount)
+		}
+		st.Payments = append(st.Payments[:i], st.Payments[i+1:]...)
+		return p
+	}).(Payment)
+
+	_, code := rt.Send(payment.To, builtin.MethodSend, nil, payment.Amount)
+	builtin.RequireSuccess(rt, code, "failed to send payment %d", payment.ID)
+	return nil
+}
+
+// GetReturn is the return of Get.
+type GetReturn struct {
+	_     struct{} `cbor:",toarray"`
+	Found bool
+	Value []byte
+}
+
+// Get returns the value of a key.
+func (a Actor) Get(rt vmr.Runtime, params *KeyParams) *GetReturn {
+	rt.ValidateImmediateCallerAcceptAny()
+
+	var st State
+	rt.State().Readonly(&st)
+	value, found := st.Get(params.Key)
+	return &GetReturn{Found: found, Value: value}
+}
+
+func resolve(rt vmr.Runtime, addr address.Address) address.Address {
+	resolved, ok := rt.ResolveAddress(addr)
+	if !ok {
+		rt.Abortf(exitcode.ErrIllegalArgument, "failed to resolve address %s", addr)
+	}
+	return resolved
+}
+
+//
+// CBOR encoding, the types implement cbg.CBORMarshaler and
+// cbg.CBORUnmarshaler as expected by the runtime.
+//
+
+func marshal(w io.Writer, obj interface{}) error {
+	bs, err := fxamackercbor.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bs)
+	return err
+}
+
+func unmarshal(r io.Reader, obj interface{}) error {
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return fxamackercbor.Unmarshal(bs, obj)
+}
+
+// MarshalCBOR implements cbg.CBORMarshaler.
+func (st *State) MarshalCBOR(w io.Writer) error { return marshal(w, st) }
+
+// UnmarshalCBOR implements cbg.CBORUnmarshaler.
+func (st *State) UnmarshalCBOR(r io.Reader) error { return unmarshal(r, st) }
+
+// MarshalCBOR implements cbg.CBORMarshaler.
+func (p *ConstructorParams) MarshalCBOR(w io.Writer) error { return marshal(w, p) }
+
+// UnmarshalCBOR implements cbg.CBORUnmarshaler.
+func (p *ConstructorParams) UnmarshalCBOR(r io.Reader) error { return unmarshal(r, p) }
+
+// MarshalCBOR implements cbg.CBORMarshaler.
+func (p *SetParams) MarshalCBOR(w io.Writer) error { return marshal(w, p) }
+
+// UnmarshalCBOR implements cbg.CBORUnmarshaler.
+func (p *SetParams) UnmarshalCBOR(r io.Reader) error { return unmarshal(r, p) }
+
+// MarshalCBOR implements cbg.CBORMarshaler.
+func (p *KeyParams) MarshalCBOR(w io.Writer) error { return marshal(w, p) }
+
+// UnmarshalCBOR implements cbg.CBORUnmarshaler.
+func (p *KeyParams) UnmarshalCBOR(r io.Reader) error { return unmarshal(r, p) }
+
+// MarshalCBOR implements cbg.CBORMarshaler.
+func (p *AddPaymentParams) MarshalCBOR(w io.Writer) error { return marshal(w, p) }
+
+// UnmarshalCBOR implements cbg.CBORUnmarshaler.
+func (p *AddPaymentParams) UnmarshalCBOR(r io.Reader) error { return unmarshal(r, p) }
+
+// MarshalCBOR implements cbg.CBORMarshaler.
+func (p *PaymentIDReturn) MarshalCBOR(w io.Writer) error { return marshal(w, p) }
+
+// UnmarshalCBOR implements cbg.CBORUnmarshaler.
+func (p *PaymentIDReturn) UnmarshalCBOR(r io.Reader) error { return unmarshal(r, p) }
+
+// MarshalCBOR implements cbg.CBORMarshaler.
+func (r *GetReturn) MarshalCBOR(w io.Writer) error { return marshal(w, r) }
+
+// UnmarshalCBOR implements cbg.CBORUnmarshaler.
+func (r *GetReturn) UnmarshalCBOR(rd io.Reader) error { return unmarshal(rd, r) }
@@ -0,0 +1,101 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/filecoin-project/specs-actors/actors/runtime/exitcode"
+	"github.com/filecoin-project/specs-actors/support/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/actor/builtin/kv"
+)
+
+func TestKVActor(t *testing.T) {
+	tf.UnitTest(t)
+
+	receiver := requireIDAddress(t, 100)
+	owner := requireIDAddress(t, 101)
+	writer := requireIDAddress(t, 102)
+	other := requireIDAddress(t, 103)
+	actor := kv.Actor{}
+
+	rt := mock.NewBuilder(context.Background(), receiver).
+		WithCaller(builtin.InitActorAddr, builtin.InitActorCodeID).
+		WithBalance(abi.NewTokenAmount(100), abi.NewTokenAmount(0)).
+		Build(t)
+	rt.ExpectValidateCallerAddr(builtin.InitActorAddr)
+	rt.Call(actor.Constructor, &kv.ConstructorParams{Owner: owner, Writers: []address.Address{writer}})
+	rt.Verify()
+
+	t.Log("writers set keys, others may not")
+	rt.SetCaller(writer, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAny()
+	rt.Call(actor.Set, &kv.SetParams{Key: "b", Value: []byte("2")})
+	rt.ExpectValidateCallerAny()
+	rt.Call(actor.Set, &kv.SetParams{Key: "a", Value: []byte("1")})
+	rt.SetCaller(other, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAny()
+	rt.ExpectAbort(exitcode.ErrForbidden, func() {
+		rt.Call(actor.Set, &kv.SetParams{Key: "a", Value: []byte("3")})
+	})
+
+	var st kv.State
+	rt.GetState(&st)
+	assert.Equal(t, []kv.Entry{{Key: "a", Value: []byte("1")}, {Key: "b", Value: []byte("2")}}, st.Entries)
+
+	t.Log("the owner adds a payment, settled once its condition holds")
+	rt.SetCaller(owner, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAddr(owner)
+	ret := rt.Call(actor.AddPayment, &kv.AddPaymentParams{Key: "a", Value: []byte("done"), To: other, Amount: abi.NewTokenAmount(40)}).(*kv.PaymentIDReturn)
+	assert.Equal(t, uint64(0), ret.ID)
+
+	rt.SetCaller(other, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAny()
+	rt.ExpectAbort(exitcode.ErrForbidden, func() {
+		rt.Call(actor.Settle, &kv.PaymentIDParams{ID: 0})
+	})
+
+	rt.SetCaller(owner, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAny()
+	rt.Call(actor.Set, &kv.SetParams{Key: "a", Value: []byte("done")})
+
+	rt.SetCaller(other, builtin.AccountActorCodeID)
+	rt.ExpectValidateCallerAny()
+	rt.ExpectSend(other, builtin.MethodSend, nil, abi.NewTokenAmount(40), nil, exitcode.Ok)
+	rt.Call(actor.Settle, &kv.PaymentIDParams{ID: 0})
+	rt.Verify()
+
+	t.Log("a payment is settled once")
+	rt.ExpectValidateCallerAny()
+	rt.ExpectAbort(exitcode.ErrNotFound, func() {
+		rt.Call(actor.Settle, &kv.PaymentIDParams{ID: 0})
+	})
+
+	rt.ExpectValidateCallerAny()
+	got := rt.Call(actor.Get, &kv.KeyParams{Key: "a"}).(*kv.GetReturn)
+	assert.True(t, got.Found)
+	assert.Equal(t, []byte("done"), got.Value)
+}
+
+func TestKVActorDisabled(t *testing.T) {
+	tf.UnitTest(t)
+
+	rt := mock.NewBuilder(context.Background(), requireIDAddress(t, 100)).
+		WithCaller(builtin.InitActorAddr, builtin.InitActorCodeID).
+		Build(t)
+	rt.ExpectAbort(exitcode.SysErrForbidden, func() {
+		rt.Call(kv.Actor{}.Constructor, &kv.ConstructorParams{Owner: requireIDAddress(t, 101)})
+	})
+}
+
+func requireIDAddress(t *testing.T, id uint64) address.Address {
+	addr, err := address.NewIDAddress(id)
+	require.NoError(t, err)
+	return addr
+}
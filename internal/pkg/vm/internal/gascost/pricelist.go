@@ -0,0 +1,106 @@
+package gascost
+
+import (
+	"fmt"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/internal/message"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+)
+
+// pricelist prices the operations of the VM by a gas schedule.
+type pricelist struct {
+	schedule Schedule
+}
+
+var _ Pricelist = (*pricelist)(nil)
+
+// OnChainMessage returns the gas used for storing a message of a given size in the chain.
+func (pl *pricelist) OnChainMessage(msgSize int) gas.Unit {
+	return pl.schedule.OnChainMessage.Cost(msgSize)
+}
+
+// OnChainReturnValue returns the gas used for storing the response of a message in the chain.
+func (pl *pricelist) OnChainReturnValue(receipt *message.Receipt) gas.Unit {
+	return gas.Unit(len(receipt.ReturnValue)) * pl.schedule.OnChainReturnValuePerByte
+}
+
+// OnMethodInvocation returns the gas used when invoking a method.
+func (pl *pricelist) OnMethodInvocation(value abi.TokenAmount, methodNum abi.MethodNum) gas.Unit {
+	ret := pl.schedule.SendBase
+	if value != abi.NewTokenAmount(0) {
+		ret += pl.schedule.SendTransferFunds
+	}
+	if methodNum != builtin.MethodSend {
+		ret += pl.schedule.SendInvokeMethod
+	}
+	return ret
+}
+
+// OnIpldGet returns the gas used for storing an object
+func (pl *pricelist) OnIpldGet(dataSize int) gas.Unit {
+	return pl.schedule.IpldGet.Cost(dataSize)
+}
+
+// OnIpldPut returns the gas used for storing an object
+func (pl *pricelist) OnIpldPut(dataSize int) gas.Unit {
+	return pl.schedule.IpldPut.Cost(dataSize)
+}
+
+// OnCreateActor returns the gas used for creating an actor
+func (pl *pricelist) OnCreateActor() gas.Unit {
+	return pl.schedule.CreateActorBase + pl.schedule.CreateActorExtra
+}
+
+// OnDeleteActor returns the gas used for deleting an actor
+func (pl *pricelist) OnDeleteActor() gas.Unit {
+	return pl.schedule.DeleteActor
+}
+
+// OnVerifySignature
+func (pl *pricelist) OnVerifySignature(sigType crypto.SigType, planTextSize int) (gas.Unit, error) {
+	switch sigType {
+	case crypto.SigTypeBLS:
+		return pl.schedule.VerifySignatureBLS.Cost(planTextSize), nil
+	case crypto.SigTypeSecp256k1:
+		return pl.schedule.VerifySignatureSecp.Cost(planTextSize), nil
+	}
+	return 0, fmt.Errorf("cost function for signature type %d not supported", sigType)
+}
+
+// OnHashing
+func (pl *pricelist) OnHashing(dataSize int) gas.Unit {
+	return pl.schedule.Hashing.Cost(dataSize)
+}
+
+// OnComputeUnsealedSectorCid
+func (pl *pricelist) OnComputeUnsealedSectorCid(proofType abi.RegisteredProof, pieces *[]abi.PieceInfo) gas.Unit {
+	// TODO: this needs more cost tunning, check with @lotus
+	return pl.schedule.ComputeUnsealedSectorCidBase
+}
+
+// OnVerifySeal
+func (pl *pricelist) OnVerifySeal(info abi.SealVerifyInfo) gas.Unit {
+	// TODO: this needs more cost tunning, check with @lotus
+	return pl.schedule.VerifySealBase
+}
+
+// OnVerifyWinningPoSt
+func (pl *pricelist) OnVerifyWinningPoSt(info abi.WinningPoStVerifyInfo) gas.Unit {
+	// TODO: this needs more cost tunning, check with @lotus
+	return pl.schedule.VerifyPostBase
+}
+
+// OnVerifyPoSt
+func (pl *pricelist) OnVerifyPoSt(info abi.WindowPoStVerifyInfo) gas.Unit {
+	// TODO: this needs more cost tunning, check with @lotus
+	return pl.schedule.VerifyPostBase
+}
+
+// OnVerifyConsensusFault
+func (pl *pricelist) OnVerifyConsensusFault() gas.Unit {
+	return pl.schedule.VerifyConsensusFault
+}
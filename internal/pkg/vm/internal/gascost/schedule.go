@@ -0,0 +1,186 @@
+package gascost
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
+)
+
+// LinearCost is a cost growing linearly with the size of its input.
+type LinearCost struct {
+	Base    gas.Unit
+	PerUnit gas.Unit
+}
+
+// Cost returns the cost for an input of size x.
+func (c LinearCost) Cost(x int) gas.Unit {
+	return c.Base + c.PerUnit*gas.Unit(x)
+}
+
+// Schedule is a version of the gas costs of the VM, in effect from an epoch.
+//
+// Note: the fields of a schedule in effect on a chain must never change, new
+// costs are introduced with a new version.
+type Schedule struct {
+	Version uint64
+	Epoch   abi.ChainEpoch
+
+	///////////////////////////////////////////////////////////////////////////
+	// System operations
+	///////////////////////////////////////////////////////////////////////////
+
+	// Gas cost charged to the originator of an on-chain message (regardless of
+	// whether it succeeds or fails in application) is given by:
+	//   Base + len(serialized message)*PerUnit
+	// Together, these account for the cost of message propagation and validation,
+	// up to but excluding any actual processing by the VM.
+	// This is the cost a block producer burns when including an invalid message.
+	OnChainMessage LinearCost
+
+	// Gas cost charged to the originator of a non-nil return value produced
+	// by an on-chain message is given by:
+	//   len(return value)*OnChainReturnValuePerByte
+	OnChainReturnValuePerByte gas.Unit
+
+	// Gas cost for any message send execution(including the top-level one
+	// initiated by an on-chain message).
+	// This accounts for the cost of loading sender and receiver actors and
+	// (for top-level messages) incrementing the sender's sequence number.
+	// Load and store of actor sub-state is charged separately.
+	SendBase gas.Unit
+
+	// Gas cost charged, in addition to SendBase, if a message send
+	// is accompanied by any nonzero currency amount.
+	// Accounts for writing receiver's new balance (the sender's state is
+	// already accounted for).
+	SendTransferFunds gas.Unit
+
+	// Gas cost charged, in addition to SendBase, if a message invokes
+	// a method on the receiver.
+	// Accounts for the cost of loading receiver code and method dispatch.
+	SendInvokeMethod gas.Unit
+
+	// Gas cost (Base + len*PerUnit) for any Get operation to the IPLD store
+	// in the runtime VM context.
+	IpldGet LinearCost
+
+	// Gas cost (Base + len*PerUnit) for any Put operation to the IPLD store
+	// in the runtime VM context.
+	//
+	// Note: these costs should be significantly higher than the costs for Get
+	// operations, since they reflect not only serialization/deserialization
+	// but also persistent storage of chain data.
+	IpldPut LinearCost
+
+	// Gas cost for creating a new actor (via InitActor's Exec method).
+	//
+	// Note: this costs assume that the extra will be partially or totally refunded while
+	// the base is covering for the put.
+	CreateActorBase  gas.Unit
+	CreateActorExtra gas.Unit
+
+	// Gas cost for deleting an actor.
+	//
+	// Note: this partially refunds the create cost to incentivise the deletion of the actors.
+	DeleteActor gas.Unit
+
+	// Gas cost (Base + len(plain text)*PerUnit) for verifying a signature.
+	VerifySignatureBLS  LinearCost
+	VerifySignatureSecp LinearCost
+
+	Hashing LinearCost
+
+	ComputeUnsealedSectorCidBase gas.Unit
+	VerifySealBase               gas.Unit
+	VerifyPostBase               gas.Unit
+	VerifyConsensusFault         gas.Unit
+}
+
+// DefaultSchedules are the gas schedules of the Filecoin network.
+var DefaultSchedules = []Schedule{
+	{
+		Version: 0,
+		Epoch:   0,
+		// These message base/byte values must match those in message validation,
+		// ValidateSchedules rejects schedules changing them.
+		OnChainMessage:               LinearCost{Base: gas.Zero, PerUnit: gas.NewGas(2)},
+		OnChainReturnValuePerByte:    gas.NewGas(8),
+		SendBase:                     gas.NewGas(5),
+		SendTransferFunds:            gas.NewGas(5),
+		SendInvokeMethod:             gas.NewGas(10),
+		IpldGet:                      LinearCost{Base: gas.NewGas(10), PerUnit: gas.NewGas(1)},
+		IpldPut:                      LinearCost{Base: gas.NewGas(20), PerUnit: gas.NewGas(2)},
+		CreateActorBase:              gas.NewGas(40), // IPLD put + 20
+		CreateActorExtra:             gas.NewGas(500),
+		DeleteActor:                  gas.NewGas(-500), // -createActorExtra
+		VerifySignatureBLS:           LinearCost{Base: gas.NewGas(2), PerUnit: gas.NewGas(3)},
+		VerifySignatureSecp:          LinearCost{Base: gas.NewGas(2), PerUnit: gas.NewGas(3)},
+		Hashing:                      LinearCost{Base: gas.NewGas(5), PerUnit: gas.NewGas(2)},
+		ComputeUnsealedSectorCidBase: gas.NewGas(100),
+		VerifySealBase:               gas.NewGas(2000),
+		VerifyPostBase:               gas.NewGas(700),
+		VerifyConsensusFault:         gas.NewGas(10),
+	},
+}
+
+// defaultPrices are the pricelists of the default schedules, by ascending epoch.
+var defaultPrices = pricelists(DefaultSchedules)
+
+// NewPricelist returns the pricelist of a gas schedule.
+func NewPricelist(schedule Schedule) Pricelist {
+	return &pricelist{schedule: schedule}
+}
+
+// ReadSchedules reads gas schedules from their JSON encoding.
+func ReadSchedules(r io.Reader) ([]Schedule, error) {
+	var schedules []Schedule
+	if err := json.NewDecoder(r).Decode(&schedules); err != nil {
+		return nil, err
+	}
+	if err := ValidateSchedules(schedules); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// ValidateSchedules checks that the first schedule is in effect from epoch 0,
+// and that later ones have greater epochs and versions. Schedules must price
+// messages as the default does, as message validation checks gas limits
+// against fixed message costs.
+func ValidateSchedules(schedules []Schedule) error {
+	if len(schedules) == 0 {
+		return fmt.Errorf("no gas schedule")
+	}
+	if schedules[0].Epoch != 0 {
+		return fmt.Errorf("first gas schedule must be in effect from epoch 0, not %d", schedules[0].Epoch)
+	}
+	for _, s := range schedules {
+		if s.OnChainMessage != DefaultSchedules[0].OnChainMessage {
+			return fmt.Errorf("gas schedule %d must price messages at %d plus %d per byte, as message validation does", s.Version, DefaultSchedules[0].OnChainMessage.Base, DefaultSchedules[0].OnChainMessage.PerUnit)
+		}
+	}
+	for i := 1; i < len(schedules); i++ {
+		prev, s := schedules[i-1], schedules[i]
+		if s.Epoch <= prev.Epoch || s.Version <= prev.Version {
+			return fmt.Errorf("gas schedule %d at epoch %d must follow schedule %d at epoch %d", s.Version, s.Epoch, prev.Version, prev.Epoch)
+		}
+	}
+	return nil
+}
+
+type epochPricelist struct {
+	epoch     abi.ChainEpoch
+	pricelist Pricelist
+}
+
+func pricelists(schedules []Schedule) []epochPricelist {
+	pls := make([]epochPricelist, len(schedules))
+	for i := range schedules {
+		pls[i] = epochPricelist{epoch: schedules[i].Epoch, pricelist: NewPricelist(schedules[i])}
+	}
+	return pls
+}
@@ -0,0 +1,71 @@
+package gascost
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/internal/message"
+)
+
+func TestSchedules(t *testing.T) {
+	tf.UnitTest(t)
+
+	upgraded := DefaultSchedules[0]
+	upgraded.Version = 1
+	upgraded.Epoch = 100
+	upgraded.IpldGet = LinearCost{Base: gas.NewGas(30), PerUnit: gas.NewGas(4)}
+
+	t.Log("schedules round trip through JSON")
+	raw, err := json.Marshal([]Schedule{DefaultSchedules[0], upgraded})
+	require.NoError(t, err)
+	schedules, err := ReadSchedules(bytes.NewReader(raw))
+	require.NoError(t, err)
+	require.Equal(t, []Schedule{DefaultSchedules[0], upgraded}, schedules)
+
+	t.Log("a schedule prices by its costs")
+	assert.Equal(t, gas.NewGas(12), NewPricelist(schedules[0]).OnIpldGet(2))
+	assert.Equal(t, gas.NewGas(38), NewPricelist(schedules[1]).OnIpldGet(2))
+
+	t.Log("the default schedules are in effect from their epoch")
+	assert.Equal(t, gas.NewGas(12), PricelistByEpoch(0).OnIpldGet(2))
+	assert.Equal(t, gas.NewGas(12), PricelistByEpoch(1000).OnIpldGet(2))
+
+	t.Log("schedules must start at epoch 0 and increase")
+	assert.Error(t, ValidateSchedules(nil))
+	assert.Error(t, ValidateSchedules([]Schedule{upgraded}))
+	assert.Error(t, ValidateSchedules([]Schedule{upgraded, DefaultSchedules[0]}))
+
+	t.Log("and price messages as message validation does")
+	upgraded.OnChainMessage = LinearCost{Base: gas.NewGas(1), PerUnit: gas.NewGas(2)}
+	assert.Error(t, ValidateSchedules([]Schedule{DefaultSchedules[0], upgraded}))
+}
+
+func TestDefaultSchedulePrices(t *testing.T) {
+	tf.UnitTest(t)
+
+	// the prices of the pricelist the schedules replaced, which chains were
+	// built with
+	pl := PricelistByEpoch(0)
+	assert.Equal(t, gas.NewGas(200), pl.OnChainMessage(100))
+	assert.Equal(t, gas.NewGas(80), pl.OnChainReturnValue(&message.Receipt{ReturnValue: make([]byte, 10)}))
+	assert.Equal(t, gas.NewGas(10), pl.OnMethodInvocation(abi.NewTokenAmount(1), builtin.MethodSend))
+	assert.Equal(t, gas.NewGas(20), pl.OnMethodInvocation(abi.NewTokenAmount(1), builtin.MethodConstructor))
+	assert.Equal(t, gas.NewGas(110), pl.OnIpldGet(100))
+	assert.Equal(t, gas.NewGas(220), pl.OnIpldPut(100))
+	assert.Equal(t, gas.NewGas(540), pl.OnCreateActor())
+	assert.Equal(t, gas.NewGas(-500), pl.OnDeleteActor())
+	cost, err := pl.OnVerifySignature(crypto.SigTypeBLS, 10)
+	require.NoError(t, err)
+	assert.Equal(t, gas.NewGas(32), cost)
+	assert.Equal(t, gas.NewGas(25), pl.OnHashing(10))
+	assert.Equal(t, gas.NewGas(10), pl.OnVerifyConsensusFault())
+}
@@ -24,6 +24,8 @@ type VMStorage struct {
 	writeBuffer      map[cid.Cid]ipld.Node
 	readCache        map[cid.Cid]blocks.Block
 	readCacheEnabled bool
+	// parent is the storage a fork reads through, nil unless forked.
+	parent *VMStorage
 }
 
 // ErrNotFound is returned by storage when no object matches a requested Cid.
@@ -44,6 +46,25 @@ func NewStorage(bs blockstore.Blockstore) VMStorage {
 	}
 }
 
+// Fork returns a storage reading through s and buffering its own writes, to
+// apply messages concurrently with other forks. s must not be written to
+// while forks are in use.
+func (s *VMStorage) Fork() *VMStorage {
+	return &VMStorage{
+		blockstore:  s.blockstore,
+		writeBuffer: map[cid.Cid]ipld.Node{},
+		readCache:   map[cid.Cid]blocks.Block{},
+		parent:      s,
+	}
+}
+
+// Merge adds the buffered writes of a fork of s to the writes of s.
+func (s *VMStorage) Merge(fork *VMStorage) {
+	for c, nd := range fork.writeBuffer {
+		s.writeBuffer[c] = nd
+	}
+}
+
 // SetReadCache enable/disables the read chache.
 func (s *VMStorage) SetReadCache(enabled bool) {
 	s.readCacheEnabled = enabled
@@ -103,6 +124,14 @@ func (s *VMStorage) GetRaw(ctx context.Context, cid cid.Cid) ([]byte, error) {
 		}
 	}
 
+	if s.parent != nil {
+		// the parent is not written to while forked, its write buffer is safe
+		// to read concurrently
+		if n, ok := s.parent.writeBuffer[cid]; ok {
+			return n.RawData(), nil
+		}
+	}
+
 	// read from store
 	blk, err := s.blockstore.Get(cid)
 	if err != nil {
@@ -499,7 +499,10 @@ func (ctx *invocationContext) NewActorAddress() address.Address {
 // CreateActor implements runtime.ExtendedInvocationContext.
 func (ctx *invocationContext) CreateActor(codeID cid.Cid, addr address.Address) {
 	if !builtin.IsBuiltinActor(codeID) {
-		runtime.Abortf(exitcode.SysErrorIllegalArgument, "Can only create built-in actors.")
+		// experimental actors shipped with the node may be created too
+		if _, err := ctx.rt.actorImpls.GetActorImpl(codeID); err != nil {
+			runtime.Abortf(exitcode.SysErrorIllegalArgument, "Can only create built-in actors.")
+		}
 	}
 
 	if builtin.IsSingletonActor(codeID) {
@@ -0,0 +1,336 @@
+package vmcontext
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/actor"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/internal/message"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/state"
+)
+
+// blockMessage is a message of a block to apply.
+type blockMessage struct {
+	msg         *types.UnsignedMessage
+	onChainSize int
+}
+
+// forkableTree is a state tree that can be forked to apply messages
+// concurrently.
+type forkableTree interface {
+	state.Tree
+	Fork(ctx context.Context) (*state.State, error)
+}
+
+// parallelBlocks and serialFallbacks count the blocks applied by VMs with more
+// than one message worker, in parallel and serially after all.
+var parallelBlocks, serialFallbacks uint64
+
+// ParallelApplications returns the number of blocks applied in parallel and
+// of those falling back to serial application, by VMs with more than one
+// message worker since the process started.
+func ParallelApplications() (parallel uint64, serial uint64) {
+	return atomic.LoadUint64(&parallelBlocks), atomic.LoadUint64(&serialFallbacks)
+}
+
+// applyBlockMessages applies the messages of a block, returning their receipts
+// and the total miner penalty and gas reward.
+//
+// With more than one message worker, the messages of distinct senders are
+// applied in parallel, each sender's messages in order on a fork of the state.
+// The forks are merged when no fork wrote an actor another fork read or wrote,
+// so that the result is that of applying the messages serially. Otherwise the
+// forks are discarded and the messages applied serially.
+func (vm *VM) applyBlockMessages(msgs []blockMessage, rnd crypto.RandomnessSource) ([]message.Receipt, minerPenaltyFIL, gasRewardFIL) {
+	if vm.messageWorkers > 1 {
+		if res, ok := vm.applyParallel(msgs, rnd); ok {
+			atomic.AddUint64(&parallelBlocks, 1)
+			return res.receipts, res.minerPenalty, res.gasReward
+		}
+		atomic.AddUint64(&serialFallbacks, 1)
+	}
+
+	receipts := make([]message.Receipt, len(msgs))
+	minerPenaltyTotal := big.Zero()
+	minerGasRewardTotal := big.Zero()
+	for i, m := range msgs {
+		receipt, minerPenaltyCurr, minerGasRewardCurr := vm.applyMessage(m.msg, m.onChainSize, rnd)
+
+		// accumulate result
+		minerPenaltyTotal = big.Add(minerPenaltyTotal, minerPenaltyCurr)
+		minerGasRewardTotal = big.Add(minerGasRewardTotal, minerGasRewardCurr)
+		receipts[i] = receipt
+	}
+	return receipts, minerPenaltyTotal, minerGasRewardTotal
+}
+
+type parallelResult struct {
+	receipts     []message.Receipt
+	minerPenalty minerPenaltyFIL
+	gasReward    gasRewardFIL
+}
+
+func (vm *VM) applyParallel(msgs []blockMessage, rnd crypto.RandomnessSource) (*parallelResult, bool) {
+	tree, ok := vm.state.(forkableTree)
+	if !ok {
+		return nil, false
+	}
+
+	// group the messages by sender, keeping their order
+	// Note: a sender named by distinct addresses lands in distinct groups,
+	//       which conflict as both write the sender actor
+	var groups []*forkGroup
+	bySender := map[address.Address]*forkGroup{}
+	for i, m := range msgs {
+		g, ok := bySender[m.msg.From]
+		if !ok {
+			g = &forkGroup{}
+			bySender[m.msg.From] = g
+			groups = append(groups, g)
+		}
+		g.indices = append(g.indices, i)
+	}
+	if len(groups) < 2 {
+		return nil, false
+	}
+
+	// the forks read the state as of the start of the block
+	if _, err := vm.checkpoint(); err != nil {
+		panic(err)
+	}
+	for _, g := range groups {
+		fork, err := tree.Fork(vm.context)
+		if err != nil {
+			vmlog.Debugf("serial message application, failed to fork state: %s", err)
+			return nil, false
+		}
+		g.tree = newTrackingTree(fork)
+		g.vm = &VM{
+			context:      vm.context,
+			actorImpls:   vm.actorImpls,
+			store:        vm.store.Fork(),
+			state:        g.tree,
+			syscalls:     vm.syscalls,
+			currentHead:  vm.currentHead,
+			currentEpoch: vm.currentEpoch,
+			pricelist:    vm.pricelist,
+			prices:       vm.prices,
+		}
+	}
+
+	work := make(chan *forkGroup, len(groups))
+	for _, g := range groups {
+		work <- g
+	}
+	close(work)
+	var wg sync.WaitGroup
+	workers := vm.messageWorkers
+	if workers > len(groups) {
+		workers = len(groups)
+	}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for g := range work {
+				g.apply(msgs, rnd)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, g := range groups {
+		if g.failure != nil {
+			vmlog.Debugf("serial message application, a message failed in parallel: %v", g.failure)
+			return nil, false
+		}
+	}
+	if err := checkConflicts(groups); err != nil {
+		vmlog.Debugf("serial message application, %s", err)
+		return nil, false
+	}
+
+	// merge the forks
+	res := &parallelResult{
+		receipts:     make([]message.Receipt, len(msgs)),
+		minerPenalty: big.Zero(),
+		gasReward:    big.Zero(),
+	}
+	rewardActor, found, err := vm.state.GetActor(vm.context, builtin.RewardActorAddr)
+	if err != nil {
+		panic(err)
+	}
+	if !found {
+		panic("unreachable: no reward actor")
+	}
+	rewardBalance := rewardActor.Balance
+	for _, g := range groups {
+		for j, i := range g.indices {
+			res.receipts[i] = g.receipts[j]
+			res.minerPenalty = big.Add(res.minerPenalty, g.penalties[j])
+			res.gasReward = big.Add(res.gasReward, g.rewards[j])
+		}
+		for addr := range g.tree.writes {
+			act, found, err := g.tree.GetActor(vm.context, addr)
+			if err != nil {
+				panic(err)
+			}
+			if addr == builtin.RewardActorAddr {
+				rewardBalance = big.Add(rewardBalance, big.Sub(act.Balance, rewardActor.Balance))
+				continue
+			}
+			if !found {
+				err = vm.state.DeleteActor(vm.context, addr)
+			} else {
+				err = vm.state.SetActor(vm.context, addr, act)
+			}
+			if err != nil {
+				panic(err)
+			}
+		}
+		vm.store.Merge(g.vm.store)
+	}
+	if !rewardBalance.Equals(rewardActor.Balance) {
+		rewardActor.Balance = rewardBalance
+		if err := vm.state.SetActor(vm.context, builtin.RewardActorAddr, rewardActor); err != nil {
+			panic(err)
+		}
+	}
+	return res, true
+}
+
+// forkGroup is the messages of a sender, applied in order on a fork.
+type forkGroup struct {
+	indices []int
+	tree    *trackingTree
+	vm      *VM
+
+	receipts  []message.Receipt
+	penalties []minerPenaltyFIL
+	rewards   []gasRewardFIL
+	// failure is set when applying a message panicked
+	failure interface{}
+}
+
+func (g *forkGroup) apply(msgs []blockMessage, rnd crypto.RandomnessSource) {
+	defer func() {
+		if r := recover(); r != nil {
+			g.failure = r
+		}
+	}()
+	for _, i := range g.indices {
+		// the message is copied as applying it normalizes its sender, which
+		// must not be seen by a serial fallback
+		msg := *msgs[i].msg
+		receipt, penalty, reward := g.vm.applyMessage(&msg, msgs[i].onChainSize, rnd)
+		g.receipts = append(g.receipts, receipt)
+		g.penalties = append(g.penalties, penalty)
+		g.rewards = append(g.rewards, reward)
+	}
+}
+
+// checkConflicts returns an error when an actor written by a group was read
+// or written by another. The reward actor is exempt as long as only its
+// balance is written, the gas every message deposits and refunds adding up
+// in any order.
+func checkConflicts(groups []*forkGroup) error {
+	for i, g := range groups {
+		if reward, ok := g.tree.writes[builtin.RewardActorAddr]; ok {
+			act, _, err := g.tree.GetActor(context.Background(), builtin.RewardActorAddr)
+			if err != nil {
+				return err
+			}
+			if reward == nil || act == nil || !act.Head.Equals(reward.Head.Cid) || !act.Code.Equals(reward.Code.Cid) || act.CallSeqNum != reward.CallSeqNum {
+				return fmt.Errorf("the state of the reward actor was written")
+			}
+		}
+		for addr := range g.tree.writes {
+			if addr == builtin.RewardActorAddr {
+				continue
+			}
+			for j, h := range groups {
+				if i == j {
+					continue
+				}
+				if h.tree.touched(addr) {
+					return fmt.Errorf("actor %s is written by a sender and used by another", addr)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// trackingTree records the actors read and written through a tree. The
+// initial value of each written actor is recorded, nil when it did not exist.
+type trackingTree struct {
+	*state.State
+	reads  map[address.Address]struct{}
+	writes map[address.Address]*actor.Actor
+	// readAll is set when all the actors were read
+	readAll bool
+}
+
+func newTrackingTree(st *state.State) *trackingTree {
+	return &trackingTree{
+		State:  st,
+		reads:  map[address.Address]struct{}{},
+		writes: map[address.Address]*actor.Actor{},
+	}
+}
+
+var _ state.Tree = (*trackingTree)(nil)
+
+func (t *trackingTree) touched(addr address.Address) bool {
+	_, read := t.reads[addr]
+	_, written := t.writes[addr]
+	return t.readAll || read || written
+}
+
+// GetActor implements state.Tree.
+func (t *trackingTree) GetActor(ctx context.Context, key address.Address) (*actor.Actor, bool, error) {
+	t.reads[key] = struct{}{}
+	return t.State.GetActor(ctx, key)
+}
+
+// SetActor implements state.Tree.
+func (t *trackingTree) SetActor(ctx context.Context, key address.Address, a *actor.Actor) error {
+	if err := t.recordWrite(ctx, key); err != nil {
+		return err
+	}
+	return t.State.SetActor(ctx, key, a)
+}
+
+// DeleteActor implements state.Tree.
+func (t *trackingTree) DeleteActor(ctx context.Context, key address.Address) error {
+	if err := t.recordWrite(ctx, key); err != nil {
+		return err
+	}
+	return t.State.DeleteActor(ctx, key)
+}
+
+// GetAllActors implements state.Tree.
+func (t *trackingTree) GetAllActors(ctx context.Context) <-chan state.GetAllActorsResult {
+	t.readAll = true
+	return t.State.GetAllActors(ctx)
+}
+
+func (t *trackingTree) recordWrite(ctx context.Context, key address.Address) error {
+	if _, ok := t.writes[key]; ok {
+		return nil
+	}
+	prev, _, err := t.State.GetActor(ctx, key)
+	if err != nil {
+		return err
+	}
+	t.writes[key] = prev
+	return nil
+}
@@ -0,0 +1,42 @@
+package vmcontext
+
+import (
+	"fmt"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/internal/message"
+)
+
+// PreviewMessages applies messages in order as if they were included in a
+// block at epoch on top of head, to learn their receipts before they are sent.
+// No block reward is paid, cron does not run and the state is not committed,
+// so the state of the VM must be discarded afterwards.
+func (vm *VM) PreviewMessages(msgs []*types.SignedMessage, head block.TipSetKey, epoch abi.ChainEpoch, rnd crypto.RandomnessSource) (receipts []message.Receipt, err error) {
+	// applying a message panics on failures of the state, rather than of the message
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to apply message: %v", r)
+		}
+	}()
+
+	vm.currentHead = head
+	vm.currentEpoch = epoch
+	vm.pricelist = vm.pricelistAt(epoch)
+
+	for _, sm := range msgs {
+		// the on-chain size of BLS messages does not include the signature
+		m := sm.Message
+		size := sm.OnChainLen()
+		if m.From.Protocol() == address.BLS {
+			size = m.OnChainLen()
+		}
+		receipt, _, _ := vm.applyMessage(&m, size, rnd)
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
@@ -46,6 +46,12 @@ type VM struct {
 	currentHead  block.TipSetKey
 	currentEpoch abi.ChainEpoch
 	pricelist    gascost.Pricelist
+	// prices looks up the pricelist in effect at an epoch, the default
+	// schedules are used when nil.
+	prices func(abi.ChainEpoch) gascost.Pricelist
+	// messageWorkers is the number of goroutines applying the messages of a
+	// block, they are applied serially when not greater than 1.
+	messageWorkers int
 }
 
 // ActorImplLookup provides access to upgradeable actor code.
@@ -79,11 +85,30 @@ func NewVM(actorImpls ActorImplLookup, store *storage.VMStorage, st state.Tree,
 	}
 }
 
+// SetPrices sets the lookup of the pricelist in effect at an epoch, replacing
+// the default gas schedules.
+func (vm *VM) SetPrices(prices func(abi.ChainEpoch) gascost.Pricelist) {
+	vm.prices = prices
+}
+
+func (vm *VM) pricelistAt(epoch abi.ChainEpoch) gascost.Pricelist {
+	if vm.prices != nil {
+		return vm.prices(epoch)
+	}
+	return gascost.PricelistByEpoch(epoch)
+}
+
+// SetMessageWorkers sets the number of goroutines applying the messages of a
+// block from distinct senders in parallel.
+func (vm *VM) SetMessageWorkers(n int) {
+	vm.messageWorkers = n
+}
+
 // ApplyGenesisMessage forces the execution of a message in the vm actor.
 //
 // This method is intended to be used in the generation of the genesis block only.
 func (vm *VM) ApplyGenesisMessage(from address.Address, to address.Address, method abi.MethodNum, value abi.TokenAmount, params interface{}, rnd crypto.RandomnessSource) (interface{}, error) {
-	vm.pricelist = gascost.PricelistByEpoch(vm.currentEpoch)
+	vm.pricelist = vm.pricelistAt(vm.currentEpoch)
 
 	// normalize from addr
 	var ok bool
@@ -197,7 +222,7 @@ func (vm *VM) ApplyTipSetMessages(blocks []interpreter.BlockMessagesInfo, head b
 	// update current tipset
 	vm.currentHead = head
 	vm.currentEpoch = epoch
-	vm.pricelist = gascost.PricelistByEpoch(epoch)
+	vm.pricelist = vm.pricelistAt(epoch)
 
 	// create message tracker
 	// Note: the same message could have been included by more than one miner
@@ -209,32 +234,22 @@ func (vm *VM) ApplyTipSetMessages(blocks []interpreter.BlockMessagesInfo, head b
 			panic("precond failure: block miner address must be an IDAddress")
 		}
 
-		// initial miner penalty and gas rewards
-		// Note: certain msg execution failures can cause the miner to pay for the gas
-		minerPenaltyTotal := big.Zero()
-		minerGasRewardTotal := big.Zero()
+		// collect the messages of the block not included by earlier blocks
+		msgs := []blockMessage{}
 
-		// Process BLS messages from the block
+		// BLS messages from the block
 		for _, m := range blk.BLSMessages {
 			// do not recompute already seen messages
 			mcid := msgCID(m)
 			if _, found := seenMsgs[mcid]; found {
 				continue
 			}
-
-			// apply message
-			receipt, minerPenaltyCurr, minerGasRewardCurr := vm.applyMessage(m, m.OnChainLen(), rnd)
-
-			// accumulate result
-			minerPenaltyTotal = big.Add(minerPenaltyTotal, minerPenaltyCurr)
-			minerGasRewardTotal = big.Add(minerGasRewardTotal, minerGasRewardCurr)
-			receipts = append(receipts, receipt)
-
+			msgs = append(msgs, blockMessage{msg: m, onChainSize: m.OnChainLen()})
 			// flag msg as seen
 			seenMsgs[mcid] = struct{}{}
 		}
 
-		// Process SECP messages from the block
+		// SECP messages from the block
 		for _, sm := range blk.SECPMessages {
 			// extract unsigned message part
 			m := sm.Message
@@ -244,20 +259,17 @@ func (vm *VM) ApplyTipSetMessages(blocks []interpreter.BlockMessagesInfo, head b
 			if _, found := seenMsgs[mcid]; found {
 				continue
 			}
-
-			// apply message
 			// Note: the on-chain size for SECP messages is different
-			receipt, minerPenaltyCurr, minerGasRewardCurr := vm.applyMessage(&m, sm.OnChainLen(), rnd)
-
-			// accumulate result
-			minerPenaltyTotal = big.Add(minerPenaltyTotal, minerPenaltyCurr)
-			minerGasRewardTotal = big.Add(minerGasRewardTotal, minerGasRewardCurr)
-			receipts = append(receipts, receipt)
-
+			msgs = append(msgs, blockMessage{msg: &m, onChainSize: sm.OnChainLen()})
 			// flag msg as seen
 			seenMsgs[mcid] = struct{}{}
 		}
 
+		// apply messages
+		// Note: certain msg execution failures can cause the miner to pay for the gas
+		blkReceipts, minerPenaltyTotal, minerGasRewardTotal := vm.applyBlockMessages(msgs, rnd)
+		receipts = append(receipts, blkReceipts...)
+
 		// Pay block reward.
 		// Dragons: missing final protocol design on if/how to determine the nominal power
 		rewardMessage := makeBlockRewardMessage(blk.Miner, minerPenaltyTotal, minerGasRewardTotal, 1)
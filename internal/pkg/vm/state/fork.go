@@ -0,0 +1,54 @@
+package state
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/pkg/errors"
+)
+
+// Fork returns a tree at the last committed root of st, reading through the
+// store of st and keeping its own writes in memory, so that forks may be
+// modified concurrently. st must be committed and must not be modified while
+// its forks are in use.
+func (st *State) Fork(ctx context.Context) (*State, error) {
+	if st.dirty || !st.root.Defined() {
+		return nil, errors.New("cannot fork a tree with uncommitted changes")
+	}
+	return LoadState(ctx, newOverlayStore(st.store), st.root)
+}
+
+// overlayStore writes to memory and reads from memory then from a base store.
+type overlayStore struct {
+	base  cbor.IpldStore
+	mem   cbor.IpldStore
+	memBs blockstore.Blockstore
+}
+
+func newOverlayStore(base cbor.IpldStore) *overlayStore {
+	bs := blockstore.NewBlockstore(dssync.MutexWrap(datastore.NewMapDatastore()))
+	return &overlayStore{
+		base:  base,
+		mem:   cbor.NewCborStore(bs),
+		memBs: bs,
+	}
+}
+
+func (s *overlayStore) Get(ctx context.Context, c cid.Cid, out interface{}) error {
+	has, err := s.memBs.Has(c)
+	if err != nil {
+		return err
+	}
+	if has {
+		return s.mem.Get(ctx, c, out)
+	}
+	return s.base.Get(ctx, c, out)
+}
+
+func (s *overlayStore) Put(ctx context.Context, v interface{}) (cid.Cid, error) {
+	return s.mem.Put(ctx, v)
+}
@@ -1,10 +1,17 @@
 package vm
 
 import (
+	"io"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
 	blockstore "github.com/ipfs/go-ipfs-blockstore"
 
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/actor/builtin"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/internal/dispatch"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/internal/gascost"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/internal/interpreter"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/internal/message"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/internal/storage"
@@ -35,6 +42,24 @@ func NewVM(st state.Tree, store *storage.VMStorage, syscalls SyscallsImpl) Inter
 	return &vm
 }
 
+// NewParallelVM creates a new VM interpreter running the given actors, applying
+// the messages of a block from independent senders on up to workers goroutines.
+// Gas is priced by the pricelists looked up with prices, by the default gas
+// schedules when nil.
+func NewParallelVM(actors ActorCodeLoader, prices GasPrices, st state.Tree, store *storage.VMStorage, syscalls SyscallsImpl, workers int) Interpreter {
+	vm := vmcontext.NewVM(actors, store, st, syscalls)
+	vm.SetPrices(prices)
+	vm.SetMessageWorkers(workers)
+	return &vm
+}
+
+// ParallelApplications returns the number of blocks applied in parallel and
+// of those falling back to serial application, by VMs with more than one
+// message worker since the process started.
+func ParallelApplications() (parallel uint64, serial uint64) {
+	return vmcontext.ParallelApplications()
+}
+
 // NewStorage creates a new Storage for the VM.
 func NewStorage(bs blockstore.Blockstore) Storage {
 	return storage.NewStorage(bs)
@@ -43,8 +68,53 @@ func NewStorage(bs blockstore.Blockstore) Storage {
 // DefaultActors is a code loader with the built-in actors that come with the system.
 var DefaultActors = builtin.DefaultActors
 
+// DevnetActors is a code loader with the built-in actors and the experimental
+// devnet actors.
+var DevnetActors = builtin.DevnetActors
+
 // ActorCodeLoader allows yo to load an actor's code based on its id an epoch.
 type ActorCodeLoader = dispatch.CodeLoader
 
 // ActorMethodSignature wraps a specific method and allows you to encode/decodes input/output bytes into concrete types.
 type ActorMethodSignature = dispatch.MethodSignature
+
+// GasSchedule is a version of the gas costs of the VM, in effect from an epoch.
+type GasSchedule = gascost.Schedule
+
+// GasLinearCost is a gas cost growing linearly with the size of its input.
+type GasLinearCost = gascost.LinearCost
+
+// GasPricelist prices the operations of the VM.
+type GasPricelist = gascost.Pricelist
+
+// GasPrices looks up the pricelist in effect at an epoch.
+type GasPrices = func(abi.ChainEpoch) GasPricelist
+
+// DefaultGasSchedules are the gas schedules of the Filecoin network.
+var DefaultGasSchedules = gascost.DefaultSchedules
+
+// NewGasPricelist returns the pricelist of a gas schedule.
+func NewGasPricelist(schedule GasSchedule) GasPricelist {
+	return gascost.NewPricelist(schedule)
+}
+
+// ValidateGasSchedules checks that the first schedule is in effect from epoch
+// 0, that later ones have greater epochs and versions, and that all price
+// messages as message validation does.
+func ValidateGasSchedules(schedules []GasSchedule) error {
+	return gascost.ValidateSchedules(schedules)
+}
+
+// ReadGasSchedules reads gas schedules from their JSON encoding.
+func ReadGasSchedules(r io.Reader) ([]GasSchedule, error) {
+	return gascost.ReadSchedules(r)
+}
+
+// PreviewMessages applies messages in order to st as if they were included in
+// a block at epoch on top of head, returning their receipts. st and store are
+// modified, and must be discarded. Gas is priced as by NewParallelVM.
+func PreviewMessages(actors ActorCodeLoader, prices GasPrices, st state.Tree, store *storage.VMStorage, syscalls SyscallsImpl, msgs []*types.SignedMessage, head block.TipSetKey, epoch abi.ChainEpoch, rnd crypto.RandomnessSource) ([]MessageReceipt, error) {
+	vm := vmcontext.NewVM(actors, store, st, syscalls)
+	vm.SetPrices(prices)
+	return vm.PreviewMessages(msgs, head, epoch, rnd)
+}
@@ -0,0 +1,188 @@
+package wallet
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	ds "github.com/ipfs/go-datastore"
+	dsq "github.com/ipfs/go-datastore/query"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/repo"
+)
+
+// AddressBookDSPrefix is the prefix under which address labels are stored in the datastore.
+const AddressBookDSPrefix = "/addressbook"
+
+// ErrUnknownLabel is returned when a label is not present in the address book.
+var ErrUnknownLabel = errors.New("unknown address label")
+
+// labelRegexp restricts labels to a set of characters that can't be confused with
+// flags or datastore key separators.
+var labelRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// AddressBookEntry is a single label and the address it names.
+type AddressBookEntry struct {
+	Label   string
+	Address address.Address
+}
+
+// AddressBook maps human readable labels to addresses. Each label names exactly
+// one address and each address has at most one label.
+type AddressBook struct {
+	lk sync.RWMutex
+
+	ds repo.Datastore
+
+	labels    map[string]address.Address
+	addresses map[address.Address]string
+}
+
+// NewAddressBook loads an address book from the passed in datastore.
+func NewAddressBook(store repo.Datastore) (*AddressBook, error) {
+	result, err := store.Query(dsq.Query{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query address book")
+	}
+
+	list, err := result.Rest()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read address book entries")
+	}
+
+	ab := &AddressBook{
+		ds:        store,
+		labels:    make(map[string]address.Address),
+		addresses: make(map[address.Address]string),
+	}
+	for _, el := range list {
+		addr, err := address.NewFromBytes(el.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "trying to restore invalid address for label %s", el.Key)
+		}
+		label := strings.TrimPrefix(el.Key, "/")
+		ab.labels[label] = addr
+		ab.addresses[addr] = label
+	}
+
+	return ab, nil
+}
+
+// SetLabel assigns label to addr, replacing any label the address already had.
+// It is an error to reuse a label that names a different address.
+// Safe for concurrent access.
+func (ab *AddressBook) SetLabel(addr address.Address, label string) error {
+	if err := validateLabel(label); err != nil {
+		return err
+	}
+	if addr.Empty() {
+		return errors.New("cannot label an empty address")
+	}
+
+	ab.lk.Lock()
+	defer ab.lk.Unlock()
+
+	if existing, ok := ab.labels[label]; ok && existing != addr {
+		return errors.Errorf("label %s already names address %s", label, existing)
+	}
+
+	if old, ok := ab.addresses[addr]; ok && old != label {
+		if err := ab.ds.Delete(ds.NewKey(old)); err != nil {
+			return errors.Wrapf(err, "failed to remove previous label %s", old)
+		}
+		delete(ab.labels, old)
+	}
+
+	if err := ab.ds.Put(ds.NewKey(label), addr.Bytes()); err != nil {
+		return errors.Wrap(err, "failed to store address label")
+	}
+	ab.labels[label] = addr
+	ab.addresses[addr] = label
+	return nil
+}
+
+// RemoveLabel deletes a label from the address book.
+// Safe for concurrent access.
+func (ab *AddressBook) RemoveLabel(label string) error {
+	ab.lk.Lock()
+	defer ab.lk.Unlock()
+
+	addr, ok := ab.labels[label]
+	if !ok {
+		return errors.Wrap(ErrUnknownLabel, label)
+	}
+
+	if err := ab.ds.Delete(ds.NewKey(label)); err != nil {
+		return errors.Wrap(err, "failed to remove address label")
+	}
+	delete(ab.labels, label)
+	delete(ab.addresses, addr)
+	return nil
+}
+
+// Lookup returns the address named by label.
+// Safe for concurrent access.
+func (ab *AddressBook) Lookup(label string) (address.Address, error) {
+	ab.lk.RLock()
+	defer ab.lk.RUnlock()
+
+	addr, ok := ab.labels[label]
+	if !ok {
+		return address.Undef, errors.Wrap(ErrUnknownLabel, label)
+	}
+	return addr, nil
+}
+
+// Label returns the label for addr, if it has one.
+// Safe for concurrent access.
+func (ab *AddressBook) Label(addr address.Address) (string, bool) {
+	ab.lk.RLock()
+	defer ab.lk.RUnlock()
+
+	label, ok := ab.addresses[addr]
+	return label, ok
+}
+
+// Entries returns all labels in the address book, sorted by label.
+// Safe for concurrent access.
+func (ab *AddressBook) Entries() []AddressBookEntry {
+	ab.lk.RLock()
+	defer ab.lk.RUnlock()
+
+	out := make([]AddressBookEntry, 0, len(ab.labels))
+	for label, addr := range ab.labels {
+		out = append(out, AddressBookEntry{Label: label, Address: addr})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Label < out[j].Label
+	})
+	return out
+}
+
+// Resolve interprets s as an address string, falling back to looking it up as a label.
+// Safe for concurrent access.
+func (ab *AddressBook) Resolve(s string) (address.Address, error) {
+	addr, err := address.NewFromString(s)
+	if err == nil {
+		return addr, nil
+	}
+
+	addr, lookupErr := ab.Lookup(s)
+	if lookupErr != nil {
+		return address.Undef, errors.Errorf("%s is neither a valid address (%s) nor a known label", s, err)
+	}
+	return addr, nil
+}
+
+func validateLabel(label string) error {
+	if !labelRegexp.MatchString(label) {
+		return errors.Errorf("invalid label %q: labels must start with a letter or digit and contain only letters, digits, '.', '_' and '-'", label)
+	}
+	if _, err := address.NewFromString(label); err == nil {
+		return errors.Errorf("invalid label %q: labels must not be valid addresses", label)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package wallet
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/ipfs/go-datastore"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestAddressBookSetAndLookup(t *testing.T) {
+	tf.UnitTest(t)
+
+	ds := datastore.NewMapDatastore()
+	ab, err := NewAddressBook(ds)
+	require.NoError(t, err)
+
+	addr, err := address.NewSecp256k1Address([]byte("miner owner"))
+	require.NoError(t, err)
+
+	require.NoError(t, ab.SetLabel(addr, "my-miner-owner"))
+
+	found, err := ab.Lookup("my-miner-owner")
+	require.NoError(t, err)
+	assert.Equal(t, addr, found)
+
+	label, ok := ab.Label(addr)
+	assert.True(t, ok)
+	assert.Equal(t, "my-miner-owner", label)
+
+	t.Log("labels are restored when loading a fresh address book")
+	ab2, err := NewAddressBook(ds)
+	require.NoError(t, err)
+	found, err = ab2.Lookup("my-miner-owner")
+	require.NoError(t, err)
+	assert.Equal(t, addr, found)
+}
+
+func TestAddressBookRelabel(t *testing.T) {
+	tf.UnitTest(t)
+
+	ab, err := NewAddressBook(datastore.NewMapDatastore())
+	require.NoError(t, err)
+
+	addr1, err := address.NewSecp256k1Address([]byte("one"))
+	require.NoError(t, err)
+	addr2, err := address.NewSecp256k1Address([]byte("two"))
+	require.NoError(t, err)
+
+	require.NoError(t, ab.SetLabel(addr1, "first"))
+
+	t.Log("relabeling an address replaces its old label")
+	require.NoError(t, ab.SetLabel(addr1, "renamed"))
+	_, err = ab.Lookup("first")
+	assert.True(t, errors.Is(err, ErrUnknownLabel))
+	assert.Len(t, ab.Entries(), 1)
+
+	t.Log("a label cannot name two addresses")
+	assert.Error(t, ab.SetLabel(addr2, "renamed"))
+
+	t.Log("removed labels no longer resolve")
+	require.NoError(t, ab.RemoveLabel("renamed"))
+	_, ok := ab.Label(addr1)
+	assert.False(t, ok)
+	assert.Error(t, ab.RemoveLabel("renamed"))
+}
+
+func TestAddressBookResolve(t *testing.T) {
+	tf.UnitTest(t)
+
+	ab, err := NewAddressBook(datastore.NewMapDatastore())
+	require.NoError(t, err)
+
+	addr, err := address.NewSecp256k1Address([]byte("resolve"))
+	require.NoError(t, err)
+	require.NoError(t, ab.SetLabel(addr, "target"))
+
+	resolved, err := ab.Resolve(addr.String())
+	require.NoError(t, err)
+	assert.Equal(t, addr, resolved)
+
+	resolved, err = ab.Resolve("target")
+	require.NoError(t, err)
+	assert.Equal(t, addr, resolved)
+
+	_, err = ab.Resolve("nobody")
+	assert.Error(t, err)
+}
+
+func TestAddressBookInvalidLabels(t *testing.T) {
+	tf.UnitTest(t)
+
+	ab, err := NewAddressBook(datastore.NewMapDatastore())
+	require.NoError(t, err)
+
+	addr, err := address.NewSecp256k1Address([]byte("invalid"))
+	require.NoError(t, err)
+
+	assert.Error(t, ab.SetLabel(addr, ""))
+	assert.Error(t, ab.SetLabel(addr, "-flag"))
+	assert.Error(t, ab.SetLabel(addr, "a/b"))
+	assert.Error(t, ab.SetLabel(addr, addr.String()))
+	assert.Error(t, ab.SetLabel(address.Undef, "empty"))
+}
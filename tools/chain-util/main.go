@@ -54,7 +54,7 @@ var exportCmd = &cli.Command{
 func main() {
 	app := &cli.App{
 		Name:     "chain-export",
-		Commands: []*cli.Command{exportCmd},
+		Commands: []*cli.Command{exportCmd, runVectorsCmd},
 	}
 	app.Setup()
 
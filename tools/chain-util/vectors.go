@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/filecoin-project/sector-storage/ffiwrapper"
+	"github.com/filecoin-project/specs-actors/actors/runtime"
+	cli "gopkg.in/urfave/cli.v2"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/conformance"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/slashing"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vmsupport"
+)
+
+var runVectorsCmd = &cli.Command{
+	Name:      "run-vectors",
+	Usage:     "Run test vectors exported with `go-filecoin chain export-vector`",
+	ArgsUsage: "<vector files>",
+	Action: func(cctx *cli.Context) error {
+		if cctx.NArg() == 0 {
+			return fmt.Errorf("vector files required")
+		}
+		syscalls := vmsupport.NewSyscalls(noFaultChecker{}, ffiwrapper.ProofVerifier)
+
+		failed := 0
+		for _, path := range cctx.Args().Slice() {
+			passed, err := runVector(path, syscalls)
+			if err != nil {
+				return fmt.Errorf("%s: %s", path, err)
+			}
+			if !passed {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d vectors failed", failed, cctx.NArg())
+		}
+		return nil
+	},
+}
+
+func runVector(path string, syscalls *vmsupport.Syscalls) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = f.Close() }()
+	v, err := conformance.ReadVector(f)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := conformance.Run(context.Background(), v, syscalls)
+	if err != nil {
+		return false, err
+	}
+	if result.Passed() {
+		fmt.Printf("PASS %s (epoch %d, %d messages)\n", path, v.Epoch, v.Messages())
+		return true, nil
+	}
+	fmt.Printf("FAIL %s (epoch %d, %d messages)\n", path, v.Epoch, v.Messages())
+	for _, failure := range result.Failures {
+		fmt.Printf("\t%s\n", failure)
+	}
+	return false, nil
+}
+
+// noFaultChecker rejects consensus fault reports, which need the chain the
+// vectors are run without.
+type noFaultChecker struct{}
+
+func (noFaultChecker) VerifyConsensusFault(_ context.Context, _, _, _ []byte, _ block.TipSetKey, _ slashing.FaultStateView) (*runtime.ConsensusFault, error) {
+	return nil, fmt.Errorf("consensus faults cannot be verified without a chain")
+}
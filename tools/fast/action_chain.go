@@ -3,9 +3,12 @@ package fast
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	"github.com/ipfs/go-cid"
+	files "github.com/ipfs/go-ipfs-files"
 
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
 	"github.com/filecoin-project/go-filecoin/internal/pkg/chain"
 )
 
@@ -19,6 +22,12 @@ func (f *Filecoin) ChainHead(ctx context.Context) ([]cid.Cid, error) {
 
 }
 
+// ChainHeadWatch runs the chain head command with --watch against the filecoin
+// process. The command streams the key of each new head until `ctx` is done.
+func (f *Filecoin) ChainHeadWatch(ctx context.Context) (*json.Decoder, error) {
+	return f.RunCmdLDJSONWithStdin(ctx, nil, "go-filecoin", "chain", "head", "--watch")
+}
+
 // ChainLs runs the chain ls command against the filecoin process.
 func (f *Filecoin) ChainLs(ctx context.Context) (*json.Decoder, error) {
 	return f.RunCmdLDJSONWithStdin(ctx, nil, "go-filecoin", "chain", "ls")
@@ -32,3 +41,46 @@ func (f *Filecoin) ChainStatus(ctx context.Context) (*chain.Status, error) {
 	}
 	return out, nil
 }
+
+// ChainExport runs the chain export command against the filecoin process,
+// writing the chain from `head` to the genesis to the file at `path`, which is
+// opened by the process.
+func (f *Filecoin) ChainExport(ctx context.Context, path string, head []cid.Cid) error {
+	args := []string{"go-filecoin", "chain", "export", path}
+	for _, c := range head {
+		args = append(args, c.String())
+	}
+	return f.runCmdNoOutput(ctx, args...)
+}
+
+// ChainImport runs the chain import command against the filecoin process,
+// returning the key of the head of the imported chain.
+func (f *Filecoin) ChainImport(ctx context.Context, file files.File) (block.TipSetKey, error) {
+	var out block.TipSetKey
+	if err := f.RunCmdJSONWithStdin(ctx, file, &out, "go-filecoin", "chain", "import"); err != nil {
+		return block.UndefTipSet.Key(), err
+	}
+	return out, nil
+}
+
+// ChainSetHead runs the chain set-head command against the filecoin process.
+func (f *Filecoin) ChainSetHead(ctx context.Context, head block.TipSetKey) error {
+	args := []string{"go-filecoin", "chain", "set-head"}
+	for _, c := range head.ToSlice() {
+		args = append(args, c.String())
+	}
+	return f.runCmdNoOutput(ctx, args...)
+}
+
+func (f *Filecoin) runCmdNoOutput(ctx context.Context, args ...string) error {
+	out, err := f.RunCmdWithStdin(ctx, nil, args...)
+	if err != nil {
+		return err
+	}
+
+	if out.ExitCode() > 0 {
+		return fmt.Errorf("filecoin command: %s, exited with non-zero exitcode: %d", out.Args(), out.ExitCode())
+	}
+
+	return nil
+}
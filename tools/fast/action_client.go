@@ -13,6 +13,8 @@ import (
 	files "github.com/ipfs/go-ipfs-files"
 
 	commands "github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net/bidsub"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/pin"
 )
 
 // ClientCat runs the client cat command against the filecoin process.
@@ -83,3 +85,57 @@ func (f *Filecoin) ClientVerifyStorageDeal(ctx context.Context, prop cid.Cid) (*
 func (f *Filecoin) ClientListAsks(ctx context.Context) (*json.Decoder, error) {
 	return f.RunCmdLDJSONWithStdin(ctx, nil, "go-filecoin", "client", "list-asks")
 }
+
+// ClientPin runs the client pin command against the filecoin process.
+func (f *Filecoin) ClientPin(ctx context.Context, data cid.Cid) error {
+	return f.runClientPinCmd(ctx, "pin", data)
+}
+
+// ClientUnpin runs the client unpin command against the filecoin process.
+func (f *Filecoin) ClientUnpin(ctx context.Context, data cid.Cid) error {
+	return f.runClientPinCmd(ctx, "unpin", data)
+}
+
+func (f *Filecoin) runClientPinCmd(ctx context.Context, cmd string, data cid.Cid) error {
+	out, err := f.RunCmdWithStdin(ctx, nil, "go-filecoin", "client", cmd, data.String())
+	if err != nil {
+		return err
+	}
+	if out.ExitCode() > 0 {
+		return fmt.Errorf("filecoin command: %s, exited with non-zero exitcode: %d", out.Args(), out.ExitCode())
+	}
+	return nil
+}
+
+// ClientGC runs the client gc command against the filecoin process.
+func (f *Filecoin) ClientGC(ctx context.Context, options ...ActionOption) (*pin.GCResult, error) {
+	var out pin.GCResult
+
+	args := []string{"go-filecoin", "client", "gc"}
+	for _, opt := range options {
+		args = append(args, opt()...)
+	}
+
+	if err := f.RunCmdJSONWithStdin(ctx, nil, &out, args...); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ClientRequestStorage runs the client request-storage command against the filecoin process.
+func (f *Filecoin) ClientRequestStorage(ctx context.Context, size uint64, duration uint64, maxPrice string) (*bidsub.SignedRequest, error) {
+	var out bidsub.SignedRequest
+
+	sSize := fmt.Sprintf("%d", size)
+	sDuration := fmt.Sprintf("%d", duration)
+	if err := f.RunCmdJSONWithStdin(ctx, nil, &out, "go-filecoin", "client", "request-storage", sSize, sDuration, maxPrice); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ClientStorageBids runs the client storage-bids command against the filecoin process.
+// A json decoder is returned that bids may be decoded from.
+func (f *Filecoin) ClientStorageBids(ctx context.Context, request cid.Cid) (*json.Decoder, error) {
+	return f.RunCmdLDJSONWithStdin(ctx, nil, "go-filecoin", "client", "storage-bids", request.String())
+}
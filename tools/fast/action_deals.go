@@ -3,6 +3,10 @@ package fast
 import (
 	"context"
 	"encoding/json"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/protocol/storage/reconcile"
 )
 
 // DealsList runs the `deals list` command against the filecoin process
@@ -15,3 +19,14 @@ func (f *Filecoin) DealsList(ctx context.Context, options ...ActionOption) (*jso
 
 	return f.RunCmdLDJSONWithStdin(ctx, nil, args...)
 }
+
+// DealsReconcile runs the `deals reconcile` command against the filecoin process
+func (f *Filecoin) DealsReconcile(ctx context.Context, minerAddr address.Address) (*reconcile.Report, error) {
+	var out reconcile.Report
+
+	if err := f.RunCmdJSONWithStdin(ctx, nil, &out, "go-filecoin", "deals", "reconcile", "--miner", minerAddr.String()); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
@@ -10,6 +10,7 @@ import (
 
 	commands "github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
 	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/scrubber"
 )
 
 // MinerCreate runs the `miner create` command against the filecoin process
@@ -100,3 +101,51 @@ func (f *Filecoin) MinerSetWorker(ctx context.Context, newAddr address.Address,
 	}
 	return out, nil
 }
+
+// MinerRotateWorkerPropose runs the `miner rotate-worker propose` command
+// against the filecoin process, with a new key when newAddr is undefined
+func (f *Filecoin) MinerRotateWorkerPropose(ctx context.Context, newAddr address.Address, options ...ActionOption) (porcelain.MinerWorkerRotation, error) {
+	var out porcelain.MinerWorkerRotation
+
+	args := []string{"go-filecoin", "miner", "rotate-worker", "propose"}
+
+	for _, option := range options {
+		args = append(args, option()...)
+	}
+
+	if !newAddr.Empty() {
+		args = append(args, newAddr.String())
+	}
+
+	if err := f.RunCmdJSONWithStdin(ctx, nil, &out, args...); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// MinerRotateWorkerConfirm runs the `miner rotate-worker confirm` command
+// against the filecoin process, for the proposal message
+func (f *Filecoin) MinerRotateWorkerConfirm(ctx context.Context, proposal cid.Cid) (porcelain.MinerWorkerRotation, error) {
+	var out porcelain.MinerWorkerRotation
+
+	if err := f.RunCmdJSONWithStdin(ctx, nil, &out, "go-filecoin", "miner", "rotate-worker", "confirm", proposal.String()); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// MinerScrub runs the `miner scrub` command against the filecoin process,
+// checking the sectors with `--now` when now is set
+func (f *Filecoin) MinerScrub(ctx context.Context, now bool) (scrubber.Report, error) {
+	var out scrubber.Report
+
+	args := []string{"go-filecoin", "miner", "scrub"}
+	if now {
+		args = append(args, "--now")
+	}
+
+	if err := f.RunCmdJSONWithStdin(ctx, nil, &out, args...); err != nil {
+		return out, err
+	}
+	return out, nil
+}
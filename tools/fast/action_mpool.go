@@ -2,6 +2,7 @@ package fast
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
 )
@@ -22,3 +23,9 @@ func (f *Filecoin) MpoolLs(ctx context.Context, options ...ActionOption) ([]*typ
 
 	return out, nil
 }
+
+// MpoolWatch runs the `mpool watch` command against the filecoin process. The
+// command streams message.PoolEvent values until `ctx` is done.
+func (f *Filecoin) MpoolWatch(ctx context.Context) (*json.Decoder, error) {
+	return f.RunCmdLDJSONWithStdin(ctx, nil, "go-filecoin", "mpool", "watch")
+}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/filecoin-project/go-address"
 	"github.com/ipfs/go-cid"
+
+	commands "github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
 )
 
 // RetrievalClientRetrievePiece runs the retrieval-client retrieve-piece commands against the filecoin process.
@@ -20,3 +22,19 @@ func (f *Filecoin) RetrievalClientRetrievePiece(ctx context.Context, pieceCID ci
 	}
 	return out.Stdout(), nil
 }
+
+// RetrievalClientRetrieve runs the retrieval-client retrieve command against the filecoin process.
+func (f *Filecoin) RetrievalClientRetrieve(ctx context.Context, payload cid.Cid, options ...ActionOption) (*commands.RetrieveResult, error) {
+	var out commands.RetrieveResult
+
+	args := []string{"go-filecoin", "retrieval-client", "retrieve", payload.String()}
+	for _, option := range options {
+		args = append(args, option()...)
+	}
+
+	if err := f.RunCmdJSONWithStdin(ctx, nil, &out, args...); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
@@ -0,0 +1,55 @@
+package fast
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/state"
+)
+
+// StatePower runs the `state power` command against the filecoin process
+func (f *Filecoin) StatePower(ctx context.Context) (*porcelain.PowerTable, error) {
+	var out porcelain.PowerTable
+
+	if err := f.RunCmdJSONWithStdin(ctx, nil, &out, "go-filecoin", "state", "power"); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// StatePowerHistory runs the `state power-history` command against the filecoin process
+func (f *Filecoin) StatePowerHistory(ctx context.Context, minerAddr address.Address, options ...ActionOption) ([]porcelain.PowerHistoryEntry, error) {
+	var out []porcelain.PowerHistoryEntry
+
+	args := []string{"go-filecoin", "state", "power-history", minerAddr.String()}
+
+	for _, option := range options {
+		args = append(args, option()...)
+	}
+
+	if err := f.RunCmdJSONWithStdin(ctx, nil, &out, args...); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// StateReadActor runs the `state read-actor` command against the filecoin process
+func (f *Filecoin) StateReadActor(ctx context.Context, addr address.Address, options ...ActionOption) (*state.ActorStateReport, error) {
+	var out state.ActorStateReport
+
+	args := []string{"go-filecoin", "state", "read-actor", addr.String()}
+
+	for _, option := range options {
+		args = append(args, option()...)
+	}
+
+	if err := f.RunCmdJSONWithStdin(ctx, nil, &out, args...); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
@@ -42,3 +42,55 @@ func (f *Filecoin) SwarmPeers(ctx context.Context, options ...ActionOption) ([]n
 
 	return out.Peers, nil
 }
+
+// SwarmThrottle runs the `swarm throttle` command against the filecoin process
+func (f *Filecoin) SwarmThrottle(ctx context.Context, options ...ActionOption) (net.TransferLimits, error) {
+	var out net.TransferLimits
+
+	args := []string{"go-filecoin", "swarm", "throttle"}
+
+	for _, option := range options {
+		args = append(args, option()...)
+	}
+
+	if err := f.RunCmdJSONWithStdin(ctx, nil, &out, args...); err != nil {
+		return net.TransferLimits{}, err
+	}
+
+	return out, nil
+}
+
+// SwarmBan runs the `swarm ban` command against the filecoin process
+func (f *Filecoin) SwarmBan(ctx context.Context, p peer.ID, options ...ActionOption) error {
+	var out peer.ID
+
+	args := []string{"go-filecoin", "swarm", "ban", p.String()}
+
+	for _, option := range options {
+		args = append(args, option()...)
+	}
+
+	return f.RunCmdJSONWithStdin(ctx, nil, &out, args...)
+}
+
+// SwarmUnban runs the `swarm unban` command against the filecoin process
+func (f *Filecoin) SwarmUnban(ctx context.Context, p peer.ID) error {
+	var out peer.ID
+
+	args := []string{"go-filecoin", "swarm", "unban", p.String()}
+
+	return f.RunCmdJSONWithStdin(ctx, nil, &out, args...)
+}
+
+// SwarmLsBanned runs the `swarm ls-banned` command against the filecoin process
+func (f *Filecoin) SwarmLsBanned(ctx context.Context) ([]net.BannedPeer, error) {
+	var out []net.BannedPeer
+
+	args := []string{"go-filecoin", "swarm", "ls-banned"}
+
+	if err := f.RunCmdJSONWithStdin(ctx, nil, &out, args...); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
@@ -0,0 +1,66 @@
+// Package assertions provides checks of chain and deal invariants against
+// FAST nodes. Each check polls the nodes until it holds or its timeout
+// passes, and fails with an error describing what was last observed, so that
+// tests and long running tools share the same checks instead of their own
+// polling loops.
+package assertions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/filecoin-project/go-filecoin/tools/fast/series"
+)
+
+// Failure is the error of an assertion that did not hold.
+type Failure struct {
+	// Assertion describes what was asserted.
+	Assertion string
+	// Observed describes the state last observed.
+	Observed string
+	// Within is the time the assertion was given, zero for assertions checked
+	// once.
+	Within time.Duration
+}
+
+func (f *Failure) Error() string {
+	if f.Within == 0 {
+		return fmt.Sprintf("assertion failed: %s: observed %s", f.Assertion, f.Observed)
+	}
+	return fmt.Sprintf("assertion failed: %s within %s: last observed %s", f.Assertion, f.Within, f.Observed)
+}
+
+// Check reports whether an assertion holds and describes the state observed.
+// An error, such as a node not answering yet, is observed and the check
+// retried.
+type Check func(ctx context.Context) (ok bool, observed string, err error)
+
+// Eventually runs check until it holds, failing when it does not within the
+// given time or ctx is done. Checks are spaced by the series sleep delay of
+// ctx.
+func Eventually(ctx context.Context, assertion string, within time.Duration, check Check) error {
+	ctx, cancel := context.WithTimeout(ctx, within)
+	defer cancel()
+
+	observed := "nothing"
+	for {
+		ok, obs, err := check(ctx)
+		if ok && err == nil {
+			return nil
+		}
+		// a check interrupted by the timeout tells nothing new
+		if ctx.Err() == nil {
+			observed = obs
+			if err != nil {
+				observed = fmt.Sprintf("error: %s", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return &Failure{Assertion: assertion, Observed: observed, Within: within}
+		case <-series.CtxSleepDelay(ctx):
+		}
+	}
+}
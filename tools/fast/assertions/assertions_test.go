@@ -0,0 +1,75 @@
+package assertions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/tools/fast/series"
+)
+
+func TestEventually(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := series.SetCtxSleepDelay(context.Background(), time.Millisecond)
+
+	t.Run("holds once the check does", func(t *testing.T) {
+		calls := 0
+		err := Eventually(ctx, "three calls", time.Second, func(context.Context) (bool, string, error) {
+			calls++
+			if calls == 1 {
+				return false, "", errors.New("not ready")
+			}
+			return calls == 3, fmt.Sprintf("%d calls", calls), nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("fails with the last observation", func(t *testing.T) {
+		err := Eventually(ctx, "never", 20*time.Millisecond, func(context.Context) (bool, string, error) {
+			return false, "still no", nil
+		})
+		require.Error(t, err)
+		failure, ok := err.(*Failure)
+		require.True(t, ok)
+		assert.Equal(t, "still no", failure.Observed)
+		assert.Equal(t, "assertion failed: never within 20ms: last observed still no", err.Error())
+	})
+
+	t.Run("reports errors as observed", func(t *testing.T) {
+		err := Eventually(ctx, "never", 20*time.Millisecond, func(context.Context) (bool, string, error) {
+			return false, "", errors.New("node offline")
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "error: node offline")
+	})
+}
+
+func TestAgreeAt(t *testing.T) {
+	tf.UnitTest(t)
+
+	a := block.NewTipSetKey(types.CidFromString(t, "a"))
+	b := block.NewTipSetKey(types.CidFromString(t, "b"))
+
+	assert.NoError(t, agreeAt(10, 5, map[string]tipSetAt{
+		"node0": {key: a, height: 10},
+		"node1": {key: a, height: 10},
+	}))
+
+	err := agreeAt(10, 5, map[string]tipSetAt{
+		"node0": {key: a, height: 10},
+		"node1": {key: b, height: 9},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no fork deeper than 5 epochs, at height 10")
+	assert.Contains(t, err.Error(), "node1 at "+b.String()+" (height 9)")
+}
@@ -0,0 +1,127 @@
+package assertions
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/tools/fast"
+	"github.com/filecoin-project/go-filecoin/tools/fast/series"
+)
+
+// AssertChainGrows checks that the head of node grows by at least the given
+// number of epochs within the given time.
+func AssertChainGrows(ctx context.Context, node *fast.Filecoin, by abi.ChainEpoch, within time.Duration) error {
+	start, err := series.GetHeadBlockHeight(ctx, node)
+	if err != nil {
+		return err
+	}
+
+	assertion := fmt.Sprintf("chain of %s grows by %d epochs from %d", node, by, start)
+	return Eventually(ctx, assertion, within, func(ctx context.Context) (bool, string, error) {
+		height, err := series.GetHeadBlockHeight(ctx, node)
+		if err != nil {
+			return false, "", err
+		}
+		return height >= start+by, fmt.Sprintf("height %d", height), nil
+	})
+}
+
+// AssertNoForksBeyondDepth checks that the chains of the nodes agree on the
+// tipsets more than depth epochs below the lowest of their heads, that is that
+// no fork of the nodes is deeper than depth.
+func AssertNoForksBeyondDepth(ctx context.Context, nodes []*fast.Filecoin, depth abi.ChainEpoch) error {
+	if len(nodes) < 2 {
+		return nil
+	}
+
+	lowest := abi.ChainEpoch(-1)
+	for _, node := range nodes {
+		height, err := series.GetHeadBlockHeight(ctx, node)
+		if err != nil {
+			return err
+		}
+		if lowest < 0 || height < lowest {
+			lowest = height
+		}
+	}
+	target := lowest - depth
+	if target < 0 {
+		return nil
+	}
+
+	tipsets := map[string]tipSetAt{}
+	for _, node := range nodes {
+		ts, err := tipSetAtHeight(ctx, node, target)
+		if err != nil {
+			return err
+		}
+		tipsets[node.String()] = ts
+	}
+	return agreeAt(target, depth, tipsets)
+}
+
+// tipSetAt is a tipset of a chain, identified by its key.
+type tipSetAt struct {
+	key    block.TipSetKey
+	height abi.ChainEpoch
+}
+
+// tipSetAtHeight returns the tipset of the chain of node at target, or the
+// first below it when target is a null round.
+func tipSetAtHeight(ctx context.Context, node *fast.Filecoin, target abi.ChainEpoch) (tipSetAt, error) {
+	dec, err := node.ChainLs(ctx)
+	if err != nil {
+		return tipSetAt{}, err
+	}
+	for dec.More() {
+		var blocks []block.Block
+		if err := dec.Decode(&blocks); err != nil {
+			return tipSetAt{}, err
+		}
+		if len(blocks) == 0 || blocks[0].Height > target {
+			continue
+		}
+		var cids []cid.Cid
+		for _, b := range blocks {
+			cids = append(cids, b.Cid())
+		}
+		return tipSetAt{key: block.NewTipSetKey(cids...), height: blocks[0].Height}, nil
+	}
+	return tipSetAt{}, fmt.Errorf("chain of %s has no tipset at or below height %d", node, target)
+}
+
+// agreeAt fails unless all nodes have the same tipset at target.
+func agreeAt(target, depth abi.ChainEpoch, tipsets map[string]tipSetAt) error {
+	var names []string
+	for name := range tipsets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	agree := true
+	for _, name := range names[1:] {
+		if !tipsets[name].key.Equals(tipsets[names[0]].key) {
+			agree = false
+			break
+		}
+	}
+	if agree {
+		return nil
+	}
+
+	var observed []string
+	for _, name := range names {
+		observed = append(observed, fmt.Sprintf("%s at %s (height %d)", name, tipsets[name].key, tipsets[name].height))
+	}
+	return &Failure{
+		Assertion: fmt.Sprintf("no fork deeper than %d epochs, at height %d", depth, target),
+		Observed:  strings.Join(observed, ", "),
+	}
+}
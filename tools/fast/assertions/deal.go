@@ -0,0 +1,62 @@
+package assertions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/go-fil-markets/storagemarket/network"
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/go-filecoin/tools/fast"
+)
+
+// dealFailed are the states of a deal that will not become active.
+var dealFailed = map[storagemarket.StorageDealStatus]bool{
+	storagemarket.StorageDealProposalNotFound: true,
+	storagemarket.StorageDealProposalRejected: true,
+	storagemarket.StorageDealFailing:          true,
+	storagemarket.StorageDealNotFound:         true,
+	storagemarket.StorageDealError:            true,
+}
+
+// AssertDealCompletesWithin checks that the deal with the given proposal, as
+// queried by client, becomes active within the given time, and returns its
+// last response. It fails as soon as the deal fails.
+func AssertDealCompletesWithin(ctx context.Context, client *fast.Filecoin, proposal cid.Cid, within time.Duration) (*network.Response, error) {
+	var resp *network.Response
+	var failed *Failure
+	assertion := fmt.Sprintf("deal %s of %s completes", proposal, client)
+	err := Eventually(ctx, assertion, within, func(ctx context.Context) (bool, string, error) {
+		r, err := client.ClientQueryStorageDeal(ctx, proposal)
+		if err != nil {
+			return false, "", err
+		}
+		resp = r
+		observed := describeDeal(r)
+		if dealFailed[r.State] {
+			failed = &Failure{Assertion: assertion, Observed: observed}
+			return true, observed, nil
+		}
+		return r.State == storagemarket.StorageDealActive, observed, nil
+	})
+	if err != nil {
+		return resp, err
+	}
+	if failed != nil {
+		return resp, failed
+	}
+	return resp, nil
+}
+
+func describeDeal(r *network.Response) string {
+	state, ok := storagemarket.DealStates[r.State]
+	if !ok {
+		state = fmt.Sprintf("state %d", r.State)
+	}
+	if r.Message != "" {
+		return fmt.Sprintf("%s (%s)", state, r.Message)
+	}
+	return state
+}
@@ -0,0 +1,26 @@
+package assertions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+
+	"github.com/filecoin-project/go-filecoin/tools/fast"
+)
+
+// AssertMinerHasPower checks that miner, as seen by node, has raw power
+// within the given time, which it gains once a sector it committed is proven.
+func AssertMinerHasPower(ctx context.Context, node *fast.Filecoin, miner address.Address, within time.Duration) error {
+	assertion := fmt.Sprintf("miner %s has power on %s", miner, node)
+	return Eventually(ctx, assertion, within, func(ctx context.Context) (bool, string, error) {
+		status, err := node.MinerStatus(ctx, miner)
+		if err != nil {
+			return false, "", err
+		}
+		observed := fmt.Sprintf("raw power %s of %s with %d sectors", status.RawPower, status.NetworkRawPower, status.SectorCount)
+		return status.RawPower.GreaterThan(big.Zero()), observed, nil
+	})
+}
@@ -19,17 +19,22 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime/debug"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/filecoin-project/go-fil-markets/storagemarket"
 	"github.com/filecoin-project/go-fil-markets/storagemarket/network"
+	"github.com/filecoin-project/specs-actors/actors/abi"
 	files "github.com/ipfs/go-ipfs-files"
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/mitchellh/go-homedir"
 
 	commands "github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
 	"github.com/filecoin-project/go-filecoin/tools/fast"
+	"github.com/filecoin-project/go-filecoin/tools/fast/chaos"
 	"github.com/filecoin-project/go-filecoin/tools/fast/environment"
 	"github.com/filecoin-project/go-filecoin/tools/fast/series"
 	lpfc "github.com/filecoin-project/go-filecoin/tools/iptb-plugins/filecoin/local"
@@ -48,6 +53,21 @@ var (
 	minerCollateral = big.NewInt(500)
 	minerPrice      = big.NewFloat(0.000000001)
 	minerExpiry     = big.NewInt(24 * 60 * 60)
+	sectorSizes     []abi.SectorSize
+
+	chaosDuration time.Duration
+	chaosSeed     = time.Now().UnixNano()
+	chaosInterval = time.Minute
+	chaosMaxFault = 2 * time.Minute
+	chaosMaxSkew  = 10 * time.Second
+	chaosFaults   = "kill,clock-skew,disk-full"
+	chaosRecovery = 5 * time.Minute
+	chaosSchedule []chaos.Event
+
+	storageChaos         string
+	storageChaosSettle   = 5 * time.Minute
+	storageChaosDuration = abi.ChainEpoch(1000)
+	storageFaults        []chaos.StorageFault
 
 	exitcode int
 
@@ -63,6 +83,7 @@ func init() {
 		minerCollateralArg = minerCollateral.Text(10)
 		minerPriceArg      = minerPrice.Text('f', 10)
 		minerExpiryArg     = minerExpiry.Text(10)
+		sectorSizesArg     string
 	)
 
 	// We default to the binary built in the project directory, fallback
@@ -92,6 +113,16 @@ func init() {
 	flag.StringVar(&minerCollateralArg, "miner-collateral", minerCollateralArg, "amount of fil each miner will use for collateral")
 	flag.StringVar(&minerPriceArg, "miner-price", minerPriceArg, "price value used when creating ask for miners")
 	flag.StringVar(&minerExpiryArg, "miner-expiry", minerExpiryArg, "expiry value used when creating ask for miners")
+	flag.StringVar(&sectorSizesArg, "sector-sizes", sectorSizesArg, "comma separated sector sizes in bytes assigned to the miners in turn, defaults to the smallest supported size")
+	flag.DurationVar(&chaosDuration, "chaos", chaosDuration, "inject faults into the nodes for this duration once the network is up, then exit reporting whether it recovered")
+	flag.Int64Var(&chaosSeed, "chaos-seed", chaosSeed, "seed of the schedule of faults, defaults to the current time")
+	flag.DurationVar(&chaosInterval, "chaos-interval", chaosInterval, "mean time between two faults")
+	flag.DurationVar(&chaosMaxFault, "chaos-max-fault", chaosMaxFault, "maximum duration of a fault")
+	flag.DurationVar(&chaosMaxSkew, "chaos-max-skew", chaosMaxSkew, "maximum offset of the clock of a node")
+	flag.StringVar(&chaosFaults, "chaos-faults", chaosFaults, "comma separated faults to inject")
+	flag.DurationVar(&chaosRecovery, "chaos-recovery", chaosRecovery, "time given to the network to recover after the last fault")
+	flag.StringVar(&storageChaos, "storage-chaos", storageChaos, "comma separated misbehaviours of a client in deals with the first miner once the network is up, then exit reporting whether the miner rejected them (malformed,no-funds,abort-transfer,double-propose)")
+	flag.DurationVar(&storageChaosSettle, "storage-chaos-settle", storageChaosSettle, "time given to the miner to reject a deal")
 
 	// ExitOnError is set
 	flag.Parse(os.Args[1:]) // nolint: errcheck
@@ -126,6 +157,46 @@ func init() {
 		os.Exit(1)
 	}
 
+	if chaosDuration > 0 {
+		faults, err := chaos.ParseFaults(chaosFaults)
+		if err != nil {
+			handleError(err, "could not parse chaos-faults;")
+			os.Exit(1)
+		}
+
+		chaosSchedule = chaos.NewSchedule(chaos.ScheduleConfig{
+			Seed:             chaosSeed,
+			Duration:         chaosDuration,
+			MeanInterval:     chaosInterval,
+			MaxFaultDuration: chaosMaxFault,
+			MaxClockSkew:     chaosMaxSkew,
+			Faults:           faults,
+		}, minerCount+1)
+	}
+
+	if sectorSizesArg != "" {
+		for _, arg := range strings.Split(sectorSizesArg, ",") {
+			size, err := strconv.ParseUint(strings.TrimSpace(arg), 10, 64)
+			if err != nil {
+				handleError(fmt.Errorf("could not parse sector-sizes: %s", err))
+				os.Exit(1)
+			}
+			sectorSizes = append(sectorSizes, abi.SectorSize(size))
+		}
+	}
+
+	if storageChaos != "" {
+		storageFaults, err = chaos.ParseStorageFaults(storageChaos)
+		if err != nil {
+			handleError(err, "could not parse storage-chaos;")
+			os.Exit(1)
+		}
+		if minerCount == 0 {
+			handleError(fmt.Errorf("storage-chaos needs a miner"))
+			os.Exit(1)
+		}
+	}
+
 	// Set the initial balance
 	balance.SetInt64(int64(100 * fil))
 }
@@ -253,8 +324,9 @@ func main() {
 	// 9. Query deal till complete
 
 	var deals []*network.Response
+	var storageDeal chaos.StorageDeal
 
-	for _, miner := range miners {
+	for i, miner := range miners {
 		err = series.InitAndStart(ctx, miner)
 		if err != nil {
 			exitcode = handleError(err, "failed series.InitAndStart;")
@@ -280,6 +352,13 @@ func main() {
 		}
 
 		sinfo := pparams.SupportedSectors[0]
+		if len(sectorSizes) > 0 {
+			sinfo, err = supportedSector(pparams, sectorSizes[i%len(sectorSizes)])
+			if err != nil {
+				exitcode = handleError(err, "failed to choose the sector size;")
+				return
+			}
+		}
 
 		ask, err := series.CreateStorageMinerWithAsk(ctx, miner, minerCollateral, minerPrice, minerExpiry, sinfo.Size)
 		if err != nil {
@@ -302,6 +381,14 @@ func main() {
 		}
 
 		deals = append(deals, deal)
+		if storageDeal.Miner.Empty() {
+			storageDeal = chaos.StorageDeal{
+				Miner:     ask.Miner,
+				PieceSize: uint64(sinfo.MaxPieceSize),
+				Duration:  storageChaosDuration,
+				Price:     minerPrice.Text('f', 18),
+			}
+		}
 	}
 
 	for _, deal := range deals {
@@ -376,11 +463,55 @@ func main() {
 		}
 	}
 
+	if len(storageFaults) > 0 {
+		fmt.Printf("Proposing faulty deals to miner %s\n", storageDeal.Miner)
+		report, err := chaos.NewStorageHarness(genesis, miners[0], storageDeal, storageChaosSettle).Run(ctx, storageFaults)
+		if err != nil {
+			exitcode = handleError(err, "failed storage chaos run;")
+			return
+		}
+
+		fmt.Print(report)
+		if !report.Passed {
+			exitcode = 1
+			return
+		}
+		if chaosDuration == 0 {
+			return
+		}
+	}
+
+	if chaosDuration > 0 {
+		// Node 0 is the genesis node, followed by the miners
+		fmt.Printf("Injecting %d faults with seed %d\n", len(chaosSchedule), chaosSeed)
+		report, err := chaos.NewController(append([]*fast.Filecoin{genesis}, miners...)).Run(ctx, chaosSchedule, chaosRecovery)
+		if err != nil {
+			exitcode = handleError(err, "failed chaos run;")
+			return
+		}
+
+		fmt.Print(report)
+		if !report.Recovered {
+			exitcode = 1
+		}
+		return
+	}
+
 	fmt.Println("Ctrl-C to exit")
 
 	<-exit
 }
 
+// supportedSector returns the supported sector of size `size`.
+func supportedSector(pparams *porcelain.ProtocolParams, size abi.SectorSize) (porcelain.SectorInfo, error) {
+	for _, sinfo := range pparams.SupportedSectors {
+		if sinfo.Size == size {
+			return sinfo, nil
+		}
+	}
+	return porcelain.SectorInfo{}, fmt.Errorf("sector size %d is not supported", size)
+}
+
 func handleError(err error, msg ...string) int {
 	if err == nil {
 		return 0
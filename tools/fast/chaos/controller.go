@@ -0,0 +1,239 @@
+// Package chaos injects faults into the nodes of a FAST network following a
+// seeded schedule, and reports whether the network recovered from them.
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	logging "github.com/ipfs/go-log/v2"
+
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/paths"
+	"github.com/filecoin-project/go-filecoin/tools/fast"
+	"github.com/filecoin-project/go-filecoin/tools/fast/series"
+)
+
+var log = logging.Logger("chaos")
+
+// EventResult is the outcome of the injection of a fault.
+type EventResult struct {
+	Event Event
+	// Err is the error injecting or healing the fault, if any.
+	Err string
+}
+
+// NodeStatus is the state of a node after the recovery period.
+type NodeStatus struct {
+	Node   int
+	Height abi.ChainEpoch
+	// Err is the error querying the node, if any.
+	Err string
+}
+
+// Report is the outcome of a chaos run.
+type Report struct {
+	Events []EventResult
+	// HeightAfterFaults is the highest head height when the last fault ended.
+	HeightAfterFaults abi.ChainEpoch
+	Nodes             []NodeStatus
+	// Recovered is true when every node responded and its head moved past
+	// HeightAfterFaults within the recovery period.
+	Recovered bool
+}
+
+func (r *Report) String() string {
+	var b bytes.Buffer
+	for _, res := range r.Events {
+		if res.Err != "" {
+			fmt.Fprintf(&b, "%s: %s\n", res.Event, res.Err)
+		} else {
+			fmt.Fprintf(&b, "%s: ok\n", res.Event)
+		}
+	}
+	for _, n := range r.Nodes {
+		if n.Err != "" {
+			fmt.Fprintf(&b, "node %d: %s\n", n.Node, n.Err)
+		} else {
+			fmt.Fprintf(&b, "node %d: height %d\n", n.Node, n.Height)
+		}
+	}
+	if r.Recovered {
+		fmt.Fprintf(&b, "recovered past height %d\n", r.HeightAfterFaults)
+	} else {
+		fmt.Fprintf(&b, "did not recover past height %d\n", r.HeightAfterFaults)
+	}
+	return b.String()
+}
+
+// Controller injects the faults of a schedule into FAST nodes.
+type Controller struct {
+	nodes []*fast.Filecoin
+	// locks serialize the restarts of each node
+	locks []sync.Mutex
+}
+
+// NewController returns a controller for `nodes`, which must be started. The
+// Node of schedule events is an index into `nodes`.
+func NewController(nodes []*fast.Filecoin) *Controller {
+	return &Controller{
+		nodes: nodes,
+		locks: make([]sync.Mutex, len(nodes)),
+	}
+}
+
+// Run injects the faults of `schedule`, healing each when it ends, then gives
+// the network `recovery` to make progress before reporting on it. Faults still
+// active when ctx is done are healed before returning.
+func (c *Controller) Run(ctx context.Context, schedule []Event, recovery time.Duration) (*Report, error) {
+	for _, e := range schedule {
+		if e.Node < 0 || e.Node >= len(c.nodes) {
+			return nil, fmt.Errorf("event %s targets an unknown node", e)
+		}
+	}
+
+	report := &Report{Events: make([]EventResult, len(schedule))}
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i, e := range schedule {
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Until(start.Add(e.At))):
+		}
+		if ctx.Err() != nil {
+			report.Events[i] = EventResult{Event: e, Err: "not injected, " + ctx.Err().Error()}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, e Event) {
+			defer wg.Done()
+			report.Events[i] = EventResult{Event: e}
+			if err := c.inject(ctx, e); err != nil {
+				log.Warnf("fault %s: %s", e, err)
+				report.Events[i].Err = err.Error()
+			}
+		}(i, e)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return report, ctx.Err()
+	}
+
+	report.HeightAfterFaults = c.maxHeight(ctx)
+	recoveryCtx, cancel := context.WithTimeout(ctx, recovery)
+	defer cancel()
+	report.Nodes, report.Recovered = c.awaitRecovery(recoveryCtx, report.HeightAfterFaults)
+	return report, nil
+}
+
+// inject injects the fault of the event, waits for its duration and heals it.
+func (c *Controller) inject(ctx context.Context, e Event) error {
+	node := c.nodes[e.Node]
+	lk := &c.locks[e.Node]
+	log.Infof("injecting %s", e)
+
+	var heal func(context.Context) error
+	switch e.Fault {
+	case FaultKill:
+		lk.Lock()
+		err := node.StopDaemon(ctx)
+		lk.Unlock()
+		if err != nil {
+			return err
+		}
+		heal = func(ctx context.Context) error {
+			lk.Lock()
+			defer lk.Unlock()
+			_, err := node.StartDaemon(ctx, true)
+			return err
+		}
+	case FaultClockSkew:
+		lk.Lock()
+		err := node.RestartDaemon(ctx, fast.POClockSkew(e.Skew))
+		lk.Unlock()
+		if err != nil {
+			return err
+		}
+		heal = func(ctx context.Context) error {
+			lk.Lock()
+			defer lk.Unlock()
+			return node.RestartDaemon(ctx)
+		}
+	case FaultDiskFull:
+		dir, err := sectorDir(node)
+		if err != nil {
+			return err
+		}
+		restore, err := makeUnwritable(dir)
+		if err != nil {
+			return err
+		}
+		heal = func(context.Context) error { return restore() }
+	default:
+		return fmt.Errorf("unknown fault %s", e.Fault)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(e.Duration):
+	}
+	// Heal even once ctx is done, not to leave the network broken.
+	healCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	log.Infof("healing %s", e)
+	return heal(healCtx)
+}
+
+// maxHeight returns the highest head height of the nodes that respond.
+func (c *Controller) maxHeight(ctx context.Context) abi.ChainEpoch {
+	var max abi.ChainEpoch
+	for _, node := range c.nodes {
+		if h, err := series.GetHeadBlockHeight(ctx, node); err == nil && h > max {
+			max = h
+		}
+	}
+	return max
+}
+
+// awaitRecovery polls the nodes until the head of each is past `height` or ctx is done.
+func (c *Controller) awaitRecovery(ctx context.Context, height abi.ChainEpoch) ([]NodeStatus, bool) {
+	for {
+		statuses := make([]NodeStatus, len(c.nodes))
+		recovered := true
+		for i, node := range c.nodes {
+			statuses[i].Node = i
+			h, err := series.GetHeadBlockHeight(ctx, node)
+			if err != nil {
+				statuses[i].Err = err.Error()
+				recovered = false
+				continue
+			}
+			statuses[i].Height = h
+			recovered = recovered && h > height
+		}
+		if recovered {
+			return statuses, true
+		}
+
+		select {
+		case <-ctx.Done():
+			return statuses, false
+		case <-series.CtxSleepDelay(ctx):
+		}
+	}
+}
+
+// sectorDir returns the sector storage directory of a node.
+func sectorDir(node *fast.Filecoin) (string, error) {
+	cfg, err := node.Config()
+	if err != nil {
+		return "", err
+	}
+	// the repo of FAST processes is in the repo directory of the process directory
+	return paths.GetSectorPath(cfg.SectorBase.RootDirPath, filepath.Join(node.Dir(), "repo"))
+}
@@ -0,0 +1,55 @@
+package chaos
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// makeUnwritable removes the write permissions of `dir` and of the directories
+// below it, so that creating or growing files in them fails as it would on a
+// full disk, while existing files can still be read. It returns a function
+// restoring the permissions.
+//
+// Permissions do not restrict root: the fault has no effect on daemons running
+// as root.
+func makeUnwritable(dir string) (func() error, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, errors.Errorf("%s is not a directory", dir)
+	}
+
+	modes := map[string]os.FileMode{}
+	restore := func() error {
+		var firstErr error
+		for path, mode := range modes {
+			if err := os.Chmod(path, mode); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if err := os.Chmod(path, info.Mode().Perm()&^0222); err != nil {
+			return err
+		}
+		modes[path] = info.Mode().Perm()
+		return nil
+	})
+	if err != nil {
+		_ = restore()
+		return nil, errors.Wrapf(err, "failed to make %s unwritable", dir)
+	}
+	return restore, nil
+}
@@ -0,0 +1,45 @@
+package chaos
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+func TestMakeUnwritable(t *testing.T) {
+	tf.UnitTest(t)
+
+	dir, err := ioutil.TempDir("", "chaos")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	sealed := filepath.Join(dir, "sealed")
+	require.NoError(t, os.Mkdir(sealed, 0750))
+	sector := filepath.Join(sealed, "s-1")
+	require.NoError(t, ioutil.WriteFile(sector, []byte("sector"), 0640))
+
+	restore, err := makeUnwritable(dir)
+	require.NoError(t, err)
+
+	for path, mode := range map[string]os.FileMode{dir: 0500, sealed: 0550, sector: 0640} {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, mode, info.Mode().Perm(), path)
+	}
+
+	require.NoError(t, restore())
+	for path, mode := range map[string]os.FileMode{dir: 0700, sealed: 0750} {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, mode, info.Mode().Perm(), path)
+	}
+
+	_, err = makeUnwritable(sector)
+	assert.Error(t, err)
+}
@@ -0,0 +1,124 @@
+package chaos
+
+import (
+	"fmt"
+	mrand "math/rand"
+	"strings"
+	"time"
+)
+
+// Fault is a kind of failure injected into a node.
+type Fault string
+
+const (
+	// FaultKill stops the daemon of a node and starts it again once the fault ends.
+	FaultKill Fault = "kill"
+	// FaultClockSkew restarts the daemon of a node with its clock offset, and
+	// restarts it again without offset once the fault ends.
+	FaultClockSkew Fault = "clock-skew"
+	// FaultDiskFull makes the sector directory of a node unwritable until the fault ends.
+	FaultDiskFull Fault = "disk-full"
+)
+
+// Faults are all the kinds of faults.
+var Faults = []Fault{FaultKill, FaultClockSkew, FaultDiskFull}
+
+// ParseFaults parses a comma separated list of kinds of faults.
+func ParseFaults(s string) ([]Fault, error) {
+	var faults []Fault
+	for _, name := range strings.Split(s, ",") {
+		fault := Fault(strings.TrimSpace(name))
+		known := false
+		for _, f := range Faults {
+			known = known || f == fault
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown fault %q", name)
+		}
+		faults = append(faults, fault)
+	}
+	return faults, nil
+}
+
+// Event is a fault injected into a node at a time of the schedule.
+type Event struct {
+	// At is the time of the event since the start of the schedule.
+	At time.Duration
+	// Node is the index of the node the fault is injected into.
+	Node  int
+	Fault Fault
+	// Duration is how long the fault lasts.
+	Duration time.Duration
+	// Skew is the offset of the clock of the node for FaultClockSkew.
+	Skew time.Duration
+}
+
+func (e Event) String() string {
+	s := fmt.Sprintf("%s node %d %s for %s", e.At, e.Node, e.Fault, e.Duration)
+	if e.Fault == FaultClockSkew {
+		s += fmt.Sprintf(" skew %s", e.Skew)
+	}
+	return s
+}
+
+// ScheduleConfig parameterizes the generation of a schedule.
+type ScheduleConfig struct {
+	// Seed makes the schedule reproducible.
+	Seed int64
+	// Duration is the period during which faults are injected.
+	Duration time.Duration
+	// MeanInterval is the mean time between two faults.
+	MeanInterval time.Duration
+	// MaxFaultDuration bounds how long a fault lasts, faults last at least half of it.
+	MaxFaultDuration time.Duration
+	// MaxClockSkew bounds the offset of clocks, in both directions.
+	MaxClockSkew time.Duration
+	// Faults are the kinds of faults to inject, all kinds if empty.
+	Faults []Fault
+}
+
+// NewSchedule returns the events of a schedule injecting faults into `nodes`
+// nodes, sorted by time. The same config always produces the same schedule.
+// A node has at most one fault at a time, and every fault ends within the
+// duration of the schedule.
+func NewSchedule(cfg ScheduleConfig, nodes int) []Event {
+	faults := cfg.Faults
+	if len(faults) == 0 {
+		faults = Faults
+	}
+	if nodes == 0 || cfg.MeanInterval <= 0 || cfg.MaxFaultDuration <= 0 {
+		return []Event{}
+	}
+
+	rnd := mrand.New(mrand.NewSource(cfg.Seed))
+	busyUntil := make([]time.Duration, nodes)
+	events := []Event{}
+	at := time.Duration(rnd.Int63n(int64(2 * cfg.MeanInterval)))
+	for ; at < cfg.Duration; at += time.Duration(rnd.Int63n(int64(2 * cfg.MeanInterval))) {
+		// Draw every value before deciding to skip, so that skipping an event
+		// does not change the following ones.
+		e := Event{
+			At:       at,
+			Node:     rnd.Intn(nodes),
+			Fault:    faults[rnd.Intn(len(faults))],
+			Duration: cfg.MaxFaultDuration/2 + time.Duration(rnd.Int63n(int64(cfg.MaxFaultDuration/2)+1)),
+		}
+		skew := time.Duration(0)
+		if cfg.MaxClockSkew > 0 {
+			skew = time.Duration(rnd.Int63n(int64(2*cfg.MaxClockSkew)+1)) - cfg.MaxClockSkew
+		}
+
+		if e.At < busyUntil[e.Node] || e.At+e.Duration > cfg.Duration {
+			continue
+		}
+		if e.Fault == FaultClockSkew {
+			if skew == 0 {
+				continue
+			}
+			e.Skew = skew
+		}
+		busyUntil[e.Node] = e.At + e.Duration
+		events = append(events, e)
+	}
+	return events
+}
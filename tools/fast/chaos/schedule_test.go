@@ -0,0 +1,78 @@
+package chaos_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/tools/fast/chaos"
+)
+
+func TestNewSchedule(t *testing.T) {
+	tf.UnitTest(t)
+
+	cfg := chaos.ScheduleConfig{
+		Seed:             42,
+		Duration:         time.Hour,
+		MeanInterval:     time.Minute,
+		MaxFaultDuration: 4 * time.Minute,
+		MaxClockSkew:     10 * time.Second,
+	}
+
+	t.Run("same seed same schedule", func(t *testing.T) {
+		first := chaos.NewSchedule(cfg, 3)
+		require.NotEmpty(t, first)
+		assert.Equal(t, first, chaos.NewSchedule(cfg, 3))
+
+		other := cfg
+		other.Seed = 43
+		assert.NotEqual(t, first, chaos.NewSchedule(other, 3))
+	})
+
+	t.Run("faults are within bounds and do not overlap on a node", func(t *testing.T) {
+		busyUntil := map[int]time.Duration{}
+		var last time.Duration
+		for _, e := range chaos.NewSchedule(cfg, 3) {
+			assert.True(t, e.At >= last, "events are sorted")
+			last = e.At
+
+			assert.True(t, e.Node >= 0 && e.Node < 3)
+			assert.True(t, e.At >= busyUntil[e.Node], "%s overlaps a previous fault", e)
+			assert.True(t, e.Duration >= 2*time.Minute && e.Duration <= 4*time.Minute)
+			assert.True(t, e.At+e.Duration <= time.Hour)
+			if e.Fault == chaos.FaultClockSkew {
+				assert.NotZero(t, e.Skew)
+				assert.True(t, e.Skew >= -10*time.Second && e.Skew <= 10*time.Second)
+			} else {
+				assert.Zero(t, e.Skew)
+			}
+			busyUntil[e.Node] = e.At + e.Duration
+		}
+	})
+
+	t.Run("only configured faults", func(t *testing.T) {
+		only := cfg
+		only.Faults = []chaos.Fault{chaos.FaultKill}
+		for _, e := range chaos.NewSchedule(only, 3) {
+			assert.Equal(t, chaos.FaultKill, e.Fault)
+		}
+	})
+
+	t.Run("no nodes no events", func(t *testing.T) {
+		assert.Empty(t, chaos.NewSchedule(cfg, 0))
+	})
+}
+
+func TestParseFaults(t *testing.T) {
+	tf.UnitTest(t)
+
+	faults, err := chaos.ParseFaults("kill, disk-full")
+	require.NoError(t, err)
+	assert.Equal(t, []chaos.Fault{chaos.FaultKill, chaos.FaultDiskFull}, faults)
+
+	_, err = chaos.ParseFaults("kill,meteor")
+	assert.Error(t, err)
+}
@@ -0,0 +1,356 @@
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
+	files "github.com/ipfs/go-ipfs-files"
+
+	commands "github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
+	"github.com/filecoin-project/go-filecoin/tools/fast"
+	"github.com/filecoin-project/go-filecoin/tools/fast/series"
+)
+
+// StorageFault is a misbehaviour of a storage client, which the miner must
+// handle by rejecting the deal rather than crashing.
+type StorageFault string
+
+const (
+	// StorageMalformed proposes deals whose parameters are invalid: a deal
+	// ending before it starts, and a deal starting in the past.
+	StorageMalformed StorageFault = "malformed"
+	// StorageNoFunds proposes a deal at a price the client cannot pay.
+	StorageNoFunds StorageFault = "no-funds"
+	// StorageAbortTransfer proposes a valid deal and stops the client daemon
+	// while the data is transferred, starting it again after a while.
+	StorageAbortTransfer StorageFault = "abort-transfer"
+	// StorageDoublePropose proposes the same deal for the same piece twice.
+	StorageDoublePropose StorageFault = "double-propose"
+)
+
+// StorageFaults are all the kinds of storage faults.
+var StorageFaults = []StorageFault{StorageMalformed, StorageNoFunds, StorageAbortTransfer, StorageDoublePropose}
+
+// ParseStorageFaults parses a comma separated list of kinds of storage faults.
+func ParseStorageFaults(s string) ([]StorageFault, error) {
+	var faults []StorageFault
+	for _, name := range strings.Split(s, ",") {
+		fault := StorageFault(strings.TrimSpace(name))
+		known := false
+		for _, f := range StorageFaults {
+			known = known || f == fault
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown storage fault %q", name)
+		}
+		faults = append(faults, fault)
+	}
+	return faults, nil
+}
+
+// StorageDeal are the parameters of a valid deal with a miner, which storage
+// faults derive their proposals from.
+type StorageDeal struct {
+	Miner address.Address
+	// PieceSize is the size of the data proposed, which must fit in a sector of the miner.
+	PieceSize uint64
+	// Duration is the number of epochs of the deals.
+	Duration abi.ChainEpoch
+	// Price is the price per epoch of the deals in FIL, e.g. 0.01, at least
+	// the miner's ask.
+	Price string
+}
+
+// StorageResult is the outcome of a storage fault.
+type StorageResult struct {
+	Fault StorageFault
+	// Outcome describes how the proposals of the fault ended.
+	Outcome string
+	// Err is why the fault failed, if it did: the miner accepted a deal it
+	// should have rejected, or stopped responding.
+	Err string
+}
+
+// StorageReport is the outcome of a run of storage faults.
+type StorageReport struct {
+	Results []StorageResult
+	// Passed is true when the miner handled every fault and still responds.
+	Passed bool
+}
+
+func (r *StorageReport) String() string {
+	var b bytes.Buffer
+	for _, res := range r.Results {
+		if res.Err != "" {
+			fmt.Fprintf(&b, "%s: failed, %s\n", res.Fault, res.Err)
+		} else {
+			fmt.Fprintf(&b, "%s: ok, %s\n", res.Fault, res.Outcome)
+		}
+	}
+	if r.Passed {
+		fmt.Fprintln(&b, "miner handled all storage faults")
+	} else {
+		fmt.Fprintln(&b, "miner did not handle all storage faults")
+	}
+	return b.String()
+}
+
+// StorageHarness drives a client into misbehaving in deals with a miner, and
+// checks that the miner rejects the deals and keeps responding.
+type StorageHarness struct {
+	client *fast.Filecoin
+	miner  *fast.Filecoin
+	deal   StorageDeal
+	// settle is how long a deal is given to be rejected
+	settle time.Duration
+	// outage is how long the client is stopped for StorageAbortTransfer
+	outage time.Duration
+}
+
+// NewStorageHarness returns a harness proposing deals from `client` to the
+// miner run by `miner`, both of which must be started and connected. Deals
+// are given `settle` to be rejected.
+func NewStorageHarness(client, miner *fast.Filecoin, deal StorageDeal, settle time.Duration) *StorageHarness {
+	return &StorageHarness{
+		client: client,
+		miner:  miner,
+		deal:   deal,
+		settle: settle,
+		outage: settle / 2,
+	}
+}
+
+// Run injects each of `faults` in turn, checking after each that the miner
+// still responds.
+func (h *StorageHarness) Run(ctx context.Context, faults []StorageFault) (*StorageReport, error) {
+	report := &StorageReport{Passed: true}
+	for _, fault := range faults {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		log.Infof("injecting storage fault %s", fault)
+		res := StorageResult{Fault: fault}
+		outcome, err := h.inject(ctx, fault)
+		res.Outcome = outcome
+		if err != nil {
+			res.Err = err.Error()
+		} else if _, err := series.GetHeadBlockHeight(ctx, h.miner); err != nil {
+			res.Err = fmt.Sprintf("miner stopped responding: %s", err)
+		}
+		if res.Err != "" {
+			log.Warnf("storage fault %s: %s", fault, res.Err)
+			report.Passed = false
+		}
+		report.Results = append(report.Results, res)
+	}
+	return report, nil
+}
+
+func (h *StorageHarness) inject(ctx context.Context, fault StorageFault) (string, error) {
+	switch fault {
+	case StorageMalformed:
+		return h.injectMalformed(ctx)
+	case StorageNoFunds:
+		return h.injectNoFunds(ctx)
+	case StorageAbortTransfer:
+		return h.injectAbortTransfer(ctx)
+	case StorageDoublePropose:
+		return h.injectDoublePropose(ctx)
+	default:
+		return "", fmt.Errorf("unknown storage fault %s", fault)
+	}
+}
+
+func (h *StorageHarness) injectMalformed(ctx context.Context) (string, error) {
+	data, err := h.importData(ctx)
+	if err != nil {
+		return "", err
+	}
+	start, err := h.nextStart(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var outcomes []string
+	for _, bounds := range [][2]abi.ChainEpoch{
+		{start + h.deal.Duration, start},           // ends before it starts
+		{start - 10, start - 10 + h.deal.Duration}, // starts in the past
+	} {
+		outcome, err := h.proposeRejected(ctx, data, bounds[0], bounds[1], h.deal.Price)
+		if err != nil {
+			return strings.Join(outcomes, ", "), err
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return strings.Join(outcomes, ", "), nil
+}
+
+func (h *StorageHarness) injectNoFunds(ctx context.Context) (string, error) {
+	data, err := h.importData(ctx)
+	if err != nil {
+		return "", err
+	}
+	start, err := h.nextStart(ctx)
+	if err != nil {
+		return "", err
+	}
+	// Far more than the supply of the network.
+	return h.proposeRejected(ctx, data, start, start+h.deal.Duration, "1000000000000")
+}
+
+func (h *StorageHarness) injectAbortTransfer(ctx context.Context) (string, error) {
+	data, err := h.importData(ctx)
+	if err != nil {
+		return "", err
+	}
+	start, err := h.nextStart(ctx)
+	if err != nil {
+		return "", err
+	}
+	proposal, err := h.propose(ctx, data, start, start+h.deal.Duration, h.deal.Price)
+	if err != nil {
+		return "", fmt.Errorf("valid deal not proposed: %s", err)
+	}
+
+	// The transfer to the miner starts once the proposal is accepted.
+	if err := h.client.StopDaemon(ctx); err != nil {
+		return "", err
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(h.outage):
+	}
+	restartCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := h.client.StartDaemon(restartCtx, true); err != nil {
+		return "", fmt.Errorf("client not restarted: %s", err)
+	}
+
+	// The deal may fail, or the transfer resume, the miner must respond either way.
+	state, err := h.awaitState(ctx, proposal, func(state storagemarket.StorageDealStatus) bool {
+		return rejectedState(state) || state >= storagemarket.StorageDealStaged && state <= storagemarket.StorageDealActive
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("deal %s", storagemarket.DealStates[state]), nil
+}
+
+func (h *StorageHarness) injectDoublePropose(ctx context.Context) (string, error) {
+	data, err := h.importData(ctx)
+	if err != nil {
+		return "", err
+	}
+	start, err := h.nextStart(ctx)
+	if err != nil {
+		return "", err
+	}
+	first, err := h.propose(ctx, data, start, start+h.deal.Duration, h.deal.Price)
+	if err != nil {
+		return "", fmt.Errorf("first deal not proposed: %s", err)
+	}
+	second, err := h.propose(ctx, data, start, start+h.deal.Duration, h.deal.Price)
+	if err != nil {
+		return fmt.Sprintf("second proposal failed: %s", err), nil
+	}
+	if second.Equals(first) {
+		return "second proposal is the same deal", nil
+	}
+
+	state, err := h.awaitState(ctx, second, rejectedState)
+	if err != nil {
+		return "", err
+	}
+	if !rejectedState(state) {
+		return "", fmt.Errorf("second deal %s for the same piece is %s", second, storagemarket.DealStates[state])
+	}
+	return fmt.Sprintf("second deal %s", storagemarket.DealStates[state]), nil
+}
+
+// proposeRejected proposes a deal the miner must reject and returns how it
+// was rejected. A proposal failing within the client is a rejection too.
+func (h *StorageHarness) proposeRejected(ctx context.Context, data cid.Cid, start, end abi.ChainEpoch, price string) (string, error) {
+	proposal, err := h.propose(ctx, data, start, end, price)
+	if err != nil {
+		return fmt.Sprintf("proposal failed: %s", err), nil
+	}
+	state, err := h.awaitState(ctx, proposal, rejectedState)
+	if err != nil {
+		return "", err
+	}
+	if !rejectedState(state) {
+		return "", fmt.Errorf("deal %s from %d to %d at %s FIL was not rejected, it is %s",
+			proposal, start, end, price, storagemarket.DealStates[state])
+	}
+	return fmt.Sprintf("deal %s", storagemarket.DealStates[state]), nil
+}
+
+func (h *StorageHarness) propose(ctx context.Context, data cid.Cid, start, end abi.ChainEpoch, price string) (cid.Cid, error) {
+	var out commands.ClientProposeStorageDealResult
+	err := h.client.RunCmdJSONWithStdin(ctx, nil, &out, "go-filecoin", "client", "propose-storage-deal",
+		h.deal.Miner.String(), data.String(), fmt.Sprintf("%d", start), fmt.Sprintf("%d", end),
+		price, "0")
+	if err != nil {
+		return cid.Undef, err
+	}
+	return out.ProposalCid, nil
+}
+
+// awaitState polls the client's state of a deal until `done` is true for it
+// or the settle period ends, returning the last state.
+func (h *StorageHarness) awaitState(ctx context.Context, proposal cid.Cid, done func(storagemarket.StorageDealStatus) bool) (storagemarket.StorageDealStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.settle)
+	defer cancel()
+	state := storagemarket.StorageDealUnknown
+	for {
+		deal, err := h.client.ClientQueryStorageDeal(ctx, proposal)
+		if err == nil {
+			state = deal.State
+			if done(state) {
+				return state, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			// The settle period ending is the outcome, not an error.
+			return state, nil
+		case <-series.CtxSleepDelay(ctx):
+		}
+	}
+}
+
+// nextStart returns a start epoch leaving the miner time to accept a deal.
+func (h *StorageHarness) nextStart(ctx context.Context) (abi.ChainEpoch, error) {
+	height, err := series.GetHeadBlockHeight(ctx, h.client)
+	if err != nil {
+		return 0, err
+	}
+	return height + 20, nil
+}
+
+// importData imports random data of the piece size to the client.
+func (h *StorageHarness) importData(ctx context.Context) (cid.Cid, error) {
+	data := io.LimitReader(rand.Reader, int64(h.deal.PieceSize))
+	return h.client.ClientImport(ctx, files.NewReaderFile(data))
+}
+
+// rejectedState is true for the states of deals that did not go through.
+func rejectedState(state storagemarket.StorageDealStatus) bool {
+	switch state {
+	case storagemarket.StorageDealProposalRejected, storagemarket.StorageDealFailing,
+		storagemarket.StorageDealError, storagemarket.StorageDealProposalNotFound:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package chaos_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/tools/fast/chaos"
+)
+
+func TestParseStorageFaults(t *testing.T) {
+	tf.UnitTest(t)
+
+	faults, err := chaos.ParseStorageFaults("malformed, double-propose")
+	require.NoError(t, err)
+	assert.Equal(t, []chaos.StorageFault{chaos.StorageMalformed, chaos.StorageDoublePropose}, faults)
+
+	_, err = chaos.ParseStorageFaults("malformed,kill")
+	assert.Error(t, err)
+}
+
+func TestStorageReportString(t *testing.T) {
+	tf.UnitTest(t)
+
+	report := &chaos.StorageReport{
+		Results: []chaos.StorageResult{
+			{Fault: chaos.StorageNoFunds, Outcome: "deal StorageDealProposalRejected"},
+			{Fault: chaos.StorageDoublePropose, Err: "second deal is StorageDealActive"},
+		},
+	}
+	assert.Equal(t, "no-funds: ok, deal StorageDealProposalRejected\n"+
+		"double-propose: failed, second deal is StorageDealActive\n"+
+		"miner did not handle all storage faults\n", report.String())
+}
@@ -0,0 +1,40 @@
+package environment
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/filecoin-project/go-filecoin/tools/fast"
+	inprocplugin "github.com/filecoin-project/go-filecoin/tools/iptb-plugins/filecoin/inproc"
+)
+
+// InProcess is a FAST lib environment that runs all of its nodes inside the
+// test process, as goroutines, from the same local genesis as MemoryGenesis.
+// No go-filecoin binary is needed, which makes it the quickest environment to
+// run functional tests like deal flows in.
+type InProcess struct {
+	*MemoryGenesis
+}
+
+// NewInProcess builds an environment with a local genesis whose processes run
+// in the current process.
+func NewInProcess(funds *big.Int, location string) (Environment, error) {
+	env, err := NewMemoryGenesis(funds, location)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InProcess{MemoryGenesis: env.(*MemoryGenesis)}, nil
+}
+
+// NewProcess builds an in-process node with the options passed. The process
+// is tracked by the environment and returned. processType must be empty or the
+// in-process plugin name.
+func (e *InProcess) NewProcess(ctx context.Context, processType string, options map[string]string, eo fast.FilecoinOpts) (*fast.Filecoin, error) {
+	if processType != "" && processType != inprocplugin.PluginName {
+		return nil, fmt.Errorf("in-process environment cannot run process type %s", processType)
+	}
+
+	return e.MemoryGenesis.NewProcess(ctx, inprocplugin.PluginName, options, eo)
+}
@@ -0,0 +1,54 @@
+package environment
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/tools/fast"
+)
+
+func TestInProcess(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	testDir, err := ioutil.TempDir(".", "environmentTest")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(testDir))
+	}()
+
+	env, err := NewInProcess(big.NewInt(100000), testDir)
+	require.NoError(t, err)
+
+	_, err = env.NewProcess(ctx, "localfilecoin", nil, fast.FilecoinOpts{})
+	assert.Error(t, err)
+
+	p, err := env.NewProcess(ctx, "", nil, fast.FilecoinOpts{
+		InitOpts: []fast.ProcessInitOption{fast.POGenesisFile(env.GenesisCar())},
+	})
+	require.NoError(t, err)
+
+	_, err = p.InitDaemon(ctx)
+	require.NoError(t, err)
+
+	_, err = p.StartDaemon(ctx, true)
+	require.NoError(t, err)
+
+	id, err := p.ID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, p.PeerID, id.ID)
+
+	require.NoError(t, env.Teardown(ctx))
+	_, existsErr := os.Stat(testDir)
+	assert.True(t, os.IsNotExist(existsErr))
+}
@@ -198,30 +198,40 @@ func (e *MemoryGenesis) TeardownProcess(ctx context.Context, p *fast.Filecoin) e
 // startGenesisServer builds and starts a server which will serve the genesis
 // file, the url for the genesis.car is returned by GenesisCar()
 func (e *MemoryGenesis) startGenesisServer() error {
+	server, addr, err := serveGenesis(e.genesisCar, e.log)
+	if err != nil {
+		return err
+	}
+
+	e.genesisServer = server
+	e.genesisServerAddr = addr
+	return nil
+}
+
+// serveGenesis starts a server on the loopback interface serving `car` as
+// "/genesis.car", and returns the server and its address.
+func serveGenesis(car []byte, log logging.EventLogger) (*http.Server, string, error) {
 	handler := http.NewServeMux()
 	handler.HandleFunc("/genesis.car", func(w http.ResponseWriter, req *http.Request) {
-		car := bytes.NewBuffer(e.genesisCar)
-		if n, err := io.Copy(w, car); err != nil {
-			e.log.Errorf(`Failed to serve "/genesis.car" after writing %d bytes with error %s`, n, err)
+		if n, err := io.Copy(w, bytes.NewBuffer(car)); err != nil {
+			log.Errorf(`Failed to serve "/genesis.car" after writing %d bytes with error %s`, n, err)
 		}
 	})
 
-	e.genesisServer = &http.Server{Handler: handler}
+	server := &http.Server{Handler: handler}
 
 	ln, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
-	e.genesisServerAddr = ln.Addr().String()
-
 	go func() {
-		if err := e.genesisServer.Serve(ln); err != nil && err != http.ErrServerClosed {
-			e.log.Errorf("Genesis file server: %s", err)
+		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Errorf("Genesis file server: %s", err)
 		}
 	}()
 
-	return nil
+	return server, ln.Addr().String(), nil
 }
 
 // buildGenesis builds a genesis with the specified funds.
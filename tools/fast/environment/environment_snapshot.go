@@ -0,0 +1,384 @@
+package environment
+
+// The snapshot FAST environment starts a local network from a bundle holding the genesis, the
+// chain built on it and the pre-sealed sectors of the genesis miner, so that tests needing a
+// network with a powered miner skip mining from genesis. Bundles are produced with CreateSnapshot
+// from a running genesis node.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	logging "github.com/ipfs/go-log/v2"
+	iptb "github.com/ipfs/iptb/testbed"
+
+	commands "github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/tools/fast"
+	"github.com/filecoin-project/go-filecoin/tools/fast/series"
+)
+
+// Names of the content of a snapshot bundle.
+const (
+	SnapshotManifestFile = "manifest.json"
+	SnapshotGenesisFile  = "genesis.car"
+	SnapshotChainFile    = "chain.car"
+	SnapshotSectorsDir   = "sectors"
+)
+
+// SnapshotManifest describes the chain of a snapshot bundle and its genesis
+// miner.
+type SnapshotManifest struct {
+	// Head is the key of the head of the chain of the bundle
+	Head block.TipSetKey
+
+	// MinerAddress is the address of the genesis miner
+	MinerAddress address.Address
+
+	// MinerOwner holds the key of the owner of the genesis miner
+	MinerOwner commands.WalletSerializeResult
+
+	// PresealedSectors is true when the bundle holds the pre-sealed sectors
+	// of the genesis miner
+	PresealedSectors bool
+}
+
+// Snapshot is a FAST lib environment that is meant to be used when working
+// locally with a network started from a snapshot bundle.
+type Snapshot struct {
+	bundle     string
+	manifest   SnapshotManifest
+	genesisCar []byte
+
+	location string
+
+	genesisServer     *http.Server
+	genesisServerAddr string
+
+	log logging.EventLogger
+
+	processesMu sync.Mutex
+	processes   []*fast.Filecoin
+
+	processCountMu sync.Mutex
+	processCount   int
+}
+
+// NewSnapshot builds an environment from the snapshot bundle in the directory
+// `bundle`. The genesis file of the bundle is provided by an http server.
+func NewSnapshot(bundle string, location string) (*Snapshot, error) {
+	bundle, err := filepath.Abs(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &Snapshot{
+		bundle:   bundle,
+		location: location,
+		log:      logging.Logger("environment"),
+	}
+
+	manifest, err := ioutil.ReadFile(filepath.Join(bundle, SnapshotManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(manifest, &env.manifest); err != nil {
+		return nil, fmt.Errorf("invalid snapshot manifest: %s", err)
+	}
+	if len(env.manifest.MinerOwner.KeyInfo) == 0 {
+		return nil, fmt.Errorf("snapshot manifest has no key for the owner of miner %s", env.manifest.MinerAddress)
+	}
+
+	env.genesisCar, err = ioutil.ReadFile(filepath.Join(bundle, SnapshotGenesisFile))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(env.location, 0775); err != nil {
+		return nil, err
+	}
+
+	env.genesisServer, env.genesisServerAddr, err = serveGenesis(env.genesisCar, env.log)
+	if err != nil {
+		return nil, err
+	}
+
+	return env, nil
+}
+
+// GenesisCar provides a url where the genesis file can be fetched from
+func (e *Snapshot) GenesisCar() string {
+	uri := url.URL{
+		Host:   e.genesisServerAddr,
+		Path:   "genesis.car",
+		Scheme: "http",
+	}
+
+	return uri.String()
+}
+
+// GenesisMiner provides required information to create a genesis node and
+// load the wallet.
+func (e *Snapshot) GenesisMiner() (*GenesisMiner, error) {
+	owner, err := json.Marshal(e.manifest.MinerOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GenesisMiner{
+		Address: e.manifest.MinerAddress,
+		Owner:   bytes.NewBuffer(owner),
+	}, nil
+}
+
+// GenesisInitOpts returns the options to initialize the genesis node with, to
+// import the pre-sealed sectors of the genesis miner.
+func (e *Snapshot) GenesisInitOpts() []fast.ProcessInitOption {
+	opts := []fast.ProcessInitOption{
+		fast.POGenesisFile(e.GenesisCar()),
+		fast.POMinerActorAddress(e.manifest.MinerAddress.String()),
+	}
+	if e.manifest.PresealedSectors {
+		opts = append(opts, fast.POPresealedSectorDir(filepath.Join(e.bundle, SnapshotSectorsDir)))
+	}
+	return opts
+}
+
+// SetupGenesisNode initializes and starts `node` as the genesis miner, with
+// the chain of the snapshot as its head. The node must have been created with
+// the GenesisInitOpts.
+func (e *Snapshot) SetupGenesisNode(ctx context.Context, node *fast.Filecoin) error {
+	miner, err := e.GenesisMiner()
+	if err != nil {
+		return err
+	}
+
+	chain, err := os.Open(filepath.Join(e.bundle, SnapshotChainFile))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = chain.Close() }()
+
+	return series.SetupSnapshotGenesisNode(ctx, node, miner.Address, miner.Owner, chain, e.manifest.Head)
+}
+
+// Log returns the logger for the environment.
+func (e *Snapshot) Log() logging.EventLogger {
+	return e.log
+}
+
+// NewProcess builds a iptb process of the given type and options passed. The
+// process is tracked by the environment and returned.
+func (e *Snapshot) NewProcess(ctx context.Context, processType string, options map[string]string, eo fast.FilecoinOpts) (*fast.Filecoin, error) {
+	e.processesMu.Lock()
+	defer e.processesMu.Unlock()
+
+	e.processCountMu.Lock()
+	defer e.processCountMu.Unlock()
+
+	ns := iptb.NodeSpec{
+		Type:  processType,
+		Dir:   fmt.Sprintf("%s/%d", e.location, e.processCount),
+		Attrs: options,
+	}
+	e.processCount = e.processCount + 1
+
+	e.log.Infof("New Process type: %s, dir: %s", processType, ns.Dir)
+
+	if err := os.MkdirAll(ns.Dir, 0775); err != nil {
+		return nil, err
+	}
+
+	c, err := ns.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	// We require a slightly more extended core interface
+	fc, ok := c.(fast.IPTBCoreExt)
+	if !ok {
+		return nil, fmt.Errorf("%s does not implement the extended IPTB.Core interface IPTBCoreExt", processType)
+	}
+
+	p := fast.NewFilecoinProcess(ctx, fc, eo)
+	e.processes = append(e.processes, p)
+	return p, nil
+}
+
+// Processes returns all processes the environment knows about.
+func (e *Snapshot) Processes() []*fast.Filecoin {
+	e.processesMu.Lock()
+	defer e.processesMu.Unlock()
+	return e.processes[:]
+}
+
+// Teardown stops all of the nodes and cleans up the environment. The bundle
+// is left untouched.
+func (e *Snapshot) Teardown(ctx context.Context) error {
+	e.processesMu.Lock()
+	defer e.processesMu.Unlock()
+
+	e.log.Info("Teardown environment")
+	for _, p := range e.processes {
+		if err := p.StopDaemon(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := e.genesisServer.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(e.location)
+}
+
+// TeardownProcess stops the running process and removes it from the
+// environment.
+func (e *Snapshot) TeardownProcess(ctx context.Context, p *fast.Filecoin) error {
+	e.processesMu.Lock()
+	defer e.processesMu.Unlock()
+
+	e.log.Infof("Teardown process: %s", p.String())
+	if err := p.StopDaemon(ctx); err != nil {
+		return err
+	}
+
+	for i, n := range e.processes {
+		if n == p {
+			e.processes = append(e.processes[:i], e.processes[i+1:]...)
+			break
+		}
+	}
+
+	// remove the provess from the process list
+	return os.RemoveAll(p.Dir())
+}
+
+// GetFunds retrieves a fixed amount of tokens from the environment to the
+// Filecoin processes default wallet address.
+// GetFunds will cause the genesis node to send 1000 filecoin to process `p`.
+func (e *Snapshot) GetFunds(ctx context.Context, p *fast.Filecoin) error {
+	e.log.Infof("GetFunds for process: %s", p.String())
+	return series.SendFilecoinDefaults(ctx, e.Processes()[0], p, 1000)
+}
+
+// CreateSnapshot writes a snapshot bundle to the directory `dir` holding the
+// genesis of `env`, the chain of `node` up to its head and the pre-sealed
+// sectors of the genesis miner in `presealedSectorDir`, if not empty. The
+// chain is written by the node, which must share the filesystem.
+func CreateSnapshot(ctx context.Context, env Environment, node *fast.Filecoin, presealedSectorDir string, dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0775); err != nil {
+		return err
+	}
+
+	if err := fetchGenesis(env.GenesisCar(), filepath.Join(dir, SnapshotGenesisFile)); err != nil {
+		return err
+	}
+
+	miner, err := env.GenesisMiner()
+	if err != nil {
+		return err
+	}
+	manifest := SnapshotManifest{MinerAddress: miner.Address}
+	if err := json.NewDecoder(miner.Owner).Decode(&manifest.MinerOwner); err != nil {
+		return err
+	}
+
+	head, err := node.ChainHead(ctx)
+	if err != nil {
+		return err
+	}
+	manifest.Head = block.NewTipSetKey(head...)
+	if err := node.ChainExport(ctx, filepath.Join(dir, SnapshotChainFile), head); err != nil {
+		return err
+	}
+
+	if presealedSectorDir != "" {
+		if err := copyDir(presealedSectorDir, filepath.Join(dir, SnapshotSectorsDir)); err != nil {
+			return err
+		}
+		manifest.PresealedSectors = true
+	}
+
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, SnapshotManifestFile), raw, 0644)
+}
+
+// fetchGenesis writes the genesis file at `uri`, a path or an http(s) url, to
+// `path`.
+func fetchGenesis(uri string, path string) error {
+	var source io.ReadCloser
+	if u, err := url.Parse(uri); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		resp, err := http.Get(uri)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			_ = resp.Body.Close()
+			return fmt.Errorf("failed to fetch genesis file %s: %s", uri, resp.Status)
+		}
+		source = resp.Body
+	} else {
+		file, err := os.Open(uri)
+		if err != nil {
+			return err
+		}
+		source = file
+	}
+	defer func() { _ = source.Close() }()
+
+	return writeFile(path, source, 0644)
+}
+
+// copyDir copies the files of the directory `src` to `dst`.
+func copyDir(src string, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0775)
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = file.Close() }()
+		return writeFile(target, file, info.Mode().Perm())
+	})
+}
+
+func writeFile(path string, src io.Reader, perm os.FileMode) error {
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
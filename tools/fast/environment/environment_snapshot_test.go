@@ -0,0 +1,120 @@
+package environment
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	commands "github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	vmaddr "github.com/filecoin-project/go-filecoin/internal/pkg/vm/address"
+	"github.com/filecoin-project/go-filecoin/tools/fast"
+	mockplugin "github.com/filecoin-project/go-filecoin/tools/iptb-plugins/filecoin/mock"
+)
+
+func TestSnapshot(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+
+	testDir, err := ioutil.TempDir(".", "environmentTest")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(testDir))
+	}()
+
+	ki := types.MustGenerateKeyInfo(1, 42)[0]
+	manifest := SnapshotManifest{
+		Head:             block.NewTipSetKey(types.NewCidForTestGetter()()),
+		MinerAddress:     vmaddr.RequireIDAddress(t, 1000),
+		MinerOwner:       commands.WalletSerializeResult{KeyInfo: []*crypto.KeyInfo{&ki}},
+		PresealedSectors: true,
+	}
+	bundle := filepath.Join(testDir, "bundle")
+	require.NoError(t, os.MkdirAll(bundle, 0775))
+	raw, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bundle, SnapshotManifestFile), raw, 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bundle, SnapshotGenesisFile), []byte("genesis"), 0644))
+
+	location := filepath.Join(testDir, "env")
+	env, err := NewSnapshot(bundle, location)
+	require.NoError(t, err)
+
+	t.Run("serves the genesis of the bundle", func(t *testing.T) {
+		resp, err := http.Get(env.GenesisCar())
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		car, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "genesis", string(car))
+	})
+
+	t.Run("provides the genesis miner", func(t *testing.T) {
+		miner, err := env.GenesisMiner()
+		require.NoError(t, err)
+		assert.Equal(t, manifest.MinerAddress, miner.Address)
+		var owner commands.WalletSerializeResult
+		require.NoError(t, json.NewDecoder(miner.Owner).Decode(&owner))
+		require.Len(t, owner.KeyInfo, 1)
+		assert.True(t, ki.Equals(owner.KeyInfo[0]))
+
+		var args []string
+		for _, opt := range env.GenesisInitOpts() {
+			args = append(args, opt()...)
+		}
+		assert.Contains(t, args, manifest.MinerAddress.String())
+		assert.Contains(t, args, filepath.Join(env.bundle, SnapshotSectorsDir))
+	})
+
+	p, err := env.NewProcess(ctx, mockplugin.PluginName, nil, fast.FilecoinOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(env.Processes()))
+
+	// teardown leaves the bundle in place
+	require.NoError(t, env.Teardown(ctx))
+	_, existsErr := os.Stat(p.Dir())
+	assert.True(t, os.IsNotExist(existsErr))
+	_, err = os.Stat(filepath.Join(bundle, SnapshotManifestFile))
+	assert.NoError(t, err)
+}
+
+func TestCopyDir(t *testing.T) {
+	tf.UnitTest(t)
+
+	testDir, err := ioutil.TempDir(".", "environmentTest")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(testDir))
+	}()
+
+	src := filepath.Join(testDir, "src")
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "cache", "s-t01000-0"), 0775))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(src, "sectors.json"), []byte("{}"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(src, "cache", "s-t01000-0", "t_aux"), []byte("aux"), 0600))
+
+	dst := filepath.Join(testDir, "dst")
+	require.NoError(t, copyDir(src, dst))
+
+	meta, err := ioutil.ReadFile(filepath.Join(dst, "sectors.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(meta))
+	aux, err := ioutil.ReadFile(filepath.Join(dst, "cache", "s-t01000-0", "t_aux"))
+	require.NoError(t, err)
+	assert.Equal(t, "aux", string(aux))
+
+	require.NoError(t, fetchGenesis(filepath.Join(dst, "sectors.json"), filepath.Join(testDir, "genesis.car")))
+	genesis, err := ioutil.ReadFile(filepath.Join(testDir, "genesis.car"))
+	require.NoError(t, err)
+	assert.Equal(t, "{}", string(genesis))
+}
@@ -112,6 +112,70 @@ func makeLocal(ctx context.Context, t *testing.T, dir string, fastenvOpts fast.F
 	}
 }
 
+// NewSnapshotDeploymentEnvironment creates a DeploymentEnvironment like the `local` network
+// of NewDeploymentEnvironment, whose genesis node starts from the chain and the pre-sealed
+// sectors of the snapshot bundle in the directory `bundle` instead of mining from genesis.
+func NewSnapshotDeploymentEnvironment(ctx context.Context, t *testing.T, bundle string, fastenvOpts fast.FilecoinOpts) (context.Context, *DeploymentEnvironment) {
+	dir, err := ioutil.TempDir("", strings.Replace(t.Name(), "/", ".", -1))
+	require.NoError(t, err)
+
+	env, err := environment.NewSnapshot(bundle, dir)
+	require.NoError(t, err)
+
+	defer func() {
+		dumpEnvOutputOnFail(t, env.Processes())
+	}()
+
+	// Setup options for nodes.
+	options := make(map[string]string)
+	options[localplugin.AttrLogJSON] = "0"
+	options[localplugin.AttrLogLevel] = "5"
+	options[localplugin.AttrFilecoinBinary] = testhelpers.MustGetFilecoinBinary()
+
+	fastenvOpts.DaemonOpts = append([]fast.ProcessDaemonOption{fast.POBlockTime(time.Second * 5)}, fastenvOpts.DaemonOpts...)
+
+	ctx = series.SetCtxSleepDelay(ctx, time.Second*5)
+
+	// The genesis node imports the sectors of the genesis miner at init, the other nodes only
+	// need the genesis file
+	genesisOpts := fastenvOpts
+	genesisOpts.InitOpts = append(env.GenesisInitOpts(), fastenvOpts.InitOpts...)
+	genesis, err := env.NewProcess(ctx, localplugin.PluginName, options, genesisOpts)
+	require.NoError(t, err)
+
+	err = env.SetupGenesisNode(ctx, genesis)
+	require.NoError(t, err)
+
+	err = genesis.MiningStart(ctx)
+	require.NoError(t, err)
+
+	details, err := genesis.ID(ctx)
+	require.NoError(t, err)
+
+	fastenvOpts.InitOpts = append([]fast.ProcessInitOption{fast.POGenesisFile(env.GenesisCar())}, fastenvOpts.InitOpts...)
+
+	return ctx, &DeploymentEnvironment{
+		Environment: env,
+		t:           t,
+		ctx:         ctx,
+		pluginName:  localplugin.PluginName,
+		pluginOpts:  options,
+		fastenvOpts: fastenvOpts,
+		postInitFn: func(ctx context.Context, node *fast.Filecoin) error {
+			config, err := node.Config()
+			if err != nil {
+				return err
+			}
+
+			config.Bootstrap.Addresses = []string{details.Addresses[0].String()}
+			config.Bootstrap.MinPeerThreshold = 1
+			config.Bootstrap.Period = "10s"
+
+			return node.WriteConfig(config)
+		},
+	}
+}
+
 func makeDevnet(ctx context.Context, t *testing.T, network string, dir string, fastenvOpts fast.FilecoinOpts) (context.Context, *DeploymentEnvironment) {
 	// Create an environment that includes a genesis block with 1MM FIL
 	networkConfig, err := environment.FindDevnetConfigByName(network)
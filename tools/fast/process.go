@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	logging "github.com/ipfs/go-log/v2"
 	iptb "github.com/ipfs/iptb/testbed"
@@ -14,8 +15,10 @@ import (
 	"github.com/libp2p/go-libp2p-core/peer"
 
 	fcconfig "github.com/filecoin-project/go-filecoin/internal/pkg/config"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/errcode"
 	"github.com/filecoin-project/go-filecoin/tools/fast/fastutil"
 	dockerplugin "github.com/filecoin-project/go-filecoin/tools/iptb-plugins/filecoin/docker"
+	inprocplugin "github.com/filecoin-project/go-filecoin/tools/iptb-plugins/filecoin/inproc"
 	localplugin "github.com/filecoin-project/go-filecoin/tools/iptb-plugins/filecoin/local"
 )
 
@@ -58,6 +61,17 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+
+	_, err = iptb.RegisterPlugin(iptb.IptbPlugin{
+		From:       "<builtin>",
+		NewNode:    inprocplugin.NewNode,
+		PluginName: inprocplugin.PluginName,
+		BuiltIn:    true,
+	}, false)
+
+	if err != nil {
+		panic(err)
+	}
 }
 
 // IPTBCoreExt is an extended interface of the iptb.Core. It defines additional requirement.
@@ -81,6 +95,9 @@ type Filecoin struct {
 	core IPTBCoreExt
 	ctx  context.Context
 
+	// attached is true when the process was created by AttachFilecoinProcess
+	attached bool
+
 	lastCmdOutput testbedi.Output
 
 	stderr io.ReadCloser
@@ -105,6 +122,10 @@ func NewFilecoinProcess(ctx context.Context, c IPTBCoreExt, eo FilecoinOpts) *Fi
 
 // InitDaemon initializes the filecoin daemon process.
 func (f *Filecoin) InitDaemon(ctx context.Context, args ...string) (testbedi.Output, error) {
+	if f.attached {
+		return nil, ErrAttachedProcess
+	}
+
 	if len(args) != 0 && len(f.initOpts) != 0 {
 		return nil, ErrDoubleInitOpts
 	}
@@ -122,6 +143,10 @@ func (f *Filecoin) InitDaemon(ctx context.Context, args ...string) (testbedi.Out
 
 // StartDaemon starts the filecoin daemon process.
 func (f *Filecoin) StartDaemon(ctx context.Context, wait bool, args ...string) (testbedi.Output, error) {
+	if f.attached {
+		return nil, ErrAttachedProcess
+	}
+
 	if len(args) != 0 && len(f.daemonOpts) != 0 {
 		return nil, ErrDoubleDaemonOpts
 	}
@@ -155,6 +180,10 @@ func (f *Filecoin) StartDaemon(ctx context.Context, wait bool, args ...string) (
 
 // StopDaemon stops the filecoin daemon process.
 func (f *Filecoin) StopDaemon(ctx context.Context) error {
+	if f.attached {
+		return ErrAttachedProcess
+	}
+
 	if err := f.core.Stop(ctx); err != nil {
 		// TODO this may break the `IsAlive` parameter
 		return err
@@ -163,6 +192,30 @@ func (f *Filecoin) StopDaemon(ctx context.Context) error {
 	return f.teardownStderrCapturing()
 }
 
+// RestartDaemon stops the filecoin daemon process and starts it again with the
+// daemon options of the process followed by `extra`.
+func (f *Filecoin) RestartDaemon(ctx context.Context, extra ...ProcessDaemonOption) error {
+	if err := f.StopDaemon(ctx); err != nil {
+		return err
+	}
+
+	var args []string
+	for _, opt := range f.daemonOpts {
+		args = append(args, opt()...)
+	}
+	for _, opt := range extra {
+		args = append(args, opt()...)
+	}
+
+	// The options are passed as arguments, which StartDaemon refuses alongside process options
+	daemonOpts := f.daemonOpts
+	f.daemonOpts = nil
+	defer func() { f.daemonOpts = daemonOpts }()
+
+	_, err := f.StartDaemon(ctx, true, args...)
+	return err
+}
+
 // Shell starts a user shell targeting the filecoin process. Exact behavior is plugin
 // dependent. Please refer to the plugin documentation for more information.
 func (f *Filecoin) Shell() error {
@@ -174,6 +227,12 @@ func (f *Filecoin) Dir() string {
 	return f.core.Dir()
 }
 
+// Attached returns true if the process was created by AttachFilecoinProcess and runs
+// commands against a daemon it does not manage.
+func (f *Filecoin) Attached() bool {
+	return f.attached
+}
+
 // String returns the string representation of the filecoin process.
 func (f *Filecoin) String() string {
 	return f.core.String()
@@ -240,7 +299,7 @@ func (f *Filecoin) RunCmdJSONWithStdin(ctx context.Context, stdin io.Reader, v i
 
 	// check command exit code
 	if out.ExitCode() > 0 {
-		return fmt.Errorf("filecoin command: %s, exited with non-zero exitcode: %d", out.Args(), out.ExitCode())
+		return f.commandError(out)
 	}
 
 	dec := json.NewDecoder(out.Stdout())
@@ -260,12 +319,28 @@ func (f *Filecoin) RunCmdLDJSONWithStdin(ctx context.Context, stdin io.Reader, a
 
 	// check command exit code
 	if out.ExitCode() > 0 {
-		return nil, fmt.Errorf("filecoin command: %s, exited with non-zero exitcode: %d", out.Args(), out.ExitCode())
+		return nil, f.commandError(out)
 	}
 
 	return json.NewDecoder(out.Stdout()), nil
 }
 
+// commandError returns the error of a command that exited with a non-zero exit
+// code. It is tagged with the error code the command reported, if any, which
+// errcode.CodeOf returns.
+func (f *Filecoin) commandError(out testbedi.Output) error {
+	err := fmt.Errorf("filecoin command: %s, exited with non-zero exitcode: %d", out.Args(), out.ExitCode())
+	stderr, rerr := f.LastCmdStdErrStr()
+	if rerr != nil {
+		return err
+	}
+	code, msg := errcode.Parse(strings.TrimPrefix(strings.TrimSpace(stderr), "Error: "))
+	if code == errcode.Unknown {
+		return err
+	}
+	return errcode.Errorf(code, "%s: %s", err, msg)
+}
+
 // Config return the config file of the FAST process.
 func (f *Filecoin) Config() (*fcconfig.Config, error) {
 	fcc, err := f.core.Config()
@@ -0,0 +1,237 @@
+package fast
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/ipfs/iptb/testbed/interfaces"
+	"github.com/ipfs/iptb/util"
+	ma "github.com/multiformats/go-multiaddr"
+
+	"github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
+)
+
+// ErrAttachedProcess is returned by lifecycle methods (InitDaemon, StartDaemon, StopDaemon, ...)
+// when called on a Filecoin process created by AttachFilecoinProcess. The lifecycle of an
+// attached daemon is managed outside of FAST.
+var ErrAttachedProcess = errors.New("operation not supported on an attached filecoin process")
+
+// AttachOpts are used to configure a Filecoin process attached to an existing daemon.
+type AttachOpts struct {
+	// BinPath is the go-filecoin binary used to run commands against the daemon. If empty,
+	// the go-filecoin binary found in PATH is used.
+	BinPath string
+
+	// Name is used to identify the process in logs. Defaults to the API address.
+	Name string
+}
+
+// AttachFilecoinProcess returns a pointer to a Filecoin process that runs commands against
+// an already running daemon listening on the API multiaddr `apiAddr`. No repo is created and
+// the daemon is never initialized, started or stopped; series and actions can be used against
+// long-lived nodes such as those in a devnet.
+func AttachFilecoinProcess(ctx context.Context, apiAddr string, opts AttachOpts) (*Filecoin, error) {
+	if _, err := ma.NewMultiaddr(apiAddr); err != nil {
+		return nil, fmt.Errorf("invalid api address %s: %s", apiAddr, err)
+	}
+
+	binPath := opts.BinPath
+	if binPath == "" {
+		var err error
+		if binPath, err = exec.LookPath("go-filecoin"); err != nil {
+			return nil, err
+		}
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = apiAddr
+	}
+
+	c := &attachedCore{
+		binPath: binPath,
+		apiAddr: apiAddr,
+		name:    name,
+	}
+
+	f := &Filecoin{
+		core:     c,
+		Log:      logging.Logger(name),
+		ctx:      ctx,
+		attached: true,
+	}
+
+	idinfo, err := f.ID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	f.PeerID = idinfo.ID
+
+	return f, nil
+}
+
+// attachedCore implements IPTBCoreExt for a daemon FAST does not own. Commands are run with
+// the api address set explicitly, all lifecycle operations fail with ErrAttachedProcess.
+type attachedCore struct {
+	binPath string
+	apiAddr string
+	name    string
+}
+
+var _ IPTBCoreExt = (*attachedCore)(nil)
+
+func (a *attachedCore) Init(ctx context.Context, args ...string) (testbedi.Output, error) {
+	return nil, ErrAttachedProcess
+}
+
+func (a *attachedCore) Start(ctx context.Context, wait bool, args ...string) (testbedi.Output, error) {
+	return nil, ErrAttachedProcess
+}
+
+func (a *attachedCore) Stop(ctx context.Context) error {
+	return ErrAttachedProcess
+}
+
+// RunCmd runs the command against the attached daemon. A leading "go-filecoin" argument
+// is replaced with the configured binary.
+func (a *attachedCore) RunCmd(ctx context.Context, stdin io.Reader, args ...string) (testbedi.Output, error) {
+	if len(args) == 0 {
+		return nil, errors.New("no command provided")
+	}
+
+	name := args[0]
+	if name == "go-filecoin" {
+		name = a.binPath
+	}
+
+	cmdArgs := append([]string{}, args[1:]...)
+	cmdArgs = append(cmdArgs, "--cmdapiaddr="+a.apiAddr)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, cmdArgs...)
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	var err error
+	exitcode := 0
+	switch oerr := cmd.Run().(type) {
+	case *exec.ExitError:
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("context deadline exceeded for command: %q: %s", args, oerr)
+		}
+		exitcode = oerr.ExitCode()
+	case nil:
+	default:
+		return nil, oerr
+	}
+
+	return iptbutil.NewOutput(args, stdout.Bytes(), stderr.Bytes(), exitcode, err), nil
+}
+
+func (a *attachedCore) Connect(ctx context.Context, n testbedi.Core) error {
+	swarmaddrs, err := n.SwarmAddrs()
+	if err != nil {
+		return err
+	}
+
+	out, err := a.RunCmd(ctx, nil, "go-filecoin", "swarm", "connect", swarmaddrs[0])
+	if err != nil {
+		return err
+	}
+
+	if out.ExitCode() != 0 {
+		stderr, err := ioutil.ReadAll(out.Stderr())
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%s", string(stderr))
+	}
+
+	return nil
+}
+
+func (a *attachedCore) Shell(ctx context.Context, ns []testbedi.Core) error {
+	return ErrAttachedProcess
+}
+
+// Dir returns an empty string, an attached process has no local directory.
+func (a *attachedCore) Dir() string {
+	return ""
+}
+
+func (a *attachedCore) Type() string {
+	return "attachedfilecoin"
+}
+
+func (a *attachedCore) String() string {
+	return a.name
+}
+
+func (a *attachedCore) PeerID() (string, error) {
+	details, err := a.id()
+	if err != nil {
+		return "", err
+	}
+
+	return details.ID.String(), nil
+}
+
+func (a *attachedCore) APIAddr() (string, error) {
+	return a.apiAddr, nil
+}
+
+func (a *attachedCore) SwarmAddrs() ([]string, error) {
+	details, err := a.id()
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []string
+	for _, addr := range details.Addresses {
+		addrs = append(addrs, addr.String())
+	}
+
+	return addrs, nil
+}
+
+// Config is unsupported, the config of an attached daemon can be read through ConfigGet.
+func (a *attachedCore) Config() (interface{}, error) {
+	return nil, ErrAttachedProcess
+}
+
+// WriteConfig is unsupported, the config of an attached daemon can be changed through ConfigSet.
+func (a *attachedCore) WriteConfig(interface{}) error {
+	return ErrAttachedProcess
+}
+
+// StderrReader returns an empty reader, the daemon's stderr belongs to whoever started it.
+func (a *attachedCore) StderrReader() (io.ReadCloser, error) {
+	return ioutil.NopCloser(&bytes.Buffer{}), nil
+}
+
+func (a *attachedCore) id() (*commands.IDDetails, error) {
+	out, err := a.RunCmd(context.Background(), nil, "go-filecoin", "id", "--enc=json")
+	if err != nil {
+		return nil, err
+	}
+
+	if out.ExitCode() > 0 {
+		return nil, fmt.Errorf("filecoin command: %s, exited with non-zero exitcode: %d", out.Args(), out.ExitCode())
+	}
+
+	var details commands.IDDetails
+	if err := json.NewDecoder(out.Stdout()).Decode(&details); err != nil {
+		return nil, err
+	}
+
+	return &details, nil
+}
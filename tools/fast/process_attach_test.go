@@ -0,0 +1,89 @@
+package fast
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+// writeFakeBinary writes a script standing in for go-filecoin. It answers `id` with
+// the given peer id and records the arguments of every call in the file args.
+func writeFakeBinary(t *testing.T, dir string, pid peer.ID) string {
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$@" >> %s
+if [ "$1" = "id" ]; then
+  echo '{"Addresses":["/ip4/127.0.0.1/tcp/6000"],"ID":"%s"}'
+  exit 0
+fi
+echo '{}'
+`, filepath.Join(dir, "args"), pid.Pretty())
+
+	bin := filepath.Join(dir, "go-filecoin")
+	require.NoError(t, ioutil.WriteFile(bin, []byte(script), 0755))
+	return bin
+}
+
+func TestAttachFilecoinProcess(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "fast-attach")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	_, pub, err := crypto.GenerateEd25519Key(nil)
+	require.NoError(t, err)
+	pid, err := peer.IDFromPublicKey(pub)
+	require.NoError(t, err)
+
+	apiAddr := "/ip4/127.0.0.1/tcp/3453"
+	bin := writeFakeBinary(t, dir, pid)
+
+	fc, err := AttachFilecoinProcess(ctx, apiAddr, AttachOpts{BinPath: bin})
+	require.NoError(t, err)
+
+	assert.True(t, fc.Attached())
+	assert.Equal(t, pid, fc.PeerID)
+	assert.Equal(t, apiAddr, fc.String())
+
+	t.Run("commands target the api address", func(t *testing.T) {
+		_, err := fc.RunCmdWithStdin(ctx, nil, "go-filecoin", "chain", "head")
+		require.NoError(t, err)
+
+		args, err := ioutil.ReadFile(filepath.Join(dir, "args"))
+		require.NoError(t, err)
+		assert.Contains(t, string(args), "chain head --cmdapiaddr="+apiAddr)
+	})
+
+	t.Run("swarm addresses come from the daemon", func(t *testing.T) {
+		addrs, err := fc.core.SwarmAddrs()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"/ip4/127.0.0.1/tcp/6000"}, addrs)
+	})
+
+	t.Run("lifecycle is not managed", func(t *testing.T) {
+		_, err := fc.InitDaemon(ctx)
+		assert.Equal(t, ErrAttachedProcess, err)
+
+		_, err = fc.StartDaemon(ctx, true)
+		assert.Equal(t, ErrAttachedProcess, err)
+
+		assert.Equal(t, ErrAttachedProcess, fc.StopDaemon(ctx))
+	})
+
+	t.Run("invalid api address", func(t *testing.T) {
+		_, err := AttachFilecoinProcess(ctx, "127.0.0.1:3453", AttachOpts{BinPath: bin})
+		assert.Error(t, err)
+	})
+}
@@ -31,6 +31,20 @@ func POAutoSealIntervalSeconds(seconds int) ProcessInitOption {
 	}
 }
 
+// POPresealedSectorDir provides the `--presealed-sectordir=<path>` option to process at init
+func POPresealedSectorDir(dir string) ProcessInitOption {
+	return func() []string {
+		return []string{"--presealed-sectordir", dir}
+	}
+}
+
+// POMinerActorAddress provides the `--miner-actor-address=<address>` option to process at init
+func POMinerActorAddress(addr string) ProcessInitOption {
+	return func() []string {
+		return []string{"--miner-actor-address", addr}
+	}
+}
+
 // PODevnet provides the `--devnet-<net>` option to process at init
 func PODevnet(net string) ProcessInitOption {
 	return func() []string {
@@ -69,6 +83,13 @@ func POBlockTime(d time.Duration) ProcessDaemonOption {
 	}
 }
 
+// POClockSkew provides the `--clock-skew=<duration>` to process when starting.
+func POClockSkew(d time.Duration) ProcessDaemonOption {
+	return func() []string {
+		return []string{"--clock-skew", d.String()}
+	}
+}
+
 // POIsRelay provides the `--is-relay` to process when starting.
 func POIsRelay() ProcessDaemonOption {
 	return func() []string {
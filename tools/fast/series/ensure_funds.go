@@ -0,0 +1,62 @@
+package series
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/filecoin-project/go-address"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/tools/fast"
+)
+
+// FundsFunc requests tokens for a filecoin process, environment.Environment#GetFunds
+// satisfies it.
+type FundsFunc func(context.Context, *fast.Filecoin) error
+
+// BalanceFunc returns the balance of the address funding deals.
+type BalanceFunc func(context.Context) (types.AttoFIL, error)
+
+// EnsureFunds checks the balance of `addr` on node `fc` and, while it is below `threshold`,
+// requests more tokens with `getFunds`, waiting between attempts with CtxSleepDelay until
+// the funds arrive. At most `maxRequests` requests are made before an error reporting the
+// balance shortfall is returned. The final balance is returned.
+func EnsureFunds(ctx context.Context, fc *fast.Filecoin, addr address.Address, threshold types.AttoFIL, maxRequests int, getFunds FundsFunc) (types.AttoFIL, error) {
+	balance := func(ctx context.Context) (types.AttoFIL, error) {
+		return fc.WalletBalance(ctx, addr)
+	}
+	requestFunds := func(ctx context.Context, balance types.AttoFIL) error {
+		fc.Log.Infof("balance of %s is %s, below %s, requesting funds", addr, balance, threshold)
+		return getFunds(ctx, fc)
+	}
+	return ensureFunds(ctx, addr, balance, threshold, maxRequests, requestFunds)
+}
+
+func ensureFunds(ctx context.Context, addr address.Address, getBalance BalanceFunc, threshold types.AttoFIL, maxRequests int, requestFunds func(context.Context, types.AttoFIL) error) (types.AttoFIL, error) {
+	requests := 0
+	for {
+		balance, err := getBalance(ctx)
+		if err != nil {
+			return types.ZeroAttoFIL, err
+		}
+
+		if balance.GreaterThanEqual(threshold) {
+			return balance, nil
+		}
+
+		if requests >= maxRequests {
+			return balance, fmt.Errorf("balance of %s is %s after %d funding requests, need at least %s", addr, balance, requests, threshold)
+		}
+
+		if err := requestFunds(ctx, balance); err != nil {
+			return balance, err
+		}
+		requests++
+
+		select {
+		case <-ctx.Done():
+			return balance, ctx.Err()
+		case <-CtxSleepDelay(ctx):
+		}
+	}
+}
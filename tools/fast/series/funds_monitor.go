@@ -0,0 +1,221 @@
+package series
+
+import (
+	"context"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/tools/fast"
+)
+
+// FundsMonitor gates deal-making on the balance of the address funding the
+// deals of a node. Deals wait in WaitForFunds while the monitor is paused,
+// which it is while funds requested with the FundsFunc because the balance is
+// below the threshold have not arrived yet, or while any explicit Pause has not
+// been matched by a Resume.
+type FundsMonitor struct {
+	addr         address.Address
+	balance      BalanceFunc
+	threshold    types.AttoFIL
+	maxRequests  int
+	requestFunds func(context.Context, types.AttoFIL) error
+
+	lk      sync.Mutex
+	pauses  int  // explicit pauses not resumed yet
+	funding bool // a caller of WaitForFunds is requesting funds
+	resumed chan struct{}
+}
+
+// NewFundsMonitor creates a monitor of the balance of `addr` on node `fc`,
+// requesting funds with `getFunds` up to `maxRequests` times whenever the
+// balance falls below `threshold`.
+func NewFundsMonitor(fc *fast.Filecoin, addr address.Address, threshold types.AttoFIL, maxRequests int, getFunds FundsFunc) *FundsMonitor {
+	balance := func(ctx context.Context) (types.AttoFIL, error) {
+		return fc.WalletBalance(ctx, addr)
+	}
+	requestFunds := func(ctx context.Context, balance types.AttoFIL) error {
+		fc.Log.Infof("balance of %s is %s, below %s, pausing deals and requesting funds", addr, balance, threshold)
+		return getFunds(ctx, fc)
+	}
+	return newFundsMonitor(addr, balance, threshold, maxRequests, requestFunds)
+}
+
+func newFundsMonitor(addr address.Address, balance BalanceFunc, threshold types.AttoFIL, maxRequests int, requestFunds func(context.Context, types.AttoFIL) error) *FundsMonitor {
+	return &FundsMonitor{
+		addr:         addr,
+		balance:      balance,
+		threshold:    threshold,
+		maxRequests:  maxRequests,
+		requestFunds: requestFunds,
+		resumed:      make(chan struct{}),
+	}
+}
+
+// Pause makes WaitForFunds block until Resume is called. Pauses nest: each
+// Pause must be matched by a Resume for deal-making to resume.
+func (m *FundsMonitor) Pause() {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	m.setPaused(func() { m.pauses++ })
+}
+
+// Resume releases a pause made with Pause, releasing the callers blocked in
+// WaitForFunds once no pause remains and no funds are being requested.
+func (m *FundsMonitor) Resume() {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if m.pauses > 0 {
+		m.setPaused(func() { m.pauses-- })
+	}
+}
+
+// Paused returns whether deal-making is paused.
+func (m *FundsMonitor) Paused() bool {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	return m.paused()
+}
+
+// WaitForFunds waits until the monitor is not paused and the balance is at
+// least the threshold, requesting funds while it is below. Deal-making is
+// paused while the funds are requested, and an error is returned if they do
+// not arrive after the maximum number of requests. The pauses held by other
+// callers are left as they were on every return. The balance is returned.
+func (m *FundsMonitor) WaitForFunds(ctx context.Context) (types.AttoFIL, error) {
+	for {
+		if err := m.waitResumed(ctx); err != nil {
+			return types.ZeroAttoFIL, err
+		}
+
+		balance, err := m.balance(ctx)
+		if err != nil {
+			return types.ZeroAttoFIL, err
+		}
+		if balance.GreaterThanEqual(m.threshold) {
+			return balance, nil
+		}
+
+		// only one caller requests funds, the others wait for them
+		if !m.startFunding() {
+			continue
+		}
+		balance, err = ensureFunds(ctx, m.addr, m.balance, m.threshold, m.maxRequests, m.requestFunds)
+		m.endFunding()
+		return balance, err
+	}
+}
+
+// startFunding pauses the monitor for a request of funds, returning false if
+// funds are being requested already.
+func (m *FundsMonitor) startFunding() bool {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if m.funding {
+		return false
+	}
+	m.setPaused(func() { m.funding = true })
+	return true
+}
+
+func (m *FundsMonitor) endFunding() {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	m.setPaused(func() { m.funding = false })
+}
+
+func (m *FundsMonitor) paused() bool {
+	return m.pauses > 0 || m.funding
+}
+
+// setPaused applies `update` to the pause state, replacing the resumed channel
+// when the monitor becomes paused and closing it when it resumes. It must be
+// called with the lock held.
+func (m *FundsMonitor) setPaused(update func()) {
+	wasPaused := m.paused()
+	update()
+	switch isPaused := m.paused(); {
+	case isPaused && !wasPaused:
+		m.resumed = make(chan struct{})
+	case !isPaused && wasPaused:
+		close(m.resumed)
+	}
+}
+
+func (m *FundsMonitor) waitResumed(ctx context.Context) error {
+	m.lk.Lock()
+	paused, resumed := m.paused(), m.resumed
+	m.lk.Unlock()
+	if !paused {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-resumed:
+		return nil
+	}
+}
+
+// SpendLedger accounts for the funds spent on the deals made with each miner.
+// Its methods are thread safe.
+type SpendLedger struct {
+	lk    sync.Mutex
+	spent map[address.Address]types.AttoFIL
+	deals map[address.Address]int
+}
+
+// NewSpendLedger creates an empty spend ledger.
+func NewSpendLedger() *SpendLedger {
+	return &SpendLedger{
+		spent: make(map[address.Address]types.AttoFIL),
+		deals: make(map[address.Address]int),
+	}
+}
+
+// Record accounts for a deal with `miner` costing `amount`.
+func (l *SpendLedger) Record(miner address.Address, amount types.AttoFIL) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+	spent, ok := l.spent[miner]
+	if !ok {
+		spent = types.ZeroAttoFIL
+	}
+	l.spent[miner] = big.Add(spent, amount)
+	l.deals[miner]++
+}
+
+// Spent returns the funds spent on the deals with `miner` and their number.
+func (l *SpendLedger) Spent(miner address.Address) (types.AttoFIL, int) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+	spent, ok := l.spent[miner]
+	if !ok {
+		return types.ZeroAttoFIL, 0
+	}
+	return spent, l.deals[miner]
+}
+
+// Total returns the funds spent on deals with all miners.
+func (l *SpendLedger) Total() types.AttoFIL {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+	total := types.ZeroAttoFIL
+	for _, spent := range l.spent {
+		total = big.Add(total, spent)
+	}
+	return total
+}
+
+// Miners returns the miners deals were recorded with.
+func (l *SpendLedger) Miners() []address.Address {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+	miners := make([]address.Address, 0, len(l.spent))
+	for miner := range l.spent {
+		miners = append(miners, miner)
+	}
+	return miners
+}
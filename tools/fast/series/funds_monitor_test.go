@@ -0,0 +1,208 @@
+package series
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	"github.com/filecoin-project/go-filecoin/tools/fast"
+)
+
+// fakeWallet is a balance topped up by a fake FundsFunc.
+type fakeWallet struct {
+	lk       sync.Mutex
+	balance  types.AttoFIL
+	grant    types.AttoFIL
+	requests int
+}
+
+func (w *fakeWallet) Balance(context.Context) (types.AttoFIL, error) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+	return w.balance, nil
+}
+
+func (w *fakeWallet) GetFunds(context.Context, *fast.Filecoin) error {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+	w.requests++
+	w.balance = big.Add(w.balance, w.grant)
+	return nil
+}
+
+func (w *fakeWallet) requestFunds(ctx context.Context, _ types.AttoFIL) error {
+	return w.GetFunds(ctx, nil)
+}
+
+func TestEnsureFunds(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := SetCtxSleepDelay(context.Background(), time.Millisecond)
+	addr, err := address.NewIDAddress(100)
+	require.NoError(t, err)
+
+	t.Log("funds are requested until the balance reaches the threshold")
+	w := &fakeWallet{balance: types.NewAttoFILFromFIL(1), grant: types.NewAttoFILFromFIL(2)}
+	balance, err := ensureFunds(ctx, addr, w.Balance, types.NewAttoFILFromFIL(4), 3, w.requestFunds)
+	require.NoError(t, err)
+	assert.Equal(t, types.NewAttoFILFromFIL(5), balance)
+	assert.Equal(t, 2, w.requests)
+
+	t.Log("the shortfall is reported after the maximum number of requests")
+	w = &fakeWallet{balance: types.NewAttoFILFromFIL(1), grant: types.ZeroAttoFIL}
+	_, err = ensureFunds(ctx, addr, w.Balance, types.NewAttoFILFromFIL(4), 2, w.requestFunds)
+	assert.Error(t, err)
+	assert.Equal(t, 2, w.requests)
+}
+
+func TestFundsMonitor(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := SetCtxSleepDelay(context.Background(), time.Millisecond)
+	addr, err := address.NewIDAddress(100)
+	require.NoError(t, err)
+
+	w := &fakeWallet{balance: types.NewAttoFILFromFIL(5), grant: types.NewAttoFILFromFIL(5)}
+	m := newFundsMonitor(addr, w.Balance, types.NewAttoFILFromFIL(4), 2, w.requestFunds)
+
+	t.Log("deals proceed while the balance is above the threshold")
+	balance, err := m.WaitForFunds(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, types.NewAttoFILFromFIL(5), balance)
+	assert.Equal(t, 0, w.requests)
+	assert.False(t, m.Paused())
+
+	t.Log("deals wait while paused until resumed")
+	m.Pause()
+	assert.True(t, m.Paused())
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	_, err = m.WaitForFunds(waitCtx)
+	cancel()
+	assert.Equal(t, context.DeadlineExceeded, err)
+	done := make(chan error)
+	go func() {
+		_, err := m.WaitForFunds(ctx)
+		done <- err
+	}()
+	m.Resume()
+	require.NoError(t, <-done)
+
+	t.Log("funds are requested once the balance falls below the threshold, then deals resume")
+	w.lk.Lock()
+	w.balance = types.NewAttoFILFromFIL(1)
+	w.lk.Unlock()
+	balance, err = m.WaitForFunds(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, types.NewAttoFILFromFIL(6), balance)
+	assert.Equal(t, 1, w.requests)
+	assert.False(t, m.Paused())
+
+	t.Log("the shortfall is reported when the funds do not arrive, without leaving deals paused")
+	w.lk.Lock()
+	w.balance = types.NewAttoFILFromFIL(1)
+	w.grant = types.ZeroAttoFIL
+	w.lk.Unlock()
+	_, err = m.WaitForFunds(ctx)
+	assert.Error(t, err)
+	assert.False(t, m.Paused())
+
+	t.Log("pauses nest")
+	m.Pause()
+	m.Pause()
+	m.Resume()
+	assert.True(t, m.Paused())
+	m.Resume()
+	assert.False(t, m.Paused())
+	m.Resume()
+	assert.False(t, m.Paused())
+}
+
+func TestFundsMonitorKeepsCallerPause(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := SetCtxSleepDelay(context.Background(), time.Millisecond)
+	addr, err := address.NewIDAddress(100)
+	require.NoError(t, err)
+
+	requested := make(chan struct{})
+	release := make(chan error)
+	m := newFundsMonitor(addr, func(context.Context) (types.AttoFIL, error) {
+		return types.ZeroAttoFIL, nil
+	}, types.NewAttoFILFromFIL(1), 1, func(context.Context, types.AttoFIL) error {
+		requested <- struct{}{}
+		return <-release
+	})
+
+	t.Log("a request of funds failing while a caller holds a pause leaves the pause held")
+	done := make(chan error)
+	go func() {
+		_, err := m.WaitForFunds(ctx)
+		done <- err
+	}()
+	<-requested
+	m.Pause()
+	release <- errors.New("faucet unavailable")
+	assert.EqualError(t, <-done, "faucet unavailable")
+	assert.True(t, m.Paused())
+
+	t.Log("deals wait for the caller to resume")
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	_, err = m.WaitForFunds(waitCtx)
+	cancel()
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	m.Resume()
+	assert.False(t, m.Paused())
+}
+
+func TestFundsMonitorRequestErrors(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := SetCtxSleepDelay(context.Background(), time.Millisecond)
+	addr, err := address.NewIDAddress(100)
+	require.NoError(t, err)
+
+	m := newFundsMonitor(addr, func(context.Context) (types.AttoFIL, error) {
+		return types.ZeroAttoFIL, nil
+	}, types.NewAttoFILFromFIL(1), 2, func(context.Context, types.AttoFIL) error {
+		return errors.New("faucet unavailable")
+	})
+	_, err = m.WaitForFunds(ctx)
+	assert.EqualError(t, err, "faucet unavailable")
+}
+
+func TestSpendLedger(t *testing.T) {
+	tf.UnitTest(t)
+
+	minerA, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	minerB, err := address.NewIDAddress(1001)
+	require.NoError(t, err)
+
+	l := NewSpendLedger()
+	spent, deals := l.Spent(minerA)
+	assert.Equal(t, types.ZeroAttoFIL, spent)
+	assert.Equal(t, 0, deals)
+
+	l.Record(minerA, types.NewAttoFILFromFIL(2))
+	l.Record(minerA, types.NewAttoFILFromFIL(3))
+	l.Record(minerB, types.NewAttoFILFromFIL(1))
+
+	spent, deals = l.Spent(minerA)
+	assert.Equal(t, types.NewAttoFILFromFIL(5), spent)
+	assert.Equal(t, 2, deals)
+	spent, deals = l.Spent(minerB)
+	assert.Equal(t, types.NewAttoFILFromFIL(1), spent)
+	assert.Equal(t, 1, deals)
+	assert.Equal(t, types.NewAttoFILFromFIL(6), l.Total())
+	assert.ElementsMatch(t, []address.Address{minerA, minerB}, l.Miners())
+}
@@ -0,0 +1,50 @@
+package series
+
+import (
+	"context"
+	"io"
+	"math/big"
+
+	"github.com/filecoin-project/go-address"
+	files "github.com/ipfs/go-ipfs-files"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/block"
+	"github.com/filecoin-project/go-filecoin/tools/fast"
+)
+
+// SetupSnapshotGenesisNode will initialize and start the filecoin process
+// `node`, import the chain in `chain` and set `head` as its head. Process
+// `node` will be configured with miner `minerAddress`, import the address of
+// the miner `minerOwner` and announce its peer id for the miner. The chain
+// must have been built on the genesis the process is initialized with.
+func SetupSnapshotGenesisNode(ctx context.Context, node *fast.Filecoin, minerAddress address.Address, minerOwner io.Reader, chain io.Reader, head block.TipSetKey) error {
+	if _, err := node.InitDaemon(ctx); err != nil {
+		return err
+	}
+
+	if _, err := node.StartDaemon(ctx, true); err != nil {
+		return err
+	}
+
+	if _, err := node.ChainImport(ctx, files.NewReaderFile(chain)); err != nil {
+		return err
+	}
+	if err := node.ChainSetHead(ctx, head); err != nil {
+		return err
+	}
+
+	if err := node.ConfigSet(ctx, "mining.minerAddress", minerAddress.String()); err != nil {
+		return err
+	}
+
+	wallet, err := node.WalletImport(ctx, files.NewReaderFile(minerOwner))
+	if err != nil {
+		return err
+	}
+	if err := node.ConfigSet(ctx, "wallet.defaultAddress", wallet[0].String()); err != nil {
+		return err
+	}
+
+	_, err = node.MinerUpdatePeerid(ctx, minerAddress, node.PeerID, fast.AOFromAddr(wallet[0]), fast.AOPrice(big.NewFloat(.0000001)), fast.AOLimit(1))
+	return err
+}
@@ -0,0 +1,244 @@
+// Package collateral tracks the grants of the faucet for the collateral of new
+// miners. Each address is granted collateral once, and must create a miner it
+// owns within a number of blocks of the grant.
+package collateral
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/pkg/errors"
+)
+
+var log = logging.Logger("faucet/collateral")
+
+// ErrRepeatGrant is returned when collateral was already granted to an address.
+var ErrRepeatGrant = errors.New("collateral was already granted to this address")
+
+// ErrAlreadyMiner is returned when an address asking for collateral already
+// owns a miner.
+var ErrAlreadyMiner = errors.New("address already owns a miner")
+
+// State is the state of the verification of a grant.
+type State string
+
+const (
+	// StatePending is a grant whose address has not created a miner yet.
+	StatePending = State("pending")
+	// StateVerified is a grant whose address created a miner in time.
+	StateVerified = State("verified")
+	// StateExpired is a grant whose address did not create a miner in time.
+	StateExpired = State("expired")
+)
+
+// Grant is collateral granted to an address.
+type Grant struct {
+	Address address.Address
+	// Message is the message sending the collateral.
+	Message cid.Cid
+	// Height is the height of the head when the collateral was granted, the
+	// address must create a miner by Deadline.
+	Height   abi.ChainEpoch
+	Deadline abi.ChainEpoch
+	State    State
+	// Miner is the miner created by the address once verified.
+	Miner address.Address
+}
+
+// Chain is the chain on which grants are verified.
+type Chain interface {
+	// Miners returns the height of the head and the miners at the head.
+	Miners(ctx context.Context) (abi.ChainEpoch, []address.Address, error)
+	// MinerOwner returns the public key address of the owner of `miner`.
+	MinerOwner(ctx context.Context, miner address.Address) (address.Address, error)
+}
+
+// Granter grants collateral to addresses that own no miner, at most once per
+// address, and verifies that they create a miner within `window` blocks. Its
+// methods are thread safe.
+type Granter struct {
+	chain  Chain
+	window abi.ChainEpoch
+	// path is the file in which grants are persisted, none when empty.
+	path string
+
+	lk     sync.Mutex
+	grants map[address.Address]*Grant
+	// owners maps the owners of the miners looked up to their miners.
+	owners map[address.Address]address.Address
+	miners map[address.Address]struct{}
+}
+
+// NewGranter creates a granter verifying grants against `chain`, which must
+// create a miner within `window` blocks. Grants are persisted in the file
+// `path` and loaded from it when it exists, unless `path` is empty.
+func NewGranter(chain Chain, window abi.ChainEpoch, path string) (*Granter, error) {
+	g := &Granter{
+		chain:  chain,
+		window: window,
+		path:   path,
+		grants: map[address.Address]*Grant{},
+		owners: map[address.Address]address.Address{},
+		miners: map[address.Address]struct{}{},
+	}
+	if path == "" {
+		return g, nil
+	}
+	raw, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return g, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to read grants")
+	}
+	var grants []*Grant
+	if err := json.Unmarshal(raw, &grants); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode grants of %s", path)
+	}
+	for _, grant := range grants {
+		g.grants[grant.Address] = grant
+	}
+	return g, nil
+}
+
+// Grant grants collateral to `addr` by calling `send`, which sends it and
+// returns the CID of the message, unless collateral was granted to the address
+// before or the address already owns a miner.
+func (g *Granter) Grant(ctx context.Context, addr address.Address, send func() (cid.Cid, error)) (*Grant, error) {
+	g.lk.Lock()
+	defer g.lk.Unlock()
+
+	if _, ok := g.grants[addr]; ok {
+		return nil, ErrRepeatGrant
+	}
+	height, err := g.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := g.owners[addr]; ok {
+		return nil, ErrAlreadyMiner
+	}
+
+	msg, err := send()
+	if err != nil {
+		return nil, err
+	}
+	grant := &Grant{
+		Address:  addr,
+		Message:  msg,
+		Height:   height,
+		Deadline: height + g.window,
+		State:    StatePending,
+	}
+	g.grants[addr] = grant
+	if err := g.save(); err != nil {
+		log.Errorf("failed to save grants: %s", err)
+	}
+	c := *grant
+	return &c, nil
+}
+
+// Verify looks up the miners created since the last verification, verifies
+// the pending grants whose address owns one of them and expires those past
+// their deadline.
+func (g *Granter) Verify(ctx context.Context) error {
+	g.lk.Lock()
+	defer g.lk.Unlock()
+
+	height, err := g.refresh(ctx)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for addr, grant := range g.grants {
+		if grant.State != StatePending {
+			continue
+		}
+		if miner, ok := g.owners[addr]; ok {
+			log.Infof("verified collateral grant to %s, which created miner %s", addr, miner)
+			grant.State = StateVerified
+			grant.Miner = miner
+			changed = true
+		} else if height > grant.Deadline {
+			log.Warnf("collateral grant to %s expired at height %d without a miner", addr, grant.Deadline)
+			grant.State = StateExpired
+			changed = true
+		}
+	}
+	if changed {
+		return g.save()
+	}
+	return nil
+}
+
+// Get returns the grant to `addr`, if any.
+func (g *Granter) Get(addr address.Address) (*Grant, bool) {
+	g.lk.Lock()
+	defer g.lk.Unlock()
+	grant, ok := g.grants[addr]
+	if !ok {
+		return nil, false
+	}
+	c := *grant
+	return &c, true
+}
+
+// Grants lists the grants, ordered by height.
+func (g *Granter) Grants() []Grant {
+	g.lk.Lock()
+	defer g.lk.Unlock()
+	return g.list()
+}
+
+func (g *Granter) list() []Grant {
+	out := make([]Grant, 0, len(g.grants))
+	for _, grant := range g.grants {
+		out = append(out, *grant)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Height != out[j].Height {
+			return out[i].Height < out[j].Height
+		}
+		return out[i].Address.String() < out[j].Address.String()
+	})
+	return out
+}
+
+// refresh looks up the owners of the miners not seen before and returns the
+// height of the head.
+func (g *Granter) refresh(ctx context.Context) (abi.ChainEpoch, error) {
+	height, miners, err := g.chain.Miners(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list miners")
+	}
+	for _, miner := range miners {
+		if _, ok := g.miners[miner]; ok {
+			continue
+		}
+		owner, err := g.chain.MinerOwner(ctx, miner)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to get owner of miner %s", miner)
+		}
+		g.miners[miner] = struct{}{}
+		g.owners[owner] = miner
+	}
+	return height, nil
+}
+
+func (g *Granter) save() error {
+	if g.path == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(g.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(g.path, raw, 0644)
+}
@@ -0,0 +1,98 @@
+package collateral
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	vmaddr "github.com/filecoin-project/go-filecoin/internal/pkg/vm/address"
+)
+
+type fakeChain struct {
+	height abi.ChainEpoch
+	owners map[address.Address]address.Address
+}
+
+func (f *fakeChain) Miners(context.Context) (abi.ChainEpoch, []address.Address, error) {
+	var miners []address.Address
+	for miner := range f.owners {
+		miners = append(miners, miner)
+	}
+	return f.height, miners, nil
+}
+
+func (f *fakeChain) MinerOwner(_ context.Context, miner address.Address) (address.Address, error) {
+	return f.owners[miner], nil
+}
+
+func TestGranter(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "collateral")
+	require.NoError(t, err)
+	defer func() { require.NoError(t, os.RemoveAll(dir)) }()
+	path := filepath.Join(dir, "grants.json")
+
+	addrs := vmaddr.NewForTestGetter()
+	owner, late, miner := addrs(), addrs(), addrs()
+	existing, existingOwner := addrs(), addrs()
+	chain := &fakeChain{height: 10, owners: map[address.Address]address.Address{existing: existingOwner}}
+	g, err := NewGranter(chain, 5, path)
+	require.NoError(t, err)
+
+	sent := 0
+	send := func() (cid.Cid, error) {
+		sent++
+		return types.CidFromString(t, "msg"), nil
+	}
+
+	t.Log("addresses owning a miner are denied collateral")
+	_, err = g.Grant(ctx, existingOwner, send)
+	assert.Equal(t, ErrAlreadyMiner, err)
+
+	t.Log("collateral is granted once per address")
+	grant, err := g.Grant(ctx, owner, send)
+	require.NoError(t, err)
+	assert.Equal(t, abi.ChainEpoch(15), grant.Deadline)
+	assert.Equal(t, StatePending, grant.State)
+	_, err = g.Grant(ctx, owner, send)
+	assert.Equal(t, ErrRepeatGrant, err)
+	_, err = g.Grant(ctx, late, send)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+
+	t.Log("grants are verified once the address creates a miner")
+	chain.owners[miner] = owner
+	chain.height = 14
+	require.NoError(t, g.Verify(ctx))
+	grant, ok := g.Get(owner)
+	require.True(t, ok)
+	assert.Equal(t, StateVerified, grant.State)
+	assert.Equal(t, miner, grant.Miner)
+
+	t.Log("and expire past the deadline")
+	grant, _ = g.Get(late)
+	assert.Equal(t, StatePending, grant.State)
+	chain.height = 16
+	require.NoError(t, g.Verify(ctx))
+	grant, _ = g.Get(late)
+	assert.Equal(t, StateExpired, grant.State)
+
+	t.Log("grants are persisted")
+	reloaded, err := NewGranter(chain, 5, path)
+	require.NoError(t, err)
+	assert.Equal(t, g.Grants(), reloaded.Grants())
+	_, err = reloaded.Grant(ctx, late, send)
+	assert.Equal(t, ErrRepeatGrant, err)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -9,9 +10,13 @@ import (
 	"time"
 
 	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
 	"github.com/ipfs/go-cid"
 	logging "github.com/ipfs/go-log/v2"
+	"github.com/pkg/errors"
 
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/tools/faucet/collateral"
 	"github.com/filecoin-project/go-filecoin/tools/faucet/limiter"
 )
 
@@ -40,6 +45,10 @@ func main() {
 	filwal := flag.String("fil-wallet", "", "(required) set the wallet address for the controlled filecoin node to send funds from")
 	expiry := flag.Duration("limiter-expiry", defaultLimiterExpiry, "minimum time duration between faucet request to the same wallet addr")
 	faucetval := flag.Int64("faucet-val", 500, "set the amount of fil to pay to each requester")
+	collateralval := flag.Int64("collateral-val", 0, "set the amount of fil granted once to each address for the collateral of a new miner, 0 disables collateral grants")
+	collateralBlocks := flag.Uint64("collateral-blocks", 100, "number of blocks within which an address granted collateral must create a miner")
+	collateralState := flag.String("collateral-state", "", "file in which collateral grants are persisted across restarts")
+	verifyInterval := flag.Duration("collateral-verify-interval", time.Minute, "time between verifications of collateral grants on chain")
 	flag.Parse()
 
 	if *filwal == "" {
@@ -81,49 +90,164 @@ func main() {
 			return
 		}
 
-		reqStr := fmt.Sprintf("http://%s/api/message/send?arg=%s&value=%d&from=%s&gas-price=0.0001&gas-limit=1000", *filapi, addr, *faucetval, *filwal)
-		log.Infof("Request URL: %s", reqStr)
-
-		resp, err := http.Post(reqStr, "application/json", nil)
+		msgcid, err := sendFunds(*filapi, *filwal, addr, *faucetval)
 		if err != nil {
-			log.Errorf("failed to Post request: %s", err)
-			http.Error(w, err.Error(), 500)
+			log.Errorf("failed to send funds: %s", err)
+			http.Error(w, "failed to send funds", 500)
 			return
 		}
 
-		out, err := ioutil.ReadAll(resp.Body)
+		addrLimiter.Add(target, time.Now().Add(*expiry))
+
+		log.Info("Request successful. Message CID: %s", msgcid.String())
+		w.Header().Add("Message-Cid", msgcid.String())
+		w.WriteHeader(200)
+		fmt.Fprint(w, "Success! Message CID: ") // nolint: errcheck
+		fmt.Fprintln(w, msgcid.String())        // nolint: errcheck
+	})
+
+	if *collateralval > 0 {
+		granter, err := collateral.NewGranter(&apiChain{filapi: *filapi}, abi.ChainEpoch(*collateralBlocks), *collateralState)
 		if err != nil {
-			log.Errorf("failed to read response body: %s", err)
-			http.Error(w, "failed to read response", 500)
-			return
+			panic(err)
 		}
-		if resp.StatusCode != 200 {
-			log.Errorf("status: %s body: %s", resp.Status, string(out))
-			http.Error(w, "failed to send funds", 500)
+		go func() {
+			for range time.Tick(*verifyInterval) {
+				if err := granter.Verify(context.Background()); err != nil {
+					log.Errorf("failed to verify collateral grants: %s", err)
+				}
+			}
+		}()
+		handleCollateral(granter, *filapi, *filwal, *collateralval)
+	}
+
+	panic(http.ListenAndServe(":9797", nil))
+}
+
+// handleCollateral serves the collateral grants:
+//
+//	/collateral         grants collateral to the address of the target form value
+//	/collateral/grants  lists the grants and the state of their verification as JSON
+func handleCollateral(granter *collateral.Granter, filapi, filwal string, value int64) {
+	http.HandleFunc("/collateral", func(w http.ResponseWriter, r *http.Request) {
+		target := r.FormValue("target")
+		if target == "" {
+			http.Error(w, "must specify a target address to send collateral to", 400)
 			return
 		}
+		log.Infof("Request to send collateral to: %s", target)
 
-		msgResp := struct{ Cid cid.Cid }{}
-
-		// result should be a message cid
-		if err := json.Unmarshal(out, &msgResp); err != nil {
-			log.Errorf("json unmarshal from response failed: %s", err)
-			log.Errorf("response data was: %s", out)
-			http.Error(w, "faucet unmarshal failed", 500)
+		addr, err := address.NewFromString(target)
+		if err != nil {
+			log.Errorf("failed to parse target address: %s %s", target, err)
+			http.Error(w, fmt.Sprintf("Failed to parse target address %s %s", target, err.Error()), 400)
 			return
 		}
-		msgcid := msgResp.Cid
 
-		addrLimiter.Add(target, time.Now().Add(*expiry))
+		grant, err := granter.Grant(r.Context(), addr, func() (cid.Cid, error) {
+			return sendFunds(filapi, filwal, addr, value)
+		})
+		if err == collateral.ErrRepeatGrant || err == collateral.ErrAlreadyMiner {
+			log.Errorf("denied collateral to %s: %s", target, err)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		} else if err != nil {
+			log.Errorf("failed to grant collateral: %s", err)
+			http.Error(w, "failed to send collateral", 500)
+			return
+		}
 
-		log.Info("Request successful. Message CID: %s", msgcid.String())
-		w.Header().Add("Message-Cid", msgcid.String())
+		log.Infof("Collateral granted to %s until height %d. Message CID: %s", addr, grant.Deadline, grant.Message)
+		w.Header().Add("Message-Cid", grant.Message.String())
 		w.WriteHeader(200)
-		fmt.Fprint(w, "Success! Message CID: ") // nolint: errcheck
-		fmt.Fprintln(w, msgcid.String())        // nolint: errcheck
+		fmt.Fprintf(w, "Success! Create a miner by height %d. Message CID: %s\n", grant.Deadline, grant.Message) // nolint: errcheck
 	})
+	http.HandleFunc("/collateral/grants", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(granter.Grants()); err != nil {
+			log.Errorf("failed to write grants: %s", err)
+		}
+	})
+}
 
-	panic(http.ListenAndServe(":9797", nil))
+// sendFunds sends `value` FIL from `filwal` to `addr` through the API of the
+// node at `filapi` and returns the CID of the message.
+func sendFunds(filapi, filwal string, addr address.Address, value int64) (cid.Cid, error) {
+	reqStr := fmt.Sprintf("http://%s/api/message/send?arg=%s&value=%d&from=%s&gas-price=0.0001&gas-limit=1000", filapi, addr, value, filwal)
+	log.Infof("Request URL: %s", reqStr)
+
+	resp, err := http.Post(reqStr, "application/json", nil)
+	if err != nil {
+		return cid.Undef, errors.Wrap(err, "failed to post request")
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	out, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return cid.Undef, errors.Wrap(err, "failed to read response body")
+	}
+	if resp.StatusCode != 200 {
+		return cid.Undef, fmt.Errorf("status: %s body: %s", resp.Status, string(out))
+	}
+
+	msgResp := struct{ Cid cid.Cid }{}
+
+	// result should be a message cid
+	if err := json.Unmarshal(out, &msgResp); err != nil {
+		return cid.Undef, errors.Wrapf(err, "json unmarshal of response %s failed", out)
+	}
+	return msgResp.Cid, nil
+}
+
+// apiChain looks up the miners and their owners through the HTTP API of a
+// filecoin node.
+type apiChain struct {
+	filapi string
+}
+
+func (c *apiChain) Miners(ctx context.Context) (abi.ChainEpoch, []address.Address, error) {
+	var table porcelain.PowerTable
+	if err := c.post(ctx, "state/power", &table); err != nil {
+		return 0, nil, err
+	}
+	miners := make([]address.Address, len(table.Miners))
+	for i, m := range table.Miners {
+		miners[i] = m.Miner
+	}
+	return table.Height, miners, nil
+}
+
+func (c *apiChain) MinerOwner(ctx context.Context, miner address.Address) (address.Address, error) {
+	var status porcelain.MinerStatus
+	if err := c.post(ctx, "miner/status?arg="+miner.String(), &status); err != nil {
+		return address.Undef, err
+	}
+	// the owner is an ID address, requesters give the key address of its account
+	var owner struct {
+		State struct {
+			Address address.Address
+		}
+	}
+	if err := c.post(ctx, "state/read-actor?arg="+status.OwnerAddress.String(), &owner); err != nil {
+		return address.Undef, err
+	}
+	return owner.State.Address, nil
+}
+
+func (c *apiChain) post(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequest("POST", fmt.Sprintf("http://%s/api/%s", c.filapi, path), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("%s failed: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
 }
 
 const form = `
@@ -137,6 +261,13 @@ const form = `
 			<input type="text" name="target" size="30" />
 			<input type="submit" value="Submit" size="30" />
 		</form>
+		<h1> Creating a miner? </h1>
+		<p> Addresses that own no miner may ask once for the collateral of a new miner, </p>
+		<p> which they must create with <tt> go-filecoin miner create </tt> soon after. </p>
+		<form action="/collateral" method="post">
+			<input type="text" name="target" size="30" />
+			<input type="submit" value="Submit" size="30" />
+		</form>
 	</body>
 </html>
 `
@@ -0,0 +1,154 @@
+// Package calibrate measures the cost of the operations of the VM on the
+// current machine and suggests gas schedules from the measurements.
+package calibrate
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
+)
+
+// Operation is an operation of the VM priced by a linear cost of a gas
+// schedule.
+type Operation struct {
+	Name string
+	// Sizes are the input sizes to measure, the operation has a fixed cost
+	// when there are fewer than two.
+	Sizes []int
+	// Setup prepares the operation on an input of size, the returned function
+	// is timed.
+	Setup func(size int) (func(), error)
+	// Cost returns the cost of the operation in a schedule.
+	Cost func(s *vm.GasSchedule) *vm.GasLinearCost
+}
+
+// Sample is the measured duration of an operation on an input size.
+type Sample struct {
+	Size     int
+	Duration time.Duration
+}
+
+// Result is the measurement of an operation, as a linear cost in
+// nanoseconds.
+type Result struct {
+	Name    string
+	Samples []Sample
+	BaseNs  float64
+	// PerUnitNs is zero for fixed cost operations.
+	PerUnitNs float64
+}
+
+// Timer returns the duration of a call of op.
+type Timer func(op func()) time.Duration
+
+// Repeat returns a timer calling the operation repeatedly for at least d,
+// and returning the mean duration of a call.
+func Repeat(d time.Duration) Timer {
+	return func(op func()) time.Duration {
+		op() // warm up
+		n := 0
+		start := time.Now()
+		for time.Since(start) < d {
+			op()
+			n++
+		}
+		return time.Since(start) / time.Duration(n)
+	}
+}
+
+// Measure times the operation on each of its sizes.
+func Measure(op Operation, timer Timer) (*Result, error) {
+	sizes := op.Sizes
+	if len(sizes) == 0 {
+		sizes = []int{0}
+	}
+	res := &Result{Name: op.Name}
+	for _, size := range sizes {
+		f, err := op.Setup(size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up %s on %d bytes: %s", op.Name, size, err)
+		}
+		res.Samples = append(res.Samples, Sample{Size: size, Duration: timer(f)})
+	}
+	res.BaseNs, res.PerUnitNs = Fit(res.Samples)
+	return res, nil
+}
+
+// Fit returns the least squares linear fit of samples, as a base and per
+// unit duration in nanoseconds. A single sample or samples of a single size
+// fit a base duration. Negative coefficients are clamped to zero, a faster
+// operation on larger inputs being measurement noise.
+func Fit(samples []Sample) (base, perUnit float64) {
+	n := float64(len(samples))
+	if n == 0 {
+		return 0, 0
+	}
+	var sx, sy, sxx, sxy float64
+	for _, s := range samples {
+		x, y := float64(s.Size), float64(s.Duration.Nanoseconds())
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return math.Max(sy/n, 0), 0
+	}
+	perUnit = (n*sxy - sx*sy) / den
+	if perUnit < 0 {
+		return math.Max(sy/n, 0), 0
+	}
+	base = (sy - perUnit*sx) / n
+	if base < 0 {
+		// all the cost is in the input
+		return 0, sxy / sxx
+	}
+	return base, perUnit
+}
+
+// NsPerGas returns the nanoseconds a unit of gas buys, such that the cost of
+// the anchor operation on an input of size in the current schedule matches
+// its measurement.
+func NsPerGas(current *vm.GasSchedule, anchor Operation, res *Result, size int) (float64, error) {
+	gasCost := anchor.Cost(current).Cost(size)
+	if gasCost <= 0 {
+		return 0, fmt.Errorf("%s costs no gas in the current schedule", anchor.Name)
+	}
+	ns := res.BaseNs + res.PerUnitNs*float64(size)
+	if ns <= 0 {
+		return 0, fmt.Errorf("%s took no measurable time", anchor.Name)
+	}
+	return ns / float64(gasCost), nil
+}
+
+// Suggest returns a copy of the current schedule with the costs of the
+// measured operations priced at nsPerGas. Results may be nil for operations
+// that were not measured, which keep their current cost.
+func Suggest(current *vm.GasSchedule, ops []Operation, results []*Result, nsPerGas float64) vm.GasSchedule {
+	suggested := *current
+	for i, op := range ops {
+		res := results[i]
+		if res == nil {
+			continue
+		}
+		cost := op.Cost(&suggested)
+		cost.Base = toGas(res.BaseNs, nsPerGas)
+		if len(op.Sizes) > 1 {
+			cost.PerUnit = toGas(res.PerUnitNs, nsPerGas)
+		}
+	}
+	return suggested
+}
+
+// toGas rounds a duration to gas, charging at least one unit for any
+// measured time.
+func toGas(ns float64, nsPerGas float64) gas.Unit {
+	if ns <= 0 {
+		return gas.Zero
+	}
+	return gas.NewGas(int64(math.Max(1, math.Round(ns/nsPerGas))))
+}
@@ -0,0 +1,68 @@
+package calibrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm/gas"
+)
+
+func TestFit(t *testing.T) {
+	tf.UnitTest(t)
+
+	base, perUnit := Fit([]Sample{{Size: 0, Duration: 100}, {Size: 10, Duration: 150}, {Size: 100, Duration: 600}})
+	assert.InDelta(t, 100, base, 0.01)
+	assert.InDelta(t, 5, perUnit, 0.01)
+
+	t.Log("a single size fits a base duration")
+	base, perUnit = Fit([]Sample{{Size: 0, Duration: 100}, {Size: 0, Duration: 200}})
+	assert.InDelta(t, 150, base, 0.01)
+	assert.Equal(t, 0.0, perUnit)
+
+	t.Log("noise making larger inputs faster fits a base duration")
+	base, perUnit = Fit([]Sample{{Size: 0, Duration: 200}, {Size: 100, Duration: 100}})
+	assert.InDelta(t, 150, base, 0.01)
+	assert.Equal(t, 0.0, perUnit)
+}
+
+func TestSuggest(t *testing.T) {
+	tf.UnitTest(t)
+
+	current := vm.DefaultGasSchedules[0]
+	current.Hashing = vm.GasLinearCost{Base: gas.NewGas(10), PerUnit: gas.NewGas(1)}
+
+	hashing := Operation{
+		Name:  "hashing",
+		Sizes: []int{0, 10, 100},
+		Setup: func(size int) (func(), error) { return func() {}, nil },
+		Cost:  func(s *vm.GasSchedule) *vm.GasLinearCost { return &s.Hashing },
+	}
+	size := 0
+	res, err := Measure(hashing, func(func()) time.Duration {
+		// 20ns + 2ns per byte, twice the current cost
+		d := time.Duration(20 + 2*hashing.Sizes[size])
+		size++
+		return d
+	})
+	require.NoError(t, err)
+
+	t.Log("the anchor keeps its current cost")
+	nsPerGas, err := NsPerGas(&current, hashing, res, 0)
+	require.NoError(t, err)
+	assert.InDelta(t, 2, nsPerGas, 0.01)
+
+	ipldGet := Operation{Name: "ipld-get", Cost: func(s *vm.GasSchedule) *vm.GasLinearCost { return &s.IpldGet }}
+	ipldRes := &Result{Name: ipldGet.Name, BaseNs: 400}
+	ipldPut := Operation{Name: "ipld-put", Sizes: []int{0, 10}, Cost: func(s *vm.GasSchedule) *vm.GasLinearCost { return &s.IpldPut }}
+	suggested := Suggest(&current, []Operation{hashing, ipldGet, ipldPut}, []*Result{res, ipldRes, nil}, nsPerGas)
+	assert.Equal(t, vm.GasLinearCost{Base: gas.NewGas(10), PerUnit: gas.NewGas(1)}, suggested.Hashing)
+	t.Log("fixed cost operations keep their current per unit cost")
+	assert.Equal(t, vm.GasLinearCost{Base: gas.NewGas(200), PerUnit: current.IpldGet.PerUnit}, suggested.IpldGet)
+	t.Log("operations not measured keep their cost")
+	assert.Equal(t, current.IpldPut, suggested.IpldPut)
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	badgerds "github.com/ipfs/go-ds-badger2"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/minio/blake2b-simd"
+	mh "github.com/multiformats/go-multihash"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/vm"
+	"github.com/filecoin-project/go-filecoin/tools/gas-calibration/calibrate"
+)
+
+/* gas-calibration benchmarks the operations of the VM priced by a linear gas
+cost on the current machine, and suggests a gas schedule pricing them by
+their measured time:
+
+	$ gas-calibration -anchor ipld-get -out schedules.json
+
+The time a unit of gas buys is taken from the anchor operation, whose cost is
+kept as in the current schedule, unless set with -ns-per-gas. The suggested
+schedules are written for the networkParams.gasScheduleFile config of the nodes
+of a private network. With -from-epoch, the suggested schedule is appended to
+the current ones as a new version taking effect from that epoch, otherwise it
+replaces them, for a new network.
+
+Operations that are not measured (message sends, actor creation, and proof
+verification) keep their current cost.
+*/
+
+var log = logging.Logger("gas-calibration")
+
+func init() {
+	// Info level
+	logging.SetAllLoggers(4)
+}
+
+func main() {
+	schedulesFile := flag.String("schedules", "", "a JSON file of the current gas schedules, defaults to those of the Filecoin network")
+	anchor := flag.String("anchor", "ipld-get", "the operation whose current cost sets the time a unit of gas buys")
+	anchorSize := flag.Int("anchor-size", 1024, "the input size at which the anchor cost is matched")
+	nsPerGas := flag.Float64("ns-per-gas", 0, "the nanoseconds a unit of gas buys, overrides -anchor")
+	duration := flag.Duration("duration", 500*time.Millisecond, "time spent measuring each operation on each size")
+	fromEpoch := flag.Int64("from-epoch", 0, "the epoch from which the suggested schedule takes effect, 0 to replace the current schedules")
+	out := flag.String("out", "", "the file to write the suggested schedules to, they are printed when not set")
+	flag.Parse()
+
+	schedules := vm.DefaultGasSchedules
+	if *schedulesFile != "" {
+		f, err := os.Open(*schedulesFile)
+		if err != nil {
+			exit(err)
+		}
+		schedules, err = vm.ReadGasSchedules(f)
+		if err != nil {
+			exit(err)
+		}
+		f.Close() // nolint: errcheck
+	}
+	current := schedules[len(schedules)-1]
+
+	dir, err := ioutil.TempDir("", "gas-calibration")
+	if err != nil {
+		exit(err)
+	}
+	defer os.RemoveAll(dir) // nolint: errcheck
+	ops, err := operations(dir)
+	if err != nil {
+		exit(err)
+	}
+
+	timer := calibrate.Repeat(*duration)
+	results := make([]*calibrate.Result, len(ops))
+	anchorIdx := -1
+	for i, op := range ops {
+		log.Infof("measuring %s", op.Name)
+		res, err := measure(op, timer)
+		if err != nil {
+			log.Warnf("failed to measure %s, keeping its current cost: %s", op.Name, err)
+			continue
+		}
+		results[i] = res
+		if op.Name == *anchor {
+			anchorIdx = i
+		}
+	}
+
+	if *nsPerGas == 0 {
+		if anchorIdx < 0 {
+			exit(fmt.Errorf("anchor %s was not measured", *anchor))
+		}
+		*nsPerGas, err = calibrate.NsPerGas(&current, ops[anchorIdx], results[anchorIdx], *anchorSize)
+		if err != nil {
+			exit(err)
+		}
+	}
+	suggested := calibrate.Suggest(&current, ops, results, *nsPerGas)
+
+	report(&current, &suggested, ops, results, *nsPerGas)
+
+	if *fromEpoch > 0 {
+		if abi.ChainEpoch(*fromEpoch) <= current.Epoch {
+			exit(fmt.Errorf("the suggested schedule must take effect after epoch %d", current.Epoch))
+		}
+		suggested.Version = current.Version + 1
+		suggested.Epoch = abi.ChainEpoch(*fromEpoch)
+		schedules = append(append([]vm.GasSchedule{}, schedules...), suggested)
+	} else {
+		suggested.Version = 0
+		suggested.Epoch = 0
+		schedules = []vm.GasSchedule{suggested}
+	}
+
+	raw, err := json.MarshalIndent(schedules, "", "  ")
+	if err != nil {
+		exit(err)
+	}
+	if *out == "" {
+		fmt.Println(string(raw))
+		return
+	}
+	if err := ioutil.WriteFile(*out, raw, 0644); err != nil {
+		exit(err)
+	}
+	log.Infof("wrote suggested schedules to %s", *out)
+}
+
+// measure recovers from panics of operations unsupported by the build, such
+// as BLS without the filecoin ffi.
+func measure(op calibrate.Operation, timer calibrate.Timer) (res *calibrate.Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return calibrate.Measure(op, timer)
+}
+
+func operations(dir string) ([]calibrate.Operation, error) {
+	ds, err := badgerds.NewDatastore(dir, &badgerds.DefaultOptions)
+	if err != nil {
+		return nil, err
+	}
+	bs := blockstore.NewBlockstore(ds)
+	prefix := cid.Prefix{Version: 1, Codec: cid.DagCBOR, MhType: mh.BLAKE2B_MIN + 31, MhLength: -1}
+	sizes := []int{0, 1 << 10, 16 << 10, 256 << 10}
+	sigSizes := []int{0, 1 << 10, 16 << 10}
+
+	return []calibrate.Operation{
+		{
+			Name:  "hashing",
+			Sizes: sizes,
+			Setup: func(size int) (func(), error) {
+				data := randomBytes(size)
+				return func() { blake2b.Sum256(data) }, nil
+			},
+			Cost: func(s *vm.GasSchedule) *vm.GasLinearCost { return &s.Hashing },
+		},
+		{
+			Name:  "ipld-get",
+			Sizes: sizes,
+			Setup: func(size int) (func(), error) {
+				blk, err := newBlock(prefix, size)
+				if err != nil {
+					return nil, err
+				}
+				if err := bs.Put(blk); err != nil {
+					return nil, err
+				}
+				return func() {
+					if _, err := bs.Get(blk.Cid()); err != nil {
+						panic(err)
+					}
+				}, nil
+			},
+			Cost: func(s *vm.GasSchedule) *vm.GasLinearCost { return &s.IpldGet },
+		},
+		{
+			Name:  "ipld-put",
+			Sizes: sizes,
+			Setup: func(size int) (func(), error) {
+				data := randomBytes(size + 8)
+				n := uint64(0)
+				return func() {
+					// vary the data so that every put writes a new block
+					n++
+					for i := 0; i < 8; i++ {
+						data[i] = byte(n >> (8 * i))
+					}
+					c, err := prefix.Sum(data)
+					if err != nil {
+						panic(err)
+					}
+					blk, err := blocks.NewBlockWithCid(data, c)
+					if err != nil {
+						panic(err)
+					}
+					if err := bs.Put(blk); err != nil {
+						panic(err)
+					}
+				}, nil
+			},
+			Cost: func(s *vm.GasSchedule) *vm.GasLinearCost { return &s.IpldPut },
+		},
+		signatureOperation("verify-signature-secp", crypto.SigTypeSecp256k1, sigSizes, func(s *vm.GasSchedule) *vm.GasLinearCost { return &s.VerifySignatureSecp }),
+		signatureOperation("verify-signature-bls", crypto.SigTypeBLS, sigSizes, func(s *vm.GasSchedule) *vm.GasLinearCost { return &s.VerifySignatureBLS }),
+	}, nil
+}
+
+func signatureOperation(name string, sigType crypto.SigType, sizes []int, cost func(s *vm.GasSchedule) *vm.GasLinearCost) calibrate.Operation {
+	return calibrate.Operation{
+		Name:  name,
+		Sizes: sizes,
+		Setup: func(size int) (func(), error) {
+			var ki crypto.KeyInfo
+			var err error
+			if sigType == crypto.SigTypeBLS {
+				ki, err = crypto.NewBLSKeyFromSeed(rand.Reader)
+			} else {
+				ki, err = crypto.NewSecpKeyFromSeed(rand.Reader)
+			}
+			if err != nil {
+				return nil, err
+			}
+			addr, err := ki.Address()
+			if err != nil {
+				return nil, err
+			}
+			data := randomBytes(size)
+			sig, err := crypto.Sign(data, ki.Key(), sigType)
+			if err != nil {
+				return nil, err
+			}
+			return func() {
+				if err := crypto.ValidateSignature(data, addr, sig); err != nil {
+					panic(err)
+				}
+			}, nil
+		},
+		Cost: cost,
+	}
+}
+
+func report(current, suggested *vm.GasSchedule, ops []calibrate.Operation, results []*calibrate.Result, nsPerGas float64) {
+	w := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "%.2f ns per unit of gas\n\n", nsPerGas)            // nolint: errcheck
+	fmt.Fprintln(w, "OPERATION\tBASE NS\tNS/BYTE\tCURRENT\tSUGGESTED") // nolint: errcheck
+	for i, op := range ops {
+		cur, sug := op.Cost(current), op.Cost(suggested)
+		if results[i] == nil {
+			fmt.Fprintf(w, "%s\t-\t-\t%d+%d/B\t%d+%d/B\n", op.Name, cur.Base, cur.PerUnit, sug.Base, sug.PerUnit) // nolint: errcheck
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%.0f\t%.3f\t%d+%d/B\t%d+%d/B\n", op.Name, results[i].BaseNs, results[i].PerUnitNs, cur.Base, cur.PerUnit, sug.Base, sug.PerUnit) // nolint: errcheck
+	}
+	w.Flush() // nolint: errcheck
+	fmt.Println()
+}
+
+func newBlock(prefix cid.Prefix, size int) (blocks.Block, error) {
+	data := randomBytes(size)
+	c, err := prefix.Sum(data)
+	if err != nil {
+		return nil, err
+	}
+	return blocks.NewBlockWithCid(data, c)
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return b
+}
+
+func exit(err error) {
+	fmt.Printf("ERROR: %s\n", err)
+	os.Exit(1)
+}
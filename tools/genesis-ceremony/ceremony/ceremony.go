@@ -0,0 +1,376 @@
+package ceremony
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/ipfs/go-cid"
+	car "github.com/ipld/go-car"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/types"
+	gengen "github.com/filecoin-project/go-filecoin/tools/gengen/util"
+)
+
+// Domain separation prefixes of the signed payloads, so that a ceremony
+// signature cannot be mistaken for the signature of anything else.
+const (
+	contributionDomain = "filecoin genesis ceremony contribution\n"
+	manifestDomain     = "filecoin genesis ceremony manifest\n"
+)
+
+// Contribution is what an operator brings to the genesis of a private network:
+// funded accounts and miners with pre-sealed sectors. It holds no private key.
+type Contribution struct {
+	// Name identifies the operator, it must be unique among the contributions.
+	Name     string
+	Accounts []*gengen.AccountConfig
+	Miners   []*MinerSpec
+}
+
+// MinerSpec describes a genesis miner contributed by an operator.
+type MinerSpec struct {
+	// Owner is the account owning the miner, also its worker. It must sign the
+	// contribution and, as a worker, be a BLS address.
+	Owner            address.Address
+	PeerID           string
+	SealProofType    abi.RegisteredProof
+	CommittedSectors []*gengen.CommitConfig
+}
+
+// SignedContribution is a contribution signed by the owner of its miners, or by
+// any key of the operator if it has no miners.
+type SignedContribution struct {
+	Contribution Contribution
+	Signer       address.Address
+	Signature    crypto.Signature
+}
+
+// Params are the parameters of the network agreed on by all operators.
+type Params struct {
+	Network string
+	// Time is the genesis block time in unix seconds.
+	Time uint64
+	// Seed derives the keys generated for the genesis, it is public.
+	Seed int64
+}
+
+// ContributionRef identifies a contribution assembled into a genesis.
+type ContributionRef struct {
+	Name   string
+	Signer address.Address
+	// Hash is the hex encoded sha256 of the contribution.
+	Hash string
+}
+
+// ManifestSignature is the approval of a manifest by an operator.
+type ManifestSignature struct {
+	Signer    address.Address
+	Signature crypto.Signature
+}
+
+// Manifest records how a genesis was assembled, for every operator to verify
+// and sign.
+type Manifest struct {
+	Params        Params
+	GenesisCid    cid.Cid
+	Contributions []ContributionRef
+	Signatures    []ManifestSignature
+}
+
+// Sign signs the contribution with the key.
+func Sign(c Contribution, key *crypto.KeyInfo) (*SignedContribution, error) {
+	signer, err := key.Address()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSigner(c, signer); err != nil {
+		return nil, err
+	}
+	payload, err := contributionPayload(c)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := crypto.Sign(payload, key.PrivateKey, key.SigType)
+	if err != nil {
+		return nil, err
+	}
+	return &SignedContribution{Contribution: c, Signer: signer, Signature: sig}, nil
+}
+
+// Verify checks the signature of a contribution and that it was signed by the owner of its miners.
+func (sc *SignedContribution) Verify() error {
+	if err := checkSigner(sc.Contribution, sc.Signer); err != nil {
+		return err
+	}
+	payload, err := contributionPayload(sc.Contribution)
+	if err != nil {
+		return err
+	}
+	if err := crypto.ValidateSignature(payload, sc.Signer, sc.Signature); err != nil {
+		return errors.Wrapf(err, "invalid signature of contribution %s", sc.Contribution.Name)
+	}
+	return nil
+}
+
+func checkSigner(c Contribution, signer address.Address) error {
+	if c.Name == "" {
+		return errors.New("contribution has no name")
+	}
+	for _, m := range c.Miners {
+		if m.Owner != signer {
+			return errors.Errorf("contribution %s must be signed by %s, the owner of its miners", c.Name, m.Owner)
+		}
+	}
+	return nil
+}
+
+// Hash returns the hex encoded sha256 of the contribution.
+func (sc *SignedContribution) Hash() (string, error) {
+	data, err := json.Marshal(sc.Contribution)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func contributionPayload(c Contribution) ([]byte, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return append([]byte(contributionDomain), sum[:]...), nil
+}
+
+// Assemble verifies the contributions and writes the genesis they describe as
+// a CAR to `out`. The genesis does not depend on the order of the contributions:
+// they are applied sorted by name. The returned manifest is not signed.
+func Assemble(params Params, contributions []*SignedContribution, out io.Writer) (*Manifest, error) {
+	sorted := make([]*SignedContribution, len(contributions))
+	copy(sorted, contributions)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Contribution.Name < sorted[j].Contribution.Name })
+
+	manifest := &Manifest{Params: params, Signatures: []ManifestSignature{}}
+	for i, sc := range sorted {
+		if i > 0 && sorted[i-1].Contribution.Name == sc.Contribution.Name {
+			return nil, errors.Errorf("two contributions are named %s", sc.Contribution.Name)
+		}
+		if err := sc.Verify(); err != nil {
+			return nil, err
+		}
+		hash, err := sc.Hash()
+		if err != nil {
+			return nil, err
+		}
+		manifest.Contributions = append(manifest.Contributions, ContributionRef{
+			Name:   sc.Contribution.Name,
+			Signer: sc.Signer,
+			Hash:   hash,
+		})
+	}
+
+	cfg, err := genesisConfig(params, sorted)
+	if err != nil {
+		return nil, err
+	}
+	info, err := gengen.GenGenesisCar(cfg, out)
+	if err != nil {
+		return nil, err
+	}
+	manifest.GenesisCid = info.GenesisCid
+	return manifest, nil
+}
+
+// genesisConfig translates sorted contributions to a gengen configuration. A
+// single key is generated from the public seed, funded with nothing, to be the
+// client of the self-deals of all pre-sealed sectors since the keys of the
+// miner owners are not available.
+func genesisConfig(params Params, contributions []*SignedContribution) (*gengen.GenesisCfg, error) {
+	const dealClient = 0
+	cfg := &gengen.GenesisCfg{
+		Seed:                 params.Seed,
+		KeysToGen:            1,
+		PreallocatedFunds:    []string{"0"},
+		PreallocatedAccounts: []*gengen.AccountConfig{},
+		Miners:               []*gengen.CreateStorageMinerConfig{},
+		Network:              params.Network,
+		Time:                 params.Time,
+	}
+
+	funded := map[address.Address]bool{}
+	for _, sc := range contributions {
+		for _, account := range sc.Contribution.Accounts {
+			if funded[account.Address] {
+				return nil, errors.Errorf("account %s is funded more than once", account.Address)
+			}
+			if _, ok := types.NewAttoFILFromFILString(account.FIL); !ok {
+				return nil, errors.Errorf("invalid amount %s for account %s", account.FIL, account.Address)
+			}
+			funded[account.Address] = true
+			cfg.PreallocatedAccounts = append(cfg.PreallocatedAccounts, account)
+		}
+	}
+	for _, sc := range contributions {
+		for _, m := range sc.Contribution.Miners {
+			if m.Owner.Protocol() != address.BLS {
+				return nil, errors.Errorf("owner %s of a miner of %s must be a BLS address", m.Owner, sc.Contribution.Name)
+			}
+			// the owner account must exist before the miner is created
+			if !funded[m.Owner] {
+				funded[m.Owner] = true
+				cfg.PreallocatedAccounts = append(cfg.PreallocatedAccounts, &gengen.AccountConfig{Address: m.Owner, FIL: "0"})
+			}
+			for _, comm := range m.CommittedSectors {
+				if comm.DealCfg == nil {
+					return nil, errors.Errorf("sector %d of miner %s has no deal", comm.SectorNum, m.Owner)
+				}
+			}
+			cfg.Miners = append(cfg.Miners, &gengen.CreateStorageMinerConfig{
+				Owner:            dealClient,
+				OwnerAddress:     m.Owner,
+				PeerID:           m.PeerID,
+				CommittedSectors: m.CommittedSectors,
+				SealProofType:    m.SealProofType,
+			})
+		}
+	}
+	return cfg, nil
+}
+
+// SignManifest adds the signature of the key to the manifest. Only one
+// signature is kept per signer.
+func SignManifest(m *Manifest, key *crypto.KeyInfo) error {
+	signer, err := key.Address()
+	if err != nil {
+		return err
+	}
+	payload, err := manifestPayload(m)
+	if err != nil {
+		return err
+	}
+	sig, err := crypto.Sign(payload, key.PrivateKey, key.SigType)
+	if err != nil {
+		return err
+	}
+
+	signatures := []ManifestSignature{}
+	for _, s := range m.Signatures {
+		if s.Signer != signer {
+			signatures = append(signatures, s)
+		}
+	}
+	m.Signatures = append(signatures, ManifestSignature{Signer: signer, Signature: sig})
+	return nil
+}
+
+// manifestPayload is the signed content of a manifest, everything but the signatures.
+func manifestPayload(m *Manifest) ([]byte, error) {
+	unsigned := *m
+	unsigned.Signatures = nil
+	data, err := json.Marshal(unsigned)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return append([]byte(manifestDomain), sum[:]...), nil
+}
+
+// Report is the result of the verification of a genesis.
+type Report struct {
+	GenesisCid cid.Cid
+	// Signed are the signers of the contributions who signed the manifest.
+	Signed []address.Address
+	// Unsigned are the signers of the contributions who have not signed the manifest yet.
+	Unsigned []address.Address
+}
+
+// Complete is true when every operator has signed the manifest.
+func (r *Report) Complete() bool {
+	return len(r.Unsigned) == 0
+}
+
+// Verify assembles the genesis again from the contributions and checks that it
+// is the genesis of the manifest and of the CAR in `genesisCar`, and that the
+// manifest signatures are valid. Operators who have not signed yet are
+// reported rather than failing the verification.
+func Verify(m *Manifest, contributions []*SignedContribution, genesisCar io.Reader) (*Report, error) {
+	var regenerated bytes.Buffer
+	assembled, err := Assemble(m.Params, contributions, &regenerated)
+	if err != nil {
+		return nil, err
+	}
+	if len(assembled.Contributions) != len(m.Contributions) {
+		return nil, errors.Errorf("manifest has %d contributions, %d were given", len(m.Contributions), len(assembled.Contributions))
+	}
+	for i, ref := range assembled.Contributions {
+		if ref != m.Contributions[i] {
+			return nil, errors.Errorf("contribution %s does not match the manifest", ref.Name)
+		}
+	}
+	if !assembled.GenesisCid.Equals(m.GenesisCid) {
+		return nil, errors.Errorf("contributions assemble to genesis %s, the manifest has %s", assembled.GenesisCid, m.GenesisCid)
+	}
+
+	if genesisCar != nil {
+		data, err := ioutil.ReadAll(genesisCar)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read genesis car")
+		}
+		header, err := car.ReadHeader(bufio.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read genesis car")
+		}
+		if len(header.Roots) != 1 || !header.Roots[0].Equals(m.GenesisCid) {
+			return nil, errors.Errorf("genesis car roots %v do not match the manifest genesis %s", header.Roots, m.GenesisCid)
+		}
+		if !bytes.Equal(data, regenerated.Bytes()) {
+			return nil, errors.New("genesis car does not match the assembled genesis")
+		}
+	}
+
+	payload, err := manifestPayload(m)
+	if err != nil {
+		return nil, err
+	}
+	signed := map[address.Address]bool{}
+	for _, s := range m.Signatures {
+		if err := crypto.ValidateSignature(payload, s.Signer, s.Signature); err != nil {
+			return nil, errors.Wrapf(err, "invalid manifest signature of %s", s.Signer)
+		}
+		signed[s.Signer] = true
+	}
+
+	report := &Report{GenesisCid: m.GenesisCid, Signed: []address.Address{}, Unsigned: []address.Address{}}
+	for _, ref := range m.Contributions {
+		if signed[ref.Signer] {
+			report.Signed = append(report.Signed, ref.Signer)
+		} else {
+			report.Unsigned = append(report.Unsigned, ref.Signer)
+		}
+	}
+	return report, nil
+}
+
+func (r *Report) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "genesis %s\n", r.GenesisCid)
+	for _, s := range r.Signed {
+		fmt.Fprintf(&b, "signed by %s\n", s)
+	}
+	for _, s := range r.Unsigned {
+		fmt.Fprintf(&b, "not signed by %s\n", s)
+	}
+	return b.String()
+}
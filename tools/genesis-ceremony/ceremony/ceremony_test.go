@@ -0,0 +1,156 @@
+package ceremony_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/constants"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+	. "github.com/filecoin-project/go-filecoin/tools/genesis-ceremony/ceremony"
+	gengen "github.com/filecoin-project/go-filecoin/tools/gengen/util"
+)
+
+// newKey returns a BLS key, as miner workers must be.
+func newKey(t *testing.T) (*crypto.KeyInfo, address.Address) {
+	key, err := crypto.NewBLSKeyFromSeed(rand.Reader)
+	require.NoError(t, err)
+	addr, err := key.Address()
+	require.NoError(t, err)
+	return &key, addr
+}
+
+func contribution(t *testing.T, name string, owner address.Address, sectors int) Contribution {
+	commCfgs, err := gengen.MakeCommitCfgs(sectors)
+	require.NoError(t, err)
+	return Contribution{
+		Name:     name,
+		Accounts: []*gengen.AccountConfig{{Address: owner, FIL: "1000"}},
+		Miners: []*MinerSpec{{
+			Owner:            owner,
+			SealProofType:    constants.DevSealProofType,
+			CommittedSectors: commCfgs,
+		}},
+	}
+}
+
+func TestSignContribution(t *testing.T) {
+	tf.UnitTest(t)
+
+	key, owner := newKey(t)
+	otherKey, _ := newKey(t)
+
+	t.Run("signed by the owner verifies", func(t *testing.T) {
+		sc, err := Sign(contribution(t, "alice", owner, 1), key)
+		require.NoError(t, err)
+		assert.Equal(t, owner, sc.Signer)
+		assert.NoError(t, sc.Verify())
+	})
+
+	t.Run("must be signed by the owner of its miners", func(t *testing.T) {
+		_, err := Sign(contribution(t, "alice", owner, 1), otherKey)
+		assert.Error(t, err)
+	})
+
+	t.Run("must be named", func(t *testing.T) {
+		_, err := Sign(contribution(t, "", owner, 1), key)
+		assert.Error(t, err)
+	})
+
+	t.Run("tampered contribution does not verify", func(t *testing.T) {
+		sc, err := Sign(contribution(t, "alice", owner, 1), key)
+		require.NoError(t, err)
+		before, err := sc.Hash()
+		require.NoError(t, err)
+
+		sc.Contribution.Accounts[0].FIL = "1000000"
+		after, err := sc.Hash()
+		require.NoError(t, err)
+		assert.NotEqual(t, before, after)
+		assert.Error(t, sc.Verify())
+	})
+}
+
+func TestAssembleRejectsDuplicateNames(t *testing.T) {
+	tf.UnitTest(t)
+
+	aliceKey, alice := newKey(t)
+	bobKey, bob := newKey(t)
+	first, err := Sign(Contribution{Name: "ops", Accounts: []*gengen.AccountConfig{{Address: alice, FIL: "1"}}}, aliceKey)
+	require.NoError(t, err)
+	second, err := Sign(Contribution{Name: "ops", Accounts: []*gengen.AccountConfig{{Address: bob, FIL: "1"}}}, bobKey)
+	require.NoError(t, err)
+
+	_, err = Assemble(Params{Network: "ceremonytest"}, []*SignedContribution{first, second}, &bytes.Buffer{})
+	assert.Error(t, err)
+}
+
+func TestSignManifest(t *testing.T) {
+	tf.UnitTest(t)
+
+	aliceKey, alice := newKey(t)
+	bobKey, bob := newKey(t)
+	m := &Manifest{
+		Params: Params{Network: "ceremonytest", Seed: 1},
+		Contributions: []ContributionRef{
+			{Name: "alice", Signer: alice, Hash: "00"},
+			{Name: "bob", Signer: bob, Hash: "01"},
+		},
+	}
+
+	require.NoError(t, SignManifest(m, aliceKey))
+	require.NoError(t, SignManifest(m, bobKey))
+	require.Len(t, m.Signatures, 2)
+
+	t.Log("signing again replaces the signature of the signer")
+	require.NoError(t, SignManifest(m, aliceKey))
+	require.Len(t, m.Signatures, 2)
+	assert.Equal(t, bob, m.Signatures[0].Signer)
+	assert.Equal(t, alice, m.Signatures[1].Signer)
+}
+
+func TestAssembleAndVerify(t *testing.T) {
+	tf.IntegrationTest(t)
+
+	aliceKey, alice := newKey(t)
+	bobKey, bob := newKey(t)
+	aliceContribution, err := Sign(contribution(t, "alice", alice, 2), aliceKey)
+	require.NoError(t, err)
+	bobContribution, err := Sign(contribution(t, "bob", bob, 1), bobKey)
+	require.NoError(t, err)
+
+	params := Params{Network: "ceremonytest", Time: 123456789, Seed: 4}
+	var genesisCar bytes.Buffer
+	m, err := Assemble(params, []*SignedContribution{bobContribution, aliceContribution}, &genesisCar)
+	require.NoError(t, err)
+	require.Len(t, m.Contributions, 2)
+	assert.Equal(t, "alice", m.Contributions[0].Name)
+
+	t.Log("the genesis does not depend on the order of the contributions")
+	report, err := Verify(m, []*SignedContribution{aliceContribution, bobContribution}, bytes.NewReader(genesisCar.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, m.GenesisCid, report.GenesisCid)
+	assert.False(t, report.Complete())
+	assert.Equal(t, []address.Address{alice, bob}, report.Unsigned)
+
+	require.NoError(t, SignManifest(m, aliceKey))
+	require.NoError(t, SignManifest(m, bobKey))
+	report, err = Verify(m, []*SignedContribution{aliceContribution, bobContribution}, nil)
+	require.NoError(t, err)
+	assert.True(t, report.Complete())
+
+	t.Log("a missing contribution fails the verification")
+	_, err = Verify(m, []*SignedContribution{aliceContribution}, nil)
+	assert.Error(t, err)
+
+	t.Log("a tampered car fails the verification")
+	tampered := genesisCar.Bytes()
+	tampered[len(tampered)-1]++
+	_, err = Verify(m, []*SignedContribution{aliceContribution, bobContribution}, bytes.NewReader(tampered))
+	assert.Error(t, err)
+}
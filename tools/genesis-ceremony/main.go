@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	flg "flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	commands "github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/crypto"
+	"github.com/filecoin-project/go-filecoin/tools/genesis-ceremony/ceremony"
+)
+
+/* genesis-ceremony assembles the genesis of a private network from the
+contributions of several operators, none of whom controls the genesis alone.
+
+1. Each operator writes a contribution, its funded accounts and miners with
+   pre-sealed sectors, and signs it with the owner key of its miners:
+   $ genesis-ceremony contribute -key owner.key -out alice.signed.json alice.json
+
+2. Anyone assembles the signed contributions into a genesis CAR and a manifest:
+   $ genesis-ceremony assemble -network mynet -time 1590000000 -seed 42 \
+       -out-car genesis.car -out-manifest manifest.json alice.signed.json bob.signed.json
+
+3. Each operator verifies the genesis by assembling it again and signs the manifest:
+   $ genesis-ceremony verify -manifest manifest.json -car genesis.car alice.signed.json bob.signed.json
+   $ genesis-ceremony sign -key owner.key -manifest manifest.json
+
+The genesis is ready when verify reports that every operator signed the manifest.
+Keys are files as written by 'go-filecoin wallet export'.
+*/
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "contribute":
+		err = contribute(os.Args[2:])
+	case "assemble":
+		err = assemble(os.Args[2:])
+	case "sign":
+		err = sign(os.Args[2:])
+	case "verify":
+		err = verify(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err) // nolint: errcheck
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s contribute|assemble|sign|verify [flags] [files]\n", os.Args[0]) // nolint: errcheck
+	os.Exit(2)
+}
+
+func contribute(args []string) error {
+	flag := flg.NewFlagSet("contribute", flg.ExitOnError)
+	keyPath := flag.String("key", "", "key signing the contribution, the owner key of its miners")
+	out := flag.String("out", "", "writes the signed contribution to this file, instead of stdout")
+	_ = flag.Parse(args)
+	if flag.NArg() != 1 || *keyPath == "" {
+		return fmt.Errorf("contribute takes a -key and one contribution file")
+	}
+
+	var c ceremony.Contribution
+	if err := readJSON(flag.Arg(0), &c); err != nil {
+		return err
+	}
+	key, err := readKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	signed, err := ceremony.Sign(c, key)
+	if err != nil {
+		return err
+	}
+	return writeJSON(*out, signed)
+}
+
+func assemble(args []string) error {
+	flag := flg.NewFlagSet("assemble", flg.ExitOnError)
+	network := flag.String("network", "", "name of the network")
+	genTime := flag.Uint64("time", 0, "genesis block time in unix seconds")
+	seed := flag.Int64("seed", 0, "public seed of the keys generated for the genesis")
+	outCar := flag.String("out-car", "genesis.car", "writes the genesis car to this file")
+	outManifest := flag.String("out-manifest", "manifest.json", "writes the manifest to this file")
+	_ = flag.Parse(args)
+	if *network == "" || flag.NArg() == 0 {
+		return fmt.Errorf("assemble takes a -network and the signed contribution files")
+	}
+
+	contributions, err := readContributions(flag.Args())
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(*outCar)
+	if err != nil {
+		return err
+	}
+	defer f.Close() // nolint: errcheck
+
+	params := ceremony.Params{Network: *network, Time: *genTime, Seed: *seed}
+	manifest, err := ceremony.Assemble(params, contributions, f)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "assembled genesis %s from %d contributions\n", manifest.GenesisCid, len(manifest.Contributions)) // nolint: errcheck
+	return writeJSON(*outManifest, manifest)
+}
+
+func sign(args []string) error {
+	flag := flg.NewFlagSet("sign", flg.ExitOnError)
+	keyPath := flag.String("key", "", "key of the operator approving the genesis")
+	manifestPath := flag.String("manifest", "manifest.json", "manifest to sign, updated in place")
+	_ = flag.Parse(args)
+	if *keyPath == "" {
+		return fmt.Errorf("sign takes a -key")
+	}
+
+	var manifest ceremony.Manifest
+	if err := readJSON(*manifestPath, &manifest); err != nil {
+		return err
+	}
+	key, err := readKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	if err := ceremony.SignManifest(&manifest, key); err != nil {
+		return err
+	}
+	return writeJSON(*manifestPath, &manifest)
+}
+
+func verify(args []string) error {
+	flag := flg.NewFlagSet("verify", flg.ExitOnError)
+	manifestPath := flag.String("manifest", "manifest.json", "manifest of the genesis")
+	carPath := flag.String("car", "", "genesis car to check against the manifest")
+	_ = flag.Parse(args)
+	if flag.NArg() == 0 {
+		return fmt.Errorf("verify takes the signed contribution files")
+	}
+
+	var manifest ceremony.Manifest
+	if err := readJSON(*manifestPath, &manifest); err != nil {
+		return err
+	}
+	contributions, err := readContributions(flag.Args())
+	if err != nil {
+		return err
+	}
+	var genesisCar io.Reader
+	if *carPath != "" {
+		f, err := os.Open(*carPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close() // nolint: errcheck
+		genesisCar = f
+	}
+
+	report, err := ceremony.Verify(&manifest, contributions, genesisCar)
+	if err != nil {
+		return err
+	}
+	fmt.Print(report) // nolint: errcheck
+	if !report.Complete() {
+		return fmt.Errorf("%d operators have not signed the manifest", len(report.Unsigned))
+	}
+	return nil
+}
+
+func readContributions(paths []string) ([]*ceremony.SignedContribution, error) {
+	var contributions []*ceremony.SignedContribution
+	for _, path := range paths {
+		var sc ceremony.SignedContribution
+		if err := readJSON(path, &sc); err != nil {
+			return nil, err
+		}
+		contributions = append(contributions, &sc)
+	}
+	return contributions, nil
+}
+
+func readKey(path string) (*crypto.KeyInfo, error) {
+	var wir commands.WalletSerializeResult
+	if err := readJSON(path, &wir); err != nil {
+		return nil, err
+	}
+	if len(wir.KeyInfo) != 1 {
+		return nil, fmt.Errorf("%s must hold exactly one key, it has %d", path, len(wir.KeyInfo))
+	}
+	return wir.KeyInfo[0], nil
+}
+
+func readJSON(path string, v interface{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close() // nolint: errcheck
+	if err := json.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %s", path, err)
+	}
+	return nil
+}
+
+func writeJSON(path string, v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	out = append(out, '\n')
+	if path == "" {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	return ioutil.WriteFile(path, out, 0644)
+}
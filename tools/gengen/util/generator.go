@@ -243,20 +243,28 @@ func (g *GenesisGenerator) setupPrealloc() error {
 		if err != nil {
 			return err
 		}
-
-		value, ok := types.NewAttoFILFromFILString(v)
-		if !ok {
-			return fmt.Errorf("failed to parse FIL value '%s'", v)
+		if err := g.prealloc(addr, v); err != nil {
+			return err
 		}
-
-		_, err = g.vm.ApplyGenesisMessage(builtin.RewardActorAddr, addr, builtin.MethodSend, value, nil, &g.chainRand)
-		if err != nil {
+	}
+	for _, account := range g.cfg.PreallocatedAccounts {
+		if err := g.prealloc(account.Address, account.FIL); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (g *GenesisGenerator) prealloc(addr address.Address, v string) error {
+	value, ok := types.NewAttoFILFromFILString(v)
+	if !ok {
+		return fmt.Errorf("failed to parse FIL value '%s'", v)
+	}
+
+	_, err := g.vm.ApplyGenesisMessage(builtin.RewardActorAddr, addr, builtin.MethodSend, value, nil, &g.chainRand)
+	return err
+}
+
 func (g *GenesisGenerator) genBlock(ctx context.Context) (cid.Cid, error) {
 	stateRoot, err := g.flush(ctx)
 	if err != nil {
@@ -346,11 +354,17 @@ func (g *GenesisGenerator) setupMiners(ctx context.Context) ([]*RenderedMinerInf
 			return nil, err
 		}
 
-		// Add configured deals to the market actor with miner as provider and worker as client
+		// Add the configured deals to the market actor, with the miner as provider and the account
+		// of the key Owner as client. That account owns the miner unless it has an OwnerAddress.
 		dealIDs := []abi.DealID{}
 		if len(m.CommittedSectors) > 0 {
-			ownerKey := g.keys[m.Owner]
-			dealIDs, err = g.publishDeals(actorAddr, ownerAddr, ownerKey, m.CommittedSectors)
+			clientAddr := ownerAddr
+			if !m.OwnerAddress.Empty() {
+				if clientAddr, err = g.resolveKeyAddress(ctx, g.keys[m.Owner]); err != nil {
+					return nil, err
+				}
+			}
+			dealIDs, err = g.publishDeals(actorAddr, ownerAddr, clientAddr, g.keys[m.Owner], m.CommittedSectors)
 			if err != nil {
 				return nil, err
 			}
@@ -442,9 +456,12 @@ func (g *GenesisGenerator) loadMinerState(ctx context.Context, actorAddr address
 }
 
 func (g *GenesisGenerator) createMiner(ctx context.Context, m *CreateStorageMinerConfig) (address.Address, address.Address, error) {
-	pkAddr, err := g.keys[m.Owner].Address()
-	if err != nil {
-		return address.Undef, address.Undef, err
+	pkAddr := m.OwnerAddress
+	if pkAddr.Empty() {
+		var err error
+		if pkAddr, err = g.keys[m.Owner].Address(); err != nil {
+			return address.Undef, address.Undef, err
+		}
 	}
 
 	// Resolve worker account's ID address.
@@ -489,13 +506,26 @@ func (g *GenesisGenerator) createMiner(ctx context.Context, m *CreateStorageMine
 	return ownerAddr, ret.IDAddress, nil
 }
 
-func (g *GenesisGenerator) publishDeals(actorAddr, clientAddr address.Address, clientkey *crypto.KeyInfo, comms []*CommitConfig) ([]abi.DealID, error) {
+// resolveKeyAddress returns the ID address of the account of a key.
+func (g *GenesisGenerator) resolveKeyAddress(ctx context.Context, key *crypto.KeyInfo) (address.Address, error) {
+	pkAddr, err := key.Address()
+	if err != nil {
+		return address.Undef, err
+	}
+	stateRoot, err := g.flush(ctx)
+	if err != nil {
+		return address.Undef, err
+	}
+	return gfcstate.NewView(g.cst, stateRoot).InitResolveAddress(ctx, pkAddr)
+}
+
+func (g *GenesisGenerator) publishDeals(actorAddr, workerAddr, clientAddr address.Address, clientkey *crypto.KeyInfo, comms []*CommitConfig) ([]abi.DealID, error) {
 	// Add 0 balance to escrow and locked table
-	_, err := g.vm.ApplyGenesisMessage(clientAddr, builtin.StorageMarketActorAddr, builtin.MethodsMarket.AddBalance, big.Zero(), &clientAddr, &g.chainRand)
+	_, err := g.vm.ApplyGenesisMessage(workerAddr, builtin.StorageMarketActorAddr, builtin.MethodsMarket.AddBalance, big.Zero(), &clientAddr, &g.chainRand)
 	if err != nil {
 		return nil, err
 	}
-	_, err = g.vm.ApplyGenesisMessage(clientAddr, builtin.StorageMarketActorAddr, builtin.MethodsMarket.AddBalance, big.Zero(), &actorAddr, &g.chainRand)
+	_, err = g.vm.ApplyGenesisMessage(workerAddr, builtin.StorageMarketActorAddr, builtin.MethodsMarket.AddBalance, big.Zero(), &actorAddr, &g.chainRand)
 	if err != nil {
 		return nil, err
 	}
@@ -530,8 +560,8 @@ func (g *GenesisGenerator) publishDeals(actorAddr, clientAddr address.Address, c
 		})
 	}
 
-	// apply deal builtin.MethodsMarket.PublishStorageDeals
-	out, err := g.vm.ApplyGenesisMessage(clientAddr, builtin.StorageMarketActorAddr, builtin.MethodsMarket.PublishStorageDeals, big.Zero(), params, &g.chainRand)
+	// apply deal builtin.MethodsMarket.PublishStorageDeals, sent by the provider's worker
+	out, err := g.vm.ApplyGenesisMessage(workerAddr, builtin.StorageMarketActorAddr, builtin.MethodsMarket.PublishStorageDeals, big.Zero(), params, &g.chainRand)
 	if err != nil {
 		return nil, err
 	}
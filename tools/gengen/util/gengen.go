@@ -32,6 +32,12 @@ type CreateStorageMinerConfig struct {
 	// It must be a name of a key from the configs 'Keys' list
 	Owner int
 
+	// OwnerAddress, when set, is the account owning the miner instead of the key
+	// Owner, for miners whose owner key is not available to the generator. The
+	// account must be preallocated, and the key Owner is still the client of the
+	// self-deals of the committed sectors.
+	OwnerAddress address.Address
+
 	// PeerID is the peer ID to set as the miners owner
 	PeerID string
 
@@ -72,6 +78,13 @@ type DealConfig struct {
 	// Collateral values are 0 for now (might need to change to some minimum)
 }
 
+// AccountConfig is an account preallocated funds in the genesis block.
+type AccountConfig struct {
+	Address address.Address
+	// FIL is the string value of whole filecoin allocated to the account
+	FIL string
+}
+
 // GenesisCfg is the top level configuration struct used to create a genesis block.
 type GenesisCfg struct {
 	// Seed is used to sample randomness for generating keys
@@ -87,6 +100,10 @@ type GenesisCfg struct {
 	// that will be preallocated to each account
 	PreallocatedFunds []string
 
+	// PreallocatedAccounts are accounts, known by address only, that will be preallocated funds
+	// after those of the keys
+	PreallocatedAccounts []*AccountConfig
+
 	// Miners is a list of miners that should be set up at the start of the network
 	Miners []*CreateStorageMinerConfig
 
@@ -8,9 +8,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
 	"github.com/filecoin-project/go-filecoin/tools/iptb-plugins/filecoin"
 
 	"github.com/ipfs/go-cid"
@@ -23,6 +25,7 @@ import (
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-units"
 
 	"github.com/ipfs/iptb/testbed/interfaces"
 	"github.com/ipfs/iptb/util"
@@ -55,12 +58,26 @@ var DefaultLogLevel = "3"
 // DefaultLogJSON is the value that will be used for GO_FILECOIN_LOG_JSON
 var DefaultLogJSON = "false"
 
+// DefaultStopTimeout is how long Stop waits for the daemon to exit after
+// interrupting it, before killing the container.
+var DefaultStopTimeout = 30 * time.Second
+
 var (
 	// AttrLogLevel is the key used to set the log level through NewNode attrs
 	AttrLogLevel = "logLevel"
 
 	// AttrLogJSON is the key used to set the node to output json logs
 	AttrLogJSON = "logJSON"
+
+	// AttrCPUs is the key used to limit the number of CPUs the container may use, e.g. "1.5"
+	AttrCPUs = "dockerCPUs"
+
+	// AttrMemory is the key used to limit the memory the container may use, e.g. "2g"
+	AttrMemory = "dockerMemory"
+
+	// AttrNetwork is the key used to attach the container to a user-defined bridge network,
+	// the network is created if it does not exist
+	AttrNetwork = "dockerNetwork"
 )
 
 // Dockerfilecoin represents attributes of a dockerized filecoin node.
@@ -78,6 +95,11 @@ type Dockerfilecoin struct {
 
 	logLevel string
 	logJSON  string
+
+	// nanoCPUs and memory are the resource limits of the container, zero when unlimited
+	nanoCPUs int64
+	memory   int64
+	network  string
 }
 
 var NewNode testbedi.NewNodeFunc // nolint: golint
@@ -138,6 +160,23 @@ func init() {
 			logJSON = v
 		}
 
+		var nanoCPUs int64
+		if v, ok := attrs[AttrCPUs]; ok {
+			cpus, err := strconv.ParseFloat(v, 64)
+			if err != nil || cpus <= 0 {
+				return nil, errors.Errorf("invalid %s: %q", AttrCPUs, v)
+			}
+			nanoCPUs = int64(cpus * 1e9)
+		}
+
+		var memory int64
+		if v, ok := attrs[AttrMemory]; ok {
+			memory, err = units.RAMInBytes(v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s", AttrMemory)
+			}
+		}
+
 		return &Dockerfilecoin{
 			EntryPoint:   dockerEntry,
 			Host:         dockerHost,
@@ -149,6 +188,10 @@ func init() {
 			logLevel: logLevel,
 			logJSON:  logJSON,
 
+			nanoCPUs: nanoCPUs,
+			memory:   memory,
+			network:  attrs[AttrNetwork],
+
 			dir:       dir,
 			apiaddr:   apiaddr,
 			swarmaddr: swarmaddr,
@@ -176,6 +219,10 @@ func (l *Dockerfilecoin) Init(ctx context.Context, args ...string) (testbedi.Out
 		return nil, err
 	}
 
+	if err := l.ensureNetwork(ctx, cli); err != nil {
+		return nil, err
+	}
+
 	resp, err := cli.ContainerCreate(ctx,
 		&container.Config{
 			Entrypoint: l.EntryPoint,
@@ -185,16 +232,14 @@ func (l *Dockerfilecoin) Init(ctx context.Context, args ...string) (testbedi.Out
 			Cmd:        cmds,
 			Tty:        false,
 		},
-		&container.HostConfig{
-			Binds: []string{
-				fmt.Sprintf("%s%s:%s", l.VolumePrefix, l.Dir(), "/data/filecoin"),
-			},
-		},
-		&network.NetworkingConfig{},
+		l.hostConfig(),
+		l.networkingConfig(),
 		"")
 	if err != nil {
 		return nil, err
 	}
+	// the init container is only needed until its logs are collected
+	defer cli.ContainerRemove(context.Background(), resp.ID, types.ContainerRemoveOptions{}) // nolint: errcheck
 
 	// This runs the init command, when the command completes the container will stop running, since we
 	// have the added the bindings above the repo will be persisted, meaning we can create a second container
@@ -219,7 +264,7 @@ func (l *Dockerfilecoin) Init(ctx context.Context, args ...string) (testbedi.Out
 	// collect logs generated during container execution
 	out, err := cli.ContainerLogs(ctx, resp.ID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	defer out.Close() // nolint: errcheck
 
@@ -265,6 +310,10 @@ func (l *Dockerfilecoin) Start(ctx context.Context, wait bool, args ...string) (
 		return nil, err
 	}
 
+	if err := l.ensureNetwork(ctx, cli); err != nil {
+		return nil, err
+	}
+
 	// Create the container, first command needs to be daemon, now we have an ID for it
 	resp, err := cli.ContainerCreate(ctx,
 		&container.Config{
@@ -277,10 +326,8 @@ func (l *Dockerfilecoin) Start(ctx context.Context, wait bool, args ...string) (
 			AttachStdout: true,
 			AttachStderr: true,
 		},
-		&container.HostConfig{
-			Binds: []string{fmt.Sprintf("%s%s:%s", l.VolumePrefix, l.Dir(), "/data/filecoin")},
-		},
-		&network.NetworkingConfig{},
+		l.hostConfig(),
+		l.networkingConfig(),
 		"")
 	if err != nil {
 		return nil, err
@@ -290,6 +337,13 @@ func (l *Dockerfilecoin) Start(ctx context.Context, wait bool, args ...string) (
 	if err := cli.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
 		return nil, err
 	}
+	l.ID = resp.ID
+
+	if wait {
+		if err := l.waitOnAPI(ctx); err != nil {
+			return nil, err
+		}
+	}
 
 	// TODO this when it would be nice to have filecoin log to a file, then we could just mount that..
 	// Sleep for a bit, else we don't see any logs
@@ -327,22 +381,50 @@ func (l *Dockerfilecoin) Start(ctx context.Context, wait bool, args ...string) (
 	return iptbutil.NewOutput(cmds, outBuf.Bytes(), errBuf.Bytes(), int(0), err), err
 }
 
-// Stop stops the node process.
+// Stop stops the node process. The daemon is interrupted and given until ctx is done, or
+// DefaultStopTimeout when ctx has no deadline, to exit before its container is killed.
+// The container is removed once it has stopped.
 func (l *Dockerfilecoin) Stop(ctx context.Context) error {
 	cli, err := l.GetClient()
 	if err != nil {
 		return err
 	}
 
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultStopTimeout)
+		defer cancel()
+	}
+
+	// wait on the container before interrupting it so its exit can't be missed
+	statusCh, errCh := cli.ContainerWait(ctx, l.ID, container.WaitConditionNotRunning)
+
 	// "2" is the same as Ctrl+C
 	if err := cli.ContainerKill(ctx, l.ID, "2"); err != nil {
 		return err
 	}
 
+	select {
+	case <-statusCh:
+	case err := <-errCh:
+		if ctx.Err() == nil {
+			return err
+		}
+		l.Errorf("daemon did not exit in time, killing container")
+		if err := cli.ContainerKill(context.Background(), l.ID, "KILL"); err != nil {
+			return err
+		}
+	}
+
+	if err := cli.ContainerRemove(context.Background(), l.ID, types.ContainerRemoveOptions{}); err != nil {
+		return err
+	}
+
 	// remove the dockerid file since we use this in `Start` as a liveness check
 	if err := os.Remove(filepath.Join(l.Dir(), "dockerid")); err != nil {
 		return err
 	}
+	l.ID = ""
 
 	return nil
 }
@@ -356,7 +438,7 @@ func (l *Dockerfilecoin) RunCmd(ctx context.Context, stdin io.Reader, args ...st
 	}
 
 	// TODO use an env var
-	return Exec(ctx, cli, l.ID, false, "/data/filecoin", args...)
+	return Exec(ctx, cli, l.ID, l.User, stdin, "/data/filecoin", args...)
 }
 
 // Connect connects the node to another testbed node.
@@ -395,6 +477,74 @@ func (l *Dockerfilecoin) env() ([]string, error) {
 	return envs, nil
 }
 
+// hostConfig returns the host configuration of the node containers, binding the repo
+// directory and applying the resource limits and network of the node.
+func (l *Dockerfilecoin) hostConfig() *container.HostConfig {
+	hc := &container.HostConfig{
+		Binds: []string{fmt.Sprintf("%s%s:%s", l.VolumePrefix, l.Dir(), "/data/filecoin")},
+		Resources: container.Resources{
+			NanoCPUs: l.nanoCPUs,
+			Memory:   l.memory,
+		},
+	}
+	if l.network != "" {
+		hc.NetworkMode = container.NetworkMode(l.network)
+	}
+	return hc
+}
+
+func (l *Dockerfilecoin) networkingConfig() *network.NetworkingConfig {
+	if l.network == "" {
+		return &network.NetworkingConfig{}
+	}
+	return &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			l.network: {},
+		},
+	}
+}
+
+// ensureNetwork creates the bridge network of the node if it does not exist yet.
+func (l *Dockerfilecoin) ensureNetwork(ctx context.Context, cli client.APIClient) error {
+	if l.network == "" {
+		return nil
+	}
+
+	_, err := cli.NetworkInspect(ctx, l.network, types.NetworkInspectOptions{})
+	if err == nil {
+		return nil
+	}
+	if !client.IsErrNotFound(err) {
+		return err
+	}
+
+	_, err = cli.NetworkCreate(ctx, l.network, types.NetworkCreate{
+		CheckDuplicate: true,
+		Driver:         "bridge",
+	})
+	// another node of the testbed may have created it concurrently
+	if err != nil && !strings.Contains(err.Error(), "already exists") {
+		return err
+	}
+	return nil
+}
+
+// waitOnAPI waits for the daemon in the container to answer api requests.
+func (l *Dockerfilecoin) waitOnAPI(ctx context.Context) error {
+	for i := 0; i < 50; i++ {
+		out, err := l.RunCmd(ctx, nil, "go-filecoin", "id")
+		if err == nil && out.ExitCode() == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(400 * time.Millisecond):
+		}
+	}
+	return errors.Errorf("node %s failed to come online in given time period", l.Dir())
+}
+
 // Shell starts a shell in the context of a node.
 func (l *Dockerfilecoin) Shell(ctx context.Context, ns []testbedi.Core) error {
 	panic("NYI")
@@ -461,12 +611,13 @@ func (l *Dockerfilecoin) SwarmAddrs() ([]string, error) {
 
 /** Config Interface **/
 
-// GetConfig returns the nodes config.
-func (l *Dockerfilecoin) GetConfig() (interface{}, error) {
-	panic("NYI")
+// Config returns the nodes config.
+func (l *Dockerfilecoin) Config() (interface{}, error) {
+	return config.ReadFile(filepath.Join(l.Dir(), "config.json"))
 }
 
 // WriteConfig writes a nodes config file.
 func (l *Dockerfilecoin) WriteConfig(cfg interface{}) error {
-	panic("NYI")
+	lcfg := cfg.(*config.Config)
+	return lcfg.WriteFile(filepath.Join(l.Dir(), "config.json"))
 }
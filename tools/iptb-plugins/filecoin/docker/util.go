@@ -44,18 +44,18 @@ func (res *ExecResult) Combined() string {
 // Exec executes a command inside a container, returning the result
 // containing stdout, stderr, and exit code. Note:
 //  - this is a synchronous operation;
-//  - cmd stdin is closed.
-func Exec(ctx context.Context, cli client.APIClient, containerID string, detach bool, repoDir string, args ...string) (testbedi.Output, error) {
+//  - cmd stdin is closed once stdin, if not nil, is consumed.
+func Exec(ctx context.Context, cli client.APIClient, containerID string, user string, stdin io.Reader, repoDir string, args ...string) (testbedi.Output, error) {
 	// prepare exec
 	cmd := []string{"sh", "-c"}
 	cmd = append(cmd, strings.Join(args, " "))
 	execConfig := types.ExecConfig{
-		User:         "filecoin",
+		User:         user,
+		AttachStdin:  stdin != nil,
 		AttachStdout: true,
 		AttachStderr: true,
 		Env:          []string{fmt.Sprintf("FIL_PATH=%s", repoDir)},
 		Cmd:          cmd,
-		Detach:       detach,
 	}
 	cresp, err := cli.ContainerExecCreate(ctx, containerID, execConfig)
 	if err != nil {
@@ -70,6 +70,13 @@ func Exec(ctx context.Context, cli client.APIClient, containerID string, detach
 	}
 	defer aresp.Close()
 
+	if stdin != nil {
+		go func() {
+			_, _ = io.Copy(aresp.Conn, stdin)
+			_ = aresp.CloseWrite()
+		}()
+	}
+
 	// read the output
 	var outBuf, errBuf bytes.Buffer
 	outputDone := make(chan error)
@@ -104,7 +111,7 @@ func Exec(ctx context.Context, cli client.APIClient, containerID string, detach
 // TODO this a temp fix, should read the nodes keystore instead
 func (l *Dockerfilecoin) GetPeerID() (cid.Cid, error) {
 	// run the id command
-	out, err := l.RunCmd(context.TODO(), nil, "id", "--format=<id>")
+	out, err := l.RunCmd(context.TODO(), nil, "go-filecoin", "id", "--format='<id>'")
 	if err != nil {
 		return cid.Undef, err
 	}
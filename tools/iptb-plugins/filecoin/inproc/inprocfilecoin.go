@@ -0,0 +1,396 @@
+package plugininprocfilecoin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ipfs/iptb/testbed/interfaces"
+	"github.com/ipfs/iptb/util"
+
+	commands "github.com/filecoin-project/go-filecoin/cmd/go-filecoin"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/config"
+	"github.com/filecoin-project/go-filecoin/tools/iptb-plugins/filecoin"
+)
+
+// PluginName is the name of the plugin
+var PluginName = "inprocfilecoin"
+
+var log = logging.Logger(PluginName)
+
+// errIsAlive will be returned by Start if the node is already running
+var errIsAlive = errors.New("node is already running")
+
+// errNotAlive will be returned by Stop if the node is not running
+var errNotAlive = errors.New("node is not running")
+
+// defaultRepoPath is the name of the repo path relative to the plugin root directory
+const defaultRepoPath = "repo"
+
+// defaultSectorsPath is the name of the sector path relative to the plugin root directory
+const defaultSectorsPath = "sectors"
+
+var (
+	// AttrSectorsPath is the key used to set the sectors path
+	AttrSectorsPath = "sectorsPath"
+)
+
+// Inprocfilecoin represents a filecoin node running in the process of its testbed. Commands run
+// against it, including the daemon, are executed by the go-filecoin commands package in
+// goroutines, so no go-filecoin binary is needed.
+//
+// Nodes of the same process share its global state, such as logging and network parameters,
+// so they all need to be initialized for the same network.
+type Inprocfilecoin struct {
+	iptbPath    string // Absolute path for all process data
+	repoPath    string // Absolute path to repo
+	sectorsPath string // Absolute path to sectors
+
+	lk sync.Mutex
+	// stop cancels the running daemon, done is closed once it has returned
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+var NewNode testbedi.NewNodeFunc // nolint: golint
+
+func init() {
+	NewNode = func(dir string, attrs map[string]string) (testbedi.Core, error) {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		sectorsPath := filepath.Join(dir, defaultSectorsPath)
+		if v, ok := attrs[AttrSectorsPath]; ok {
+			sectorsPath = v
+		}
+
+		return &Inprocfilecoin{
+			iptbPath:    dir,
+			repoPath:    filepath.Join(dir, defaultRepoPath),
+			sectorsPath: sectorsPath,
+		}, nil
+	}
+}
+
+/** Core Interface **/
+
+// Init runs the node init process.
+func (l *Inprocfilecoin) Init(ctx context.Context, args ...string) (testbedi.Output, error) {
+	args = append([]string{"go-filecoin", "init"}, args...)
+	output, oerr := l.RunCmd(ctx, nil, args...)
+	if oerr != nil {
+		return nil, oerr
+	}
+	if output.ExitCode() != 0 {
+		return output, errors.Errorf("%s exited with non-zero code %d", output.Args(), output.ExitCode())
+	}
+
+	icfg, err := l.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	lcfg := icfg.(*config.Config)
+
+	if err := lcfg.Set("api.address", `"/ip4/127.0.0.1/tcp/0"`); err != nil {
+		return nil, err
+	}
+
+	if err := lcfg.Set("swarm.address", `"/ip4/127.0.0.1/tcp/0"`); err != nil {
+		return nil, err
+	}
+
+	// only set sectors path to l.sectorsPath if init command does not set
+	isectorsPath, err := lcfg.Get("sectorbase.rootdir")
+	if err != nil {
+		return nil, err
+	}
+	if isectorsPath.(string) == "" {
+		if err := lcfg.Set("sectorbase.rootdir", l.sectorsPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := l.WriteConfig(lcfg); err != nil {
+		return nil, err
+	}
+
+	return output, oerr
+}
+
+// Start starts the node daemon in a goroutine. Its output is written to the daemon.stdout and
+// daemon.stderr files of the node directory.
+func (l *Inprocfilecoin) Start(ctx context.Context, wait bool, args ...string) (testbedi.Output, error) {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	if l.stop != nil {
+		return nil, errIsAlive
+	}
+
+	stdout, err := os.Create(filepath.Join(l.iptbPath, "daemon.stdout"))
+	if err != nil {
+		return nil, err
+	}
+
+	stderr, err := os.Create(filepath.Join(l.iptbPath, "daemon.stderr"))
+	if err != nil {
+		return nil, err
+	}
+
+	dargs := append([]string{"go-filecoin", "daemon", l.repoFlag()}, args...)
+
+	// The daemon outlives the start request, it runs until Stop cancels it.
+	dctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer stdout.Close() // nolint: errcheck
+		defer stderr.Close() // nolint: errcheck
+
+		code, err := commands.Run(dctx, dargs, nil, stdout, stderr)
+		if err != nil || code != 0 {
+			l.Errorf("daemon exited with code %d: %v", code, err)
+		}
+	}()
+	l.stop = cancel
+	l.done = done
+
+	l.Infof("Started daemon: %s", l)
+
+	if wait {
+		if err := filecoin.WaitOnAPI(l); err != nil {
+			return nil, err
+		}
+	}
+	return iptbutil.NewOutput(dargs, []byte{}, []byte{}, 0, nil), nil
+}
+
+// Stop stops the node daemon and waits for it to return, or for ctx to be done.
+func (l *Inprocfilecoin) Stop(ctx context.Context) error {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+
+	if l.stop == nil {
+		return errNotAlive
+	}
+
+	l.stop()
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		return fmt.Errorf("error stopping daemon %s: %s", l.iptbPath, ctx.Err())
+	}
+	l.stop = nil
+	l.done = nil
+
+	err := os.Remove(filepath.Join(l.repoPath, "api"))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing API file for daemon at %s: %s", l.repoPath, err)
+	}
+	return nil
+}
+
+// RunCmd runs a command in the context of the node. Only go-filecoin commands can be run,
+// the first argument is expected to be `go-filecoin`.
+func (l *Inprocfilecoin) RunCmd(ctx context.Context, stdin io.Reader, args ...string) (testbedi.Output, error) {
+	if len(args) == 0 || filepath.Base(args[0]) != "go-filecoin" {
+		return nil, errors.Errorf("%s only runs go-filecoin commands, not %q", PluginName, args)
+	}
+
+	cmdArgs := append([]string{"go-filecoin", l.repoFlag()}, args[1:]...)
+
+	var stdinR *os.File
+	if stdin != nil {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close() // nolint: errcheck
+		go func() {
+			_, _ = io.Copy(w, stdin)
+			_ = w.Close()
+		}()
+		stdinR = r
+	}
+
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stderrR, stderrW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the outputs while the command runs so that it never blocks on a full pipe.
+	var g errgroup.Group
+	var stdoutbytes, stderrbytes []byte
+	g.Go(func() error {
+		var err error
+		stdoutbytes, err = ioutil.ReadAll(stdoutR)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		stderrbytes, err = ioutil.ReadAll(stderrR)
+		return err
+	})
+
+	exitcode, runErr := commands.Run(ctx, cmdArgs, stdinR, stdoutW, stderrW)
+	// Close the write side of the pipes so that the reads complete.
+	_ = stdoutW.Close()
+	_ = stderrW.Close()
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	_ = stdoutR.Close()
+	_ = stderrR.Close()
+
+	var outErr error
+	if runErr != nil && ctx.Err() == context.DeadlineExceeded {
+		outErr = errors.Wrapf(runErr, "context deadline exceeded for command: %q", strings.Join(args, " "))
+	}
+
+	return iptbutil.NewOutput(args, stdoutbytes, stderrbytes, exitcode, outErr), nil
+}
+
+// Connect connects the node to another testbed node.
+func (l *Inprocfilecoin) Connect(ctx context.Context, n testbedi.Core) error {
+	swarmaddrs, err := n.SwarmAddrs()
+	if err != nil {
+		return err
+	}
+
+	output, err := l.RunCmd(ctx, nil, "go-filecoin", "swarm", "connect", swarmaddrs[0])
+	if err != nil {
+		return err
+	}
+
+	if output.ExitCode() != 0 {
+		out, err := ioutil.ReadAll(output.Stderr())
+		if err != nil {
+			return err
+		}
+
+		return fmt.Errorf("%s", string(out))
+	}
+
+	return nil
+}
+
+// Shell is not supported, the node has no process of its own to attach a shell to.
+func (l *Inprocfilecoin) Shell(ctx context.Context, ns []testbedi.Core) error {
+	return errors.Errorf("%s does not support shells", PluginName)
+}
+
+// Infof writes an info log.
+func (l *Inprocfilecoin) Infof(format string, args ...interface{}) {
+	log.Infof("Node: %s %s", l, fmt.Sprintf(format, args...))
+}
+
+// Errorf writes an error log.
+func (l *Inprocfilecoin) Errorf(format string, args ...interface{}) {
+	log.Errorf("Node: %s %s", l, fmt.Sprintf(format, args...))
+}
+
+// Dir returns the IPTB directory the node is using.
+func (l *Inprocfilecoin) Dir() string {
+	return l.iptbPath
+}
+
+// Type returns the type of the node.
+func (l *Inprocfilecoin) Type() string {
+	return PluginName
+}
+
+// String implements the stringr interface.
+func (l *Inprocfilecoin) String() string {
+	return l.iptbPath
+}
+
+func (l *Inprocfilecoin) repoFlag() string {
+	return fmt.Sprintf("--repodir=%s", l.repoPath)
+}
+
+/** Libp2p Interface **/
+
+// PeerID returns the nodes peerID.
+func (l *Inprocfilecoin) PeerID() (string, error) {
+	details, err := l.id()
+	if err != nil {
+		return "", err
+	}
+
+	return details.ID.String(), nil
+}
+
+// APIAddr returns the api address of the node.
+func (l *Inprocfilecoin) APIAddr() (string, error) {
+	apiaddr, err := filecoin.GetAPIAddrFromRepo(l.repoPath)
+	if err != nil {
+		return "", err
+	}
+
+	return apiaddr.String(), nil
+}
+
+// SwarmAddrs returns the addresses a node is listening on for swarm connections.
+func (l *Inprocfilecoin) SwarmAddrs() ([]string, error) {
+	details, err := l.id()
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]string, len(details.Addresses))
+	for i, addr := range details.Addresses {
+		addrs[i] = addr.String()
+	}
+	return addrs, nil
+}
+
+// id returns the details of the node from its `id` command.
+func (l *Inprocfilecoin) id() (commands.IDDetails, error) {
+	var details commands.IDDetails
+
+	out, err := l.RunCmd(context.TODO(), nil, "go-filecoin", "id")
+	if err != nil {
+		return details, err
+	}
+
+	if out.ExitCode() != 0 {
+		return details, errors.New("Could not get node id, non-zero exit code")
+	}
+
+	b, err := ioutil.ReadAll(out.Stdout())
+	if err != nil {
+		return details, err
+	}
+
+	err = details.UnmarshalJSON(b)
+	return details, err
+}
+
+/** Config Interface **/
+
+// Config returns the nodes config.
+func (l *Inprocfilecoin) Config() (interface{}, error) {
+	return config.ReadFile(filepath.Join(l.repoPath, "config.json"))
+}
+
+// WriteConfig writes a nodes config file.
+func (l *Inprocfilecoin) WriteConfig(cfg interface{}) error {
+	lcfg := cfg.(*config.Config)
+	return lcfg.WriteFile(filepath.Join(l.repoPath, "config.json"))
+}
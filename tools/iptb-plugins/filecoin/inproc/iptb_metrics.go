@@ -0,0 +1,46 @@
+package plugininprocfilecoin
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Events not implemented
+func (l *Inprocfilecoin) Events() (io.ReadCloser, error) {
+	panic("Not Implemented")
+}
+
+// StderrReader provides an io.ReadCloser to the running daemons stderr
+func (l *Inprocfilecoin) StderrReader() (io.ReadCloser, error) {
+	return l.readerFor("daemon.stderr")
+}
+
+// StdoutReader provides an io.ReadCloser to the running daemons stdout
+func (l *Inprocfilecoin) StdoutReader() (io.ReadCloser, error) {
+	return l.readerFor("daemon.stdout")
+}
+
+// Heartbeat not implemented
+func (l *Inprocfilecoin) Heartbeat() (map[string]string, error) {
+	panic("Not Implemented")
+}
+
+// Metric not implemented
+func (l *Inprocfilecoin) Metric(key string) (string, error) {
+	panic("Not Implemented")
+}
+
+// GetMetricList not implemented
+func (l *Inprocfilecoin) GetMetricList() []string {
+	panic("Not Implemented")
+}
+
+// GetMetricDesc not implemented
+func (l *Inprocfilecoin) GetMetricDesc(key string) (string, error) {
+	panic("Not Implemented")
+}
+
+func (l *Inprocfilecoin) readerFor(file string) (io.ReadCloser, error) {
+	return os.OpenFile(filepath.Join(l.iptbPath, file), os.O_RDONLY, 0)
+}
@@ -0,0 +1,270 @@
+// Package crawler crawls the nodes of a filecoin network and records their
+// chain height, power, peer links and agent versions.
+package crawler
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/filecoin-project/specs-actors/actors/abi/big"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p-core/peer"
+)
+
+var log = logging.Logger("netstat/crawler")
+
+// Probe is what a node tells about itself when probed.
+type Probe struct {
+	Addrs        []string
+	AgentVersion string
+	// Height and Genesis are the head height and genesis cid of the node, as
+	// sent in its hello message.
+	Height  abi.ChainEpoch
+	Genesis string
+	// Links are the peers connected to the node.
+	Links []peer.ID
+}
+
+// Prober discovers the nodes of a network and probes them.
+type Prober interface {
+	// Discover returns the first nodes to crawl.
+	Discover(ctx context.Context) ([]peer.ID, error)
+	// Probe connects to a node and returns what it tells about itself.
+	Probe(ctx context.Context, p peer.ID) (*Probe, error)
+	// Reset closes the connections opened by the crawl, so that the next
+	// crawl receives fresh hello messages.
+	Reset()
+}
+
+// MinerPower is the power of a miner run by a node.
+type MinerPower struct {
+	Miner    address.Address
+	RawPower abi.StoragePower
+}
+
+// PowerSource returns the power of the miners of the network by peer.
+type PowerSource func(ctx context.Context) (map[peer.ID]MinerPower, error)
+
+// Node is the state of a node recorded by a crawl.
+type Node struct {
+	ID peer.ID
+	// Reachable is false when the node was linked to by another node but could
+	// not be probed, Error tells why.
+	Reachable    bool
+	Error        string `json:",omitempty"`
+	Addrs        []string
+	AgentVersion string
+	Height       abi.ChainEpoch
+	Genesis      string
+	Links        []peer.ID
+	// Miner is undefined when the node runs no miner.
+	Miner    address.Address
+	RawPower abi.StoragePower
+}
+
+// Summary sums up the health of the network.
+type Summary struct {
+	Nodes     int
+	Reachable int
+	MinHeight abi.ChainEpoch
+	MaxHeight abi.ChainEpoch
+	// Partitions is the number of groups of reachable nodes not linked to
+	// each other, a healthy network has one.
+	Partitions int
+	Miners     int
+	RawPower   abi.StoragePower
+	// Agents and Genesis count the reachable nodes by agent version and by
+	// genesis cid.
+	Agents  map[string]int
+	Genesis map[string]int
+}
+
+// Snapshot is the result of a crawl.
+type Snapshot struct {
+	Time     time.Time
+	Duration time.Duration
+	Summary  Summary
+	// Nodes are sorted by peer id.
+	Nodes []*Node
+	// PowerError is set when the power of the miners could not be looked up.
+	PowerError string `json:",omitempty"`
+}
+
+// Crawler crawls a network from the nodes discovered by its prober, following
+// their links.
+type Crawler struct {
+	prober      Prober
+	power       PowerSource
+	parallelism int
+	timeout     time.Duration
+}
+
+// New returns a crawler probing `parallelism` nodes at a time, for at most
+// `timeout` each. `power` may be nil.
+func New(prober Prober, power PowerSource, parallelism int, timeout time.Duration) *Crawler {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	return &Crawler{
+		prober:      prober,
+		power:       power,
+		parallelism: parallelism,
+		timeout:     timeout,
+	}
+}
+
+// Crawl probes every node reachable from the discovered ones.
+func (c *Crawler) Crawl(ctx context.Context) (*Snapshot, error) {
+	start := time.Now()
+	defer c.prober.Reset()
+
+	seeds, err := c.prober.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var lk sync.Mutex
+	nodes := map[peer.ID]*Node{}
+	pending := make(chan peer.ID, len(seeds))
+	var wg sync.WaitGroup
+	enqueue := func(p peer.ID) {
+		// called with lk held
+		if _, ok := nodes[p]; ok {
+			return
+		}
+		nodes[p] = &Node{ID: p, RawPower: big.Zero()}
+		wg.Add(1)
+		go func() { pending <- p }()
+	}
+
+	lk.Lock()
+	for _, p := range seeds {
+		enqueue(p)
+	}
+	lk.Unlock()
+
+	done := make(chan struct{})
+	for i := 0; i < c.parallelism; i++ {
+		go func() {
+			for {
+				select {
+				case p := <-pending:
+					probe, err := c.probe(ctx, p)
+					lk.Lock()
+					node := nodes[p]
+					if err != nil {
+						node.Error = err.Error()
+					} else {
+						node.Reachable = true
+						node.Addrs = probe.Addrs
+						node.AgentVersion = probe.AgentVersion
+						node.Height = probe.Height
+						node.Genesis = probe.Genesis
+						node.Links = probe.Links
+						for _, link := range probe.Links {
+							enqueue(link)
+						}
+					}
+					lk.Unlock()
+					wg.Done()
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+
+	snapshot := &Snapshot{Time: start, Nodes: []*Node{}}
+	for _, node := range nodes {
+		snapshot.Nodes = append(snapshot.Nodes, node)
+	}
+	sort.Slice(snapshot.Nodes, func(i, j int) bool { return snapshot.Nodes[i].ID < snapshot.Nodes[j].ID })
+
+	if c.power != nil {
+		power, err := c.power(ctx)
+		if err != nil {
+			log.Warnf("failed to look up power: %s", err)
+			snapshot.PowerError = err.Error()
+		}
+		for _, node := range snapshot.Nodes {
+			if mp, ok := power[node.ID]; ok {
+				node.Miner = mp.Miner
+				node.RawPower = mp.RawPower
+			}
+		}
+	}
+
+	snapshot.Summary = summarize(snapshot.Nodes)
+	snapshot.Duration = time.Since(start)
+	return snapshot, ctx.Err()
+}
+
+func (c *Crawler) probe(ctx context.Context, p peer.ID) (*Probe, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return c.prober.Probe(ctx, p)
+}
+
+func summarize(nodes []*Node) Summary {
+	s := Summary{
+		Nodes:    len(nodes),
+		RawPower: big.Zero(),
+		Agents:   map[string]int{},
+		Genesis:  map[string]int{},
+	}
+
+	// reachable nodes are grouped in partitions by their links, in either
+	// direction
+	parent := map[peer.ID]peer.ID{}
+	var find func(p peer.ID) peer.ID
+	find = func(p peer.ID) peer.ID {
+		if parent[p] == p {
+			return p
+		}
+		root := find(parent[p])
+		parent[p] = root
+		return root
+	}
+	for _, node := range nodes {
+		if node.Reachable {
+			parent[node.ID] = node.ID
+		}
+	}
+
+	for _, node := range nodes {
+		if !node.Reachable {
+			continue
+		}
+		if s.Reachable == 0 || node.Height < s.MinHeight {
+			s.MinHeight = node.Height
+		}
+		if node.Height > s.MaxHeight {
+			s.MaxHeight = node.Height
+		}
+		s.Reachable++
+		s.Agents[node.AgentVersion]++
+		s.Genesis[node.Genesis]++
+		if !node.Miner.Empty() {
+			s.Miners++
+			s.RawPower = big.Add(s.RawPower, node.RawPower)
+		}
+		for _, link := range node.Links {
+			if _, ok := parent[link]; ok {
+				parent[find(link)] = find(node.ID)
+			}
+		}
+	}
+
+	for p := range parent {
+		if find(p) == p {
+			s.Partitions++
+		}
+	}
+	return s
+}
@@ -0,0 +1,85 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/abi"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	th "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers"
+	tf "github.com/filecoin-project/go-filecoin/internal/pkg/testhelpers/testflags"
+)
+
+type fakeProber struct {
+	seeds  []peer.ID
+	probes map[peer.ID]*Probe
+	resets int
+}
+
+func (f *fakeProber) Discover(context.Context) ([]peer.ID, error) {
+	return f.seeds, nil
+}
+
+func (f *fakeProber) Probe(_ context.Context, p peer.ID) (*Probe, error) {
+	probe, ok := f.probes[p]
+	if !ok {
+		return nil, fmt.Errorf("connection refused")
+	}
+	return probe, nil
+}
+
+func (f *fakeProber) Reset() {
+	f.resets++
+}
+
+func TestCrawlFollowsLinks(t *testing.T) {
+	tf.UnitTest(t)
+
+	a, b, c, d, e := th.RequireIntPeerID(t, 1), th.RequireIntPeerID(t, 2), th.RequireIntPeerID(t, 3), th.RequireIntPeerID(t, 4), th.RequireIntPeerID(t, 5)
+	t.Log("a and b are linked, c is linked to the unreachable d, e is alone")
+	prober := &fakeProber{
+		seeds: []peer.ID{a, e},
+		probes: map[peer.ID]*Probe{
+			a: {AgentVersion: "v1", Height: 10, Genesis: "g", Links: []peer.ID{b}},
+			b: {AgentVersion: "v1", Height: 12, Genesis: "g", Links: []peer.ID{a, c}},
+			c: {AgentVersion: "v2", Height: 7, Genesis: "g", Links: []peer.ID{d}},
+			e: {AgentVersion: "v2", Height: 3, Genesis: "other"},
+		},
+	}
+	miner, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	power := func(context.Context) (map[peer.ID]MinerPower, error) {
+		return map[peer.ID]MinerPower{b: {Miner: miner, RawPower: abi.NewStoragePower(1024)}}, nil
+	}
+
+	snapshot, err := New(prober, power, 2, time.Second).Crawl(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, prober.resets)
+
+	require.Len(t, snapshot.Nodes, 5)
+	byID := map[peer.ID]*Node{}
+	for _, node := range snapshot.Nodes {
+		byID[node.ID] = node
+	}
+	assert.True(t, byID[c].Reachable)
+	assert.False(t, byID[d].Reachable)
+	assert.Equal(t, "connection refused", byID[d].Error)
+	assert.Equal(t, miner, byID[b].Miner)
+
+	s := snapshot.Summary
+	assert.Equal(t, 5, s.Nodes)
+	assert.Equal(t, 4, s.Reachable)
+	assert.Equal(t, abi.ChainEpoch(3), s.MinHeight)
+	assert.Equal(t, abi.ChainEpoch(12), s.MaxHeight)
+	assert.Equal(t, 2, s.Partitions)
+	assert.Equal(t, 1, s.Miners)
+	assert.Equal(t, abi.NewStoragePower(1024), s.RawPower)
+	assert.Equal(t, map[string]int{"v1": 2, "v2": 2}, s.Agents)
+	assert.Equal(t, map[string]int{"g": 3, "other": 1}, s.Genesis)
+}
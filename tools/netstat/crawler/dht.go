@@ -0,0 +1,221 @@
+package crawler
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	dhtopts "github.com/libp2p/go-libp2p-kad-dht/opts"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/pkg/cborutil"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/discovery"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/encoding"
+	"github.com/filecoin-project/go-filecoin/internal/pkg/net"
+)
+
+// randomWalks is the number of random DHT lookups made to discover nodes.
+const randomWalks = 5
+
+// helloPoll is the interval at which a probe checks for the hello message of
+// the node.
+const helloPoll = 100 * time.Millisecond
+
+// DHTProber discovers nodes by walking the filecoin DHT of a network, from
+// bootstrap nodes. Nodes tell their chain head in the hello message they send
+// upon connection, and their links are found by asking the DHT which peers
+// are connected to them.
+type DHTProber struct {
+	host      host.Host
+	dht       *dht.IpfsDHT
+	bootstrap []peer.AddrInfo
+
+	lk     sync.Mutex
+	hellos map[peer.ID]*discovery.HelloMessage
+}
+
+// NewDHTProber returns a prober of the network `networkName` using the host,
+// which must not run a filecoin node.
+func NewDHTProber(ctx context.Context, h host.Host, networkName string, bootstrap []peer.AddrInfo) (*DHTProber, error) {
+	r, err := dht.New(ctx, h, dhtopts.Client(true), dhtopts.Protocols(net.FilecoinDHT(networkName)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to set up the DHT")
+	}
+	p := &DHTProber{
+		host:      h,
+		dht:       r,
+		bootstrap: bootstrap,
+		hellos:    map[peer.ID]*discovery.HelloMessage{},
+	}
+	h.SetStreamHandler(discovery.HelloProtocolID, p.handleHello)
+	return p, nil
+}
+
+// Discover connects to the bootstrap nodes and returns the nodes found by
+// random walks of the DHT.
+func (p *DHTProber) Discover(ctx context.Context) ([]peer.ID, error) {
+	connected := 0
+	for _, pi := range p.bootstrap {
+		if err := p.host.Connect(ctx, pi); err != nil {
+			log.Warnf("failed to connect to bootstrap node %s: %s", pi.ID, err)
+			continue
+		}
+		connected++
+	}
+	if connected == 0 && len(p.bootstrap) > 0 {
+		return nil, fmt.Errorf("failed to connect to any of the %d bootstrap nodes", len(p.bootstrap))
+	}
+
+	found := map[peer.ID]struct{}{}
+	for _, pi := range p.bootstrap {
+		found[pi.ID] = struct{}{}
+	}
+	for i := 0; i < randomWalks; i++ {
+		key, err := randomPeerID()
+		if err != nil {
+			return nil, err
+		}
+		closest, err := p.dht.GetClosestPeers(ctx, string(key))
+		if err != nil {
+			log.Debugf("random walk failed: %s", err)
+			continue
+		}
+		for id := range closest {
+			found[id] = struct{}{}
+		}
+	}
+	for _, id := range p.dht.RoutingTable().ListPeers() {
+		found[id] = struct{}{}
+	}
+
+	ids := make([]peer.ID, 0, len(found))
+	for id := range found {
+		if id != p.host.ID() {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Probe connects to the node and waits for its hello message.
+func (p *DHTProber) Probe(ctx context.Context, id peer.ID) (*Probe, error) {
+	pi := peer.AddrInfo{ID: id, Addrs: p.host.Peerstore().Addrs(id)}
+	if len(pi.Addrs) == 0 {
+		var err error
+		pi, err = p.dht.FindPeer(ctx, id)
+		if err != nil {
+			return nil, errors.Wrap(err, "no address")
+		}
+	}
+	if err := p.host.Connect(ctx, pi); err != nil {
+		return nil, err
+	}
+
+	hello, err := p.waitHello(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	probe := &Probe{
+		Addrs:   []string{},
+		Height:  hello.HeaviestTipSetHeight,
+		Genesis: hello.GenesisHash.String(),
+		Links:   []peer.ID{},
+	}
+	for _, addr := range p.host.Peerstore().Addrs(id) {
+		probe.Addrs = append(probe.Addrs, addr.String())
+	}
+	if agent, err := p.host.Peerstore().Get(id, "AgentVersion"); err == nil {
+		probe.AgentVersion, _ = agent.(string)
+	}
+
+	links, err := p.dht.FindPeersConnectedToPeer(ctx, id)
+	if err != nil {
+		// the node is linked to nobody known
+		return probe, nil
+	}
+	seen := map[peer.ID]struct{}{}
+	for {
+		select {
+		case pi, ok := <-links:
+			if !ok {
+				return probe, nil
+			}
+			if _, ok := seen[pi.ID]; !ok && pi.ID != p.host.ID() {
+				seen[pi.ID] = struct{}{}
+				probe.Links = append(probe.Links, pi.ID)
+			}
+		case <-ctx.Done():
+			// the peers found before the timeout are reported
+			return probe, nil
+		}
+	}
+}
+
+// Reset closes the connections to all nodes and forgets their hello messages.
+func (p *DHTProber) Reset() {
+	for _, id := range p.host.Network().Peers() {
+		_ = p.host.Network().ClosePeer(id)
+	}
+	p.lk.Lock()
+	p.hellos = map[peer.ID]*discovery.HelloMessage{}
+	p.lk.Unlock()
+}
+
+func (p *DHTProber) waitHello(ctx context.Context, id peer.ID) (*discovery.HelloMessage, error) {
+	ticker := time.NewTicker(helloPoll)
+	defer ticker.Stop()
+	for {
+		p.lk.Lock()
+		hello, ok := p.hellos[id]
+		p.lk.Unlock()
+		if ok {
+			return hello, nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, errors.New("no hello message, not a filecoin node")
+		}
+	}
+}
+
+// handleHello records the hello message a node sends upon connection and
+// answers with the latency message it expects.
+func (p *DHTProber) handleHello(s network.Stream) {
+	defer s.Close() // nolint: errcheck
+
+	var hello discovery.HelloMessage
+	if err := cborutil.NewMsgReader(s).ReadMsg(&hello); err != nil {
+		log.Debugf("failed to read hello message from %s: %s", s.Conn().RemotePeer(), err)
+		return
+	}
+	arrival := time.Now().UnixNano()
+	p.lk.Lock()
+	p.hellos[s.Conn().RemotePeer()] = &hello
+	p.lk.Unlock()
+
+	raw, err := encoding.Encode(&discovery.LatencyMessage{TArrival: arrival, TSent: time.Now().UnixNano()})
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if _, err := s.Write(raw); err != nil {
+		log.Debugf("failed to send latency message to %s: %s", s.Conn().RemotePeer(), err)
+	}
+}
+
+func randomPeerID() (peer.ID, error) {
+	_, pub, err := crypto.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	return peer.IDFromPublicKey(pub)
+}
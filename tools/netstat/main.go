@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/specs-actors/actors/builtin"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/go-filecoin/internal/app/go-filecoin/porcelain"
+	"github.com/filecoin-project/go-filecoin/tools/netstat/crawler"
+)
+
+/* netstat crawls a devnet through its DHT and serves the topology and health
+of the network it finds:
+
+	$ netstat -network mynet -bootstrap /ip4/1.2.3.4/tcp/6000/p2p/Qm... -fil-api localhost:3453
+
+  /              dashboard of the last crawl
+  /api/snapshot  the last crawl as JSON: a summary, and each node with its
+                 height, genesis, agent version, links, miner and power
+
+The power of the miners is looked up through the API of the node -fil-api,
+it is omitted when the flag is not set.
+*/
+
+var log = logging.Logger("netstat")
+
+func init() {
+	// Info level
+	logging.SetAllLoggers(4)
+}
+
+func main() {
+	network := flag.String("network", "", "(required) the name of the network to crawl")
+	bootstrap := flag.String("bootstrap", "", "(required) comma separated multiaddrs of the nodes from which to crawl, ending with /p2p/<peer id>")
+	filapi := flag.String("fil-api", "", "the api address of a filecoin node used to look up the power of miners")
+	interval := flag.Duration("interval", time.Minute, "time between crawls")
+	timeout := flag.Duration("timeout", 20*time.Second, "maximum time to probe a node")
+	parallelism := flag.Int("parallelism", 16, "number of nodes probed at a time")
+	listen := flag.String("listen", ":9798", "the address on which to serve the dashboard")
+	flag.Parse()
+
+	if *network == "" || *bootstrap == "" {
+		fmt.Println("ERROR: must provide the network name and bootstrap nodes")
+		flag.Usage()
+		return
+	}
+	peers, err := parseBootstrap(*bootstrap)
+	if err != nil {
+		fmt.Printf("ERROR: invalid bootstrap address: %s\n", err)
+		return
+	}
+
+	ctx := context.Background()
+	h, err := libp2p.New(ctx)
+	if err != nil {
+		panic(err)
+	}
+	prober, err := crawler.NewDHTProber(ctx, h, *network, peers)
+	if err != nil {
+		panic(err)
+	}
+	var power crawler.PowerSource
+	if *filapi != "" {
+		power = apiPower(*filapi)
+	}
+	c := crawler.New(prober, power, *parallelism, *timeout)
+
+	var lk sync.Mutex
+	last := &crawler.Snapshot{Nodes: []*crawler.Node{}}
+	go func() {
+		for {
+			log.Infof("crawling %s", *network)
+			snapshot, err := c.Crawl(ctx)
+			if err != nil {
+				log.Errorf("crawl failed: %s", err)
+			} else {
+				log.Infof("crawled %d nodes in %s", snapshot.Summary.Nodes, snapshot.Duration)
+				lk.Lock()
+				last = snapshot
+				lk.Unlock()
+			}
+			time.Sleep(*interval)
+		}
+	}()
+	current := func() *crawler.Snapshot {
+		lk.Lock()
+		defer lk.Unlock()
+		return last
+	}
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if err := dashboard.Execute(w, dashboardData{Network: *network, Snapshot: current()}); err != nil {
+			log.Errorf("failed to render dashboard: %s", err)
+		}
+	})
+	http.HandleFunc("/api/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(current()); err != nil {
+			log.Errorf("failed to write snapshot: %s", err)
+		}
+	})
+
+	panic(http.ListenAndServe(*listen, nil))
+}
+
+// apiPower looks up the miner actors and their power through the HTTP API of
+// a filecoin node.
+func apiPower(filapi string) crawler.PowerSource {
+	return func(ctx context.Context) (map[peer.ID]crawler.MinerPower, error) {
+		resp, err := http.Post(fmt.Sprintf("http://%s/api/actor/ls", filapi), "application/json", nil)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close() // nolint: errcheck
+		if resp.StatusCode != 200 {
+			return nil, fmt.Errorf("listing actors failed: %s", resp.Status)
+		}
+
+		var miners []address.Address
+		dec := json.NewDecoder(resp.Body)
+		for {
+			var actor struct {
+				Address string
+				Code    map[string]string
+			}
+			if err := dec.Decode(&actor); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, errors.Wrap(err, "failed to decode actor")
+			}
+			if actor.Code["/"] != builtin.StorageMinerActorCodeID.String() {
+				continue
+			}
+			addr, err := address.NewFromString(actor.Address)
+			if err != nil {
+				return nil, err
+			}
+			miners = append(miners, addr)
+		}
+
+		power := map[peer.ID]crawler.MinerPower{}
+		for _, miner := range miners {
+			status, err := minerStatus(filapi, miner)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get the status of miner %s", miner)
+			}
+			power[status.PeerID] = crawler.MinerPower{Miner: miner, RawPower: status.RawPower}
+		}
+		return power, nil
+	}
+}
+
+func minerStatus(filapi string, miner address.Address) (*porcelain.MinerStatus, error) {
+	resp, err := http.Post(fmt.Sprintf("http://%s/api/miner/status?arg=%s", filapi, miner), "application/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("status: %s", resp.Status)
+	}
+	var status porcelain.MinerStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+type dashboardData struct {
+	Network  string
+	Snapshot *crawler.Snapshot
+}
+
+var dashboard = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"short": func(p peer.ID) string {
+		s := p.String()
+		if len(s) > 12 {
+			return s[len(s)-12:]
+		}
+		return s
+	},
+}).Parse(`
+<html>
+	<head><title>{{.Network}} netstat</title></head>
+	<body>
+		<h1>{{.Network}}</h1>
+		{{with .Snapshot}}
+		<p>Crawled {{.Time.Format "2006-01-02 15:04:05"}} in {{.Duration}}</p>
+		{{with .Summary}}
+		<table>
+			<tr><td>Nodes</td><td>{{.Nodes}} ({{.Reachable}} reachable)</td></tr>
+			<tr><td>Heights</td><td>{{.MinHeight}} - {{.MaxHeight}}</td></tr>
+			<tr><td>Partitions</td><td>{{.Partitions}}</td></tr>
+			<tr><td>Miners</td><td>{{.Miners}}, raw power {{.RawPower}} bytes</td></tr>
+			<tr><td>Agents</td><td>{{range $agent, $n := .Agents}}{{$agent}}: {{$n}}<br/>{{end}}</td></tr>
+			<tr><td>Genesis</td><td>{{range $genesis, $n := .Genesis}}{{$genesis}}: {{$n}}<br/>{{end}}</td></tr>
+		</table>
+		{{end}}
+		{{if .PowerError}}<p>Power unavailable: {{.PowerError}}</p>{{end}}
+		<table border="1">
+			<tr><th>Peer</th><th>Agent</th><th>Height</th><th>Miner</th><th>Raw power</th><th>Links</th><th>Addresses</th></tr>
+			{{range .Nodes}}
+			<tr>
+				<td title="{{.ID}}">{{short .ID}}</td>
+				{{if .Reachable}}
+				<td>{{.AgentVersion}}</td>
+				<td>{{.Height}}</td>
+				<td>{{if not .Miner.Empty}}{{.Miner}}{{end}}</td>
+				<td>{{.RawPower}}</td>
+				<td>{{range .Links}}<span title="{{.}}">{{short .}}</span> {{end}}</td>
+				<td>{{range .Addrs}}{{.}}<br/>{{end}}</td>
+				{{else}}
+				<td colspan="6">unreachable: {{.Error}}</td>
+				{{end}}
+			</tr>
+			{{end}}
+		</table>
+		{{end}}
+	</body>
+</html>
+`))
+
+func parseBootstrap(s string) ([]peer.AddrInfo, error) {
+	var addrs []ma.Multiaddr
+	for _, a := range strings.Split(s, ",") {
+		addr, err := ma.NewMultiaddr(strings.TrimSpace(a))
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return peer.AddrInfosFromP2pAddrs(addrs...)
+}